@@ -0,0 +1,67 @@
+package reindexer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Activity - client side operation, which is in progress
+type Activity struct {
+	// Operation (e.g. "Upsert", "Query.Exec", "Tx.Commit")
+	Op string
+	// Namespace of the operation
+	Namespace string
+	// Start time of the operation
+	Started time.Time
+	// Time, elapsed since the start of the operation
+	Elapsed time.Duration
+	// Deadline of the operation's context (including default deadline of the namespace). Zero, if there is no deadline
+	Deadline time.Time
+}
+
+// activityTracker contains operations, which are in progress
+type activityTracker struct {
+	lock       sync.Mutex
+	activities map[*Activity]struct{}
+}
+
+func newActivityTracker() *activityTracker {
+	return &activityTracker{activities: make(map[*Activity]struct{})}
+}
+
+// startActivity registers operation op in the tracker. Returned function must be called after the end of the operation
+func (db *reindexerImpl) startActivity(ctx context.Context, op string, namespace string) (finish func()) {
+	a := &Activity{Op: op, Namespace: namespace, Started: time.Now()}
+	if ctx != nil {
+		a.Deadline, _ = ctx.Deadline()
+	}
+	t := db.activities
+	t.lock.Lock()
+	t.activities[a] = struct{}{}
+	t.lock.Unlock()
+	return func() {
+		t.lock.Lock()
+		delete(t.activities, a)
+		t.lock.Unlock()
+	}
+}
+
+// activity returns operations, which are in progress, from the oldest to the newest
+func (db *reindexerImpl) activity() []Activity {
+	now := time.Now()
+	t := db.activities
+	t.lock.Lock()
+	activities := make([]Activity, 0, len(t.activities))
+	for a := range t.activities {
+		activities = append(activities, *a)
+	}
+	t.lock.Unlock()
+
+	for i := range activities {
+		activities[i].Elapsed = now.Sub(activities[i].Started)
+	}
+	sort.Slice(activities, func(i, j int) bool { return activities[i].Started.Before(activities[j].Started) })
+	return activities
+}
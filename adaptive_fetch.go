@@ -0,0 +1,99 @@
+package reindexer
+
+import (
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+const (
+	defaultAdaptiveFetchMinCount  = 100
+	defaultAdaptiveFetchMaxCount  = 10000
+	defaultAdaptiveFetchPageBytes = 1 << 20
+)
+
+// AdaptiveFetch - limits of the adaptive count of the items of the query results' pages (see WithAdaptiveFetch)
+type AdaptiveFetch struct {
+	// Min count of the items of the page. 100, if zero
+	MinCount int
+	// Max count of the items of the page. 10000, if zero
+	MaxCount int
+	// Target size of the page in bytes. Count of the items of the next page is limited by the average size of the items
+	// of the current page. 1MB, if zero
+	PageBytes int64
+}
+
+func newAdaptiveFetch(opt bindings.OptionAdaptiveFetch) *AdaptiveFetch {
+	f := &AdaptiveFetch{MinCount: opt.MinCount, MaxCount: opt.MaxCount, PageBytes: opt.PageBytes}
+	if f.MinCount <= 0 {
+		f.MinCount = defaultAdaptiveFetchMinCount
+	}
+	if f.MaxCount <= 0 {
+		f.MaxCount = defaultAdaptiveFetchMaxCount
+	}
+	if f.MaxCount < f.MinCount {
+		f.MaxCount = f.MinCount
+	}
+	if f.PageBytes <= 0 {
+		f.PageBytes = defaultAdaptiveFetchPageBytes
+	}
+	return f
+}
+
+// firstCount returns count of the items of the first page. Size of the items is unknown yet, so default fetch count is used within the limits
+func (f *AdaptiveFetch) firstCount() int {
+	return f.clamp(defaultFetchCount)
+}
+
+// nextCount returns count of the items of the next page by the current page: count of its items, their size and consumption rate.
+// If the page was consumed faster than it was fetched, round trips dominate and the page grows. If the consumer is much slower
+// than the fetch, the page shrinks to save memory. Unknown fetch time (e.g. of the first page) doesn't change the count
+func (f *AdaptiveFetch) nextCount(count, items, bytes int, consumed, fetched time.Duration) int {
+	if fetched > 0 {
+		if consumed < fetched {
+			count *= 2
+		} else if consumed > 4*fetched {
+			count /= 2
+		}
+	}
+	if items > 0 && bytes > 0 {
+		if bySize := f.PageBytes * int64(items) / int64(bytes); bySize < int64(count) {
+			count = int(bySize)
+		}
+	}
+	return f.clamp(count)
+}
+
+func (f *AdaptiveFetch) clamp(count int) int {
+	if count < f.MinCount {
+		return f.MinCount
+	}
+	if count > f.MaxCount {
+		return f.MaxCount
+	}
+	return count
+}
+
+// queryFetchCount returns count of the items of the first page of the query's results
+func (db *reindexerImpl) queryFetchCount(q *Query) int {
+	if db.adaptiveFetch == nil || q.fetchCountSet {
+		return q.fetchCount
+	}
+	return db.adaptiveFetch.firstCount()
+}
+
+// nextFetchCount returns count of the items of the next page of the iterator's results
+func (it *Iterator) nextFetchCount() int {
+	if it.query == nil {
+		return defaultFetchCount
+	}
+	f := it.db.adaptiveFetch
+	if f == nil || it.query.fetchCountSet {
+		return it.query.fetchCount
+	}
+	if it.fetchCount == 0 {
+		it.fetchCount = f.firstCount()
+	}
+	it.fetchCount = f.nextCount(it.fetchCount, it.rawQueryParams.count, len(it.result.GetBuf()), time.Since(it.pageAt), it.fetchTime)
+	return it.fetchCount
+}
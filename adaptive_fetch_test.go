@@ -0,0 +1,68 @@
+package reindexer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestAdaptiveFetch(t *testing.T) {
+	db, srv := newMockDB(t, "adaptive", reindexer.WithAdaptiveFetch(reindexer.AdaptiveFetch{MinCount: 2, MaxCount: 50, PageBytes: 4000}))
+	defer db.Close()
+
+	items := make([]interface{}, 0, 40)
+	for i := 0; i < 40; i++ {
+		items = append(items, testItem{ID: i, Name: strings.Repeat("x", 1000)})
+	}
+	srv.SetResults(testNs, items...)
+	srv.SetFetchLimit(10)
+	fetchCounts := func() (counts []int) {
+		for _, call := range srv.CallsOf(mock.MethodFetchResults) {
+			counts = append(counts, call.FetchCount)
+		}
+		return counts
+	}
+
+	// Pages are limited by the size of the items
+	res, err := db.Query(testNs).Exec().FetchAll()
+	require.NoError(t, err)
+	require.Len(t, res, 40)
+	selects := srv.CallsOf(mock.MethodSelectQuery)
+	assert.Equal(t, 50, selects[len(selects)-1].FetchCount)
+	counts := fetchCounts()
+	require.NotEmpty(t, counts)
+	assert.Equal(t, 3, counts[0])
+	for _, count := range counts {
+		assert.True(t, count >= 2 && count <= 3, "fetch counts: %v", counts)
+	}
+
+	// Pages of the slow consumer shrink to the min count
+	srv.Reset()
+	srv.SetResults(testNs, items[:4]...)
+	srv.SetFetchLimit(1)
+	it := db.Query(testNs).Exec()
+	for it.Next() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, it.Error())
+	it.Close()
+	assert.Equal(t, []int{3, 2, 2}, fetchCounts())
+
+	// Fetch count of the query overrides the adaptive count
+	srv.Reset()
+	srv.SetResults(testNs, items...)
+	srv.SetFetchLimit(10)
+	res, err = db.Query(testNs).FetchCount(7).Exec().FetchAll()
+	require.NoError(t, err)
+	require.Len(t, res, 40)
+	assert.Equal(t, 7, srv.CallsOf(mock.MethodSelectQuery)[0].FetchCount)
+	for _, count := range fetchCounts() {
+		assert.Equal(t, 7, count)
+	}
+}
@@ -0,0 +1,143 @@
+package reindexer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Modes of automatic timestamp fields
+const (
+	autotimeCreate = "create"
+	autotimeUpdate = "update"
+)
+
+var autotimeUnits = map[string]bool{
+	"sec":  true,
+	"msec": true,
+	"usec": true,
+	"nsec": true,
+}
+
+// autotimeField describes field, marked by `autotime:create` or `autotime:update` option in `reindex:` tag
+type autotimeField struct {
+	structFieldRef
+	// Either autotimeCreate or autotimeUpdate
+	mode string
+	// Time unit for now() function: sec, msec, usec or nsec
+	unit string
+}
+
+// parseAutotimeOpt parses option in form of `autotime:create[:unit]` or `autotime:update[:unit]`
+func parseAutotimeOpt(opt string) (mode string, unit string, err error) {
+	parts := strings.Split(opt, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "autotime" {
+		return "", "", fmt.Errorf("Invalid autotime option '%s'", opt)
+	}
+	mode = parts[1]
+	if mode != autotimeCreate && mode != autotimeUpdate {
+		return "", "", fmt.Errorf("Invalid autotime mode '%s'. Expected '%s' or '%s'", mode, autotimeCreate, autotimeUpdate)
+	}
+	unit = "sec"
+	if len(parts) == 3 {
+		unit = strings.ToLower(parts[2])
+		if !autotimeUnits[unit] {
+			return "", "", fmt.Errorf("Invalid autotime unit '%s'. Expected one of sec, msec, usec or nsec", parts[2])
+		}
+	}
+	return mode, unit, nil
+}
+
+func parseAutotimeFields(st reflect.Type, fields *[]autotimeField) error {
+	return walkStructFields(st, "", nil, func(sf reflect.StructField, t reflect.Type, reindexPath string, fieldIdx []int, idxSettings []string) error {
+		for _, opt := range idxSettings {
+			if !strings.HasPrefix(opt, "autotime:") {
+				continue
+			}
+			mode, unit, err := parseAutotimeOpt(opt)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(reindexPath) == "" || strings.HasSuffix(reindexPath, ".") {
+				return fmt.Errorf("autotime option requires index on field %s", sf.Name)
+			}
+			switch t.Kind() {
+			case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+			default:
+				return fmt.Errorf("autotime option is allowed only for int, int64, uint and uint64 fields. Field %s has type %s", sf.Name, t.Kind().String())
+			}
+			*fields = append(*fields, autotimeField{structFieldRef: structFieldRef{index: reindexPath, fieldIdx: fieldIdx}, mode: mode, unit: unit})
+		}
+		return nil
+	})
+}
+
+// splitPrecept splits precept in form of `field=expression`
+func splitPrecept(precept string) (field string, expr string, ok bool) {
+	eq := strings.IndexByte(precept, '=')
+	if eq <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(precept[:eq]), strings.TrimSpace(precept[eq+1:]), true
+}
+
+func hasPreceptForField(precepts []string, field string) bool {
+	for _, p := range precepts {
+		if f, _, ok := splitPrecept(p); ok && strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAutotimeFieldZero checks, if autotime field is not set in the item. Items in JSON format are always treated as non-zero
+func isAutotimeFieldZero(item interface{}, f *autotimeField) bool {
+	if item == nil {
+		return false
+	}
+	if isRawItem(item) {
+		return false
+	}
+	v, ok := f.value(item)
+	if !ok {
+		return true
+	}
+	v = reflect.Indirect(v)
+	return !v.IsValid() || v.IsZero()
+}
+
+// appendAutotimePrecepts adds now() precepts for autotime fields of namespace.
+// Insert sets both 'create' and 'update' fields, Update sets 'update' fields only.
+// Upsert sets 'update' fields and 'create' fields, which are not set in the item.
+// Precepts, which were passed explicitly for the same field, have priority
+func (ns *reindexerNamespace) appendAutotimePrecepts(mode int, item interface{}, precepts []string) []string {
+	if len(ns.autotime) == 0 || mode == modeDelete {
+		return precepts
+	}
+	var ret []string
+	for i := range ns.autotime {
+		f := &ns.autotime[i]
+		if f.mode == autotimeCreate {
+			switch mode {
+			case modeUpdate:
+				continue
+			case modeUpsert:
+				if !isAutotimeFieldZero(item, f) {
+					continue
+				}
+			}
+		}
+		if hasPreceptForField(precepts, f.index) || hasPreceptForField(ret, f.index) {
+			continue
+		}
+		if ret == nil {
+			ret = make([]string, 0, len(precepts)+len(ns.autotime))
+			ret = append(ret, precepts...)
+		}
+		ret = append(ret, f.index+"=now("+f.unit+")")
+	}
+	if ret == nil {
+		return precepts
+	}
+	return ret
+}
@@ -0,0 +1,252 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelattr "go.opentelemetry.io/otel/attribute"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+// BatchError is returned by UpsertBatch, if some of the items were not upserted
+type BatchError struct {
+	// Errors of the items in the order of the batch. nil for upserted items
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("rq: %d of %d items are not upserted: %v", failed, len(e.Errors), first)
+}
+
+// upsertBatch upserts the items by one call of the binding, if it supports batches, or one by one otherwise
+func (db *reindexerImpl) upsertBatch(ctx context.Context, namespace string, items []interface{}, precepts ...string) (err error) {
+	namespace = strings.ToLower(namespace)
+
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.UpsertBatch", otelattr.String("rx.ns", namespace))
+		defer span.End()
+	}
+
+	if db.promMetrics != nil {
+		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("UpsertBatch", namespace)).ObserveDuration()
+	}
+
+	defer db.recoverPanic("UpsertBatch", &err)
+
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return err
+	}
+	if err = ns.validatePrecepts(precepts); err != nil {
+		return err
+	}
+
+	errs := make([]error, len(items))
+	batchBinding, ok := db.binding.(bindings.RawBindingModifyItems)
+	// Versioned items are checked by the server one by one (see modifyVersionedItem)
+	if !ok || ns.version != nil {
+		for i, item := range items {
+			_, errs[i] = db.modifyItem(ctx, namespace, ns, item, nil, modeUpsert, precepts...)
+		}
+		return batchError(errs)
+	}
+
+	ctx, cancel := ns.withDefaultDeadline(ctx)
+	defer cancel()
+	defer db.startActivity(ctx, "UpsertBatch", ns.name)()
+
+	history, err := db.beginHistory(ctx, ns)
+	if err != nil {
+		return err
+	}
+	pending := make([]int, len(items))
+	for i := range pending {
+		pending[i] = i
+	}
+	var modified []int
+	for tryCount := 0; tryCount < 2 && len(pending) > 0; tryCount++ {
+		if tryCount > 0 {
+			atomic.AddInt64(&db.counters.stateRetries, 1)
+			db.query(ns.name).Limit(0).ExecCtx(ctx).Close()
+		}
+		var done []int
+		if pending, done, err = db.upsertBatchItems(ctx, ns, batchBinding, items, pending, precepts, errs); err != nil {
+			// Items, which are upserted by the previous try, are written to history
+			break
+		}
+		modified = append(modified, done...)
+	}
+	for _, i := range pending {
+		errs[i] = bindings.NewError("rq: Payload types of the namespace were changed while the item was upserted", ErrCodeStateInvalidated)
+	}
+
+	if len(modified) > 0 {
+		db.queryCache.invalidate(ns.name)
+	}
+	events := make([]TxEvent, 0, len(modified))
+	for _, i := range modified {
+		events = append(events, ns.newTxEvent(items[i], nil, modeUpsert))
+	}
+	historyErr := history.commit(events)
+	if err != nil {
+		return err
+	}
+	if historyErr != nil {
+		return historyErr
+	}
+	return batchError(errs)
+}
+
+// upsertBatchItems sends the pending items of the batch. Returns modified items and items, which must be resent, because the payload types
+// of the namespace were changed. Errors of the other items are set to errs
+func (db *reindexerImpl) upsertBatchItems(ctx context.Context, ns *reindexerNamespace, batchBinding bindings.RawBindingModifyItems, items []interface{},
+	pending []int, precepts []string, errs []error) (retry []int, modified []int, err error) {
+	if err = db.waitRateLimit(ctx, ns.name); err != nil {
+		return nil, nil, err
+	}
+
+	ser := cjson.NewPoolSerializer()
+	defer ser.Close()
+
+	// Items are packed into one buffer, so the data of the items is sliced after packing of all of them
+	batch := make([]bindings.BatchItem, 0, len(pending))
+	batchIdx := make([]int, 0, len(pending))
+	offsets := make([]int, 0, len(pending)+1)
+	offsets = append(offsets, 0)
+	for _, i := range pending {
+		format, stateToken, err := packItem(ns, items[i], nil, ser)
+		if err != nil {
+			errs[i] = err
+			ser.Truncate(offsets[len(offsets)-1])
+			continue
+		}
+		batch = append(batch, bindings.BatchItem{
+			Format:     format,
+			Precepts:   ns.appendAutotimePrecepts(modeUpsert, items[i], precepts),
+			StateToken: stateToken,
+		})
+		batchIdx = append(batchIdx, i)
+		offsets = append(offsets, len(ser.Bytes()))
+	}
+	if len(batch) == 0 {
+		return nil, nil, nil
+	}
+	buf := ser.Bytes()
+	for n := range batch {
+		batch[n].Data = buf[offsets[n]:offsets[n+1]]
+	}
+
+	release, err := db.acquireNsSlot(ctx, ns.name)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, batchErrs := batchBinding.ModifyItems(ctx, ns.nsHash, ns.name, batch, modeUpsert)
+	release()
+
+	for n, i := range batchIdx {
+		if err := batchErrs[n]; err != nil {
+			if rerr, ok := err.(bindings.Error); ok && rerr.Code() == bindings.ErrStateInvalidated {
+				retry = append(retry, i)
+				continue
+			}
+			errs[i] = asUniqueViolation(err)
+			continue
+		}
+		count, err := db.readModifyResult(ns, items[i], results[n], batch[n].Precepts)
+		results[n].Free()
+		errs[i] = err
+		if err == nil && count > 0 {
+			modified = append(modified, i)
+		}
+	}
+	return retry, modified, nil
+}
+
+func batchError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &BatchError{Errors: errs}
+		}
+	}
+	return nil
+}
+
+// execBatch executes the select queries by one call of the binding, if it supports batches. Queries with client side cache or spilling
+// to disk, queries to the namespaces with concurrency limits and queries of other DB instances are executed one by one
+func (db *reindexerImpl) execBatch(ctx context.Context, queries []*Query) []*Iterator {
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.ExecBatch", otelattr.Int("rx.batch.size", len(queries)))
+		defer span.End()
+	}
+
+	iters := make([]*Iterator, len(queries))
+	batchBinding, ok := db.binding.(bindings.RawBindingSelectQueries)
+	batch := make([]bindings.BatchQuery, 0, len(queries))
+	batchQueries := make([]*Query, 0, len(queries))
+	batchIdx := make([]int, 0, len(queries))
+	for i, q := range queries {
+		if q.root != nil {
+			q = q.root
+		}
+		if !ok || !db.isBatchable(q) {
+			iters[i] = q.ExecCtx(ctx)
+			continue
+		}
+		q, err := q.prepareExec(ctx)
+		var data []byte
+		if err == nil {
+			data, err = db.serializeQuery(ctx, q)
+		}
+		if err != nil {
+			iters[i] = errIterator(err)
+			continue
+		}
+		batch = append(batch, bindings.BatchQuery{Data: data, Flags: q.resultsFlags, PtVersions: q.ptVersions, FetchCount: db.queryFetchCount(q)})
+		batchQueries = append(batchQueries, q)
+		batchIdx = append(batchIdx, i)
+	}
+	if len(batch) == 0 {
+		return iters
+	}
+
+	finish := db.startActivity(ctx, "ExecBatch", batchQueries[0].Namespace)
+	results, errs := batchBinding.SelectQueries(ctx, batch)
+	finish()
+	for n, i := range batchIdx {
+		if errs[n] != nil {
+			iters[i] = errIterator(errs[n])
+			continue
+		}
+		q := batchQueries[n]
+		// Default deadline of the namespace limits fetching of the rest of the results
+		qctx, cancel := db.withDefaultDeadline(ctx, q.Namespace)
+		iters[i] = newIterator(qctx, db, q.Namespace, q, results[n], q.nsArray, q.joinToFields, q.joinHandlers, q.context)
+		iters[i].cancel = cancel
+	}
+	return iters
+}
+
+func (db *reindexerImpl) isBatchable(q *Query) bool {
+	if q.db != db || q.spill || (q.cacheTTL > 0 && q.cacheMaxEntries > 0) {
+		return false
+	}
+	_, limited := db.nsLimiters[strings.ToLower(q.Namespace)]
+	return !limited
+}
@@ -0,0 +1,38 @@
+package reindexer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestExecBatch(t *testing.T) {
+	db, srv := newMockDB(t, "batch")
+	defer db.Close()
+
+	srv.SetResults(testNs, testItem{ID: 1, Name: "first"}, testItem{ID: 2, Name: "second"})
+
+	iters := db.ExecBatch(context.Background(), []*reindexer.Query{
+		db.Query(testNs).WhereInt("id", reindexer.EQ, 1),
+		db.Query(testNs).Limit(10),
+		db.Query("missing"),
+	})
+	require.Len(t, iters, 3)
+	for _, it := range iters[:2] {
+		items, err := it.FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+	}
+	assert.Error(t, iters[2].Error())
+	iters[2].Close()
+
+	calls := srv.CallsOf(mock.MethodSelectQueries)
+	require.Len(t, calls, 2)
+	assert.Equal(t, testNs, calls[0].Namespace)
+	assert.Empty(t, srv.CallsOf(mock.MethodSelectQuery))
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	otelattr "go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
 	"github.com/restream/reindexer/v3/bindings"
 	"github.com/restream/reindexer/v3/bindings/builtinserver/config"
@@ -23,6 +25,11 @@ const (
 )
 
 func (db *reindexerImpl) modifyItem(ctx context.Context, namespace string, ns *reindexerNamespace, item interface{}, json []byte, mode int, precepts ...string) (count int, err error) {
+	if db.promMetrics != nil {
+		// Registered before recoverPanic, so recovered panics are counted as errors
+		defer func(start time.Time) { db.promMetrics.observeWrite(namespace, modifyModeNames[mode], start, err) }(time.Now())
+	}
+	defer db.recoverPanic(modifyModeNames[mode], &err)
 
 	if ns == nil {
 		ns, err = db.getNS(namespace)
@@ -31,6 +38,55 @@ func (db *reindexerImpl) modifyItem(ctx context.Context, namespace string, ns *r
 		}
 	}
 
+	ctx, cancel := ns.withDefaultDeadline(ctx)
+	defer cancel()
+	defer db.startActivity(ctx, modifyModeNames[mode], ns.name)()
+
+	if err = ns.validatePrecepts(precepts); err != nil {
+		return 0, err
+	}
+	precepts = ns.appendAutotimePrecepts(mode, item, precepts)
+
+	history, err := db.beginHistory(ctx, ns)
+	if err != nil {
+		return 0, err
+	}
+	if count, err = db.modifyNsItem(ctx, ns, item, json, mode, precepts); err != nil || count == 0 {
+		history.rollback()
+		if err == nil && mode == modeInsert {
+			err = ns.insertViolation(item)
+		}
+		return count, err
+	}
+	db.queryCache.invalidate(ns.name)
+	return count, history.commit([]TxEvent{ns.newTxEvent(item, json, mode)})
+}
+
+func (db *reindexerImpl) modifyNsItem(ctx context.Context, ns *reindexerNamespace, item interface{}, json []byte, mode int, precepts []string) (count int, err error) {
+	if mode == modeDelete && ns.opts.softDeleteField != "" {
+		return db.softDeleteItem(ctx, ns, item, precepts)
+	}
+
+	if ns.version != nil && json == nil && mode != modeDelete {
+		if _, ok := ns.version.get(item); ok {
+			return db.modifyVersionedItem(ctx, ns, item, mode, precepts)
+		}
+	}
+
+	return db.modifyItemImpl(ctx, ns, item, json, mode, precepts)
+}
+
+func (db *reindexerImpl) modifyItemImpl(ctx context.Context, ns *reindexerNamespace, item interface{}, json []byte, mode int, precepts []string) (count int, err error) {
+	db.doWithPprofLabels(ctx, modifyModeNames[mode], ns.name, "", func() {
+		count, err = db.modifyItemLabeled(ctx, ns, item, json, mode, precepts)
+	})
+	return
+}
+
+func (db *reindexerImpl) modifyItemLabeled(ctx context.Context, ns *reindexerNamespace, item interface{}, json []byte, mode int, precepts []string) (count int, err error) {
+	if err = db.waitRateLimit(ctx, ns.name); err != nil {
+		return 0, err
+	}
 	for tryCount := 0; tryCount < 2; tryCount++ {
 		ser := cjson.NewPoolSerializer()
 		defer ser.Close()
@@ -42,43 +98,59 @@ func (db *reindexerImpl) modifyItem(ctx context.Context, namespace string, ns *r
 			return
 		}
 
-		out, err := db.binding.ModifyItem(ctx, ns.nsHash, ns.name, format, ser.Bytes(), mode, precepts, stateToken)
+		release, err := db.acquireNsSlot(ctx, ns.name)
+		if err != nil {
+			return 0, err
+		}
+		out, err := db.interceptCall(ctx, OpInfo{Op: OpModifyItem, Namespace: ns.name, Mode: modifyModeNames[mode]}, func(ctx context.Context) (bindings.RawBuffer, error) {
+			return db.binding.ModifyItem(ctx, ns.nsHash, ns.name, format, ser.Bytes(), mode, precepts, stateToken)
+		})
+		release()
 
 		if err != nil {
 			rerr, ok := err.(bindings.Error)
 			if ok && rerr.Code() == bindings.ErrStateInvalidated {
+				atomic.AddInt64(&db.counters.stateRetries, 1)
 				db.query(ns.name).Limit(0).ExecCtx(ctx).Close()
 				err = rerr
 				continue
 			}
-			return 0, err
+			return 0, asUniqueViolation(err)
+		}
+		if db.promMetrics != nil {
+			db.promMetrics.observeWriteSize(ns.name, modifyModeNames[mode], int64(len(ser.Bytes())))
 		}
 
 		defer out.Free()
+		return db.readModifyResult(ns, item, out, precepts)
+	}
+	return 0, err
+}
 
-		rdSer := newSerializer(out.GetBuf())
-		rawQueryParams := rdSer.readRawQueryParams(func(nsid int) {
-			ns.cjsonState.ReadPayloadType(&rdSer.Serializer, db.binding, ns.name)
-		})
+// readModifyResult reads result of the item's modification: drops the item from the cache and, if precepts are provided,
+// updates the item by its modified version. Returns count of the modified items
+func (db *reindexerImpl) readModifyResult(ns *reindexerNamespace, item interface{}, out bindings.RawBuffer, precepts []string) (int, error) {
+	rdSer := newSerializer(out.GetBuf())
+	rawQueryParams := rdSer.readRawQueryParams(func(nsid int) {
+		ns.cjsonState.ReadPayloadType(&rdSer.Serializer, db.binding, ns.name)
+	})
 
-		if rawQueryParams.count == 0 {
-			return 0, err
-		}
+	if rawQueryParams.count == 0 {
+		return 0, nil
+	}
 
-		resultp := rdSer.readRawtItemParams()
+	resultp := rdSer.readRawtItemParams()
 
-		ns.cacheItems.Remove(resultp.id)
+	ns.cacheItems.Remove(resultp.id)
 
-		if len(precepts) > 0 && (resultp.cptr != 0 || resultp.data != nil) && reflect.TypeOf(item).Kind() == reflect.Ptr {
-			nsArrEntry := nsArrayEntry{ns, ns.cjsonState.Copy()}
-			if _, err := unpackItem(db.binding, &nsArrEntry, &resultp, false, true, item); err != nil {
-				return 0, err
-			}
+	if len(precepts) > 0 && (resultp.cptr != 0 || resultp.data != nil) && reflect.TypeOf(item).Kind() == reflect.Ptr {
+		nsArrEntry := nsArrayEntry{ns, ns.cjsonState.Copy()}
+		if _, err := unpackItem(db.binding, &nsArrEntry, &resultp, false, true, item); err != nil {
+			return 0, err
 		}
-
-		return rawQueryParams.count, err
 	}
-	return 0, err
+
+	return rawQueryParams.count, nil
 }
 
 func packItem(ns *reindexerNamespace, item interface{}, json []byte, ser *cjson.Serializer) (format int, stateToken int, err error) {
@@ -87,7 +159,10 @@ func packItem(ns *reindexerNamespace, item interface{}, json []byte, ser *cjson.
 		json, _ = item.([]byte)
 	}
 
-	if json == nil {
+	if data, ok := item.(msgpackItem); ok {
+		format = bindings.FormatMsgPack
+		ser.Write(data)
+	} else if json == nil {
 		t := reflect.TypeOf(item)
 		if t.Kind() == reflect.Ptr {
 			t = t.Elem()
@@ -111,6 +186,29 @@ func packItem(ns *reindexerNamespace, item interface{}, json []byte, ser *cjson.
 	return
 }
 
+// withDefaultDeadline returns context with namespace's default deadline, if ctx has no deadline.
+// Returned cancel function must be called after the end of the operation
+func (ns *reindexerNamespace) withDefaultDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ns.opts.defaultDeadline <= 0 {
+		return ctx, func() {}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, ns.opts.defaultDeadline)
+}
+
+func (db *reindexerImpl) withDefaultDeadline(ctx context.Context, namespace string) (context.Context, context.CancelFunc) {
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return ctx, func() {}
+	}
+	return ns.withDefaultDeadline(ctx)
+}
+
 func (db *reindexerImpl) getNS(namespace string) (*reindexerNamespace, error) {
 	db.lock.RLock()
 	defer db.lock.RUnlock()
@@ -124,16 +222,24 @@ func (db *reindexerImpl) getNS(namespace string) (*reindexerNamespace, error) {
 }
 
 func unpackItem(bin bindings.RawBinding, ns *nsArrayEntry, params *rawResultItemParams, allowUnsafe bool, nonCacheableData bool, item interface{}) (interface{}, error) {
+	if params.msgpack {
+		// Items in msgpack format are returned as is (see Iterator.MsgPack)
+		return append([]byte(nil), params.data...), nil
+	}
+
 	useCache := item == nil && (ns.deepCopyIface || allowUnsafe) && !nonCacheableData
 	needCopy := ns.deepCopyIface && !allowUnsafe
 	var err error
 
 	if useCache && ns.cacheItems != nil {
 		if citem, ok := ns.cacheItems.Get(params.id); ok && citem.version == params.version {
+			atomic.AddInt64(&ns.cacheItems.hits, 1)
 			item = citem.item
 		} else {
+			atomic.AddInt64(&ns.cacheItems.misses, 1)
 			item = reflect.New(ns.rtype).Interface()
 			dec := ns.localCjsonState.NewDecoder(item, bin)
+			start := time.Now()
 			if params.cptr != 0 {
 				err = dec.DecodeCPtr(params.cptr, item)
 			} else if params.data != nil {
@@ -141,6 +247,7 @@ func unpackItem(bin bindings.RawBinding, ns *nsArrayEntry, params *rawResultItem
 			} else {
 				panic(fmt.Errorf("Internal error while decoding item id %d from ns %s: cptr and data are both null", params.id, ns.name))
 			}
+			ns.cacheItems.decoded(start)
 			if err != nil {
 				return item, err
 			}
@@ -149,17 +256,18 @@ func unpackItem(bin bindings.RawBinding, ns *nsArrayEntry, params *rawResultItem
 				if citem.version == params.version {
 					item = citem.item
 				} else if citem.version < params.version {
-					ns.cacheItems.Add(params.id, &cacheItem{item: item, version: params.version})
+					ns.cacheItems.Add(params.id, &cacheItem{item: item, version: params.version, size: estimateCacheItemSize(ns, params)})
 				}
 			} else {
-				ns.cacheItems.Add(params.id, &cacheItem{item: item, version: params.version})
+				ns.cacheItems.Add(params.id, &cacheItem{item: item, version: params.version, size: estimateCacheItemSize(ns, params)})
 			}
 		}
 	} else {
 		if item == nil {
-			item = reflect.New(ns.rtype).Interface()
+			item = ns.newItem()
 		}
 		dec := ns.localCjsonState.NewDecoder(item, bin)
+		start := time.Now()
 		if params.cptr != 0 {
 			err = dec.DecodeCPtr(params.cptr, item)
 		} else if params.data != nil {
@@ -167,6 +275,9 @@ func unpackItem(bin bindings.RawBinding, ns *nsArrayEntry, params *rawResultItem
 		} else {
 			panic(fmt.Errorf("Internal error while decoding item id %d from ns %s: cptr and data are both null", params.id, ns.name))
 		}
+		if ns.cacheItems != nil {
+			ns.cacheItems.decoded(start)
+		}
 		if err != nil {
 			return item, err
 		}
@@ -185,13 +296,15 @@ func unpackItem(bin bindings.RawBinding, ns *nsArrayEntry, params *rawResultItem
 	return item, err
 }
 
-func (db *reindexerImpl) rawResultToJson(rawResult []byte, jsonName string, totalName string, initJson []byte, initOffsets []int) (json []byte, offsets []int, explain []byte, err error) {
+// rawResultToJson builds ExecToJson output from the results. joins is nil for the queries without joined queries and for SQL queries:
+// joined items are embedded into the item's JSON by the server as 'joined_<namespace>' fields then
+func (db *reindexerImpl) rawResultToJson(rawResult []byte, jsonName string, totalName string, aggsName string, joins *jsonJoins, initJson []byte, initOffsets []int) (json []byte, offsets []int, explain []byte, aggs [][]byte, err error) {
 
 	ser := newSerializer(rawResult)
 	rawQueryParams := ser.readRawQueryParams()
 	explain = rawQueryParams.explainResults
 
-	jsonReserveLen := len(rawResult) + len(totalName) + len(jsonName) + 20
+	jsonReserveLen := len(rawResult) + len(totalName) + len(aggsName) + len(jsonName) + 20
 	if cap(initJson) < jsonReserveLen {
 		initJson = make([]byte, 0, jsonReserveLen)
 	} else {
@@ -214,6 +327,24 @@ func (db *reindexerImpl) rawResultToJson(rawResult []byte, jsonName string, tota
 		jsonBuf.WriteString(",\"")
 	}
 
+	if len(rawQueryParams.aggResults) != 0 {
+		aggs = make([][]byte, 0, len(rawQueryParams.aggResults))
+		for _, agg := range rawQueryParams.aggResults {
+			aggs = append(aggs, append([]byte(nil), agg...))
+		}
+		if len(aggsName) != 0 {
+			jsonBuf.WriteString(aggsName)
+			jsonBuf.WriteString("\":[")
+			for i, agg := range rawQueryParams.aggResults {
+				if i != 0 {
+					jsonBuf.WriteString(",")
+				}
+				jsonBuf.Write(agg)
+			}
+			jsonBuf.WriteString("],\"")
+		}
+	}
+
 	jsonBuf.WriteString(jsonName)
 	jsonBuf.WriteString("\":[")
 
@@ -223,31 +354,44 @@ func (db *reindexerImpl) rawResultToJson(rawResult []byte, jsonName string, tota
 			jsonBuf.WriteString(",")
 		}
 		offsets = append(offsets, len(jsonBuf.Bytes()))
-		jsonBuf.Write(item.data)
 
-		if (rawQueryParams.flags&bindings.ResultsWithJoined) != 0 && ser.GetVarUInt() != 0 {
-			panic("Sorry, not implemented: Can't return join query results as json")
+		subNSRes := 0
+		if (rawQueryParams.flags & bindings.ResultsWithJoined) != 0 {
+			subNSRes = int(ser.GetVarUInt())
+		}
+		if joins != nil {
+			if err = joins.writeItem(&jsonBuf, item.data, item.nsid, &ser, subNSRes); err != nil {
+				return nil, nil, nil, nil, err
+			}
+			continue
+		}
+		jsonBuf.Write(item.data)
+		// Joined items are embedded into the item's JSON by the server
+		for nsIndex := 0; nsIndex < subNSRes; nsIndex++ {
+			siRes := int(ser.GetVarUInt())
+			for si := 0; si < siRes; si++ {
+				ser.readRawtItemParams()
+			}
 		}
 	}
 	jsonBuf.WriteString("]}")
 
-	return jsonBuf.Bytes(), offsets, explain, nil
+	return jsonBuf.Bytes(), offsets, explain, aggs, nil
 }
 
-func (db *reindexerImpl) prepareQuery(ctx context.Context, q *Query, asJson bool) (result bindings.RawBuffer, err error) {
-
+// fillNsArray adds namespaces of the query, of the merged and of the joined queries to the query's nsArray
+func (db *reindexerImpl) fillNsArray(q *Query) error {
 	if ns, err := db.getNS(q.Namespace); err == nil {
 		q.nsArray = append(q.nsArray, nsArrayEntry{ns, ns.cjsonState.Copy()})
 	} else {
-		return nil, err
+		return err
 	}
 
-	ser := q.ser
 	for _, sq := range q.mergedQueries {
 		if ns, err := db.getNS(sq.Namespace); err == nil {
 			q.nsArray = append(q.nsArray, nsArrayEntry{ns, ns.cjsonState.Copy()})
 		} else {
-			return nil, err
+			return err
 		}
 	}
 
@@ -255,7 +399,7 @@ func (db *reindexerImpl) prepareQuery(ctx context.Context, q *Query, asJson bool
 		if ns, err := db.getNS(sq.Namespace); err == nil {
 			q.nsArray = append(q.nsArray, nsArrayEntry{ns, ns.cjsonState.Copy()})
 		} else {
-			return nil, err
+			return err
 		}
 	}
 
@@ -264,23 +408,86 @@ func (db *reindexerImpl) prepareQuery(ctx context.Context, q *Query, asJson bool
 			if ns, err := db.getNS(sq.Namespace); err == nil {
 				q.nsArray = append(q.nsArray, nsArrayEntry{ns, ns.cjsonState.Copy()})
 			} else {
-				return nil, err
+				return err
 			}
 		}
 	}
+	return nil
+}
 
+func (db *reindexerImpl) prepareQuery(ctx context.Context, q *Query, asJson bool) (result bindings.RawBuffer, err error) {
+	data, err := db.serializeQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	fetchCount := db.queryFetchCount(q)
+	if asJson {
+		// json iterator not support fetch queries
+		fetchCount = -1
+	}
+	release, err := db.acquireNsSlot(ctx, q.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	resultsFlags := q.resultsFlags
+	start := time.Now()
+	result, err = db.interceptCall(ctx, db.queryOpInfo(OpSelect, q), func(ctx context.Context) (bindings.RawBuffer, error) {
+		if flagsBinding, ok := db.binding.(bindings.RawBindingResultsFlags); ok && resultsFlags != 0 && !asJson {
+			return flagsBinding.SelectQueryWithFlags(ctx, data, resultsFlags, q.ptVersions, fetchCount)
+		}
+		// Bindings without results flags return items in JSON format for raw JSON iteration (see Query.RawJSON)
+		return db.binding.SelectQuery(ctx, data, asJson || resultsFlags&bindings.ResultsFormatMask == bindings.ResultsJson, q.ptVersions, fetchCount)
+	})
+	release()
+	if err != nil {
+		db.logQueryError(q.Namespace, func() string {
+			sql, serr := q.SQL()
+			if serr != nil {
+				return "<" + serr.Error() + ">"
+			}
+			return sql
+		}, time.Since(start), err)
+	}
+
+	if err == nil && result.GetBuf() == nil {
+		panic(fmt.Errorf("result.Buffer is nil"))
+	}
+	return
+}
+
+// serializeQuery fills namespaces of the query and serializes it with joined and merged queries to be sent to the binding
+func (db *reindexerImpl) serializeQuery(ctx context.Context, q *Query) ([]byte, error) {
+	if err := q.validateResultsFlags(); err != nil {
+		return nil, err
+	}
+	if err := db.waitRateLimit(ctx, q.Namespace); err != nil {
+		return nil, err
+	}
+
+	if err := db.fillNsArray(q); err != nil {
+		return nil, err
+	}
+	q.addSortPkTiebreaker(q.nsArray[0].reindexerNamespace)
+
+	// Filters must be added before the copy of the serializer
+	q.addDefaultFilters(ctx)
+	q.addSoftDeleteFilter()
+	ser := q.ser
 	ser.PutVarCUInt(queryEnd)
 	for _, sq := range q.joinQueries {
+		sq.addSoftDeleteFilter()
 		ser.PutVarCUInt(sq.joinType)
 		ser.Append(sq.ser)
 		ser.PutVarCUInt(queryEnd)
 	}
 
 	for _, mq := range q.mergedQueries {
+		mq.addSoftDeleteFilter()
 		ser.PutVarCUInt(merge)
 		ser.Append(mq.ser)
 		ser.PutVarCUInt(queryEnd)
 		for _, sq := range mq.joinQueries {
+			sq.addSoftDeleteFilter()
 			ser.PutVarCUInt(sq.joinType)
 			ser.Append(sq.ser)
 			ser.PutVarCUInt(queryEnd)
@@ -290,82 +497,181 @@ func (db *reindexerImpl) prepareQuery(ctx context.Context, q *Query, asJson bool
 	for _, ns := range q.nsArray {
 		q.ptVersions = append(q.ptVersions, ns.localCjsonState.Version^ns.localCjsonState.StateToken)
 	}
-	fetchCount := q.fetchCount
-	if asJson {
-		// json iterator not support fetch queries
-		fetchCount = -1
-	}
-	result, err = db.binding.SelectQuery(ctx, ser.Bytes(), asJson, q.ptVersions, fetchCount)
-
-	if err == nil && result.GetBuf() == nil {
-		panic(fmt.Errorf("result.Buffer is nil"))
-	}
-	return
+	return ser.Bytes(), nil
 }
 
 // Execute query
-func (db *reindexerImpl) execQuery(ctx context.Context, q *Query) *Iterator {
+func (db *reindexerImpl) execQuery(ctx context.Context, q *Query) (iter *Iterator) {
+	defer db.recoverPanicIterator("Query.Exec", &iter)
+	var span *tracingSpan
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Query.Exec", otelattr.String("rx.ns", q.Namespace)).End()
+		ctx, span = db.startQuerySpan(ctx, "Reindexer.Query.Exec", q)
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("Query.Exec", q.Namespace)).ObserveDuration()
 	}
 
-	result, err := db.prepareQuery(ctx, q, false)
+	ctx, cancel := db.withDefaultDeadline(ctx, q.Namespace)
+	defer db.startActivity(ctx, "Query.Exec", q.Namespace)()
+	var result bindings.RawBuffer
+	var err error
+	cacheKey := ""
+	var cacheGeneration uint64
+	if q.cacheTTL > 0 && q.cacheMaxEntries > 0 {
+		// Default filters may depend on the context, so they are the part of the key
+		q.addDefaultFilters(ctx)
+		cacheKey = q.cacheKey()
+		db.watchQueryCache(q.cacheNamespaces())
+		if result, cacheGeneration = db.queryCache.get(cacheKey); result != nil {
+			if err = db.fillNsArray(q); err != nil {
+				result.Free()
+				cancel()
+				return errIterator(err)
+			}
+			iter = newIterator(ctx, q.db, q.Namespace, q, result, q.nsArray, q.joinToFields, q.joinHandlers, q.context)
+			iter.cancel = cancel
+			return iter
+		}
+	}
+	db.doWithPprofLabels(ctx, "Query.Exec", q.Namespace, q.label, func() {
+		result, err = db.prepareQuery(ctx, q, false)
+	})
+	if err == nil && cacheKey != "" {
+		result, err = db.queryCache.put(ctx, q, cacheKey, cacheGeneration, result)
+	} else if err == nil && q.spill {
+		result, err = db.spillResult(ctx, q.spillDir, q.fetchCount, result)
+	}
 	if err != nil {
+		cancel()
 		return errIterator(err)
 	}
-	iter := newIterator(ctx, q.db, q.Namespace, q, result, q.nsArray, q.joinToFields, q.joinHandlers, q.context)
+	iter = newIterator(ctx, q.db, q.Namespace, q, result, q.nsArray, q.joinToFields, q.joinHandlers, q.context)
+	iter.cancel = cancel
+	iter.verifier = db.newResultVerifier(q)
+	if span != nil {
+		span.SetAttributes(otelattr.Int("rx.items.count", iter.rawQueryParams.qcount))
+	}
 	return iter
 }
 
 func (db *reindexerImpl) execToJsonQuery(ctx context.Context, q *Query, jsonRoot string) *JSONIterator {
+	var span *tracingSpan
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Query.ExecToJson", otelattr.String("rx.ns", q.Namespace)).End()
+		ctx, span = db.startQuerySpan(ctx, "Reindexer.Query.ExecToJson", q)
+		defer span.End()
 	}
 
 	if q.db.promMetrics != nil {
 		defer prometheus.NewTimer(q.db.promMetrics.clientCallsLatency.WithLabelValues("Query.ExecToJson", q.Namespace)).ObserveDuration()
 	}
 
-	result, err := db.prepareQuery(ctx, q, true)
-	if err != nil {
-		return errJSONIterator(err)
-	}
-	defer result.Free()
+	ctx, cancel := db.withDefaultDeadline(ctx, q.Namespace)
+	defer cancel()
+	defer db.startActivity(ctx, "Query.ExecToJson", q.Namespace)()
+
 	var explain []byte
-	q.json, q.jsonOffsets, explain, err = db.rawResultToJson(result.GetBuf(), jsonRoot, q.totalName, q.json, q.jsonOffsets)
+	var aggs [][]byte
+	var err error
+	db.doWithPprofLabels(ctx, "Query.ExecToJson", q.Namespace, q.label, func() {
+		defer db.recoverPanic("Query.ExecToJson", &err)
+		var result bindings.RawBuffer
+		if result, err = db.prepareQuery(ctx, q, true); err != nil {
+			return
+		}
+		defer result.Free()
+		var received int64
+		if err = checkResultSize(q, &received, len(result.GetBuf())); err != nil {
+			return
+		}
+		q.json, q.jsonOffsets, explain, aggs, err = db.rawResultToJson(result.GetBuf(), jsonRoot, q.totalName, q.aggsName, newJSONJoins(q), q.json, q.jsonOffsets)
+	})
 	if err != nil {
 		return errJSONIterator(err)
 	}
-	return newJSONIterator(ctx, q, q.json, q.jsonOffsets, explain)
+	ji := newJSONIterator(ctx, q, q.json, q.jsonOffsets, explain, aggs)
+	if span != nil {
+		span.SetAttributes(otelattr.Int("rx.items.count", len(q.jsonOffsets)))
+	}
+	if db.memBudget != nil {
+		ji.chargeBudget(db.memBudget)
+	}
+	return ji
 }
 
-func (db *reindexerImpl) prepareSQL(ctx context.Context, namespace, query string, asJson bool) (result bindings.RawBuffer, nsArray []nsArrayEntry, err error) {
+func (db *reindexerImpl) prepareSQL(ctx context.Context, namespace string, namespaces sqlNamespaces, query string, asJson bool) (result bindings.RawBuffer, nsArray []nsArrayEntry, err error) {
 	nsArray = make([]nsArrayEntry, 0, 3)
 	var ns *reindexerNamespace
 
 	if ns, err = db.getNS(namespace); err != nil {
 		return
 	}
+	if err = db.waitRateLimit(ctx, namespace); err != nil {
+		return
+	}
 
 	nsArray = append(nsArray, nsArrayEntry{ns, ns.cjsonState.Copy()})
 
+	// Namespaces of merged and joined queries are resolved best-effort: if some of them are not opened by the client,
+	// items of the main namespace are decoded only
+	names := append(append(namespaces.merged[:len(namespaces.merged):len(namespaces.merged)], namespaces.joined...), namespaces.mergedJoined...)
+	for _, name := range names {
+		sqlNs, nsErr := db.getNS(name)
+		if nsErr != nil {
+			nsArray = nsArray[:1]
+			break
+		}
+		nsArray = append(nsArray, nsArrayEntry{sqlNs, sqlNs.cjsonState.Copy()})
+	}
+
 	ptVersions := make([]int32, 0, 16)
 	for _, ns := range nsArray {
 		ptVersions = append(ptVersions, ns.localCjsonState.Version^ns.localCjsonState.StateToken)
 	}
 
-	result, err = db.binding.Select(ctx, query, asJson, ptVersions, defaultFetchCount)
+	release, err := db.acquireNsSlot(ctx, namespace)
+	if err != nil {
+		return
+	}
+	start := time.Now()
+	result, err = db.interceptCall(ctx, OpInfo{Op: OpSelect, Namespace: namespace, SQL: query}, func(ctx context.Context) (bindings.RawBuffer, error) {
+		return db.binding.Select(ctx, query, asJson, ptVersions, defaultFetchCount)
+	})
+	release()
+	if err != nil {
+		db.logQueryError(namespace, func() string { return query }, time.Since(start), err)
+	}
 	return
 }
 
+// logQueryError logs failed query to the binding's logger with the query's text, duration and error code,
+// and counts it by Prometheus metrics. Text of the query is built only if the logger writes the records of ERROR level
+func (db *reindexerImpl) logQueryError(namespace string, query func() string, duration time.Duration, err error) {
+	if db.promMetrics != nil {
+		db.promMetrics.observeError("Select", namespace, err)
+	}
+	logger := db.binding.GetLogger()
+	if !bindings.LogEnabled(logger, ERROR) {
+		return
+	}
+	bindings.Log(logger, ERROR, "rq: query failed", bindings.ErrorFields(err,
+		bindings.LogField{Key: bindings.LogFieldNamespace, Value: namespace},
+		bindings.LogField{Key: bindings.LogFieldQuery, Value: query()},
+		bindings.LogField{Key: bindings.LogFieldDuration, Value: duration},
+	)...)
+}
+
 // Execute query
-func (db *reindexerImpl) deleteQuery(ctx context.Context, q *Query) (int, error) {
+func (db *reindexerImpl) deleteQuery(ctx context.Context, q *Query) (count int, err error) {
+	defer db.recoverPanic("Query.Delete", &err)
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Query.Delete", otelattr.String("rx.ns", q.Namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startQuerySpan(ctx, "Reindexer.Query.Delete", q)
+		defer func() {
+			span.SetAttributes(otelattr.Int("rx.items.count", count))
+			span.End()
+		}()
 	}
 
 	if db.promMetrics != nil {
@@ -377,10 +683,39 @@ func (db *reindexerImpl) deleteQuery(ctx context.Context, q *Query) (int, error)
 		return 0, err
 	}
 
-	result, err := db.binding.DeleteQuery(ctx, ns.nsHash, q.ser.Bytes())
+	ctx, cancel := ns.withDefaultDeadline(ctx)
+	defer cancel()
+	defer db.startActivity(ctx, "Query.Delete", ns.name)()
+
+	if ns.opts.softDeleteField != "" && !q.withDeleted {
+		return db.softDeleteQuery(ctx, q)
+	}
+	if ns.opts.history && !q.withoutHistory {
+		// Deleted items are written to history, so they are selected first
+		objects, err := db.deleteReturningObjects(ctx, ns, q)
+		return len(objects), err
+	}
+
+	if err = db.waitRateLimit(ctx, ns.name); err != nil {
+		return 0, err
+	}
+
+	q.addDefaultFilters(ctx)
+	release, err := db.acquireNsSlot(ctx, ns.name)
+	if err != nil {
+		return 0, err
+	}
+	result, err := db.interceptCall(ctx, db.queryOpInfo(OpDeleteQuery, q), func(ctx context.Context) (bindings.RawBuffer, error) {
+		return db.binding.DeleteQuery(ctx, ns.nsHash, q.ser.Bytes())
+	})
+	release()
 	if err != nil {
+		if db.promMetrics != nil {
+			db.promMetrics.observeError("Query.Delete", q.Namespace, err)
+		}
 		return 0, err
 	}
+	db.queryCache.invalidate(ns.name)
 	defer result.Free()
 
 	ser := newSerializer(result.GetBuf())
@@ -404,8 +739,10 @@ func (db *reindexerImpl) deleteQuery(ctx context.Context, q *Query) (int, error)
 
 // Execute query
 func (db *reindexerImpl) updateQuery(ctx context.Context, q *Query) *Iterator {
+	var span *tracingSpan
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Query.Update", otelattr.String("rx.ns", q.Namespace)).End()
+		ctx, span = db.startQuerySpan(ctx, "Reindexer.Query.Update", q)
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -417,10 +754,37 @@ func (db *reindexerImpl) updateQuery(ctx context.Context, q *Query) *Iterator {
 		return errIterator(err)
 	}
 
-	result, err := db.binding.UpdateQuery(ctx, ns.nsHash, q.ser.Bytes())
+	ctx, cancel := ns.withDefaultDeadline(ctx)
+	defer cancel()
+	defer db.startActivity(ctx, "Query.Update", ns.name)()
+
+	if err = db.waitRateLimit(ctx, ns.name); err != nil {
+		return errIterator(err)
+	}
+
+	q.addDefaultFilters(ctx)
+	q.addSoftDeleteFilter()
+	history, err := db.beginHistory(ctx, ns)
+	if err != nil {
+		return errIterator(err)
+	}
+	release, err := db.acquireNsSlot(ctx, ns.name)
 	if err != nil {
+		history.rollback()
 		return errIterator(err)
 	}
+	result, err := db.interceptCall(ctx, db.queryOpInfo(OpUpdateQuery, q), func(ctx context.Context) (bindings.RawBuffer, error) {
+		return db.binding.UpdateQuery(ctx, ns.nsHash, q.ser.Bytes())
+	})
+	release()
+	if err != nil {
+		history.rollback()
+		if db.promMetrics != nil {
+			db.promMetrics.observeError("Query.Update", q.Namespace, err)
+		}
+		return errIterator(asUniqueViolation(err))
+	}
+	db.queryCache.invalidate(ns.name)
 
 	ser := newSerializer(result.GetBuf())
 	// skip total count
@@ -440,36 +804,57 @@ func (db *reindexerImpl) updateQuery(ctx context.Context, q *Query) *Iterator {
 	if !ser.Eof() {
 		panic("Internal error: data after end of update query result")
 	}
+	if span != nil {
+		span.SetAttributes(otelattr.Int("rx.items.count", rawQueryParams.count))
+	}
 
 	q.nsArray = append(q.nsArray, nsArrayEntry{ns, ns.cjsonState.Copy()})
-	return newIterator(ctx, q.db, q.Namespace, q, result, q.nsArray, nil, nil, nil)
+	it := newIterator(ctx, q.db, q.Namespace, q, result, q.nsArray, nil, nil, nil)
+	if history != nil {
+		return history.commitUpdated(ctx, it)
+	}
+	return it
 }
 
 // Execute query
 func (db *reindexerImpl) updateQueryTx(ctx context.Context, q *Query, tx *Tx) *Iterator {
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Tx.Query.Update", otelattr.String("rx.ns", q.Namespace)).End()
+		_, span := db.startTracingSpan(ctx, "Reindexer.Tx.Query.Update", otelattr.String("rx.ns", q.Namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("Tx.Query.Update", q.Namespace)).ObserveDuration()
 	}
 
+	if tx.history != nil {
+		return errIterator(errHistoryTxQuery)
+	}
 	err := db.binding.UpdateQueryTx(&tx.ctx, q.ser.Bytes())
+	if err == nil {
+		tx.journalQuery(q.ser.Bytes(), true)
+	}
 	return errIterator(err)
 }
 
 // Execute query
 func (db *reindexerImpl) deleteQueryTx(ctx context.Context, q *Query, tx *Tx) (int, error) {
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Tx.Query.Delete", otelattr.String("rx.ns", q.Namespace)).End()
+		_, span := db.startTracingSpan(ctx, "Reindexer.Tx.Query.Delete", otelattr.String("rx.ns", q.Namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("Tx.Query.Delete", q.Namespace)).ObserveDuration()
 	}
 
+	if tx.history != nil {
+		return 0, errHistoryTxQuery
+	}
 	err := db.binding.DeleteQueryTx(&tx.ctx, q.ser.Bytes())
+	if err == nil {
+		tx.journalQuery(q.ser.Bytes(), false)
+	}
 	return 0, err
 }
 
@@ -484,13 +869,19 @@ func (db *reindexerImpl) resetCachesCtx(ctx context.Context) {
 		nsArray = append(nsArray, ns)
 	}
 	db.lock.RUnlock()
+	db.queryCache.reset()
 	for _, ns := range nsArray {
-		ns.cacheItems.Reset()
-		ns.cjsonState.Reset()
-		db.query(ns.name).Limit(0).ExecCtx(ctx).Close()
+		db.resetNamespaceCaches(ctx, ns)
 	}
 }
 
+// resetNamespaceCaches drops decoded items and payload types of the namespace, and requests the actual payload types from the server
+func (db *reindexerImpl) resetNamespaceCaches(ctx context.Context, ns *reindexerNamespace) {
+	ns.cacheItems.Reset()
+	ns.cjsonState.Reset()
+	db.query(ns.name).Limit(0).ExecCtx(ctx).Close()
+}
+
 func (db *reindexerImpl) resetCaches() {
 	db.resetCachesCtx(context.Background())
 }
@@ -511,6 +902,27 @@ func WithRetryAttempts(read int, write int) interface{} {
 	return bindings.OptionRetryAttempts{Read: read, Write: write}
 }
 
+// WithHedgedReads enables hedging of read queries (cproto only): if the query is not completed within the delay,
+// the same query is sent once more (via connection, chosen by the pool's load balancing algorithm) and the first
+// successful response is used. Response of the other request is freed. Modifying queries and transactions are never hedged
+func WithHedgedReads(delay time.Duration) interface{} {
+	return bindings.OptionHedgedReads{Delay: delay}
+}
+
+// WithReadReplicas sets DSNs of the read-only replicas of the database (cproto only). Select queries are load-balanced
+// between the healthy replicas, while items modifications, update/delete queries, transactions and the other calls are sent
+// to the leader (the DB's DSN). Replicas are checked periodically (see WithReplicaHealthCheck): unavailable replicas are
+// skipped until they recover, and selects are sent to the leader, when there are no healthy replicas
+func WithReadReplicas(dsns ...string) interface{} {
+	_, dsnParsed := dsnParse(dsns)
+	return bindings.OptionReadReplicas{DSNs: dsnParsed}
+}
+
+// WithReplicaHealthCheck sets interval of the health checks of the read replicas (5 seconds by default)
+func WithReplicaHealthCheck(interval time.Duration) interface{} {
+	return bindings.OptionReplicaHealthCheck{Interval: interval}
+}
+
 func WithServerConfig(startupTimeout time.Duration, serverConfig *config.ServerConfig) interface{} {
 	return bindings.OptionBuiltinWithServer{ServerConfig: serverConfig, StartupTimeout: startupTimeout}
 }
@@ -535,10 +947,140 @@ func WithAppName(appName string) interface{} {
 	return bindings.OptionAppName{AppName: appName}
 }
 
-func WithPrometheusMetrics() interface{} {
-	return bindings.OptionPrometheusMetrics{EnablePrometheusMetrics: true}
+// WithPrometheusMetrics enables client side Prometheus metrics. Optional opts allow to set custom registerer,
+// prefix, constant labels and latency buckets of the metrics (only the first value is used)
+func WithPrometheusMetrics(opts ...PrometheusMetricsOptions) interface{} {
+	opt := bindings.OptionPrometheusMetrics{EnablePrometheusMetrics: true}
+	if len(opts) > 0 {
+		opt.Registerer = opts[0].Registerer
+		opt.Prefix = opts[0].Prefix
+		opt.ConstLabels = opts[0].ConstLabels
+		opt.Buckets = opts[0].Buckets
+	}
+	return opt
 }
 
 func WithOpenTelemetry() interface{} {
 	return bindings.OptionOpenTelemetry{EnableTracing: true}
 }
+
+// WithOpenTelemetryTracerProvider enables OpenTelemetry tracing of the client side calls (see WithOpenTelemetry) with the tracer of tp
+// and the options, which customize names of the spans, add SQL of the queries to the spans and trace only slow operations
+func WithOpenTelemetryTracerProvider(tp oteltrace.TracerProvider, opts ...TracingOption) interface{} {
+	opt := bindings.OptionOpenTelemetry{EnableTracing: true, TracerProvider: tp}
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// WithPprofLabels enables pprof labels for client side calls, so CPU profiles show namespaces, operations
+// and query labels (see Query.Label), which spend time on serialization and decoding of items
+func WithPprofLabels() interface{} {
+	return bindings.OptionPprofLabels{EnablePprofLabels: true}
+}
+
+// WithQueryValidation enables validation of the fields, referenced by Where, Sort and Set of the queries, against the structs
+// and indexes of the namespaces: the query returns QueryValidationError without sending to the server, if the field doesn't exist
+// or type of its values doesn't match. Fields, which are not described by the struct (e.g. added by JSON items), are reported too
+func WithQueryValidation() interface{} {
+	return bindings.OptionQueryValidation{EnableQueryValidation: true}
+}
+
+// WithAllowUnsafe sets default of Iterator.AllowUnsafe for the queries of the DB. It may be overridden for the query by Query.AllowUnsafe
+// or for the results by Iterator.AllowUnsafe. Shared objects of the cache MUST NOT be modified by the application
+func WithAllowUnsafe(allow bool) interface{} {
+	return bindings.OptionAllowUnsafe{AllowUnsafe: allow}
+}
+
+// WithRateLimit limits rate of client side calls (queries, items modifications, etc) to opsPerSecond on average
+// with bursts up to burst calls. Calls wait for the limiter, until it allows them, or context is done
+func WithRateLimit(opsPerSecond float64, burst int) interface{} {
+	return bindings.OptionRateLimit{OpsPerSecond: opsPerSecond, Burst: burst}
+}
+
+// WithNamespaceRateLimit limits rate of client side calls of the namespace. It's applied in addition to WithRateLimit
+func WithNamespaceRateLimit(namespace string, opsPerSecond float64, burst int) interface{} {
+	return bindings.OptionRateLimit{Namespace: namespace, OpsPerSecond: opsPerSecond, Burst: burst}
+}
+
+// WithMaxConcurrentQueries bounds count of simultaneous calls (queries, items modifications, etc) of the namespace to n.
+// Calls over the limit wait for a free slot, or context is done
+func WithMaxConcurrentQueries(namespace string, n int) interface{} {
+	return bindings.OptionMaxConcurrentQueries{Namespace: namespace, Count: n}
+}
+
+// WithMemoryBudget limits memory (in bytes), which is used by object caches of all the namespaces and by JSON buffers
+// of not closed JSON iterators. When usage exceeds the budget, items are evicted from the caches in proportion to
+// memory, used by each cache
+func WithMemoryBudget(bytes int64) interface{} {
+	return bindings.OptionMemoryBudget{Bytes: bytes}
+}
+
+// WithItemCacheConfig sets limits of the object cache of the namespace and its eviction policy (CacheLRU or CacheARC).
+// maxItems overrides NamespaceOptions.ObjCacheSize, if it's not zero. Size of the items in bytes is estimated and is not limited, if maxBytes is zero
+func WithItemCacheConfig(namespace string, maxItems int, maxBytes int64, policy CachePolicy) interface{} {
+	return bindings.OptionItemCache{Namespace: namespace, MaxItems: maxItems, MaxBytes: maxBytes, Policy: int(policy)}
+}
+
+// WithItemCacheStore replaces built-in store of the object cache of the namespace by the store, created by newStore.
+// Limits of WithItemCacheConfig (except the policy) are applied to the store too
+func WithItemCacheStore(namespace string, newStore CacheStoreFactory) interface{} {
+	return bindings.OptionItemCache{Namespace: namespace, NewStore: newStore}
+}
+
+// WithItemCacheShards splits the object cache of the namespace into shards, which are selected by hash of item id and have own locks,
+// to reduce lock contention of the concurrent decoding of the results. Limit of the items is divided between the shards, so eviction
+// order is approximate. The store of WithItemCacheStore is created for each shard. The cache is not sharded by default
+func WithItemCacheShards(namespace string, shards int) interface{} {
+	return bindings.OptionItemCache{Namespace: namespace, Shards: shards}
+}
+
+// WithItemPool enables reuse of the structs of the namespace for decoding of the items, which are not taken from the object cache:
+// structs are created by newFn and are returned to the pool by Reindexer.ReleaseItem, after resetFn clears them. newFn must return
+// pointer to the struct of the namespace. Structs are zeroed, if resetFn is nil, so resetFn may be used to keep capacity of the slices
+func WithItemPool(namespace string, newFn func() interface{}, resetFn func(item interface{})) interface{} {
+	return bindings.OptionItemPool{Namespace: namespace, New: newFn, Reset: resetFn}
+}
+
+// WithTxAsyncWindow bounds count and size of the items of each transaction, which are sent by async methods (e.g. Tx.UpsertAsync)
+// and are waiting for the responses. By default count of the items is limited by 500 and size is not limited. When the window is full,
+// async methods wait for the responses, or return ErrTxAsyncWindowFull, if FailOnFull is set
+func WithTxAsyncWindow(window TxAsyncWindow) interface{} {
+	return bindings.OptionTxAsyncWindow{MaxItems: window.MaxItems, MaxBytes: window.MaxBytes, FailOnFull: window.FailOnFull}
+}
+
+// WithAdaptiveFetch enables adaptive count of the items of the next page of the query results instead of the fixed fetch count:
+// the page is limited by the size of the items and grows or shrinks by the consumption rate of the results.
+// Fetch count of the query, set by Query.FetchCount, takes precedence over the adaptive count
+func WithAdaptiveFetch(fetch AdaptiveFetch) interface{} {
+	return bindings.OptionAdaptiveFetch{MinCount: fetch.MinCount, MaxCount: fetch.MaxCount, PageBytes: fetch.PageBytes}
+}
+
+// WithTracingAttributes sets function, which returns custom attributes for each OpenTelemetry span.
+// Takes effect only with WithOpenTelemetry option
+func WithTracingAttributes(fn TracingAttributesFunc) interface{} {
+	return bindings.OptionOpenTelemetryAttributes{AttributesFunc: fn}
+}
+
+// WithRecoverHandler recovers internal panics of the client (e.g. encoder state mismatches or misparsed result buffers)
+// in items modifications, queries, iterators and transactions, and returns errors, converted by handler, instead.
+// It's a safety net for long-running services. Without this option panics are not recovered
+func WithRecoverHandler(handler RecoverHandler) interface{} {
+	return bindings.OptionRecoverHandler{Handler: handler}
+}
+
+// WithInterceptor adds interceptor, which is called around the binding calls: selects, items modifications, update and delete queries
+// and commits of the transactions. It allows to retry, audit, rate limit or measure the calls without forking the client.
+// Several interceptors are applied in the order of the options, so the first one is the outermost
+func WithInterceptor(interceptor Interceptor) interface{} {
+	return bindings.OptionInterceptor{Interceptor: interceptor}
+}
+
+// WithResultVerification enables debug verification of the queries' results: share sampleRate (from 0 to 1) of the queries is sampled,
+// and the client evaluates conditions of the query on each returned item. Items, which don't match the conditions (e.g. due to
+// misconfigured indexes or collations), are counted by Prometheus metrics and are passed to handler. Queries with joins, merges,
+// aggregations, full text search, LIKE and DWithin conditions, and with conditions on the fields, which are not in the struct, are not verified
+func WithResultVerification(sampleRate float64, handler ResultMismatchHandler) interface{} {
+	return bindings.OptionResultVerification{SampleRate: sampleRate, Handler: handler}
+}
@@ -188,6 +188,40 @@ func (binding *Builtin) Init(u []url.URL, options ...interface{}) error {
 			// nothing
 		case bindings.OptionOpenTelemetry:
 			// nothing
+		case bindings.OptionOpenTelemetryAttributes:
+			// nothing
+		case bindings.OptionPprofLabels:
+			// nothing
+		case bindings.OptionRateLimit:
+			// nothing
+		case bindings.OptionMaxConcurrentQueries:
+			// nothing
+		case bindings.OptionMemoryBudget:
+			// nothing
+		case bindings.OptionTxAsyncWindow:
+			// nothing
+		case bindings.OptionAdaptiveFetch:
+			// nothing
+		case bindings.OptionItemCache:
+			// nothing
+		case bindings.OptionItemPool:
+			// nothing
+		case bindings.OptionResultVerification:
+			// nothing
+		case bindings.OptionInterceptor:
+			// nothing
+		case bindings.OptionQueryValidation:
+			// nothing
+		case bindings.OptionAllowUnsafe:
+			// nothing
+		case bindings.OptionHedgedReads:
+			// nothing
+		case bindings.OptionReadReplicas:
+			// nothing
+		case bindings.OptionReplicaHealthCheck:
+			// nothing
+		case bindings.OptionRecoverHandler:
+			// nothing
 		case bindings.OptionBuiltinWithServer:
 			// nothing
 		case bindings.OptionCgoLimit:
@@ -641,6 +675,12 @@ func (binding *Builtin) ReopenLogFiles() error {
 	return nil
 }
 
+// SetInstance replaces the reindexer instance, which is owned by the embedded server, after restart of the server (see builtinserver).
+// It must not be called concurrently with the other calls of the binding
+func (binding *Builtin) SetInstance(rx uintptr) {
+	binding.rx = C.uintptr_t(rx)
+}
+
 func (binding *Builtin) Finalize() error {
 	C.destroy_reindexer(binding.rx)
 	binding.rx = 0
@@ -663,6 +703,11 @@ func (binding *Builtin) Status(ctx context.Context) (status bindings.Status) {
 	return status
 }
 
+// CgoLimiterStatus returns count of active cgo calls and the limit of them
+func (binding *Builtin) CgoLimiterStatus() (usage int, limit int) {
+	return len(binding.cgoLimiter), cap(binding.cgoLimiter)
+}
+
 func newBufFreeBatcher() (bf *bufFreeBatcher) {
 	bf = &bufFreeBatcher{
 		bufs:   make([]*RawCBuffer, 0, 100),
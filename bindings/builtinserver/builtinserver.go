@@ -45,7 +45,14 @@ type BuiltinServer struct {
 	builtin         bindings.RawBinding
 	wg              sync.WaitGroup
 	shutdownTimeout time.Duration
+	startupTimeout  time.Duration
 	svc             C.uintptr_t
+	// State of the server, which is changed by StopServer and RestartServer
+	lock    sync.Mutex
+	dbURL   url.URL
+	config  *config.ServerConfig
+	ready   chan struct{}
+	stopped bool
 }
 
 func (server *BuiltinServer) stopServer(timeout time.Duration) error {
@@ -67,6 +74,39 @@ func (server *BuiltinServer) stopServer(timeout time.Duration) error {
 	}
 }
 
+// startServer starts the server with the config and waits until its storage is ready
+func (server *BuiltinServer) startServer(yamlStr string) error {
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		err := err2go(C.start_reindexer_server(server.svc, str2c(yamlStr)))
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	tTimeout := time.Now().Add(server.startupTimeout)
+	for !server.checkStorageReady() {
+		if time.Now().After(tTimeout) {
+			return bindings.NewError("Server startup timeout expired.", bindings.ErrLogic)
+		}
+		time.Sleep(time.Second)
+	}
+	close(server.ready)
+	server.stopped = false
+	return nil
+}
+
+func (server *BuiltinServer) getInstance() (uintptr, error) {
+	pass, _ := server.dbURL.User.Password()
+
+	var rx C.uintptr_t = 0
+	if err := err2go(C.get_reindexer_instance(server.svc, str2c(server.dbURL.Host), str2c(server.dbURL.User.Username()), str2c(pass), &rx)); err != nil {
+		return 0, err
+	}
+	return uintptr(rx), nil
+}
+
 func (server *BuiltinServer) Init(u []url.URL, options ...interface{}) error {
 	if server.builtin != nil {
 		return bindings.NewError("already initialized", bindings.ErrConflict)
@@ -74,7 +114,7 @@ func (server *BuiltinServer) Init(u []url.URL, options ...interface{}) error {
 	server.svc = C.init_reindexer_server()
 
 	server.builtin = &builtin.Builtin{}
-	startupTimeout := defaultStartupTimeout
+	server.startupTimeout = defaultStartupTimeout
 	server.shutdownTimeout = defaultShutdownTimeout
 	serverCfg := config.DefaultServerConfig()
 
@@ -82,12 +122,29 @@ func (server *BuiltinServer) Init(u []url.URL, options ...interface{}) error {
 		switch v := option.(type) {
 		case bindings.OptionPrometheusMetrics:
 		case bindings.OptionOpenTelemetry:
+		case bindings.OptionOpenTelemetryAttributes:
+		case bindings.OptionPprofLabels:
+		case bindings.OptionRateLimit:
+		case bindings.OptionMaxConcurrentQueries:
+		case bindings.OptionMemoryBudget:
+		case bindings.OptionTxAsyncWindow:
+		case bindings.OptionAdaptiveFetch:
+		case bindings.OptionItemCache:
+		case bindings.OptionItemPool:
+		case bindings.OptionResultVerification:
+		case bindings.OptionInterceptor:
+		case bindings.OptionQueryValidation:
+		case bindings.OptionAllowUnsafe:
+		case bindings.OptionHedgedReads:
+		case bindings.OptionReadReplicas:
+		case bindings.OptionReplicaHealthCheck:
+		case bindings.OptionRecoverHandler:
 		case bindings.OptionCgoLimit:
 		case bindings.OptionBuiltintCtxWatch:
 		case bindings.ConnectOptions:
 		case bindings.OptionBuiltinWithServer:
 			if v.StartupTimeout != 0 {
-				startupTimeout = v.StartupTimeout
+				server.startupTimeout = v.StartupTimeout
 			}
 			if v.ServerConfig != nil {
 				serverCfg = v.ServerConfig
@@ -105,34 +162,22 @@ func (server *BuiltinServer) Init(u []url.URL, options ...interface{}) error {
 		return err
 	}
 
-	server.wg.Add(1)
-	go func() {
-		defer server.wg.Done()
-		err := err2go(C.start_reindexer_server(server.svc, str2c(yamlStr)))
-		if err != nil {
-			panic(err)
-		}
-	}()
-
-	tTimeout := time.Now().Add(startupTimeout)
-	for !server.checkStorageReady() {
-		if time.Now().After(tTimeout) {
-			panic(bindings.NewError("Server startup timeout expired.", bindings.ErrLogic))
-		}
-		time.Sleep(time.Second)
+	server.config = serverCfg
+	server.dbURL = u[0]
+	server.ready = make(chan struct{})
+	if err := server.startServer(yamlStr); err != nil {
+		panic(err)
 	}
 
-	pass, _ := u[0].User.Password()
-
-	var rx C.uintptr_t = 0
-	if err := err2go(C.get_reindexer_instance(server.svc, str2c(u[0].Host), str2c(u[0].User.Username()), str2c(pass), &rx)); err != nil {
+	rx, err := server.getInstance()
+	if err != nil {
 		return err
 	}
 
 	builtinURL := append(u[:0:0], u...)
 	builtinURL[0].Path = ""
 
-	options = append(options, bindings.OptionReindexerInstance{Instance: uintptr(rx)})
+	options = append(options, bindings.OptionReindexerInstance{Instance: rx})
 	return server.builtin.Init(builtinURL, options...)
 }
 
@@ -257,10 +302,74 @@ func (server *BuiltinServer) ReopenLogFiles() error {
 	return err2go(C.reopen_log_files(server.svc))
 }
 
-func (server *BuiltinServer) Finalize() error {
+// StopServer stops the embedded server. Calls of the binding fail after the stop, so it's used to shutdown the server
+// before the rest of the application
+func (server *BuiltinServer) StopServer() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return server.stopLocked()
+}
+
+func (server *BuiltinServer) stopLocked() error {
+	if server.stopped {
+		return nil
+	}
 	if err := server.stopServer(server.shutdownTimeout); err != nil {
 		return err
 	}
+	server.stopped = true
+	server.ready = make(chan struct{})
+	return nil
+}
+
+// RestartServer stops the embedded server and starts it with the new config. The server's namespaces are closed by the restart.
+// It must not be called concurrently with the other calls of the binding
+func (server *BuiltinServer) RestartServer(cfg *config.ServerConfig) error {
+	yamlStr, err := cfg.GetYamlString()
+	if err != nil {
+		return err
+	}
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if err := server.stopLocked(); err != nil {
+		return err
+	}
+	C.destroy_reindexer_server(server.svc)
+	server.svc = C.init_reindexer_server()
+	server.config = cfg
+	if err := server.startServer(yamlStr); err != nil {
+		return err
+	}
+
+	rx, err := server.getInstance()
+	if err != nil {
+		return err
+	}
+	server.builtin.(*builtin.Builtin).SetInstance(rx)
+	return nil
+}
+
+// ServerReady returns the channel, which is closed, when the server is started and its storage is ready
+func (server *BuiltinServer) ServerReady() <-chan struct{} {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return server.ready
+}
+
+// ServerConfig returns the config of the running server
+func (server *BuiltinServer) ServerConfig() *config.ServerConfig {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return server.config
+}
+
+func (server *BuiltinServer) Finalize() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if err := server.stopLocked(); err != nil {
+		return err
+	}
 	C.destroy_reindexer_server(server.svc)
 	server.builtin = nil
 	server.shutdownTimeout = 0
@@ -271,6 +380,14 @@ func (server *BuiltinServer) Status(ctx context.Context) (status bindings.Status
 	return server.builtin.Status(ctx)
 }
 
+// CgoLimiterStatus returns count of active cgo calls and the limit of them
+func (server *BuiltinServer) CgoLimiterStatus() (usage int, limit int) {
+	if limited, ok := server.builtin.(bindings.RawBindingCgoLimited); ok {
+		return limited.CgoLimiterStatus()
+	}
+	return 0, 0
+}
+
 func (server *BuiltinServer) Ping(ctx context.Context) error {
 	return server.builtin.Ping(ctx)
 }
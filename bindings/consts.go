@@ -93,8 +93,9 @@ const (
 	CacheModeAggressive = 1
 	CacheModeOff        = 2
 
-	FormatJson  = 0
-	FormatCJson = 1
+	FormatJson    = 0
+	FormatCJson   = 1
+	FormatMsgPack = 2
 
 	ModeUpdate = 0
 	ModeInsert = 1
@@ -119,6 +120,7 @@ const (
 	ResultsPtrs       = 0x1
 	ResultsCJson      = 0x2
 	ResultsJson       = 0x3
+	ResultsMsgPack    = 0x4
 
 	ResultsWithPayloadTypes   = 0x10
 	ResultsWithItemID         = 0x20
@@ -157,6 +159,7 @@ const (
 	ErrNetwork              = 12
 	ErrNotFound             = 13
 	ErrStateInvalidated     = 14
+	ErrOutdatedWAL          = 16
 	ErrTimeout              = 19
 	ErrCanceled             = 20
 	ErrTagsMissmatch        = 21
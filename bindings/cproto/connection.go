@@ -15,6 +15,7 @@ import (
 
 	"github.com/restream/reindexer/v3/bindings"
 	"github.com/restream/reindexer/v3/cjson"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type bufPtr struct {
@@ -29,6 +30,8 @@ const bufsCap = 16 * 1024
 const queueSize = 512
 const maxSeqNum = queueSize * 1000000
 
+var errConnClosed = errors.New("connection closed")
+
 const cprotoMagic = 0xEEDD1132
 const cprotoVersion = 0x104
 const cprotoMinCompatVersion = 0x101
@@ -89,6 +92,7 @@ type requestInfo struct {
 type connection struct {
 	owner *NetCProto
 	conn  net.Conn
+	slot  *connSlot
 
 	wrBuf, wrBuf2 *bytes.Buffer
 	wrKick        chan struct{}
@@ -110,9 +114,10 @@ type connection struct {
 	enableSnappy int32
 }
 
-func newConnection(ctx context.Context, owner *NetCProto) (c *connection, err error) {
+func newConnection(ctx context.Context, owner *NetCProto, slot *connSlot) (c *connection, err error) {
 	c = &connection{
 		owner:  owner,
+		slot:   slot,
 		wrBuf:  bytes.NewBuffer(make([]byte, 0, bufsCap)),
 		wrBuf2: bytes.NewBuffer(make([]byte, 0, bufsCap)),
 		wrKick: make(chan struct{}, 1),
@@ -186,7 +191,7 @@ func (c *connection) deadlineTicker() {
 					}
 					c.seqs <- nextSeqNum(seqNum)
 					if c.owner != nil {
-						c.owner.logMsg(1, "rq: async deadline exceeded. Seq number: %v\n", seqNum)
+						c.owner.logMsg(1, "rq: async deadline exceeded", bindings.LogField{Key: "seq_number", Value: seqNum})
 					}
 					cmpl(nil, context.DeadlineExceeded)
 				} else {
@@ -411,7 +416,19 @@ func nextSeqNum(seqNum uint32) uint32 {
 	return seqNum - maxSeqNum
 }
 
-func (c *connection) packRPC(cmd int, seq uint32, execTimeout int, args ...interface{}) {
+// traceParent returns W3C traceparent of the span from ctx, which is sent to the server, if OpenTelemetry is enabled
+func (c *connection) traceParent(ctx context.Context) string {
+	if !c.owner.traceContext || ctx == nil {
+		return ""
+	}
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+}
+
+func (c *connection) packRPC(cmd int, seq uint32, execTimeout int, traceParent string, args ...interface{}) {
 
 	in := newRPCEncoder(cmd, seq, atomic.LoadInt32(&c.enableSnappy) != 0, c.owner.dedicatedThreads.DedicatedThreads)
 	for _, a := range args {
@@ -435,12 +452,17 @@ func (c *connection) packRPC(cmd int, seq uint32, execTimeout int, args ...inter
 
 	in.startArgsChunck()
 	in.int64Arg(int64(execTimeout))
+	if traceParent != "" {
+		in.stringArg(traceParent)
+	}
 
 	c.write(in.bytes())
 	in.ser.Close()
 }
 
 func (c *connection) awaitSeqNum(ctx context.Context) (seq uint32, remainingTimeout time.Duration, err error) {
+	atomic.AddInt32(&c.slot.waiting, 1)
+	defer atomic.AddInt32(&c.slot.waiting, -1)
 	select {
 	case seq = <-c.seqs:
 		if err = ctx.Err(); err != nil {
@@ -501,7 +523,7 @@ func (c *connection) rpcCallAsync(ctx context.Context, cmd int, netTimeout uint3
 	}
 	c.requests[reqID].cmplLock.Unlock()
 
-	c.packRPC(cmd, seq, int(timeout.Milliseconds()), args...)
+	c.packRPC(cmd, seq, int(timeout.Milliseconds()), c.traceParent(ctx), args...)
 }
 
 func (c *connection) rpcCall(ctx context.Context, cmd int, netTimeout uint32, args ...interface{}) (buf *NetBuffer, err error) {
@@ -518,7 +540,8 @@ func (c *connection) rpcCall(ctx context.Context, cmd int, netTimeout uint32, ar
 	reply := c.requests[reqID].repl
 
 	atomic.StoreUint32(&c.requests[reqID].seqNum, seq)
-	c.packRPC(cmd, seq, int(timeout.Milliseconds()), args...)
+	start := time.Now()
+	c.packRPC(cmd, seq, int(timeout.Milliseconds()), c.traceParent(ctx), args...)
 
 for_loop:
 	for {
@@ -557,6 +580,7 @@ for_loop:
 		buf.Free()
 		return nil, err
 	}
+	c.slot.observeRTT(time.Since(start))
 	if err = buf.parseArgs(); err != nil {
 		buf.Free()
 		return nil, err
@@ -565,7 +589,7 @@ for_loop:
 }
 
 func (c *connection) rpcCallNoReply(ctx context.Context, cmd int, netTimeout uint32, seq uint32, args ...interface{}) {
-	c.packRPC(cmd, seq, int((time.Second * time.Duration(netTimeout)).Milliseconds()), args...)
+	c.packRPC(cmd, seq, int((time.Second * time.Duration(netTimeout)).Milliseconds()), c.traceParent(ctx), args...)
 }
 
 func (c *connection) rpcCallNoResults(ctx context.Context, cmd int, netTimeout uint32, args ...interface{}) error {
@@ -578,6 +602,9 @@ func (c *connection) onError(err error) {
 	c.lock.Lock()
 	if c.err == nil {
 		c.err = err
+		if err != errConnClosed {
+			c.slot.setError(err)
+		}
 		if c.conn != nil {
 			c.conn.Close()
 		}
@@ -633,7 +660,7 @@ func (c *connection) lastReadTime() time.Time {
 }
 
 func (c *connection) Close() {
-	c.onError(errors.New("connection closed"))
+	c.onError(errConnClosed)
 }
 
 func (c *connection) Finalize() error {
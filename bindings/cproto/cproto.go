@@ -14,6 +14,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	otelattr "go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
 	"github.com/restream/reindexer/v3/bindings"
 	"github.com/restream/reindexer/v3/cjson"
 )
@@ -43,17 +46,23 @@ type Logger interface {
 }
 
 type NetCProto struct {
+	// Counter of round-robin balancing between the replicas. Must be the first field to keep 64-bit alignment
+	replicaNext      uint64
+	replicas         []*replica
 	dsn              dsn
 	pool             pool
 	isServerChanged  int32
 	onChangeCallback func()
 	serverStartTime  int64
 	retryAttempts    bindings.OptionRetryAttempts
+	retries          *retryCounters
+	hedgeDelay       time.Duration
 	timeouts         bindings.OptionTimeouts
 	connectOpts      bindings.OptionConnect
 	compression      bindings.OptionCompression
 	dedicatedThreads bindings.OptionDedicatedThreads
 	appName          string
+	traceContext     bool
 	termCh           chan struct{}
 	lock             sync.RWMutex
 	logger           Logger
@@ -79,13 +88,44 @@ func (binding *NetCProto) nextDSN() {
 func (binding *NetCProto) Init(u []url.URL, options ...interface{}) (err error) {
 	connPoolSize := defConnPoolSize
 	connPoolLBAlgorithm := defConnPoolLBAlgorithm
+	var replicaDSNs []url.URL
+	var replicaHealthCheck time.Duration
 	binding.appName = defAppName
+	binding.retries = &retryCounters{}
 
 	for _, option := range options {
 		switch v := option.(type) {
 		case bindings.OptionPrometheusMetrics:
 			// nothing
 		case bindings.OptionOpenTelemetry:
+			binding.traceContext = v.EnableTracing
+		case bindings.OptionOpenTelemetryAttributes:
+			// nothing
+		case bindings.OptionPprofLabels:
+			// nothing
+		case bindings.OptionRateLimit:
+			// nothing
+		case bindings.OptionMaxConcurrentQueries:
+			// nothing
+		case bindings.OptionMemoryBudget:
+			// nothing
+		case bindings.OptionTxAsyncWindow:
+			// nothing
+		case bindings.OptionAdaptiveFetch:
+			// nothing
+		case bindings.OptionItemCache:
+			// nothing
+		case bindings.OptionItemPool:
+			// nothing
+		case bindings.OptionResultVerification:
+			// nothing
+		case bindings.OptionInterceptor:
+			// nothing
+		case bindings.OptionQueryValidation:
+			// nothing
+		case bindings.OptionAllowUnsafe:
+			// nothing
+		case bindings.OptionRecoverHandler:
 			// nothing
 		case bindings.OptionConnPoolSize:
 			connPoolSize = v.ConnPoolSize
@@ -96,6 +136,15 @@ func (binding *NetCProto) Init(u []url.URL, options ...interface{}) (err error)
 		case bindings.OptionRetryAttempts:
 			binding.retryAttempts = v
 
+		case bindings.OptionHedgedReads:
+			binding.hedgeDelay = v.Delay
+
+		case bindings.OptionReadReplicas:
+			replicaDSNs = v.DSNs
+
+		case bindings.OptionReplicaHealthCheck:
+			replicaHealthCheck = v.Interval
+
 		case bindings.OptionTimeouts:
 			binding.timeouts = v
 
@@ -141,6 +190,9 @@ func (binding *NetCProto) Init(u []url.URL, options ...interface{}) (err error)
 	binding.connectDSN(context.Background(), connPoolSize, connPoolLBAlgorithm)
 	binding.termCh = make(chan struct{})
 	go binding.pinger()
+	if len(replicaDSNs) > 0 {
+		binding.initReplicas(replicaDSNs, replicaHealthCheck, options)
+	}
 	return
 }
 
@@ -150,8 +202,17 @@ func (binding *NetCProto) newPool(ctx context.Context, connPoolSize int, connPoo
 		conn.Close()
 	}
 
+	slots := binding.pool.slots
+	if len(slots) != connPoolSize {
+		slots = newConnSlots(connPoolSize)
+	} else {
+		for _, slot := range slots {
+			atomic.AddInt64(&slot.reconnects, 1)
+		}
+	}
 	binding.pool = pool{
 		conns:       make([]*connection, connPoolSize),
+		slots:       slots,
 		lbAlgorithm: connPoolLBAlgorithm,
 	}
 
@@ -160,7 +221,7 @@ func (binding *NetCProto) newPool(ctx context.Context, connPoolSize int, connPoo
 		go func(binding *NetCProto, wg *sync.WaitGroup, i int) {
 			defer wg.Done()
 
-			conn, _ := newConnection(ctx, binding)
+			conn, _ := newConnection(ctx, binding, slots[i])
 			binding.pool.conns[i] = conn
 		}(binding, &wg, i)
 	}
@@ -269,6 +330,49 @@ func (binding *NetCProto) ModifyItem(ctx context.Context, nsHash int, namespace
 	return binding.rpcCall(ctx, opWr, cmdModifyItem, namespace, format, data, mode, packedPercepts, stateToken, 0)
 }
 
+// ModifyItems sends all the items via one connection without waiting for the responses, so the batch takes one network round trip
+func (binding *NetCProto) ModifyItems(ctx context.Context, nsHash int, namespace string, items []bindings.BatchItem, mode int) ([]bindings.RawBuffer, []error) {
+	results := make([]bindings.RawBuffer, len(items))
+	errs := make([]error, len(items))
+	conn, err := binding.getConnection(ctx)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return results, errs
+	}
+
+	netTimeout := uint32(binding.timeouts.RequestTimeout / time.Second)
+	var wg sync.WaitGroup
+	wg.Add(len(items))
+	for i := range items {
+		i := i
+		var packedPercepts []byte
+		ser := cjson.NewPoolSerializer()
+		if len(items[i].Precepts) != 0 {
+			ser.PutVarCUInt(len(items[i].Precepts))
+			for _, precept := range items[i].Precepts {
+				ser.PutVString(precept)
+			}
+			packedPercepts = ser.Bytes()
+		}
+		conn.rpcCallAsync(ctx, cmdModifyItem, netTimeout, func(buf bindings.RawBuffer, err error) {
+			if err != nil {
+				if buf != nil {
+					buf.Free()
+				}
+				errs[i] = err
+			} else {
+				results[i] = buf
+			}
+			wg.Done()
+		}, namespace, items[i].Format, items[i].Data, mode, packedPercepts, items[i].StateToken, 0)
+		ser.Close()
+	}
+	wg.Wait()
+	return results, errs
+}
+
 func (binding *NetCProto) OpenNamespace(ctx context.Context, namespace string, enableStorage, dropOnFormatError bool) error {
 	storageOtps := bindings.StorageOpts{
 		EnableStorage:     enableStorage,
@@ -344,6 +448,20 @@ func (binding *NetCProto) GetMeta(ctx context.Context, namespace, key string) (b
 	return binding.rpcCall(ctx, opRd, cmdGetMeta, namespace, key)
 }
 
+func (binding *NetCProto) EnumMeta(ctx context.Context, namespace string) ([]string, error) {
+	buf, err := binding.rpcCall(ctx, opRd, cmdEnumMeta, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+
+	keys := make([]string, 0, len(buf.args))
+	for _, arg := range buf.args {
+		keys = append(keys, string(arg.([]byte)))
+	}
+	return keys, nil
+}
+
 func (binding *NetCProto) Select(ctx context.Context, query string, asJson bool, ptVersions []int32, fetchCount int) (bindings.RawBuffer, error) {
 	flags := 0
 	if asJson {
@@ -357,14 +475,11 @@ func (binding *NetCProto) Select(ctx context.Context, query string, asJson bool,
 		fetchCount = math.MaxInt32
 	}
 
-	buf, err := binding.rpcCall(ctx, opRd, cmdSelectSQL, query, flags, int32(fetchCount), ptVersions)
-	if buf != nil {
-		buf.reqID = buf.args[1].(int)
-		if len(buf.args) > 2 {
-			buf.uid = buf.args[2].(int64)
-		}
+	if !isSelectSQL(query) {
+		// UPDATE and DELETE statements must not be hedged
+		return binding.selectCallOnce(ctx, cmdSelectSQL, query, flags, int32(fetchCount), ptVersions)
 	}
-	return buf, err
+	return binding.readSelectCall(ctx, cmdSelectSQL, query, flags, int32(fetchCount), ptVersions)
 }
 
 func (binding *NetCProto) SelectQuery(ctx context.Context, data []byte, asJson bool, ptVersions []int32, fetchCount int) (bindings.RawBuffer, error) {
@@ -374,22 +489,71 @@ func (binding *NetCProto) SelectQuery(ctx context.Context, data []byte, asJson b
 	} else {
 		flags |= bindings.ResultsCJson | bindings.ResultsWithPayloadTypes | bindings.ResultsWithItemID
 	}
+	return binding.SelectQueryWithFlags(ctx, data, flags, ptVersions, fetchCount)
+}
+
+// SelectQueryWithFlags executes query and requests results in the format of flags. The same format is used to fetch the rest of the results
+func (binding *NetCProto) SelectQueryWithFlags(ctx context.Context, data []byte, flags int, ptVersions []int32, fetchCount int) (bindings.RawBuffer, error) {
 	flags |= bindings.ResultsSupportIdleTimeout
 
 	if fetchCount <= 0 {
 		fetchCount = math.MaxInt32
 	}
 
-	buf, err := binding.rpcCall(ctx, opRd, cmdSelect, data, flags, int32(fetchCount), ptVersions)
-	if buf != nil {
-		buf.reqID = buf.args[1].(int)
-		if len(buf.args) > 2 {
-			buf.uid = buf.args[2].(int64)
-		}
+	buf, err := binding.readSelectCall(ctx, cmdSelect, data, flags, int32(fetchCount), ptVersions)
+	if err == nil {
+		// Payload types are sent only with the first chunk of results
+		buf.fetchFlags = flags &^ bindings.ResultsWithPayloadTypes
 	}
 	return buf, err
 }
 
+// SelectQueries sends all the queries via one connection without waiting for the responses, so the batch takes one network round trip
+func (binding *NetCProto) SelectQueries(ctx context.Context, queries []bindings.BatchQuery) ([]bindings.RawBuffer, []error) {
+	results := make([]bindings.RawBuffer, len(queries))
+	errs := make([]error, len(queries))
+	conn, err := binding.readConnection(ctx)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return results, errs
+	}
+
+	netTimeout := uint32(binding.timeouts.RequestTimeout / time.Second)
+	var wg sync.WaitGroup
+	wg.Add(len(queries))
+	for i := range queries {
+		i := i
+		flags := queries[i].Flags
+		if flags == 0 {
+			flags = bindings.ResultsCJson | bindings.ResultsWithPayloadTypes | bindings.ResultsWithItemID
+		}
+		flags |= bindings.ResultsSupportIdleTimeout
+		fetchCount := queries[i].FetchCount
+		if fetchCount <= 0 {
+			fetchCount = math.MaxInt32
+		}
+		conn.rpcCallAsync(ctx, cmdSelect, netTimeout, func(buf bindings.RawBuffer, err error) {
+			if err != nil {
+				if buf != nil {
+					buf.Free()
+				}
+				errs[i] = err
+			} else {
+				netBuf := buf.(*NetBuffer)
+				netBuf.readQueryID()
+				// Payload types are sent only with the first chunk of results
+				netBuf.fetchFlags = flags &^ bindings.ResultsWithPayloadTypes
+				results[i] = netBuf
+			}
+			wg.Done()
+		}, queries[i].Data, flags, int32(fetchCount), queries[i].PtVersions)
+	}
+	wg.Wait()
+	return results, errs
+}
+
 func (binding *NetCProto) DeleteQuery(ctx context.Context, nsHash int, data []byte) (bindings.RawBuffer, error) {
 	return binding.rpcCall(ctx, opWr, cmdDeleteQuery, data)
 }
@@ -404,6 +568,9 @@ func (binding *NetCProto) Commit(ctx context.Context, namespace string) error {
 
 func (binding *NetCProto) OnChangeCallback(f func()) {
 	binding.onChangeCallback = f
+	for _, r := range binding.replicas {
+		r.binding.OnChangeCallback(f)
+	}
 }
 
 func (binding *NetCProto) EnableLogger(log bindings.Logger) {
@@ -481,20 +648,36 @@ func (binding *NetCProto) Finalize() error {
 	for _, conn := range conns {
 		conn.Finalize()
 	}
+	for _, r := range binding.replicas {
+		r.binding.Finalize()
+	}
 	return nil
 }
 
+// PoolStats returns statistics of the connections of the pool in the order of the pool's slots
+func (binding *NetCProto) PoolStats() []bindings.ConnStats {
+	binding.lock.RLock()
+	conns, slots := binding.pool.conns, binding.pool.slots
+	binding.lock.RUnlock()
+
+	stats := make([]bindings.ConnStats, len(conns))
+	for i, conn := range conns {
+		stats[i] = slots[i].stats(conn)
+	}
+	return stats
+}
+
 func (binding *NetCProto) getAllConns() []*connection {
 	binding.lock.RLock()
 	defer binding.lock.RUnlock()
 	return binding.pool.conns
 }
 
-func (binding *NetCProto) logMsg(level int, fmt string, msg ...interface{}) {
+func (binding *NetCProto) logMsg(level int, msg string, fields ...bindings.LogField) {
 	binding.logMtx.RLock()
 	defer binding.logMtx.RUnlock()
 	if binding.logger != nil {
-		binding.logger.Printf(level, fmt, msg)
+		bindings.Log(binding.logger, level, msg, fields...)
 	}
 }
 
@@ -514,7 +697,7 @@ func (binding *NetCProto) getConnection(ctx context.Context) (conn *connection,
 			default:
 			}
 			if currVersion == binding.dsn.connVersion {
-				binding.logMsg(3, "rq: reconnecting after err: %s \n", conn.curError().Error())
+				binding.logMsg(3, "rq: reconnecting after error", bindings.ErrorFields(conn.curError())...)
 				conn, err = binding.reconnect(ctx)
 				binding.lock.Unlock()
 				if err != nil {
@@ -581,9 +764,15 @@ func (binding *NetCProto) rpcCall(ctx context.Context, op int, cmd int, args ...
 		attempts = binding.retryAttempts.Write + 1
 	}
 	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			binding.onRetry(ctx, i, err)
+		}
 		var conn *connection
 		if conn, err = binding.getConnection(ctx); err == nil {
 			if buf, err = conn.rpcCall(ctx, cmd, uint32(binding.timeouts.RequestTimeout/time.Second), args...); err == nil {
+				if i > 0 {
+					atomic.AddInt64(&binding.retries.succeeded, 1)
+				}
 				return
 			}
 		}
@@ -592,16 +781,58 @@ func (binding *NetCProto) rpcCall(ctx context.Context, op int, cmd int, args ...
 			select {
 			case <-ctx.Done():
 				err = ctx.Err()
+				binding.onAbandon(attempts)
 				return
 			case <-time.After(time.Second * time.Duration(i)):
 			}
 		default:
+			if i > 0 {
+				binding.onAbandon(attempts)
+			}
 			return
 		}
 	}
+	binding.onAbandon(attempts)
 	return
 }
 
+// retryCounters contains counters for bindings.RetryStats. Must be allocated separately to keep 64-bit alignment
+type retryCounters struct {
+	retries   int64
+	succeeded int64
+	abandoned int64
+}
+
+// onRetry counts retry attempt. If tracing is enabled, the attempt is also added as event to the span of the context
+func (binding *NetCProto) onRetry(ctx context.Context, attempt int, lastErr error) {
+	atomic.AddInt64(&binding.retries.retries, 1)
+	if binding.traceContext {
+		oteltrace.SpanFromContext(ctx).AddEvent("rx.retry", oteltrace.WithAttributes(
+			otelattr.Int("rx.retry.attempt", attempt),
+			otelattr.String("rx.retry.error", lastErr.Error()),
+		))
+	}
+}
+
+// onAbandon counts failed operation, if retries are enabled for it
+func (binding *NetCProto) onAbandon(attempts int) {
+	if attempts > 1 {
+		atomic.AddInt64(&binding.retries.abandoned, 1)
+	}
+}
+
+// RetryStats returns statistics of the retries, performed according to OptionRetryAttempts
+func (binding *NetCProto) RetryStats() bindings.RetryStats {
+	if binding.retries == nil {
+		return bindings.RetryStats{}
+	}
+	return bindings.RetryStats{
+		Retries:   atomic.LoadInt64(&binding.retries.retries),
+		Succeeded: atomic.LoadInt64(&binding.retries.succeeded),
+		Abandoned: atomic.LoadInt64(&binding.retries.abandoned),
+	}
+}
+
 func (binding *NetCProto) rpcCallNoResults(ctx context.Context, op int, cmd int, args ...interface{}) error {
 	buf, err := binding.rpcCall(ctx, op, cmd, args...)
 	if buf != nil {
@@ -122,6 +122,50 @@ func TestCprotoStatus(t *testing.T) {
 	})
 }
 
+func TestCprotoPoolStats(t *testing.T) {
+	srv := helpers.TestServer{T: t, RpcPort: "6661", HttpPort: "9961", DbName: "cproto"}
+	dsn := fmt.Sprintf("cproto://127.0.0.1:%s/%s_%s", srv.RpcPort, srv.DbName, srv.RpcPort)
+
+	require.NoError(t, srv.Run())
+	defer srv.Stop()
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	c := new(NetCProto)
+	require.NoError(t, c.Init([]url.URL{*u}, bindings.OptionConnect{CreateDBIfMissing: true}, bindings.OptionConnPoolSize{ConnPoolSize: 2}))
+	defer c.Finalize()
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, c.Ping(context.Background()))
+	}
+	stats := c.PoolStats()
+	require.Len(t, stats, 2)
+	for _, s := range stats {
+		assert.Equal(t, "127.0.0.1:"+srv.RpcPort, s.Addr)
+		assert.False(t, s.Broken)
+		assert.Equal(t, queueSize, s.QueueSize)
+		assert.Zero(t, s.InFlight)
+		assert.Zero(t, s.Reconnects)
+		assert.NoError(t, s.LastError)
+		assert.NotZero(t, s.Latency)
+	}
+
+	// Connections are broken by the server's stop and are reconnected by the next call after the restart
+	require.NoError(t, srv.Stop())
+	assert.Error(t, c.Ping(context.Background()))
+	for _, s := range c.PoolStats() {
+		assert.True(t, s.Broken)
+		assert.Error(t, s.LastError)
+		assert.False(t, s.LastErrorAt.IsZero())
+	}
+	require.NoError(t, srv.Run())
+	require.NoError(t, c.Ping(context.Background()))
+	for _, s := range c.PoolStats() {
+		assert.False(t, s.Broken)
+		assert.NotZero(t, s.Reconnects)
+	}
+}
+
 func runTestServer() (s *testServer, addr *url.URL, err error) {
 	startPort := 40000
 	var l net.Listener
@@ -227,3 +271,30 @@ func TestInvalidDSNFromGetStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestReplicasBalancing(t *testing.T) {
+	binding := &NetCProto{}
+	assert.Nil(t, binding.nextReplica())
+
+	r1 := &replica{binding: &NetCProto{}, healthy: 1}
+	r2 := &replica{binding: &NetCProto{}, healthy: 1}
+	binding.replicas = []*replica{r1, r2}
+	picked := map[*replica]int{}
+	for i := 0; i < 10; i++ {
+		picked[binding.nextReplica()]++
+	}
+	assert.Equal(t, map[*replica]int{r1: 5, r2: 5}, picked)
+
+	assert.True(t, r1.setHealthy(false))
+	assert.False(t, r1.setHealthy(false))
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, r2, binding.nextReplica())
+	}
+
+	r2.setHealthy(false)
+	assert.Nil(t, binding.nextReplica())
+
+	// Failback after successful health check
+	assert.True(t, r1.setHealthy(true))
+	assert.Equal(t, r1, binding.nextReplica())
+}
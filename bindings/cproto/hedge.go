@@ -0,0 +1,85 @@
+package cproto
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type selectResult struct {
+	buf *NetBuffer
+	err error
+}
+
+// selectCall executes select command. If hedged reads are enabled and the command is not completed within the hedge delay,
+// the same command is sent once more (via the next connection of the pool) and the first successful response is used
+func (binding *NetCProto) selectCall(ctx context.Context, cmd int, args ...interface{}) (*NetBuffer, error) {
+	if binding.hedgeDelay <= 0 || binding.connsCount() < 2 {
+		return binding.selectCallOnce(ctx, cmd, args...)
+	}
+
+	results := make(chan selectResult, 2)
+	call := func() {
+		buf, err := binding.selectCallOnce(ctx, cmd, args...)
+		results <- selectResult{buf, err}
+	}
+	go call()
+
+	timer := time.NewTimer(binding.hedgeDelay)
+	defer timer.Stop()
+	inFlight := 1
+	hedged := false
+	for {
+		select {
+		case res := <-results:
+			inFlight--
+			if res.err == nil || inFlight == 0 {
+				if inFlight != 0 {
+					// Results of the slower request must be closed on the server
+					go func() {
+						if res := <-results; res.buf != nil {
+							res.buf.Free()
+						}
+					}()
+				}
+				return res.buf, res.err
+			}
+			if res.buf != nil {
+				res.buf.Free()
+			}
+		case <-timer.C:
+			if !hedged {
+				hedged = true
+				inFlight++
+				go call()
+			}
+		}
+	}
+}
+
+func (binding *NetCProto) selectCallOnce(ctx context.Context, cmd int, args ...interface{}) (*NetBuffer, error) {
+	buf, err := binding.rpcCall(ctx, opRd, cmd, args...)
+	if buf != nil {
+		buf.readQueryID()
+	}
+	return buf, err
+}
+
+// isSelectSQL checks, if SQL statement doesn't modify data and may be hedged
+func isSelectSQL(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "select", "explain":
+		return true
+	}
+	return false
+}
+
+func (binding *NetCProto) connsCount() int {
+	binding.lock.RLock()
+	defer binding.lock.RUnlock()
+	return len(binding.pool.conns)
+}
@@ -18,16 +18,20 @@ type NetBuffer struct {
 	reqID int
 	uid   int64
 	args  []interface{}
+	// Results flags of the select query. If not set, flags are chosen by the format of Fetch
+	fetchFlags int
 }
 
 func (buf *NetBuffer) Fetch(ctx context.Context, offset, limit int, asJson bool) (err error) {
-	flags := 0
-	if asJson {
-		flags |= bindings.ResultsJson
-	} else {
-		flags |= bindings.ResultsCJson | bindings.ResultsWithItemID
+	flags := buf.fetchFlags
+	if flags == 0 {
+		if asJson {
+			flags |= bindings.ResultsJson
+		} else {
+			flags |= bindings.ResultsCJson | bindings.ResultsWithItemID
+		}
+		flags |= bindings.ResultsSupportIdleTimeout
 	}
-	flags |= bindings.ResultsSupportIdleTimeout
 	// fmt.Printf("cmdFetchResults(reqId=%d, offset=%d, limit=%d, json=%v, flags=%v)\n", buf.reqID, offset, limit, asJson, flags)
 	netTimeout := uint32(buf.conn.owner.timeouts.RequestTimeout / time.Second)
 
@@ -118,6 +122,14 @@ func (buf *NetBuffer) closeNoReply(seq uint32) {
 	}
 }
 
+// readQueryID reads ID of the query results on the server from the response of the select command. The ID is used to fetch the rest of the results
+func (buf *NetBuffer) readQueryID() {
+	buf.reqID = buf.args[1].(int)
+	if len(buf.args) > 2 {
+		buf.uid = buf.args[2].(int64)
+	}
+}
+
 func newNetBuffer(size int, conn *connection) (buf *NetBuffer) {
 
 	obj := bufPool.Get()
@@ -134,6 +146,7 @@ func newNetBuffer(size int, conn *connection) (buf *NetBuffer) {
 	buf.conn = conn
 	buf.reqID = -1
 	buf.uid = -1
+	buf.fetchFlags = 0
 	if len(buf.args) > 0 {
 		buf.args = buf.args[:0]
 	}
@@ -2,19 +2,78 @@ package cproto
 
 import (
 	"math/rand"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/restream/reindexer/v3/bindings"
 )
 
 type pool struct {
-	conns            []*connection
+	conns []*connection
+	// Statistics of the pool's slots, which are kept by reconnects
+	slots            []*connSlot
 	lbAlgorithm      bindings.LoadBalancingAlgorithm
 	roundRobinParams struct {
 		next uint64
 	}
 }
 
+// connSlot contains statistics of the connection of the pool's slot (see bindings.ConnStats).
+// Must be allocated separately to keep 64-bit alignment
+type connSlot struct {
+	rtt        int64
+	reconnects int64
+	waiting    int32
+
+	lock      sync.Mutex
+	lastErr   error
+	lastErrAt time.Time
+}
+
+func newConnSlots(count int) []*connSlot {
+	slots := make([]*connSlot, count)
+	for i := range slots {
+		slots[i] = &connSlot{}
+	}
+	return slots
+}
+
+// observeRTT updates smoothed round-trip time of the requests like TCP does (RFC 6298)
+func (s *connSlot) observeRTT(rtt time.Duration) {
+	srtt := atomic.LoadInt64(&s.rtt)
+	if srtt == 0 {
+		srtt = int64(rtt)
+	} else {
+		srtt += (int64(rtt) - srtt) / 8
+	}
+	atomic.StoreInt64(&s.rtt, srtt)
+}
+
+func (s *connSlot) setError(err error) {
+	s.lock.Lock()
+	s.lastErr, s.lastErrAt = err, time.Now()
+	s.lock.Unlock()
+}
+
+func (s *connSlot) stats(c *connection) bindings.ConnStats {
+	stats := bindings.ConnStats{
+		Broken:     c.hasError(),
+		InFlight:   cap(c.seqs) - len(c.seqs),
+		QueueSize:  cap(c.seqs),
+		QueueDepth: int(atomic.LoadInt32(&s.waiting)),
+		Reconnects: atomic.LoadInt64(&s.reconnects),
+		Latency:    time.Duration(atomic.LoadInt64(&s.rtt)),
+	}
+	if c.conn != nil {
+		stats.Addr = c.conn.RemoteAddr().String()
+	}
+	s.lock.Lock()
+	stats.LastError, stats.LastErrorAt = s.lastErr, s.lastErrAt
+	s.lock.Unlock()
+	return stats
+}
+
 func (p *pool) GetConnection() *connection {
 	switch p.lbAlgorithm {
 	case bindings.LBRandom:
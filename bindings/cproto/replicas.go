@@ -0,0 +1,140 @@
+package cproto
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+const defReplicaHealthCheckInterval = 5 * time.Second
+
+// replica - read-only server, which executes select queries instead of the leader (see bindings.OptionReadReplicas)
+type replica struct {
+	binding *NetCProto
+	healthy int32
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) != 0
+}
+
+// setHealthy updates state of the replica. Returns true, if the state was changed
+func (r *replica) setHealthy(healthy bool) bool {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	return atomic.SwapInt32(&r.healthy, v) != v
+}
+
+// initReplicas connects to the replicas with the same options as the leader and starts their health checks
+func (binding *NetCProto) initReplicas(dsns []url.URL, interval time.Duration, options []interface{}) {
+	replicaOptions := make([]interface{}, 0, len(options))
+	for _, option := range options {
+		switch option.(type) {
+		case bindings.OptionReadReplicas, bindings.OptionReplicaHealthCheck:
+		default:
+			replicaOptions = append(replicaOptions, option)
+		}
+	}
+	for _, dsn := range dsns {
+		// Replica is considered healthy until the first failure, so Init is not delayed by the health check
+		r := &replica{binding: &NetCProto{}, healthy: 1}
+		if err := r.binding.Init([]url.URL{dsn}, replicaOptions...); err != nil {
+			binding.logMsg(1, "rq: can't init replica", bindings.ErrorFields(err, bindings.LogField{Key: "replica", Value: dsn.String()})...)
+			continue
+		}
+		binding.replicas = append(binding.replicas, r)
+	}
+	if len(binding.replicas) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = defReplicaHealthCheckInterval
+	}
+	go binding.replicasChecker(interval)
+}
+
+func (binding *NetCProto) replicasChecker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-binding.termCh:
+			return
+		case <-ticker.C:
+			binding.checkReplicas(interval)
+		}
+	}
+}
+
+// checkReplicas pings all the replicas. Unavailable replicas are excluded from load balancing until the ping succeeds
+func (binding *NetCProto) checkReplicas(timeout time.Duration) {
+	for _, r := range binding.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := r.binding.Ping(ctx)
+		cancel()
+		if !r.setHealthy(err == nil) {
+			continue
+		}
+		if err != nil {
+			binding.logMsg(2, "rq: replica is unavailable", bindings.ErrorFields(err, bindings.LogField{Key: "replica", Value: r.binding.getActiveDSN().String()})...)
+		} else {
+			binding.logMsg(3, "rq: replica is available", bindings.LogField{Key: "replica", Value: r.binding.getActiveDSN().String()})
+		}
+	}
+}
+
+// nextReplica returns the next healthy replica in round-robin fashion, or nil if there are no healthy replicas
+func (binding *NetCProto) nextReplica() *replica {
+	count := len(binding.replicas)
+	if count == 0 {
+		return nil
+	}
+	start := atomic.AddUint64(&binding.replicaNext, 1)
+	for i := 0; i < count; i++ {
+		if r := binding.replicas[(start+uint64(i))%uint64(count)]; r.isHealthy() {
+			return r
+		}
+	}
+	return nil
+}
+
+// readSelectCall executes select query on the healthy replica. Query is sent to the leader, if there are no healthy replicas
+// or the replica is unavailable
+func (binding *NetCProto) readSelectCall(ctx context.Context, cmd int, args ...interface{}) (*NetBuffer, error) {
+	if r := binding.nextReplica(); r != nil {
+		buf, err := r.binding.selectCall(ctx, cmd, args...)
+		if !isReplicaFailure(ctx, err) {
+			return buf, err
+		}
+		if r.setHealthy(false) {
+			binding.logMsg(2, "rq: replica is unavailable", bindings.ErrorFields(err, bindings.LogField{Key: "replica", Value: r.binding.getActiveDSN().String()})...)
+		}
+	}
+	return binding.selectCall(ctx, cmd, args...)
+}
+
+// readConnection returns connection to the healthy replica, or to the leader, if there are no healthy replicas
+func (binding *NetCProto) readConnection(ctx context.Context) (*connection, error) {
+	if r := binding.nextReplica(); r != nil {
+		if conn, err := r.binding.getConnection(ctx); err == nil {
+			return conn, nil
+		}
+		r.setHealthy(false)
+	}
+	return binding.getConnection(ctx)
+}
+
+// isReplicaFailure checks, if the error is caused by unavailable replica (e.g. network or connection errors), rather than
+// by the query itself (errors of the server) or by the caller's context
+func isReplicaFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	_, isServerErr := err.(bindings.Error)
+	return !isServerErr
+}
@@ -5,8 +5,11 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/restream/reindexer/v3/bindings/builtinserver/config"
 	"github.com/restream/reindexer/v3/jsonschema"
+	otelattr "go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type IndexDef struct {
@@ -153,6 +156,43 @@ func (e Error) Code() int {
 	return e.code
 }
 
+// Is reports, if the error is matched by the target, which implements ErrorMatcher (e.g. CodeError), so errors.Is checks the error's kind
+func (e Error) Is(target error) bool {
+	if m, ok := target.(ErrorMatcher); ok {
+		return m.MatchError(e)
+	}
+	return false
+}
+
+// ErrorMatcher - sentinel error, which matches Error by errors.Is by other properties than equality (e.g. by the code)
+type ErrorMatcher interface {
+	error
+	MatchError(e Error) bool
+}
+
+// NewCodeError returns sentinel error, which matches all the errors with the code
+func NewCodeError(text string, code int) error {
+	return CodeError{text, code}
+}
+
+// CodeError - sentinel error, which matches all the errors with the same code by errors.Is
+type CodeError struct {
+	s    string
+	code int
+}
+
+func (e CodeError) Error() string {
+	return e.s
+}
+
+func (e CodeError) Code() int {
+	return e.code
+}
+
+func (e CodeError) MatchError(err Error) bool {
+	return err.code == e.code
+}
+
 type Stats struct {
 	CountGetItem int
 	TimeGetItem  time.Duration
@@ -213,6 +253,104 @@ type RawBindingChanging interface {
 	OnChangeCallback(f func())
 }
 
+// RawBindingCgoLimited - binding, which limits count of concurrent cgo calls
+type RawBindingCgoLimited interface {
+	CgoLimiterStatus() (usage int, limit int)
+}
+
+// RawBindingResultsFlags - binding, which allows to request results of the query in the specified format (bindings.ResultsXXX flags)
+type RawBindingResultsFlags interface {
+	SelectQueryWithFlags(ctx context.Context, data []byte, flags int, ptVersions []int32, fetchCount int) (RawBuffer, error)
+}
+
+// RawBindingMetaEnumerator - binding, which returns keys of the namespace's meta
+type RawBindingMetaEnumerator interface {
+	EnumMeta(ctx context.Context, namespace string) ([]string, error)
+}
+
+// BatchItem - serialized item of the batch, modified by RawBindingModifyItems
+type BatchItem struct {
+	Format     int
+	Data       []byte
+	Precepts   []string
+	StateToken int
+}
+
+// RawBindingModifyItems - binding, which modifies several items by one call without waiting for the results of the previous items.
+// Results and errors are returned in the order of the items
+type RawBindingModifyItems interface {
+	ModifyItems(ctx context.Context, nsHash int, namespace string, items []BatchItem, mode int) ([]RawBuffer, []error)
+}
+
+// BatchQuery - serialized select query of the batch, executed by RawBindingSelectQueries
+type BatchQuery struct {
+	Data []byte
+	// Results flags (bindings.ResultsXXX). 0 means items in CJSON format with their IDs and payload types
+	Flags      int
+	PtVersions []int32
+	FetchCount int
+}
+
+// RawBindingSelectQueries - binding, which executes several select queries by one call without waiting for the results of the previous queries.
+// Results and errors are returned in the order of the queries
+type RawBindingSelectQueries interface {
+	SelectQueries(ctx context.Context, queries []BatchQuery) ([]RawBuffer, []error)
+}
+
+// RetryStats - statistics of the retries of the operations, failed with network errors (see OptionRetryAttempts)
+type RetryStats struct {
+	// Count of retry attempts
+	Retries int64
+	// Count of operations, which succeeded after one or more retries
+	Succeeded int64
+	// Count of operations, which failed with network error and were not completed by retries:
+	// all the attempts have failed or the context was done while waiting for the next attempt
+	Abandoned int64
+}
+
+// RawBindingRetryStats - binding, which retries failed operations
+type RawBindingRetryStats interface {
+	RetryStats() RetryStats
+}
+
+// RawBindingEmbeddedServer - binding, which runs the embedded Reindexer server (builtinserver)
+type RawBindingEmbeddedServer interface {
+	// StopServer stops the server. Calls of the binding fail after the stop
+	StopServer() error
+	// RestartServer stops the server and starts it with the new config. Namespaces must be reopened after the restart
+	RestartServer(cfg *config.ServerConfig) error
+	// ServerReady returns the channel, which is closed, when the server is started and its storage is ready
+	ServerReady() <-chan struct{}
+	// ServerConfig returns the config of the running server
+	ServerConfig() *config.ServerConfig
+}
+
+// ConnStats - statistics of the connection of the binding's pool (see RawBindingPoolStats)
+type ConnStats struct {
+	// Address of the server
+	Addr string
+	// Connection is broken and will be reconnected by the next call
+	Broken bool
+	// Count of the requests, which are sent by the connection and are waiting for the responses
+	InFlight int
+	// Size of the connection's queue of the requests
+	QueueSize int
+	// Count of the requests, which are waiting for free place in the connection's queue
+	QueueDepth int
+	// Count of reconnects of the pool's slot of the connection
+	Reconnects int64
+	// Last error of the connection and its time. Nil, if the connection of the slot has never failed
+	LastError   error
+	LastErrorAt time.Time
+	// Smoothed round-trip time of the synchronous requests of the connection, including processing of the requests by the server
+	Latency time.Duration
+}
+
+// RawBindingPoolStats - binding, which sends requests by the pool of connections
+type RawBindingPoolStats interface {
+	PoolStats() []ConnStats
+}
+
 var availableBindings = make(map[string]RawBinding)
 
 func RegisterBinding(name string, binding RawBinding) {
@@ -280,6 +418,21 @@ type OptionRetryAttempts struct {
 	Write int
 }
 
+// OptionHedgedReads - duplicates read query on another connection, if the first request is not completed within the delay (cproto only)
+type OptionHedgedReads struct {
+	Delay time.Duration
+}
+
+// OptionReadReplicas - read-only replicas of the database, which execute select queries instead of the leader (cproto only)
+type OptionReadReplicas struct {
+	DSNs []url.URL
+}
+
+// OptionReplicaHealthCheck - interval of the health checks of the read replicas (cproto only)
+type OptionReplicaHealthCheck struct {
+	Interval time.Duration
+}
+
 type OptionBuiltinWithServer struct {
 	StartupTimeout  time.Duration
 	ShutdownTimeout time.Duration
@@ -311,13 +464,156 @@ type OptionAppName struct {
 
 // OptionPrometheusMetrics - enables collection of Reindexer's client side metrics (for example,
 // information about latency and rpc of all rx client calls like Upsert, Select, etc).
+// Registerer, Prefix, ConstLabels and Buckets are optional: metrics are registered in prometheus.DefaultRegisterer
+// with 'reindexer' prefix by default. Latency metrics are histograms with Buckets, if they are set, and summaries otherwise.
 type OptionPrometheusMetrics struct {
 	EnablePrometheusMetrics bool
+	Registerer              prometheus.Registerer
+	Prefix                  string
+	ConstLabels             map[string]string
+	Buckets                 []float64
 }
 
 // OptionOpenTelemetry - enables OpenTelemetry integration.
 type OptionOpenTelemetry struct {
 	EnableTracing bool
+	// Provider of the tracer. Global provider is used, if it's nil
+	TracerProvider oteltrace.TracerProvider
+	// Returns name of the span by the name of the operation (e.g. "Reindexer.Query.Exec")
+	SpanName func(op string) string
+	// Adds SQL of the queries to the spans
+	QuerySQL bool
+	// Only the operations, which take longer than the threshold, are traced
+	SlowThreshold time.Duration
+}
+
+// OptionPprofLabels - enables pprof labels (namespace, operation and query label) for client side calls.
+type OptionPprofLabels struct {
+	EnablePprofLabels bool
+}
+
+// OptionRateLimit - limits rate of client side calls. Limit is applied to the calls of the namespace, if Namespace is set,
+// or to all the calls otherwise.
+type OptionRateLimit struct {
+	Namespace    string
+	OpsPerSecond float64
+	Burst        int
+}
+
+// OptionMaxConcurrentQueries - bounds count of simultaneous client side calls of the namespace.
+type OptionMaxConcurrentQueries struct {
+	Namespace string
+	Count     int
+}
+
+// OptionMemoryBudget - limits memory, shared by object caches of all the namespaces and JSON buffers of the DB instance.
+type OptionMemoryBudget struct {
+	Bytes int64
+}
+
+// OptionTxAsyncWindow - bounds count and size of the items of each transaction, which are sent by async methods and are waiting for the responses.
+// Zero MaxItems means default count of the items, zero MaxBytes means unlimited size
+type OptionTxAsyncWindow struct {
+	MaxItems   int
+	MaxBytes   int64
+	FailOnFull bool
+}
+
+// OptionAdaptiveFetch - count of the items of the next page of the query results is chosen by size of the items and by consumption rate
+// of the results instead of the fixed fetch count. Zero values mean defaults
+type OptionAdaptiveFetch struct {
+	MinCount  int
+	MaxCount  int
+	PageBytes int64
+}
+
+// ItemCacheStore - storage of the object cache of the namespace. Keys are internal ids of the items, values are opaque for the store.
+// Store must be safe for concurrent use and must call onEvict (see ItemCacheStoreFactory) for each item, which leaves the store
+type ItemCacheStore interface {
+	Get(key int) (value interface{}, ok bool)
+	// Add adds or replaces the item. onEvict is not called for the replaced item
+	Add(key int, value interface{})
+	Remove(key int)
+	// RemoveOldest evicts the item, which is the first candidate for eviction. Returns false, if the store is empty
+	RemoveOldest() bool
+	Purge()
+	Len() int
+}
+
+// ItemCacheStoreFactory creates store of the object cache of the namespace with capacity of maxItems items. onEvict may be nil
+type ItemCacheStoreFactory func(namespace string, maxItems int, onEvict func(key int, value interface{})) (ItemCacheStore, error)
+
+// OptionItemCache - limits of the object cache of the namespace and its eviction policy (reindexer.CacheLRU, reindexer.CacheARC).
+// NewStore replaces built-in store of the cache, if not nil. Shards splits the cache into the stores, selected by hash of item id
+type OptionItemCache struct {
+	Namespace string
+	MaxItems  int
+	MaxBytes  int64
+	Policy    int
+	NewStore  ItemCacheStoreFactory
+	Shards    int
+}
+
+// OptionItemPool - pool of the structs of the namespace, which are reused for decoding of the items. Reset clears the released item
+type OptionItemPool struct {
+	Namespace string
+	New       func() interface{}
+	Reset     func(item interface{})
+}
+
+// OptionQueryValidation - enables validation of the queries' fields against the structs of the namespaces before sending.
+type OptionQueryValidation struct {
+	EnableQueryValidation bool
+}
+
+// OptionAllowUnsafe - default of Iterator.AllowUnsafe for the queries of the DB
+type OptionAllowUnsafe struct {
+	AllowUnsafe bool
+}
+
+// OptionRecoverHandler - converts internal panics of the client into returned errors.
+type OptionRecoverHandler struct {
+	Handler func(op string, r interface{}) error
+}
+
+// ResultMismatch - item of the query's results, which doesn't match conditions of the query, evaluated by the client
+type ResultMismatch struct {
+	Namespace string
+	// SQL representation of the query. Empty, if the query can't be represented by SQL
+	Query string
+	// Label of the query (see Query.Label)
+	Label string
+	Item  interface{}
+}
+
+// ResultMismatchHandler is called for each item of the verified query, which doesn't match the query's conditions
+type ResultMismatchHandler func(ctx context.Context, mismatch ResultMismatch)
+
+// OptionResultVerification - re-checks the results of the sampled queries against the queries' conditions by the client.
+type OptionResultVerification struct {
+	// Share of the verified queries from 0 to 1
+	SampleRate float64
+	Handler    ResultMismatchHandler
+}
+
+// OpInfo - binding call, which is passed to the interceptor
+type OpInfo struct {
+	Op        string
+	Namespace string
+	// Mode of the item's modification
+	Mode string
+	// SQL of the query
+	SQL string
+}
+
+// OptionInterceptor - function, which is called around the binding calls. Interceptors are applied in the order of the options
+type OptionInterceptor struct {
+	Interceptor func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error
+}
+
+// OptionOpenTelemetryAttributes - sets function, which returns custom attributes for each OpenTelemetry span.
+type OptionOpenTelemetryAttributes struct {
+	AttributesFunc func(ctx context.Context, op string, ns string) []otelattr.KeyValue
 }
 
 type Status struct {
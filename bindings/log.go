@@ -0,0 +1,76 @@
+package bindings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keys of the fields of the log records
+const (
+	LogFieldNamespace = "namespace"
+	LogFieldQuery     = "query"
+	LogFieldDuration  = "duration"
+	LogFieldError     = "error"
+	LogFieldErrorCode = "error_code"
+)
+
+// LogField - field of the structured log record
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// StructuredLogger - logger, which receives the fields of the log records separately from the message.
+// Loggers, passed to EnableLogger, are checked for this interface, and the plain loggers receive the fields formatted into the message
+type StructuredLogger interface {
+	Logger
+	Log(level int, msg string, fields ...LogField)
+}
+
+// LevelLogger - logger, which reports, whether the records of the level are written. Records of the disabled levels are not
+// passed to the logger, and their fields (e.g. SQL text of the query) are not built
+type LevelLogger interface {
+	Enabled(level int) bool
+}
+
+// LogEnabled returns false, if the logger discards the records of the level
+func LogEnabled(logger Logger, level int) bool {
+	switch l := logger.(type) {
+	case nil, NullLogger, *NullLogger:
+		return false
+	case LevelLogger:
+		return l.Enabled(level)
+	}
+	return true
+}
+
+// ErrorFields returns the fields with the error and its code, if the error has code
+func ErrorFields(err error, fields ...LogField) []LogField {
+	fields = append(fields, LogField{Key: LogFieldError, Value: err.Error()})
+	if rerr, ok := err.(Error); ok {
+		fields = append(fields, LogField{Key: LogFieldErrorCode, Value: rerr.Code()})
+	}
+	return fields
+}
+
+// Log writes the record to the logger. Fields are formatted as 'key=value' after the message for the loggers, which are not StructuredLogger
+func Log(logger Logger, level int, msg string, fields ...LogField) {
+	switch l := logger.(type) {
+	case nil, NullLogger, *NullLogger:
+		return
+	case StructuredLogger:
+		l.Log(level, msg, fields...)
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, f := range fields {
+		if v, ok := f.Value.(string); ok && strings.ContainsAny(v, " =\"") {
+			// Strings with spaces are quoted, so the fields are separable
+			fmt.Fprintf(&sb, " %s=%q", f.Key, v)
+		} else {
+			fmt.Fprintf(&sb, " %s=%v", f.Key, f.Value)
+		}
+	}
+	logger.Printf(level, "%s", sb.String())
+}
@@ -0,0 +1,13 @@
+package mock
+
+import (
+	"github.com/restream/reindexer/v3"
+)
+
+// NewDB creates DB instance with the mock binding of the server with the name. State of the server is reset, so each test
+// starts with the empty server. Options are passed to reindexer.NewReindex
+func NewDB(name string, options ...interface{}) (*reindexer.Reindexer, *Server) {
+	srv := GetServer(name)
+	srv.Reset()
+	return reindexer.NewReindex("mock://"+name, options...), srv
+}
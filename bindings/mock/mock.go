@@ -0,0 +1,434 @@
+// Package mock provides in-memory binding for unit tests of the code, which uses reindexer, without cgo and running server.
+//
+// Import the package and create DB instance with 'mock://<name>' DSN. State of the binding is available via GetServer(name):
+// it records calls of the binding and returns programmable results of the queries.
+// Queries are not evaluated by the mock: each query returns results, which are set by SetResults or OnSelect
+package mock
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+var emptyLogger bindings.NullLogger
+
+func init() {
+	bindings.RegisterBinding("mock", new(Mock))
+}
+
+// Mock - in-memory implementation of bindings.RawBinding
+type Mock struct {
+	srv       *Server
+	logger    bindings.Logger
+	loggerMtx sync.RWMutex
+}
+
+type rawBuffer struct {
+	buf []byte
+}
+
+func (b *rawBuffer) GetBuf() []byte {
+	return b.buf
+}
+
+func (b *rawBuffer) Free() {
+}
+
+// pagedBuffer - results, which are returned by pages of the server's fetch limit (see Server.SetFetchLimit)
+type pagedBuffer struct {
+	rawBuffer
+	srv   *Server
+	ns    *namespace
+	items [][]byte
+	flags int
+}
+
+func (b *pagedBuffer) Fetch(ctx context.Context, offset, limit int, asJson bool) error {
+	if err := b.srv.record(Call{Method: MethodFetchResults, Namespace: b.ns.name, FetchCount: limit}); err != nil {
+		return err
+	}
+	if offset > len(b.items) {
+		offset = len(b.items)
+	}
+	b.buf = b.page(offset, limit, b.flags&^bindings.ResultsWithPayloadTypes)
+	return nil
+}
+
+// page returns results buffer with the items from offset. Count of the items is limited by limit and the server's fetch limit
+func (b *pagedBuffer) page(offset, limit, flags int) []byte {
+	b.srv.lock.Lock()
+	if fetchLimit := b.srv.fetchLimit; fetchLimit > 0 && (limit <= 0 || fetchLimit < limit) {
+		limit = fetchLimit
+	}
+	b.srv.lock.Unlock()
+	end := len(b.items)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	ser := cjson.NewSerializer(nil)
+	writeResultsPage(&ser, b.ns, b.items[offset:end], len(b.items), flags)
+	return ser.Bytes()
+}
+
+// Server returns state of the binding
+func (binding *Mock) Server() *Server {
+	return binding.srv
+}
+
+func (binding *Mock) Init(u []url.URL, options ...interface{}) error {
+	if len(u) == 0 {
+		return errors.New("mock: empty DSN")
+	}
+	binding.srv = GetServer(u[0].Host + u[0].Path)
+	return nil
+}
+
+func (binding *Mock) Clone() bindings.RawBinding {
+	return &Mock{}
+}
+
+func (binding *Mock) OpenNamespace(ctx context.Context, namespace string, enableStorage, dropOnFileFormatError bool) error {
+	if err := binding.srv.record(Call{Method: MethodOpenNamespace, Namespace: namespace}); err != nil {
+		return err
+	}
+	binding.srv.ns(namespace)
+	return nil
+}
+
+func (binding *Mock) CloseNamespace(ctx context.Context, namespace string) error {
+	return binding.srv.record(Call{Method: MethodCloseNamespace, Namespace: namespace})
+}
+
+func (binding *Mock) DropNamespace(ctx context.Context, namespace string) error {
+	if err := binding.srv.record(Call{Method: MethodDropNamespace, Namespace: namespace}); err != nil {
+		return err
+	}
+	binding.srv.dropNs(namespace)
+	return nil
+}
+
+func (binding *Mock) TruncateNamespace(ctx context.Context, namespace string) error {
+	return binding.srv.record(Call{Method: MethodTruncateNamespace, Namespace: namespace})
+}
+
+func (binding *Mock) RenameNamespace(ctx context.Context, srcNs string, dstNs string) error {
+	return binding.srv.record(Call{Method: MethodRenameNamespace, Namespace: srcNs, Key: dstNs})
+}
+
+func (binding *Mock) EnableStorage(ctx context.Context, namespace string) error {
+	return nil
+}
+
+func (binding *Mock) AddIndex(ctx context.Context, namespace string, indexDef bindings.IndexDef) error {
+	return binding.srv.record(Call{Method: MethodAddIndex, Namespace: namespace, Index: indexDef})
+}
+
+func (binding *Mock) SetSchema(ctx context.Context, namespace string, schema bindings.SchemaDef) error {
+	return binding.srv.record(Call{Method: MethodSetSchema, Namespace: namespace})
+}
+
+func (binding *Mock) UpdateIndex(ctx context.Context, namespace string, indexDef bindings.IndexDef) error {
+	return binding.srv.record(Call{Method: MethodUpdateIndex, Namespace: namespace, Index: indexDef})
+}
+
+func (binding *Mock) DropIndex(ctx context.Context, namespace, index string) error {
+	return binding.srv.record(Call{Method: MethodDropIndex, Namespace: namespace, Index: bindings.IndexDef{Name: index}})
+}
+
+func (binding *Mock) BeginTx(ctx context.Context, namespace string) (bindings.TxCtx, error) {
+	srv := binding.srv
+	srv.lock.Lock()
+	srv.nextTxID++
+	id := srv.nextTxID
+	srv.txs[id] = namespace
+	srv.lock.Unlock()
+	if err := srv.record(Call{Method: MethodBeginTx, Namespace: namespace, TxID: id}); err != nil {
+		return bindings.TxCtx{}, err
+	}
+	return bindings.TxCtx{Id: id, UserCtx: ctx}, nil
+}
+
+func (binding *Mock) txNamespace(txCtx *bindings.TxCtx) string {
+	binding.srv.lock.Lock()
+	defer binding.srv.lock.Unlock()
+	return binding.srv.txs[txCtx.Id]
+}
+
+func (binding *Mock) CommitTx(txCtx *bindings.TxCtx) (bindings.RawBuffer, error) {
+	namespace := binding.txNamespace(txCtx)
+	if err := binding.srv.record(Call{Method: MethodCommitTx, Namespace: namespace, TxID: txCtx.Id}); err != nil {
+		return nil, err
+	}
+	ser := cjson.NewSerializer(nil)
+	writeResults(&ser, nil, nil, bindings.ResultsPure)
+	return &rawBuffer{buf: ser.Bytes()}, nil
+}
+
+func (binding *Mock) RollbackTx(txCtx *bindings.TxCtx) error {
+	return binding.srv.record(Call{Method: MethodRollbackTx, Namespace: binding.txNamespace(txCtx), TxID: txCtx.Id})
+}
+
+func (binding *Mock) ModifyItemTx(txCtx *bindings.TxCtx, format int, data []byte, mode int, precepts []string, stateToken int) error {
+	call := Call{Method: MethodModifyItemTx, Namespace: binding.txNamespace(txCtx), TxID: txCtx.Id, Mode: mode, Precepts: precepts}
+	if err := binding.applyItem(&call, format, data); err != nil {
+		return err
+	}
+	return binding.srv.record(call)
+}
+
+func (binding *Mock) ModifyItemTxAsync(txCtx *bindings.TxCtx, format int, data []byte, mode int, precepts []string, stateToken int, cmpl bindings.RawCompletion) {
+	err := binding.ModifyItemTx(txCtx, format, data, mode, precepts, stateToken)
+	cmpl(nil, err)
+}
+
+func (binding *Mock) DeleteQueryTx(txCtx *bindings.TxCtx, rawQuery []byte) error {
+	return binding.srv.record(Call{Method: MethodDeleteQueryTx, Namespace: binding.txNamespace(txCtx), TxID: txCtx.Id, Query: copyBytes(rawQuery)})
+}
+
+func (binding *Mock) UpdateQueryTx(txCtx *bindings.TxCtx, rawQuery []byte) error {
+	return binding.srv.record(Call{Method: MethodUpdateQueryTx, Namespace: binding.txNamespace(txCtx), TxID: txCtx.Id, Query: copyBytes(rawQuery)})
+}
+
+func (binding *Mock) PutMeta(ctx context.Context, namespace, key, data string) error {
+	if err := binding.srv.record(Call{Method: MethodPutMeta, Namespace: namespace, Key: key}); err != nil {
+		return err
+	}
+	binding.srv.lock.Lock()
+	binding.srv.meta[namespace+"\x00"+key] = data
+	binding.srv.lock.Unlock()
+	return nil
+}
+
+func (binding *Mock) GetMeta(ctx context.Context, namespace, key string) (bindings.RawBuffer, error) {
+	binding.srv.lock.Lock()
+	defer binding.srv.lock.Unlock()
+	return &rawBuffer{buf: []byte(binding.srv.meta[namespace+"\x00"+key])}, nil
+}
+
+func (binding *Mock) EnumMeta(ctx context.Context, namespace string) ([]string, error) {
+	binding.srv.lock.Lock()
+	defer binding.srv.lock.Unlock()
+	var keys []string
+	for key := range binding.srv.meta {
+		if ns, metaKey, _ := strings.Cut(key, "\x00"); ns == namespace {
+			keys = append(keys, metaKey)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// applyItem records the item of the call and updates tags matcher of the namespace
+func (binding *Mock) applyItem(call *Call, format int, data []byte) error {
+	call.format = format
+	if format != bindings.FormatCJson {
+		call.data = copyBytes(data)
+		return nil
+	}
+	data, tags, err := binding.srv.ns(call.Namespace).applyItem(data)
+	if err != nil {
+		return err
+	}
+	call.data, call.tags = copyBytes(data), tags
+	return nil
+}
+
+func (binding *Mock) ModifyItem(ctx context.Context, nsHash int, namespace string, format int, data []byte, mode int, precepts []string, stateToken int) (bindings.RawBuffer, error) {
+	return binding.modifyItem(Call{Method: MethodModifyItem, Namespace: namespace, Mode: mode, Precepts: precepts}, format, data)
+}
+
+// ModifyItems records a call of MethodModifyItems for each item
+func (binding *Mock) ModifyItems(ctx context.Context, nsHash int, namespace string, items []bindings.BatchItem, mode int) ([]bindings.RawBuffer, []error) {
+	results := make([]bindings.RawBuffer, len(items))
+	errs := make([]error, len(items))
+	for i, item := range items {
+		results[i], errs[i] = binding.modifyItem(Call{Method: MethodModifyItems, Namespace: namespace, Mode: mode, Precepts: item.Precepts}, item.Format, item.Data)
+	}
+	return results, errs
+}
+
+func (binding *Mock) modifyItem(call Call, format int, data []byte) (bindings.RawBuffer, error) {
+	namespace := call.Namespace
+	if err := binding.applyItem(&call, format, data); err != nil {
+		return nil, err
+	}
+	if err := binding.srv.record(call); err != nil {
+		return nil, err
+	}
+
+	ser := cjson.NewSerializer(nil)
+	if format == bindings.FormatCJson {
+		writeResults(&ser, binding.srv.ns(namespace), [][]byte{call.data}, bindings.ResultsCJson|bindings.ResultsWithPayloadTypes)
+	} else {
+		writeResults(&ser, nil, [][]byte{nil}, bindings.ResultsPure)
+	}
+	return &rawBuffer{buf: ser.Bytes()}, nil
+}
+
+func (binding *Mock) Select(ctx context.Context, query string, asJson bool, ptVersions []int32, fetchCount int) (bindings.RawBuffer, error) {
+	return binding.selectResults(Call{Method: MethodSelect, Namespace: sqlNamespace(query), SQL: query}, asJson)
+}
+
+func (binding *Mock) SelectQuery(ctx context.Context, rawQuery []byte, asJson bool, ptVersions []int32, fetchCount int) (bindings.RawBuffer, error) {
+	return binding.selectResults(Call{Method: MethodSelectQuery, Namespace: queryNamespace(rawQuery), Query: copyBytes(rawQuery), FetchCount: fetchCount,
+		PtVersions: append([]int32(nil), ptVersions...)}, asJson)
+}
+
+// SelectQueries records a call of MethodSelectQueries for each query
+func (binding *Mock) SelectQueries(ctx context.Context, queries []bindings.BatchQuery) ([]bindings.RawBuffer, []error) {
+	results := make([]bindings.RawBuffer, len(queries))
+	errs := make([]error, len(queries))
+	for i, q := range queries {
+		results[i], errs[i] = binding.selectResults(Call{Method: MethodSelectQueries, Namespace: queryNamespace(q.Data), Query: copyBytes(q.Data)}, false)
+	}
+	return results, errs
+}
+
+func (binding *Mock) UpdateQuery(ctx context.Context, nsHash int, rawQuery []byte) (bindings.RawBuffer, error) {
+	return binding.selectResults(Call{Method: MethodUpdateQuery, Namespace: queryNamespace(rawQuery), Query: copyBytes(rawQuery)}, false)
+}
+
+func (binding *Mock) DeleteQuery(ctx context.Context, nsHash int, rawQuery []byte) (bindings.RawBuffer, error) {
+	call := Call{Method: MethodDeleteQuery, Namespace: queryNamespace(rawQuery), Query: copyBytes(rawQuery)}
+	if err := binding.srv.record(call); err != nil {
+		return nil, err
+	}
+	items, err := binding.srv.selectResults(call)
+	if err != nil {
+		return nil, err
+	}
+	ser := cjson.NewSerializer(nil)
+	writeResults(&ser, nil, make([][]byte, len(items)), bindings.ResultsPure)
+	return &rawBuffer{buf: ser.Bytes()}, nil
+}
+
+func (binding *Mock) selectResults(call Call, asJson bool) (bindings.RawBuffer, error) {
+	if err := binding.srv.record(call); err != nil {
+		return nil, err
+	}
+	items, err := binding.srv.selectResults(call)
+	if err != nil {
+		return nil, err
+	}
+	ns := binding.srv.ns(call.Namespace)
+	data, err := ns.encode(items, asJson)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := bindings.ResultsCJson | bindings.ResultsWithPayloadTypes
+	if asJson {
+		flags = bindings.ResultsJson
+	}
+	binding.srv.lock.Lock()
+	fetchLimit := binding.srv.fetchLimit
+	binding.srv.lock.Unlock()
+	// Results in JSON format are fetched by pages only for raw JSON iteration, ExecToJson requests all the results (fetchCount is -1)
+	if fetchLimit > 0 && len(data) > fetchLimit && (!asJson || call.FetchCount > 0) {
+		b := &pagedBuffer{srv: binding.srv, ns: ns, items: data, flags: flags}
+		b.buf = b.page(0, fetchLimit, b.flags)
+		return b, nil
+	}
+	ser := cjson.NewSerializer(nil)
+	writeResults(&ser, ns, data, flags)
+	return &rawBuffer{buf: ser.Bytes()}, nil
+}
+
+// writeResults writes query results in the format of the server. ns is required for results with payload types
+func writeResults(ser *cjson.Serializer, ns *namespace, items [][]byte, flags int) {
+	writeResultsPage(ser, ns, items, len(items), flags)
+}
+
+// writeResultsPage writes the page of query results, which contain total items
+func writeResultsPage(ser *cjson.Serializer, ns *namespace, items [][]byte, total int, flags int) {
+	ser.PutVarUInt(uint64(flags))
+	// Total count, count of query results and count of results in the buffer
+	ser.PutVarUInt(uint64(total))
+	ser.PutVarUInt(uint64(total))
+	ser.PutVarUInt(uint64(len(items)))
+	if (flags & bindings.ResultsWithPayloadTypes) != 0 {
+		ser.PutVarUInt(1)
+		// nsid
+		ser.PutVarUInt(0)
+		ser.PutVString(ns.name)
+		ns.writePayloadType(ser)
+	}
+	ser.PutVarUInt(bindings.QueryResultEnd)
+	for _, item := range items {
+		switch flags & bindings.ResultsFormatMask {
+		case bindings.ResultsCJson, bindings.ResultsJson:
+			ser.PutUInt32(uint32(len(item)))
+			ser.Write(item)
+		}
+	}
+}
+
+func (binding *Mock) Commit(ctx context.Context, namespace string) error {
+	return nil
+}
+
+func (binding *Mock) EnableLogger(log bindings.Logger) {
+	binding.loggerMtx.Lock()
+	defer binding.loggerMtx.Unlock()
+	binding.logger = log
+}
+
+func (binding *Mock) DisableLogger() {
+	binding.loggerMtx.Lock()
+	defer binding.loggerMtx.Unlock()
+	binding.logger = nil
+}
+
+func (binding *Mock) GetLogger() bindings.Logger {
+	binding.loggerMtx.RLock()
+	defer binding.loggerMtx.RUnlock()
+	if binding.logger != nil {
+		return binding.logger
+	}
+	return &emptyLogger
+}
+
+func (binding *Mock) ReopenLogFiles() error {
+	return nil
+}
+
+func (binding *Mock) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (binding *Mock) Finalize() error {
+	return nil
+}
+
+func (binding *Mock) Status(ctx context.Context) bindings.Status {
+	return bindings.Status{}
+}
+
+// queryNamespace returns namespace of the serialized query
+func queryNamespace(rawQuery []byte) string {
+	ser := cjson.NewSerializer(rawQuery)
+	return ser.GetVString()
+}
+
+// sqlNamespace returns namespace of SQL statement: the word after FROM (or after UPDATE)
+func sqlNamespace(query string) string {
+	words := strings.Fields(query)
+	for i := 0; i+1 < len(words); i++ {
+		switch strings.ToLower(words[i]) {
+		case "from", "update":
+			return strings.ToLower(strings.Trim(words[i+1], "();"))
+		}
+	}
+	return ""
+}
+
+func copyBytes(data []byte) []byte {
+	return append([]byte(nil), data...)
+}
@@ -0,0 +1,341 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+// Names of the recorded methods. They are the same as names of bindings.RawBinding methods
+const (
+	MethodOpenNamespace     = "OpenNamespace"
+	MethodCloseNamespace    = "CloseNamespace"
+	MethodDropNamespace     = "DropNamespace"
+	MethodTruncateNamespace = "TruncateNamespace"
+	MethodRenameNamespace   = "RenameNamespace"
+	MethodAddIndex          = "AddIndex"
+	MethodUpdateIndex       = "UpdateIndex"
+	MethodDropIndex         = "DropIndex"
+	MethodSetSchema         = "SetSchema"
+	MethodModifyItem        = "ModifyItem"
+	MethodModifyItems       = "ModifyItems"
+	MethodSelect            = "Select"
+	MethodSelectQuery       = "SelectQuery"
+	MethodSelectQueries     = "SelectQueries"
+	MethodDeleteQuery       = "DeleteQuery"
+	MethodUpdateQuery       = "UpdateQuery"
+	MethodBeginTx           = "BeginTx"
+	MethodModifyItemTx      = "ModifyItemTx"
+	MethodDeleteQueryTx     = "DeleteQueryTx"
+	MethodUpdateQueryTx     = "UpdateQueryTx"
+	MethodCommitTx          = "CommitTx"
+	MethodRollbackTx        = "RollbackTx"
+	MethodPutMeta           = "PutMeta"
+	// Fetch of the next results of the select (see SetFetchLimit)
+	MethodFetchResults = "FetchResults"
+)
+
+// Call - recorded call of the mock binding
+type Call struct {
+	Method    string
+	Namespace string
+	// Serialized query (SelectQuery, DeleteQuery, UpdateQuery and their Tx versions)
+	Query []byte
+	// SQL statement (Select)
+	SQL string
+	// Modification mode of the item (bindings.ModeUpsert, etc)
+	Mode     int
+	Precepts []string
+	// Index definition (AddIndex, UpdateIndex) or name of the dropped index (DropIndex)
+	Index bindings.IndexDef
+	// Meta key (PutMeta) or new name of the namespace (RenameNamespace)
+	Key string
+	// Id of the transaction (transaction's methods)
+	TxID uint64
+	// Count of the items, requested by the select (SelectQuery) or by the fetch of the next results (FetchResults)
+	FetchCount int
+	// Versions of the payload types of the query's namespaces, which are known by the client (SelectQuery)
+	PtVersions []int32
+	format     int
+	data       []byte
+	tags       []string
+}
+
+// DecodeItem decodes item of ModifyItem/ModifyItemTx call into dest, which must be pointer to the struct of the namespace
+func (c *Call) DecodeItem(dest interface{}) error {
+	switch {
+	case c.data == nil:
+		return errors.New("mock: call has no item")
+	case c.format == bindings.FormatJson:
+		return json.Unmarshal(c.data, dest)
+	}
+	state := newCJSONState(c.Namespace, 0, 0, c.tags)
+	dec := state.NewDecoder(dest, nil)
+	return dec.Decode(c.data, dest)
+}
+
+// SelectFunc returns results of the select (or update) query. Results are items of the namespace's struct
+type SelectFunc func(call Call) ([]interface{}, error)
+
+// Server - in-memory state, which is shared by all the DB instances, created with 'mock://<name>' DSN.
+// It records calls of the binding and returns programmable results
+type Server struct {
+	lock       sync.Mutex
+	name       string
+	calls      []Call
+	namespaces map[string]*namespace
+	results    map[string][]interface{}
+	errors     map[string]error
+	onSelect   SelectFunc
+	meta       map[string]string
+	txs        map[uint64]string
+	nextTxID   uint64
+	fetchLimit int
+}
+
+var (
+	serversLock sync.Mutex
+	servers     = make(map[string]*Server)
+)
+
+// GetServer returns server for 'mock://<name>' DSN. Server is created, if it does not exist
+func GetServer(name string) *Server {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+	srv, ok := servers[name]
+	if !ok {
+		srv = &Server{name: name}
+		srv.reset()
+		servers[name] = srv
+	}
+	return srv
+}
+
+// Calls returns all the recorded calls
+func (s *Server) Calls() []Call {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsOf returns recorded calls of the method
+func (s *Server) CallsOf(method string) []Call {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var calls []Call
+	for _, c := range s.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// SetResults sets items, which are returned by each select and update query to the namespace.
+// Delete queries return count of these items
+func (s *Server) SetResults(namespace string, items ...interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.results[namespace] = items
+}
+
+// SetFetchLimit limits count of the items in the results of the select and in each fetch of the next results,
+// so the client fetches the rest of the results by FetchResults calls. 0 returns all the items at once
+func (s *Server) SetFetchLimit(limit int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.fetchLimit = limit
+}
+
+// OnSelect sets function, which returns results of the select and update queries instead of SetResults
+func (s *Server) OnSelect(fn SelectFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onSelect = fn
+}
+
+// SetError makes each call of the method to fail with err. nil err resets the error
+func (s *Server) SetError(method string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == nil {
+		delete(s.errors, method)
+	} else {
+		s.errors[method] = err
+	}
+}
+
+// Reset removes recorded calls, results, errors and namespaces
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reset()
+}
+
+func (s *Server) reset() {
+	s.calls = nil
+	s.namespaces = make(map[string]*namespace)
+	s.results = make(map[string][]interface{})
+	s.errors = make(map[string]error)
+	s.onSelect = nil
+	s.meta = make(map[string]string)
+	s.txs = make(map[uint64]string)
+	s.fetchLimit = 0
+}
+
+// record saves the call and returns error, which is set for the method
+func (s *Server) record(call Call) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls = append(s.calls, call)
+	return s.errors[call.Method]
+}
+
+func (s *Server) ns(name string) *namespace {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ns, ok := s.namespaces[name]
+	if !ok {
+		ns = &namespace{name: name, version: 1, stateToken: 1}
+		s.namespaces[name] = ns
+	}
+	return ns
+}
+
+func (s *Server) dropNs(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.namespaces, name)
+}
+
+// selectResults returns programmed results of the select call
+func (s *Server) selectResults(call Call) ([]interface{}, error) {
+	s.lock.Lock()
+	onSelect, items := s.onSelect, s.results[call.Namespace]
+	s.lock.Unlock()
+	if onSelect != nil {
+		return onSelect(call)
+	}
+	return items, nil
+}
+
+// namespace contains tags matcher of the namespace. Items, sent by clients, and programmed results are encoded with it
+type namespace struct {
+	lock       sync.Mutex
+	name       string
+	tags       []string
+	version    int32
+	stateToken int32
+}
+
+// applyItem updates tags matcher by the tags of item's CJSON and returns CJSON without tags matcher.
+// ErrStateInvalidated is returned, if the client's tags matcher is not compatible with the namespace's one
+func (ns *namespace) applyItem(data []byte) ([]byte, []string, error) {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	data, tags := splitTagsMatcher(data)
+	if tags != nil {
+		if !hasPrefix(tags, ns.tags) {
+			// Client must reload tags matcher
+			ns.version++
+			return nil, nil, bindings.NewError("mock: tags matcher of the item is outdated", bindings.ErrStateInvalidated)
+		}
+		if len(tags) > len(ns.tags) {
+			ns.tags = tags
+			ns.version++
+		}
+	}
+	return data, ns.tags, nil
+}
+
+// encode encodes programmed items to CJSON (or JSON) with the namespace's tags matcher
+func (ns *namespace) encode(items []interface{}, asJSON bool) ([][]byte, error) {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	res := make([][]byte, 0, len(items))
+	if asJSON {
+		for _, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, data)
+		}
+		return res, nil
+	}
+
+	state := newCJSONState(ns.name, ns.stateToken, ns.version, ns.tags)
+	for _, item := range items {
+		ser := cjson.NewSerializer(nil)
+		enc := state.NewEncoder()
+		if _, err := enc.Encode(item, &ser); err != nil {
+			return nil, err
+		}
+		data, tags := splitTagsMatcher(ser.Bytes())
+		if tags != nil {
+			ns.tags = tags
+			ns.version++
+		}
+		res = append(res, data)
+	}
+	return res, nil
+}
+
+func (ns *namespace) writePayloadType(ser *cjson.Serializer) {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	writePayloadType(ser, ns.stateToken, ns.version, ns.tags)
+}
+
+// writePayloadType writes tags matcher and empty payload type. All the fields of items are stored in CJSON
+func writePayloadType(ser *cjson.Serializer, stateToken, version int32, tags []string) {
+	ser.PutVarUInt(uint64(stateToken))
+	ser.PutVarUInt(uint64(version))
+	ser.PutVarUInt(uint64(len(tags)))
+	for _, tag := range tags {
+		ser.PutVString(tag)
+	}
+	// PStringHdrOffset and count of fields
+	ser.PutVarUInt(0)
+	ser.PutVarUInt(0)
+}
+
+func newCJSONState(name string, stateToken, version int32, tags []string) cjson.State {
+	ser := cjson.NewSerializer(nil)
+	writePayloadType(&ser, stateToken, version, tags)
+	rdser := cjson.NewSerializer(ser.Bytes())
+	state := cjson.NewState()
+	return state.ReadPayloadType(&rdser, nil, name)
+}
+
+// splitTagsMatcher splits CJSON, encoded by cjson.Encoder, into the item's data and the updated tags matcher.
+// Tags are nil, if the tags matcher was not updated by the encoder
+func splitTagsMatcher(data []byte) ([]byte, []string) {
+	ser := cjson.NewSerializer(data)
+	if len(data) == 0 || ser.GetVarUInt() != cjson.TAG_END {
+		return data, nil
+	}
+	tmOffset := int(ser.GetUInt32())
+	cjsonData := append([]byte(nil), data[ser.Pos():tmOffset]...)
+	tmser := cjson.NewSerializer(data[tmOffset:])
+	tags := make([]string, int(tmser.GetVarUInt()))
+	for i := range tags {
+		tags[i] = tmser.GetVString()
+	}
+	return cjsonData, tags
+}
+
+func hasPrefix(tags, prefix []string) bool {
+	if len(tags) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if tags[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -201,6 +201,10 @@ func (pl *payloadIface) getArrayLen(field int) int {
 func (pl *payloadIface) getValue(field int, idx int, v reflect.Value) {
 
 	k := v.Type().Kind()
+	if k == reflect.Interface {
+		v.Set(reflect.ValueOf(pl.getScalarIface(field, idx)))
+		return
+	}
 	switch pl.t.Fields[field].Type {
 	case valueBool:
 		v.SetBool(pl.getBool(field, idx))
@@ -490,6 +494,34 @@ func (pl *payloadIface) getArray(field int, startIdx int, cnt int, v reflect.Val
 	}
 }
 
+// getScalarIface returns value of the payload field as go interface. Integers are returned as int, like in CJSON decoding into interface
+func (pl *payloadIface) getScalarIface(field int, idx int) interface{} {
+	switch pl.t.Fields[field].Type {
+	case valueBool:
+		return pl.getBool(field, idx)
+	case valueInt:
+		return pl.getInt(field, idx)
+	case valueInt64:
+		return int(pl.getInt64(field, idx))
+	case valueDouble:
+		return pl.getFloat64(field, idx)
+	case valueString:
+		return pl.getString(field, idx)
+	case valueUuid:
+		return pl.getUuid(field, idx)
+	}
+	panic(fmt.Errorf("Unknown key value type %d", pl.t.Fields[field].Type))
+}
+
+// getIfaceArray returns cnt values of the payload array field, starting from startIdx, as slice of go interfaces
+func (pl *payloadIface) getIfaceArray(field int, startIdx int, cnt int) []interface{} {
+	a := make([]interface{}, cnt)
+	for i := range a {
+		a[i] = pl.getScalarIface(field, startIdx+i)
+	}
+	return a
+}
+
 // Slow and generic method: convert c payload to go interface
 // Use only for debug purposes
 func (pl *payloadIface) getIface(field int) interface{} {
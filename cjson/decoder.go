@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -15,6 +17,7 @@ import (
 var (
 	ifaceSlice     []interface{}
 	ifaceSliceType = reflect.TypeOf(ifaceSlice)
+	ifaceType      = ifaceSliceType.Elem()
 )
 
 type LoggerOwner interface {
@@ -30,20 +33,52 @@ type Decoder struct {
 
 const MaxIndexes = 256
 
+// decodeScratch - scratch buffers of the decoding of one item. They are taken from the pool, because decoding of each item
+// needs them, and counters of the index fields take 2KB
+type decodeScratch struct {
+	fieldsoutcnt [MaxIndexes]int
+	ctagsPath    []int
+}
+
+var decodeScratchPool = sync.Pool{
+	New: func() interface{} {
+		return &decodeScratch{ctagsPath: make([]int, 0, 8)}
+	},
+}
+
+func getDecodeScratch() *decodeScratch {
+	return decodeScratchPool.Get().(*decodeScratch)
+}
+
+// putDecodeScratch clears counters of the fields and returns the buffers to the pool
+func putDecodeScratch(s *decodeScratch) {
+	s.fieldsoutcnt = [MaxIndexes]int{}
+	s.ctagsPath = s.ctagsPath[:0]
+	decodeScratchPool.Put(s)
+}
+
+// fieldByTag returns field of the struct, which is encoded with name tag. Fields of the flattened structs are searched
+// recursively (see FieldEmbedding)
 func fieldByTag(t reflect.Type, tag string) (result reflect.StructField, ok bool) {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
 	for i := 0; i < t.NumField(); i++ {
 		result = t.Field(i)
-		if ftag := result.Tag.Get("json"); len(ftag) > 0 {
-			ftag, _, _ = splitStr(ftag, ',')
-			if tag == ftag {
-				return result, true
+		if flatten, prefix := FieldEmbedding(result); flatten {
+			ft := result.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
 			}
-		} else if result.Anonymous {
-			if result, ok := fieldByTag(result.Type, tag); ok {
-				result.Index = append([]int{i}, result.Index...)
+			if ft.Kind() == reflect.Struct && strings.HasPrefix(tag, prefix) {
+				if result, ok := fieldByTag(ft, tag[len(prefix):]); ok {
+					result.Index = append([]int{i}, result.Index...)
+					return result, true
+				}
+			}
+		} else if ftag := result.Tag.Get("json"); len(ftag) > 0 {
+			ftag, _, _ = splitStr(ftag, ',')
+			if tag == ftag || (len(ftag) == 0 && result.Name == tag) {
 				return result, true
 			}
 		} else if result.Name == tag {
@@ -502,6 +537,9 @@ func (dec *Decoder) decodeValue(pl *payloadIface, rdser *Serializer, v reflect.V
 		k = v.Kind()
 	}
 	var idx *[]int
+	// Field with custom unmarshaler is decoded into interface{} and then is passed to the unmarshaler
+	var custom reflect.Value
+	customKind := marshalerNone
 
 	mv, isMap := v, false
 	if ctagName != 0 {
@@ -562,6 +600,12 @@ func (dec *Decoder) decodeValue(pl *payloadIface, rdser *Serializer, v reflect.V
 			v = v.Elem()
 			k = v.Kind()
 		}
+		if !isMap && k != reflect.Interface {
+			if customKind = getUnmarshalerKind(v.Type()); customKind != marshalerNone {
+				custom = v
+				v, k = reflect.New(ifaceType).Elem(), reflect.Interface
+			}
+		}
 	}
 
 	//fmt.Printf("intf=%s, name='%s' %s,tagspath=%v,idx=%v\n", v.Type().Name(), dec.state.tagsMatcher.tag2name(ctagName), ctag.Dump(), cctagsPath, *idx)
@@ -572,7 +616,11 @@ func (dec *Decoder) decodeValue(pl *payloadIface, rdser *Serializer, v reflect.V
 		switch ctagType {
 		case TAG_ARRAY:
 			count := int(rdser.GetVarUInt())
-			pl.getArray(ctagField, *cnt, count, v)
+			if k == reflect.Interface {
+				v.Set(reflect.ValueOf(pl.getIfaceArray(ctagField, *cnt, count)))
+			} else {
+				pl.getArray(ctagField, *cnt, count, v)
+			}
 			*cnt += count
 		default:
 			pl.getValue(ctagField, *cnt, v)
@@ -626,6 +674,10 @@ func (dec *Decoder) decodeValue(pl *payloadIface, rdser *Serializer, v reflect.V
 		}
 	}
 
+	if customKind != marshalerNone {
+		unmarshalField(custom, customKind, v.Interface())
+	}
+
 	if isMap {
 		if mv.Type().Elem().Kind() == reflect.Ptr {
 			v = v.Addr()
@@ -682,10 +734,10 @@ func (dec *Decoder) DecodeCPtr(cptr uintptr, dest interface{}) (err error) {
 		}
 	}()
 
-	fieldsoutcnt := make([]int, MaxIndexes)
-	ctagsPath := make([]int, 0, 8)
+	scratch := getDecodeScratch()
+	defer putDecodeScratch(scratch)
 
-	dec.decodeValue(pl, ser, reflect.ValueOf(dest), fieldsoutcnt, ctagsPath)
+	dec.decodeValue(pl, ser, reflect.ValueOf(dest), scratch.fieldsoutcnt[:], scratch.ctagsPath)
 	if !ser.Eof() {
 		panic(fmt.Errorf("Internal error - left unparsed data"))
 	}
@@ -721,10 +773,10 @@ func (dec *Decoder) Decode(cjson []byte, dest interface{}) (err error) {
 		}
 	}()
 
-	fieldsoutcnt := make([]int, MaxIndexes)
-	ctagsPath := make([]int, 0, 8)
+	scratch := getDecodeScratch()
+	defer putDecodeScratch(scratch)
 
-	dec.decodeValue(nil, ser, reflect.ValueOf(dest), fieldsoutcnt, ctagsPath)
+	dec.decodeValue(nil, ser, reflect.ValueOf(dest), scratch.fieldsoutcnt[:], scratch.ctagsPath)
 	// if !ser.Eof() {
 	// 	panic(fmt.Errorf("Internal error - left unparsed data"))
 	// }
@@ -18,16 +18,19 @@ type Encoder struct {
 }
 
 type fieldInfo struct {
-	ctagName    int
-	kind        reflect.Kind
-	elemKind    reflect.Kind
-	isAnon      bool
+	ctagName int
+	kind     reflect.Kind
+	elemKind reflect.Kind
+	// Fields of the struct are encoded as fields of the parent object with the prefix (see FieldEmbedding)
+	isFlat      bool
+	prefix      string
 	isNullable  bool
 	isPrivate   bool
 	isOmitEmpty bool
 	isTime      bool
 	isPtr       bool
 	isUuid      bool
+	marshaler   marshalerKind
 }
 
 func SplitFieldOptions(str string) []string {
@@ -68,6 +71,47 @@ func isUuid(sf reflect.StructField) bool {
 	return false
 }
 
+// FieldEmbedding returns true, if fields of the struct field sf are encoded as fields of the parent object, and the prefix of
+// their names. Embedded structs without json name are flattened by default, as in encoding/json. It may be changed by the options
+// of reindex tag: 'flatten' flattens named struct field, 'nested' encodes embedded struct as object (named by its type or json name)
+// and 'prefix=<prefix>' flattens struct field and prefixes the names of its fields
+func FieldEmbedding(sf reflect.StructField) (flatten bool, prefix string) {
+	t := sf.Type
+	if t == nil {
+		return false, ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// Struct values of the interfaces are flattened in the same way
+	if (t.Kind() != reflect.Struct && t.Kind() != reflect.Interface) || t == timeType {
+		return false, ""
+	}
+	jsonName, _, _ := splitStr(sf.Tag.Get("json"), ',')
+	if jsonName == "-" {
+		return false, ""
+	}
+	flatten = sf.Anonymous && len(jsonName) == 0
+	tagsSlice := strings.SplitN(sf.Tag.Get("reindex"), ",", 3)
+	if len(tagsSlice) < 3 || tagsSlice[1] == "ttl" {
+		return flatten, ""
+	}
+	for _, opt := range SplitFieldOptions(tagsSlice[2]) {
+		switch {
+		case opt == "flatten":
+			flatten = true
+		case opt == "nested":
+			flatten = false
+		case strings.HasPrefix(opt, "prefix="):
+			flatten, prefix = true, strings.TrimPrefix(opt, "prefix=")
+		}
+	}
+	if !flatten {
+		prefix = ""
+	}
+	return flatten, prefix
+}
+
 func mkFieldInfo(v reflect.Value, ctagName int, sf reflect.StructField) fieldInfo {
 	t := v.Type()
 	k := t.Kind()
@@ -78,7 +122,6 @@ func mkFieldInfo(v reflect.Value, ctagName int, sf reflect.StructField) fieldInf
 	}
 
 	f := fieldInfo{
-		isAnon:     sf.Anonymous,
 		isNullable: (k == reflect.Ptr || k == reflect.Map || k == reflect.Slice || k == reflect.Interface),
 		isPtr:      k == reflect.Ptr,
 		kind:       kk,
@@ -91,6 +134,10 @@ func mkFieldInfo(v reflect.Value, ctagName int, sf reflect.StructField) fieldInf
 	if kk == reflect.String || ((kk == reflect.Slice || kk == reflect.Array) && f.elemKind == reflect.String) {
 		f.isUuid = isUuid(sf)
 	}
+	// Custom marshalers are supported for the fields of the structs only
+	if f.isFlat, f.prefix = FieldEmbedding(sf); !f.isFlat && len(sf.Name) != 0 && !sf.Anonymous {
+		f.marshaler = getMarshalerKind(t)
+	}
 
 	return f
 }
@@ -126,7 +173,8 @@ func parseStructField(sf reflect.StructField) (name string, skip, omitEmpty bool
 	return
 }
 
-func (enc *Encoder) encodeStruct(v reflect.Value, rdser *Serializer, idx []int) error {
+// encodeStruct encodes fields of the struct. prefix is added to the names of the fields of the flattened struct
+func (enc *Encoder) encodeStruct(v reflect.Value, rdser *Serializer, idx []int, prefix string) error {
 	for field := 0; field < v.NumField(); field++ {
 
 		iidx := idx
@@ -150,7 +198,7 @@ func (enc *Encoder) encodeStruct(v reflect.Value, rdser *Serializer, idx []int)
 			name, skip, omitempty := parseStructField(f)
 			ctagName := 0
 			if !skip {
-				ctagName = enc.name2tag(name)
+				ctagName = enc.name2tag(prefix + name)
 			}
 			if enc.tmUpdated {
 				// if tagsMatcher or lock is updated - we have temporary tags, do not cache them
@@ -158,6 +206,10 @@ func (enc *Encoder) encodeStruct(v reflect.Value, rdser *Serializer, idx []int)
 			}
 
 			ce.fieldInfo = mkFieldInfo(vv, ctagName, f)
+			if ce.fieldInfo.isFlat {
+				// Prefixes of the nested flattened structs are accumulated
+				ce.fieldInfo.prefix = prefix + ce.fieldInfo.prefix
+			}
 			ce.isPrivate = len(f.PkgPath) != 0 || skip
 			ce.isOmitEmpty = omitempty
 		}
@@ -793,6 +845,9 @@ func (enc *Encoder) encodeValue(v reflect.Value, rdser *Serializer, f fieldInfo,
 	if f.isPtr {
 		v = v.Elem()
 	}
+	if f.marshaler != marshalerNone {
+		return enc.encodeMarshaled(v, rdser, f)
+	}
 	switch f.kind {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		val := v.Int()
@@ -847,15 +902,15 @@ func (enc *Encoder) encodeValue(v reflect.Value, rdser *Serializer, f fieldInfo,
 				return nil
 			}
 		}
-		if !f.isAnon {
+		if !f.isFlat {
 			rdser.PutCTag(mkctag(TAG_OBJECT, f.ctagName, 0))
-			err := enc.encodeStruct(v, rdser, idx)
+			err := enc.encodeStruct(v, rdser, idx, "")
 			if err != nil {
 				return err
 			}
 			rdser.PutCTag(mkctag(TAG_END, 0, 0))
 		} else {
-			err := enc.encodeStruct(v, rdser, idx)
+			err := enc.encodeStruct(v, rdser, idx, f.prefix)
 			if err != nil {
 				return err
 			}
@@ -869,10 +924,9 @@ func (enc *Encoder) encodeValue(v reflect.Value, rdser *Serializer, f fieldInfo,
 		rdser.PutCTag(mkctag(TAG_END, 0, 0))
 	case reflect.Interface:
 		vv := v.Elem()
-		sf := reflect.StructField{
-			Anonymous: f.isAnon,
-		}
-		err := enc.encodeValue(vv, rdser, mkFieldInfo(vv, f.ctagName, sf), nil)
+		vf := mkFieldInfo(vv, f.ctagName, reflect.StructField{})
+		vf.isFlat, vf.prefix = f.isFlat, f.prefix
+		err := enc.encodeValue(vv, rdser, vf, nil)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,183 @@
+package cjson
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// FieldMarshaler is implemented by the types of the struct fields, which control their representation in CJSON (and so in the indexes).
+// MarshalReindexField returns value, which is encoded instead of the field: bool, integer, float, string or slice, map or struct of them
+type FieldMarshaler interface {
+	MarshalReindexField() (interface{}, error)
+}
+
+// FieldUnmarshaler is implemented by the types of the struct fields, which are encoded by FieldMarshaler.
+// UnmarshalReindexField receives decoded value of the field: nil, bool, int, float64, string, []interface{} or map[string]interface{}
+type FieldUnmarshaler interface {
+	UnmarshalReindexField(value interface{}) error
+}
+
+type marshalerKind int
+
+const (
+	marshalerNone marshalerKind = iota
+	// Type implements FieldMarshaler/FieldUnmarshaler
+	marshalerField
+	// Type implements encoding.TextMarshaler/encoding.TextUnmarshaler and is encoded as string
+	marshalerText
+)
+
+var (
+	fieldMarshalerType   = reflect.TypeOf((*FieldMarshaler)(nil)).Elem()
+	fieldUnmarshalerType = reflect.TypeOf((*FieldUnmarshaler)(nil)).Elem()
+	textMarshalerType    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType             = reflect.TypeOf(time.Time{})
+)
+
+// marshalerKinds caches kinds of the marshalers and unmarshalers of the types
+var marshalerKinds, unmarshalerKinds sync.Map
+
+// isTextMarshaled returns true for the types, which are encoded by encoding.TextMarshaler. Types of the kinds, which are natively supported
+// by CJSON (numbers, strings, slices, etc), are encoded as is to keep stored data compatible. time.Time is encoded in RFC3339 format
+func isTextMarshaled(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return t != timeType
+	case reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+func lookupKind(cache *sync.Map, t reflect.Type, fieldIface, textIface reflect.Type) marshalerKind {
+	if k, ok := cache.Load(t); ok {
+		return k.(marshalerKind)
+	}
+	k := marshalerNone
+	ptr := reflect.PtrTo(t)
+	if ptr.Implements(fieldIface) {
+		k = marshalerField
+	} else if isTextMarshaled(t) && ptr.Implements(textIface) {
+		k = marshalerText
+	}
+	cache.Store(t, k)
+	return k
+}
+
+// getMarshalerKind returns kind of the marshaler of the field's type t (pointers are dereferenced)
+func getMarshalerKind(t reflect.Type) marshalerKind {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return lookupKind(&marshalerKinds, t, fieldMarshalerType, textMarshalerType)
+}
+
+// getUnmarshalerKind returns kind of the unmarshaler of the field's type t (pointers are dereferenced)
+func getUnmarshalerKind(t reflect.Type) marshalerKind {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return lookupKind(&unmarshalerKinds, t, fieldUnmarshalerType, textUnmarshalerType)
+}
+
+// MarshaledType returns type of the value, which represents the field of type t in CJSON, if the type implements FieldMarshaler
+// or encoding.TextMarshaler. Type of FieldMarshaler's value is detected by marshaling of the zero value of t
+func MarshaledType(t reflect.Type) (reflect.Type, bool, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch getMarshalerKind(t) {
+	case marshalerText:
+		return reflect.TypeOf(""), true, nil
+	case marshalerField:
+		val, err := reflect.New(t).Interface().(FieldMarshaler).MarshalReindexField()
+		if err != nil {
+			return nil, true, err
+		}
+		if val == nil {
+			return nil, true, fmt.Errorf("Type of the field %s is unknown: MarshalReindexField returned nil for zero value", t.String())
+		}
+		return reflect.TypeOf(val), true, nil
+	}
+	return nil, false, nil
+}
+
+// MarshalValue returns value, which represents v in CJSON, if type of v implements FieldMarshaler or encoding.TextMarshaler
+func MarshalValue(v reflect.Value) (interface{}, bool, error) {
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, false, nil
+	}
+	switch getMarshalerKind(v.Type()) {
+	case marshalerText:
+		text, err := addrOf(v).(encoding.TextMarshaler).MarshalText()
+		return string(text), true, err
+	case marshalerField:
+		val, err := addrOf(v).(FieldMarshaler).MarshalReindexField()
+		return val, true, err
+	}
+	return nil, false, nil
+}
+
+// addrOf returns pointer to the value v, so methods with pointer receivers may be called
+func addrOf(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		return v.Interface()
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr.Interface()
+}
+
+// encodeMarshaled encodes value of the field with custom marshaler
+func (enc *Encoder) encodeMarshaled(v reflect.Value, rdser *Serializer, f fieldInfo) error {
+	if f.marshaler == marshalerText {
+		text, err := addrOf(v).(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		if len(text) != 0 || !f.isOmitEmpty {
+			rdser.PutCTag(mkctag(TAG_STRING, f.ctagName, 0))
+			rdser.PutVString(string(text))
+		}
+		return nil
+	}
+
+	val, err := addrOf(v).(FieldMarshaler).MarshalReindexField()
+	if err != nil {
+		return err
+	}
+	vv := reflect.ValueOf(val)
+	if !vv.IsValid() {
+		if !f.isOmitEmpty {
+			rdser.PutCTag(mkctag(TAG_NULL, f.ctagName, 0))
+		}
+		return nil
+	}
+	vf := mkFieldInfo(vv, f.ctagName, reflect.StructField{})
+	vf.isOmitEmpty = f.isOmitEmpty
+	return enc.encodeValue(vv, rdser, vf, nil)
+}
+
+// unmarshalField sets value of the field with custom unmarshaler from the decoded value
+func unmarshalField(v reflect.Value, kind marshalerKind, val interface{}) {
+	if kind == marshalerText {
+		str, ok := val.(string)
+		if !ok && val != nil {
+			panic(fmt.Errorf("Can't unmarshal %T to %s: string is expected", val, v.Type().String()))
+		}
+		if err := addrOf(v).(encoding.TextUnmarshaler).UnmarshalText([]byte(str)); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if err := addrOf(v).(FieldUnmarshaler).UnmarshalReindexField(val); err != nil {
+		panic(err)
+	}
+}
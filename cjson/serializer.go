@@ -7,10 +7,14 @@ import (
 	"unsafe"
 
 	"sync"
+	"sync/atomic"
 )
 
 var serPool sync.Pool
 
+// Count of serializers, taken from the pool and not returned yet
+var serPoolInUse int64
+
 type Serializer struct {
 	buf  []byte
 	pos  int
@@ -18,6 +22,7 @@ type Serializer struct {
 }
 
 func NewPoolSerializer() *Serializer {
+	atomic.AddInt64(&serPoolInUse, 1)
 	obj := serPool.Get()
 	if obj != nil {
 		ser := obj.(*Serializer)
@@ -34,10 +39,16 @@ func NewSerializer(buf []byte) Serializer {
 
 func (s *Serializer) Close() {
 	if s.pool {
+		atomic.AddInt64(&serPoolInUse, -1)
 		serPool.Put(s)
 	}
 }
 
+// PoolSerializersInUse returns count of serializers, taken from the pool by NewPoolSerializer and not closed yet
+func PoolSerializersInUse() int64 {
+	return atomic.LoadInt64(&serPoolInUse)
+}
+
 func (s *Serializer) Bytes() []byte {
 	return s.buf
 }
@@ -0,0 +1,124 @@
+package reindexer
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+// ConnStats - statistics of the connection of cproto binding's pool: in-flight requests, queue depth, reconnects, last error and latency
+type ConnStats = bindings.ConnStats
+
+// ClientStats - runtime statistics of the client side of the DB instance
+type ClientStats struct {
+	// Count of iterators, which are not closed yet
+	OpenIterators int64
+	// Count of serializers, taken from the pool and not returned yet. Shared by all the DB instances
+	PooledSerializers int64
+	// Count of active cgo calls (builtin binding only)
+	CgoCalls int
+	// Limit of concurrent cgo calls, set by WithCgoLimit (builtin binding only)
+	CgoLimit int
+	// Count of async operations of transactions, which are waiting for completion
+	PendingAsyncOps int64
+	// Size of the serialized items of async operations, which are waiting for completion (tracked with WithTxAsyncWindow size limit only)
+	PendingAsyncBytes int64
+	// Count of async operations of transactions, which waited for free window (see WithTxAsyncWindow)
+	TxWindowWaits int64
+	// Count of async operations of transactions, which were rejected with ErrTxAsyncWindowFull
+	TxWindowRejects int64
+	// Count of started and not committed (or rolled back) transactions
+	TxInFlight int64
+	// Memory budget, set by WithMemoryBudget. 0, if memory is not limited
+	MemoryBudget int64
+	// Estimated memory, used by object caches of all the namespaces (tracked with memory budget only)
+	CacheMemory int64
+	// Memory, used by JSON buffers of not closed JSON iterators (tracked with memory budget only)
+	JSONMemory int64
+	// Count of items, evicted from object caches due to memory budget
+	CacheEvictions int64
+	// Count of retries of items modifications (including transactions), caused by ErrStateInvalidated error.
+	// Frequent retries mean, that tags or payload type of the namespace are often changed by concurrent writes or schema updates
+	StateInvalidatedRetries int64
+	// Count of retries of the operations, failed with network errors (see WithRetryAttempts; cproto binding only)
+	RetryAttempts int64
+	// Count of operations, which succeeded after retries (cproto binding only)
+	RetrySuccesses int64
+	// Count of operations, which were not completed by retries: all the attempts have failed or the context was done
+	// while waiting for the next attempt (cproto binding only)
+	RetryAbandoned int64
+	// Statistics of object caches by namespaces
+	NamespaceCaches map[string]NamespaceCacheStats
+}
+
+// NamespaceCacheStats - statistics of object cache of the namespace
+type NamespaceCacheStats struct {
+	// Count of cached items
+	Items int
+	// Count of items, which were taken from the cache
+	Hits int64
+	// Count of items, which were not found in the cache (or were outdated) and were decoded
+	Misses int64
+	// Count of items, evicted from the cache due to its limits or memory budget
+	Evictions int64
+	// Count of decoded items of the namespace (including items, which are not cached) and total time of their decoding
+	Decodes    int64
+	DecodeTime time.Duration
+}
+
+// clientCounters contains counters for ClientStats. Must be allocated separately to keep 64-bit alignment
+type clientCounters struct {
+	openIterators   int64
+	pendingAsyncOps int64
+	txInFlight      int64
+	stateRetries    int64
+	// Size of async items of transactions, which are waiting for the responses
+	pendingAsyncBytes int64
+	txWindowWaits     int64
+	txWindowRejects   int64
+}
+
+func (db *reindexerImpl) clientStats() ClientStats {
+	stats := ClientStats{
+		OpenIterators:           atomic.LoadInt64(&db.counters.openIterators),
+		PooledSerializers:       cjson.PoolSerializersInUse(),
+		PendingAsyncOps:         atomic.LoadInt64(&db.counters.pendingAsyncOps),
+		PendingAsyncBytes:       atomic.LoadInt64(&db.counters.pendingAsyncBytes),
+		TxWindowWaits:           atomic.LoadInt64(&db.counters.txWindowWaits),
+		TxWindowRejects:         atomic.LoadInt64(&db.counters.txWindowRejects),
+		TxInFlight:              atomic.LoadInt64(&db.counters.txInFlight),
+		StateInvalidatedRetries: atomic.LoadInt64(&db.counters.stateRetries),
+	}
+	if b := db.memBudget; b != nil {
+		stats.MemoryBudget = b.limit
+		stats.CacheMemory = atomic.LoadInt64(&b.cacheBytes)
+		stats.JSONMemory = atomic.LoadInt64(&b.jsonBytes)
+		stats.CacheEvictions = atomic.LoadInt64(&b.evictions)
+	}
+	db.lock.RLock()
+	stats.NamespaceCaches = make(map[string]NamespaceCacheStats, len(db.ns))
+	for name, ns := range db.ns {
+		if ns.cacheItems != nil {
+			stats.NamespaceCaches[name] = ns.cacheItems.stats()
+		}
+	}
+	db.lock.RUnlock()
+	if limited, ok := db.binding.(bindings.RawBindingCgoLimited); ok {
+		stats.CgoCalls, stats.CgoLimit = limited.CgoLimiterStatus()
+	}
+	if retrying, ok := db.binding.(bindings.RawBindingRetryStats); ok {
+		retries := retrying.RetryStats()
+		stats.RetryAttempts, stats.RetrySuccesses, stats.RetryAbandoned = retries.Retries, retries.Succeeded, retries.Abandoned
+	}
+	return stats
+}
+
+// poolStats returns statistics of the binding's connections, or nil, if the binding doesn't use connections pool
+func (db *reindexerImpl) poolStats() []ConnStats {
+	if pooled, ok := db.binding.(bindings.RawBindingPoolStats); ok {
+		return pooled.PoolStats()
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// indexField is indexed field of the struct, for which filter is generated
+type indexField struct {
+	// Name of the filter's function, made from the path of the field in the struct
+	FuncName string
+	// Name of the index
+	Index string
+	// Go type of the filter's keys
+	KeyType string
+}
+
+// generator collects indexed fields of the struct from the parsed files of its package
+type generator struct {
+	types  map[string]*ast.StructType
+	fields []indexField
+	names  map[string]string
+}
+
+func newGenerator(files []*ast.File) *generator {
+	g := &generator{types: make(map[string]*ast.StructType), names: make(map[string]string)}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					g.types[spec.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+	return g
+}
+
+// basicTypes are types of the keys, which are passed to the filters as is. Keys of the other types are passed as interface{}
+var basicTypes = map[string]bool{
+	"bool": true, "string": true, "float32": true, "float64": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+}
+
+// keyType returns type of the keys for the field of type expr. Elements of slices and arrays are used as keys of the array indexes
+func keyType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return keyType(t.X)
+	case *ast.ArrayType:
+		return keyType(t.Elt)
+	case *ast.Ident:
+		if basicTypes[t.Name] {
+			return t.Name
+		}
+	}
+	return "interface{}"
+}
+
+// structType returns struct, declared in the package, if expr refers to it (including pointers and slices of structs)
+func (g *generator) structType(expr ast.Expr) *ast.StructType {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.structType(t.X)
+	case *ast.ArrayType:
+		return g.structType(t.Elt)
+	case *ast.Ident:
+		return g.types[t.Name]
+	case *ast.StructType:
+		return t
+	}
+	return nil
+}
+
+// parseStruct collects indexed fields of the struct. Paths of the indexes are built in the same way, as reindexer does it for nested structs
+func (g *generator) parseStruct(st *ast.StructType, indexBasePath, funcBaseName string, visited map[*ast.StructType]bool) error {
+	if visited[st] {
+		return nil
+	}
+	visited[st] = true
+	defer delete(visited, st)
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(value)
+		}
+		tags := strings.SplitN(tag.Get("reindex"), ",", 3)
+		idxName, idxType, idxOpts := tags[0], "", ""
+		if len(tags) > 1 {
+			idxType = tags[1]
+		}
+		if len(tags) > 2 {
+			idxOpts = tags[2]
+		}
+		if idxName == "-" || strings.Contains(idxOpts, "joined") {
+			continue
+		}
+
+		fieldName := ""
+		if len(field.Names) > 0 {
+			fieldName = field.Names[0].Name
+			if !ast.IsExported(fieldName) {
+				continue
+			}
+		}
+		indexPath := indexBasePath + idxName
+		funcName := funcBaseName + fieldName
+
+		if nested := g.structType(field.Type); nested != nil && !strings.Contains(idxOpts, "composite") {
+			if err := g.parseStruct(nested, nestedIndexBasePath(indexPath, idxOpts), funcName, visited); err != nil {
+				return err
+			}
+			continue
+		}
+		// Composite and rtree indexes require specific keys, so use Where for them
+		if len(idxName) == 0 || strings.Contains(idxOpts, "composite") || idxType == "rtree" {
+			continue
+		}
+		if len(fieldName) == 0 {
+			continue
+		}
+		if prev, ok := g.names[funcName]; ok {
+			return fmt.Errorf("filters for the indexes '%s' and '%s' have the same name By%s", prev, indexPath, funcName)
+		}
+		g.names[funcName] = indexPath
+		g.fields = append(g.fields, indexField{FuncName: funcName, Index: indexPath, KeyType: keyType(field.Type)})
+	}
+	return nil
+}
+
+// nestedIndexBasePath returns base path of the indexes of the nested struct at indexPath. Index names of the fields of
+// the struct, which is flattened with prefix, are prefixed in the same way, as their JSON names
+func nestedIndexBasePath(indexPath, idxOpts string) string {
+	if len(indexPath) != 0 && !strings.HasSuffix(indexPath, ".") {
+		indexPath += "."
+	}
+	for _, opt := range strings.Split(idxOpts, ",") {
+		if strings.HasPrefix(opt, "prefix=") {
+			indexPath += strings.TrimPrefix(opt, "prefix=")
+		}
+	}
+	return indexPath
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by querygen from {{.Type}}. DO NOT EDIT.
+
+// Package {{.Package}} contains typed filters for the indexes of {{.Type}}
+package {{.Package}}
+
+import "github.com/restream/reindexer/v3"
+
+// Names of the indexes of {{.Type}}
+const (
+{{- range .Fields}}
+	Index{{.FuncName}} = "{{.Index}}"
+{{- end}}
+)
+{{range .Fields}}
+// By{{.FuncName}} returns filter by "{{.Index}}" index of {{$.Type}}
+func By{{.FuncName}}(condition int, keys ...{{.KeyType}}) reindexer.QueryFilter {
+	return reindexer.WhereFilter(Index{{.FuncName}}, condition, keys)
+}
+{{end}}`))
+
+// generate returns source of the package with the filters for the indexes of the struct typeName
+func generate(files []*ast.File, typeName, pkgName string) ([]byte, error) {
+	g := newGenerator(files)
+	st, ok := g.types[typeName]
+	if !ok {
+		return nil, fmt.Errorf("struct %s is not found", typeName)
+	}
+	if err := g.parseStruct(st, "", "", make(map[*ast.StructType]bool)); err != nil {
+		return nil, err
+	}
+	if len(g.fields) == 0 {
+		return nil, fmt.Errorf("struct %s has no indexed fields", typeName)
+	}
+
+	var buf bytes.Buffer
+	err := fileTemplate.Execute(&buf, struct {
+		Type    string
+		Package string
+		Fields  []indexField
+	}{typeName, pkgName, g.fields})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// defaultPackageName returns name of the generated package for the struct: lowercased name with "q" suffix (e.g. "userq" for User)
+func defaultPackageName(typeName string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, typeName) + "q"
+}
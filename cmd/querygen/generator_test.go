@@ -0,0 +1,104 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSource = `package models
+
+type Base struct {
+	ID int64 ` + "`reindex:\"id,,pk\"`" + `
+}
+
+type Actor struct {
+	Name string ` + "`reindex:\"actor_name\"`" + `
+}
+
+type Info struct {
+	Score float64 ` + "`reindex:\"score\"`" + `
+}
+
+type User struct {
+	Base
+	Age      int        ` + "`reindex:\"age,tree\"`" + `
+	Tags     []string   ` + "`reindex:\"tags\"`" + `
+	Actor    Actor
+	Info     *Info      ` + "`reindex:\"info\"`" + `
+	Location [2]float64 ` + "`reindex:\"location,rtree\"`" + `
+	AgeTags  struct{}   ` + "`reindex:\"age+tags,,composite\"`" + `
+	Friends  []*User    ` + "`reindex:\"friends,,joined\"`" + `
+	Skipped  string     ` + "`reindex:\"-\"`" + `
+	Created  Timestamp  ` + "`reindex:\"created\"`" + `
+	secret   string     ` + "`reindex:\"secret\"`" + `
+}
+`
+
+func parseTestSource(t *testing.T, src string) []*ast.File {
+	file, err := parser.ParseFile(token.NewFileSet(), "models.go", src, 0)
+	require.NoError(t, err)
+	return []*ast.File{file}
+}
+
+func TestGenerateFilters(t *testing.T) {
+	src, err := generate(parseTestSource(t, testSource), "User", "userq")
+	require.NoError(t, err)
+
+	file, err := parser.ParseFile(token.NewFileSet(), "user_filters.go", src, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "userq", file.Name.Name)
+
+	code := string(src)
+	assert.Contains(t, code, `IndexID        = "id"`)
+	assert.Contains(t, code, `IndexInfoScore = "info.score"`)
+	assert.Contains(t, code, "func ByID(condition int, keys ...int64) reindexer.QueryFilter")
+	assert.Contains(t, code, "func ByAge(condition int, keys ...int) reindexer.QueryFilter")
+	assert.Contains(t, code, "func ByTags(condition int, keys ...string) reindexer.QueryFilter")
+	assert.Contains(t, code, "func ByActorName(condition int, keys ...string) reindexer.QueryFilter")
+	assert.Contains(t, code, "func ByInfoScore(condition int, keys ...float64) reindexer.QueryFilter")
+	assert.Contains(t, code, "func ByCreated(condition int, keys ...interface{}) reindexer.QueryFilter")
+	for _, name := range []string{"ByLocation", "ByAgeTags", "ByFriends", "BySkipped", "Bysecret"} {
+		assert.NotContains(t, code, name)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	files := parseTestSource(t, testSource)
+	_, err := generate(files, "Missing", "missingq")
+	assert.Error(t, err)
+	_, err = generate(files, "Base", "baseq")
+	assert.NoError(t, err)
+
+	_, err = generate(parseTestSource(t, "package models\n\ntype Empty struct {\n\tName string\n}\n"), "Empty", "emptyq")
+	assert.Error(t, err)
+}
+
+func TestDefaultPackageName(t *testing.T) {
+	assert.Equal(t, "userq", defaultPackageName("User"))
+	assert.Equal(t, "orderitemq", defaultPackageName("Order_Item"))
+}
+
+func TestGeneratePrefixedFilters(t *testing.T) {
+	src, err := generate(parseTestSource(t, `package models
+
+type Address struct {
+	City string `+"`reindex:\"city\"`"+`
+}
+
+type Order struct {
+	ID       int64    `+"`reindex:\"id,,pk\"`"+`
+	Billing  Address  `+"`reindex:\",,prefix=billing_\"`"+`
+	Shipping *Address `+"`reindex:\"shipping,,prefix=to_\"`"+`
+}
+`), "Order", "orderq")
+	require.NoError(t, err)
+
+	code := string(src)
+	assert.Contains(t, code, `IndexBillingCity  = "billing_city"`)
+	assert.Contains(t, code, `IndexShippingCity = "shipping.to_city"`)
+}
@@ -0,0 +1,69 @@
+// Command querygen generates typed filters for the indexes of the struct, e.g. userq.ByAge(reindexer.GT, 18), which are added to the query
+// by Query.Apply. Filters are checked by the compiler, so renaming of the fields doesn't break the queries silently.
+//
+// Usage with go:generate in the file with the struct:
+//
+//	//go:generate go run github.com/restream/reindexer/v3/cmd/querygen -type User
+//
+// Filters are written to ./userq/user_filters.go by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the struct (required)")
+	pkgName := flag.String("pkg", "", "name of the generated package (default: lowercased struct name with 'q' suffix)")
+	dir := flag.String("dir", ".", "directory of the package with the struct")
+	out := flag.String("out", "", "output file (default: <pkg>/<type>_filters.go)")
+	flag.Parse()
+
+	if len(*typeName) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if len(*pkgName) == 0 {
+		*pkgName = defaultPackageName(*typeName)
+	}
+	if len(*out) == 0 {
+		*out = filepath.Join(*dir, *pkgName, strings.ToLower(*typeName)+"_filters.go")
+	}
+
+	if err := run(*dir, *typeName, *pkgName, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "querygen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, typeName, pkgName, out string) error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			files = append(files, file)
+		}
+	}
+
+	src, err := generate(files, typeName, pkgName)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0644)
+}
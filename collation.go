@@ -0,0 +1,50 @@
+package reindexer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var customCollations = struct {
+	sync.RWMutex
+	orders map[string]string
+}{orders: make(map[string]string)}
+
+// RegisterCollation registers named custom sort order. Order is a sequence of letters and letter ranges, e.g. "А-Яа-яA-Za-z0-9".
+// Registered collation may be referenced in `reindex:` tag as `collate_<name>`, or by CustomCollation in IndexDef.
+// Sort by index with custom collation uses it's order
+func RegisterCollation(name string, order string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) == 0 {
+		return fmt.Errorf("Collation name is empty")
+	}
+	if len(order) == 0 {
+		return fmt.Errorf("Order of collation '%s' is empty", name)
+	}
+	if _, ok := collateModes["collate_"+name]; ok {
+		return fmt.Errorf("Collation '%s' conflicts with builtin collate mode", name)
+	}
+	customCollations.Lock()
+	defer customCollations.Unlock()
+	customCollations.orders[name] = order
+	return nil
+}
+
+// CustomCollation returns index definition with collation, registered by RegisterCollation
+func (indexDef IndexDef) CustomCollation(name string) (IndexDef, error) {
+	order, ok := getCollation(name)
+	if !ok {
+		return indexDef, fmt.Errorf("Collation '%s' is not registered", name)
+	}
+	indexDef.CollateMode = "custom"
+	indexDef.SortOrder = order
+	return indexDef, nil
+}
+
+func getCollation(name string) (string, bool) {
+	customCollations.RLock()
+	defer customCollations.RUnlock()
+	order, ok := customCollations.orders[strings.ToLower(name)]
+	return order, ok
+}
@@ -0,0 +1,48 @@
+package reindexer
+
+import (
+	"context"
+	"strings"
+)
+
+// concurrencyLimiter is a semaphore, which bounds count of simultaneous binding calls
+type concurrencyLimiter chan struct{}
+
+func newConcurrencyLimiter(count int) concurrencyLimiter {
+	return make(concurrencyLimiter, count)
+}
+
+// acquire waits for a free slot, or ctx is done
+func (l concurrencyLimiter) acquire(ctx context.Context) error {
+	select {
+	case l <- struct{}{}:
+		return nil
+	default:
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l concurrencyLimiter) release() {
+	<-l
+}
+
+// acquireNsSlot takes a slot of the namespace's limiter, set by WithMaxConcurrentQueries option.
+// Returned release function must be called after the end of the binding call
+func (db *reindexerImpl) acquireNsSlot(ctx context.Context, namespace string) (release func(), err error) {
+	l, ok := db.nsLimiters[strings.ToLower(namespace)]
+	if !ok {
+		return func() {}, nil
+	}
+	if err = l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return l.release, nil
+}
@@ -0,0 +1,102 @@
+package reindexer
+
+import (
+	"context"
+)
+
+// defaultNamespaceConfig is the server's default config of the namespace. It's used for the namespace without its own config,
+// if '#config' has no config for all the namespaces ('*')
+var defaultNamespaceConfig = DBNamespacesConfig{
+	JoinCacheMode:           "off",
+	StartCopyPolicyTxSize:   10000,
+	CopyPolicyMultiplier:    5,
+	TxSizeToAlwaysCopy:      100000,
+	OptimizationTimeout:     800,
+	OptimizationSortWorkers: 4,
+	WALSize:                 4000000,
+}
+
+// configItem returns item of '#config' system namespace by type. New item is returned, if there is no such item
+func (db *reindexerImpl) configItem(ctx context.Context, itemType string) (*DBConfigItem, error) {
+	item, err := db.query(ConfigNamespaceName).WhereString("type", EQ, itemType).ExecCtx(ctx).FetchOne()
+	if err == ErrNotFound {
+		return &DBConfigItem{Type: itemType}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.(*DBConfigItem), nil
+}
+
+// namespaceConfig returns index of the namespace's config in the config item, or -1 with the config, which is used for the namespace
+func namespaceConfig(citem *DBConfigItem, namespace string) (int, DBNamespacesConfig) {
+	cfg := defaultNamespaceConfig
+	if citem.Namespaces == nil {
+		return -1, cfg
+	}
+	for i, nsCfg := range *citem.Namespaces {
+		switch nsCfg.Namespace {
+		case namespace:
+			return i, nsCfg
+		case "*":
+			cfg = nsCfg
+		}
+	}
+	return -1, cfg
+}
+
+func (db *reindexerImpl) getNamespaceConfig(ctx context.Context, namespace string) (*DBNamespacesConfig, error) {
+	citem, err := db.configItem(ctx, "namespaces")
+	if err != nil {
+		return nil, err
+	}
+	_, cfg := namespaceConfig(citem, namespace)
+	cfg.Namespace = namespace
+	return &cfg, nil
+}
+
+// setNamespaceConfig reads config of the namespace, modifies it by update and writes the whole config item back
+func (db *reindexerImpl) setNamespaceConfig(ctx context.Context, namespace string, update func(cfg *DBNamespacesConfig)) error {
+	citem, err := db.configItem(ctx, "namespaces")
+	if err != nil {
+		return err
+	}
+	if citem.Namespaces == nil {
+		namespaces := make([]DBNamespacesConfig, 0, 1)
+		citem.Namespaces = &namespaces
+	}
+	i, cfg := namespaceConfig(citem, namespace)
+	if i < 0 {
+		// Namespace gets its own config, which is based on the config for all the namespaces
+		i = len(*citem.Namespaces)
+		*citem.Namespaces = append(*citem.Namespaces, cfg)
+	}
+	nsCfg := &(*citem.Namespaces)[i]
+	update(nsCfg)
+	nsCfg.Namespace = namespace
+	return db.upsert(ctx, ConfigNamespaceName, citem)
+}
+
+func (db *reindexerImpl) getProfilingConfig(ctx context.Context) (*DBProfilingConfig, error) {
+	citem, err := db.configItem(ctx, "profiling")
+	if err != nil {
+		return nil, err
+	}
+	if citem.Profiling == nil {
+		return &DBProfilingConfig{}, nil
+	}
+	return citem.Profiling, nil
+}
+
+// setProfilingConfig reads profiling config, modifies it by update and writes it back
+func (db *reindexerImpl) setProfilingConfig(ctx context.Context, update func(cfg *DBProfilingConfig)) error {
+	citem, err := db.configItem(ctx, "profiling")
+	if err != nil {
+		return err
+	}
+	if citem.Profiling == nil {
+		citem.Profiling = &DBProfilingConfig{}
+	}
+	update(citem.Profiling)
+	return db.upsert(ctx, ConfigNamespaceName, citem)
+}
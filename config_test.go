@@ -0,0 +1,46 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestNamespaceConfig(t *testing.T) {
+	db, srv := newMockDB(t, "nsconfig")
+	defer db.Close()
+
+	srv.SetResults(reindexer.ConfigNamespaceName, reindexer.DBConfigItem{
+		Type:       "namespaces",
+		Namespaces: &[]reindexer.DBNamespacesConfig{{Namespace: "*", WALSize: 100, JoinCacheMode: "on"}},
+	})
+	cfg, err := db.GetNamespaceConfig(testNs)
+	require.NoError(t, err)
+	assert.Equal(t, testNs, cfg.Namespace)
+	assert.Equal(t, int64(100), cfg.WALSize)
+
+	require.NoError(t, db.SetNamespaceConfig(testNs, func(cfg *reindexer.DBNamespacesConfig) {
+		cfg.Lazyload = true
+	}))
+	var calls []mock.Call
+	for _, c := range srv.CallsOf(mock.MethodModifyItem) {
+		if c.Namespace == reindexer.ConfigNamespaceName {
+			calls = append(calls, c)
+		}
+	}
+	require.Len(t, calls, 1)
+	var written reindexer.DBConfigItem
+	require.NoError(t, calls[0].DecodeItem(&written))
+	require.NotNil(t, written.Namespaces)
+	require.Len(t, *written.Namespaces, 2)
+	// Config of the namespace is based on the config of all the namespaces
+	nsCfg := (*written.Namespaces)[1]
+	assert.Equal(t, testNs, nsCfg.Namespace)
+	assert.True(t, nsCfg.Lazyload)
+	assert.Equal(t, int64(100), nsCfg.WALSize)
+	assert.Equal(t, "on", nsCfg.JoinCacheMode)
+}
@@ -217,7 +217,8 @@ private:
 				} else if constexpr (std::is_same_v<T, const Query>) {
 					auto params = longUpdDelLoggingParams_.load(std::memory_order_relaxed);
 					const bool isEnabled = params.thresholdUs >= 0 && !isSystemNamespaceNameFast(v.NsName());
-					auto statCalculator = QueryStatCalculator(long_actions::MakeLogger<enumVal>(v, std::move(params)), isEnabled);
+					auto statCalculator = QueryStatCalculator(
+						long_actions::MakeLogger<enumVal>(v, std::move(params), ctx.rdxContext.traceParent_), isEnabled);
 					auto locker = statCalculator.CreateLock(*ns, &NamespaceImpl::wLock, ctx.rdxContext);
 					calc.LockHit();
 					cg.Reset();
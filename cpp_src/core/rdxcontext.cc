@@ -6,6 +6,7 @@ namespace reindexer {
 RdxContext::RdxContext(RdxContext&& other) noexcept
 	: fromReplication_(other.fromReplication_),
 	  LSNs_(other.LSNs_),
+	  traceParent_(std::move(other.traceParent_)),
 	  holdStatus_(other.holdStatus_),
 	  activityPtr_(nullptr),
 	  cancelCtx_(other.cancelCtx_),
@@ -79,24 +80,34 @@ RdxActivityContext::Ward RdxContext::BeforeSelectLoop() const noexcept {
 
 RdxContext InternalRdxContext::CreateRdxContext(std::string_view query, ActivityContainer& activityContainer) const {
 	if (activityTracer_.empty() || query.empty()) {
-		return {(deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr), cmpl_};
+		RdxContext ctx{(deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr), cmpl_};
+		ctx.traceParent_ = traceParent_;
+		return ctx;
 	} else {
-		return {activityTracer_,
-				user_,
-				query,
-				activityContainer,
-				connectionId_,
-				(deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr),
-				cmpl_};
+		RdxContext ctx{activityTracer_,
+					   user_,
+					   query,
+					   activityContainer,
+					   connectionId_,
+					   (deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr),
+					   cmpl_};
+		ctx.traceParent_ = traceParent_;
+		return ctx;
 	}
 }
 
 RdxContext InternalRdxContext::CreateRdxContext(std::string_view query, ActivityContainer& activityContainer,
 												QueryResults& qresults) const {
-	if (activityTracer_.empty() || query.empty()) return {(deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr), cmpl_};
+	if (activityTracer_.empty() || query.empty()) {
+		RdxContext ctx{(deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr), cmpl_};
+		ctx.traceParent_ = traceParent_;
+		return ctx;
+	}
 	assertrx(!qresults.activityCtx_);
 	qresults.activityCtx_.emplace(activityTracer_, user_, query, activityContainer, connectionId_, true);
-	return RdxContext{&*(qresults.activityCtx_), (deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr), cmpl_};
+	RdxContext ctx{&*(qresults.activityCtx_), (deadlineCtx_.IsCancelable() ? &deadlineCtx_ : nullptr), cmpl_};
+	ctx.traceParent_ = traceParent_;
+	return ctx;
 }
 
 }  // namespace reindexer
@@ -116,6 +116,8 @@ public:
 
 	const bool fromReplication_;
 	LSNPair LSNs_;
+	/// W3C traceparent of the client's call. It's owned by the context, because the context may outlive the call (e.g. with completion)
+	std::string traceParent_;
 
 private:
 	enum { kHold, kPtr, kEmpty } holdStatus_;
@@ -133,21 +135,27 @@ class InternalRdxContext {
 public:
 	InternalRdxContext() noexcept {}
 	InternalRdxContext(RdxContext::Completion cmpl, RdxDeadlineContext ctx, std::string activityTracer, std::string user,
-					   int connectionId) noexcept
+					   int connectionId, std::string traceParent = {}) noexcept
 		: cmpl_(std::move(cmpl)),
 		  deadlineCtx_(std::move(ctx)),
 		  activityTracer_(std::move(activityTracer)),
 		  user_(std::move(user)),
-		  connectionId_(connectionId) {}
+		  connectionId_(connectionId),
+		  traceParent_(std::move(traceParent)) {}
 
 	InternalRdxContext WithCompletion(RdxContext::Completion cmpl) const noexcept {
-		return InternalRdxContext(std::move(cmpl), deadlineCtx_, activityTracer_, user_, connectionId_);
+		return InternalRdxContext(std::move(cmpl), deadlineCtx_, activityTracer_, user_, connectionId_, traceParent_);
 	}
 	InternalRdxContext WithTimeout(milliseconds timeout) const noexcept {
-		return InternalRdxContext(cmpl_, RdxDeadlineContext(timeout, deadlineCtx_.parent()), activityTracer_, user_, connectionId_);
+		return InternalRdxContext(cmpl_, RdxDeadlineContext(timeout, deadlineCtx_.parent()), activityTracer_, user_, connectionId_,
+								  traceParent_);
 	}
 	InternalRdxContext WithCancelParent(const IRdxCancelContext* parent) const noexcept {
-		return InternalRdxContext(cmpl_, RdxDeadlineContext(deadlineCtx_.deadline(), parent), activityTracer_, user_, connectionId_);
+		return InternalRdxContext(cmpl_, RdxDeadlineContext(deadlineCtx_.deadline(), parent), activityTracer_, user_, connectionId_,
+								  traceParent_);
+	}
+	InternalRdxContext WithTraceParent(std::string_view traceParent) const {
+		return InternalRdxContext(cmpl_, deadlineCtx_, activityTracer_, user_, connectionId_, std::string(traceParent));
 	}
 	InternalRdxContext WithActivityTracer(std::string_view activityTracer, std::string&& user, int connectionId = kNoConnectionId) const {
 		return activityTracer.empty()
@@ -155,16 +163,17 @@ public:
 				   : InternalRdxContext(cmpl_, deadlineCtx_,
 										activityTracer_.empty() ? std::string(activityTracer)
 																: std::string(activityTracer_).append("/").append(activityTracer),
-										std::move(user), connectionId);
+										std::move(user), connectionId, traceParent_);
 	}
 	InternalRdxContext WithContextParams(milliseconds timeout, std::string_view activityTracer, std::string&& user,
 										 int connectionId) const {
 		return activityTracer.empty()
-				   ? InternalRdxContext(cmpl_, RdxDeadlineContext(timeout, deadlineCtx_.parent()), activityTracer_, user_, connectionId_)
+				   ? InternalRdxContext(cmpl_, RdxDeadlineContext(timeout, deadlineCtx_.parent()), activityTracer_, user_, connectionId_,
+										traceParent_)
 				   : InternalRdxContext(cmpl_, RdxDeadlineContext(timeout, deadlineCtx_.parent()),
 										activityTracer_.empty() ? std::string(activityTracer)
 																: std::string(activityTracer_).append("/").append(activityTracer),
-										std::move(user), connectionId);
+										std::move(user), connectionId, traceParent_);
 	}
 	void SetActivityTracer(std::string&& activityTracer, std::string&& user, int connectionId = kNoConnectionId) noexcept {
 		activityTracer_ = std::move(activityTracer);
@@ -176,6 +185,8 @@ public:
 	RdxContext CreateRdxContext(std::string_view query, ActivityContainer&, QueryResults&) const;
 	RdxContext::Completion Compl() const { return cmpl_; }
 	bool NeedTraceActivity() const { return !activityTracer_.empty(); }
+	/// W3C traceparent of the client's span, which requested the call (empty, if it's not sent by the client)
+	const std::string& TraceParent() const noexcept { return traceParent_; }
 
 	static const int kNoConnectionId = -1;
 
@@ -185,6 +196,7 @@ private:
 	std::string activityTracer_;
 	std::string user_;
 	int connectionId_ = kNoConnectionId;
+	std::string traceParent_;
 };
 
 }  // namespace reindexer
@@ -261,6 +261,9 @@ public:
 	Reindexer WithContextParams(milliseconds timeout, std::string_view activityTracer, std::string user, int connectionId) const {
 		return Reindexer(impl_, ctx_.WithContextParams(timeout, activityTracer, std::move(user), connectionId));
 	}
+	/// Add trace context of the client's call, which is written to the slow queries log
+	/// @param traceParent - W3C traceparent of the client's span
+	Reindexer WithTraceParent(std::string_view traceParent) const { return Reindexer(impl_, ctx_.WithTraceParent(traceParent)); }
 
 	/// Set activityTracer to current DB
 	/// @param activityTracer - name of activity tracer
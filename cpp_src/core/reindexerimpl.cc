@@ -882,7 +882,7 @@ Error ReindexerImpl::Select(const Query& q, QueryResults& result, const Internal
 			std::move(hitter), std::chrono::microseconds(queriesThresholdUS),
 			queriesPerfStatsEnabled || configProvider_.GetSelectLoggingParams().thresholdUs >= 0,
 			long_actions::MakeLogger<QueryType::QuerySelect>(
-				q, isSystemNsRequest ? LongQueriesLoggingParams{} : configProvider_.GetSelectLoggingParams()));
+				q, isSystemNsRequest ? LongQueriesLoggingParams{} : configProvider_.GetSelectLoggingParams(), ctx.TraceParent()));
 
 		StatsLocker::StatsLockT statsSelectLck;
 		if (isSystemNsRequest) {
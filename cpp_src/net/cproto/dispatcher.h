@@ -26,6 +26,7 @@ struct RPCCall {
 	uint32_t seq;
 	Args args;
 	milliseconds execTimeout_;
+	std::string traceParent_;
 };
 
 struct ClientData {
@@ -170,6 +170,7 @@ ServerConnection::BaseConnT::ReadResT ServerConnection::onRead() {
 				ser = Serializer(uncompressed);
 			}
 			ctx.call->execTimeout_ = milliseconds(0);
+			ctx.call->traceParent_.clear();
 
 			ctx.call->args.Unpack(ser);
 
@@ -179,6 +180,9 @@ ServerConnection::BaseConnT::ReadResT ServerConnection::onRead() {
 				if (ctxArgs.size() > 0) {
 					ctx.call->execTimeout_ = milliseconds(int64_t(ctxArgs[0]));
 				}
+				if (ctxArgs.size() > 1 && ctxArgs[1].Type().Is<KeyValueType::String>()) {
+					ctx.call->traceParent_ = ctxArgs[1].As<std::string>();
+				}
 			}
 
 			handleRPC(ctx);
@@ -324,7 +328,7 @@ void ServerConnection::sendUpdates() {
 		return;
 	}
 
-	RPCCall callUpdate{kCmdUpdates, 0, {}, milliseconds(0)};
+	RPCCall callUpdate{kCmdUpdates, 0, {}, milliseconds(0), {}};
 	cproto::Context ctx{"", &callUpdate, this, {{}, {}}, false};
 	size_t len = 0;
 	Args args;
@@ -345,7 +349,7 @@ void ServerConnection::sendUpdates() {
 	try {
 		BaseConnT::wrBuf_.write(ser.DetachChunk());
 	} catch (...) {
-		RPCCall callLost{kCmdUpdates, 0, {}, milliseconds(0)};
+		RPCCall callLost{kCmdUpdates, 0, {}, milliseconds(0), {}};
 		cproto::Context ctxLost{"", &callLost, this, {{}, {}}, false};
 		{
 			std::lock_guard lck(updates_mtx_);
@@ -633,9 +633,11 @@ Reindexer RPCServer::getDB(cproto::Context &ctx, UserRole role) {
 			throw status;
 		}
 		if (rx_likely(db != nullptr)) {
-			return db->NeedTraceActivity()
-					   ? db->WithContextParams(ctx.call->execTimeout_, ctx.clientAddr, clientData->auth.Login(), clientData->connID)
-					   : db->WithTimeout(ctx.call->execTimeout_);
+			auto rx = db->NeedTraceActivity()
+						  ? db->WithContextParams(ctx.call->execTimeout_, ctx.clientAddr, clientData->auth.Login(), clientData->connID)
+						  : db->WithTimeout(ctx.call->execTimeout_);
+			// Client's trace context allows to join slow queries with distributed traces
+			return ctx.call->traceParent_.empty() ? rx : rx.WithTraceParent(ctx.call->traceParent_);
 		}
 	}
 	throw Error(errParams, "Database is not opened, you should open it first");
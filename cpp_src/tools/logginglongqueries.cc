@@ -68,6 +68,10 @@ static std::string_view describeDurationStorageIdx(DurationStorageIdx idx) {
 	throw Error(errLogic, "Unknown duration storage index");
 }
 
+static std::string describeTraceParent(std::string_view traceParent) {
+	return traceParent.empty() ? std::string() : fmt::sprintf("; traceparent - %s", traceParent);
+}
+
 template <typename Storage>
 static auto fillStorageInfo(std::ostringstream& os, const Storage& storage) {
 	os << "[slowlog] Waiting for a mutex lock:" << std::endl;
@@ -85,8 +89,8 @@ template <>
 void Logger<QueryEnum2Type<QueryType::QuerySelect>>::Dump(std::chrono::microseconds time) {
 	if (wrapper_.loggingParams.thresholdUs >= 0 && time.count() > wrapper_.loggingParams.thresholdUs) {
 		std::ostringstream os;
-		os << fmt::sprintf("[slowlog] Long execution query: sql - %s; (%dus)\n", wrapper_.query.GetSQL(wrapper_.loggingParams.normalized),
-						   time.count());
+		os << fmt::sprintf("[slowlog] Long execution query: sql - %s; (%dus)%s\n", wrapper_.query.GetSQL(wrapper_.loggingParams.normalized),
+						   time.count(), describeTraceParent(wrapper_.traceParent));
 
 		if (wrapper_.durationStorage) {
 			os << "[slowlog] Explain statistics:\n";
@@ -103,8 +107,8 @@ template <>
 void Logger<QueryEnum2Type<QueryType::QueryUpdate>>::Dump(std::chrono::microseconds time) {
 	if (wrapper_.loggingParams.thresholdUs >= 0 && time.count() > wrapper_.loggingParams.thresholdUs) {
 		std::ostringstream os;
-		os << fmt::sprintf("[slowlog] Long execution query: sql - %s; (%dus)\n", wrapper_.query.GetSQL(wrapper_.loggingParams.normalized),
-						   time.count());
+		os << fmt::sprintf("[slowlog] Long execution query: sql - %s; (%dus)%s\n", wrapper_.query.GetSQL(wrapper_.loggingParams.normalized),
+						   time.count(), describeTraceParent(wrapper_.traceParent));
 		fillStorageInfo(os, wrapper_.durationStorage);
 		logPrint(LogWarning, os.str().data());
 	}
@@ -113,8 +117,8 @@ template <>
 void Logger<QueryEnum2Type<QueryType::QueryDelete>>::Dump(std::chrono::microseconds time) {
 	if (wrapper_.loggingParams.thresholdUs >= 0 && time.count() > wrapper_.loggingParams.thresholdUs) {
 		std::ostringstream os;
-		os << fmt::sprintf("[slowlog] Long execution query: sql - %s; (%dus)\n", wrapper_.query.GetSQL(wrapper_.loggingParams.normalized),
-						   time.count());
+		os << fmt::sprintf("[slowlog] Long execution query: sql - %s; (%dus)%s\n", wrapper_.query.GetSQL(wrapper_.loggingParams.normalized),
+						   time.count(), describeTraceParent(wrapper_.traceParent));
 		fillStorageInfo(os, wrapper_.durationStorage);
 		logPrint(LogWarning, os.str().data());
 	}
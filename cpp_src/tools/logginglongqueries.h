@@ -72,6 +72,7 @@ struct LockDurationStorage {
 struct QueryParams {
 	const Query& query;
 	LongQueriesLoggingParams loggingParams;
+	std::string traceParent = {};
 };
 
 struct TransactionParams {
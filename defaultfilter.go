@@ -0,0 +1,71 @@
+package reindexer
+
+import (
+	"context"
+)
+
+// DefaultFilter adds conditions to the queries of the namespace (see NamespaceOptions.WithDefaultFilter).
+// ctx is the context of the query's execution, so the conditions may depend on it (e.g. tenant of the request).
+// Filter may add no conditions
+type DefaultFilter func(ctx context.Context, q *Query)
+
+// addDefaultFilters adds default filters of the namespaces to the query and to its joined and merged queries.
+// Filters are added once, so it's safe to call it several times for the same query
+func (q *Query) addDefaultFilters(ctx context.Context) {
+	q.addOwnDefaultFilters(ctx)
+	for _, sq := range q.joinQueries {
+		sq.addOwnDefaultFilters(ctx)
+	}
+	for _, mq := range q.mergedQueries {
+		mq.addOwnDefaultFilters(ctx)
+		for _, sq := range mq.joinQueries {
+			sq.addOwnDefaultFilters(ctx)
+		}
+	}
+}
+
+// addOwnDefaultFilters adds default filters of the query's namespace to the query. Conditions of each filter are enclosed in brackets,
+// so they are combined with the query's conditions by AND
+func (q *Query) addOwnDefaultFilters(ctx context.Context) {
+	if q.withoutDefaults || q.defaultsAdded || q.db == nil {
+		return
+	}
+	q.defaultsAdded = true
+	ns, err := q.db.getNS(q.Namespace)
+	if err != nil {
+		return
+	}
+	q.markFiltersPos()
+	for _, filter := range ns.opts.defaultFilters {
+		pos, queriesCount, nextOp := len(q.ser.Bytes()), q.queriesCount, q.nextOp
+		q.OpenBracket()
+		filter(ctx, q)
+		if q.queriesCount == queriesCount+1 {
+			// Empty brackets are not sent
+			q.ser.Truncate(pos)
+			q.queriesCount, q.nextOp = queriesCount, nextOp
+			q.opennedBrackets = q.opennedBrackets[:len(q.opennedBrackets)-1]
+			continue
+		}
+		q.CloseBracket()
+	}
+}
+
+// markFiltersPos saves state of the serializer before the first filter, which is added on execution (default and soft delete filters),
+// so the filters may be removed by resetFilters
+func (q *Query) markFiltersPos() {
+	if q.filtersPos == 0 {
+		q.filtersPos, q.filtersQueriesCount, q.filtersNextOp = len(q.ser.Bytes()), q.queriesCount, q.nextOp
+	}
+}
+
+// resetFilters removes the filters, which were added on execution, from the query, so they are added again on the next execution
+func (q *Query) resetFilters() {
+	if q.filtersPos != 0 {
+		q.ser.Truncate(q.filtersPos)
+		q.queriesCount, q.nextOp = q.filtersQueriesCount, q.filtersNextOp
+		q.filtersPos = 0
+	}
+	q.defaultsAdded = false
+	q.softDeleteAdded = false
+}
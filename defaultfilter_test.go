@@ -0,0 +1,50 @@
+package reindexer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+type testTenantKey struct{}
+
+func TestDefaultFilter(t *testing.T) {
+	db, srv := mock.NewDB("default_filter")
+	defer db.Close()
+	opts := reindexer.DefaultNamespaceOptions().WithDefaultFilter(func(ctx context.Context, q *reindexer.Query) {
+		if tenant, ok := ctx.Value(testTenantKey{}).(string); ok {
+			q.WhereString("name", reindexer.EQ, tenant)
+		}
+	})
+	require.NoError(t, db.OpenNamespace(testNs, opts, testItem{}))
+
+	lastQuery := func(method string) []byte {
+		calls := srv.CallsOf(method)
+		require.NotEmpty(t, calls)
+		return calls[len(calls)-1].Query
+	}
+	tenantCtx := context.WithValue(context.Background(), testTenantKey{}, "tenant_42")
+
+	_, err := db.Query(testNs).WhereInt("id", reindexer.GT, 1).ExecCtx(tenantCtx).FetchAll()
+	require.NoError(t, err)
+	assert.Contains(t, string(lastQuery(mock.MethodSelectQuery)), "tenant_42")
+
+	_, err = db.Query(testNs).WhereInt("id", reindexer.GT, 1).WithoutDefaults().ExecCtx(tenantCtx).FetchAll()
+	require.NoError(t, err)
+	unfiltered := lastQuery(mock.MethodSelectQuery)
+	assert.NotContains(t, string(unfiltered), "tenant_42")
+
+	// Filter without conditions doesn't change the query
+	_, err = db.Query(testNs).WhereInt("id", reindexer.GT, 1).Exec().FetchAll()
+	require.NoError(t, err)
+	assert.Equal(t, unfiltered, lastQuery(mock.MethodSelectQuery))
+
+	_, err = db.Query(testNs).WhereInt("id", reindexer.GT, 1).DeleteCtx(tenantCtx)
+	require.NoError(t, err)
+	assert.Contains(t, string(lastQuery(mock.MethodDeleteQuery)), "tenant_42")
+}
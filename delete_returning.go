@@ -0,0 +1,171 @@
+package reindexer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/restream/reindexer/v3/bindings"
+	otelattr "go.opentelemetry.io/otel/attribute"
+)
+
+// deleteReturning deletes the items, which match the query, and returns iterator over the deleted items.
+// Server returns ids of the deleted items only, so the items are selected first and then deleted by their primary keys
+// together with the query's conditions. Items, which are inserted or modified to match the query after the select, are not deleted
+func (db *reindexerImpl) deleteReturning(ctx context.Context, q *Query) *Iterator {
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Query.DeleteReturning", otelattr.String("rx.ns", q.Namespace))
+		defer span.End()
+	}
+
+	ns, err := db.getNS(q.Namespace)
+	if err != nil {
+		q.close()
+		return errIterator(err)
+	}
+	if ns.opts.softDeleteField != "" && !q.withDeleted {
+		// Soft deleted items are returned by the update query. Query is closed by the iterator
+		q.SetExpression(ns.opts.softDeleteField, "now(sec)")
+		return db.updateQuery(ctx, q)
+	}
+
+	defer q.close()
+	objects, err := db.deleteReturningObjects(ctx, ns, q)
+	if err != nil {
+		return errIterator(err)
+	}
+	return newObjectsIterator(ctx, db, ns.name, objects, len(objects))
+}
+
+// deleteReturningObjects deletes the items, which match the query, and returns the deleted items. Deleted items are written
+// to history of the namespace. Query is not closed
+func (db *reindexerImpl) deleteReturningObjects(ctx context.Context, ns *reindexerNamespace, q *Query) ([]interface{}, error) {
+	if len(q.mergedQueries) != 0 {
+		return nil, bindings.NewError("rq: DeleteReturning does not support merged queries", ErrCodeParams)
+	}
+	q.addDefaultFilters(ctx)
+	d, err := q.toDSL()
+	if err != nil {
+		return nil, err
+	}
+	d.Aggregations = nil
+	d.SelectFilter = nil
+	d.ReqTotal = ""
+
+	objects, err := db.selectDSL(ctx, d)
+	if err != nil || len(objects) == 0 {
+		return nil, err
+	}
+
+	// Conditions of the query are kept, so the items, which don't match the query anymore, are not deleted
+	dd := *d
+	dd.Sort, dd.Limit, dd.Offset = nil, nil, nil
+	if len(d.Filters) != 0 {
+		dd.Filters = []dslFilter{{Filters: d.Filters}}
+	}
+	dq, err := db.queryFromDSLCopy(&dd)
+	if err != nil {
+		return nil, err
+	}
+	dq.WithoutDefaults().WithDeleted()
+	dq.withoutHistory = true
+	if err = ns.wherePks(dq, objects); err != nil {
+		dq.close()
+		return nil, err
+	}
+	history, err := db.beginHistory(ctx, ns)
+	if err != nil {
+		dq.close()
+		return nil, err
+	}
+	count, err := dq.DeleteCtx(ctx)
+	if err != nil {
+		history.rollback()
+		return nil, err
+	}
+
+	if count < len(objects) {
+		// Some of the items were deleted or modified concurrently. They are excluded by the select of the remaining ones
+		if objects, err = db.excludeExisting(ctx, ns, objects); err != nil {
+			history.rollback()
+			return nil, err
+		}
+	}
+	events := make([]TxEvent, 0, len(objects))
+	for _, item := range objects {
+		events = append(events, ns.newTxEvent(item, nil, modeDelete))
+	}
+	return objects, history.commit(events)
+}
+
+// queryFromDSLCopy builds the query from the DSL, whose values are converted to the query's values by JSON round trip
+func (db *reindexerImpl) queryFromDSLCopy(d *dslQuery) (*Query, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var jd dslQuery
+	if err = decodeDSL(data, &jd); err != nil {
+		return nil, err
+	}
+	return db.queryFromDSL(&jd)
+}
+
+// selectDSL returns the items, which match the DSL. Default filters and soft delete filter are expected to be in the DSL already
+func (db *reindexerImpl) selectDSL(ctx context.Context, d *dslQuery) ([]interface{}, error) {
+	q, err := db.queryFromDSLCopy(d)
+	if err != nil {
+		return nil, err
+	}
+	it := q.WithoutDefaults().WithDeleted().ExecCtx(ctx)
+	defer it.Close()
+	var objects []interface{}
+	for it.Next() {
+		objects = append(objects, it.Object())
+	}
+	return objects, it.Error()
+}
+
+// wherePks adds the condition, which matches the items by their primary keys
+func (ns *reindexerNamespace) wherePks(q *Query, items []interface{}) error {
+	q.OpenBracket()
+	for i, item := range items {
+		if i != 0 {
+			q.Or()
+		}
+		q.OpenBracket()
+		if err := ns.wherePk(q, item); err != nil {
+			return err
+		}
+		q.CloseBracket()
+	}
+	q.CloseBracket()
+	return nil
+}
+
+// excludeExisting returns the items, which are not found by their primary keys
+func (db *reindexerImpl) excludeExisting(ctx context.Context, ns *reindexerNamespace, items []interface{}) ([]interface{}, error) {
+	q := db.query(ns.name).WithoutDefaults().WithDeleted()
+	if err := ns.wherePks(q, items); err != nil {
+		q.close()
+		return nil, err
+	}
+	it := q.ExecCtx(ctx)
+	defer it.Close()
+	existing := make(map[string]bool)
+	for it.Next() {
+		if key, err := ns.pkKey(it.Object()); err == nil {
+			existing[key] = true
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	deleted := items[:0]
+	for _, item := range items {
+		if key, err := ns.pkKey(item); err != nil || !existing[key] {
+			deleted = append(deleted, item)
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,42 @@
+package reindexer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestDeleteReturning(t *testing.T) {
+	db, srv := newMockDB(t, "deletereturning")
+	defer db.Close()
+
+	srv.SetResults(testNs, testItem{ID: 1, Name: "first"}, testItem{ID: 2, Name: "second"})
+	it := db.Query(testNs).WhereInt("id", reindexer.GT, 0).DeleteReturning(context.Background())
+	defer it.Close()
+	require.NoError(t, it.Error())
+	var names []string
+	for it.Next() {
+		names = append(names, it.Object().(*testItem).Name)
+	}
+	assert.Equal(t, []string{"first", "second"}, names)
+	assert.Len(t, srv.CallsOf(mock.MethodDeleteQuery), 1)
+
+	// Nothing is deleted, if nothing matches the query
+	srv.Reset()
+	it = db.Query(testNs).WhereInt("id", reindexer.GT, 0).DeleteReturning(context.Background())
+	require.NoError(t, it.Error())
+	assert.Equal(t, 0, it.Count())
+	it.Close()
+	assert.Empty(t, srv.CallsOf(mock.MethodDeleteQuery))
+
+	tx, err := db.BeginTx(testNs)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	it = tx.Query().DeleteReturning(context.Background())
+	assert.Error(t, it.Error())
+}
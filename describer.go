@@ -174,38 +174,51 @@ type NamespaceMemStat struct {
 	} `json:"replication"`
 
 	// Indexes memory statistic
-	Indexes []struct {
-		// Name of index. There are special index with name `-tuple`. It's stores original document's json structure with non indexe fields
-		Name string `json:"name"`
-		// Count of unique keys values stored in index
-		UniqKeysCount int64 `json:"unique_keys_count"`
-		// Total memory consumption of documents's data, holded by index
-		DataSize int64 `json:"data_size"`
-		// Total memory consumption of SORT statement and `GT`, `LT` conditions optimized structures. Applicabe only to `tree` indexes
-		SortOrdresSize int64 `json:"sort_orders_size"`
-		// Total memory consumption of reverse index vectors. For `store` ndexes always 0
-		IDSetPlainSize int64 `json:"idset_plain_size"`
-		// Total memory consumption of reverse index b-tree structures. For `dense` and `store` indexes always 0
-		IDSetBTreeSize int64 `json:"idset_btree_size"`
-		// Total memory consumption of fulltext search structures
-		FulltextSize int64 `json:"fulltext_size"`
-		// Idset cache stats. Stores merged reverse index results of SELECT field IN(...) by IN(...) keys
-		IDSetCache CacheMemStat `json:"idset_cache"`
-		// Updates count, pending in index updates tracker
-		TrackedUpdatesCount int64 `json:"tracked_updates_count"`
-		// Buckets count in index updates tracker map
-		TrackedUpdatesBuckets int64 `json:"tracked_updates_buckets"`
-		// Updates tracker map size in bytes
-		TrackedUpdatesSize int64 `json:"tracked_updates_size"`
-		// Updates tracker map overflow (number of elements, stored outside of the main buckets)
-		TrackedUpdatesOverflow int64 `json:"tracked_updates_overflow"`
-	} `json:"indexes"`
+	Indexes []IndexMemStat `json:"indexes"`
 	// Join cache stats. Stores results of selects to right table by ON condition
 	JoinCache CacheMemStat `json:"join_cache"`
 	// Query cache stats. Stores results of SELECT COUNT(*) by Where conditions
 	QueryCache CacheMemStat `json:"query_cache"`
 }
 
+// IndexMemStat information about memory consumption of the namespace's index
+type IndexMemStat struct {
+	// Name of index. There are special index with name `-tuple`. It's stores original document's json structure with non indexe fields
+	Name string `json:"name"`
+	// Count of unique keys values stored in index
+	UniqKeysCount int64 `json:"unique_keys_count"`
+	// Total memory consumption of documents's data, holded by index
+	DataSize int64 `json:"data_size"`
+	// Total memory consumption of SORT statement and `GT`, `LT` conditions optimized structures. Applicabe only to `tree` indexes
+	SortOrdresSize int64 `json:"sort_orders_size"`
+	// Total memory consumption of reverse index vectors. For `store` ndexes always 0
+	IDSetPlainSize int64 `json:"idset_plain_size"`
+	// Total memory consumption of reverse index b-tree structures. For `dense` and `store` indexes always 0
+	IDSetBTreeSize int64 `json:"idset_btree_size"`
+	// Total memory consumption of fulltext search structures
+	FulltextSize int64 `json:"fulltext_size"`
+	// Idset cache stats. Stores merged reverse index results of SELECT field IN(...) by IN(...) keys
+	IDSetCache CacheMemStat `json:"idset_cache"`
+	// Updates count, pending in index updates tracker
+	TrackedUpdatesCount int64 `json:"tracked_updates_count"`
+	// Buckets count in index updates tracker map
+	TrackedUpdatesBuckets int64 `json:"tracked_updates_buckets"`
+	// Updates tracker map size in bytes
+	TrackedUpdatesSize int64 `json:"tracked_updates_size"`
+	// Updates tracker map overflow (number of elements, stored outside of the main buckets)
+	TrackedUpdatesOverflow int64 `json:"tracked_updates_overflow"`
+}
+
+// Index returns memory statistics of the index by name, or nil, if there is no such index
+func (s *NamespaceMemStat) Index(name string) *IndexMemStat {
+	for i := range s.Indexes {
+		if strings.EqualFold(s.Indexes[i].Name, name) {
+			return &s.Indexes[i]
+		}
+	}
+	return nil
+}
+
 // PerfStat is information about different reinexer's objects performance statistics
 type PerfStat struct {
 	// Total count of queries to this object
@@ -271,6 +284,28 @@ type NamespacePerfStat struct {
 	Selects PerfStat `json:"selects"`
 	// Performance statistics for transactions
 	Transactions TxPerfStat `json:"transactions"`
+	// Performance statistics of the namespace's indexes
+	Indexes []IndexPerfStat `json:"indexes"`
+}
+
+// IndexPerfStat is information about performance statistics of the namespace's index
+type IndexPerfStat struct {
+	// Name of index
+	Name string `json:"name"`
+	// Performance statistics for selects by the index
+	Selects PerfStat `json:"selects"`
+	// Performance statistics for commits of the index
+	Commits PerfStat `json:"commits"`
+}
+
+// Index returns performance statistics of the index by name, or nil, if there is no such index
+func (s *NamespacePerfStat) Index(name string) *IndexPerfStat {
+	for i := range s.Indexes {
+		if strings.EqualFold(s.Indexes[i].Name, name) {
+			return &s.Indexes[i]
+		}
+	}
+	return nil
 }
 
 // ClientConnectionStat is information about client connection
@@ -538,7 +573,7 @@ func (db *Reindexer) GetNamespacesMemStat() ([]*NamespaceMemStat, error) {
 	return result, nil
 }
 
-// GetNamespaceMemStat makes a 'SELECT * FROM #memstat' query to database.
+// GetNamespaceMemStat makes a 'SELECT * FROM #memstats' query to database.
 // Return NamespaceMemStat results, error
 func (db *Reindexer) GetNamespaceMemStat(namespace string) (*NamespaceMemStat, error) {
 	desc, err := db.Query(MemstatsNamespaceName).Where("name", EQ, namespace).ExecCtx(db.ctx).FetchOne()
@@ -547,3 +582,33 @@ func (db *Reindexer) GetNamespaceMemStat(namespace string) (*NamespaceMemStat, e
 	}
 	return desc.(*NamespaceMemStat), nil
 }
+
+// GetNamespacesPerfStat makes a 'SELECT * FROM #perfstats' query to database.
+// Return NamespacePerfStat results, error
+func (db *Reindexer) GetNamespacesPerfStat() ([]*NamespacePerfStat, error) {
+	result := []*NamespacePerfStat{}
+
+	stats, err := db.Query(PerfstatsNamespaceName).ExecCtx(db.ctx).FetchAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stat := range stats {
+		nsstat, ok := stat.(*NamespacePerfStat)
+		if ok {
+			result = append(result, nsstat)
+		}
+	}
+
+	return result, nil
+}
+
+// GetNamespacePerfStat makes a 'SELECT * FROM #perfstats' query to database.
+// Return NamespacePerfStat results, error. Statistics are collected by the server, only if perfstats are enabled in profiling config
+func (db *Reindexer) GetNamespacePerfStat(namespace string) (*NamespacePerfStat, error) {
+	stat, err := db.Query(PerfstatsNamespaceName).Where("name", EQ, namespace).ExecCtx(db.ctx).FetchOne()
+	if err != nil {
+		return nil, err
+	}
+	return stat.(*NamespacePerfStat), nil
+}
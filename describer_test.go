@@ -0,0 +1,38 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+)
+
+func TestNamespacePerfStat(t *testing.T) {
+	db, srv := newMockDB(t, "perfstat")
+	defer db.Close()
+
+	stat := reindexer.NamespacePerfStat{Name: testNs, Indexes: []reindexer.IndexPerfStat{{Name: "id"}, {Name: "name"}}}
+	stat.Selects.LastSecAvgLatencyUs = 120
+	stat.Indexes[1].Commits.MaxLatencyUs = 15
+	srv.SetResults(reindexer.PerfstatsNamespaceName, stat)
+
+	perf, err := db.GetNamespacePerfStat(testNs)
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), perf.Selects.LastSecAvgLatencyUs)
+	require.NotNil(t, perf.Index("Name"))
+	assert.Equal(t, int64(15), perf.Index("Name").Commits.MaxLatencyUs)
+	assert.Nil(t, perf.Index("tags"))
+
+	stats, err := db.GetNamespacesPerfStat()
+	require.NoError(t, err)
+	assert.Len(t, stats, 1)
+
+	mem := reindexer.NamespaceMemStat{Name: testNs, Indexes: []reindexer.IndexMemStat{{Name: "id", DataSize: 64}}}
+	srv.SetResults(reindexer.MemstatsNamespaceName, mem)
+	memStat, err := db.GetNamespaceMemStat(testNs)
+	require.NoError(t, err)
+	require.NotNil(t, memStat.Index("id"))
+	assert.Equal(t, int64(64), memStat.Index("id").DataSize)
+}
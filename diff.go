@@ -0,0 +1,149 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// ResultsSnapshot - state of query results, which is used by Query.Diff to find changes of the results
+type ResultsSnapshot struct {
+	items map[string]snapshotItem
+}
+
+type snapshotItem struct {
+	item interface{}
+	// Internal version of the item. -1, if unknown
+	version int
+}
+
+// Len returns count of items in the snapshot
+func (s *ResultsSnapshot) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.items)
+}
+
+// ResultsDiff - changes of query results since the previous snapshot
+type ResultsDiff struct {
+	// Items, which were not present in the previous snapshot
+	Added []interface{}
+	// Items, which were modified since the previous snapshot
+	Changed []interface{}
+	// Items of the previous snapshot, which are not present in the results anymore, ordered by primary key
+	Removed []interface{}
+	// Snapshot of the current results. Should be passed to the next Diff call
+	Snapshot *ResultsSnapshot
+}
+
+// Diff executes query and compares its results with the previous snapshot (nil snapshot means empty results).
+// Items are matched by primary key. Item is treated as changed, if its internal version differs
+// (or, if versions are not available, if decoded items are not equal).
+// Query can't contain merged queries. Query is executed with AllowUnsafe(false), so the items are not shared with object cache
+// and may be kept by the caller
+func (q *Query) Diff(prev *ResultsSnapshot) (*ResultsDiff, error) {
+	return q.DiffCtx(context.Background(), prev)
+}
+
+// DiffCtx executes query and compares its results with the previous snapshot. See Diff for details
+func (q *Query) DiffCtx(ctx context.Context, prev *ResultsSnapshot) (*ResultsDiff, error) {
+	if q.root != nil {
+		q = q.root
+	}
+	if len(q.mergedQueries) != 0 {
+		return nil, bindings.NewError("rq: Diff does not support merged queries", ErrCodeParams)
+	}
+	ns, err := q.db.getNS(q.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(ns.pk) == 0 {
+		return nil, ErrNoPK
+	}
+
+	it := q.AllowUnsafe(false).ExecCtx(ctx)
+	defer it.Close()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	diff := &ResultsDiff{Snapshot: &ResultsSnapshot{items: make(map[string]snapshotItem, it.Count())}}
+	for it.Next() {
+		cur := snapshotItem{item: it.Object(), version: it.current.version}
+		key, err := ns.pkKey(cur.item)
+		if err != nil {
+			return nil, err
+		}
+		diff.Snapshot.items[key] = cur
+
+		old, found := prev.get(key)
+		switch {
+		case !found:
+			diff.Added = append(diff.Added, cur.item)
+		case old.version >= 0 && cur.version >= 0:
+			if old.version != cur.version {
+				diff.Changed = append(diff.Changed, cur.item)
+			}
+		case !reflect.DeepEqual(old.item, cur.item):
+			diff.Changed = append(diff.Changed, cur.item)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	if prev != nil {
+		for key, old := range prev.items {
+			if _, found := diff.Snapshot.items[key]; !found {
+				diff.Removed = append(diff.Removed, old.item)
+			}
+		}
+		sort.Slice(diff.Removed, func(i, j int) bool { return ns.pkLess(diff.Removed[i], diff.Removed[j]) })
+	}
+	return diff, nil
+}
+
+func (s *ResultsSnapshot) get(key string) (snapshotItem, bool) {
+	if s == nil {
+		return snapshotItem{}, false
+	}
+	item, ok := s.items[key]
+	return item, ok
+}
+
+// pkLess orders the items by values of their primary key's fields. Values of different types are compared as strings
+func (ns *reindexerNamespace) pkLess(a, b interface{}) bool {
+	pa, pb := ns.pkValues(a), ns.pkValues(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		c, err := compareValues(pa[i], pb[i])
+		if err != nil {
+			c = strings.Compare(fmt.Sprint(pa[i]), fmt.Sprint(pb[i]))
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// pkKey returns string, which uniquely identifies primary key of the item
+func (ns *reindexerNamespace) pkKey(item interface{}) (string, error) {
+	var sb strings.Builder
+	for i := range ns.pk {
+		v, ok := ns.pk[i].value(item)
+		if v = reflect.Indirect(v); !ok || !v.IsValid() {
+			return "", ErrNoPK
+		}
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.Quote(fmt.Sprint(v.Interface())))
+	}
+	return sb.String(), nil
+}
@@ -20,6 +20,8 @@ however some of the fields are not implemented or may have a little bit differen
 - 'value' may be json array, scalar or null (in the original DSL it is always must be array)
 - if 'value' is 'null' and 'cond' is not 'any'/'empty', then this filter will be skipped
 
+Queries in the standard DSL format (including merged queries, select filters and functions) may be converted by Reindexer.QueryFromDSL.
+
 Usage:
 rxDB := reindexer.NewReindex(...)
 jsonDSL := "{...}" // Contains JSON string, corresponding the DSL's format
@@ -65,6 +67,9 @@ type Filter struct {
 	Cond    string
 	Value   interface{}
 	Filters []Filter `json:"Filters,omitempty"`
+	// Fields for comparison of two fields of the item. Field and Value must be empty
+	FirstField  string
+	SecondField string
 }
 
 type JoinOnCondition struct {
@@ -101,6 +106,9 @@ type filter struct {
 	Cond    string     `json:"cond,omitempty"`
 	Value   value      `json:"value,omitempty"`
 	Filters []filter   `json:"filters,omitempty"`
+
+	FirstField  string `json:"first_field,omitempty"`
+	SecondField string `json:"second_field,omitempty"`
 }
 
 type value struct {
@@ -145,6 +153,8 @@ func (f *Filter) fillFilter(flt *filter) error {
 	f.Op = flt.Op
 	f.Cond = flt.Cond
 	f.Field = flt.Field
+	f.FirstField = flt.FirstField
+	f.SecondField = flt.SecondField
 	if flt.Joined != nil {
 		f.Joined = &JoinQuery{Namespace: flt.Joined.Namespace,
 			Type: flt.Joined.Type, Sort: flt.Joined.Sort,
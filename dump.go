@@ -0,0 +1,222 @@
+package reindexer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelattr "go.opentelemetry.io/otel/attribute"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// Namespace dump is NDJSON stream: each line is JSON object with the record of the dump, which type is set by "type" field.
+// Records are written in the following order:
+//
+//	{"type":"header","format":"reindexer-ns-dump","version":1,"namespace":"items","indexes":[{"name":"id",...}]}
+//	{"type":"meta","key":"key","value":"<base64 of the meta's data>"}
+//	{"type":"item","item":{"id":1,...}}
+//	{"type":"footer","items":1,"meta":1}
+//
+// Footer contains count of the meta and item records, so the truncated dump is detected on restore
+const (
+	dumpFormat  = "reindexer-ns-dump"
+	dumpVersion = 1
+	// dumpPageSize is count of the items, which are requested by one query of DumpNamespace
+	dumpPageSize = 1000
+)
+
+const (
+	dumpRecordHeader = "header"
+	dumpRecordMeta   = "meta"
+	dumpRecordItem   = "item"
+	dumpRecordFooter = "footer"
+)
+
+// dumpRecord is the line of the namespace dump
+type dumpRecord struct {
+	Type      string          `json:"type"`
+	Format    string          `json:"format,omitempty"`
+	Version   int             `json:"version,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Indexes   []IndexDef      `json:"indexes,omitempty"`
+	Key       string          `json:"key,omitempty"`
+	Value     []byte          `json:"value,omitempty"`
+	Item      json.RawMessage `json:"item,omitempty"`
+	Items     int             `json:"items,omitempty"`
+	Meta      int             `json:"meta,omitempty"`
+}
+
+func (db *reindexerImpl) dumpNamespace(ctx context.Context, namespace string, w io.Writer) (err error) {
+	namespace = strings.ToLower(namespace)
+
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.DumpNamespace", otelattr.String("rx.ns", namespace))
+		defer span.End()
+	}
+
+	if db.promMetrics != nil {
+		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("DumpNamespace", namespace)).ObserveDuration()
+	}
+	defer db.startActivity(ctx, "DumpNamespace", namespace)()
+
+	if _, err = db.getNS(namespace); err != nil {
+		return err
+	}
+	desc, err := db.describeNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	header := dumpRecord{Type: dumpRecordHeader, Format: dumpFormat, Version: dumpVersion, Namespace: namespace}
+	for _, index := range desc.Indexes {
+		header.Indexes = append(header.Indexes, index.IndexDef)
+	}
+	if err = enc.Encode(header); err != nil {
+		return err
+	}
+
+	// Meta is dumped only by the bindings, which are able to enumerate its keys
+	var keys []string
+	if enumerator, ok := db.binding.(bindings.RawBindingMetaEnumerator); ok {
+		if keys, err = enumerator.EnumMeta(ctx, namespace); err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		data, err := db.getMeta(ctx, namespace, key)
+		if err != nil {
+			return err
+		}
+		if err = enc.Encode(dumpRecord{Type: dumpRecordMeta, Key: key, Value: data}); err != nil {
+			return err
+		}
+	}
+
+	items := 0
+	for {
+		// Items are requested by pages in the order of primary key, so the whole namespace is not held in memory
+		it := db.query(namespace).WithoutDefaults().WithDeleted().SortMulti(nil, true).Offset(items).Limit(dumpPageSize).ExecToJsonCtx(ctx)
+		count := 0
+		for err == nil && it.Next() {
+			err = enc.Encode(dumpRecord{Type: dumpRecordItem, Item: it.JSON()})
+			count++
+		}
+		if err == nil {
+			err = it.Error()
+		}
+		it.Close()
+		if err != nil {
+			return err
+		}
+		items += count
+		if count < dumpPageSize {
+			break
+		}
+	}
+
+	if err = enc.Encode(dumpRecord{Type: dumpRecordFooter, Items: items, Meta: len(keys)}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (db *reindexerImpl) restoreNamespace(ctx context.Context, namespace string, r io.Reader) (err error) {
+	namespace = strings.ToLower(namespace)
+
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.RestoreNamespace", otelattr.String("rx.ns", namespace))
+		defer span.End()
+	}
+
+	if db.promMetrics != nil {
+		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("RestoreNamespace", namespace)).ObserveDuration()
+	}
+	defer db.startActivity(ctx, "RestoreNamespace", namespace)()
+
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return err
+	}
+	desc, err := db.describeNamespace(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var header dumpRecord
+	if err = dec.Decode(&header); err != nil {
+		return dumpError("can't read header", err)
+	}
+	if header.Type != dumpRecordHeader || header.Format != dumpFormat {
+		return bindings.NewError("rq: Invalid namespace dump: header is not found", ErrCodeParams)
+	}
+	if header.Version > dumpVersion {
+		return bindings.NewError(fmt.Sprintf("rq: Unsupported version %d of namespace dump", header.Version), ErrCodeParams)
+	}
+
+	// Existing indexes of the namespace are kept as is
+	existing := make(map[string]bool, len(desc.Indexes))
+	for _, index := range desc.Indexes {
+		existing[strings.ToLower(index.Name)] = true
+	}
+	for _, index := range header.Indexes {
+		if existing[strings.ToLower(index.Name)] {
+			continue
+		}
+		if err = db.addIndex(ctx, namespace, index); err != nil {
+			return err
+		}
+	}
+
+	items, meta := 0, 0
+	defer func() {
+		if items != 0 {
+			db.queryCache.invalidate(ns.name)
+		}
+	}()
+	for {
+		var rec dumpRecord
+		if err = dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return dumpError("can't read record", err)
+		}
+		switch rec.Type {
+		case dumpRecordMeta:
+			if err = db.putMeta(ctx, namespace, rec.Key, rec.Value); err != nil {
+				return err
+			}
+			meta++
+		case dumpRecordItem:
+			// Items are restored as is: autotime, versioning and history of the namespace are not applied
+			if _, err = db.modifyItemImpl(ctx, ns, nil, rec.Item, modeUpsert, nil); err != nil {
+				return err
+			}
+			items++
+		case dumpRecordFooter:
+			if rec.Items != items || rec.Meta != meta {
+				return bindings.NewError(fmt.Sprintf("rq: Invalid namespace dump: footer expects %d items and %d meta, but %d items and %d meta are read",
+					rec.Items, rec.Meta, items, meta), ErrCodeParams)
+			}
+			return nil
+		default:
+			return bindings.NewError(fmt.Sprintf("rq: Invalid namespace dump: unknown record type '%s'", rec.Type), ErrCodeParams)
+		}
+	}
+}
+
+func dumpError(msg string, err error) error {
+	return bindings.NewError(fmt.Sprintf("rq: Invalid namespace dump: %s: %v", msg, err), ErrCodeParams)
+}
@@ -0,0 +1,72 @@
+package reindexer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestDumpRestoreNamespace(t *testing.T) {
+	db, srv := newMockDB(t, "dump")
+	defer db.Close()
+
+	srv.SetResults(reindexer.NamespacesNamespaceName, reindexer.NamespaceDescription{
+		Name:    testNs,
+		Indexes: []reindexer.IndexDescription{{IndexDef: reindexer.IndexDef{Name: "id", JSONPaths: []string{"id"}, IndexType: "hash", FieldType: "int", IsPK: true}}},
+	})
+	srv.SetResults(testNs, testItem{ID: 1, Name: "first"}, testItem{ID: 2, Name: "second", Tags: []string{"a"}})
+	require.NoError(t, db.PutMeta(testNs, "version", []byte("42")))
+
+	var dump bytes.Buffer
+	require.NoError(t, db.DumpNamespace(context.Background(), testNs, &dump))
+	lines := strings.Split(strings.TrimSpace(dump.String()), "\n")
+	require.Len(t, lines, 5)
+	var header struct {
+		Type, Format, Namespace string
+		Indexes                 []reindexer.IndexDef
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, "header", header.Type)
+	assert.Equal(t, "reindexer-ns-dump", header.Format)
+	assert.Equal(t, testNs, header.Namespace)
+	require.Len(t, header.Indexes, 1)
+	assert.True(t, header.Indexes[0].IsPK)
+	assert.JSONEq(t, `{"type":"meta","key":"version","value":"NDI="}`, lines[1])
+	assert.JSONEq(t, `{"type":"item","item":{"ID":1,"Name":"first","Tags":null}}`, lines[2])
+	assert.JSONEq(t, `{"type":"footer","items":2,"meta":1}`, lines[4])
+
+	// Index 'name' is missing in the namespace, so it's added on restore
+	restored := strings.Join([]string{
+		`{"type":"header","format":"reindexer-ns-dump","version":1,"namespace":"old","indexes":[{"name":"id","json_paths":["id"],"index_type":"hash","field_type":"int","is_pk":true},{"name":"name","json_paths":["name"],"index_type":"hash","field_type":"string"}]}`,
+		`{"type":"meta","key":"version","value":"NDM="}`,
+		`{"type":"item","item":{"ID":3,"Name":"third","Tags":null}}`,
+		`{"type":"footer","items":1,"meta":1}`,
+	}, "\n")
+	addIndexCalls, modifyCalls := len(srv.CallsOf(mock.MethodAddIndex)), len(srv.CallsOf(mock.MethodModifyItem))
+	require.NoError(t, db.RestoreNamespace(context.Background(), testNs, strings.NewReader(restored)))
+	addIndex := srv.CallsOf(mock.MethodAddIndex)[addIndexCalls:]
+	require.Len(t, addIndex, 1)
+	assert.Equal(t, "name", addIndex[0].Index.Name)
+	modify := srv.CallsOf(mock.MethodModifyItem)[modifyCalls:]
+	require.Len(t, modify, 1)
+	var item testItem
+	require.NoError(t, modify[0].DecodeItem(&item))
+	assert.Equal(t, testItem{ID: 3, Name: "third"}, item)
+	meta, err := db.GetMeta(testNs, "version")
+	require.NoError(t, err)
+	assert.Equal(t, "43", string(meta))
+
+	// Dump without footer is truncated
+	truncated := restored[:strings.LastIndex(restored, "\n")]
+	err = db.RestoreNamespace(context.Background(), testNs, strings.NewReader(truncated))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid namespace dump")
+}
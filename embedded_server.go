@@ -0,0 +1,84 @@
+package reindexer
+
+import (
+	"context"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/builtinserver/config"
+)
+
+// EmbeddedServer controls the Reindexer server, which is embedded into the application by builtinserver binding (see WithServerConfig)
+type EmbeddedServer struct {
+	db  *reindexerImpl
+	ctx context.Context
+	srv bindings.RawBindingEmbeddedServer
+}
+
+// BuiltinServer returns control of the embedded server, or nil, if the DB is not opened by builtinserver binding
+func (db *Reindexer) BuiltinServer() *EmbeddedServer {
+	srv, ok := db.impl.binding.(bindings.RawBindingEmbeddedServer)
+	if !ok {
+		return nil
+	}
+	return &EmbeddedServer{db: db.impl, ctx: db.ctx, srv: srv}
+}
+
+// Ready returns the channel, which is closed, when the server is started and its storage is ready.
+// The channel of the stopped server is not closed until the server is started again by ReloadConfig
+func (s *EmbeddedServer) Ready() <-chan struct{} {
+	return s.srv.ServerReady()
+}
+
+// Stop gracefully stops the server: it stops accepting the connections and waits for the running requests.
+// Calls of the DB fail after the stop, and Close of the DB doesn't stop the server again
+func (s *EmbeddedServer) Stop() error {
+	return s.srv.StopServer()
+}
+
+// ReloadConfig restarts the server with the new config and reopens the namespaces, which were opened by the DB.
+// Items of the namespaces without storage are lost by the restart. The DB must not be used concurrently with ReloadConfig
+func (s *EmbeddedServer) ReloadConfig(cfg *config.ServerConfig) error {
+	if err := s.srv.RestartServer(cfg); err != nil {
+		return err
+	}
+
+	s.db.lock.RLock()
+	namespaces := make([]*reindexerNamespace, 0, len(s.db.ns))
+	for _, ns := range s.db.ns {
+		if ns.opened {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	s.db.lock.RUnlock()
+
+	for _, ns := range namespaces {
+		if err := s.db.binding.OpenNamespace(s.ctx, ns.name, ns.opts.enableStorage, ns.opts.dropOnFileFormatError); err != nil {
+			return err
+		}
+		// Namespaces without storage are created again, so indexes and schema are set as by OpenNamespace
+		for _, index := range ns.indexes {
+			if err := s.db.binding.AddIndex(s.ctx, ns.name, index); err != nil {
+				return err
+			}
+		}
+		if err := s.db.binding.SetSchema(s.ctx, ns.name, ns.schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Config returns the config of the running server
+func (s *EmbeddedServer) Config() *config.ServerConfig {
+	return s.srv.ServerConfig()
+}
+
+// HTTPAddr returns the address of the server's HTTP API
+func (s *EmbeddedServer) HTTPAddr() string {
+	return s.srv.ServerConfig().Net.HTTPAddr
+}
+
+// RPCAddr returns the address of the server's RPC API, which may be used by cproto binding
+func (s *EmbeddedServer) RPCAddr() string {
+	return s.srv.ServerConfig().Net.RPCAddr
+}
@@ -0,0 +1,58 @@
+package reindexer
+
+import (
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// Sentinel errors of the error codes. They are matched by errors.Is with any error of the client or of the server with the same code,
+// including the errors, wrapped by the typed errors (e.g. IteratorError). Code and message of the server's error are available
+// via errors.As with Error interface
+var (
+	ErrParseSQL         = bindings.NewCodeError("rq: SQL parse error", ErrCodeParseSQL)
+	ErrQueryExec        = bindings.NewCodeError("rq: Query execution error", ErrCodeQueryExec)
+	ErrParams           = bindings.NewCodeError("rq: Invalid parameters", ErrCodeParams)
+	ErrLogic            = bindings.NewCodeError("rq: Logic error", ErrCodeLogic)
+	ErrParseJSON        = bindings.NewCodeError("rq: JSON parse error", ErrCodeParseJson)
+	ErrConflict         = bindings.NewCodeError("rq: Conflict", ErrCodeConflict)
+	ErrForbidden        = bindings.NewCodeError("rq: Forbidden", ErrCodeForbidden)
+	ErrNotValid         = bindings.NewCodeError("rq: Not valid", ErrCodeNotValid)
+	ErrNetwork          = bindings.NewCodeError("rq: Network error", ErrCodeNetwork)
+	ErrStateInvalidated = bindings.NewCodeError("rq: State invalidated", ErrCodeStateInvalidated)
+	ErrTimeout          = bindings.NewCodeError("rq: Timeout", ErrCodeTimeout)
+	ErrCanceled         = bindings.NewCodeError("rq: Canceled", bindings.ErrCanceled)
+)
+
+// isCodeError reports, if target is the sentinel of the code. It's used by the typed errors, which don't wrap the binding's error
+func isCodeError(target error, code int) bool {
+	c, ok := target.(bindings.CodeError)
+	return ok && c.Code() == code
+}
+
+// ErrNamespaceNotFound is matched by errors.Is with the errors about the namespace, which is not opened by the client
+// or doesn't exist on the server
+var ErrNamespaceNotFound error = namespaceNotFoundError{}
+
+// namespaceNotFoundError - sentinel of ErrNamespaceNotFound. The server reports the missing namespace by different codes,
+// so its errors are matched by the message
+type namespaceNotFoundError struct{}
+
+func (namespaceNotFoundError) Error() string {
+	return "rq: Namespace is not found"
+}
+
+// Code returns code of the client's error about the namespace, which is not opened
+func (namespaceNotFoundError) Code() int {
+	return ErrCodeNotFound
+}
+
+// Server's error looks like "Namespace 'items' does not exist"
+func (namespaceNotFoundError) MatchError(err bindings.Error) bool {
+	if err == errNsNotFound {
+		return true
+	}
+	msg := err.Error()
+	return (err.Code() == ErrCodeNotFound || err.Code() == ErrCodeParams) &&
+		strings.HasPrefix(msg, "Namespace '") && strings.HasSuffix(msg, "' does not exist")
+}
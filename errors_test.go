@@ -0,0 +1,48 @@
+package reindexer_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestTypedErrors(t *testing.T) {
+	db, srv := newMockDB(t, "typederrors")
+	defer db.Close()
+
+	// Errors of the server are matched by the code, and the code and the message are available via errors.As
+	srv.SetError(mock.MethodSelectQuery, bindings.NewError("Query timeout", reindexer.ErrCodeTimeout))
+	_, err := db.Query(testNs).Exec().FetchAll()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, reindexer.ErrTimeout))
+	assert.False(t, errors.Is(err, reindexer.ErrConflict))
+	assert.False(t, errors.Is(err, reindexer.ErrNamespaceNotFound))
+	var rerr reindexer.Error
+	require.True(t, errors.As(err, &rerr))
+	assert.Equal(t, reindexer.ErrCodeTimeout, rerr.Code())
+	assert.Equal(t, "Query timeout", rerr.Error())
+
+	// Wrapped errors are matched too
+	assert.True(t, errors.Is(fmt.Errorf("select failed: %w", err), reindexer.ErrTimeout))
+
+	// Missing namespace is reported by the client or by the server
+	srv.SetError(mock.MethodSelectQuery, bindings.NewError("Namespace 'items' does not exist", reindexer.ErrCodeNotFound))
+	_, err = db.Query(testNs).Exec().FetchAll()
+	assert.True(t, errors.Is(err, reindexer.ErrNamespaceNotFound))
+	srv.SetError(mock.MethodSelectQuery, nil)
+	_, err = db.Query("unknown").Exec().FetchAll()
+	assert.True(t, errors.Is(err, reindexer.ErrNamespaceNotFound))
+
+	// Typed errors of the client are matched by their codes
+	assert.True(t, errors.Is(&reindexer.VersionConflictError{Namespace: testNs, Expected: 1, Actual: 2}, reindexer.ErrConflict))
+	assert.True(t, errors.Is(&reindexer.VersionConflictError{}, reindexer.ErrVersionConflict))
+	assert.True(t, errors.Is(reindexer.ErrSnapshotChanged, reindexer.ErrConflict))
+	assert.False(t, errors.Is(reindexer.ErrSnapshotChanged, reindexer.ErrVersionConflict))
+}
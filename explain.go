@@ -0,0 +1,84 @@
+package reindexer
+
+import "strings"
+
+var (
+	explainOpPrefixes   = []string{"and ", "or ", "not "}
+	explainJoinPrefixes = []string{"inner_join ", "or_inner_join ", "left_join ", "merge "}
+)
+
+// ExplainJoin - plan of the joined or merged query
+type ExplainJoin struct {
+	// Type of the join: "inner_join", "or_inner_join", "left_join" or "merge"
+	Type string
+	// Namespace of the joined query
+	Namespace string
+	// Selector of the joined query in the main query. Contains preselect method, counters and explain results of the joined query
+	Selector ExplainSelector
+}
+
+// IndexesUsed returns fields, which conditions were processed by index (without scan of the items). Fields of the nested brackets are included
+func (e *ExplainResults) IndexesUsed() []string {
+	var fields []string
+	seen := make(map[string]bool)
+	walkExplainSelectors(e.Selectors, func(s *ExplainSelector) {
+		if s.Method != "index" {
+			return
+		}
+		field := explainFieldName(s.Field)
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	})
+	return fields
+}
+
+// Cost returns total cost expectation of the query's selectors
+func (e *ExplainResults) Cost() float64 {
+	cost := 0.0
+	walkExplainSelectors(e.Selectors, func(s *ExplainSelector) {
+		cost += s.Cost
+	})
+	return cost
+}
+
+// Joins returns plans of the joined and merged queries
+func (e *ExplainResults) Joins() []ExplainJoin {
+	var joins []ExplainJoin
+	walkExplainSelectors(e.Selectors, func(s *ExplainSelector) {
+		field := explainFieldName(s.Field)
+		for _, prefix := range explainJoinPrefixes {
+			if strings.HasPrefix(field, prefix) {
+				joins = append(joins, ExplainJoin{
+					Type:      strings.TrimSpace(prefix),
+					Namespace: field[len(prefix):],
+					Selector:  *s,
+				})
+				return
+			}
+		}
+	})
+	return joins
+}
+
+// walkExplainSelectors calls fn for each selector, which is not a bracket. Selectors of the brackets are visited recursively
+func walkExplainSelectors(selectors []ExplainSelector, fn func(s *ExplainSelector)) {
+	for i := range selectors {
+		if len(selectors[i].Selectors) > 0 {
+			walkExplainSelectors(selectors[i].Selectors, fn)
+			continue
+		}
+		fn(&selectors[i])
+	}
+}
+
+// explainFieldName removes operation prefix ("or ", "not ") from the selector's field
+func explainFieldName(field string) string {
+	for _, prefix := range explainOpPrefixes {
+		if strings.HasPrefix(field, prefix) {
+			return field[len(prefix):]
+		}
+	}
+	return field
+}
@@ -0,0 +1,366 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// Exec executes copy of the query and returns at most maxItems items of type T (e.g. *Item or Item) and total count of items,
+// matching the query. Total is requested by ReqTotal, unless ReqTotal/CachedTotal is already called.
+// maxItems <= 0 means no cap. The copy's limit is set to maxItems, unless the smaller limit is already set, so the server
+// doesn't return the items, which would be skipped. The query itself is not modified and may be reused. Iterator is always closed
+func Exec[T any](ctx context.Context, q *Query, maxItems int) ([]T, int, error) {
+	qc := q.makeCopy(q.db, nil)
+	if !qc.totalRequested {
+		qc.ReqTotal()
+	}
+	if maxItems > 0 && (!qc.limitSet || qc.limit > maxItems) {
+		qc.Limit(maxItems)
+	}
+	it := qc.ExecCtx(ctx)
+	defer it.Close()
+	if err := it.Error(); err != nil {
+		return nil, 0, err
+	}
+
+	total := it.TotalCount()
+	size := it.Count()
+	if maxItems > 0 && size > maxItems {
+		size = maxItems
+	}
+
+	items := make([]T, 0, size)
+	for (maxItems <= 0 || len(items) < maxItems) && it.Next() {
+		item, err := castItem[T](it.Object())
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+	if err := it.Error(); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// castItem converts item, returned by iterator, to T. Both pointer and value types of the namespace's struct are allowed
+func castItem[T any](obj interface{}) (T, error) {
+	if item, ok := obj.(T); ok {
+		return item, nil
+	}
+	var item T
+	if v := reflect.ValueOf(obj); v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type() == reflect.TypeOf(item) {
+		return v.Elem().Interface().(T), nil
+	}
+	return item, fmt.Errorf("rq: can't convert item of type %T to %T", obj, item)
+}
+
+// TypedQuery is the query, which returns items of type T (the namespace's struct or pointer to it) without casting.
+// All the Query's methods for building the query are available. Common builders (Where*, Sort, Limit, etc) return
+// the typed query, so they may be chained with Exec
+type TypedQuery[T any] struct {
+	*Query
+}
+
+// NewTypedQuery creates new typed query to the namespace
+func NewTypedQuery[T any](db *Reindexer, namespace string) *TypedQuery[T] {
+	return Typed[T](db.Query(namespace))
+}
+
+// Typed converts query to the typed query. Further calls of the query's methods modify the same query
+func Typed[T any](q *Query) *TypedQuery[T] {
+	return &TypedQuery[T]{Query: q}
+}
+
+// Methods below wrap the Query's builders, so the chain of calls keeps the typed query
+
+// Where - see Query.Where
+func (q *TypedQuery[T]) Where(index string, condition int, keys interface{}) *TypedQuery[T] {
+	q.Query.Where(index, condition, keys)
+	return q
+}
+
+// WhereInt - see Query.WhereInt
+func (q *TypedQuery[T]) WhereInt(index string, condition int, keys ...int) *TypedQuery[T] {
+	q.Query.WhereInt(index, condition, keys...)
+	return q
+}
+
+// WhereInt32 - see Query.WhereInt32
+func (q *TypedQuery[T]) WhereInt32(index string, condition int, keys ...int32) *TypedQuery[T] {
+	q.Query.WhereInt32(index, condition, keys...)
+	return q
+}
+
+// WhereInt64 - see Query.WhereInt64
+func (q *TypedQuery[T]) WhereInt64(index string, condition int, keys ...int64) *TypedQuery[T] {
+	q.Query.WhereInt64(index, condition, keys...)
+	return q
+}
+
+// WhereString - see Query.WhereString
+func (q *TypedQuery[T]) WhereString(index string, condition int, keys ...string) *TypedQuery[T] {
+	q.Query.WhereString(index, condition, keys...)
+	return q
+}
+
+// WhereUuid - see Query.WhereUuid
+func (q *TypedQuery[T]) WhereUuid(index string, condition int, keys ...string) *TypedQuery[T] {
+	q.Query.WhereUuid(index, condition, keys...)
+	return q
+}
+
+// WhereBool - see Query.WhereBool
+func (q *TypedQuery[T]) WhereBool(index string, condition int, keys ...bool) *TypedQuery[T] {
+	q.Query.WhereBool(index, condition, keys...)
+	return q
+}
+
+// WhereDouble - see Query.WhereDouble
+func (q *TypedQuery[T]) WhereDouble(index string, condition int, keys ...float64) *TypedQuery[T] {
+	q.Query.WhereDouble(index, condition, keys...)
+	return q
+}
+
+// WhereComposite - see Query.WhereComposite
+func (q *TypedQuery[T]) WhereComposite(index string, condition int, keys ...interface{}) *TypedQuery[T] {
+	q.Query.WhereComposite(index, condition, keys...)
+	return q
+}
+
+// WhereBetweenFields - see Query.WhereBetweenFields
+func (q *TypedQuery[T]) WhereBetweenFields(firstField string, condition int, secondField string) *TypedQuery[T] {
+	q.Query.WhereBetweenFields(firstField, condition, secondField)
+	return q
+}
+
+// Match - see Query.Match
+func (q *TypedQuery[T]) Match(index string, keys ...string) *TypedQuery[T] {
+	q.Query.Match(index, keys...)
+	return q
+}
+
+// OpenBracket - see Query.OpenBracket
+func (q *TypedQuery[T]) OpenBracket() *TypedQuery[T] {
+	q.Query.OpenBracket()
+	return q
+}
+
+// CloseBracket - see Query.CloseBracket
+func (q *TypedQuery[T]) CloseBracket() *TypedQuery[T] {
+	q.Query.CloseBracket()
+	return q
+}
+
+// And - see Query.And
+func (q *TypedQuery[T]) And() *TypedQuery[T] {
+	q.Query.And()
+	return q
+}
+
+// Or - see Query.Or
+func (q *TypedQuery[T]) Or() *TypedQuery[T] {
+	q.Query.Or()
+	return q
+}
+
+// Not - see Query.Not
+func (q *TypedQuery[T]) Not() *TypedQuery[T] {
+	q.Query.Not()
+	return q
+}
+
+// Sort - see Query.Sort
+func (q *TypedQuery[T]) Sort(sortIndex string, desc bool, values ...interface{}) *TypedQuery[T] {
+	q.Query.Sort(sortIndex, desc, values...)
+	return q
+}
+
+// SortMulti - see Query.SortMulti
+func (q *TypedQuery[T]) SortMulti(entries []SortEntry, pkTiebreaker bool) *TypedQuery[T] {
+	q.Query.SortMulti(entries, pkTiebreaker)
+	return q
+}
+
+// Distinct - see Query.Distinct
+func (q *TypedQuery[T]) Distinct(distinctIndex string) *TypedQuery[T] {
+	q.Query.Distinct(distinctIndex)
+	return q
+}
+
+// ReqTotal - see Query.ReqTotal
+func (q *TypedQuery[T]) ReqTotal(totalNames ...string) *TypedQuery[T] {
+	q.Query.ReqTotal(totalNames...)
+	return q
+}
+
+// CachedTotal - see Query.CachedTotal
+func (q *TypedQuery[T]) CachedTotal(totalNames ...string) *TypedQuery[T] {
+	q.Query.CachedTotal(totalNames...)
+	return q
+}
+
+// Limit - see Query.Limit
+func (q *TypedQuery[T]) Limit(limitItems int) *TypedQuery[T] {
+	q.Query.Limit(limitItems)
+	return q
+}
+
+// Offset - see Query.Offset
+func (q *TypedQuery[T]) Offset(startOffset int) *TypedQuery[T] {
+	q.Query.Offset(startOffset)
+	return q
+}
+
+// Select - see Query.Select
+func (q *TypedQuery[T]) Select(fields ...string) *TypedQuery[T] {
+	q.Query.Select(fields...)
+	return q
+}
+
+// Functions - see Query.Functions
+func (q *TypedQuery[T]) Functions(fields ...string) *TypedQuery[T] {
+	q.Query.Functions(fields...)
+	return q
+}
+
+// FetchCount - see Query.FetchCount
+func (q *TypedQuery[T]) FetchCount(n int) *TypedQuery[T] {
+	q.Query.FetchCount(n)
+	return q
+}
+
+// Strict - see Query.Strict
+func (q *TypedQuery[T]) Strict(mode QueryStrictMode) *TypedQuery[T] {
+	q.Query.Strict(mode)
+	return q
+}
+
+// Debug - see Query.Debug
+func (q *TypedQuery[T]) Debug(level int) *TypedQuery[T] {
+	q.Query.Debug(level)
+	return q
+}
+
+// Explain - see Query.Explain
+func (q *TypedQuery[T]) Explain() *TypedQuery[T] {
+	q.Query.Explain()
+	return q
+}
+
+// WithRank - see Query.WithRank
+func (q *TypedQuery[T]) WithRank() *TypedQuery[T] {
+	q.Query.WithRank()
+	return q
+}
+
+// Exec executes query and returns typed iterator
+func (q *TypedQuery[T]) Exec() *TypedIterator[T] {
+	return q.ExecCtx(context.Background())
+}
+
+// ExecCtx executes query and returns typed iterator
+func (q *TypedQuery[T]) ExecCtx(ctx context.Context) *TypedIterator[T] {
+	it := q.Query.ExecCtx(ctx)
+	if it.Error() == nil && len(it.nsArray) != 0 {
+		if err := checkItemType[T](it.nsArray[0].rtype); err != nil {
+			it.Close()
+			it = errIterator(err)
+		}
+	}
+	return &TypedIterator[T]{Iterator: it}
+}
+
+// Get executes query and returns 1st item, panic on error
+func (q *TypedQuery[T]) Get() (item T, found bool) {
+	return q.GetCtx(context.Background())
+}
+
+// GetCtx executes query and returns 1st item, panic on error
+func (q *TypedQuery[T]) GetCtx(ctx context.Context) (item T, found bool) {
+	q.Limit(1)
+	it := q.ExecCtx(ctx)
+	defer it.Close()
+	if it.Error() != nil {
+		panic(it.Error())
+	}
+	if it.Next() {
+		return it.Object(), true
+	}
+	return item, false
+}
+
+// TypedIterator presents query results of type T. All the Iterator's methods are available
+type TypedIterator[T any] struct {
+	*Iterator
+}
+
+// Object returns current object.
+// Will panic when pointer was not moved, Next() must be called before.
+func (it *TypedIterator[T]) Object() T {
+	item, err := castItem[T](it.Iterator.Object())
+	if err != nil {
+		// Type is checked on query execution for the main namespace, so it may fail for merged queries only
+		panic(err)
+	}
+	return item
+}
+
+// AllowUnsafe takes bool, that enable or disable unsafe behavior. See Iterator.AllowUnsafe for details
+func (it *TypedIterator[T]) AllowUnsafe(allow bool) *TypedIterator[T] {
+	it.Iterator.AllowUnsafe(allow)
+	return it
+}
+
+// FetchAll returns all query results and closes the iterator.
+func (it *TypedIterator[T]) FetchAll() (items []T, err error) {
+	defer it.Close()
+	items = make([]T, 0, it.Count())
+	for it.Next() {
+		items = append(items, it.Object())
+	}
+	return items, it.Error()
+}
+
+// FetchOne returns first element and closes the iterator.
+// When it's impossible (count is 0) err will be ErrNotFound.
+func (it *TypedIterator[T]) FetchOne() (item T, err error) {
+	defer it.Close()
+	if it.Next() {
+		return it.Object(), it.Error()
+	}
+	if it.err == nil {
+		it.err = ErrNotFound
+	}
+	return item, it.err
+}
+
+// JoinedItems returns joined items of the iterator's current item for the given field as items of type T
+// (the joined namespace's struct or pointer to it). See Iterator.JoinedItemsFor
+func JoinedItems[T any](it *Iterator, field string) ([]T, error) {
+	objects, err := it.JoinedItemsFor(field)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]T, 0, len(objects))
+	for _, obj := range objects {
+		item, err := castItem[T](obj)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// checkItemType checks, that T is the namespace's struct or pointer to it
+func checkItemType[T any](rtype reflect.Type) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t == rtype || (t.Kind() == reflect.Ptr && t.Elem() == rtype) {
+		return nil
+	}
+	return bindings.NewError(fmt.Sprintf("rq: Type %s doesn't match type %s of namespace's items", t, rtype), ErrCodeParams)
+}
@@ -0,0 +1,26 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+type testItem struct {
+	ID   int      `reindex:"id,,pk"`
+	Name string   `reindex:"name"`
+	Tags []string `reindex:"tags"`
+}
+
+const testNs = "items"
+
+// newMockDB opens DB with the mock binding (see mock.NewDB) and the 'items' namespace of testItem
+func newMockDB(t *testing.T, name string, options ...interface{}) (*reindexer.Reindexer, *mock.Server) {
+	db, srv := mock.NewDB(name, options...)
+	require.NoError(t, db.Status().Err)
+	require.NoError(t, db.OpenNamespace(testNs, reindexer.DefaultNamespaceOptions(), testItem{}))
+	return db, srv
+}
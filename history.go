@@ -0,0 +1,237 @@
+package reindexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+const historyNamespaceSuffix = "_history"
+
+var errHistoryTxQuery = bindings.NewError("rq: Items, modified by the transaction's queries, can't be written to history", ErrCodeParams)
+
+// Precepts of the history items: versions are sequential and timestamps are set by server
+var historyPrecepts = []string{"version=serial()", "timestamp=now(nsec)"}
+
+// HistoryItem is change of the item in history namespace
+type HistoryItem struct {
+	// Sequential number of the change in history namespace
+	Version int64 `reindex:"version,,pk" json:"version"`
+	// JSON array with values of the primary key's fields of the item
+	Key string `reindex:"key,hash" json:"key"`
+	// Time of the change (unix nanoseconds)
+	Timestamp int64 `reindex:"timestamp,tree" json:"timestamp"`
+	// Modification: "Insert", "Update", "Upsert" or "Delete"
+	Op string `json:"op"`
+	// Item in JSON format, as it was passed to the modification
+	Data string `json:"data"`
+}
+
+// Decode unmarshals the item from history to dest
+func (h *HistoryItem) Decode(dest interface{}) error {
+	return json.Unmarshal([]byte(h.Data), dest)
+}
+
+// HistoryError is returned, if the items are written, but their changes are not written to history namespace
+type HistoryError struct {
+	Err error
+}
+
+func (e *HistoryError) Error() string {
+	return "rq: items are written, but their history is not: " + e.Err.Error()
+}
+
+func (e *HistoryError) Unwrap() error {
+	return e.Err
+}
+
+// HistoryNamespace returns name of the history namespace for the namespace
+func HistoryNamespace(namespace string) string {
+	return strings.ToLower(namespace) + historyNamespaceSuffix
+}
+
+// historyNamespaceOptions returns options of the history namespace for the namespace's options
+func historyNamespaceOptions(opts *NamespaceOptions) *NamespaceOptions {
+	historyOpts := DefaultNamespaceOptions()
+	historyOpts.enableStorage = opts.enableStorage
+	historyOpts.dropOnFileFormatError = opts.dropOnFileFormatError
+	historyOpts.defaultDeadline = opts.defaultDeadline
+	return historyOpts
+}
+
+// historyKey returns key of the item in history namespace for values of the primary key's fields
+func historyKey(pk []interface{}) (string, error) {
+	key, err := json.Marshal(pk)
+	if err != nil {
+		return "", bindings.NewError("rq: Can't make history key: "+err.Error(), ErrCodeParams)
+	}
+	return string(key), nil
+}
+
+// newHistoryItem returns history item for the event
+func (ns *reindexerNamespace) newHistoryItem(event *TxEvent) (*HistoryItem, error) {
+	pk := event.PK
+	data, isJSON := event.Item.([]byte)
+	if isJSON {
+		item := reflect.New(ns.rtype).Interface()
+		if err := json.Unmarshal(data, item); err != nil {
+			return nil, bindings.NewError("rq: Can't get primary key of JSON item: "+err.Error(), ErrCodeParseJson)
+		}
+		pk = ns.pkValues(item)
+	} else {
+		var err error
+		if data, err = json.Marshal(event.Item); err != nil {
+			return nil, bindings.NewError("rq: Can't marshal item to history: "+err.Error(), ErrCodeParams)
+		}
+	}
+
+	key, err := historyKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryItem{Key: key, Op: event.Op, Data: string(data)}, nil
+}
+
+// historyTx writes changes of the items to history namespace. It's begun before the items are modified, so the items are not
+// modified, if their history can't be written (e.g. history namespace is dropped). Server doesn't support transactions across
+// namespaces, so it's committed right after the items' changes, and only with the changes of the modified items
+type historyTx struct {
+	ns *reindexerNamespace
+	// nil after commit or rollback
+	tx *Tx
+}
+
+// beginHistory begins transaction of history namespace. Returns nil, if history is not enabled for the namespace
+func (db *reindexerImpl) beginHistory(ctx context.Context, ns *reindexerNamespace) (*historyTx, error) {
+	if !ns.opts.history {
+		return nil, nil
+	}
+	tx, err := db.beginTx(ctx, HistoryNamespace(ns.name))
+	if err != nil {
+		return nil, err
+	}
+	return &historyTx{ns: ns, tx: tx}, nil
+}
+
+// commit writes changes of the modified items to history namespace. Transaction is rolled back without the changes
+func (h *historyTx) commit(events []TxEvent) error {
+	if h == nil || h.tx == nil {
+		return nil
+	}
+	if len(events) == 0 {
+		h.rollback()
+		return nil
+	}
+	for i := range events {
+		item, err := h.ns.newHistoryItem(&events[i])
+		if err == nil {
+			err = h.tx.Insert(item, historyPrecepts...)
+		}
+		if err != nil {
+			h.rollback()
+			return &HistoryError{Err: err}
+		}
+	}
+	tx := h.tx
+	h.tx = nil
+	if err := tx.Commit(); err != nil {
+		return &HistoryError{Err: err}
+	}
+	return nil
+}
+
+// commitUpdated writes the items, updated by the query, to history namespace. Returns iterator over the updated items
+func (h *historyTx) commitUpdated(ctx context.Context, it *Iterator) *Iterator {
+	// Query is closed by the returned iterator
+	q, db := it.query, it.db
+	it.query = nil
+	objects, err := it.FetchAll()
+	if err != nil {
+		h.rollback()
+		if q != nil {
+			q.close()
+		}
+		return errIterator(err)
+	}
+	events := make([]TxEvent, 0, len(objects))
+	for _, item := range objects {
+		events = append(events, h.ns.newTxEvent(item, nil, modeUpdate))
+	}
+	if err = h.commit(events); err != nil {
+		if q != nil {
+			q.close()
+		}
+		return errIterator(err)
+	}
+	it = newObjectsIterator(ctx, db, h.ns.name, objects, len(objects))
+	it.query = q
+	return it
+}
+
+func (h *historyTx) rollback() {
+	if h != nil && h.tx != nil {
+		h.tx.Rollback()
+		h.tx = nil
+	}
+}
+
+// writeHistory writes changes of the items, modified by the committed transaction, to history namespace
+func (tx *Tx) writeHistory(count int) error {
+	if tx.history == nil {
+		return nil
+	}
+	events, err := tx.modifiedEvents(count)
+	if err != nil {
+		tx.history.rollback()
+		return &HistoryError{Err: err}
+	}
+	return tx.history.commit(events)
+}
+
+// modifiedEvents returns changes of the items, modified by the committed transaction. Server returns only count of the modified items,
+// so changes of inserts, updates and deletes are known, only if all or none of them are applied (upserts are always applied)
+func (tx *Tx) modifiedEvents(count int) ([]TxEvent, error) {
+	if count == tx.itemCounts.items {
+		return tx.events, nil
+	}
+	conditional := tx.itemCounts.inserts + tx.itemCounts.updatesDeletes
+	if count != tx.itemCounts.items-conditional {
+		return nil, bindings.NewError(fmt.Sprintf("rq: %d of %d inserts, updates and deletes of the transaction are not applied, so their changes are unknown",
+			tx.itemCounts.items-count, conditional), ErrCodeLogic)
+	}
+	upserts := make([]TxEvent, 0, count)
+	for _, event := range tx.events {
+		if event.Op == modifyModeNames[modeUpsert] {
+			upserts = append(upserts, event)
+		}
+	}
+	return upserts, nil
+}
+
+// itemHistory returns changes of the item, ordered by version
+func (db *reindexerImpl) itemHistory(ctx context.Context, namespace string, pk ...interface{}) ([]*HistoryItem, error) {
+	namespace = strings.ToLower(namespace)
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !ns.opts.history {
+		return nil, bindings.NewError("rq: History is not enabled for namespace '"+namespace+"'", ErrCodeParams)
+	}
+	key, err := historyKey(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	it := db.query(HistoryNamespace(namespace)).WhereString("key", EQ, key).Sort("version", false).ExecCtx(ctx)
+	defer it.Close()
+	items := make([]*HistoryItem, 0, it.Count())
+	for it.Next() {
+		items = append(items, it.Object().(*HistoryItem))
+	}
+	return items, it.Error()
+}
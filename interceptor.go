@@ -0,0 +1,82 @@
+package reindexer
+
+import (
+	"context"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// Operations of the binding calls, which are passed to the interceptors
+const (
+	OpSelect      = "Select"
+	OpModifyItem  = "ModifyItem"
+	OpDeleteQuery = "DeleteQuery"
+	OpUpdateQuery = "UpdateQuery"
+	OpCommitTx    = "CommitTx"
+)
+
+// OpInfo describes the binding call, which is passed to the interceptor: operation (one of OpXXX constants), namespace,
+// mode of ModifyItem ("Insert", "Update", "Upsert" or "Delete") and SQL of Select, DeleteQuery and UpdateQuery (see Query.SQL)
+type OpInfo = bindings.OpInfo
+
+// Handler performs the binding call. It may be called several times (e.g. to retry the failed call)
+type Handler = func(ctx context.Context) error
+
+// Interceptor is called around the binding call instead of the call. It must call next to perform the call, and may call it with
+// modified context, repeat it or return error without the call. Error of the binding call is returned to the caller, even if
+// the interceptor returns nil, because there are no results of the call. Commit of the transaction must not be repeated
+type Interceptor = func(ctx context.Context, op OpInfo, next Handler) error
+
+var errInterceptorNoCall = bindings.NewError("rq: Interceptor returned without the binding call", ErrCodeLogic)
+
+// intercept runs the chain of the interceptors around the handler. The first interceptor is the outermost one
+func (db *reindexerImpl) intercept(ctx context.Context, op OpInfo, handler Handler) error {
+	var run func(i int, ctx context.Context) error
+	run = func(i int, ctx context.Context) error {
+		if i == len(db.interceptors) {
+			return handler(ctx)
+		}
+		return db.interceptors[i](ctx, op, func(ctx context.Context) error {
+			return run(i+1, ctx)
+		})
+	}
+	return run(0, ctx)
+}
+
+// interceptCall executes the binding call, which returns results, through the interceptors.
+// Results of the repeated call and of the call, which is failed by the interceptor, are released
+func (db *reindexerImpl) interceptCall(ctx context.Context, op OpInfo, call func(ctx context.Context) (bindings.RawBuffer, error)) (bindings.RawBuffer, error) {
+	if len(db.interceptors) == 0 {
+		return call(ctx)
+	}
+	var result bindings.RawBuffer
+	callErr := errInterceptorNoCall
+	err := db.intercept(ctx, op, func(ctx context.Context) error {
+		if result != nil {
+			result.Free()
+			result = nil
+		}
+		var r bindings.RawBuffer
+		if r, callErr = call(ctx); callErr == nil {
+			result = r
+		}
+		return callErr
+	})
+	if err == nil {
+		err = callErr
+	}
+	if err != nil && result != nil {
+		result.Free()
+		result = nil
+	}
+	return result, err
+}
+
+// queryOpInfo returns description of the query's call. SQL of the query is built only for the interceptors
+func (db *reindexerImpl) queryOpInfo(op string, q *Query) OpInfo {
+	info := OpInfo{Op: op, Namespace: q.Namespace}
+	if len(db.interceptors) != 0 {
+		info.SQL, _ = q.SQL()
+	}
+	return info
+}
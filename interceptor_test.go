@@ -0,0 +1,56 @@
+package reindexer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestInterceptor(t *testing.T) {
+	// Server is used by the interceptor, so it's taken before the DB is opened
+	srv := mock.GetServer("intercept")
+	var ops []reindexer.OpInfo
+	audit := func(ctx context.Context, op reindexer.OpInfo, next reindexer.Handler) error {
+		ops = append(ops, op)
+		return next(ctx)
+	}
+	// Failed select is retried once
+	retry := func(ctx context.Context, op reindexer.OpInfo, next reindexer.Handler) error {
+		err := next(ctx)
+		if err != nil && op.Op == reindexer.OpSelect {
+			srv.SetError(mock.MethodSelectQuery, nil)
+			err = next(ctx)
+		}
+		return err
+	}
+	db, _ := newMockDB(t, "intercept", reindexer.WithInterceptor(audit), reindexer.WithInterceptor(retry))
+	defer db.Close()
+
+	require.NoError(t, db.Upsert(testNs, testItem{ID: 1}))
+	srv.SetResults(testNs, testItem{ID: 1, Name: "first"})
+	srv.SetError(mock.MethodSelectQuery, errors.New("connection reset"))
+	items, err := db.Query(testNs).WhereInt("id", reindexer.EQ, 1).Exec().FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Len(t, srv.CallsOf(mock.MethodSelectQuery), 2)
+
+	require.Len(t, ops, 2)
+	assert.Equal(t, reindexer.OpInfo{Op: reindexer.OpModifyItem, Namespace: testNs, Mode: "Upsert"}, ops[0])
+	assert.Equal(t, reindexer.OpInfo{Op: reindexer.OpSelect, Namespace: testNs, SQL: "SELECT * FROM items WHERE id = 1"}, ops[1])
+
+	// Interceptor may reject the call
+	reject := errors.New("rejected")
+	db2, _ := newMockDB(t, "intercept", reindexer.WithInterceptor(func(ctx context.Context, op reindexer.OpInfo, next reindexer.Handler) error {
+		return reject
+	}))
+	defer db2.Close()
+	modifyCalls := len(srv.CallsOf(mock.MethodModifyItem))
+	assert.ErrorIs(t, db2.Upsert(testNs, testItem{ID: 2}), reject)
+	assert.Len(t, srv.CallsOf(mock.MethodModifyItem), modifyCalls)
+}
@@ -0,0 +1,307 @@
+package reindexer
+
+import (
+	"sync"
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/golang-lru/simplelru"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// CachePolicy - eviction policy of the object cache of the namespace (see WithItemCacheConfig)
+type CachePolicy int
+
+const (
+	// CacheLRU evicts the least recently used items
+	CacheLRU CachePolicy = iota
+	// CacheARC (adaptive replacement cache) tracks both recency and frequency of use, so scans of the rarely used items
+	// don't evict frequently used ones. It requires roughly 2x more memory for the keys
+	CacheARC
+)
+
+// CacheStore - storage of the object cache of the namespace, which may be implemented outside of reindexer (see WithItemCacheStore)
+type CacheStore = bindings.ItemCacheStore
+
+// CacheStoreFactory creates CacheStore for the namespace
+type CacheStoreFactory = bindings.ItemCacheStoreFactory
+
+// newCacheStore creates built-in store of the object cache with the policy
+func newCacheStore(policy CachePolicy, maxItems int, onEvict func(key int, value interface{})) (CacheStore, error) {
+	if policy == CacheARC {
+		return newARCCacheStore(maxItems, onEvict)
+	}
+	return newLRUCacheStore(maxItems, onEvict)
+}
+
+// lruCacheStore is CacheStore with LRU eviction policy
+type lruCacheStore struct {
+	cache *lru.Cache
+}
+
+func newLRUCacheStore(maxItems int, onEvict func(key int, value interface{})) (*lruCacheStore, error) {
+	var cache *lru.Cache
+	var err error
+	if onEvict == nil {
+		cache, err = lru.New(maxItems)
+	} else {
+		cache, err = lru.NewWithEvict(maxItems, func(key interface{}, value interface{}) { onEvict(key.(int), value) })
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lruCacheStore{cache: cache}, nil
+}
+
+func (s *lruCacheStore) Get(key int) (interface{}, bool) {
+	return s.cache.Get(key)
+}
+
+func (s *lruCacheStore) Add(key int, value interface{}) {
+	s.cache.Add(key, value)
+}
+
+func (s *lruCacheStore) Remove(key int) {
+	s.cache.Remove(key)
+}
+
+func (s *lruCacheStore) RemoveOldest() bool {
+	_, _, ok := s.cache.RemoveOldest()
+	return ok
+}
+
+func (s *lruCacheStore) Purge() {
+	s.cache.Purge()
+}
+
+func (s *lruCacheStore) Len() int {
+	return s.cache.Len()
+}
+
+// arcCacheStore is CacheStore with ARC eviction policy. It's the same algorithm as lru.ARCCache, which reports evicted items.
+// Items are moved between the lists without eviction callbacks of the lists, so callbacks are called explicitly
+type arcCacheStore struct {
+	size int
+	// Preference towards recently used items
+	p int
+	// Recently and frequently used items
+	t1, t2 *simplelru.LRU
+	// Keys of the items, evicted from t1 and t2
+	b1, b2  *simplelru.LRU
+	onEvict func(key int, value interface{})
+	lock    sync.Mutex
+}
+
+func newARCCacheStore(maxItems int, onEvict func(key int, value interface{})) (*arcCacheStore, error) {
+	s := &arcCacheStore{size: maxItems, onEvict: onEvict}
+	for _, l := range []**simplelru.LRU{&s.t1, &s.t2, &s.b1, &s.b2} {
+		var err error
+		if *l, err = simplelru.NewLRU(maxItems, nil); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *arcCacheStore) evicted(key interface{}, value interface{}) {
+	if s.onEvict != nil {
+		s.onEvict(key.(int), value)
+	}
+}
+
+func (s *arcCacheStore) Get(key int) (interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Item, which is used again, becomes frequently used
+	if value, ok := s.t1.Peek(key); ok {
+		s.t1.Remove(key)
+		s.t2.Add(key, value)
+		return value, true
+	}
+	return s.t2.Get(key)
+}
+
+func (s *arcCacheStore) Add(key int, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.t1.Contains(key) {
+		s.t1.Remove(key)
+		s.t2.Add(key, value)
+		return
+	}
+	if s.t2.Contains(key) {
+		s.t2.Add(key, value)
+		return
+	}
+	// Item was recently evicted from t1, so t1 is too small
+	if s.b1.Contains(key) {
+		delta := 1
+		if b1Len, b2Len := s.b1.Len(), s.b2.Len(); b2Len > b1Len {
+			delta = b2Len / b1Len
+		}
+		if s.p += delta; s.p > s.size {
+			s.p = s.size
+		}
+		if s.t1.Len()+s.t2.Len() >= s.size {
+			s.replace(false)
+		}
+		s.b1.Remove(key)
+		s.t2.Add(key, value)
+		return
+	}
+	// Item was recently evicted from t2, so t2 is too small
+	if s.b2.Contains(key) {
+		delta := 1
+		if b1Len, b2Len := s.b1.Len(), s.b2.Len(); b1Len > b2Len {
+			delta = b1Len / b2Len
+		}
+		if s.p -= delta; s.p < 0 {
+			s.p = 0
+		}
+		if s.t1.Len()+s.t2.Len() >= s.size {
+			s.replace(true)
+		}
+		s.b2.Remove(key)
+		s.t2.Add(key, value)
+		return
+	}
+
+	if s.t1.Len()+s.t2.Len() >= s.size {
+		s.replace(false)
+	}
+	if s.b1.Len() > s.size-s.p {
+		s.b1.RemoveOldest()
+	}
+	if s.b2.Len() > s.p {
+		s.b2.RemoveOldest()
+	}
+	s.t1.Add(key, value)
+}
+
+// replace evicts item from t1 or t2 depending on the preference
+func (s *arcCacheStore) replace(b2ContainsKey bool) bool {
+	if t1Len := s.t1.Len(); t1Len > 0 && (t1Len > s.p || (t1Len == s.p && b2ContainsKey)) {
+		if key, value, ok := s.t1.RemoveOldest(); ok {
+			s.b1.Add(key, nil)
+			s.evicted(key, value)
+			return true
+		}
+	}
+	if key, value, ok := s.t2.RemoveOldest(); ok {
+		s.b2.Add(key, nil)
+		s.evicted(key, value)
+		return true
+	}
+	return false
+}
+
+func (s *arcCacheStore) Remove(key int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{s.t1, s.t2} {
+		if value, ok := l.Peek(key); ok {
+			l.Remove(key)
+			s.evicted(key, value)
+			return
+		}
+	}
+	if !s.b1.Remove(key) {
+		s.b2.Remove(key)
+	}
+}
+
+func (s *arcCacheStore) RemoveOldest() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.replace(false)
+}
+
+func (s *arcCacheStore) Purge() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, l := range []*simplelru.LRU{s.t1, s.t2} {
+		for l.Len() > 0 {
+			if key, value, ok := l.RemoveOldest(); ok {
+				s.evicted(key, value)
+			}
+		}
+	}
+	s.b1.Purge()
+	s.b2.Purge()
+}
+
+func (s *arcCacheStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.t1.Len() + s.t2.Len()
+}
+
+// shardedCacheStore splits items between the stores by hash of the key, so concurrent calls for different items mostly take different locks
+type shardedCacheStore struct {
+	shards []CacheStore
+	// Shard, which is the first to evict the oldest item. Shards are evicted in turn, so eviction order is approximate
+	next uint32
+}
+
+// newShardedCacheStore creates shards by newShard. Limit of the items is divided between the shards
+func newShardedCacheStore(shards int, maxItems int, newShard func(maxItems int) (CacheStore, error)) (*shardedCacheStore, error) {
+	shardItems := (maxItems + shards - 1) / shards
+	if shardItems < 1 {
+		shardItems = 1
+	}
+	s := &shardedCacheStore{shards: make([]CacheStore, shards)}
+	for i := range s.shards {
+		var err error
+		if s.shards[i], err = newShard(shardItems); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// shard returns store of the key. Item ids are allocated sequentially, so they are mixed by Fibonacci hashing
+func (s *shardedCacheStore) shard(key int) CacheStore {
+	h := uint64(key) * 0x9E3779B97F4A7C15
+	return s.shards[(h>>32)%uint64(len(s.shards))]
+}
+
+func (s *shardedCacheStore) Get(key int) (interface{}, bool) {
+	return s.shard(key).Get(key)
+}
+
+func (s *shardedCacheStore) Add(key int, value interface{}) {
+	s.shard(key).Add(key, value)
+}
+
+func (s *shardedCacheStore) Remove(key int) {
+	s.shard(key).Remove(key)
+}
+
+func (s *shardedCacheStore) RemoveOldest() bool {
+	first := atomic.AddUint32(&s.next, 1)
+	for i := range s.shards {
+		if s.shards[(first+uint32(i))%uint32(len(s.shards))].RemoveOldest() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *shardedCacheStore) Purge() {
+	for _, shard := range s.shards {
+		shard.Purge()
+	}
+}
+
+func (s *shardedCacheStore) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package reindexer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// itemPool reuses the decoded structs of the namespace (see WithItemPool)
+type itemPool struct {
+	rtype reflect.Type
+	pool  sync.Pool
+	reset func(item interface{})
+}
+
+// newItemPool creates pool of the structs of type t. Type of the structs, created by cfg.New, is checked by the first one
+func newItemPool(t reflect.Type, cfg bindings.OptionItemPool) (*itemPool, error) {
+	if cfg.New == nil {
+		return nil, bindings.NewError("rq: WithItemPool requires function, which creates the items", ErrCodeParams)
+	}
+	item := cfg.New()
+	if reflect.TypeOf(item) != reflect.PtrTo(t) {
+		return nil, bindings.NewError(fmt.Sprintf("rq: WithItemPool for namespace '%s' creates items of type %T instead of *%s", cfg.Namespace, item, t.Name()), ErrCodeParams)
+	}
+	p := &itemPool{rtype: t, reset: cfg.Reset}
+	p.pool.New = cfg.New
+	p.pool.Put(item)
+	return p, nil
+}
+
+func (p *itemPool) get() interface{} {
+	return p.pool.Get()
+}
+
+// put clears the item and returns it to the pool. Items of other types are ignored
+func (p *itemPool) put(item interface{}) {
+	v := reflect.ValueOf(item)
+	if v.Type() != reflect.PtrTo(p.rtype) || v.IsNil() {
+		return
+	}
+	if p.reset != nil {
+		p.reset(item)
+	} else {
+		v.Elem().Set(reflect.Zero(p.rtype))
+	}
+	p.pool.Put(item)
+}
+
+// newItem returns struct for decoding of the item: from the pool, if it's enabled, or the new one
+func (ns *reindexerNamespace) newItem() interface{} {
+	if ns.itemPool != nil {
+		return ns.itemPool.get()
+	}
+	return reflect.New(ns.rtype).Interface()
+}
+
+// releaseItem returns the item to the pool of the namespace. It does nothing, if the pool is not enabled
+func (db *reindexerImpl) releaseItem(namespace string, item interface{}) {
+	ns, err := db.getNS(strings.ToLower(namespace))
+	if err != nil || ns.itemPool == nil || item == nil {
+		return
+	}
+	ns.itemPool.put(item)
+}
@@ -0,0 +1,55 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestItemPool(t *testing.T) {
+	created, resets := 0, 0
+	db, srv := newMockDB(t, "itempool", reindexer.WithItemPool(testNs, func() interface{} {
+		created++
+		return &testItem{}
+	}, func(item interface{}) {
+		resets++
+		it := item.(*testItem)
+		*it = testItem{Tags: it.Tags[:0]}
+	}))
+	defer db.Close()
+
+	srv.SetResults(testNs, testItem{ID: 1, Name: "first", Tags: []string{"a", "b"}}, testItem{ID: 2, Name: "second", Tags: []string{"c"}})
+	items, err := db.Query(testNs).Exec().FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, item := range items {
+		db.ReleaseItem(testNs, item)
+	}
+	assert.Equal(t, 2, resets)
+
+	// Released structs are reused, and the fields of the previous items are not kept
+	srv.SetResults(testNs, testItem{ID: 3, Name: "third"}, testItem{ID: 4, Name: "fourth"})
+	items, err = db.Query(testNs).Exec().FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "third", items[0].(*testItem).Name)
+	assert.Empty(t, items[0].(*testItem).Tags)
+	assert.Equal(t, "fourth", items[1].(*testItem).Name)
+	assert.Empty(t, items[1].(*testItem).Tags)
+	assert.Less(t, created, 5)
+
+	// Items of other types are not released
+	db.ReleaseItem(testNs, &struct{}{})
+	assert.Equal(t, 2, resets)
+
+	// Pool must create structs of the namespace
+	db2, _ := mock.NewDB("itempool", reindexer.WithItemPool("other", func() interface{} { return &struct{}{} }, nil))
+	defer db2.Close()
+	err = db2.OpenNamespace("other", reindexer.DefaultNamespaceOptions(), testItem{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "WithItemPool")
+}
@@ -0,0 +1,89 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/restream/reindexer/v3/bindings"
+	otelattr "go.opentelemetry.io/otel/attribute"
+)
+
+// VersionConflictError is returned by UpdateIfVersion, when the version of the stored item differs from the expected one
+type VersionConflictError struct {
+	Namespace string
+	// Version, passed to UpdateIfVersion
+	Expected int64
+	// Version of the stored item
+	Actual int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("rq: Item version conflict in namespace '%s': expected %d, stored %d", e.Namespace, e.Expected, e.Actual)
+}
+
+// Code returns code of the error, the same as ErrVersionConflict has
+func (e *VersionConflictError) Code() int {
+	return ErrCodeConflict
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict || isCodeError(target, e.Code())
+}
+
+// updateIfVersion reads version of the stored item and updates the item, if the version is equal to expectedVersion.
+// Server doesn't support conditions on the versions of the items, so the check is made by the client
+func (db *reindexerImpl) updateIfVersion(ctx context.Context, namespace string, item interface{}, expectedVersion int64) error {
+	namespace = strings.ToLower(namespace)
+
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.UpdateIfVersion", otelattr.String("rx.ns", namespace))
+		defer span.End()
+	}
+
+	if db.promMetrics != nil {
+		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("UpdateIfVersion", namespace)).ObserveDuration()
+	}
+
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return err
+	}
+	if isRawItem(item) {
+		return ErrWrongType
+	}
+	q := db.query(namespace)
+	if err := ns.wherePk(q, item); err != nil {
+		q.close()
+		return err
+	}
+	actual, err := func() (int64, error) {
+		it := q.ExecCtx(ctx)
+		defer it.Close()
+		if !it.Next() {
+			if err := it.Error(); err != nil {
+				return 0, err
+			}
+			return 0, ErrNotFound
+		}
+		return it.Version(), it.Error()
+	}()
+	if err != nil {
+		return err
+	}
+	if actual < 0 {
+		return bindings.NewError("rq: Binding doesn't return versions of the items", ErrCodeParams)
+	}
+	if actual != expectedVersion {
+		return &VersionConflictError{Namespace: namespace, Expected: expectedVersion, Actual: actual}
+	}
+
+	count, err := db.modifyItem(ctx, namespace, nil, item, nil, modeUpdate)
+	if err == nil && count == 0 {
+		// Item was deleted after the check
+		return ErrNotFound
+	}
+	return err
+}
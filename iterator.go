@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	otelattr "go.opentelemetry.io/otel/attribute"
@@ -30,6 +32,14 @@ type ExplainSelector struct {
 	Matched int `json:"matched"`
 	// Count of scanned documents by this selector
 	Items int `json:"items"`
+	// Type of the selector's iterator (e.g. "Comparator", "TwoFieldsComparison", "Skipped")
+	Type string `json:"type,omitempty"`
+	// Description of the skipped selector (e.g. "always false")
+	Description string `json:"description,omitempty"`
+	// Count of the joined query executions (joined selectors only)
+	SelectsCount int `json:"selects_count,omitempty"`
+	// Total time of the joined query executions (joined selectors only)
+	JoinSelectTotalUs int `json:"join_select_total,omitempty"`
 	// Preselect in joined namespace execution explainings
 	ExplainPreselect *ExplainResults `json:"explain_preselect,omitempty"`
 	// One of selects in joined namespace execution explainings
@@ -136,9 +146,25 @@ func newIterator(
 	it.queryContext = queryContext
 	it.resPtr = 0
 	it.ptr = 0
+	it.resultBytes = 0
 	it.err = nil
 	it.userCtx = userCtx
+	it.cancel = nil
+	it.skipped = 0
+	it.resumed = nil
+	it.fetchedPages = 0
+	it.fetchCount = 0
+	it.fetchTime = 0
+	if db != nil && db.adaptiveFetch != nil {
+		it.pageAt = time.Now()
+	}
 	it.allowUnsafe = false
+	if db != nil {
+		it.allowUnsafe = db.allowUnsafe
+	}
+	if q != nil && q.allowUnsafe != nil {
+		it.allowUnsafe = *q.allowUnsafe
+	}
 	joinObjSize := len(it.joinToFields)
 	if q != nil {
 		for _, mq := range q.mergedQueries {
@@ -152,11 +178,22 @@ func newIterator(
 		it.current.joinObj = make([][]interface{}, joinObjSize)
 	}
 	it.setBuffer(result, true)
+	atomic.AddInt64(&db.counters.openIterators, 1)
 
 	return
 }
 
-func newJSONIterator(ctx context.Context, q *Query, json []byte, jsonOffsets []int, explain []byte) *JSONIterator {
+// newObjectsIterator returns iterator over the decoded objects (e.g. results of the transaction's select, merged with the transaction's items)
+func newObjectsIterator(userCtx context.Context, db *reindexerImpl, namespace string, objects []interface{}, totalCount int) *Iterator {
+	if objects == nil {
+		objects = []interface{}{}
+	}
+	it := &Iterator{db: db, namespace: namespace, userCtx: userCtx, objects: objects}
+	it.rawQueryParams = rawResultQueryParams{flags: bindings.ResultsCJson, totalcount: totalCount, qcount: len(objects), count: len(objects)}
+	return it
+}
+
+func newJSONIterator(ctx context.Context, q *Query, json []byte, jsonOffsets []int, explain []byte, aggs [][]byte) *JSONIterator {
 	var ji *JSONIterator
 	if q != nil {
 		ji = &q.jsonIterator
@@ -168,8 +205,11 @@ func newJSONIterator(ctx context.Context, q *Query, json []byte, jsonOffsets []i
 	ji.ptr = -1
 	ji.query = q
 	ji.explain = explain
+	ji.aggs = aggs
 	ji.err = nil
 	ji.userCtx = ctx
+	ji.budget = nil
+	ji.budgetBytes = 0
 
 	return ji
 }
@@ -189,18 +229,48 @@ type Iterator struct {
 	allowUnsafe    bool
 	resPtr         int
 	ptr            int
+	resultBytes    int64
 	current        struct {
 		obj     interface{}
 		joinObj [][]interface{}
 		rank    int
+		// Internal version of the item. -1, if results don't contain item ids
+		version int
+		// Raw params of the item. They are used to decode the item into map
+		params rawResultItemParams
 	}
 	err     error
 	userCtx context.Context
+	// Cancels namespace's default deadline
+	cancel context.CancelFunc
+	// Decoded results, which are not backed by the buffer (see newObjectsIterator)
+	objects []interface{}
+	// Verifier of the items of the sampled query. nil, if the query is not verified (see WithResultVerification)
+	verifier *resultVerifier
+	// Count of the items, which were returned before the last Resume
+	skipped int
+	// Query, which was re-executed by the last Resume. It's closed with the iterator
+	resumed *Query
+	// Count of the merged namespaces of SQL statement. Merged queries of Query are in query.mergedQueries
+	sqlMerged int
+	// Count of the pages of the results, which were fetched after the first one
+	fetchedPages int
+	// State of the adaptive fetch count (see WithAdaptiveFetch): count of the items, requested by the last fetch,
+	// time, when the current page was received, and duration of its fetch
+	fetchCount int
+	pageAt     time.Time
+	fetchTime  time.Duration
 }
 
 func (it *Iterator) setBuffer(result bindings.RawBuffer, cleanup bool) {
-	it.ser = newSerializer(result.GetBuf())
 	it.result = result
+	if it.err = checkResultSize(it.query, &it.resultBytes, len(result.GetBuf())); it.err != nil {
+		if cleanup {
+			it.rawQueryParams = rawResultQueryParams{}
+		}
+		return
+	}
+	it.ser = newSerializer(result.GetBuf())
 	if cleanup {
 		it.rawQueryParams = it.ser.readRawQueryParams(func(nsid int) {
 			it.nsArray[nsid].localCjsonState = it.nsArray[nsid].cjsonState.ReadPayloadType(&it.ser.Serializer, it.db.binding, it.nsArray[nsid].name)
@@ -216,9 +286,38 @@ func (it *Iterator) setBuffer(result bindings.RawBuffer, cleanup bool) {
 // Returns bool, that indicates the availability of the next elements.
 // Decode result to given struct
 func (it *Iterator) NextObj(obj interface{}) (hasNext bool) {
+	if it.db != nil && it.db.pprofLabels {
+		label := ""
+		if it.query != nil {
+			label = it.query.label
+		}
+		it.db.doWithPprofLabels(it.userCtx, "Iterator.Next", it.namespace, label, func() {
+			hasNext = it.nextObj(obj)
+		})
+		return
+	}
+	return it.nextObj(obj)
+}
+
+func (it *Iterator) nextObj(obj interface{}) (hasNext bool) {
 	if it.ptr >= it.rawQueryParams.qcount || it.err != nil {
 		return
 	}
+	if it.rawQueryParams.flags&bindings.ResultsFormatMask == bindings.ResultsPure {
+		// Results were requested without items (see Query.ResultsFlags)
+		return
+	}
+	defer it.db.recoverPanic("Iterator.Next", &it.err)
+	if it.objects != nil {
+		it.current.obj, it.current.version = it.objects[it.ptr], -1
+		if obj != nil {
+			reflect.ValueOf(obj).Elem().Set(reflect.Indirect(reflect.ValueOf(it.current.obj)))
+			it.current.obj = obj
+		}
+		it.resPtr++
+		it.ptr++
+		return true
+	}
 	if it.needMore() {
 		it.fetchResults()
 		if it.err != nil {
@@ -229,6 +328,9 @@ func (it *Iterator) NextObj(obj interface{}) (hasNext bool) {
 	if it.err != nil {
 		return
 	}
+	if it.verifier != nil && it.current.params.nsid == 0 && !it.current.params.json {
+		it.verifyCurrent()
+	}
 	it.resPtr++
 	it.ptr++
 	return it.ptr <= it.rawQueryParams.qcount
@@ -240,7 +342,11 @@ func (it *Iterator) Next() (hasNext bool) {
 
 func (it *Iterator) joinedNsIndexOffset(parentNsID int) int {
 	if it.query == nil {
-		return 1
+		if parentNsID != 0 {
+			// Items, joined by merged queries of SQL statement, are not decoded
+			return -1
+		}
+		return 1 + it.sqlMerged
 	}
 
 	// main NS + count of merged ones
@@ -264,24 +370,54 @@ func (it *Iterator) readItem(toObj interface{}) (item interface{}, rank int) {
 	if (it.rawQueryParams.flags & bindings.ResultsWithPercents) != 0 {
 		rank = params.proc
 	}
+	it.current.version = -1
+	if (it.rawQueryParams.flags & bindings.ResultsWithItemID) != 0 {
+		it.current.version = params.version
+	}
 
 	subNSRes := 0
 
 	if (it.rawQueryParams.flags & bindings.ResultsWithJoined) != 0 {
 		subNSRes = int(it.ser.GetVarUInt())
 	}
+	it.current.params = params
+	if params.json {
+		// Items in JSON format are returned as is, joined items are skipped (see Iterator.JSONBytes)
+		for nsIndex := 0; nsIndex < subNSRes; nsIndex++ {
+			siRes := int(it.ser.GetVarUInt())
+			for i := 0; i < siRes; i++ {
+				it.ser.readRawtItemParams()
+			}
+		}
+		return
+	}
+	if params.nsid >= len(it.nsArray) {
+		it.err = bindings.NewError("rq: Item of merged namespace can't be decoded, because the namespace is not opened", ErrCodeParams)
+		return
+	}
 	item, it.err = unpackItem(it.db.binding, &it.nsArray[params.nsid], &params, it.allowUnsafe && (subNSRes == 0), (it.rawQueryParams.flags&bindings.ResultsWithItemID) == 0, toObj)
 	if it.err != nil {
 		return
 	}
 
 	nsIndexOffset := it.joinedNsIndexOffset(params.nsid)
+	// Joined items of the previous item must not be returned by JoinedItemsFor
+	for i := range it.current.joinObj {
+		it.current.joinObj[i] = nil
+	}
 
 	for nsIndex := 0; nsIndex < subNSRes; nsIndex++ {
 		siRes := int(it.ser.GetVarUInt())
 		if siRes == 0 {
 			continue
 		}
+		if nsIndexOffset < 0 || nsIndex+nsIndexOffset >= len(it.nsArray) {
+			// Namespace of the joined items is unknown
+			for i := 0; i < siRes; i++ {
+				it.ser.readRawtItemParams()
+			}
+			continue
+		}
 		subitems := make([]interface{}, siRes)
 		for i := 0; i < siRes; i++ {
 			subparams := it.ser.readRawtItemParams()
@@ -292,7 +428,9 @@ func (it *Iterator) readItem(toObj interface{}) (item interface{}, rank int) {
 		}
 
 		it.current.joinObj[nsIndex] = subitems
-		it.join(nsIndex, nsIndexOffset, params.nsid, item)
+		if it.join(nsIndex, nsIndexOffset, params.nsid, item); it.err != nil {
+			return
+		}
 	}
 	return
 }
@@ -305,8 +443,12 @@ func (it *Iterator) needMore() bool {
 }
 
 func (it *Iterator) fetchResults() {
+	ctx := it.userCtx
 	if it.db.otelTracer != nil {
-		defer it.db.startTracingSpan(it.userCtx, "Reindexer.Iterator.FetchResults", otelattr.String("rx.ns", it.namespace)).End()
+		var span *tracingSpan
+		ctx, span = it.db.startTracingSpan(ctx, "Reindexer.Iterator.FetchResults", otelattr.String("rx.ns", it.namespace),
+			otelattr.Int("rx.fetch.offset", it.ptr), otelattr.Int("rx.fetch.page", it.fetchedPages+1))
+		defer span.End()
 	}
 
 	if it.db.promMetrics != nil {
@@ -314,10 +456,7 @@ func (it *Iterator) fetchResults() {
 	}
 
 	if fetchMore, ok := it.result.(bindings.FetchMore); ok {
-		fetchCount := defaultFetchCount
-		if it.query != nil {
-			fetchCount = it.query.fetchCount
-		}
+		fetchCount := it.nextFetchCount()
 
 		if it.ptr <= it.rawQueryParams.count {
 			// Copy aggregation results before the first fetch
@@ -336,11 +475,19 @@ func (it *Iterator) fetchResults() {
 			}
 		}
 
-		if it.err = fetchMore.Fetch(it.userCtx, it.ptr, fetchCount, false); it.err != nil {
+		start := time.Now()
+		if err := fetchMore.Fetch(ctx, it.ptr, fetchCount, false); err != nil {
+			it.err = it.fetchError(err)
 			return
 		}
+		it.pageAt = time.Now()
+		it.fetchTime = it.pageAt.Sub(start)
 		it.resPtr = 0
+		it.fetchedPages++
 		it.setBuffer(it.result, false)
+		if it.err != nil {
+			return
+		}
 	} else {
 		panic(fmt.Errorf("unexpected behavior: have the partial query but binding not support that"))
 	}
@@ -368,11 +515,16 @@ func (it *Iterator) join(nsIndex, nsIndexOffset, parentNsID int, item interface{
 	} else {
 
 		v := getJoinedField(reflect.ValueOf(item), it.nsArray[parentNsID].joined, field)
+		if !v.IsValid() && it.query == nil {
+			// SQL query: struct has no field for the joined items, they are available via JoinedItemsFor
+			return
+		}
 		if !v.IsValid() {
-			panic(fmt.Errorf("Can't find field with tag '%s' in struct '%s' for put join results from '%s'",
+			it.err = bindings.NewError(fmt.Sprintf("rq: Can't find field with tag '%s' in struct '%s' for put join results from '%s'",
 				field,
-				it.nsArray[0].rtype,
-				it.nsArray[nsIndex+nsIndexOffset].name))
+				it.nsArray[parentNsID].rtype,
+				it.nsArray[nsIndex+nsIndexOffset].name), ErrCodeParams)
+			return
 		}
 		if v.IsNil() {
 			v.Set(reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(it.nsArray[nsIndex+nsIndexOffset].rtype)), 0, len(subitems)))
@@ -392,6 +544,30 @@ func (it *Iterator) Object() interface{} {
 	return it.current.obj
 }
 
+// ObjectAsMap decodes current object into generic map with the namespace's tags matcher.
+// Joined objects are not included into the map, they are available via JoinedObjects.
+// Will panic when pointer was not moved, Next() must be called before.
+func (it *Iterator) ObjectAsMap() (map[string]interface{}, error) {
+	if it.resPtr == 0 {
+		panic(errIteratorNotReady)
+	}
+	if it.objects != nil {
+		return nil, bindings.NewError("rq: decoded results can't be converted to map", ErrCodeLogic)
+	}
+	params := &it.current.params
+	item := make(map[string]interface{})
+	dec := it.nsArray[params.nsid].localCjsonState.NewDecoder(&item, it.db.binding)
+	var err error
+	if params.json {
+		err = json.Unmarshal(params.data, &item)
+	} else if params.cptr != 0 {
+		err = dec.DecodeCPtr(params.cptr, &item)
+	} else {
+		err = dec.Decode(params.data, &item)
+	}
+	return item, err
+}
+
 // Rank returns current object search rank.
 // Will panic when pointer was not moved, Next() must be called before.
 func (it *Iterator) Rank() int {
@@ -401,13 +577,50 @@ func (it *Iterator) Rank() int {
 	return it.current.rank
 }
 
+// Version returns version (LSN) of the current object in the namespace. Version is changed by each modification of the item,
+// so it may be passed to UpdateIfVersion. Returns -1, if results don't contain versions of the items (see Query.ResultsFlags).
+// Will panic when pointer was not moved, Next() must be called before.
+func (it *Iterator) Version() int64 {
+	if it.resPtr == 0 {
+		panic(errIteratorNotReady)
+	}
+	return int64(it.current.version)
+}
+
+// JSONBytes returns current item in JSON format. Items are returned in JSON format, if the query was executed with Query.RawJSON,
+// otherwise nil is returned. The slice references the results buffer, so it's valid only until the next call of Next or Close.
+// Will panic when pointer was not moved, Next() must be called before.
+func (it *Iterator) JSONBytes() []byte {
+	if it.resPtr == 0 {
+		panic(errIteratorNotReady)
+	}
+	if !it.current.params.json {
+		return nil
+	}
+	return it.current.params.data
+}
+
 // JoinedObjects returns objects slice, that result of join for the given field
 func (it *Iterator) JoinedObjects(field string) (objects []interface{}, err error) {
+	return it.JoinedItemsFor(field)
+}
+
+// JoinedItemsFor returns joined items of the current item for the given field (the join field of InnerJoin/LeftJoin).
+// Joined items are available, even if the struct has no field for them and JoinHandler is not registered
+func (it *Iterator) JoinedItemsFor(field string) ([]interface{}, error) {
 	if it.resPtr == 0 {
 		return nil, errIteratorNotReady
 	}
-	idx := it.findJoinFieldIndex(field)
-	if idx == -1 {
+	joinToFields := it.joinToFields
+	if nsid := it.current.params.nsid; nsid > 0 && it.query != nil {
+		// Item of the merged query
+		joinToFields = it.query.mergedQueries[nsid-1].joinToFields
+	} else if nsid > 0 {
+		// Items, joined by merged queries of SQL statement, are not decoded
+		return nil, errJoinUnexpectedField
+	}
+	idx := findJoinFieldIndex(joinToFields, field)
+	if idx == -1 || idx >= len(it.current.joinObj) {
 		return nil, errJoinUnexpectedField
 	}
 	return it.current.joinObj[idx], nil
@@ -429,6 +642,7 @@ func (it *Iterator) TotalCount() int {
 // That means possible race conditions. But it's good speedup, without overhead for copying.
 //
 // By default reindexer guarantees that every object its safe to use in multithread.
+// Default for the DB is set by WithAllowUnsafe and may be overridden for the query by Query.AllowUnsafe.
 func (it *Iterator) AllowUnsafe(allow bool) *Iterator {
 	it.allowUnsafe = allow
 	return it
@@ -485,6 +699,42 @@ func (it *Iterator) FetchAllWithRank() (items []interface{}, ranks []int, err er
 	return
 }
 
+// Collect appends all query results to dst and closes the iterator. dst must be a pointer to a slice of the namespace's struct
+// or of pointers to it (*[]Item or *[]*Item). Capacity of the slice is allocated once by the count of the query results
+func (it *Iterator) Collect(dst interface{}) (err error) {
+	defer it.Close()
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("rq: Collect requires pointer to slice, got %T", dst)
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	if it.err != nil {
+		return it.err
+	}
+	if free := slice.Cap() - slice.Len(); free < it.rawQueryParams.qcount {
+		grown := reflect.MakeSlice(slice.Type(), slice.Len(), slice.Len()+it.rawQueryParams.qcount)
+		reflect.Copy(grown, slice)
+		slice = grown
+	}
+	for it.Next() {
+		obj := reflect.ValueOf(it.Object())
+		switch {
+		case obj.Type() == elemType:
+		case obj.Kind() == reflect.Ptr && !obj.IsNil() && obj.Elem().Type() == elemType:
+			obj = obj.Elem()
+		default:
+			return fmt.Errorf("rq: can't convert item of type %s to %s", obj.Type(), elemType)
+		}
+		slice = reflect.Append(slice, obj)
+	}
+	if it.err != nil {
+		return it.err
+	}
+	v.Elem().Set(slice)
+	return nil
+}
+
 // HasRank indicates if this iterator has info about search ranks.
 func (it *Iterator) HasRank() bool {
 	return (it.rawQueryParams.flags & bindings.ResultsWithPercents) != 0
@@ -513,7 +763,7 @@ func (it *Iterator) GetAggreatedValue(idx int) *float64 {
 	return res.Value
 }
 
-// GetExplainResults returns JSON bytes with explain results
+// GetExplainResults returns parsed explain results of the query, requested by Query.Explain. Returns nil, if explain was not requested
 func (it *Iterator) GetExplainResults() (*ExplainResults, error) {
 	if len(it.rawQueryParams.explainResults) > 0 {
 		explain := &ExplainResults{}
@@ -532,20 +782,29 @@ func (it *Iterator) Error() error {
 
 // Close closes the iterator and freed CGO resources
 func (it *Iterator) Close() {
+	if it.cancel != nil {
+		it.cancel()
+		it.cancel = nil
+	}
 	if it.result != nil {
 		it.rawQueryParams.aggResults = nil
 		it.rawQueryParams.explainResults = nil
 		it.result.Free()
 		it.result = nil
+		atomic.AddInt64(&it.db.counters.openIterators, -1)
 		if it.query != nil {
 			it.query.close()
 		}
+		if it.resumed != nil {
+			it.resumed.close()
+			it.resumed = nil
+		}
 	}
 }
 
-func (it *Iterator) findJoinFieldIndex(field string) (index int) {
-	for index = range it.joinToFields {
-		if strings.EqualFold(it.joinToFields[index], field) {
+func findJoinFieldIndex(joinToFields []string, field string) (index int) {
+	for index = range joinToFields {
+		if strings.EqualFold(joinToFields[index], field) {
 			return
 		}
 	}
@@ -560,7 +819,17 @@ type JSONIterator struct {
 	err         error
 	ptr         int
 	explain     []byte
+	aggs        [][]byte
 	userCtx     context.Context
+	// Memory budget, which is charged by size of JSON buffer until the iterator is closed
+	budget      *memoryBudget
+	budgetBytes int64
+}
+
+func (it *JSONIterator) chargeBudget(budget *memoryBudget) {
+	it.budget = budget
+	it.budgetBytes = int64(cap(it.json))
+	budget.chargeJSON(it.budgetBytes)
 }
 
 // Next moves iterator pointer to the next element.
@@ -591,7 +860,7 @@ func (it *JSONIterator) JSON() (json []byte) {
 	return it.json[o:l]
 }
 
-// GetExplainResults returns JSON bytes with explain results
+// GetExplainResults returns parsed explain results of the query, requested by Query.Explain. Returns nil, if explain was not requested
 func (it *JSONIterator) GetExplainResults() (*ExplainResults, error) {
 	if len(it.explain) > 0 {
 		explain := &ExplainResults{}
@@ -603,6 +872,15 @@ func (it *JSONIterator) GetExplainResults() (*ExplainResults, error) {
 	return nil, nil
 }
 
+// AggResults returns aggregation results (if present)
+func (it *JSONIterator) AggResults() (v []AggregationResult) {
+	v = make([]AggregationResult, len(it.aggs))
+	for i := range it.aggs {
+		json.Unmarshal(it.aggs[i], &v[i])
+	}
+	return
+}
+
 // Count returns count if query results
 func (it *JSONIterator) Count() int {
 	return len(it.jsonOffsets)
@@ -615,6 +893,10 @@ func (it *JSONIterator) Error() error {
 
 // Close closes the iterator.
 func (it *JSONIterator) Close() {
+	if it.budget != nil {
+		it.budget.releaseJSON(it.budgetBytes)
+		it.budget = nil
+	}
 	if it.query != nil {
 		it.query.close()
 		it.query = nil
@@ -0,0 +1,110 @@
+package reindexer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// WriteNDJSON writes the rest of the results to w in NDJSON format: one JSON document per line.
+// Documents are written as they are received from the server, without decoding into structs
+func (it *JSONIterator) WriteNDJSON(w io.Writer) error {
+	if it.err != nil {
+		return it.err
+	}
+	bw := bufio.NewWriter(w)
+	for it.Next() {
+		bw.Write(it.JSON())
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteCSV writes the rest of the results to w in CSV format. The first row contains names of the fields.
+// Nested fields are set by dotted path, e.g. "price.value". If fields are not set, top level fields of the first document are written.
+// Strings are written without quotes, missing and null fields are written as empty values, arrays and objects are written as JSON
+func (it *JSONIterator) WriteCSV(w io.Writer, fields ...string) error {
+	if it.err != nil {
+		return it.err
+	}
+	cw := csv.NewWriter(w)
+	row := make([]string, len(fields))
+	header := len(fields) != 0
+	if header {
+		if err := cw.Write(fields); err != nil {
+			return err
+		}
+	}
+	for it.Next() {
+		doc := it.JSON()
+		if !header {
+			fields = jsonKeys(doc)
+			row = make([]string, len(fields))
+			if err := cw.Write(fields); err != nil {
+				return err
+			}
+			header = true
+		}
+		for i, field := range fields {
+			row[i] = csvValue(jsonField(doc, field))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// jsonKeys returns top level keys of the JSON object in the order of the document
+func jsonKeys(doc []byte) (keys []string) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, t.(string))
+		var skip json.RawMessage
+		if err = dec.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+// jsonField returns value of the field of the JSON object by dotted path, or nil, if there is no such field
+func jsonField(doc []byte, path string) json.RawMessage {
+	value := json.RawMessage(doc)
+	for _, name := range strings.Split(path, ".") {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(value, &obj); err != nil {
+			return nil
+		}
+		if value = obj[name]; value == nil {
+			return nil
+		}
+	}
+	return value
+}
+
+func csvValue(value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	if value[0] == '"' {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			return s
+		}
+	}
+	return string(value)
+}
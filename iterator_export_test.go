@@ -0,0 +1,41 @@
+package reindexer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONIteratorExport(t *testing.T) {
+	db, srv := newMockDB(t, "export")
+	defer db.Close()
+
+	srv.SetResults(testNs,
+		map[string]interface{}{"id": 1, "name": "first, \"quoted\"", "price": map[string]interface{}{"value": 10.5}, "tags": []string{"a"}},
+		map[string]interface{}{"id": 2, "name": nil},
+	)
+
+	var ndjson bytes.Buffer
+	it := db.Query(testNs).ExecToJson()
+	require.NoError(t, it.WriteNDJSON(&ndjson))
+	it.Close()
+	lines := strings.Split(strings.TrimSuffix(ndjson.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id":2,"name":null}`, lines[1])
+
+	var csvOut bytes.Buffer
+	it = db.Query(testNs).ExecToJson()
+	require.NoError(t, it.WriteCSV(&csvOut, "id", "name", "price.value", "tags"))
+	it.Close()
+	assert.Equal(t, "id,name,price.value,tags\n1,\"first, \"\"quoted\"\"\",10.5,\"[\"\"a\"\"]\"\n2,,,\n", csvOut.String())
+
+	// Without fields the header is made of the first document's fields
+	csvOut.Reset()
+	it = db.Query(testNs).ExecToJson()
+	require.NoError(t, it.WriteCSV(&csvOut))
+	it.Close()
+	assert.True(t, strings.HasPrefix(csvOut.String(), "id,name,price,tags\n"), csvOut.String())
+}
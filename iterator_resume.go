@@ -0,0 +1,152 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// ErrIteratorInterrupted may be used with errors.Is to check, if the error is IteratorError
+var ErrIteratorInterrupted = bindings.NewError("rq: Iteration is interrupted", ErrCodeNetwork)
+
+// IteratorError is returned by Iterator.Error, when fetching of the next results fails in the middle of the iteration
+// (e.g. on network failure). It contains position of the iterator, so the iteration may be continued by Iterator.Resume
+type IteratorError struct {
+	Namespace string
+	// Count of the items, which were returned by Next before the failure (including the items before Resume)
+	Consumed int
+	// Offset of the next item in the query's results: offset of the query plus Consumed
+	Offset int
+	// RetrySafe is true, if order of the query's results is deterministic (the query is sorted by primary key,
+	// e.g. with pk tiebreaker of SortMulti), so Resume doesn't skip or repeat the items
+	RetrySafe bool
+	err       error
+}
+
+func (e *IteratorError) Error() string {
+	return fmt.Sprintf("rq: Iteration of namespace '%s' is interrupted after %d items (offset %d, retry safe %t): %v",
+		e.Namespace, e.Consumed, e.Offset, e.RetrySafe, e.err)
+}
+
+// Code returns code of the fetch error, or ErrCodeNetwork, if the error has no code
+func (e *IteratorError) Code() int {
+	if rerr, ok := e.err.(bindings.Error); ok {
+		return rerr.Code()
+	}
+	return ErrCodeNetwork
+}
+
+func (e *IteratorError) Is(target error) bool {
+	return target == ErrIteratorInterrupted || isCodeError(target, e.Code())
+}
+
+func (e *IteratorError) Unwrap() error {
+	return e.err
+}
+
+// fetchError enriches error of fetching the next results with the position of the iterator
+func (it *Iterator) fetchError(err error) error {
+	ierr := &IteratorError{Namespace: it.namespace, Consumed: it.skipped + it.ptr, err: err}
+	ierr.Offset = ierr.Consumed
+	if it.query == nil {
+		return ierr
+	}
+	d, derr := it.query.toDSL()
+	if derr != nil {
+		return ierr
+	}
+	if d.Offset != nil {
+		ierr.Offset += *d.Offset
+	}
+	ierr.RetrySafe = len(it.query.mergedQueries) == 0 && it.isOrderDeterministic()
+	return ierr
+}
+
+// isOrderDeterministic checks, that the query is sorted by primary key, so the items, which are equal by the other sort entries, are ordered too
+func (it *Iterator) isOrderDeterministic() bool {
+	ns, err := it.db.getNS(it.namespace)
+	if err != nil {
+		return false
+	}
+	for _, entry := range it.query.sortEntries {
+		for _, indexDef := range ns.indexes {
+			if indexDef.IsPK && strings.EqualFold(entry.Field, indexDef.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Resume re-executes the query from the position of the interrupted iteration (see IteratorError), so the next call of Next
+// returns the item after the last returned one. Resume is allowed only if IteratorError.RetrySafe is true.
+// Total count and aggregations of the iterator are replaced by the ones of the re-executed query
+func (it *Iterator) Resume(ctx context.Context) error {
+	ierr, ok := it.err.(*IteratorError)
+	if !ok {
+		return bindings.NewError("rq: Iterator is not interrupted by fetch error", ErrCodeLogic)
+	}
+	if !ierr.RetrySafe {
+		return bindings.NewError("rq: Iterator can't be resumed, because order of the query's results is not deterministic", ErrCodeLogic)
+	}
+	d, err := it.query.toDSL()
+	if err != nil {
+		return err
+	}
+
+	q := it.query
+	rq := q.makeCopy(q.db, nil)
+	rq.resetExecState()
+	rq.Offset(ierr.Offset)
+	if d.Limit != nil {
+		limit := *d.Limit - ierr.Consumed
+		if limit < 0 {
+			limit = 0
+		}
+		rq.Limit(limit)
+	}
+	rit := rq.ExecCtx(ctx)
+	if err = rit.Error(); err != nil {
+		rit.Close()
+		return err
+	}
+
+	// Results of the interrupted execution are released, and the iterator takes over the results of the re-executed query
+	if it.cancel != nil {
+		it.cancel()
+	}
+	if it.result != nil {
+		it.result.Free()
+		atomic.AddInt64(&it.db.counters.openIterators, -1)
+	}
+	if it.resumed != nil {
+		it.resumed.close()
+	}
+	*it = *rit
+	rit.result, rit.cancel = nil, nil
+	it.query = q
+	it.resumed = rq
+	it.skipped = ierr.Consumed
+	return nil
+}
+
+// resetExecState returns the copy of the executed query to the state before the execution: namespaces of the results are refilled,
+// and the filters, which were added on execution, are added again with the context of the re-execution
+func (q *Query) resetExecState() {
+	q.executed = false
+	q.nsArray = q.nsArray[:0]
+	q.ptVersions = q.ptVersions[:0]
+	q.resetFilters()
+	for _, sq := range q.joinQueries {
+		sq.resetFilters()
+	}
+	for _, mq := range q.mergedQueries {
+		mq.resetFilters()
+		for _, sq := range mq.joinQueries {
+			sq.resetFilters()
+		}
+	}
+}
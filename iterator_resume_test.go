@@ -0,0 +1,111 @@
+package reindexer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestIteratorResume(t *testing.T) {
+	db, srv := newMockDB(t, "resume")
+	defer db.Close()
+
+	items := []interface{}{testItem{ID: 1}, testItem{ID: 2}, testItem{ID: 3}, testItem{ID: 4}, testItem{ID: 5}}
+	selects := 0
+	srv.OnSelect(func(call mock.Call) ([]interface{}, error) {
+		selects++
+		if selects == 1 {
+			return items, nil
+		}
+		// Re-executed query starts from the offset of the interrupted one
+		return items[2:], nil
+	})
+	srv.SetFetchLimit(2)
+	srv.SetError(mock.MethodFetchResults, errors.New("connection reset"))
+
+	it := db.Query(testNs).Sort("id", false).Offset(1).Exec()
+	defer it.Close()
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Object().(*testItem).ID)
+	}
+	assert.Equal(t, []int{1, 2}, ids)
+
+	var ierr *reindexer.IteratorError
+	require.True(t, errors.As(it.Error(), &ierr))
+	assert.True(t, errors.Is(it.Error(), reindexer.ErrIteratorInterrupted))
+	assert.Equal(t, testNs, ierr.Namespace)
+	assert.Equal(t, 2, ierr.Consumed)
+	assert.Equal(t, 3, ierr.Offset)
+	assert.True(t, ierr.RetrySafe)
+	assert.EqualError(t, errors.Unwrap(ierr), "connection reset")
+
+	srv.SetError(mock.MethodFetchResults, nil)
+	require.NoError(t, it.Resume(context.Background()))
+	for it.Next() {
+		ids = append(ids, it.Object().(*testItem).ID)
+	}
+	require.NoError(t, it.Error())
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, ids)
+	assert.Equal(t, 2, selects)
+
+	// Order of the unsorted query is not deterministic
+	srv.SetError(mock.MethodFetchResults, errors.New("connection reset"))
+	selects = 0
+	it = db.Query(testNs).Exec()
+	defer it.Close()
+	for it.Next() {
+	}
+	require.True(t, errors.As(it.Error(), &ierr))
+	assert.False(t, ierr.RetrySafe)
+	assert.Error(t, it.Resume(context.Background()))
+}
+
+func TestIteratorResumeSoftDelete(t *testing.T) {
+	db, srv := mock.NewDB("resume_soft_delete")
+	defer db.Close()
+	require.NoError(t, db.OpenNamespace(testNs, reindexer.DefaultNamespaceOptions().WithSoftDelete("deleted_at"), testSoftDeleteItem{}))
+
+	items := []interface{}{testSoftDeleteItem{ID: 1}, testSoftDeleteItem{ID: 2}, testSoftDeleteItem{ID: 3}}
+	selects := 0
+	srv.OnSelect(func(call mock.Call) ([]interface{}, error) {
+		selects++
+		if selects == 1 {
+			return items, nil
+		}
+		return items[2:], nil
+	})
+	srv.SetFetchLimit(2)
+	srv.SetError(mock.MethodFetchResults, errors.New("connection reset"))
+
+	it := db.Query(testNs).Sort("id", false).Exec()
+	defer it.Close()
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Object().(*testSoftDeleteItem).ID)
+	}
+	require.Error(t, it.Error())
+	srv.SetError(mock.MethodFetchResults, nil)
+	require.NoError(t, it.Resume(context.Background()))
+	assert.Equal(t, 1, it.Count())
+	for it.Next() {
+		ids = append(ids, it.Object().(*testSoftDeleteItem).ID)
+	}
+	require.NoError(t, it.Error())
+	assert.Equal(t, []int{1, 2, 3}, ids)
+
+	// Re-executed query has payload types and filters of the same namespaces, as the interrupted one
+	calls := srv.CallsOf(mock.MethodSelectQuery)
+	require.Len(t, calls, 2)
+	for _, call := range calls {
+		assert.Len(t, call.PtVersions, 1)
+		assert.Equal(t, 1, bytes.Count(call.Query, []byte("deleted_at")))
+	}
+}
@@ -0,0 +1,64 @@
+package reindexer
+
+import "context"
+
+// StreamItem is the item of the query results, delivered by Iterator.Stream.
+// The last item of the stream contains only Err, if the iteration is failed
+type StreamItem struct {
+	Item interface{}
+	// Rank of the item. It's valid only for full text queries
+	Rank int
+	Err  error
+}
+
+// Stream decodes the query results in the background goroutine and delivers them to the returned channel.
+// The next chunk of results (see Query.FetchCount) is fetched, while the consumer processes the previous one.
+// The channel is closed, when all the results are delivered, after the error or when ctx is canceled.
+// The iterator is closed by the stream and must not be used by the caller after this call
+func (it *Iterator) Stream(ctx context.Context) <-chan StreamItem {
+	fetchCount := defaultFetchCount
+	if it.query != nil && it.query.fetchCount > 0 {
+		fetchCount = it.query.fetchCount
+	}
+	ch := make(chan StreamItem, fetchCount)
+	go func() {
+		defer close(ch)
+		defer it.Close()
+		for it.Next() {
+			select {
+			case ch <- StreamItem{Item: it.Object(), Rank: it.Rank()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := it.Error(); err != nil {
+			select {
+			case ch <- StreamItem{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch
+}
+
+// ForEach calls fn for each item of the query results and closes the iterator. Results are fetched in the background (see Stream).
+// Iteration stops on the first error of fn, which is returned by ForEach
+func (it *Iterator) ForEach(fn func(item interface{}) error) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := it.Stream(ctx)
+	defer func() {
+		cancel()
+		// Wait for the background goroutine to close the iterator
+		for range ch {
+		}
+	}()
+	for si := range ch {
+		if si.Err != nil {
+			return si.Err
+		}
+		if err = fn(si.Item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package reindexer_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestRawJSON(t *testing.T) {
+	db, srv := newMockDB(t, "rawjson")
+	defer db.Close()
+
+	items := []interface{}{
+		testItem{ID: 1, Name: "first"},
+		testItem{ID: 2, Name: "second"},
+		testItem{ID: 3, Name: "third"},
+		testItem{ID: 4, Name: "fourth"},
+		testItem{ID: 5, Name: "fifth"},
+	}
+	srv.SetResults(testNs, items...)
+	srv.SetFetchLimit(2)
+
+	// Items are returned in JSON format by pages without decoding
+	it := db.Query(testNs).RawJSON().Exec()
+	var docs []testItem
+	for it.Next() {
+		assert.Nil(t, it.Object())
+		var doc testItem
+		require.NoError(t, json.Unmarshal(it.JSONBytes(), &doc))
+		docs = append(docs, doc)
+		if doc.ID == 1 {
+			m, err := it.ObjectAsMap()
+			require.NoError(t, err)
+			assert.Equal(t, "first", m["Name"])
+		}
+	}
+	require.NoError(t, it.Error())
+	it.Close()
+	require.Len(t, docs, len(items))
+	for i, item := range items {
+		assert.Equal(t, item, docs[i])
+	}
+	assert.Len(t, srv.CallsOf(mock.MethodFetchResults), 2)
+
+	// Items of the usual queries are not available as JSON
+	it = db.Query(testNs).Exec()
+	require.True(t, it.Next())
+	assert.Nil(t, it.JSONBytes())
+	it.Close()
+}
@@ -0,0 +1,69 @@
+package reindexer
+
+import (
+	"context"
+)
+
+// defaultJSONExecutorMaxRetained is max capacity of JSON buffer in bytes, which is retained by JSONExecutor between the calls
+const defaultJSONExecutorMaxRetained = 4 << 20
+
+// JSONExecutor executes queries to the namespace and returns results in JSON format, reusing JSON buffer and offsets of
+// the results between the calls, so high-QPS JSON endpoints don't allocate them for each query.
+// JSONExecutor is not safe for concurrent use: it should be owned by a goroutine (e.g. a worker) or taken from sync.Pool.
+// Results of the previous call must be closed before the next call
+type JSONExecutor struct {
+	db          *Reindexer
+	namespace   string
+	maxRetained int
+	json        []byte
+	jsonOffsets []int
+	// Iterator over the results of the last call
+	iterator JSONIterator
+}
+
+// NewJSONExecutor creates executor of the queries to the namespace with results in JSON format
+func (db *Reindexer) NewJSONExecutor(namespace string) *JSONExecutor {
+	return &JSONExecutor{db: db, namespace: namespace, maxRetained: defaultJSONExecutorMaxRetained}
+}
+
+// MaxRetained sets max capacity of JSON buffer in bytes, which is retained between the calls. Larger buffers are released
+// after the results are closed, so single huge response doesn't pin memory. Buffer is always retained, if maxBytes is 0
+func (e *JSONExecutor) MaxRetained(maxBytes int) *JSONExecutor {
+	e.maxRetained = maxBytes
+	return e
+}
+
+// Query creates query to the namespace of the executor
+func (e *JSONExecutor) Query() *Query {
+	return e.db.Query(e.namespace)
+}
+
+// Exec executes the query and returns iterator over the results, which are placed in the buffers of the executor
+func (e *JSONExecutor) Exec(q *Query, jsonRoots ...string) *JSONIterator {
+	return e.ExecCtx(context.Background(), q, jsonRoots...)
+}
+
+// ExecCtx executes the query and returns iterator over the results, which are placed in the buffers of the executor.
+// Returned iterator is valid until the next call of the executor and must be closed before it
+func (e *JSONExecutor) ExecCtx(ctx context.Context, q *Query, jsonRoots ...string) *JSONIterator {
+	if e.iterator.query != nil {
+		return errJSONIterator(ErrJSONExecutorBusy)
+	}
+	if q.root != nil {
+		q = q.root
+	}
+	q.json, q.jsonOffsets = e.json, e.jsonOffsets
+	it := q.ExecToJsonCtx(ctx, jsonRoots...)
+	// Buffers are taken back from the query, because it's returned to the pool, when the results are closed
+	e.json, e.jsonOffsets = q.json, q.jsonOffsets
+	q.json, q.jsonOffsets = nil, nil
+	if e.maxRetained > 0 && cap(e.json) > e.maxRetained {
+		e.json, e.jsonOffsets = nil, nil
+	}
+	if it.err != nil {
+		return it
+	}
+	e.iterator = *it
+	it.query, it.budget = nil, nil
+	return &e.iterator
+}
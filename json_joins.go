@@ -0,0 +1,117 @@
+package reindexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+const serverJoinedFieldPrefix = "joined_"
+
+// jsonJoins adds joined items to the items of ExecToJson output. Server embeds joined items into the item's JSON as
+// 'joined_<namespace>' fields, so the joined queries of the same namespace are mixed. These fields are replaced by
+// the fields, named as the joined fields of the query (see Join)
+type jsonJoins struct {
+	q *Query
+	// Fields, which are embedded by the server
+	embedded map[string]struct{}
+}
+
+// newJSONJoins returns nil, if the query (and its merged queries) has no joined queries
+func newJSONJoins(q *Query) *jsonJoins {
+	j := &jsonJoins{q: q, embedded: make(map[string]struct{})}
+	for _, jq := range q.joinQueries {
+		j.embedded[serverJoinedFieldPrefix+jq.Namespace] = struct{}{}
+	}
+	for _, mq := range q.mergedQueries {
+		for _, jq := range mq.joinQueries {
+			j.embedded[serverJoinedFieldPrefix+jq.Namespace] = struct{}{}
+		}
+	}
+	if len(j.embedded) == 0 {
+		return nil
+	}
+	return j
+}
+
+// joinToFields returns names of the joined fields of the query of the item from nsid namespace
+func (j *jsonJoins) joinToFields(nsid int) []string {
+	if nsid > 0 && nsid <= len(j.q.mergedQueries) {
+		return j.q.mergedQueries[nsid-1].joinToFields
+	}
+	return j.q.joinToFields
+}
+
+// fieldName returns name of the field for the joined items of nsIndex'th joined query of the item from nsid namespace
+func (j *jsonJoins) fieldName(nsid, nsIndex int) string {
+	if joinToFields := j.joinToFields(nsid); nsIndex < len(joinToFields) {
+		return joinToFields[nsIndex]
+	}
+	return serverJoinedFieldPrefix + strconv.Itoa(nsIndex)
+}
+
+// writeItem writes the item's JSON without the fields, embedded by the server, and with joined items of each joined query,
+// which are read from the results. subNSRes is 0 for the items without joined results: they get empty arrays
+func (j *jsonJoins) writeItem(w *cjson.Serializer, data []byte, nsid int, ser *resultSerializer, subNSRes int) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return bindings.NewError("rq: Can't add joined items to non-object JSON", ErrCodeLogic)
+	}
+	w.WriteString("{")
+	hasFields := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return bindings.NewError("rq: Can't parse JSON of the item: "+err.Error(), ErrCodeParseJson)
+		}
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return bindings.NewError("rq: Can't parse JSON of the item: "+err.Error(), ErrCodeParseJson)
+		}
+		key, _ := tok.(string)
+		if _, ok := j.embedded[key]; ok {
+			continue
+		}
+		if hasFields {
+			w.WriteString(",")
+		}
+		hasFields = true
+		writeJSONString(w, key)
+		w.WriteString(":")
+		w.Write(value)
+	}
+
+	fieldsCount := subNSRes
+	if subNSRes == 0 {
+		fieldsCount = len(j.joinToFields(nsid))
+	}
+	for nsIndex := 0; nsIndex < fieldsCount; nsIndex++ {
+		if hasFields {
+			w.WriteString(",")
+		}
+		hasFields = true
+		writeJSONString(w, j.fieldName(nsid, nsIndex))
+		w.WriteString(":[")
+		siRes := 0
+		if subNSRes != 0 {
+			siRes = int(ser.GetVarUInt())
+		}
+		for si := 0; si < siRes; si++ {
+			if si != 0 {
+				w.WriteString(",")
+			}
+			w.Write(ser.readRawtItemParams().data)
+		}
+		w.WriteString("]")
+	}
+	w.WriteString("}")
+	return nil
+}
+
+func writeJSONString(w *cjson.Serializer, s string) {
+	b, _ := json.Marshal(s)
+	w.Write(b)
+}
@@ -129,6 +129,10 @@ type Reflector struct {
 
 	// FieldIsInScheme checks if field should be in scheme
 	FieldIsInScheme func(reflect.StructField) bool
+
+	// FieldEmbedding checks if properties of the struct field should be inherited by current type, and returns
+	// prefix of their names. Anonymous fields without json tag are inherited, if it's not set
+	FieldEmbedding func(reflect.StructField) (bool, string)
 }
 
 // Reflect reflects to Schema from a value.
@@ -151,7 +155,7 @@ func (r *Reflector) ReflectFromType(t reflect.Type) *Schema {
 		if r.AllowAdditionalProperties {
 			st.AdditionalProperties = []byte("true")
 		}
-		r.reflectStructFields(st, definitions, parentTypes, t)
+		r.reflectStructFields(st, definitions, parentTypes, t, "")
 		r.reflectStruct(definitions, parentTypes, t)
 		delete(definitions, r.typeName(t))
 		return &Schema{Type: st, Definitions: definitions}
@@ -328,7 +332,7 @@ func (r *Reflector) reflectStruct(definitions Definitions, parentTypes Definitio
 	}
 	definitions[r.typeName(t)] = st
 	parentTypes[r.typeName(t)] = st
-	r.reflectStructFields(st, definitions, parentTypes, t)
+	r.reflectStructFields(st, definitions, parentTypes, t, "")
 
 	if r.DoNotReference {
 		return st
@@ -340,7 +344,7 @@ func (r *Reflector) reflectStruct(definitions Definitions, parentTypes Definitio
 	}
 }
 
-func (r *Reflector) reflectStructFields(st *Type, definitions Definitions, parentTypes Definitions, t reflect.Type) {
+func (r *Reflector) reflectStructFields(st *Type, definitions Definitions, parentTypes Definitions, t reflect.Type, prefix string) {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
@@ -360,14 +364,24 @@ func (r *Reflector) reflectStructFields(st *Type, definitions Definitions, paren
 			continue
 		}
 
+		if r.FieldEmbedding != nil {
+			if inherit, fieldsPrefix := r.FieldEmbedding(f); inherit {
+				r.reflectStructFields(st, definitions, parentTypes, f.Type, prefix+fieldsPrefix)
+				continue
+			} else if name == "" && f.Anonymous {
+				name = f.Name
+			}
+		}
+
 		// if anonymous and exported type should be processed recursively
 		// current type should inherit properties of anonymous one
 		if name == "" {
 			if f.Anonymous && !exist {
-				r.reflectStructFields(st, definitions, parentTypes, f.Type)
+				r.reflectStructFields(st, definitions, parentTypes, f.Type, prefix)
 			}
 			continue
 		}
+		name = prefix + name
 
 		property := r.reflectTypeToSchema(definitions, parentTypes, f.Type)
 		property.structKeywordsFromTags(f, st, name)
@@ -0,0 +1,50 @@
+package reindexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ZapSugaredLogger is the subset of the methods of *zap.SugaredLogger, which is used by NewZapLogger
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type zapLogger struct {
+	l ZapSugaredLogger
+}
+
+// NewZapLogger returns the adapter of zap's logger, which may be passed to SetLogger, e.g. NewZapLogger(logger.Sugar()).
+// Levels are mapped as ERROR - Error, WARNING - Warn, INFO - Info, TRACE - Debug
+func NewZapLogger(l ZapSugaredLogger) StructuredLogger {
+	return zapLogger{l: l}
+}
+
+func (z zapLogger) Printf(level int, format string, msg ...interface{}) {
+	z.Log(level, logMessage(format, msg...))
+}
+
+func (z zapLogger) Log(level int, msg string, fields ...LogField) {
+	kv := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		kv = append(kv, f.Key, f.Value)
+	}
+	switch level {
+	case ERROR:
+		z.l.Errorw(msg, kv...)
+	case WARNING:
+		z.l.Warnw(msg, kv...)
+	case INFO:
+		z.l.Infow(msg, kv...)
+	default:
+		z.l.Debugw(msg, kv...)
+	}
+}
+
+// logMessage formats the message of Printf without the trailing new line, which is not expected by structured loggers
+func logMessage(format string, msg ...interface{}) string {
+	return strings.TrimRight(fmt.Sprintf(format, msg...), "\n ")
+}
@@ -0,0 +1,46 @@
+//go:build go1.21
+
+package reindexer
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns the adapter of slog's logger, which may be passed to SetLogger.
+// Levels are mapped as ERROR - LevelError, WARNING - LevelWarn, INFO - LevelInfo, TRACE - LevelDebug
+func NewSlogLogger(l *slog.Logger) StructuredLogger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) Printf(level int, format string, msg ...interface{}) {
+	s.Log(level, logMessage(format, msg...))
+}
+
+func (s slogLogger) Log(level int, msg string, fields ...LogField) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	s.l.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+func (s slogLogger) Enabled(level int) bool {
+	return s.l.Enabled(context.Background(), slogLevel(level))
+}
+
+func slogLevel(level int) slog.Level {
+	switch level {
+	case ERROR:
+		return slog.LevelError
+	case WARNING:
+		return slog.LevelWarn
+	case INFO:
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
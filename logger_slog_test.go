@@ -0,0 +1,42 @@
+//go:build go1.21
+
+package reindexer_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestSlogLogger(t *testing.T) {
+	db, srv := newMockDB(t, "slog")
+	defer db.Close()
+
+	var out bytes.Buffer
+	db.SetLogger(reindexer.NewSlogLogger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	srv.SetError(mock.MethodSelectQuery, bindings.NewError("query is broken", bindings.ErrQueryExec))
+	_, err := db.Query(testNs).Exec().FetchAll()
+	require.Error(t, err)
+
+	assert.Contains(t, out.String(), `level=ERROR msg="rq: query failed" namespace=items query="SELECT * FROM items"`)
+	assert.Contains(t, out.String(), `error="query is broken" error_code=2`)
+}
+
+func TestSlogLoggerDisabledLevel(t *testing.T) {
+	db, srv := newMockDB(t, "slog_level")
+	defer db.Close()
+
+	var out bytes.Buffer
+	db.SetLogger(reindexer.NewSlogLogger(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.Level(100)}))))
+	srv.SetError(mock.MethodSelectQuery, bindings.NewError("query is broken", bindings.ErrQueryExec))
+	_, err := db.Query(testNs).Exec().FetchAll()
+	require.Error(t, err)
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,74 @@
+package reindexer_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+type testLogRecord struct {
+	level  int
+	msg    string
+	fields map[string]interface{}
+}
+
+type testStructuredLogger struct {
+	records []testLogRecord
+}
+
+func (l *testStructuredLogger) Printf(level int, format string, msg ...interface{}) {
+	l.Log(level, fmt.Sprintf(format, msg...))
+}
+
+func (l *testStructuredLogger) Log(level int, msg string, fields ...bindings.LogField) {
+	rec := testLogRecord{level: level, msg: msg, fields: make(map[string]interface{})}
+	for _, f := range fields {
+		rec.fields[f.Key] = f.Value
+	}
+	l.records = append(l.records, rec)
+}
+
+type testPlainLogger struct {
+	lines []string
+}
+
+func (l *testPlainLogger) Printf(level int, format string, msg ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, msg...))
+}
+
+func TestQueryErrorLog(t *testing.T) {
+	db, srv := newMockDB(t, "log")
+	defer db.Close()
+
+	logger := &testStructuredLogger{}
+	db.SetLogger(logger)
+	srv.SetError(mock.MethodSelectQuery, bindings.NewError("query is broken", bindings.ErrQueryExec))
+	_, err := db.Query(testNs).WhereInt("id", reindexer.EQ, 5).Exec().FetchAll()
+	require.Error(t, err)
+
+	require.Len(t, logger.records, 1)
+	rec := logger.records[0]
+	assert.Equal(t, reindexer.ERROR, rec.level)
+	assert.Equal(t, "rq: query failed", rec.msg)
+	assert.Equal(t, testNs, rec.fields[bindings.LogFieldNamespace])
+	assert.Equal(t, "SELECT * FROM items WHERE id = 5", rec.fields[bindings.LogFieldQuery])
+	assert.Equal(t, bindings.ErrQueryExec, rec.fields[bindings.LogFieldErrorCode])
+	assert.Equal(t, "query is broken", rec.fields[bindings.LogFieldError])
+	assert.IsType(t, time.Duration(0), rec.fields[bindings.LogFieldDuration])
+
+	// Plain logger receives the fields in the message
+	plain := &testPlainLogger{}
+	db.SetLogger(plain)
+	_, err = db.Query(testNs).ExecToJson().FetchAll()
+	require.Error(t, err)
+	require.Len(t, plain.lines, 1)
+	assert.Contains(t, plain.lines[0], `rq: query failed namespace=items query="SELECT * FROM items"`)
+	assert.Contains(t, plain.lines[0], fmt.Sprintf("error_code=%d", bindings.ErrQueryExec))
+}
@@ -0,0 +1,99 @@
+package reindexer
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Part of the budget, which is freed in addition to the excess, to avoid evictions on each insertion near the limit
+const memoryBudgetSlackDiv = 10
+
+// memoryBudget limits memory, shared by object caches of all the namespaces and JSON buffers of the DB instance.
+// JSON buffers can't be evicted, so when usage exceeds the limit, items are evicted from the caches
+// in proportion to memory, used by each cache
+type memoryBudget struct {
+	// Counters are first to keep 64-bit alignment
+	cacheBytes int64
+	jsonBytes  int64
+	evictions  int64
+	limit      int64
+	lock       sync.Mutex
+	caches     map[*cacheItems]struct{}
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{
+		limit:  limit,
+		caches: make(map[*cacheItems]struct{}),
+	}
+}
+
+func (b *memoryBudget) register(ci *cacheItems) {
+	b.lock.Lock()
+	b.caches[ci] = struct{}{}
+	b.lock.Unlock()
+}
+
+func (b *memoryBudget) unregister(ci *cacheItems) {
+	b.lock.Lock()
+	delete(b.caches, ci)
+	b.lock.Unlock()
+}
+
+func (b *memoryBudget) used() int64 {
+	return atomic.LoadInt64(&b.cacheBytes) + atomic.LoadInt64(&b.jsonBytes)
+}
+
+// charge accounts size of the cached item. enforce must be called after the item is added to the cache
+func (b *memoryBudget) charge(size int64) {
+	atomic.AddInt64(&b.cacheBytes, size)
+}
+
+func (b *memoryBudget) release(size int64) {
+	atomic.AddInt64(&b.cacheBytes, -size)
+}
+
+func (b *memoryBudget) chargeJSON(size int64) {
+	atomic.AddInt64(&b.jsonBytes, size)
+	b.enforce()
+}
+
+func (b *memoryBudget) releaseJSON(size int64) {
+	atomic.AddInt64(&b.jsonBytes, -size)
+}
+
+// enforce evicts items from the caches, if the budget is exceeded
+func (b *memoryBudget) enforce() {
+	if b.used() <= b.limit {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	used := b.used()
+	if used <= b.limit {
+		return
+	}
+	cacheBytes := atomic.LoadInt64(&b.cacheBytes)
+	if cacheBytes <= 0 {
+		return
+	}
+	toFree := float64(used - b.limit + b.limit/memoryBudgetSlackDiv)
+	for ci := range b.caches {
+		size := atomic.LoadInt64(&ci.size)
+		target := size - int64(float64(size)*toFree/float64(cacheBytes))
+		for atomic.LoadInt64(&ci.size) > target {
+			if !ci.items.RemoveOldest() {
+				break
+			}
+			atomic.AddInt64(&b.evictions, 1)
+		}
+	}
+}
+
+// estimateCacheItemSize returns approximate size of the deserialized item: size of the struct and size of its CJSON,
+// which is close to size of the data, referenced by the struct (strings, slices, etc)
+func estimateCacheItemSize(ns *nsArrayEntry, params *rawResultItemParams) int64 {
+	return int64(ns.rtype.Size()) + int64(len(params.data))
+}
@@ -0,0 +1,244 @@
+// Package migrations contains helpers to review and apply changes of namespace's indexes, declared by `reindex:` tags of the struct.
+// Unlike OpenNamespace, which implicitly adds and updates indexes, changes are compared with the live namespace first,
+// so the plan may be reviewed before applying, and incompatible changes are not applied without explicit permission
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+const (
+	OpAdd    = "Add"
+	OpUpdate = "Update"
+	OpDrop   = "Drop"
+)
+
+// Change - difference of the index between the struct and the live namespace
+type Change struct {
+	// Modification of the index: OpAdd, OpUpdate or OpDrop
+	Op string
+	// Definition of the index from the struct. Live definition for OpDrop
+	Index reindexer.IndexDef
+	// Live definition of the index for OpUpdate
+	Old *reindexer.IndexDef
+	// Incompatible is set for updates, which can't be applied by UpdateIndex (change of field type, json paths, array or pk option).
+	// Such index is dropped and added again, so the index is not available while it's rebuilt
+	Incompatible bool
+	// Description of the changed options for OpUpdate
+	Reason string
+}
+
+// Plan - changes of the namespace's indexes
+type Plan struct {
+	Namespace string
+	Changes   []Change
+}
+
+// Diff compares desired definitions of the indexes with the live ones. Indexes are matched by name. Empty index type of the desired
+// definition is chosen by the server, so it's not compared. Rtree type is compared for rtree indexes only, configs are not compared.
+// Changes are ordered: drops, updates, then additions
+func Diff(desired, live []reindexer.IndexDef) []Change {
+	liveByName := make(map[string]*reindexer.IndexDef, len(live))
+	for i := range live {
+		// Internal indexes (e.g. '-tuple') are not managed by the struct
+		if strings.HasPrefix(live[i].Name, "-") {
+			continue
+		}
+		liveByName[strings.ToLower(live[i].Name)] = &live[i]
+	}
+
+	var drops, updates, adds []Change
+	for _, indexDef := range desired {
+		name := strings.ToLower(indexDef.Name)
+		old, ok := liveByName[name]
+		if !ok {
+			adds = append(adds, Change{Op: OpAdd, Index: indexDef})
+			continue
+		}
+		delete(liveByName, name)
+		if change, changed := diffIndex(indexDef, old); changed {
+			updates = append(updates, change)
+		}
+	}
+	for _, old := range liveByName {
+		drops = append(drops, Change{Op: OpDrop, Index: *old})
+	}
+	sort.Slice(drops, func(i, j int) bool { return drops[i].Index.Name < drops[j].Index.Name })
+
+	changes := append(drops, updates...)
+	return append(changes, adds...)
+}
+
+func diffIndex(indexDef reindexer.IndexDef, old *reindexer.IndexDef) (change Change, changed bool) {
+	var incompatible, compatible []string
+	if !strings.EqualFold(indexDef.FieldType, old.FieldType) {
+		incompatible = append(incompatible, fmt.Sprintf("field type %s -> %s", old.FieldType, indexDef.FieldType))
+	}
+	if !equalPaths(indexDef.JSONPaths, old.JSONPaths) {
+		incompatible = append(incompatible, fmt.Sprintf("json paths %v -> %v", old.JSONPaths, indexDef.JSONPaths))
+	}
+	if indexDef.IsArray != old.IsArray {
+		incompatible = append(incompatible, fmt.Sprintf("array %v -> %v", old.IsArray, indexDef.IsArray))
+	}
+	if indexDef.IsPK != old.IsPK {
+		incompatible = append(incompatible, fmt.Sprintf("pk %v -> %v", old.IsPK, indexDef.IsPK))
+	}
+	if indexDef.IndexType != "" && !strings.EqualFold(indexDef.IndexType, old.IndexType) {
+		reason := fmt.Sprintf("index type %s -> %s", old.IndexType, indexDef.IndexType)
+		if isFulltext(indexDef.IndexType) != isFulltext(old.IndexType) {
+			incompatible = append(incompatible, reason)
+		} else {
+			compatible = append(compatible, reason)
+		}
+	}
+	if indexDef.IsDense != old.IsDense {
+		compatible = append(compatible, fmt.Sprintf("dense %v -> %v", old.IsDense, indexDef.IsDense))
+	}
+	if indexDef.IsSparse != old.IsSparse {
+		compatible = append(compatible, fmt.Sprintf("sparse %v -> %v", old.IsSparse, indexDef.IsSparse))
+	}
+	if collateMode(indexDef.CollateMode) != collateMode(old.CollateMode) {
+		compatible = append(compatible, fmt.Sprintf("collate %s -> %s", collateMode(old.CollateMode), collateMode(indexDef.CollateMode)))
+	}
+	if indexDef.SortOrder != old.SortOrder {
+		compatible = append(compatible, fmt.Sprintf("sort order %q -> %q", old.SortOrder, indexDef.SortOrder))
+	}
+	if indexDef.ExpireAfter != old.ExpireAfter {
+		compatible = append(compatible, fmt.Sprintf("expire after %d -> %d", old.ExpireAfter, indexDef.ExpireAfter))
+	}
+	if indexDef.IndexType == "rtree" && !strings.EqualFold(indexDef.RTreeType, old.RTreeType) {
+		compatible = append(compatible, fmt.Sprintf("rtree type %s -> %s", old.RTreeType, indexDef.RTreeType))
+	}
+
+	if len(incompatible) == 0 && len(compatible) == 0 {
+		return Change{}, false
+	}
+	return Change{
+		Op:           OpUpdate,
+		Index:        indexDef,
+		Old:          old,
+		Incompatible: len(incompatible) > 0,
+		Reason:       strings.Join(append(incompatible, compatible...), ", "),
+	}, true
+}
+
+func equalPaths(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isFulltext(indexType string) bool {
+	return indexType == "text" || indexType == "fuzzytext"
+}
+
+// collateMode returns collate mode of the definition. Server reports missing collate as 'none'
+func collateMode(mode string) string {
+	if mode == "none" {
+		return ""
+	}
+	return mode
+}
+
+// NewPlan compares indexes, declared by the struct (see reindexer.DescribeStruct), with the indexes of the live namespace
+func NewPlan(db *reindexer.Reindexer, namespace string, s interface{}) (*Plan, error) {
+	desired, err := reindexer.DescribeStruct(s)
+	if err != nil {
+		return nil, err
+	}
+	live, err := describeNamespace(db, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{Namespace: namespace, Changes: Diff(desired, live)}, nil
+}
+
+// describeNamespace returns definitions of the live namespace's indexes
+func describeNamespace(db *reindexer.Reindexer, namespace string) ([]reindexer.IndexDef, error) {
+	desc, err := db.Query(reindexer.NamespacesNamespaceName).WhereString("name", reindexer.EQ, strings.ToLower(namespace)).Exec().FetchOne()
+	if err != nil {
+		return nil, err
+	}
+	indexes := desc.(*reindexer.NamespaceDescription).Indexes
+	defs := make([]reindexer.IndexDef, 0, len(indexes))
+	for _, index := range indexes {
+		defs = append(defs, index.IndexDef)
+	}
+	return defs, nil
+}
+
+// Empty returns true, if the namespace's indexes match the struct
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Incompatible returns changes, which require re-creation of the indexes
+func (p *Plan) Incompatible() []Change {
+	var changes []Change
+	for _, change := range p.Changes {
+		if change.Incompatible {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// String returns the plan for review: one change per line
+func (p *Plan) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Namespace '%s': %d changes\n", p.Namespace, len(p.Changes))
+	for _, change := range p.Changes {
+		fmt.Fprintf(&sb, "  %s index '%s'", change.Op, change.Index.Name)
+		if change.Reason != "" {
+			fmt.Fprintf(&sb, ": %s", change.Reason)
+		}
+		if change.Incompatible {
+			sb.WriteString(" (incompatible: index will be dropped and added again)")
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Apply applies the changes to the namespace. If the plan contains incompatible changes and allowIncompatible is false,
+// error is returned and nothing is applied. Changes are applied one by one, so the namespace may be partially migrated on error
+func (p *Plan) Apply(db *reindexer.Reindexer, allowIncompatible bool) error {
+	if incompatible := p.Incompatible(); len(incompatible) > 0 && !allowIncompatible {
+		names := make([]string, 0, len(incompatible))
+		for _, change := range incompatible {
+			names = append(names, change.Index.Name)
+		}
+		return bindings.NewError(fmt.Sprintf("rq: Migration of namespace '%s' contains incompatible changes of indexes: %s", p.Namespace, strings.Join(names, ", ")), reindexer.ErrCodeParams)
+	}
+
+	for _, change := range p.Changes {
+		var err error
+		switch {
+		case change.Op == OpAdd:
+			err = db.AddIndex(p.Namespace, change.Index)
+		case change.Op == OpDrop:
+			err = db.DropIndex(p.Namespace, change.Index.Name)
+		case change.Incompatible:
+			if err = db.DropIndex(p.Namespace, change.Old.Name); err == nil {
+				err = db.AddIndex(p.Namespace, change.Index)
+			}
+		default:
+			err = db.UpdateIndex(p.Namespace, change.Index)
+		}
+		if err != nil {
+			return fmt.Errorf("rq: Can't apply '%s' of index '%s': %w", change.Op, change.Index.Name, err)
+		}
+	}
+	return nil
+}
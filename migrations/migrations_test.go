@@ -0,0 +1,85 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+type testMigrationItem struct {
+	ID    int    `reindex:"id,,pk" json:"id"`
+	Name  string `reindex:"name,tree" json:"name"`
+	Price int64  `reindex:"price" json:"price"`
+}
+
+const testMigrationNs = "items"
+
+func liveIndexes() []reindexer.IndexDef {
+	return []reindexer.IndexDef{
+		{Name: "-tuple", JSONPaths: []string{}, IndexType: "-", FieldType: "string"},
+		{Name: "id", JSONPaths: []string{"id"}, IndexType: "hash", FieldType: "int64", IsPK: true, CollateMode: "none"},
+		{Name: "name", JSONPaths: []string{"name"}, IndexType: "hash", FieldType: "string", CollateMode: "none"},
+		{Name: "price", JSONPaths: []string{"price"}, IndexType: "hash", FieldType: "int", CollateMode: "none"},
+		{Name: "year", JSONPaths: []string{"year"}, IndexType: "tree", FieldType: "int", CollateMode: "none"},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	desired, err := reindexer.DescribeStruct(testMigrationItem{})
+	require.NoError(t, err)
+
+	changes := Diff(desired, liveIndexes())
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, OpDrop, changes[0].Op)
+	assert.Equal(t, "year", changes[0].Index.Name)
+
+	assert.Equal(t, OpUpdate, changes[1].Op)
+	assert.Equal(t, "name", changes[1].Index.Name)
+	assert.False(t, changes[1].Incompatible)
+	assert.Equal(t, "index type hash -> tree", changes[1].Reason)
+
+	assert.Equal(t, OpUpdate, changes[2].Op)
+	assert.Equal(t, "price", changes[2].Index.Name)
+	assert.True(t, changes[2].Incompatible)
+
+	assert.Empty(t, Diff(desired, desired))
+}
+
+func TestPlan(t *testing.T) {
+	db, srv := mock.NewDB("migrations")
+	defer db.Close()
+	srv.SetResults(reindexer.NamespacesNamespaceName, reindexer.NamespaceDescription{Name: testMigrationNs, Indexes: []reindexer.IndexDescription{
+		{IndexDef: liveIndexes()[1]}, {IndexDef: liveIndexes()[2]}, {IndexDef: liveIndexes()[3]}, {IndexDef: liveIndexes()[4]},
+	}})
+
+	plan, err := NewPlan(db, testMigrationNs, testMigrationItem{})
+	require.NoError(t, err)
+	assert.False(t, plan.Empty())
+	require.Len(t, plan.Incompatible(), 1)
+	assert.Contains(t, plan.String(), "Update index 'price': field type int -> int64 (incompatible")
+
+	t.Run("incompatible changes are not applied by default", func(t *testing.T) {
+		assert.Error(t, plan.Apply(db, false))
+		assert.Empty(t, srv.CallsOf(mock.MethodDropIndex))
+		assert.Empty(t, srv.CallsOf(mock.MethodUpdateIndex))
+	})
+
+	t.Run("incompatible changes recreate indexes", func(t *testing.T) {
+		require.NoError(t, plan.Apply(db, true))
+		drops := srv.CallsOf(mock.MethodDropIndex)
+		require.Len(t, drops, 2)
+		assert.Equal(t, "year", drops[0].Index.Name)
+		assert.Equal(t, "price", drops[1].Index.Name)
+		updates := srv.CallsOf(mock.MethodUpdateIndex)
+		require.Len(t, updates, 1)
+		assert.Equal(t, "tree", updates[0].Index.IndexType)
+		adds := srv.CallsOf(mock.MethodAddIndex)
+		require.Len(t, adds, 1)
+		assert.Equal(t, "int64", adds[0].Index.FieldType)
+	})
+}
@@ -0,0 +1,105 @@
+package reindexer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestMockRecordsModifyItem(t *testing.T) {
+	db, srv := newMockDB(t, "modify")
+	defer db.Close()
+
+	require.NoError(t, db.Upsert(testNs, testItem{ID: 1, Name: "first", Tags: []string{"a", "b"}}))
+	require.NoError(t, db.Upsert(testNs, &testItem{ID: 2, Name: "second"}))
+
+	calls := srv.CallsOf(mock.MethodModifyItem)
+	require.Len(t, calls, 2)
+	assert.Equal(t, testNs, calls[0].Namespace)
+	assert.Equal(t, bindings.ModeUpsert, calls[0].Mode)
+
+	var item testItem
+	require.NoError(t, calls[0].DecodeItem(&item))
+	assert.Equal(t, testItem{ID: 1, Name: "first", Tags: []string{"a", "b"}}, item)
+	item = testItem{}
+	require.NoError(t, calls[1].DecodeItem(&item))
+	assert.Equal(t, testItem{ID: 2, Name: "second"}, item)
+
+	assert.Len(t, srv.CallsOf(mock.MethodOpenNamespace), 1)
+	assert.Len(t, srv.CallsOf(mock.MethodAddIndex), 3)
+}
+
+func TestMockSelectResults(t *testing.T) {
+	db, srv := newMockDB(t, "select")
+	defer db.Close()
+
+	srv.SetResults(testNs, testItem{ID: 1, Name: "first"}, &testItem{ID: 2, Name: "second", Tags: []string{"x"}})
+
+	items, err := db.Query(testNs).WhereString("name", reindexer.EQ, "any").Exec().FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, &testItem{ID: 1, Name: "first"}, items[0])
+	assert.Equal(t, &testItem{ID: 2, Name: "second", Tags: []string{"x"}}, items[1])
+
+	it := db.Query(testNs).ExecToJson()
+	require.NoError(t, it.Error())
+	require.True(t, it.Next())
+	assert.JSONEq(t, `{"ID":1,"Name":"first","Tags":null}`, string(it.JSON()))
+	it.Close()
+
+	items, err = db.ExecSQL("SELECT * FROM items WHERE id = 2").FetchAll()
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	calls := srv.CallsOf(mock.MethodSelectQuery)
+	require.Len(t, calls, 2)
+	assert.Equal(t, testNs, calls[0].Namespace)
+	sqlCalls := srv.CallsOf(mock.MethodSelect)
+	require.Len(t, sqlCalls, 1)
+	assert.Equal(t, testNs, sqlCalls[0].Namespace)
+
+	srv.OnSelect(func(call mock.Call) ([]interface{}, error) {
+		return nil, errors.New("select failed")
+	})
+	_, err = db.Query(testNs).Exec().FetchAll()
+	assert.EqualError(t, err, "select failed")
+}
+
+func TestMockErrors(t *testing.T) {
+	db, srv := newMockDB(t, "errors")
+	defer db.Close()
+
+	srv.SetError(mock.MethodModifyItem, errors.New("disk is full"))
+	assert.EqualError(t, db.Upsert(testNs, testItem{ID: 1}), "disk is full")
+	srv.SetError(mock.MethodModifyItem, nil)
+	assert.NoError(t, db.Upsert(testNs, testItem{ID: 1}))
+
+	srv.SetResults(testNs, testItem{ID: 1}, testItem{ID: 2})
+	count, err := db.Query(testNs).Delete()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestMockTx(t *testing.T) {
+	db, srv := newMockDB(t, "tx")
+	defer db.Close()
+
+	tx, err := db.BeginTx(testNs)
+	require.NoError(t, err)
+	require.NoError(t, tx.Upsert(testItem{ID: 1, Name: "tx"}))
+	require.NoError(t, tx.Commit())
+
+	calls := srv.CallsOf(mock.MethodModifyItemTx)
+	require.Len(t, calls, 1)
+	assert.Equal(t, testNs, calls[0].Namespace)
+	var item testItem
+	require.NoError(t, calls[0].DecodeItem(&item))
+	assert.Equal(t, "tx", item.Name)
+	assert.Len(t, srv.CallsOf(mock.MethodCommitTx), 1)
+}
@@ -0,0 +1,63 @@
+package reindexer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelattr "go.opentelemetry.io/otel/attribute"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+var errMsgPackHistory = bindings.NewError("rq: Items in msgpack format can't be written to namespace with history", ErrCodeParams)
+
+// msgpackItem is item in msgpack format, which is sent to the server as is
+type msgpackItem []byte
+
+// isRawItem returns true for items, which are passed as JSON or msgpack data instead of the struct
+func isRawItem(item interface{}) bool {
+	switch item.(type) {
+	case []byte, msgpackItem:
+		return true
+	}
+	return false
+}
+
+// upsertMsgPack upserts item in msgpack format
+func (db *reindexerImpl) upsertMsgPack(ctx context.Context, namespace string, data []byte, precepts ...string) error {
+	namespace = strings.ToLower(namespace)
+
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.UpsertMsgPack", otelattr.String("rx.ns", namespace))
+		defer span.End()
+	}
+
+	if db.promMetrics != nil {
+		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("UpsertMsgPack", namespace)).ObserveDuration()
+	}
+
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return err
+	}
+	// History items are stored as JSON, so primary key of the item is required
+	if ns.opts.history {
+		return errMsgPackHistory
+	}
+
+	_, err = db.modifyItem(ctx, namespace, ns, msgpackItem(data), nil, modeUpsert, precepts...)
+	return err
+}
+
+// MsgPack returns current item in msgpack format. Items are returned in msgpack format, if the query was executed with Query.MsgPack
+// (cproto binding only), otherwise nil is returned.
+// Will panic when pointer was not moved, Next() must be called before.
+func (it *Iterator) MsgPack() []byte {
+	if it.resPtr == 0 {
+		panic(errIteratorNotReady)
+	}
+	data, _ := it.current.obj.([]byte)
+	return data
+}
@@ -0,0 +1,137 @@
+package reindexer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// CompensationFunc reverts changes of the committed transaction of the namespace, when commit of the later namespace of MultiTx fails
+type CompensationFunc func(ctx context.Context) error
+
+// MultiTx - transactions of several namespaces, which are committed one by one in the order of the namespaces (see BeginMultiTx).
+// Server doesn't support transactions across namespaces, so the changes of the committed namespaces are not rolled back on failure:
+// they are reverted by the compensations of the namespaces. Like Tx, MultiTx is not thread safe
+type MultiTx struct {
+	ctx        context.Context
+	namespaces []string
+	txs        map[string]*Tx
+	// Compensations by namespaces (see OnCompensate)
+	compensations map[string][]CompensationFunc
+	finalized     bool
+}
+
+// MultiTxResult - result of MultiTx.Commit
+type MultiTxResult struct {
+	// Counts of the modified items by namespaces, whose transactions were committed
+	Counts map[string]int
+	// Namespace, whose commit failed. Empty, if all the transactions were committed
+	Failed string
+	// Committed namespaces, whose compensations were called after the failure, in the order of the calls
+	Compensated []string
+	// Errors of the compensations by namespaces
+	CompensationErrors map[string]error
+}
+
+func (db *reindexerImpl) beginMultiTx(ctx context.Context, namespaces []string) (*MultiTx, error) {
+	if len(namespaces) == 0 {
+		return nil, bindings.NewError("rq: namespaces of multi-namespace transaction are not set", ErrCodeParams)
+	}
+	m := &MultiTx{ctx: ctx, txs: make(map[string]*Tx, len(namespaces)), compensations: make(map[string][]CompensationFunc)}
+	for _, namespace := range namespaces {
+		namespace = strings.ToLower(namespace)
+		if m.txs[namespace] != nil {
+			m.Rollback()
+			return nil, bindings.NewError("rq: namespace '"+namespace+"' is passed to multi-namespace transaction twice", ErrCodeParams)
+		}
+		tx, err := db.beginTx(ctx, namespace)
+		if err != nil {
+			m.Rollback()
+			return nil, err
+		}
+		m.namespaces = append(m.namespaces, namespace)
+		m.txs[namespace] = tx
+	}
+	return m, nil
+}
+
+// Tx returns transaction of the namespace, or nil, if the namespace is not the part of MultiTx.
+// Transaction must not be committed or rolled back directly
+func (m *MultiTx) Tx(namespace string) *Tx {
+	return m.txs[strings.ToLower(namespace)]
+}
+
+// OnCompensate adds compensation of the namespace. Compensations of the namespace are called in the reverse order of the addition,
+// if the transaction of the namespace was committed, and commit of some of the later namespaces failed
+func (m *MultiTx) OnCompensate(namespace string, fn CompensationFunc) error {
+	namespace = strings.ToLower(namespace)
+	if m.txs[namespace] == nil {
+		return bindings.NewError("rq: namespace '"+namespace+"' is not the part of multi-namespace transaction", ErrCodeParams)
+	}
+	m.compensations[namespace] = append(m.compensations[namespace], fn)
+	return nil
+}
+
+// Commit commits transactions of the namespaces in the order, they were passed to BeginMultiTx. If commit of the namespace fails,
+// transactions of the later namespaces are rolled back, and the compensations of the committed namespaces are called in the reverse order.
+// Returns error of the failed commit, and the result with the details of the commits and the compensations
+func (m *MultiTx) Commit() (*MultiTxResult, error) {
+	if m.finalized {
+		return nil, bindings.NewError("Tx is already finalized", bindings.ErrLogic)
+	}
+	m.finalized = true
+	res := &MultiTxResult{Counts: make(map[string]int, len(m.namespaces))}
+	for i, namespace := range m.namespaces {
+		count, err := m.txs[namespace].CommitWithCount()
+		if err == nil {
+			res.Counts[namespace] = count
+			continue
+		}
+		res.Failed = namespace
+		for _, rest := range m.namespaces[i+1:] {
+			m.txs[rest].Rollback()
+		}
+		m.compensate(res, m.namespaces[:i])
+		return res, err
+	}
+	return res, nil
+}
+
+// compensate calls compensations of the committed namespaces in the reverse order
+func (m *MultiTx) compensate(res *MultiTxResult, committed []string) {
+	for i := len(committed) - 1; i >= 0; i-- {
+		namespace := committed[i]
+		fns := m.compensations[namespace]
+		if len(fns) == 0 {
+			continue
+		}
+		res.Compensated = append(res.Compensated, namespace)
+		for j := len(fns) - 1; j >= 0; j-- {
+			if err := fns[j](m.ctx); err != nil {
+				if res.CompensationErrors == nil {
+					res.CompensationErrors = make(map[string]error)
+				}
+				// The first error of the namespace is reported, the rest of its compensations are still called
+				if res.CompensationErrors[namespace] == nil {
+					res.CompensationErrors[namespace] = err
+				}
+			}
+		}
+	}
+}
+
+// Rollback rolls back transactions of all the namespaces. Returns the first error of the rollbacks
+func (m *MultiTx) Rollback() error {
+	if m.finalized {
+		return nil
+	}
+	m.finalized = true
+	var firstErr error
+	for _, namespace := range m.namespaces {
+		if err := m.txs[namespace].Rollback(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,77 @@
+package reindexer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestMultiTx(t *testing.T) {
+	db, srv := newMockDB(t, "multitx")
+	defer db.Close()
+	const otherNs = "other_items"
+	require.NoError(t, db.OpenNamespace(otherNs, reindexer.DefaultNamespaceOptions(), testItem{}))
+
+	t.Run("commit of all namespaces", func(t *testing.T) {
+		srv.Reset()
+		mtx, err := db.BeginMultiTx(testNs, otherNs)
+		require.NoError(t, err)
+		require.NoError(t, mtx.Tx(testNs).Upsert(testItem{ID: 1}))
+		require.NoError(t, mtx.Tx(otherNs).Upsert(testItem{ID: 2}))
+		res, err := mtx.Commit()
+		require.NoError(t, err)
+		assert.Empty(t, res.Failed)
+		assert.Len(t, res.Counts, 2)
+		calls := srv.CallsOf(mock.MethodCommitTx)
+		require.Len(t, calls, 2)
+		assert.Equal(t, testNs, calls[0].Namespace)
+		assert.Equal(t, otherNs, calls[1].Namespace)
+	})
+
+	t.Run("compensation of committed namespaces", func(t *testing.T) {
+		srv.Reset()
+		mtx, err := db.BeginMultiTx(testNs, otherNs)
+		require.NoError(t, err)
+		var compensated []string
+		require.NoError(t, mtx.OnCompensate(testNs, func(ctx context.Context) error {
+			compensated = append(compensated, testNs)
+			return errors.New("compensation failed")
+		}))
+		require.NoError(t, mtx.OnCompensate(otherNs, func(ctx context.Context) error {
+			compensated = append(compensated, otherNs)
+			return nil
+		}))
+		assert.Error(t, mtx.OnCompensate("unknown", func(ctx context.Context) error { return nil }))
+
+		require.NoError(t, mtx.Tx(testNs).Upsert(testItem{ID: 1}))
+		// Failed async item fails commit of the second namespace
+		srv.SetError(mock.MethodModifyItemTx, errors.New("item failed"))
+		require.NoError(t, mtx.Tx(otherNs).UpsertAsync(testItem{ID: 2}, func(err error) {}))
+		mtx.Tx(otherNs).AwaitResults()
+		srv.SetError(mock.MethodModifyItemTx, nil)
+
+		res, err := mtx.Commit()
+		require.Error(t, err)
+		assert.Equal(t, otherNs, res.Failed)
+		assert.Equal(t, map[string]int{testNs: 0}, res.Counts)
+		assert.Equal(t, []string{testNs}, res.Compensated)
+		assert.Equal(t, []string{testNs}, compensated)
+		assert.EqualError(t, res.CompensationErrors[testNs], "compensation failed")
+
+		_, err = mtx.Commit()
+		assert.Error(t, err)
+	})
+
+	t.Run("duplicate namespace", func(t *testing.T) {
+		srv.Reset()
+		_, err := db.BeginMultiTx(testNs, "ITEMS")
+		assert.Error(t, err)
+		assert.Len(t, srv.CallsOf(mock.MethodRollbackTx), 1)
+	})
+}
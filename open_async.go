@@ -0,0 +1,111 @@
+package reindexer
+
+import (
+	"context"
+	"sync"
+)
+
+// OpenNamespaceStage - stage of the namespace opening, which is reported by OpenNamespaceHandle
+type OpenNamespaceStage int
+
+const (
+	// Namespace is being loaded from the storage by the server
+	OpenNamespaceLoading OpenNamespaceStage = iota
+	// Indexes of the struct are being added (or updated) and built
+	OpenNamespaceIndexes
+	// JSON schema of the struct is being set
+	OpenNamespaceSchema
+	// Namespace is opened (or opening failed, see OpenNamespaceHandle.Err)
+	OpenNamespaceDone
+)
+
+// String returns name of the stage
+func (s OpenNamespaceStage) String() string {
+	switch s {
+	case OpenNamespaceLoading:
+		return "loading"
+	case OpenNamespaceIndexes:
+		return "indexes"
+	case OpenNamespaceSchema:
+		return "schema"
+	case OpenNamespaceDone:
+		return "done"
+	}
+	return "unknown"
+}
+
+// OpenNamespaceProgress - progress of the namespace opening
+type OpenNamespaceProgress struct {
+	Stage OpenNamespaceStage
+	// Count of the indexes, which are already added
+	IndexesDone int
+	// Total count of the indexes of the struct
+	IndexesTotal int
+}
+
+// OpenNamespaceHandle - handle of the namespace, which is opened in background by OpenNamespaceAsync
+type OpenNamespaceHandle struct {
+	// Name of the namespace
+	Namespace string
+	lock      sync.Mutex
+	progress  OpenNamespaceProgress
+	err       error
+	done      chan struct{}
+}
+
+func newOpenNamespaceHandle(namespace string) *OpenNamespaceHandle {
+	return &OpenNamespaceHandle{Namespace: namespace, done: make(chan struct{})}
+}
+
+// Progress returns current progress of the opening. Server does not report progress of items loading,
+// so OpenNamespaceLoading stage lasts until all the items are read from the storage
+func (h *OpenNamespaceHandle) Progress() OpenNamespaceProgress {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.progress
+}
+
+// Done returns channel, which is closed, when the namespace is opened or opening failed
+func (h *OpenNamespaceHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Err returns error of the opening. Returns nil, if the namespace is not opened yet
+func (h *OpenNamespaceHandle) Err() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.err
+}
+
+// Wait waits until the namespace is opened and returns error of the opening
+func (h *OpenNamespaceHandle) Wait() error {
+	<-h.done
+	return h.Err()
+}
+
+// WaitCtx waits until the namespace is opened or context is done. Opening is not canceled, if context is done
+func (h *OpenNamespaceHandle) WaitCtx(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return h.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *OpenNamespaceHandle) setStage(stage OpenNamespaceStage, indexesDone, indexesTotal int) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	h.progress = OpenNamespaceProgress{Stage: stage, IndexesDone: indexesDone, IndexesTotal: indexesTotal}
+	h.lock.Unlock()
+}
+
+func (h *OpenNamespaceHandle) finish(err error) {
+	h.lock.Lock()
+	h.progress.Stage = OpenNamespaceDone
+	h.err = err
+	h.lock.Unlock()
+	close(h.done)
+}
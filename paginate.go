@@ -0,0 +1,204 @@
+package reindexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+const defaultPageSize = 20
+
+// ErrInvalidPageToken is returned by Paginate, if the token of the page is malformed or doesn't match the query's sort
+var ErrInvalidPageToken = bindings.NewError("rq: invalid page token", ErrCodeParams)
+
+// PageRequest - page of the query results, requested by Paginate
+type PageRequest struct {
+	// Count of the items on the page. Default is 20
+	Size int
+	// Token of the page, returned as PageResult.NextToken of the previous page. Empty token requests the first page
+	Token string
+	// Cursor enables cursor (keyset) mode: the next page is selected by the values of the sort fields of the previous page's
+	// last item instead of the offset, so pages are not shifted by inserts and deletes of the preceding items.
+	// Sort fields must be fields of the namespace's struct
+	Cursor bool
+}
+
+// PageResult - items of the page, returned by Paginate
+type PageResult[T any] struct {
+	Items []T
+	// Token of the next page. It's empty for the last page
+	NextToken string
+	// Total count of the items, matching the query. In cursor mode the items before the page are not counted
+	Total int
+}
+
+// pageToken is encoded into PageRequest.Token
+type pageToken struct {
+	// Offset of the page in offset mode
+	Offset int `json:"o,omitempty"`
+	// Values of the sort fields of the previous page's last item in cursor mode
+	Keys []interface{} `json:"k,omitempty"`
+}
+
+// Paginate executes the page of the query's results and returns the page's items of type T with the token of the next page.
+// Sort of the query is made deterministic by primary key tiebreaker (see SortMulti), so the pages don't overlap.
+// Query must not contain Limit and Offset. Query is closed by Paginate
+func Paginate[T any](q *Query, page PageRequest) (PageResult[T], error) {
+	return PaginateCtx[T](context.Background(), q, page)
+}
+
+// PaginateCtx is Paginate with context
+func PaginateCtx[T any](ctx context.Context, q *Query, page PageRequest) (res PageResult[T], err error) {
+	if page.Size <= 0 {
+		page.Size = defaultPageSize
+	}
+	var keys []structFieldRef
+	token, err := decodePageToken(page.Token)
+	if err == nil && page.Cursor {
+		keys, err = q.cursorFields()
+	}
+	if err == nil && page.Cursor && len(token.Keys) != 0 {
+		err = q.whereAfterCursor(keys, token.Keys)
+	}
+	if err != nil {
+		q.close()
+		return res, err
+	}
+
+	q.pkTiebreaker = true
+	q.ReqTotal().Offset(token.Offset).Limit(page.Size + 1)
+	// One extra item is requested to detect the last page
+	items, total, err := Exec[T](ctx, q, 0)
+	if err != nil {
+		return res, err
+	}
+	res.Total = total
+	if len(items) <= page.Size {
+		res.Items = items
+		return res, nil
+	}
+	res.Items = items[:page.Size]
+
+	next := pageToken{Offset: token.Offset + page.Size}
+	if page.Cursor {
+		next.Offset = 0
+		last := res.Items[page.Size-1]
+		for i := range keys {
+			v, ok := keys[i].value(last)
+			if v = reflect.Indirect(v); !ok || !v.IsValid() {
+				return res, bindings.NewError("rq: can't paginate by cursor: sort field '"+keys[i].index+"' of the item is nil", ErrCodeParams)
+			}
+			next.Keys = append(next.Keys, v.Interface())
+		}
+	}
+	res.NextToken, err = encodePageToken(next)
+	return res, err
+}
+
+// cursorFields returns fields of the query's sort with primary key tiebreaker
+func (q *Query) cursorFields() ([]structFieldRef, error) {
+	ns, err := q.db.getNS(q.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(ns.pk) == 0 {
+		return nil, ErrNoPK
+	}
+	fields := make([]structFieldRef, 0, len(q.sortEntries)+len(ns.pk))
+	for _, entry := range q.sortEntries {
+		ref, ok := findStructField(ns.rtype, entry.Field)
+		if !ok {
+			return nil, bindings.NewError("rq: can't paginate by cursor: sort field '"+entry.Field+"' is not found in the struct", ErrCodeParams)
+		}
+		fields = append(fields, ref)
+	}
+	for _, pk := range ns.pk {
+		if !q.isSortedBy(pk.index) {
+			fields = append(fields, pk)
+		}
+	}
+	return fields, nil
+}
+
+func (q *Query) isSortedBy(field string) bool {
+	for _, entry := range q.sortEntries {
+		if entry.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// isSortedDesc returns true, if the field is sorted by descending order. Primary key tiebreaker is sorted by ascending order
+func (q *Query) isSortedDesc(field string) bool {
+	for _, entry := range q.sortEntries {
+		if entry.Field == field {
+			return entry.Desc
+		}
+	}
+	return false
+}
+
+// whereAfterCursor adds conditions, which select items following the cursor in the sort order:
+// (f1 > v1) OR (f1 = v1 AND f2 > v2) OR ... (for descending fields '<' is used)
+func (q *Query) whereAfterCursor(fields []structFieldRef, values []interface{}) error {
+	if len(fields) != len(values) {
+		return ErrInvalidPageToken
+	}
+	q.OpenBracket()
+	for i := range fields {
+		if i != 0 {
+			q.Or()
+		}
+		q.OpenBracket()
+		for j := 0; j < i; j++ {
+			q.Where(fields[j].index, EQ, values[j])
+		}
+		cond := GT
+		if q.isSortedDesc(fields[i].index) {
+			cond = LT
+		}
+		q.Where(fields[i].index, cond, values[i])
+		q.CloseBracket()
+	}
+	q.CloseBracket()
+	return nil
+}
+
+func encodePageToken(token pageToken) (string, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePageToken(s string) (token pageToken, err error) {
+	if s == "" {
+		return token, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, ErrInvalidPageToken
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Integer keys must be passed to the conditions as integers
+	dec.UseNumber()
+	if err = dec.Decode(&token); err != nil || token.Offset < 0 {
+		return token, ErrInvalidPageToken
+	}
+	for i, key := range token.Keys {
+		if n, ok := key.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				token.Keys[i] = v
+			} else if v, err := n.Float64(); err == nil {
+				token.Keys[i] = v
+			}
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,74 @@
+package reindexer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// PreceptTimeUnit - time unit of the value, which is set by NowPrecept
+type PreceptTimeUnit string
+
+const (
+	PreceptSec  PreceptTimeUnit = "sec"
+	PreceptMsec PreceptTimeUnit = "msec"
+	PreceptUsec PreceptTimeUnit = "usec"
+	PreceptNsec PreceptTimeUnit = "nsec"
+)
+
+// SerialPrecept returns precept, which sets the field to the next value of the namespace's sequence of the field (e.g. for auto-increment id).
+// Field is checked against the namespace on modification of the item
+func SerialPrecept(field string) string {
+	return field + "=serial()"
+}
+
+// NowPrecept returns precept, which sets the field to the current time of the server in the unit.
+// Field is checked against the namespace on modification of the item
+func NowPrecept(field string, unit PreceptTimeUnit) string {
+	return field + "=now(" + string(unit) + ")"
+}
+
+// validatePrecepts checks, that fields of serial() and now() precepts are indexes of the namespace or fields of its struct.
+// Misspelled field is reported by the client, before the item is sent
+func (ns *reindexerNamespace) validatePrecepts(precepts []string) error {
+	for _, precept := range precepts {
+		field, expr, ok := splitPrecept(precept)
+		if !ok {
+			continue
+		}
+		expr = strings.ToLower(expr)
+		switch {
+		case expr == "serial()":
+		case strings.HasPrefix(expr, "now(") && strings.HasSuffix(expr, ")"):
+			if unit := expr[len("now(") : len(expr)-1]; unit != "" && !autotimeUnits[unit] {
+				return bindings.NewError(fmt.Sprintf("rq: Invalid time unit of precept '%s'. Expected one of sec, msec, usec or nsec", precept), ErrCodeParams)
+			}
+		default:
+			continue
+		}
+		if !ns.hasField(field) {
+			return bindings.NewError(fmt.Sprintf("rq: Field '%s' of precept '%s' is not found in namespace '%s'", field, precept, ns.name), ErrCodeParams)
+		}
+	}
+	return nil
+}
+
+// hasField checks, that the field is an index of the namespace (or its JSON path) or a field of the namespace's struct
+func (ns *reindexerNamespace) hasField(field string) bool {
+	for _, index := range ns.indexes {
+		if strings.EqualFold(index.Name, field) {
+			return true
+		}
+		for _, path := range index.JSONPaths {
+			if strings.EqualFold(path, field) {
+				return true
+			}
+		}
+	}
+	if ns.rtype == nil {
+		return false
+	}
+	_, found := findStructField(ns.rtype, field)
+	return found
+}
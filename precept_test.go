@@ -0,0 +1,36 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestTypedPrecepts(t *testing.T) {
+	db, srv := newMockDB(t, "precepts")
+	defer db.Close()
+
+	require.NoError(t, db.Upsert(testNs, testItem{ID: 1}, reindexer.SerialPrecept("id"), reindexer.NowPrecept("name", reindexer.PreceptMsec)))
+	calls := srv.CallsOf(mock.MethodModifyItem)
+	require.Len(t, calls, 1)
+	assert.Equal(t, []string{"id=serial()", "name=now(msec)"}, calls[0].Precepts)
+
+	err := db.Upsert(testNs, testItem{ID: 1}, reindexer.SerialPrecept("idd"))
+	assert.Error(t, err)
+	rerr, ok := err.(bindings.Error)
+	require.True(t, ok)
+	assert.Equal(t, reindexer.ErrCodeParams, rerr.Code())
+	_, err = db.Insert(testNs, testItem{ID: 1}, "tags=NOW(minutes)")
+	assert.Error(t, err)
+	assert.Len(t, srv.CallsOf(mock.MethodModifyItem), 1)
+
+	tx, err := db.BeginTx(testNs)
+	require.NoError(t, err)
+	assert.Error(t, tx.Upsert(testItem{ID: 2}, reindexer.NowPrecept("missing", reindexer.PreceptSec)))
+	require.NoError(t, tx.Rollback())
+}
@@ -0,0 +1,29 @@
+package reindexer
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+var modifyModeNames = map[int]string{
+	modeInsert: "Insert",
+	modeUpdate: "Update",
+	modeUpsert: "Upsert",
+	modeDelete: "Delete",
+}
+
+// doWithPprofLabels calls f with pprof labels of the operation, if WithPprofLabels option is enabled
+func (db *reindexerImpl) doWithPprofLabels(ctx context.Context, op string, namespace string, label string, f func()) {
+	if db == nil || !db.pprofLabels {
+		f()
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	labels := []string{"rx.op", op, "rx.ns", namespace}
+	if label != "" {
+		labels = append(labels, "rx.query", label)
+	}
+	pprof.Do(ctx, pprof.Labels(labels...), func(context.Context) { f() })
+}
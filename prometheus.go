@@ -1,35 +1,387 @@
 package reindexer
 
 import (
+	"errors"
 	"net/url"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/restream/reindexer/v3/bindings"
 )
 
+const defaultPrometheusPrefix = "reindexer"
+
+// Labels of the metrics of the writes (see reindexerPrometheusMetrics.observeWrite)
+var writeMetricsLabels = []string{"dsn", "ns", "mode"}
+
+// Labels of the errors counter (see reindexerPrometheusMetrics.observeError)
+var errorsMetricsLabels = []string{"dsn", "cmd", "ns", "code"}
+
+// Count of the created collectors of ClientStats. It's used as 'client_id' label, which distinguishes the DB instances with the same DSN
+var clientStatsCollectors int64
+
 var (
 	promStatsClientCallsLatency = promauto.NewSummaryVec(
-		prometheus.SummaryOpts{
-			Namespace: "reindexer",
-			Subsystem: "client",
-			Name:      "calls_latency_seconds",
-			Help:      "Latency of Reindexer Client calls",
-
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
-			MaxAge:     3 * time.Minute,
-			AgeBuckets: 3,
-		},
+		newClientCallsLatencyOpts(defaultPrometheusPrefix, nil),
 		[]string{"dsn", "cmd", "ns"},
 	)
+	promStatsClientWriteLatency = promauto.NewSummaryVec(newClientWriteLatencyOpts(defaultPrometheusPrefix, nil), writeMetricsLabels)
+	promStatsClientWriteErrors  = promauto.NewCounterVec(newClientWriteErrorsOpts(defaultPrometheusPrefix, nil), writeMetricsLabels)
+	promStatsClientWriteSize    = promauto.NewHistogramVec(newClientWriteSizeOpts(defaultPrometheusPrefix, nil), writeMetricsLabels)
+
+	promStatsClientErrors = promauto.NewCounterVec(newClientErrorsOpts(defaultPrometheusPrefix, nil), errorsMetricsLabels)
+
+	promStatsClientVerifiedQueries  = promauto.NewCounterVec(newClientVerifiedQueriesOpts(defaultPrometheusPrefix, nil), []string{"dsn", "ns"})
+	promStatsClientResultMismatches = promauto.NewCounterVec(newClientResultMismatchesOpts(defaultPrometheusPrefix, nil), []string{"dsn", "ns"})
 )
 
+// PrometheusMetricsOptions - options of client side Prometheus metrics
+type PrometheusMetricsOptions struct {
+	// Registerer for the metrics. prometheus.DefaultRegisterer is used, if nil
+	Registerer prometheus.Registerer
+	// Prefix of the metrics names. 'reindexer' is used, if empty
+	Prefix string
+	// Labels, which are added to all the metrics (e.g. to distinguish several instances of application)
+	ConstLabels prometheus.Labels
+	// Buckets of the latency metrics (in seconds), which are histograms instead of summaries, if buckets are set.
+	// Histograms may not be registered with the same names as the default summaries, so buckets require custom registerer
+	// or prefix, if metrics of other instances are registered in prometheus.DefaultRegisterer with the default prefix
+	Buckets []float64
+}
+
 type reindexerPrometheusMetrics struct {
 	clientCallsLatency prometheus.ObserverVec
+	// Metrics of the items modifications and of the transactions' commits by namespaces and modes
+	writeLatency prometheus.ObserverVec
+	writeErrors  *prometheus.CounterVec
+	writeSize    prometheus.ObserverVec
+	// Counters of the verified queries and of the mismatched items by namespaces (see WithResultVerification)
+	verifiedQueries  *prometheus.CounterVec
+	resultMismatches *prometheus.CounterVec
+	// Counter of the failed calls by commands, namespaces and error codes
+	errors *prometheus.CounterVec
+	// Collector of ClientStats of the DB instance
+	clientStats prometheus.Collector
+	registerer  prometheus.Registerer
+}
+
+func newClientCallsLatencyOpts(prefix string, constLabels prometheus.Labels) prometheus.SummaryOpts {
+	return prometheus.SummaryOpts{
+		Namespace:   prefix,
+		Subsystem:   "client",
+		Name:        "calls_latency_seconds",
+		Help:        "Latency of Reindexer Client calls",
+		ConstLabels: constLabels,
+
+		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		MaxAge:     3 * time.Minute,
+		AgeBuckets: 3,
+	}
+}
+
+func newClientWriteLatencyOpts(prefix string, constLabels prometheus.Labels) prometheus.SummaryOpts {
+	opts := newClientCallsLatencyOpts(prefix, constLabels)
+	opts.Name = "write_latency_seconds"
+	opts.Help = "Latency of items modifications and transactions commits"
+	return opts
+}
+
+func newClientWriteErrorsOpts(prefix string, constLabels prometheus.Labels) prometheus.CounterOpts {
+	return prometheus.CounterOpts{
+		Namespace:   prefix,
+		Subsystem:   "client",
+		Name:        "write_errors_total",
+		Help:        "Count of failed items modifications and transactions commits",
+		ConstLabels: constLabels,
+	}
+}
+
+func newClientWriteSizeOpts(prefix string, constLabels prometheus.Labels) prometheus.HistogramOpts {
+	return prometheus.HistogramOpts{
+		Namespace:   prefix,
+		Subsystem:   "client",
+		Name:        "write_size_bytes",
+		Help:        "Size of the serialized items of modifications and transactions commits",
+		ConstLabels: constLabels,
+		Buckets:     prometheus.ExponentialBuckets(64, 4, 10),
+	}
+}
+
+func newClientErrorsOpts(prefix string, constLabels prometheus.Labels) prometheus.CounterOpts {
+	return prometheus.CounterOpts{
+		Namespace:   prefix,
+		Subsystem:   "client",
+		Name:        "errors_total",
+		Help:        "Count of failed Reindexer Client calls by error codes",
+		ConstLabels: constLabels,
+	}
+}
+
+func newClientVerifiedQueriesOpts(prefix string, constLabels prometheus.Labels) prometheus.CounterOpts {
+	return prometheus.CounterOpts{
+		Namespace:   prefix,
+		Subsystem:   "client",
+		Name:        "verified_queries_total",
+		Help:        "Count of the queries, whose results were verified by the client",
+		ConstLabels: constLabels,
+	}
+}
+
+func newClientResultMismatchesOpts(prefix string, constLabels prometheus.Labels) prometheus.CounterOpts {
+	return prometheus.CounterOpts{
+		Namespace:   prefix,
+		Subsystem:   "client",
+		Name:        "result_mismatches_total",
+		Help:        "Count of the items of the verified queries, which don't match conditions of the queries",
+		ConstLabels: constLabels,
+	}
+}
+
+// registerShared registers the collector. Metrics may be shared by several instances with the same registerer and options,
+// so the collector, which is already registered, is returned instead
+func registerShared[C prometheus.Collector](registerer prometheus.Registerer, c C) (C, error) {
+	if err := registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return c, err
+		}
+		existing, ok := are.ExistingCollector.(C)
+		if !ok {
+			return c, err
+		}
+		return existing, nil
+	}
+	return c, nil
+}
+
+// registerLatency registers latency metric as histogram with buckets, or as summary, if buckets are not set
+func registerLatency(registerer prometheus.Registerer, opts prometheus.SummaryOpts, buckets []float64, labels []string) (prometheus.ObserverVec, error) {
+	if len(buckets) == 0 {
+		return registerShared(registerer, prometheus.NewSummaryVec(opts, labels))
+	}
+	return registerShared(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   opts.Namespace,
+		Subsystem:   opts.Subsystem,
+		Name:        opts.Name,
+		Help:        opts.Help,
+		ConstLabels: opts.ConstLabels,
+		Buckets:     buckets,
+	}, labels))
+}
+
+func newPrometheusMetrics(db *reindexerImpl, dsnParsed []url.URL, opt bindings.OptionPrometheusMetrics) (*reindexerPrometheusMetrics, error) {
+	registerer := opt.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	prefix := opt.Prefix
+	if prefix == "" {
+		prefix = defaultPrometheusPrefix
+	}
+
+	var latency, writeLatency prometheus.ObserverVec = promStatsClientCallsLatency, promStatsClientWriteLatency
+	writeErrors, writeSize := promStatsClientWriteErrors, promStatsClientWriteSize
+	verifiedQueries, resultMismatches := promStatsClientVerifiedQueries, promStatsClientResultMismatches
+	errorsByCode := promStatsClientErrors
+	if opt.Registerer != nil || prefix != defaultPrometheusPrefix || len(opt.ConstLabels) != 0 || len(opt.Buckets) != 0 {
+		var err error
+		if latency, err = registerLatency(registerer, newClientCallsLatencyOpts(prefix, opt.ConstLabels), opt.Buckets, []string{"dsn", "cmd", "ns"}); err != nil {
+			return nil, err
+		}
+		if writeLatency, err = registerLatency(registerer, newClientWriteLatencyOpts(prefix, opt.ConstLabels), opt.Buckets, writeMetricsLabels); err != nil {
+			return nil, err
+		}
+		if errorsByCode, err = registerShared(registerer, prometheus.NewCounterVec(newClientErrorsOpts(prefix, opt.ConstLabels), errorsMetricsLabels)); err != nil {
+			return nil, err
+		}
+		if writeErrors, err = registerShared(registerer, prometheus.NewCounterVec(newClientWriteErrorsOpts(prefix, opt.ConstLabels), writeMetricsLabels)); err != nil {
+			return nil, err
+		}
+		if writeSize, err = registerShared(registerer, prometheus.NewHistogramVec(newClientWriteSizeOpts(prefix, opt.ConstLabels), writeMetricsLabels)); err != nil {
+			return nil, err
+		}
+		if verifiedQueries, err = registerShared(registerer, prometheus.NewCounterVec(newClientVerifiedQueriesOpts(prefix, opt.ConstLabels), []string{"dsn", "ns"})); err != nil {
+			return nil, err
+		}
+		if resultMismatches, err = registerShared(registerer, prometheus.NewCounterVec(newClientResultMismatchesOpts(prefix, opt.ConstLabels), []string{"dsn", "ns"})); err != nil {
+			return nil, err
+		}
+	}
+
+	dsnLabel := prometheus.Labels{"dsn": dsnString(dsnParsed)}
+	m := &reindexerPrometheusMetrics{
+		clientCallsLatency: latency.MustCurryWith(dsnLabel),
+		writeLatency:       writeLatency.MustCurryWith(dsnLabel),
+		writeErrors:        writeErrors.MustCurryWith(dsnLabel),
+		writeSize:          writeSize.MustCurryWith(dsnLabel),
+		verifiedQueries:    verifiedQueries.MustCurryWith(dsnLabel),
+		resultMismatches:   resultMismatches.MustCurryWith(dsnLabel),
+		errors:             errorsByCode.MustCurryWith(dsnLabel),
+		registerer:         registerer,
+	}
+
+	// Statistics of the DB instances with the same DSN can't be summed (e.g. serializers pool is shared), so they are exported separately
+	constLabels := prometheus.Labels{"dsn": dsnString(dsnParsed), "client_id": strconv.FormatInt(atomic.AddInt64(&clientStatsCollectors, 1), 10)}
+	for k, v := range opt.ConstLabels {
+		constLabels[k] = v
+	}
+	clientStats := newClientStatsCollector(db, prefix, constLabels)
+	if err := registerer.Register(clientStats); err != nil {
+		return nil, err
+	}
+	m.clientStats = clientStats
+	return m, nil
+}
+
+// observeWrite records latency and result of the write. mode is the name of the modification (e.g. "Upsert" or "Tx.Commit")
+func (m *reindexerPrometheusMetrics) observeWrite(namespace, mode string, start time.Time, err error) {
+	m.writeLatency.WithLabelValues(namespace, mode).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.writeErrors.WithLabelValues(namespace, mode).Inc()
+		m.observeError(mode, namespace, err)
+	}
+}
+
+// observeError counts the failed call by code of the error. Errors without code are counted with 'unknown' code
+func (m *reindexerPrometheusMetrics) observeError(cmd, namespace string, err error) {
+	code := "unknown"
+	var rerr bindings.Error
+	if errors.As(err, &rerr) {
+		code = strconv.Itoa(rerr.Code())
+	}
+	m.errors.WithLabelValues(cmd, namespace, code).Inc()
+}
+
+// observeWriteSize records size of the serialized items of the write
+func (m *reindexerPrometheusMetrics) observeWriteSize(namespace, mode string, size int64) {
+	m.writeSize.WithLabelValues(namespace, mode).Observe(float64(size))
+}
+
+func (m *reindexerPrometheusMetrics) close() {
+	if m.clientStats != nil {
+		m.registerer.Unregister(m.clientStats)
+		m.clientStats = nil
+	}
+}
+
+// clientStatsCollector exports ClientStats of the DB instance as gauges
+type clientStatsCollector struct {
+	db                *reindexerImpl
+	openIterators     *prometheus.Desc
+	pooledSerializers *prometheus.Desc
+	cgoCalls          *prometheus.Desc
+	cgoLimit          *prometheus.Desc
+	pendingAsyncOps   *prometheus.Desc
+	pendingAsyncBytes *prometheus.Desc
+	txWindowWaits     *prometheus.Desc
+	txWindowRejects   *prometheus.Desc
+	txInFlight        *prometheus.Desc
+	memoryBudget      *prometheus.Desc
+	cacheMemory       *prometheus.Desc
+	jsonMemory        *prometheus.Desc
+	cacheEvictions    *prometheus.Desc
+	stateRetries      *prometheus.Desc
+	retryAttempts     *prometheus.Desc
+	retrySuccesses    *prometheus.Desc
+	retryAbandoned    *prometheus.Desc
+	// Statistics of object caches by namespaces
+	nsCacheHits      *prometheus.Desc
+	nsCacheMisses    *prometheus.Desc
+	nsCacheEvictions *prometheus.Desc
+	nsCacheItems     *prometheus.Desc
+	nsDecodedItems   *prometheus.Desc
+	nsDecodeSeconds  *prometheus.Desc
+}
+
+func newClientStatsCollector(db *reindexerImpl, prefix string, constLabels prometheus.Labels) *clientStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(prefix, "client", name), help, nil, constLabels)
+	}
+	nsDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(prefix, "client", name), help, []string{"ns"}, constLabels)
+	}
+	return &clientStatsCollector{
+		db:                db,
+		openIterators:     desc("open_iterators", "Count of not closed iterators"),
+		pooledSerializers: desc("pooled_serializers", "Count of serializers, taken from the pool and not returned yet"),
+		cgoCalls:          desc("cgo_calls", "Count of active cgo calls"),
+		cgoLimit:          desc("cgo_calls_limit", "Limit of concurrent cgo calls"),
+		pendingAsyncOps:   desc("pending_async_ops", "Count of async operations of transactions, which are waiting for completion"),
+		pendingAsyncBytes: desc("pending_async_bytes", "Size of the items of async operations of transactions, which are waiting for completion"),
+		txWindowWaits:     desc("tx_window_waits_total", "Count of async operations of transactions, which waited for free window"),
+		txWindowRejects:   desc("tx_window_rejects_total", "Count of async operations of transactions, which were rejected due to full window"),
+		txInFlight:        desc("tx_in_flight", "Count of started and not finished transactions"),
+		memoryBudget:      desc("memory_budget_bytes", "Memory budget of object caches and JSON buffers"),
+		cacheMemory:       desc("cache_memory_bytes", "Estimated memory, used by object caches"),
+		jsonMemory:        desc("json_memory_bytes", "Memory, used by JSON buffers of not closed iterators"),
+		cacheEvictions:    desc("cache_budget_evictions_total", "Count of items, evicted from object caches due to memory budget"),
+		stateRetries:      desc("state_invalidated_retries_total", "Count of items modifications retries, caused by invalidated namespace state"),
+		retryAttempts:     desc("retries_total", "Count of retries of the operations, failed with network errors"),
+		retrySuccesses:    desc("retry_successes_total", "Count of operations, which succeeded after retries"),
+		retryAbandoned:    desc("retries_abandoned_total", "Count of operations, which were not completed by retries"),
+		nsCacheHits:       nsDesc("cache_hits_total", "Count of items, taken from object cache of the namespace"),
+		nsCacheMisses:     nsDesc("cache_misses_total", "Count of items, which were not found in object cache of the namespace"),
+		nsCacheEvictions:  nsDesc("cache_evictions_total", "Count of items, evicted from object cache of the namespace due to its limits or memory budget"),
+		nsCacheItems:      nsDesc("cache_items", "Count of items in object cache of the namespace"),
+		nsDecodedItems:    nsDesc("decoded_items_total", "Count of decoded items of the namespace"),
+		nsDecodeSeconds:   nsDesc("decode_seconds_total", "Total time of decoding of the items of the namespace"),
+	}
+}
+
+func (c *clientStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.openIterators
+	ch <- c.pooledSerializers
+	ch <- c.cgoCalls
+	ch <- c.cgoLimit
+	ch <- c.pendingAsyncOps
+	ch <- c.pendingAsyncBytes
+	ch <- c.txWindowWaits
+	ch <- c.txWindowRejects
+	ch <- c.txInFlight
+	ch <- c.memoryBudget
+	ch <- c.cacheMemory
+	ch <- c.jsonMemory
+	ch <- c.cacheEvictions
+	ch <- c.stateRetries
+	ch <- c.retryAttempts
+	ch <- c.retrySuccesses
+	ch <- c.retryAbandoned
+	ch <- c.nsCacheHits
+	ch <- c.nsCacheMisses
+	ch <- c.nsCacheEvictions
+	ch <- c.nsCacheItems
+	ch <- c.nsDecodedItems
+	ch <- c.nsDecodeSeconds
 }
 
-func newPrometheusMetrics(dsnParsed []url.URL) *reindexerPrometheusMetrics {
-	return &reindexerPrometheusMetrics{
-		clientCallsLatency: promStatsClientCallsLatency.MustCurryWith(prometheus.Labels{"dsn": dsnString(dsnParsed)}),
+func (c *clientStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.clientStats()
+	ch <- prometheus.MustNewConstMetric(c.openIterators, prometheus.GaugeValue, float64(stats.OpenIterators))
+	ch <- prometheus.MustNewConstMetric(c.pooledSerializers, prometheus.GaugeValue, float64(stats.PooledSerializers))
+	ch <- prometheus.MustNewConstMetric(c.cgoCalls, prometheus.GaugeValue, float64(stats.CgoCalls))
+	ch <- prometheus.MustNewConstMetric(c.cgoLimit, prometheus.GaugeValue, float64(stats.CgoLimit))
+	ch <- prometheus.MustNewConstMetric(c.pendingAsyncOps, prometheus.GaugeValue, float64(stats.PendingAsyncOps))
+	ch <- prometheus.MustNewConstMetric(c.pendingAsyncBytes, prometheus.GaugeValue, float64(stats.PendingAsyncBytes))
+	ch <- prometheus.MustNewConstMetric(c.txWindowWaits, prometheus.CounterValue, float64(stats.TxWindowWaits))
+	ch <- prometheus.MustNewConstMetric(c.txWindowRejects, prometheus.CounterValue, float64(stats.TxWindowRejects))
+	ch <- prometheus.MustNewConstMetric(c.txInFlight, prometheus.GaugeValue, float64(stats.TxInFlight))
+	ch <- prometheus.MustNewConstMetric(c.memoryBudget, prometheus.GaugeValue, float64(stats.MemoryBudget))
+	ch <- prometheus.MustNewConstMetric(c.cacheMemory, prometheus.GaugeValue, float64(stats.CacheMemory))
+	ch <- prometheus.MustNewConstMetric(c.jsonMemory, prometheus.GaugeValue, float64(stats.JSONMemory))
+	ch <- prometheus.MustNewConstMetric(c.cacheEvictions, prometheus.CounterValue, float64(stats.CacheEvictions))
+	ch <- prometheus.MustNewConstMetric(c.stateRetries, prometheus.CounterValue, float64(stats.StateInvalidatedRetries))
+	ch <- prometheus.MustNewConstMetric(c.retryAttempts, prometheus.CounterValue, float64(stats.RetryAttempts))
+	ch <- prometheus.MustNewConstMetric(c.retrySuccesses, prometheus.CounterValue, float64(stats.RetrySuccesses))
+	ch <- prometheus.MustNewConstMetric(c.retryAbandoned, prometheus.CounterValue, float64(stats.RetryAbandoned))
+	for ns, cache := range stats.NamespaceCaches {
+		ch <- prometheus.MustNewConstMetric(c.nsCacheHits, prometheus.CounterValue, float64(cache.Hits), ns)
+		ch <- prometheus.MustNewConstMetric(c.nsCacheMisses, prometheus.CounterValue, float64(cache.Misses), ns)
+		ch <- prometheus.MustNewConstMetric(c.nsCacheEvictions, prometheus.CounterValue, float64(cache.Evictions), ns)
+		ch <- prometheus.MustNewConstMetric(c.nsCacheItems, prometheus.GaugeValue, float64(cache.Items), ns)
+		ch <- prometheus.MustNewConstMetric(c.nsDecodedItems, prometheus.CounterValue, float64(cache.Decodes), ns)
+		ch <- prometheus.MustNewConstMetric(c.nsDecodeSeconds, prometheus.CounterValue, cache.DecodeTime.Seconds(), ns)
 	}
 }
@@ -0,0 +1,132 @@
+package reindexer_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	db, srv := newMockDB(t, "write_metrics", reindexer.WithPrometheusMetrics(reindexer.PrometheusMetricsOptions{Registerer: registry, Prefix: "mockwrite"}))
+	defer db.Close()
+
+	require.NoError(t, db.Upsert(testNs, testItem{ID: 1, Name: "first"}))
+	require.NoError(t, db.Upsert(testNs, testItem{ID: 2, Name: "second"}))
+	srv.SetError(mock.MethodModifyItem, errors.New("write failed"))
+	assert.Error(t, db.Delete(testNs, testItem{ID: 1}))
+	srv.SetError(mock.MethodModifyItem, nil)
+	tx, err := db.BeginTx(testNs)
+	require.NoError(t, err)
+	require.NoError(t, tx.Upsert(testItem{ID: 3}))
+	require.NoError(t, tx.Commit())
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	type key struct{ name, mode string }
+	values := make(map[key]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["ns"] != testNs {
+				continue
+			}
+			values[key{f.GetName(), labels["mode"]}] = float64(m.GetSummary().GetSampleCount()) + float64(m.GetHistogram().GetSampleCount()) + m.GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, float64(2), values[key{"mockwrite_client_write_latency_seconds", "Upsert"}])
+	assert.Equal(t, float64(2), values[key{"mockwrite_client_write_size_bytes", "Upsert"}])
+	assert.Equal(t, float64(1), values[key{"mockwrite_client_write_latency_seconds", "Delete"}])
+	assert.Equal(t, float64(1), values[key{"mockwrite_client_write_errors_total", "Delete"}])
+	assert.NotContains(t, values, key{"mockwrite_client_write_errors_total", "Upsert"})
+	assert.Equal(t, float64(1), values[key{"mockwrite_client_write_latency_seconds", "Tx.Commit"}])
+	assert.Equal(t, float64(1), values[key{"mockwrite_client_write_size_bytes", "Tx.Commit"}])
+}
+
+func TestPrometheusOptions(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	db, srv := newMockDB(t, "prometheus", reindexer.WithPrometheusMetrics(reindexer.PrometheusMetricsOptions{
+		Registerer:  registry,
+		Buckets:     []float64{0.001, 0.01, 0.1},
+		ConstLabels: prometheus.Labels{"env": "test", "region": "eu"},
+	}))
+	defer db.Close()
+	require.NoError(t, db.Upsert(testNs, testItem{ID: 1}))
+
+	srv.SetError(mock.MethodSelectQuery, bindings.NewError("query is broken", bindings.ErrQueryExec))
+	_, err := db.Query(testNs).Exec().FetchAll()
+	require.Error(t, err)
+	_, err = db.Query(testNs).Exec().FetchAll()
+	require.Error(t, err)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	metrics := make(map[string][]map[string]string)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			assert.Equal(t, "test", labels["env"])
+			assert.Equal(t, "eu", labels["region"])
+			if f.GetName() == "reindexer_client_calls_latency_seconds" {
+				require.NotNil(t, m.GetHistogram())
+				assert.Len(t, m.GetHistogram().GetBucket(), 3)
+			}
+			if f.GetName() == "reindexer_client_errors_total" {
+				labels["value"] = fmt.Sprint(m.GetCounter().GetValue())
+			}
+			metrics[f.GetName()] = append(metrics[f.GetName()], labels)
+		}
+	}
+	require.Contains(t, metrics, "reindexer_client_calls_latency_seconds")
+	require.Len(t, metrics["reindexer_client_errors_total"], 1)
+	errs := metrics["reindexer_client_errors_total"][0]
+	assert.Equal(t, "Select", errs["cmd"])
+	assert.Equal(t, testNs, errs["ns"])
+	assert.Equal(t, fmt.Sprint(bindings.ErrQueryExec), errs["code"])
+	assert.Equal(t, "2", errs["value"])
+}
+
+func TestClientStatsMetricsOfSameDSN(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	opts := reindexer.WithPrometheusMetrics(reindexer.PrometheusMetricsOptions{Registerer: registry})
+	first, _ := newMockDB(t, "client_stats_metrics", opts)
+	defer first.Close()
+	second, _ := newMockDB(t, "client_stats_metrics", opts)
+
+	clientIDs := func() map[string]bool {
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		ids := make(map[string]bool)
+		for _, f := range families {
+			if f.GetName() != "reindexer_client_open_iterators" {
+				continue
+			}
+			for _, m := range f.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "client_id" {
+						ids[l.GetValue()] = true
+					}
+				}
+			}
+		}
+		return ids
+	}
+	assert.Len(t, clientIDs(), 2)
+
+	second.Close()
+	assert.Len(t, clientIDs(), 1)
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/restream/reindexer/v3/bindings"
@@ -90,6 +92,7 @@ const (
 	valueComposite = bindings.ValueComposite
 	valueTuple     = bindings.ValueTuple
 	valueUuid      = bindings.ValueUuid
+	valueNull      = bindings.ValueNull
 )
 
 const (
@@ -131,13 +134,43 @@ type Query struct {
 	json            []byte
 	jsonOffsets     []int
 	totalName       string
+	aggsName        string
+	label           string
+	spillDir        string
+	spill           bool
+	cacheTTL        time.Duration
+	cacheMaxEntries int
+	resultsFlags    int
+	accurateTotal   bool
+	cachedTotalPos  []int
+	totalRequested  bool
+	selectFilter    []string
+	subQueries      []subQueryEntry
 	executed        bool
 	fetchCount      int
+	fetchCountSet   bool
+	limit           int
+	limitSet        bool
+	maxResultBytes  int64
+	allowUnsafe     *bool
 	queriesCount    int
 	opennedBrackets []int
+	withDeleted     bool
+	withoutDefaults bool
+	defaultsAdded   bool
+	softDeleteAdded bool
+	withoutHistory  bool
+	pkTiebreaker    bool
+	sortEntries     []SortEntry
+	validationErrs  []error
 	tx              *Tx
 	traceNew        []byte
 	traceClose      []byte
+
+	// State of the serializer before the filters, added on execution (see markFiltersPos). filtersPos is 0, if there are no such filters
+	filtersPos          int
+	filtersQueriesCount int
+	filtersNextOp       int
 }
 
 var queryPool sync.Pool
@@ -180,10 +213,32 @@ func newQuery(db *reindexerImpl, namespace string, tx *Tx) *Query {
 		q.ser = cjson.NewSerializer(q.ser.Bytes()[:0])
 		q.closed = false
 		q.totalName = ""
+		q.label = ""
+		q.spillDir = ""
+		q.spill = false
+		q.cacheTTL = 0
+		q.cacheMaxEntries = 0
+		q.resultsFlags = 0
+		q.accurateTotal = false
+		q.cachedTotalPos = q.cachedTotalPos[:0]
+		q.totalRequested = false
+		q.selectFilter = q.selectFilter[:0]
+		q.subQueries = q.subQueries[:0]
 		q.executed = false
 		q.nsArray = q.nsArray[:0]
 		q.queriesCount = 0
 		q.opennedBrackets = q.opennedBrackets[:0]
+		q.withDeleted = false
+		q.withoutDefaults = false
+		q.defaultsAdded = false
+		q.softDeleteAdded = false
+		q.withoutHistory = false
+		q.filtersPos = 0
+		q.pkTiebreaker = false
+		q.sortEntries = q.sortEntries[:0]
+		q.validationErrs = q.validationErrs[:0]
+		q.maxResultBytes = 0
+		q.allowUnsafe = nil
 	}
 	mktrace(&q.traceNew)
 
@@ -191,6 +246,10 @@ func newQuery(db *reindexerImpl, namespace string, tx *Tx) *Query {
 	q.db = db
 	q.nextOp = opAND
 	q.fetchCount = defaultFetchCount
+	q.fetchCountSet = false
+	q.limit = 0
+	q.limitSet = false
+	q.aggsName = ""
 	q.tx = tx
 
 	q.ser.PutVString(namespace)
@@ -233,8 +292,38 @@ func (q *Query) makeCopy(db *reindexerImpl, root *Query) *Query {
 	qC.json = append(q.json[:0:0], q.json...)
 	qC.jsonOffsets = append(q.jsonOffsets[:0:0], q.jsonOffsets...)
 	qC.totalName = q.totalName
+	qC.aggsName = q.aggsName
+	qC.label = q.label
+	qC.spillDir = q.spillDir
+	qC.spill = q.spill
+	qC.cacheTTL = q.cacheTTL
+	qC.cacheMaxEntries = q.cacheMaxEntries
+	qC.resultsFlags = q.resultsFlags
+	qC.accurateTotal = q.accurateTotal
+	qC.cachedTotalPos = append(q.cachedTotalPos[:0:0], q.cachedTotalPos...)
+	qC.totalRequested = q.totalRequested
+	qC.selectFilter = append(q.selectFilter[:0:0], q.selectFilter...)
+	qC.subQueries = append(q.subQueries[:0:0], q.subQueries...)
+	for i := range qC.subQueries {
+		qC.subQueries[i].query = q.subQueries[i].query.makeCopy(db, nil)
+	}
 	qC.executed = q.executed
 	qC.fetchCount = q.fetchCount
+	qC.fetchCountSet = q.fetchCountSet
+	qC.limit = q.limit
+	qC.limitSet = q.limitSet
+	qC.maxResultBytes = q.maxResultBytes
+	qC.allowUnsafe = q.allowUnsafe
+	qC.withDeleted = q.withDeleted
+	qC.withoutDefaults = q.withoutDefaults
+	qC.defaultsAdded = q.defaultsAdded
+	qC.softDeleteAdded = q.softDeleteAdded
+	qC.filtersPos = q.filtersPos
+	qC.filtersQueriesCount = q.filtersQueriesCount
+	qC.filtersNextOp = q.filtersNextOp
+	qC.pkTiebreaker = q.pkTiebreaker
+	qC.sortEntries = append(q.sortEntries[:0:0], q.sortEntries...)
+	qC.validationErrs = append(q.validationErrs[:0:0], q.validationErrs...)
 
 	qC.closed = q.closed
 	if q.root != nil && root == nil {
@@ -256,11 +345,32 @@ func (q *Query) makeCopy(db *reindexerImpl, root *Query) *Query {
 
 }
 
+// QueryFilter adds conditions to the query. Typed filters for the indexes of the struct may be generated by cmd/querygen
+type QueryFilter func(q *Query) *Query
+
+// WhereFilter returns filter, which adds where condition to the query (see Where)
+func WhereFilter(index string, condition int, keys interface{}) QueryFilter {
+	return func(q *Query) *Query {
+		return q.Where(index, condition, keys)
+	}
+}
+
+// Apply - Add conditions of the filters to DB query
+func (q *Query) Apply(filters ...QueryFilter) *Query {
+	for _, filter := range filters {
+		q = filter(q)
+	}
+	return q
+}
+
 // Where - Add where condition to DB query
 // For composite indexes keys must be []interface{}, with value of each subindex
 func (q *Query) Where(index string, condition int, keys interface{}) *Query {
 	t := reflect.TypeOf(keys)
 	v := reflect.ValueOf(keys)
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 
 	q.ser.PutVarCUInt(queryCondition)
 	q.ser.PutVString(index)
@@ -283,9 +393,13 @@ func (q *Query) Where(index string, condition int, keys interface{}) *Query {
 	return q
 }
 
-// Where - Add comparing two fields where condition to DB query
-// For composite indexes keys must be []interface{}, with value of each subindex
+// WhereBetweenFields - Add condition, which compares two fields of the same item, to DB query (e.g. 'spent > budget').
+// Fields may be indexes (including composite ones) or non-indexed fields
 func (q *Query) WhereBetweenFields(firstField string, condition int, secondField string) *Query {
+	if q.validating() {
+		q.validateField("WhereBetweenFields", firstField, nil, false)
+		q.validateField("WhereBetweenFields", secondField, nil, false)
+	}
 	q.ser.PutVarCUInt(queryBetweenFieldsCondition)
 	q.ser.PutVarCUInt(q.nextOp)
 	q.ser.PutVString(firstField)
@@ -325,6 +439,16 @@ func (q *Query) putValue(v reflect.Value) error {
 		v = v.Elem()
 		k = v.Kind()
 	}
+	// Values of the types with custom marshalers are compared in the same representation, as they are indexed
+	if mv, ok, err := cjson.MarshalValue(v); ok {
+		if err != nil {
+			// The query is not executed with this error, so null is put only to keep the serializer consistent
+			q.validationErrs = append(q.validationErrs, fmt.Errorf("can't marshal value of type %s: %s", v.Type().String(), err.Error()))
+			q.ser.PutVarCUInt(valueNull)
+			return nil
+		}
+		return q.putValue(reflect.ValueOf(mv))
+	}
 
 	switch k {
 	case reflect.Bool:
@@ -381,7 +505,9 @@ func (q *Query) putValue(v reflect.Value) error {
 
 // WhereInt - Add where condition to DB query with int args
 func (q *Query) WhereInt(index string, condition int, keys ...int) *Query {
-
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(condition)
 	q.nextOp = opAND
 	q.queriesCount++
@@ -395,7 +521,9 @@ func (q *Query) WhereInt(index string, condition int, keys ...int) *Query {
 
 // WhereInt - Add where condition to DB query with int args
 func (q *Query) WhereInt32(index string, condition int, keys ...int32) *Query {
-
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(condition)
 	q.nextOp = opAND
 	q.queriesCount++
@@ -409,7 +537,9 @@ func (q *Query) WhereInt32(index string, condition int, keys ...int32) *Query {
 
 // WhereInt64 - Add where condition to DB query with int64 args
 func (q *Query) WhereInt64(index string, condition int, keys ...int64) *Query {
-
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(condition)
 	q.nextOp = opAND
 	q.queriesCount++
@@ -423,7 +553,9 @@ func (q *Query) WhereInt64(index string, condition int, keys ...int64) *Query {
 
 // WhereString - Add where condition to DB query with string args
 func (q *Query) WhereString(index string, condition int, keys ...string) *Query {
-
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(condition)
 	q.nextOp = opAND
 	q.queriesCount++
@@ -439,7 +571,9 @@ func (q *Query) WhereString(index string, condition int, keys ...string) *Query
 // This function applies binary encoding to the uuid value.
 // 'index' MUST be declared as uuid index in this case
 func (q *Query) WhereUuid(index string, condition int, keys ...string) *Query {
-
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(condition)
 	q.nextOp = opAND
 	q.queriesCount++
@@ -456,11 +590,54 @@ func (q *Query) WhereUuid(index string, condition int, keys ...string) *Query {
 	return q
 }
 
-// WhereComposite - Add where condition to DB query with interface args for composite indexes
+// WhereComposite - Add where condition to DB query with interface args for composite indexes.
+// Each key is a slice with values of all the index parts in the index order (e.g. []interface{}{5, 2010} for 'rating+year').
+// Count of the values is checked against the index definition, if the namespace is opened by this client: mismatch is returned
+// as QueryValidationError on execution of the query
 func (q *Query) WhereComposite(index string, condition int, keys ...interface{}) *Query {
+	if err := q.validateCompositeKeys(index, keys); err != nil {
+		q.validationErrs = append(q.validationErrs, err)
+	}
 	return q.Where(index, condition, keys)
 }
 
+// validateCompositeKeys checks, that each key of the composite index condition contains values for all of the index parts
+func (q *Query) validateCompositeKeys(index string, keys []interface{}) error {
+	parts := 0
+	if q.db != nil {
+		if ns, err := q.db.getNS(q.Namespace); err == nil {
+			for _, indexDef := range ns.indexes {
+				if !strings.EqualFold(indexDef.Name, index) {
+					continue
+				}
+				if indexDef.FieldType != "composite" {
+					return fmt.Errorf("WhereComposite: index '%s' is not composite", index)
+				}
+				parts = len(indexDef.JSONPaths)
+				break
+			}
+		}
+	}
+	if parts == 0 && strings.Contains(index, "+") {
+		parts = len(strings.Split(index, "+"))
+	}
+	if parts == 0 {
+		// Unknown index: the server checks the condition
+		return nil
+	}
+
+	for i, key := range keys {
+		v := reflect.ValueOf(key)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("WhereComposite: key %d of composite index '%s' must be a slice of %d values, got %T", i, index, parts, key)
+		}
+		if v.Len() != parts {
+			return fmt.Errorf("WhereComposite: key %d of composite index '%s' expects %d values, got %d", i, index, parts, v.Len())
+		}
+	}
+	return nil
+}
+
 // WhereString - Add where condition to DB query with string args
 func (q *Query) Match(index string, keys ...string) *Query {
 
@@ -469,7 +646,9 @@ func (q *Query) Match(index string, keys ...string) *Query {
 
 // WhereString - Add where condition to DB query with bool args
 func (q *Query) WhereBool(index string, condition int, keys ...bool) *Query {
-
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(condition)
 	q.nextOp = opAND
 	q.queriesCount++
@@ -488,7 +667,9 @@ func (q *Query) WhereBool(index string, condition int, keys ...bool) *Query {
 
 // WhereDouble - Add where condition to DB query with float args
 func (q *Query) WhereDouble(index string, condition int, keys ...float64) *Query {
-
+	if q.validating() {
+		q.validateField("Where", index, keys, true)
+	}
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(condition)
 	q.nextOp = opAND
 	q.queriesCount++
@@ -502,6 +683,9 @@ func (q *Query) WhereDouble(index string, condition int, keys ...float64) *Query
 
 // DWithin - Add DWithin condition to DB query
 func (q *Query) DWithin(index string, point Point, distance float64) *Query {
+	if q.validating() {
+		q.validateField("DWithin", index, point, true)
+	}
 
 	q.ser.PutVarCUInt(queryCondition).PutVString(index).PutVarCUInt(q.nextOp).PutVarCUInt(DWITHIN)
 	q.nextOp = opAND
@@ -570,7 +754,11 @@ func (r *AggregateFacetRequest) Sort(field string, desc bool) *AggregateFacetReq
 // For composite indexes values must be []interface{}, with value of each subindex
 // Forced sort is support for the first sorting field only
 func (q *Query) Sort(sortIndex string, desc bool, values ...interface{}) *Query {
+	if q.validating() {
+		q.validateField("Sort", sortIndex, values, true)
+	}
 
+	q.sortEntries = append(q.sortEntries, SortEntry{Field: sortIndex, Desc: desc})
 	q.ser.PutVarCUInt(querySortIndex)
 	q.ser.PutVString(sortIndex)
 	if desc {
@@ -587,6 +775,75 @@ func (q *Query) Sort(sortIndex string, desc bool, values ...interface{}) *Query
 	return q
 }
 
+// SortEntry - sort criteria for SortMulti
+type SortEntry struct {
+	Field string
+	Desc  bool
+	// Collate - expected collation of the string field (CollateASCII, CollateUTF8, CollateNumeric or CollateCustom).
+	// Server sorts by collation of the field's index and has no collation of the query's sort, so the query fails,
+	// if the index has another collation. Default CollateNone accepts collation of the index
+	Collate int
+}
+
+// SortMulti - Apply sort order by several fields. Entries are applied in the passed order: the next entry is used only for items,
+// which are equal by the previous ones. Order of items, which are equal by all of the entries, is undefined.
+// If pkTiebreaker is true, ascending sort by primary key is added as the last entry, so the order is stable and
+// may be used for pagination with Offset/Limit
+func (q *Query) SortMulti(entries []SortEntry, pkTiebreaker bool) *Query {
+	for _, entry := range entries {
+		if entry.Collate != CollateNone {
+			q.checkSortCollate(entry)
+		}
+		q.Sort(entry.Field, entry.Desc)
+	}
+	q.pkTiebreaker = q.pkTiebreaker || pkTiebreaker
+	return q
+}
+
+// checkSortCollate records error of the query, if the entry's collation differs from collation of the field's index
+func (q *Query) checkSortCollate(entry SortEntry) {
+	if q.db == nil {
+		return
+	}
+	ns, err := q.db.getNS(q.Namespace)
+	if err != nil {
+		// Unknown namespace is reported by the server
+		return
+	}
+	for _, indexDef := range ns.indexes {
+		if !strings.EqualFold(indexDef.Name, entry.Field) {
+			continue
+		}
+		if collateModes["collate_"+indexDef.CollateMode] != entry.Collate {
+			q.validationErrs = append(q.validationErrs, fmt.Errorf("SortMulti: index '%s' of namespace '%s' has collation '%s', which differs from the requested one",
+				indexDef.Name, q.Namespace, indexDef.CollateMode))
+		}
+		return
+	}
+	q.validationErrs = append(q.validationErrs, fmt.Errorf("SortMulti: collation is requested for field '%s' of namespace '%s', which is not indexed",
+		entry.Field, q.Namespace))
+}
+
+// addSortPkTiebreaker adds sort by primary key of the namespace, if it was requested by SortMulti or Paginate
+func (q *Query) addSortPkTiebreaker(ns *reindexerNamespace) {
+	if !q.pkTiebreaker {
+		return
+	}
+	for _, indexDef := range ns.indexes {
+		if !indexDef.IsPK {
+			continue
+		}
+		for _, entry := range q.sortEntries {
+			if strings.EqualFold(entry.Field, indexDef.Name) {
+				// Already sorted by primary key
+				return
+			}
+		}
+		q.Sort(indexDef.Name, false)
+		return
+	}
+}
+
 // SortStDistance - wrapper for geometry sorting by shortes distance between geometry field and point (ST_Distance)
 func (q *Query) SortStPointDistance(field string, p Point, desc bool) *Query {
 	var sb strings.Builder
@@ -646,28 +903,56 @@ func (q *Query) Distinct(distinctIndex string) *Query {
 func (q *Query) ReqTotal(totalNames ...string) *Query {
 	q.ser.PutVarCUInt(queryReqTotal)
 	q.ser.PutVarCUInt(modeAccurateTotal)
+	q.totalRequested = true
 	if len(totalNames) != 0 {
 		q.totalName = totalNames[0]
 	}
 	return q
 }
 
+// AggregationsJsonName adds aggregation results to ExecToJson output as the key with the name (e.g. "aggregations").
+// By default (and with empty name) they are not added and are available via JSONIterator.AggResults only
+func (q *Query) AggregationsJsonName(name string) *Query {
+	q.aggsName = name
+	return q
+}
+
 // CachedTotal Request cached total items calculation
 func (q *Query) CachedTotal(totalNames ...string) *Query {
 	q.ser.PutVarCUInt(queryReqTotal)
-	q.ser.PutVarCUInt(modeCachedTotal)
+	if q.accurateTotal {
+		q.ser.PutVarCUInt(modeAccurateTotal)
+	} else {
+		q.cachedTotalPos = append(q.cachedTotalPos, len(q.ser.Bytes()))
+		q.ser.PutVarCUInt(modeCachedTotal)
+	}
+	q.totalRequested = true
 	if len(totalNames) != 0 {
 		q.totalName = totalNames[0]
 	}
 	return q
 }
 
+// AccurateTotal makes total count, requested by CachedTotal, to be calculated accurately, as it's done by ReqTotal,
+// so it's not taken from (or put into) the namespace's query cache. May be called before or after CachedTotal
+func (q *Query) AccurateTotal() *Query {
+	q.accurateTotal = true
+	buf := q.ser.Bytes()
+	for _, pos := range q.cachedTotalPos {
+		buf[pos] = modeAccurateTotal
+	}
+	q.cachedTotalPos = q.cachedTotalPos[:0]
+	return q
+}
+
 // Limit - Set limit (count) of returned items
 func (q *Query) Limit(limitItems int) *Query {
 	if limitItems > cInt32Max {
 		limitItems = cInt32Max
 	}
 	q.ser.PutVarCUInt(queryLimit).PutVarCUInt(limitItems)
+	q.limit = limitItems
+	q.limitSet = true
 	return q
 }
 
@@ -705,6 +990,115 @@ func (q *Query) WithRank() *Query {
 	return q
 }
 
+// WithDeleted disables filtering of soft deleted items for the namespace with soft delete enabled.
+// Delete query with this flag removes items physically
+func (q *Query) WithDeleted() *Query {
+	q.withDeleted = true
+	return q
+}
+
+// WithoutDefaults disables default filters of the namespace (see NamespaceOptions.WithDefaultFilter) for the query.
+// Joined and merged queries have their own setting
+func (q *Query) WithoutDefaults() *Query {
+	q.withoutDefaults = true
+	return q
+}
+
+// SpillToDisk enables staging of the query results to the temporary file in dir (or in the default directory for
+// temporary files, if dir is empty). All the results are fetched from the server on Exec, and iterator reads them
+// from the file chunk by chunk, so huge results may be exported without holding them in memory or on the server.
+// Has no effect for builtin binding, which doesn't fetch results by chunks
+func (q *Query) SpillToDisk(dir string) *Query {
+	q.spill = true
+	q.spillDir = dir
+	return q
+}
+
+// CachePolicy enables client side caching of the query results. Results are cached by serialized query for ttl, and
+// up to maxEntries results are cached for the query's namespace. Cached results are invalidated by writes of this client
+// to the namespaces of the query (including joined and merged ones). With cproto binding WAL of the namespaces is watched,
+// so writes of other clients invalidate the results within a second, otherwise they are visible after ttl expires.
+// Only results of Exec are cached
+func (q *Query) CachePolicy(ttl time.Duration, maxEntries int) *Query {
+	q.cacheTTL = ttl
+	q.cacheMaxEntries = maxEntries
+	return q
+}
+
+// ResultsFlags sets flags of the results, requested from the server (bindings.ResultsXXX): format (bindings.ResultsCJson, bindings.ResultsJson, bindings.ResultsMsgPack or bindings.ResultsPure)
+// and additional data of the items (bindings.ResultsWithItemID, bindings.ResultsWithPayloadTypes, etc). It allows to minimize size of the results for hot queries:
+// e.g. bindings.ResultsPure returns only counters and aggregations without items, and results without bindings.ResultsWithItemID don't contain versions of the items.
+// Without bindings.ResultsWithPayloadTypes the local state of the namespace's payload types must be actual, so it should be omitted only for namespaces with the stable schema.
+// There is no flag to request LSNs of the items in the current protocol. Flags are used by cproto binding only, builtin binding always returns pointers to the items
+func (q *Query) ResultsFlags(flags int) *Query {
+	q.resultsFlags = flags
+	return q
+}
+
+// validateResultsFlags checks, that format of the results, requested by ResultsFlags, may be read by iterator
+func (q *Query) validateResultsFlags() error {
+	if q.resultsFlags == 0 {
+		return nil
+	}
+	switch q.resultsFlags & bindings.ResultsFormatMask {
+	case bindings.ResultsCJson, bindings.ResultsJson, bindings.ResultsMsgPack, bindings.ResultsPure:
+		return nil
+	}
+	return bindings.NewError(fmt.Sprintf("rq: Unsupported format of the results flags 0x%x: only CJSON, JSON, msgpack and pure formats are supported", q.resultsFlags), ErrCodeParams)
+}
+
+// MsgPack requests items of the results in msgpack format, so they are not decoded into the structs and are available via Iterator.MsgPack.
+// It's a shortcut for ResultsFlags with bindings.ResultsMsgPack format. Supported by cproto binding only
+func (q *Query) MsgPack() *Query {
+	return q.ResultsFlags(bindings.ResultsMsgPack)
+}
+
+// RawJSON requests items of the results in JSON format, so they are not decoded into the structs and are available via Iterator.JSONBytes
+// directly from the results buffer. It's useful for the services, which just forward the documents. Joined items are not returned
+// in this mode (use ExecToJson for them). Unlike ExecToJson, results are fetched by pages. It's a shortcut for ResultsFlags with bindings.ResultsJson format
+func (q *Query) RawJSON() *Query {
+	return q.ResultsFlags(bindings.ResultsJson)
+}
+
+// Label sets label of the query. Label is added to pprof labels of the query execution, if WithPprofLabels option is enabled
+func (q *Query) Label(label string) *Query {
+	q.label = label
+	return q
+}
+
+// Namespaces returns names of the namespaces of the query, of its joined, merged queries and subqueries (e.g. to check their LSNs by ReadSnapshot)
+func (q *Query) Namespaces() []string {
+	if q.root != nil {
+		q = q.root
+	}
+	var namespaces []string
+	add := func(namespace string) {
+		namespace = strings.ToLower(namespace)
+		for _, ns := range namespaces {
+			if ns == namespace {
+				return
+			}
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	add(q.Namespace)
+	for _, jq := range q.joinQueries {
+		add(jq.Namespace)
+	}
+	for _, mq := range q.mergedQueries {
+		add(mq.Namespace)
+		for _, jq := range mq.joinQueries {
+			add(jq.Namespace)
+		}
+	}
+	for _, sq := range q.subQueries {
+		for _, namespace := range sq.query.Namespaces() {
+			add(namespace)
+		}
+	}
+	return namespaces
+}
+
 // SetContext set interface, which will be passed to Joined interface
 func (q *Query) SetContext(ctx interface{}) *Query {
 	q.context = ctx
@@ -722,6 +1116,18 @@ func (q *Query) Exec() *Iterator {
 
 // ExecCtx will execute query, and return slice of items
 func (q *Query) ExecCtx(ctx context.Context) *Iterator {
+	q, err := q.prepareExec(ctx)
+	if err != nil {
+		return errIterator(err)
+	}
+	if q.tx != nil && q.tx.reads != nil {
+		return q.db.execQueryTx(ctx, q)
+	}
+	return q.db.execQuery(ctx, q)
+}
+
+// prepareExec marks the root query as executed, validates it and resolves its subqueries. Returns the root query
+func (q *Query) prepareExec(ctx context.Context) (*Query, error) {
 	if q.root != nil {
 		q = q.root
 	}
@@ -737,7 +1143,13 @@ func (q *Query) ExecCtx(ctx context.Context) *Iterator {
 
 	q.executed = true
 
-	return q.db.execQuery(ctx, q)
+	if err := q.validationError(); err != nil {
+		return q, err
+	}
+	if err := q.resolveSubQueries(ctx); err != nil {
+		return q, err
+	}
+	return q, nil
 }
 
 // ExecToJson will execute query, and return iterator
@@ -762,6 +1174,12 @@ func (q *Query) ExecToJsonCtx(ctx context.Context, jsonRoots ...string) *JSONIte
 
 	q.executed = true
 
+	if err := q.validationError(); err != nil {
+		return errJSONIterator(err)
+	}
+	if err := q.resolveSubQueries(ctx); err != nil {
+		return errJSONIterator(err)
+	}
 	jsonRoot := q.Namespace
 	if len(jsonRoots) != 0 && len(jsonRoots[0]) != 0 {
 		jsonRoot = jsonRoots[0]
@@ -770,6 +1188,38 @@ func (q *Query) ExecToJsonCtx(ctx context.Context, jsonRoots ...string) *JSONIte
 	return q.db.execToJsonQuery(ctx, q, jsonRoot)
 }
 
+// ExecToMaps will execute query, and return all the results, decoded into generic maps.
+// It's useful for the consumers, which don't know the namespace's struct (e.g. admin UIs and rules engines)
+func (q *Query) ExecToMaps(ctx context.Context) ([]map[string]interface{}, error) {
+	it := q.ExecCtx(ctx)
+	defer it.Close()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	items := make([]map[string]interface{}, 0, it.Count())
+	for it.Next() {
+		item, err := it.ObjectAsMap()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, it.Error()
+}
+
+// ExecToJsonStream will execute query, and write its results in JSON format to w. Returns count of the written bytes.
+// Unlike JSONIterator.FetchAll, results are written before the query is returned to the pool, so w may keep them.
+// Results are written by single call of w.Write, so its argument is the whole response (e.g. for Content-Length)
+func (q *Query) ExecToJsonStream(ctx context.Context, w io.Writer, jsonRoots ...string) (int64, error) {
+	it := q.ExecToJsonCtx(ctx, jsonRoots...)
+	defer it.Close()
+	if it.err != nil {
+		return 0, it.err
+	}
+	n, err := w.Write(it.json)
+	return int64(n), err
+}
+
 func (q *Query) close() {
 	if q.root != nil {
 		q = q.root
@@ -830,6 +1280,12 @@ func (q *Query) DeleteCtx(ctx context.Context) (int, error) {
 
 	defer q.close()
 
+	if err := q.validationError(); err != nil {
+		return 0, err
+	}
+	if err := q.resolveSubQueries(ctx); err != nil {
+		return 0, err
+	}
 	if q.tx != nil {
 		return q.db.deleteQueryTx(ctx, q, q.tx)
 	}
@@ -837,6 +1293,34 @@ func (q *Query) DeleteCtx(ctx context.Context) (int, error) {
 	return q.db.deleteQuery(ctx, q)
 }
 
+// DeleteReturning will execute query, and delete items, which matches query. Returns iterator over the deleted items
+// (e.g. for audit or cascade deletion). Items of the namespace with soft delete are returned with the updated field of the deletion time
+func (q *Query) DeleteReturning(ctx context.Context) *Iterator {
+	if q.root != nil || len(q.joinQueries) != 0 {
+		return errIterator(errors.New("DeleteReturning does not support joined queries"))
+	}
+	if q.closed {
+		q.panicTrace("DeleteReturning call on already closed query. You should create new Query")
+	}
+
+	q.executed = true
+
+	if err := q.validationError(); err != nil {
+		q.close()
+		return errIterator(err)
+	}
+	if q.tx != nil {
+		q.close()
+		return errIterator(bindings.NewError("rq: DeleteReturning is not supported by transaction's queries", ErrCodeParams))
+	}
+	if err := q.resolveSubQueries(ctx); err != nil {
+		q.close()
+		return errIterator(err)
+	}
+
+	return q.db.deleteReturning(ctx, q)
+}
+
 func getValueJSON(value interface{}) string {
 	ok := false
 	var err error
@@ -857,6 +1341,9 @@ func getValueJSON(value interface{}) string {
 
 // SetObject adds update of object field request for update query
 func (q *Query) SetObject(field string, values interface{}) *Query {
+	if q.validating() {
+		q.validateField("SetObject", field, nil, false)
+	}
 	size := 1
 	isArray := false
 	t := reflect.TypeOf(values)
@@ -905,6 +1392,9 @@ func (q *Query) Set(field string, values interface{}) *Query {
 		return q.SetObject(field, values)
 	}
 	v := reflect.ValueOf(values)
+	if q.validating() {
+		q.validateField("Set", field, values, true)
+	}
 
 	cmd := queryUpdateField
 	if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && v.Len() <= 1 {
@@ -945,6 +1435,9 @@ func (q *Query) Set(field string, values interface{}) *Query {
 
 // Drop removes field from item within Update statement
 func (q *Query) Drop(field string) *Query {
+	if q.validating() {
+		q.validateField("Drop", field, nil, false)
+	}
 	q.ser.PutVarCUInt(queryDropField)
 	q.ser.PutVString(field)
 	return q
@@ -952,6 +1445,9 @@ func (q *Query) Drop(field string) *Query {
 
 // SetExpression updates indexed field by arithmetical expression
 func (q *Query) SetExpression(field string, value string) *Query {
+	if q.validating() {
+		q.validateField("SetExpression", field, nil, false)
+	}
 	q.ser.PutVarCUInt(queryUpdateField)
 	q.ser.PutVString(field)
 
@@ -980,6 +1476,12 @@ func (q *Query) UpdateCtx(ctx context.Context) *Iterator {
 
 	q.executed = true
 
+	if err := q.validationError(); err != nil {
+		return errIterator(err)
+	}
+	if err := q.resolveSubQueries(ctx); err != nil {
+		return errIterator(err)
+	}
 	if q.tx != nil {
 		return q.db.updateQueryTx(ctx, q, q.tx)
 	}
@@ -1143,13 +1645,29 @@ func (q *Query) Select(fields ...string) *Query {
 	for _, field := range fields {
 		q.ser.PutVarCUInt(querySelectFilter).PutVString(field)
 	}
+	q.selectFilter = append(q.selectFilter, fields...)
 	return q
 }
 
 // FetchCount sets the number of items that will be fetched by one operation
-// When n <= 0 query will fetch all results in one operation
+// When n <= 0 query will fetch all results in one operation. It overrides adaptive fetch count (see WithAdaptiveFetch)
 func (q *Query) FetchCount(n int) *Query {
 	q.fetchCount = n
+	q.fetchCountSet = true
+	return q
+}
+
+// AllowUnsafe overrides default of Iterator.AllowUnsafe (see WithAllowUnsafe) for the results of the query.
+// Iterator.AllowUnsafe, called on the results, takes precedence over both
+func (q *Query) AllowUnsafe(allow bool) *Query {
+	q.allowUnsafe = &allow
+	return q
+}
+
+// MaxResultBytes limits total size of the raw results, received by the query (in all the fetched chunks).
+// Iterator stops with ResultSizeError, when the limit is exceeded. When n <= 0 the size is not limited
+func (q *Query) MaxResultBytes(n int64) *Query {
+	q.maxResultBytes = n
 	return q
 }
 
@@ -0,0 +1,251 @@
+package reindexer
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// queryCache is client side cache of query results, enabled by Query.CachePolicy.
+// Results are keyed by serialized query, and are invalidated by writes of this client to the namespaces of the query, by changes
+// in the namespaces' WAL (see reindexerImpl.watchQueryCache) or by TTL
+type queryCache struct {
+	lock    sync.Mutex
+	entries map[string]*queryCacheEntry
+	// Entries of each namespace in LRU order (front is the most recently used)
+	lru map[string]*list.List
+	// Incremented on each invalidation. Results of the queries, which were executed concurrently with invalidation, are not cached
+	generation uint64
+	// Namespaces, which WAL is watched
+	watched map[string]struct{}
+	// Watching is stopped, when DB is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type queryCacheEntry struct {
+	key string
+	// Namespace of the query. Entries of the namespace are limited by max entries of the cache policy
+	namespace string
+	// Namespaces of the query, merged and joined queries. Writes to any of them invalidate the entry
+	namespaces []string
+	expires    time.Time
+	// Copied chunks of the result, fetched by chunks
+	chunks [][]byte
+	// Result, which is not fetched by chunks (e.g. result of builtin binding). It is freed, when the entry is not referenced anymore
+	raw  bindings.RawBuffer
+	refs int32
+	elem *list.Element
+}
+
+// cachedResult reads cached result. Each iterator gets own reader
+type cachedResult struct {
+	entry *queryCacheEntry
+	chunk int
+}
+
+func newQueryCache() *queryCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &queryCache{
+		entries: make(map[string]*queryCacheEntry),
+		lru:     make(map[string]*list.List),
+		watched: make(map[string]struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// cacheKey returns key of the query's results: serialized query with its joined and merged queries and flags of the results
+func (q *Query) cacheKey() string {
+	var sb strings.Builder
+	writePart := func(kind int, data []byte) {
+		sb.WriteString(strconv.Itoa(kind))
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(len(data)))
+		sb.WriteByte(':')
+		sb.Write(data)
+	}
+	writePart(queryEnd, q.ser.Bytes())
+	sb.WriteString(strconv.Itoa(q.resultsFlags))
+	for _, sq := range q.joinQueries {
+		writePart(sq.joinType, sq.ser.Bytes())
+	}
+	for _, mq := range q.mergedQueries {
+		writePart(merge, mq.ser.Bytes())
+		for _, sq := range mq.joinQueries {
+			writePart(sq.joinType, sq.ser.Bytes())
+		}
+	}
+	return sb.String()
+}
+
+// cacheNamespaces returns namespaces of the query, of the merged and of the joined queries
+func (q *Query) cacheNamespaces() []string {
+	namespaces := []string{q.Namespace}
+	for _, sq := range q.joinQueries {
+		namespaces = append(namespaces, sq.Namespace)
+	}
+	for _, mq := range q.mergedQueries {
+		namespaces = append(namespaces, mq.Namespace)
+		for _, sq := range mq.joinQueries {
+			namespaces = append(namespaces, sq.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// get returns reader of the cached result, or nil, if there is no actual result for the key.
+// Also returns generation of the cache, which must be passed to put
+func (c *queryCache) get(key string) (bindings.RawBuffer, uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, c.generation
+	}
+	if time.Now().After(entry.expires) {
+		c.remove(entry)
+		return nil, c.generation
+	}
+	c.lru[entry.namespace].MoveToFront(entry.elem)
+	return entry.newReader(), c.generation
+}
+
+// put caches the query's result, if the cache was not invalidated since generation. All the chunks of the result are fetched from the server.
+// Returns reader of the cached result, which must be used instead of the result
+func (c *queryCache) put(ctx context.Context, q *Query, key string, generation uint64, result bindings.RawBuffer) (bindings.RawBuffer, error) {
+	entry := &queryCacheEntry{
+		key:        key,
+		namespace:  q.Namespace,
+		namespaces: q.cacheNamespaces(),
+		expires:    time.Now().Add(q.cacheTTL),
+		refs:       1,
+	}
+	if fetchMore, ok := result.(bindings.FetchMore); ok {
+		defer result.Free()
+		err := forEachResultChunk(ctx, fetchMore, result, q.fetchCount, func(chunk []byte) error {
+			entry.chunks = append(entry.chunks, append([]byte(nil), chunk...))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		entry.raw = result
+	}
+	reader := entry.newReader()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.generation != generation {
+		entry.release()
+		return reader, nil
+	}
+	if old, ok := c.entries[key]; ok {
+		c.remove(old)
+	}
+	lru, ok := c.lru[entry.namespace]
+	if !ok {
+		lru = list.New()
+		c.lru[entry.namespace] = lru
+	}
+	entry.elem = lru.PushFront(entry)
+	c.entries[key] = entry
+	for lru.Len() > q.cacheMaxEntries {
+		c.remove(lru.Back().Value.(*queryCacheEntry))
+	}
+	return reader, nil
+}
+
+// invalidate removes cached results of the queries, which use the namespace
+func (c *queryCache) invalidate(namespace string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	for _, entry := range c.entries {
+		for _, ns := range entry.namespaces {
+			if ns == namespace {
+				c.remove(entry)
+				break
+			}
+		}
+	}
+}
+
+// reset removes all the cached results
+func (c *queryCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	for _, entry := range c.entries {
+		c.remove(entry)
+	}
+}
+
+// setWatched marks the namespace as watched or not watched. Returns false, if the mark is not changed
+func (c *queryCache) setWatched(namespace string, watched bool) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, ok := c.watched[namespace]
+	if watched {
+		c.watched[namespace] = struct{}{}
+	} else {
+		delete(c.watched, namespace)
+	}
+	return ok != watched
+}
+
+// close stops watching of the namespaces
+func (c *queryCache) close() {
+	c.cancel()
+}
+
+func (c *queryCache) remove(entry *queryCacheEntry) {
+	delete(c.entries, entry.key)
+	lru := c.lru[entry.namespace]
+	lru.Remove(entry.elem)
+	if lru.Len() == 0 {
+		delete(c.lru, entry.namespace)
+	}
+	entry.release()
+}
+
+func (entry *queryCacheEntry) newReader() *cachedResult {
+	atomic.AddInt32(&entry.refs, 1)
+	return &cachedResult{entry: entry}
+}
+
+func (entry *queryCacheEntry) release() {
+	if atomic.AddInt32(&entry.refs, -1) == 0 && entry.raw != nil {
+		entry.raw.Free()
+	}
+}
+
+func (r *cachedResult) GetBuf() []byte {
+	if r.entry.raw != nil {
+		return r.entry.raw.GetBuf()
+	}
+	return r.entry.chunks[r.chunk]
+}
+
+// Fetch switches to the next cached chunk. Chunks are read sequentially, so offset and limit are ignored
+func (r *cachedResult) Fetch(ctx context.Context, offset, limit int, asJson bool) error {
+	if r.entry == nil || r.chunk+1 >= len(r.entry.chunks) {
+		return bindings.NewError("rq: No more chunks in cached result", ErrCodeLogic)
+	}
+	r.chunk++
+	return nil
+}
+
+func (r *cachedResult) Free() {
+	if r.entry != nil {
+		r.entry.release()
+		r.entry = nil
+	}
+}
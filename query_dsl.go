@@ -0,0 +1,882 @@
+package reindexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+// dslQuery is the query in the standard JSON DSL format of reindexer (see cpp_src/server/contrib/server.yml).
+// It's decoded from the serialized query by Query.DSL and converted to the query by Reindexer.QueryFromDSL
+type dslQuery struct {
+	Namespace       string             `json:"namespace"`
+	Limit           *int               `json:"limit,omitempty"`
+	Offset          *int               `json:"offset,omitempty"`
+	ReqTotal        dslReqTotal        `json:"req_total,omitempty"`
+	Explain         bool               `json:"explain"`
+	Type            string             `json:"type,omitempty"`
+	StrictMode      string             `json:"strict_mode,omitempty"`
+	WithRank        bool               `json:"select_with_rank"`
+	SelectFilter    []string           `json:"select_filter,omitempty"`
+	SelectFunctions []string           `json:"select_functions,omitempty"`
+	Sort            dslSorts           `json:"sort,omitempty"`
+	Filters         []dslFilter        `json:"filters"`
+	MergeQueries    []dslQuery         `json:"merge_queries,omitempty"`
+	Aggregations    []dslAggregation   `json:"aggregations,omitempty"`
+	EqualPositions  []dslEqualPosition `json:"equal_positions,omitempty"`
+	DropFields      []string           `json:"drop_fields,omitempty"`
+	UpdateFields    []dslUpdateField   `json:"update_fields,omitempty"`
+	// Conditions of the join, if the query is joined one
+	on []dslJoinOn
+}
+
+type dslSort struct {
+	Field  string        `json:"field"`
+	Desc   bool          `json:"desc"`
+	Values []interface{} `json:"values,omitempty"`
+}
+
+// dslSorts is sort entries of the query. DSL allows both single entry and array of entries
+type dslSorts []dslSort
+
+func (s *dslSorts) UnmarshalJSON(data []byte) error {
+	if data = bytes.TrimSpace(data); len(data) != 0 && data[0] == '{' {
+		var entry dslSort
+		if err := decodeDSL(data, &entry); err != nil {
+			return err
+		}
+		*s = dslSorts{entry}
+		return nil
+	}
+	var entries []dslSort
+	if err := decodeDSL(data, &entries); err != nil {
+		return err
+	}
+	*s = entries
+	return nil
+}
+
+// dslReqTotal is the mode of total count calculation: "disabled", "enabled" or "cached". Boolean values are accepted too
+type dslReqTotal string
+
+func (r *dslReqTotal) UnmarshalJSON(data []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		*r = "disabled"
+		if enabled {
+			*r = "enabled"
+		}
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(r))
+}
+
+type dslFilter struct {
+	Op             string             `json:"op,omitempty"`
+	Cond           string             `json:"cond,omitempty"`
+	Field          string             `json:"field,omitempty"`
+	Value          interface{}        `json:"value,omitempty"`
+	Filters        []dslFilter        `json:"filters,omitempty"`
+	JoinQuery      *dslJoinQuery      `json:"join_query,omitempty"`
+	FirstField     string             `json:"first_field,omitempty"`
+	SecondField    string             `json:"second_field,omitempty"`
+	EqualPositions []dslEqualPosition `json:"equal_positions,omitempty"`
+}
+
+type dslJoinQuery struct {
+	Type         string      `json:"type"`
+	Namespace    string      `json:"namespace"`
+	Limit        *int        `json:"limit,omitempty"`
+	Offset       *int        `json:"offset,omitempty"`
+	Filters      []dslFilter `json:"filters"`
+	Sort         dslSorts    `json:"sort,omitempty"`
+	On           []dslJoinOn `json:"on"`
+	SelectFilter []string    `json:"select_filter,omitempty"`
+}
+
+type dslJoinOn struct {
+	LeftField  string `json:"left_field"`
+	RightField string `json:"right_field"`
+	Cond       string `json:"cond"`
+	Op         string `json:"op"`
+}
+
+type dslAggregation struct {
+	Type   dslAggType `json:"type"`
+	Sort   dslSorts   `json:"sort,omitempty"`
+	Limit  *int       `json:"limit,omitempty"`
+	Offset *int       `json:"offset,omitempty"`
+	Fields []string   `json:"fields"`
+}
+
+// dslAggType is the name of the aggregation. Numeric types (AggSum, AggAvg, etc) are accepted too
+type dslAggType string
+
+func (a *dslAggType) UnmarshalJSON(data []byte) error {
+	var aggType int
+	if err := json.Unmarshal(data, &aggType); err == nil {
+		*a = dslAggType(dslAggNames[aggType])
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(a))
+}
+
+type dslEqualPosition struct {
+	Positions []string `json:"positions"`
+}
+
+type dslUpdateField struct {
+	Name    string        `json:"name"`
+	Type    string        `json:"type,omitempty"`
+	IsArray bool          `json:"is_array"`
+	Values  []interface{} `json:"values"`
+}
+
+// dslDouble is the double value of the query. It's always encoded with the fraction part, so its type is kept by QueryFromDSL
+type dslDouble float64
+
+func (d dslDouble) MarshalJSON() ([]byte, error) {
+	f := float64(d)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("rq: double value %v can't be represented by DSL", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return []byte(s), nil
+}
+
+var dslCondNames = map[int]string{
+	ANY: "any", EQ: "eq", LT: "lt", LE: "le", GT: "gt", GE: "ge", RANGE: "range",
+	SET: "set", ALLSET: "allset", EMPTY: "empty", LIKE: "like", DWITHIN: "dwithin",
+}
+
+var dslOpNames = map[int]string{opAND: "and", opOR: "or", opNOT: "not"}
+
+var dslJoinTypes = map[int]string{innerJoin: "inner", orInnerJoin: "orinner", leftJoin: "left"}
+
+var dslReqTotalModes = map[int]dslReqTotal{modeNoCalc: "disabled", modeAccurateTotal: "enabled", modeCachedTotal: "cached"}
+
+var dslAggNames = map[int]string{
+	AggSum: "sum", AggAvg: "avg", AggFacet: "facet", AggMin: "min", AggMax: "max", AggDistinct: "distinct",
+}
+
+var dslStrictModes = map[int]string{
+	int(QueryStrictModeNone): "none", int(QueryStrictModeNames): "names", int(QueryStrictModeIndexes): "indexes",
+}
+
+// decodeDSL unmarshals JSON DSL to v. Numbers are decoded as json.Number, so integer and double values are distinguished
+func decodeDSL(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// DSL returns the query in the standard JSON DSL format of reindexer, which may be stored or transported and
+// converted back to the query by Reindexer.QueryFromDSL. Joined and merged queries, aggregations and update fields are included.
+// Query with subqueries (WhereQuery) can't be represented by DSL, because they are resolved on execution only
+func (q *Query) DSL() ([]byte, error) {
+	if q.root != nil {
+		q = q.root
+	}
+	d, err := q.toDSL()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Select functions contain HTML tags (e.g. 'highlight(<b>,</b>)'), which are kept as is
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(d); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// toDSL decodes the serialized query with its joined and merged queries
+func (q *Query) toDSL() (*dslQuery, error) {
+	if len(q.subQueries) != 0 {
+		return nil, bindings.NewError("rq: query with subqueries can't be represented by DSL", ErrCodeParams)
+	}
+	d := &dslQuery{Filters: []dslFilter{}, ReqTotal: dslReqTotalModes[modeNoCalc], Type: "select"}
+	ser := cjson.NewSerializer(q.ser.Bytes())
+	d.Namespace = ser.GetVString()
+	// Filters of the opened brackets. Filters of the innermost one are the last
+	levels := []*[]dslFilter{&d.Filters}
+	var agg *dslAggregation
+	for !ser.Eof() {
+		filters := levels[len(levels)-1]
+		switch cmd := int(ser.GetVarUInt()); cmd {
+		case queryCondition:
+			f := dslFilter{Field: ser.GetVString()}
+			f.Op = dslOpNames[int(ser.GetVarUInt())]
+			f.Cond = dslCondNames[int(ser.GetVarUInt())]
+			values, err := getQueryValues(&ser, int(ser.GetVarUInt()))
+			if err != nil {
+				return nil, err
+			}
+			if len(values) == 1 {
+				if _, isTuple := values[0].([]interface{}); !isTuple {
+					f.Value = values[0]
+				}
+			}
+			if f.Value == nil && len(values) != 0 {
+				f.Value = values
+			}
+			*filters = append(*filters, f)
+		case queryBetweenFieldsCondition:
+			f := dslFilter{Op: dslOpNames[int(ser.GetVarUInt())], FirstField: ser.GetVString()}
+			f.Cond = dslCondNames[int(ser.GetVarUInt())]
+			f.SecondField = ser.GetVString()
+			*filters = append(*filters, f)
+		case queryOpenBracket:
+			*filters = append(*filters, dslFilter{Op: dslOpNames[int(ser.GetVarUInt())], Filters: []dslFilter{}})
+			levels = append(levels, &(*filters)[len(*filters)-1].Filters)
+		case queryCloseBracket:
+			if len(levels) == 1 {
+				return nil, bindings.NewError("rq: close bracket before open it", ErrCodeParams)
+			}
+			levels = levels[:len(levels)-1]
+		case queryJoinCondition:
+			joinType, joinIdx := int(ser.GetVarUInt()), int(ser.GetVarUInt())
+			jq, err := q.joinToDSL(joinIdx)
+			if err != nil {
+				return nil, err
+			}
+			op := opAND
+			if joinType == orInnerJoin {
+				op = opOR
+			}
+			*filters = append(*filters, dslFilter{Op: dslOpNames[op], JoinQuery: jq})
+		case queryJoinOn:
+			on := dslJoinOn{Op: dslOpNames[int(ser.GetVarUInt())], Cond: dslCondNames[int(ser.GetVarUInt())]}
+			on.LeftField = ser.GetVString()
+			on.RightField = ser.GetVString()
+			d.on = append(d.on, on)
+		case querySortIndex:
+			s := dslSort{Field: ser.GetVString(), Desc: ser.GetVarUInt() != 0}
+			values, err := getQueryValues(&ser, int(ser.GetVarUInt()))
+			if err != nil {
+				return nil, err
+			}
+			s.Values = values
+			d.Sort = append(d.Sort, s)
+		case queryLimit:
+			limit := int(ser.GetVarUInt())
+			d.Limit = &limit
+		case queryOffset:
+			offset := int(ser.GetVarUInt())
+			d.Offset = &offset
+		case queryReqTotal:
+			d.ReqTotal = dslReqTotalModes[int(ser.GetVarUInt())]
+		case queryExplain:
+			d.Explain = true
+		case queryWithRank:
+			d.WithRank = true
+		case queryStrictMode:
+			d.StrictMode = dslStrictModes[int(ser.GetVarUInt())]
+		case queryDebugLevel:
+			ser.GetVarUInt()
+		case querySelectFilter:
+			d.SelectFilter = append(d.SelectFilter, ser.GetVString())
+		case querySelectFunction:
+			d.SelectFunctions = append(d.SelectFunctions, ser.GetVString())
+		case queryEqualPosition:
+			ser.GetVarUInt() // Equal positions are applied to the innermost opened bracket
+			ep := dslEqualPosition{Positions: make([]string, int(ser.GetVarUInt()))}
+			for i := range ep.Positions {
+				ep.Positions[i] = ser.GetVString()
+			}
+			*filters = append(*filters, dslFilter{EqualPositions: []dslEqualPosition{ep}})
+		case queryAggregation:
+			aggType := int(ser.GetVarUInt())
+			d.Aggregations = append(d.Aggregations, dslAggregation{Type: dslAggType(dslAggNames[aggType])})
+			agg = &d.Aggregations[len(d.Aggregations)-1]
+			agg.Fields = make([]string, int(ser.GetVarUInt()))
+			for i := range agg.Fields {
+				agg.Fields[i] = ser.GetVString()
+			}
+		case queryAggregationLimit, queryAggregationOffset, queryAggregationSort:
+			if agg == nil {
+				return nil, bindings.NewError("rq: aggregation parameters before aggregation", ErrCodeParams)
+			}
+			switch cmd {
+			case queryAggregationLimit:
+				limit := int(ser.GetVarUInt())
+				agg.Limit = &limit
+			case queryAggregationOffset:
+				offset := int(ser.GetVarUInt())
+				agg.Offset = &offset
+			default:
+				agg.Sort = append(agg.Sort, dslSort{Field: ser.GetVString(), Desc: ser.GetVarUInt() != 0})
+			}
+		case queryUpdateField, queryUpdateFieldV2:
+			uf := dslUpdateField{Name: ser.GetVString(), Type: "value"}
+			if cmd == queryUpdateFieldV2 {
+				uf.IsArray = ser.GetVarUInt() != 0
+			}
+			uf.Values = make([]interface{}, int(ser.GetVarUInt()))
+			uf.IsArray = uf.IsArray || len(uf.Values) > 1
+			for i := range uf.Values {
+				if ser.GetVarUInt() != 0 {
+					uf.Type = "expression"
+				}
+				v, err := getQueryValue(&ser)
+				if err != nil {
+					return nil, err
+				}
+				uf.Values[i] = v
+			}
+			d.UpdateFields = append(d.UpdateFields, uf)
+			d.Type = "update"
+		case queryUpdateObject:
+			uf := dslUpdateField{Name: ser.GetVString(), Type: "object"}
+			uf.Values = make([]interface{}, int(ser.GetVarUInt()))
+			uf.IsArray = ser.GetVarUInt() != 0
+			for i := range uf.Values {
+				ser.GetVarUInt()
+				v, err := getQueryValue(&ser)
+				if err != nil {
+					return nil, err
+				}
+				s, _ := v.(string)
+				uf.Values[i] = json.RawMessage(s)
+			}
+			d.UpdateFields = append(d.UpdateFields, uf)
+			d.Type = "update"
+		case queryDropField:
+			d.DropFields = append(d.DropFields, ser.GetVString())
+			d.Type = "update"
+		default:
+			return nil, bindings.NewError(fmt.Sprintf("rq: query entry %d can't be represented by DSL", cmd), ErrCodeParams)
+		}
+	}
+	if len(levels) != 1 {
+		return nil, bindings.NewError("rq: query has unclosed brackets", ErrCodeParams)
+	}
+
+	// Left joins don't filter items, so they are not the part of conditions
+	for i, jq := range q.joinQueries {
+		if jq.joinType != leftJoin {
+			continue
+		}
+		j, err := q.joinToDSL(i)
+		if err != nil {
+			return nil, err
+		}
+		d.Filters = append(d.Filters, dslFilter{JoinQuery: j})
+	}
+	for _, mq := range q.mergedQueries {
+		m, err := mq.toDSL()
+		if err != nil {
+			return nil, err
+		}
+		d.MergeQueries = append(d.MergeQueries, *m)
+	}
+	return d, nil
+}
+
+// joinToDSL decodes the joined query with index idx
+func (q *Query) joinToDSL(idx int) (*dslJoinQuery, error) {
+	if idx >= len(q.joinQueries) {
+		return nil, bindings.NewError(fmt.Sprintf("rq: join query %d is not found", idx), ErrCodeParams)
+	}
+	jq := q.joinQueries[idx]
+	if len(jq.joinQueries) != 0 || len(jq.mergedQueries) != 0 {
+		return nil, bindings.NewError("rq: nested joined and merged queries of the joined query can't be represented by DSL", ErrCodeParams)
+	}
+	d, err := jq.toDSL()
+	if err != nil {
+		return nil, err
+	}
+	if len(d.Aggregations) != 0 || d.Type != "select" {
+		return nil, bindings.NewError("rq: aggregations and updates of the joined query can't be represented by DSL", ErrCodeParams)
+	}
+	return &dslJoinQuery{
+		Type:         dslJoinTypes[jq.joinType],
+		Namespace:    d.Namespace,
+		Limit:        d.Limit,
+		Offset:       d.Offset,
+		Filters:      d.Filters,
+		Sort:         d.Sort,
+		On:           append([]dslJoinOn{}, d.on...),
+		SelectFilter: d.SelectFilter,
+	}, nil
+}
+
+// getQueryValues reads count values of the condition from the serialized query
+func getQueryValues(ser *cjson.Serializer, count int) ([]interface{}, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	values := make([]interface{}, count)
+	for i := range values {
+		v, err := getQueryValue(ser)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// getQueryValue reads the value, which was written by Query.putValue
+func getQueryValue(ser *cjson.Serializer) (interface{}, error) {
+	switch t := int(ser.GetVarUInt()); t {
+	case valueInt, valueInt64:
+		return ser.GetVarInt(), nil
+	case valueDouble:
+		return dslDouble(ser.GetDouble()), nil
+	case valueString:
+		return ser.GetVString(), nil
+	case valueBool:
+		return ser.GetVarUInt() != 0, nil
+	case valueUuid:
+		return ser.GetUuid(), nil
+	case valueTuple:
+		return getQueryValues(ser, int(ser.GetVarUInt()))
+	default:
+		return nil, bindings.NewError(fmt.Sprintf("rq: value of type %d can't be represented by DSL", t), ErrCodeParams)
+	}
+}
+
+// QueryFromDSL creates query from JSON in the standard DSL format of reindexer (e.g. produced by Query.DSL or
+// sent to the HTTP API of the server). Joined and merged queries, aggregations and update fields are supported.
+// Joined queries are made with the join handlers, which skip joined items, so they only filter the items of the main query
+func (db *Reindexer) QueryFromDSL(jsonDSL []byte) (*Query, error) {
+	var d dslQuery
+	if err := decodeDSL(jsonDSL, &d); err != nil {
+		return nil, bindings.NewError(fmt.Sprintf("rq: can't parse query DSL: %s", err.Error()), ErrCodeParams)
+	}
+	return db.impl.queryFromDSL(&d)
+}
+
+func (db *reindexerImpl) queryFromDSL(d *dslQuery) (*Query, error) {
+	if d.Namespace == "" {
+		return nil, ErrEmptyNamespace
+	}
+	switch strings.ToLower(d.Type) {
+	case "", "select", "update", "delete":
+	default:
+		return nil, bindings.NewError(fmt.Sprintf("rq: dsl query type '%s' is not supported", d.Type), ErrCodeParams)
+	}
+	q := db.query(d.Namespace)
+	if err := db.buildFromDSL(q, d, make(map[string]int)); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// buildFromDSL adds clauses of the DSL query d to q. joinIDs are used to name joined queries of the query
+func (db *reindexerImpl) buildFromDSL(q *Query, d *dslQuery, joinIDs map[string]int) error {
+	if d.Limit != nil {
+		q.Limit(*d.Limit)
+	}
+	if d.Offset != nil {
+		q.Offset(*d.Offset)
+	}
+	switch strings.ToLower(string(d.ReqTotal)) {
+	case "", "disabled":
+	case "enabled":
+		q.ReqTotal()
+	case "cached":
+		q.CachedTotal()
+	default:
+		return bindings.NewError(fmt.Sprintf("rq: dsl req_total mode '%s' is invalid", d.ReqTotal), ErrCodeParams)
+	}
+	if d.Explain {
+		q.Explain()
+	}
+	if d.WithRank {
+		q.WithRank()
+	}
+	if d.StrictMode != "" {
+		mode, err := dslStrictMode(d.StrictMode)
+		if err != nil {
+			return err
+		}
+		q.Strict(mode)
+	}
+	q.Select(d.SelectFilter...)
+	q.Functions(d.SelectFunctions...)
+	if err := addSortFromDSL(q, d.Sort); err != nil {
+		return err
+	}
+	if err := db.addFiltersFromDSL(q, d.Filters, joinIDs); err != nil {
+		return err
+	}
+	if err := addEqualPositionsFromDSL(q, d.EqualPositions); err != nil {
+		return err
+	}
+	for i := range d.MergeQueries {
+		m := &d.MergeQueries[i]
+		if m.Namespace == "" {
+			return ErrEmptyNamespace
+		}
+		// Joins of the merged query are attached to the root one, if it's already merged
+		mq := db.query(m.Namespace)
+		if err := db.buildFromDSL(mq, m, make(map[string]int)); err != nil {
+			return err
+		}
+		q.Merge(mq)
+	}
+	for _, agg := range d.Aggregations {
+		if err := addAggregationFromDSL(q, &agg); err != nil {
+			return err
+		}
+	}
+	for _, field := range d.DropFields {
+		q.Drop(field)
+	}
+	for _, uf := range d.UpdateFields {
+		if err := addUpdateFieldFromDSL(q, &uf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dslStrictMode(name string) (QueryStrictMode, error) {
+	for mode, modeName := range dslStrictModes {
+		if strings.EqualFold(name, modeName) {
+			return QueryStrictMode(mode), nil
+		}
+	}
+	return queryStrictModeNotSet, bindings.NewError(fmt.Sprintf("rq: dsl strict_mode '%s' is invalid", name), ErrCodeParams)
+}
+
+func addSortFromDSL(q *Query, sorts dslSorts) error {
+	for i, s := range sorts {
+		values, err := dslValues(s.Values)
+		if err != nil {
+			return err
+		}
+		if len(values) != 0 && i != 0 {
+			return bindings.NewError("rq: forced sort order is allowed for the first sorting entry only", ErrCodeParams)
+		}
+		if s.Field != "" {
+			q.Sort(s.Field, s.Desc, values...)
+		}
+	}
+	return nil
+}
+
+// setOpFromDSL sets operation of the next condition of the query
+func setOpFromDSL(q *Query, op string) error {
+	switch strings.ToLower(op) {
+	case "", "and":
+		q.And()
+	case "or":
+		q.Or()
+	case "not":
+		q.Not()
+	default:
+		return bindings.NewError("rq: dsl filter op is invalid", ErrCodeParams)
+	}
+	return nil
+}
+
+func (db *reindexerImpl) addFiltersFromDSL(q *Query, filters []dslFilter, joinIDs map[string]int) error {
+	for i := range filters {
+		f := &filters[i]
+		switch {
+		case f.JoinQuery != nil:
+			if joinIDs == nil {
+				return bindings.NewError("rq: nested join quieries are not supported", ErrCodeParams)
+			}
+			if err := db.addJoinFromDSL(q, f.JoinQuery, dslJoinedField(joinIDs, f.JoinQuery.Namespace)); err != nil {
+				return err
+			}
+		case len(f.EqualPositions) != 0:
+			if err := addEqualPositionsFromDSL(q, f.EqualPositions); err != nil {
+				return err
+			}
+		case f.Filters != nil:
+			if err := setOpFromDSL(q, f.Op); err != nil {
+				return err
+			}
+			q.OpenBracket()
+			if err := db.addFiltersFromDSL(q, f.Filters, joinIDs); err != nil {
+				return err
+			}
+			q.CloseBracket()
+		default:
+			cond := EQ
+			if f.Cond != "" {
+				var err error
+				if cond, err = GetCondType(f.Cond); err != nil {
+					return err
+				}
+			}
+			if f.FirstField != "" || f.SecondField != "" {
+				if f.FirstField == "" || f.SecondField == "" {
+					return ErrEmptyFieldName
+				}
+				if err := setOpFromDSL(q, f.Op); err != nil {
+					return err
+				}
+				q.WhereBetweenFields(f.FirstField, cond, f.SecondField)
+				continue
+			}
+			if f.Field == "" {
+				return ErrEmptyFieldName
+			}
+			values, err := dslFilterValues(f.Value, cond)
+			if err != nil {
+				return err
+			}
+			if err := setOpFromDSL(q, f.Op); err != nil {
+				return err
+			}
+			q.Where(f.Field, cond, values)
+		}
+	}
+	return nil
+}
+
+func (db *reindexerImpl) addJoinFromDSL(q *Query, j *dslJoinQuery, field string) error {
+	if j.Namespace == "" {
+		return ErrEmptyNamespace
+	}
+	jq := db.query(j.Namespace)
+	q.And()
+	switch strings.ToLower(j.Type) {
+	case "left":
+		q.LeftJoin(jq, field)
+	case "inner":
+		q.InnerJoin(jq, field)
+	case "orinner":
+		q.Or().InnerJoin(jq, field)
+	default:
+		return bindings.NewError("rq: join type is invalid", ErrCodeParams)
+	}
+	q.JoinHandler(field, func(field string, item interface{}, subitems []interface{}) bool {
+		return false // Do not handle joined data
+	})
+
+	if j.Limit != nil {
+		jq.Limit(*j.Limit)
+	}
+	if j.Offset != nil {
+		jq.Offset(*j.Offset)
+	}
+	jq.Select(j.SelectFilter...)
+	if err := addSortFromDSL(jq, j.Sort); err != nil {
+		return err
+	}
+	if err := db.addFiltersFromDSL(jq, j.Filters, nil); err != nil {
+		return err
+	}
+	for _, on := range j.On {
+		cond, err := GetCondType(on.Cond)
+		if err != nil {
+			return err
+		}
+		if on.LeftField == "" {
+			return bindings.NewError("rq: dsl join on empty field (left)", ErrCodeParams)
+		}
+		if on.RightField == "" {
+			return bindings.NewError("rq: dsl join on empty field (right)", ErrCodeParams)
+		}
+		if err := setOpFromDSL(jq, on.Op); err != nil {
+			return bindings.NewError("rq: dsl join_query op is invalid", ErrCodeParams)
+		}
+		jq.On(on.LeftField, cond, on.RightField)
+	}
+	return nil
+}
+
+func addEqualPositionsFromDSL(q *Query, equalPositions []dslEqualPosition) error {
+	for _, ep := range equalPositions {
+		if len(ep.Positions) < 2 {
+			return bindings.NewError("rq: equal_positions are supposed to have at least 2 fields", ErrCodeParams)
+		}
+		q.EqualPosition(ep.Positions...)
+	}
+	return nil
+}
+
+func addAggregationFromDSL(q *Query, agg *dslAggregation) error {
+	if len(agg.Fields) == 0 {
+		return ErrEmptyAggFieldName
+	}
+	aggType := -1
+	for t, name := range dslAggNames {
+		if strings.EqualFold(string(agg.Type), name) {
+			aggType = t
+		}
+	}
+	if aggType != AggFacet && (len(agg.Sort) != 0 || agg.Limit != nil || agg.Offset != nil) {
+		return bindings.NewError(fmt.Sprintf("rq: dsl aggregation '%s' doesn't support sort, limit and offset", agg.Type), ErrCodeParams)
+	}
+	switch aggType {
+	case AggSum:
+		q.AggregateSum(agg.Fields[0])
+	case AggAvg:
+		q.AggregateAvg(agg.Fields[0])
+	case AggMin:
+		q.AggregateMin(agg.Fields[0])
+	case AggMax:
+		q.AggregateMax(agg.Fields[0])
+	case AggDistinct:
+		q.Distinct(agg.Fields[0])
+	case AggFacet:
+		aggReq := q.AggregateFacet(agg.Fields...)
+		if agg.Limit != nil {
+			aggReq.Limit(*agg.Limit)
+		}
+		if agg.Offset != nil {
+			aggReq.Offset(*agg.Offset)
+		}
+		for _, sort := range agg.Sort {
+			aggReq.Sort(sort.Field, sort.Desc)
+		}
+	default:
+		return ErrAggInvalid
+	}
+	return nil
+}
+
+func addUpdateFieldFromDSL(q *Query, uf *dslUpdateField) error {
+	if uf.Name == "" {
+		return ErrEmptyFieldName
+	}
+	switch strings.ToLower(uf.Type) {
+	case "", "value":
+		values, err := dslValues(uf.Values)
+		if err != nil {
+			return err
+		}
+		if !uf.IsArray && len(values) == 1 {
+			q.Set(uf.Name, values[0])
+		} else if values == nil {
+			q.Set(uf.Name, []interface{}{})
+		} else {
+			q.Set(uf.Name, values)
+		}
+	case "expression":
+		if len(uf.Values) != 1 {
+			return bindings.NewError("rq: the array 'values' must contain only a string value for the type 'expression'", ErrCodeParams)
+		}
+		expr, ok := uf.Values[0].(string)
+		if !ok {
+			return bindings.NewError("rq: the array 'values' must contain only a string value for the type 'expression'", ErrCodeParams)
+		}
+		q.SetExpression(uf.Name, expr)
+	case "object":
+		objects := make([][]byte, len(uf.Values))
+		for i, v := range uf.Values {
+			obj, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			objects[i] = obj
+		}
+		if !uf.IsArray && len(objects) == 1 {
+			q.SetObject(uf.Name, objects[0])
+		} else {
+			q.SetObject(uf.Name, objects)
+		}
+	default:
+		return bindings.NewError(fmt.Sprintf("rq: dsl update field type '%s' is invalid", uf.Type), ErrCodeParams)
+	}
+	return nil
+}
+
+// dslJoinedField returns unique name of the joined query, which is made from DSL (join queries of DSL don't have names)
+func dslJoinedField(joinIDs map[string]int, namespace string) string {
+	if v, found := joinIDs[namespace]; found {
+		joinIDs[namespace]++
+		return fmt.Sprintf("_dsl_joined_%s_%d", namespace, v)
+	}
+	joinIDs[namespace] = 0
+	return fmt.Sprintf("_dsl_joined_%s", namespace)
+}
+
+// dslFilterValues converts value of the DSL filter (scalar, array or null) to the values of the condition.
+// Point of DWithin condition may be passed as the nested array
+func dslFilterValues(value interface{}, cond int) ([]interface{}, error) {
+	raw, isArray := value.([]interface{})
+	if !isArray {
+		if value == nil {
+			return nil, nil
+		}
+		raw = []interface{}{value}
+	}
+	values, err := dslValues(raw)
+	if err != nil || cond != DWITHIN {
+		return values, err
+	}
+	var coords []interface{}
+	for _, v := range values {
+		if tuple, ok := v.([]interface{}); ok {
+			coords = append(coords, tuple...)
+		} else {
+			coords = append(coords, v)
+		}
+	}
+	for i, v := range coords {
+		switch n := v.(type) {
+		case int64:
+			coords[i] = float64(n)
+		case float64:
+		default:
+			return nil, bindings.NewError("rq: dwithin condition expects point and distance", ErrCodeParams)
+		}
+	}
+	if len(coords) != 3 {
+		return nil, bindings.NewError("rq: dwithin condition expects point and distance", ErrCodeParams)
+	}
+	return coords, nil
+}
+
+// dslValues converts values of DSL to the values of the query: integer numbers to int64, numbers with the fraction part
+// to float64, arrays to tuples and objects to JSON strings
+func dslValues(raw []interface{}) ([]interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	values := make([]interface{}, len(raw))
+	for i, v := range raw {
+		switch tv := v.(type) {
+		case json.Number:
+			if n, err := tv.Int64(); err == nil && !strings.ContainsAny(tv.String(), ".eE") {
+				values[i] = n
+			} else if f, err := tv.Float64(); err == nil {
+				values[i] = f
+			} else {
+				return nil, err
+			}
+		case string, bool:
+			values[i] = tv
+		case []interface{}:
+			tuple, err := dslValues(tv)
+			if err != nil {
+				return nil, err
+			}
+			if tuple == nil {
+				tuple = []interface{}{}
+			}
+			values[i] = tuple
+		case map[string]interface{}:
+			obj, err := json.Marshal(tv)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = string(obj)
+		case nil:
+			return nil, bindings.NewError("rq: dsl values can't contain null", ErrCodeParams)
+		default:
+			return nil, bindings.NewError(fmt.Sprintf("rq: unexpected dsl value of type %T", v), ErrCodeParams)
+		}
+	}
+	return values, nil
+}
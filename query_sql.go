@@ -0,0 +1,409 @@
+package reindexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+var sqlCondNames = map[string]string{
+	"any": "IS NOT NULL", "eq": "=", "lt": "<", "le": "<=", "gt": ">", "ge": ">=", "range": "RANGE",
+	"set": "IN", "allset": "ALLSET", "empty": "IS NULL", "like": "LIKE", "dwithin": "DWITHIN",
+}
+
+var sqlJoinTypes = map[string]string{"inner": "INNER JOIN", "orinner": "OR INNER JOIN", "left": "LEFT JOIN"}
+
+// SQL returns the query as SQL statement of reindexer. It's intended for logging and debugging, e.g. to report slow queries:
+// the statement describes conditions, joins, merges, sorting and aggregations of the query, but some clauses of the query builder
+// have no SQL equivalent (strict mode, select functions) and are omitted. Query with subqueries (WhereQuery) can't be represented by SQL
+func (q *Query) SQL() (string, error) {
+	if q.root != nil {
+		q = q.root
+	}
+	d, err := q.toDSL()
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if d.Explain {
+		sb.WriteString("EXPLAIN ")
+	}
+	if err := writeSQL(&sb, d); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writeSQL writes SQL statement of the query. The statement is built in the same way, as it's done by SQL encoder of the server
+func writeSQL(sb *strings.Builder, d *dslQuery) error {
+	if d.Type == "update" {
+		if err := writeSQLUpdate(sb, d); err != nil {
+			return err
+		}
+	} else {
+		writeSQLSelect(sb, d)
+	}
+
+	if len(d.Filters) != 0 {
+		var where strings.Builder
+		if err := writeSQLFilters(&where, d.Namespace, d.Filters); err != nil {
+			return err
+		}
+		if where.Len() != 0 {
+			sb.WriteString(" WHERE ")
+			sb.WriteString(where.String())
+		}
+	}
+	// Left joins are not the part of the conditions
+	for _, f := range d.Filters {
+		if f.JoinQuery != nil && f.JoinQuery.Type == "left" {
+			sb.WriteByte(' ')
+			if err := writeSQLJoin(sb, d.Namespace, f.JoinQuery); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range d.MergeQueries {
+		sb.WriteString(" MERGE (")
+		if err := writeSQL(sb, &d.MergeQueries[i]); err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+	}
+	writeSQLOrderBy(sb, d.Sort)
+	if d.Offset != nil {
+		fmt.Fprintf(sb, " OFFSET %d", *d.Offset)
+	}
+	if d.Limit != nil {
+		fmt.Fprintf(sb, " LIMIT %d", *d.Limit)
+	}
+	return nil
+}
+
+func writeSQLSelect(sb *strings.Builder, d *dslQuery) {
+	var fields []string
+	if d.WithRank {
+		fields = append(fields, "RANK()")
+	}
+	for _, agg := range d.Aggregations {
+		var a strings.Builder
+		a.WriteString(string(agg.Type))
+		a.WriteByte('(')
+		a.WriteString(strings.Join(agg.Fields, ", "))
+		for _, s := range agg.Sort {
+			a.WriteString(" ORDER BY ")
+			a.WriteString(sqlString(s.Field))
+			if s.Desc {
+				a.WriteString(" DESC")
+			} else {
+				a.WriteString(" ASC")
+			}
+		}
+		if agg.Offset != nil {
+			fmt.Fprintf(&a, " OFFSET %d", *agg.Offset)
+		}
+		if agg.Limit != nil {
+			fmt.Fprintf(&a, " LIMIT %d", *agg.Limit)
+		}
+		a.WriteByte(')')
+		fields = append(fields, a.String())
+	}
+	if len(d.Aggregations) == 0 || (len(d.Aggregations) == 1 && d.Aggregations[0].Type == "distinct") {
+		distinct := ""
+		if len(d.Aggregations) != 0 {
+			distinct = d.Aggregations[0].Fields[0]
+		}
+		if len(d.SelectFilter) == 0 {
+			// Query with zero limit and total count requests the count only
+			if d.Limit == nil || *d.Limit != 0 || d.ReqTotal == "disabled" {
+				fields = append(fields, "*")
+			}
+		}
+		for _, field := range d.SelectFilter {
+			if field != distinct {
+				fields = append(fields, field)
+			}
+		}
+	}
+	switch d.ReqTotal {
+	case "enabled":
+		fields = append(fields, "COUNT(*)")
+	case "cached":
+		fields = append(fields, "COUNT_CACHED(*)")
+	}
+	sb.WriteString("SELECT ")
+	sb.WriteString(strings.Join(fields, ", "))
+	sb.WriteString(" FROM ")
+	sb.WriteString(d.Namespace)
+}
+
+func writeSQLUpdate(sb *strings.Builder, d *dslQuery) error {
+	sb.WriteString("UPDATE ")
+	sb.WriteString(d.Namespace)
+	if len(d.UpdateFields) == 0 {
+		sb.WriteString(" DROP ")
+		sb.WriteString(strings.Join(d.DropFields, ","))
+		return nil
+	}
+	if len(d.DropFields) != 0 {
+		return bindings.NewError("rq: query with both updated and dropped fields can't be represented by SQL", ErrCodeParams)
+	}
+	sb.WriteString(" SET ")
+	for i, uf := range d.UpdateFields {
+		if i != 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(sqlField(uf.Name))
+		sb.WriteString(" = ")
+		isArray := uf.IsArray || len(uf.Values) > 1
+		if isArray {
+			sb.WriteByte('[')
+		}
+		for j, v := range uf.Values {
+			if j != 0 {
+				sb.WriteByte(',')
+			}
+			switch tv := v.(type) {
+			case json.RawMessage:
+				sb.Write(tv)
+			case string:
+				if uf.Type == "expression" {
+					sb.WriteString(tv)
+				} else {
+					sb.WriteString(sqlString(tv))
+				}
+			default:
+				sb.WriteString(sqlValue(v))
+			}
+		}
+		if isArray {
+			sb.WriteByte(']')
+		}
+	}
+	return nil
+}
+
+// writeSQLFilters writes conditions of the query (or of the bracket) and their equal positions
+func writeSQLFilters(sb *strings.Builder, namespace string, filters []dslFilter) error {
+	written := 0
+	var equalPositions []dslEqualPosition
+	for _, f := range filters {
+		if len(f.EqualPositions) != 0 {
+			equalPositions = append(equalPositions, f.EqualPositions...)
+			continue
+		}
+		if f.JoinQuery != nil && f.JoinQuery.Type == "left" {
+			continue
+		}
+		op := strings.ToUpper(f.Op)
+		if op == "" {
+			op = "AND"
+		}
+		if op == "NOT" {
+			op = "AND NOT"
+		}
+		if written != 0 {
+			sb.WriteByte(' ')
+			// Operation of OR INNER JOIN is the part of its name
+			if f.JoinQuery == nil || f.JoinQuery.Type != "orinner" {
+				sb.WriteString(op)
+				sb.WriteByte(' ')
+			}
+		} else if op == "AND NOT" {
+			sb.WriteString("NOT ")
+		}
+		written++
+
+		switch {
+		case f.JoinQuery != nil:
+			if err := writeSQLJoin(sb, namespace, f.JoinQuery); err != nil {
+				return err
+			}
+		case f.Filters != nil:
+			sb.WriteByte('(')
+			if err := writeSQLFilters(sb, namespace, f.Filters); err != nil {
+				return err
+			}
+			sb.WriteByte(')')
+		case f.FirstField != "":
+			sb.WriteString(sqlField(f.FirstField))
+			sb.WriteByte(' ')
+			sb.WriteString(sqlCondNames[f.Cond])
+			sb.WriteByte(' ')
+			sb.WriteString(sqlField(f.SecondField))
+		default:
+			writeSQLCondition(sb, &f)
+		}
+	}
+	for _, ep := range equalPositions {
+		sb.WriteString(" equal_position(")
+		sb.WriteString(strings.Join(ep.Positions, ", "))
+		sb.WriteByte(')')
+	}
+	return nil
+}
+
+func writeSQLCondition(sb *strings.Builder, f *dslFilter) {
+	values, isArray := f.Value.([]interface{})
+	if !isArray && f.Value != nil {
+		values = []interface{}{f.Value}
+	}
+	if f.Cond == "dwithin" && len(values) == 3 {
+		fmt.Fprintf(sb, "ST_DWithin(%s, ST_GeomFromText('POINT(%s %s)'), %s)", sqlField(f.Field), sqlValue(values[0]), sqlValue(values[1]), sqlValue(values[2]))
+		return
+	}
+	sb.WriteString(sqlField(f.Field))
+	sb.WriteByte(' ')
+	sb.WriteString(sqlCondNames[f.Cond])
+	if f.Cond == "any" || f.Cond == "empty" {
+		return
+	}
+	sb.WriteByte(' ')
+	if len(values) == 1 {
+		sb.WriteString(sqlValue(values[0]))
+		return
+	}
+	sb.WriteByte('(')
+	for i, v := range values {
+		if i != 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(sqlValue(v))
+	}
+	sb.WriteByte(')')
+}
+
+func writeSQLJoin(sb *strings.Builder, namespace string, j *dslJoinQuery) error {
+	sb.WriteString(sqlJoinTypes[j.Type])
+	sb.WriteByte(' ')
+	if len(j.Filters) == 0 && j.Limit == nil && j.Offset == nil && len(j.Sort) == 0 {
+		sb.WriteString(j.Namespace)
+	} else {
+		sb.WriteByte('(')
+		err := writeSQL(sb, &dslQuery{
+			Namespace:    j.Namespace,
+			Limit:        j.Limit,
+			Offset:       j.Offset,
+			ReqTotal:     dslReqTotalModes[modeNoCalc],
+			SelectFilter: j.SelectFilter,
+			Sort:         j.Sort,
+			Filters:      j.Filters,
+		})
+		if err != nil {
+			return err
+		}
+		sb.WriteByte(')')
+	}
+	sb.WriteString(" ON ")
+	if len(j.On) != 1 {
+		sb.WriteByte('(')
+	}
+	for i, on := range j.On {
+		if i != 0 {
+			sb.WriteByte(' ')
+			sb.WriteString(strings.ToUpper(on.Op))
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(sb, "%s.%s %s %s.%s", namespace, on.LeftField, sqlCondNames[on.Cond], j.Namespace, on.RightField)
+	}
+	if len(j.On) != 1 {
+		sb.WriteByte(')')
+	}
+	return nil
+}
+
+func writeSQLOrderBy(sb *strings.Builder, sorts dslSorts) {
+	if len(sorts) == 0 {
+		return
+	}
+	sb.WriteString(" ORDER BY ")
+	for i, s := range sorts {
+		if i != 0 {
+			sb.WriteString(", ")
+		}
+		if len(s.Values) != 0 {
+			sb.WriteString("FIELD(")
+			sb.WriteString(sqlField(s.Field))
+			for _, v := range s.Values {
+				sb.WriteString(", ")
+				sb.WriteString(sqlValue(v))
+			}
+			sb.WriteByte(')')
+		} else if isExpression(s.Field) {
+			sb.WriteString(sqlString(s.Field))
+		} else {
+			sb.WriteString(sqlField(s.Field))
+		}
+		if s.Desc {
+			sb.WriteString(" DESC")
+		}
+	}
+}
+
+// sqlField returns name of the field or index, which is enclosed in double quotes, if it's composite or doesn't start with letter, '_' or '#'
+func sqlField(name string) string {
+	if len(name) != 0 && !strings.Contains(name, "+") {
+		if r := []rune(name)[0]; unicode.IsLetter(r) || r == '_' || r == '#' {
+			return name
+		}
+	}
+	return `"` + name + `"`
+}
+
+// sqlString returns string literal of SQL with escaped special characters
+func sqlString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'':
+			sb.WriteString(`\'`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\f':
+			sb.WriteString(`\f`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
+// sqlValue returns SQL literal of the value of the query
+func sqlValue(v interface{}) string {
+	switch tv := v.(type) {
+	case string:
+		return sqlString(tv)
+	case bool:
+		return strconv.FormatBool(tv)
+	case int64:
+		return strconv.FormatInt(tv, 10)
+	case dslDouble:
+		s, err := tv.MarshalJSON()
+		if err != nil {
+			return strconv.FormatFloat(float64(tv), 'g', -1, 64)
+		}
+		return string(s)
+	case []interface{}:
+		values := make([]string, len(tv))
+		for i, tuple := range tv {
+			values[i] = sqlValue(tuple)
+		}
+		return "(" + strings.Join(values, ",") + ")"
+	}
+	return fmt.Sprint(v)
+}
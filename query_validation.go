@@ -0,0 +1,376 @@
+package reindexer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+// QueryValidationError is returned by the query, if WithQueryValidation option is enabled and the query refers to the fields,
+// which don't exist in the namespace, or compares them with values of mismatched types. It contains all the found mistakes
+type QueryValidationError struct {
+	// Name of the namespace of the query
+	Namespace string
+	Errors    []error
+}
+
+func (e *QueryValidationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "rq: Invalid query to namespace '%s':", e.Namespace)
+	for _, err := range e.Errors {
+		sb.WriteString("\n - ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Code returns ErrCodeParams
+func (e *QueryValidationError) Code() int {
+	return ErrCodeParams
+}
+
+func (e *QueryValidationError) Is(target error) bool {
+	return isCodeError(target, e.Code())
+}
+
+// fieldKind is kind of the values of the field, which are compatible for the query
+type fieldKind int
+
+const (
+	// Values of any type are allowed (composite indexes, nested objects, maps, tuples, etc)
+	fieldAny fieldKind = iota
+	fieldBool
+	fieldNumber
+	fieldString
+)
+
+func (k fieldKind) String() string {
+	switch k {
+	case fieldBool:
+		return "bool"
+	case fieldNumber:
+		return "numeric"
+	case fieldString:
+		return "string"
+	}
+	return "any"
+}
+
+// queryFields describes fields of the namespace, which may be referenced by the queries
+type queryFields struct {
+	// Kinds of the indexes by lowercased names (index names are case insensitive)
+	indexes map[string]fieldKind
+	// Kinds of the fields by JSON paths
+	paths map[string]fieldKind
+	// JSON paths of maps and interfaces, which may contain any nested fields
+	dynamic []string
+	// Names of the indexes and fields for hints
+	names []string
+}
+
+// newQueryFields collects fields of the namespace from its struct and indexes
+func newQueryFields(st reflect.Type, indexes []bindings.IndexDef) *queryFields {
+	f := &queryFields{indexes: make(map[string]fieldKind), paths: make(map[string]fieldKind)}
+	f.walk(st, "", make(map[reflect.Type]bool))
+	for _, indexDef := range indexes {
+		f.indexes[strings.ToLower(indexDef.Name)] = indexFieldKind(indexDef.FieldType)
+		f.names = append(f.names, indexDef.Name)
+	}
+	sort.Strings(f.names)
+	return f
+}
+
+// indexFieldKind returns kind of the values of the index with field type fieldType
+func indexFieldKind(fieldType string) fieldKind {
+	switch fieldType {
+	case "bool":
+		return fieldBool
+	case "int", "int64", "double", "point":
+		return fieldNumber
+	case "string", "uuid":
+		return fieldString
+	}
+	return fieldAny
+}
+
+// typeFieldKind returns kind of the values of go type t. Types with custom marshalers are described by types of their marshaled values
+func typeFieldKind(t reflect.Type) fieldKind {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if mt, marshaled, err := cjson.MarshaledType(t); marshaled {
+		if err != nil || mt == nil {
+			return fieldAny
+		}
+		return typeFieldKind(mt)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return fieldBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fieldNumber
+	case reflect.String:
+		return fieldString
+	}
+	return fieldAny
+}
+
+// walk collects JSON paths of the fields of the struct in the same way, as they are encoded to CJSON
+func (f *queryFields) walk(st reflect.Type, jsonBasePath string, visited map[reflect.Type]bool) {
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if visited[st] {
+		return
+	}
+	visited[st] = true
+	defer delete(visited, st)
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		jsonName := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		_, _, _, idxSettings := parseRxTags(sf)
+		if parseByKeyWord(&idxSettings, "joined") || parseByKeyWord(&idxSettings, "composite") {
+			continue
+		}
+
+		t := sf.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if flatten, prefix := cjson.FieldEmbedding(sf); flatten && t.Kind() == reflect.Struct {
+			// Fields of flattened structs are encoded as fields of the parent struct
+			f.walk(t, jsonBasePath+prefix, visited)
+			continue
+		}
+		_, marshaled, _ := cjson.MarshaledType(t)
+		if sf.PkgPath != "" {
+			continue
+		}
+		if len(jsonName) == 0 {
+			jsonName = sf.Name
+		}
+		jsonPath := jsonBasePath + jsonName
+		f.names = append(f.names, jsonPath)
+
+		elem := t
+		if !marshaled && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8 {
+			// Conditions on arrays are applied to their elements
+			elem = t.Elem()
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+		}
+		if _, elemMarshaled, _ := cjson.MarshaledType(elem); !elemMarshaled {
+			switch elem.Kind() {
+			case reflect.Struct:
+				f.paths[jsonPath] = fieldAny
+				f.walk(elem, nestedBasePath(jsonPath), visited)
+				continue
+			case reflect.Map, reflect.Interface:
+				f.paths[jsonPath] = fieldAny
+				f.dynamic = append(f.dynamic, jsonPath+".")
+				continue
+			case reflect.Slice, reflect.Array:
+				f.paths[jsonPath] = fieldAny
+				continue
+			}
+		}
+		f.paths[jsonPath] = typeFieldKind(elem)
+	}
+}
+
+// lookup returns kind of the field, referenced by the query. Indexes of the array elements (e.g. 'items[0].price') are ignored
+func (f *queryFields) lookup(field string) (fieldKind, bool) {
+	name := stripArrayIndexes(field)
+	if k, ok := f.indexes[strings.ToLower(name)]; ok {
+		return k, true
+	}
+	if k, ok := f.paths[name]; ok {
+		return k, true
+	}
+	for _, prefix := range f.dynamic {
+		if strings.HasPrefix(name, prefix) {
+			return fieldAny, true
+		}
+	}
+	// Composite index, which is not declared by the struct, may be referenced by the names of its parts
+	if strings.Contains(name, "+") {
+		for _, part := range strings.Split(name, "+") {
+			if _, ok := f.lookup(part); !ok {
+				return fieldAny, false
+			}
+		}
+		return fieldAny, true
+	}
+	return fieldAny, false
+}
+
+// hint returns the closest name of the field or index, which may be misspelled as field. Short names are not hinted,
+// because most of the names are close to them
+func (f *queryFields) hint(field string) string {
+	best, bestDist := "", 3
+	for _, name := range f.names {
+		if d := editDistance(strings.ToLower(field), strings.ToLower(name)); d < bestDist && d <= len(field)/2 {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// stripArrayIndexes removes indexes of the array elements from the path of the field
+func stripArrayIndexes(field string) string {
+	if !strings.Contains(field, "[") {
+		return field
+	}
+	var sb strings.Builder
+	depth := 0
+	for _, r := range field {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// isExpression returns true, if field is not a plain name of the field or index (e.g. sort expression 'rank()' or 'price * 2')
+func isExpression(field string) bool {
+	return strings.HasPrefix(field, "#") || strings.ContainsAny(stripArrayIndexes(field), "()*/ -'\"")
+}
+
+// editDistance returns Levenshtein distance between the strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// getQueryFields returns fields of the namespace for validation of the queries. They are collected on the first use
+func (ns *reindexerNamespace) getQueryFields() *queryFields {
+	ns.queryFieldsOnce.Do(func() {
+		ns.queryFields = newQueryFields(ns.rtype, ns.indexes)
+	})
+	return ns.queryFields
+}
+
+// validating returns true, if fields of the query are validated (see WithQueryValidation)
+func (q *Query) validating() bool {
+	return q.db != nil && q.db.queryValidation
+}
+
+// validateField checks, that the field, referenced by clause of the query, exists in the namespace and may be compared with values.
+// values is a single value or a slice of values, if isList is true. Types of the values are not checked, if values is nil
+func (q *Query) validateField(clause, field string, values interface{}, isList bool) {
+	if isExpression(field) {
+		return
+	}
+	ns, err := q.db.getNS(q.Namespace)
+	if err != nil {
+		// Unknown namespace is reported by the server
+		return
+	}
+	fields := ns.getQueryFields()
+	kind, ok := fields.lookup(field)
+	if !ok {
+		err := fmt.Errorf("%s: field '%s' is not found in namespace '%s'", clause, field, q.Namespace)
+		if hint := fields.hint(field); len(hint) != 0 {
+			err = fmt.Errorf("%s. Did you mean '%s'?", err.Error(), hint)
+		}
+		q.validationErrs = append(q.validationErrs, err)
+		return
+	}
+	if kind == fieldAny || values == nil {
+		return
+	}
+
+	v := reflect.ValueOf(values)
+	if !isList || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		q.validateValue(clause, field, kind, v)
+		return
+	}
+	if elem := v.Type().Elem(); elem.Kind() != reflect.Interface {
+		// All the values have the same type, so the first one is enough
+		if v.Len() > 0 {
+			q.validateValue(clause, field, kind, v.Index(0))
+		}
+		return
+	}
+	for i := 0; i < v.Len(); i++ {
+		if !q.validateValue(clause, field, kind, v.Index(i)) {
+			return
+		}
+	}
+}
+
+// validateValue checks, that value v may be compared with the field of kind. Returns false and records error on mismatch
+func (q *Query) validateValue(clause, field string, kind fieldKind, v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return true
+	}
+	vkind := typeFieldKind(v.Type())
+	if vkind == fieldAny || vkind == kind {
+		return true
+	}
+	q.validationErrs = append(q.validationErrs, fmt.Errorf("%s: field '%s' of namespace '%s' has %s type, but value of type %s is passed",
+		clause, field, q.Namespace, kind, v.Type().String()))
+	return false
+}
+
+// validationError returns errors, which were found by validation of the fields of the query (and of its joined and merged queries)
+func (q *Query) validationError() error {
+	var errs []error
+	errs = append(errs, q.validationErrs...)
+	for _, jq := range q.joinQueries {
+		errs = append(errs, jq.validationErrs...)
+	}
+	for _, mq := range q.mergedQueries {
+		errs = append(errs, mq.validationErrs...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &QueryValidationError{Namespace: q.Namespace, Errors: errs}
+}
@@ -0,0 +1,100 @@
+package reindexer
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+var errRateLimitDeadline = bindings.NewError("rq: Rate limit wait exceeds context deadline", ErrCodeTimeout)
+
+// rateLimiter is a token bucket, which allows opsPerSecond operations on average with bursts up to burst operations
+type rateLimiter struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(opsPerSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   opsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes one token and returns duration to wait before the operation.
+// Returns false, if the operation can't be started before the deadline
+func (l *rateLimiter) reserve(now time.Time, deadline time.Time, hasDeadline bool) (time.Duration, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	var wait time.Duration
+	if l.tokens < 1 {
+		wait = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+	if hasDeadline && now.Add(wait).After(deadline) {
+		return 0, false
+	}
+	l.tokens--
+	return wait, true
+}
+
+// cancel returns token, taken by reserve
+func (l *rateLimiter) cancel() {
+	l.lock.Lock()
+	l.tokens++
+	l.lock.Unlock()
+}
+
+// wait blocks until the operation is allowed by the limiter, or ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	wait, ok := l.reserve(time.Now(), deadline, hasDeadline)
+	if !ok {
+		return errRateLimitDeadline
+	}
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// waitRateLimit waits for global and namespace's rate limiters, set by WithRateLimit and WithNamespaceRateLimit options
+func (db *reindexerImpl) waitRateLimit(ctx context.Context, namespace string) error {
+	if db.rateLimiter != nil {
+		if err := db.rateLimiter.wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l, ok := db.nsRateLimiters[strings.ToLower(namespace)]; ok {
+		return l.wait(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package reindexer
+
+import (
+	"context"
+	"strings"
+)
+
+// ReadSnapshot - LSNs of the namespaces, pinned by BeginReadSnapshot. Server doesn't keep old versions of the items,
+// so consistency of the queries is checked optimistically: Validate fails, if some of the namespaces were modified after the snapshot was taken
+type ReadSnapshot struct {
+	db   *reindexerImpl
+	ctx  context.Context
+	lsns map[string]LsnT
+}
+
+func (db *reindexerImpl) beginReadSnapshot(ctx context.Context, namespaces []string) (*ReadSnapshot, error) {
+	if len(namespaces) == 0 {
+		return nil, ErrEmptyNamespace
+	}
+	names := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		names = append(names, strings.ToLower(namespace))
+	}
+	lsns, err := db.namespacesLSN(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadSnapshot{db: db, ctx: ctx, lsns: lsns}, nil
+}
+
+// LSN returns pinned LSN of the namespace. Returns false, if the namespace is not pinned by the snapshot
+func (s *ReadSnapshot) LSN(namespace string) (LsnT, bool) {
+	lsn, ok := s.lsns[strings.ToLower(namespace)]
+	return lsn, ok
+}
+
+// Query creates new query of the namespace. The namespace and namespaces of the joined queries must be pinned by the snapshot
+func (s *ReadSnapshot) Query(namespace string) *Query {
+	return s.db.query(namespace)
+}
+
+// Validate checks, that the pinned namespaces were not modified since the snapshot was taken.
+// Returns ErrSnapshotChanged, if results of the snapshot's queries may be inconsistent
+func (s *ReadSnapshot) Validate() error {
+	names := make([]string, 0, len(s.lsns))
+	for namespace := range s.lsns {
+		names = append(names, namespace)
+	}
+	lsns, err := s.db.namespacesLSN(s.ctx, names)
+	if err != nil {
+		return err
+	}
+	for namespace, lsn := range s.lsns {
+		if lsns[namespace] != lsn {
+			return ErrSnapshotChanged
+		}
+	}
+	return nil
+}
+
+func (db *reindexerImpl) readConsistent(ctx context.Context, namespaces []string, attempts int, fn func(s *ReadSnapshot) error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		s, err := db.beginReadSnapshot(ctx, namespaces)
+		if err != nil {
+			return err
+		}
+		if err = fn(s); err != nil {
+			return err
+		}
+		if err = s.Validate(); err != ErrSnapshotChanged || attempt == attempts {
+			return err
+		}
+	}
+}
+
+// namespacesLSN returns LSNs of the last modifications of the namespaces
+func (db *reindexerImpl) namespacesLSN(ctx context.Context, namespaces []string) (map[string]LsnT, error) {
+	it := db.query(MemstatsNamespaceName).WhereString("name", SET, namespaces...).ExecCtx(ctx)
+	defer it.Close()
+	lsns := make(map[string]LsnT, len(namespaces))
+	for it.Next() {
+		stat := it.Object().(*NamespaceMemStat)
+		lsns[stat.Name] = stat.Replication.LastLSN
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	for _, namespace := range namespaces {
+		if _, ok := lsns[namespace]; !ok {
+			return nil, errNsNotFound
+		}
+	}
+	return lsns, nil
+}
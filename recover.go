@@ -0,0 +1,54 @@
+package reindexer
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// RecoverHandler converts panic r, recovered inside the client during operation op (e.g. "Upsert", "Query.Exec", "Iterator.Next"), into error.
+// Stack of the panic is available via debug.Stack() inside the handler. If the handler returns nil, PanicError is returned
+type RecoverHandler func(op string, r interface{}) error
+
+// PanicError is returned instead of the internal panic of the client, if the panic is recovered by WithRecoverHandler option
+type PanicError struct {
+	// Operation, which was interrupted by the panic
+	Op string
+	// Recovered value
+	Value interface{}
+	// Stack of the panic
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("rq: panic in %s: %v", e.Op, e.Value)
+}
+
+// panicToError converts recovered panic into error with the recover handler
+func (db *reindexerImpl) panicToError(op string, r interface{}) error {
+	stack := debug.Stack()
+	if err := db.recoverHandler(op, r); err != nil {
+		return err
+	}
+	return &PanicError{Op: op, Value: r, Stack: stack}
+}
+
+// recoverPanic recovers panic of op into *err. Panics are not recovered without recover handler.
+// Must be called by defer directly
+func (db *reindexerImpl) recoverPanic(op string, err *error) {
+	if db == nil || db.recoverHandler == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		*err = db.panicToError(op, r)
+	}
+}
+
+// recoverPanicIterator recovers panic of op into the iterator with error. Must be called by defer directly
+func (db *reindexerImpl) recoverPanicIterator(op string, it **Iterator) {
+	if db == nil || db.recoverHandler == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		*it = errIterator(db.panicToError(op, r))
+	}
+}
@@ -35,6 +35,7 @@ type indexOptions struct {
 	isSparse    bool
 	rtreeType   string
 	isUuid      bool
+	isVersion   bool
 }
 
 func parseRxTags(field reflect.StructField) (idxName string, idxType string, expireAfter string, idxSettings []string) {
@@ -56,8 +57,38 @@ func parseRxTags(field reflect.StructField) (idxName string, idxType string, exp
 	return
 }
 
+// parseStruct validates struct of the namespace and parses indexes and special fields from its tags
+func (ns *reindexerNamespace) parseStruct(s interface{}) (err error) {
+	validator := cjson.Validator{}
+
+	if err = validator.Validate(s); err != nil {
+		return err
+	}
+	if ns.opts.strictTags {
+		if err = validateTagsStrict(ns.rtype); err != nil {
+			return err
+		}
+	}
+	if ns.indexes, err = parseIndexes(ns.name, ns.rtype, &ns.joined); err != nil {
+		return err
+	}
+	if err = parseAutotimeFields(ns.rtype, &ns.autotime); err != nil {
+		return err
+	}
+	if ns.pk, err = parsePkFields(ns.rtype); err != nil {
+		return err
+	}
+	if ns.version, err = parseVersionField(ns.rtype, ns.pk); err != nil {
+		return err
+	}
+	if schema := parseSchema(ns.name, ns.rtype); schema != nil {
+		ns.schema = *schema
+	}
+	return nil
+}
+
 func parseIndexes(namespace string, st reflect.Type, joined *map[string][]int) (indexDefs []bindings.IndexDef, err error) {
-	if err = parseIndexesImpl(&indexDefs, st, false, "", "", joined, nil); err != nil {
+	if err = parseIndexesImpl(&indexDefs, st, false, "", "", joined, newParsedStructs()); err != nil {
 		return nil, err
 	}
 
@@ -73,6 +104,16 @@ func parseSchema(namespace string, st reflect.Type) *bindings.SchemaDef {
 		}
 		return true
 	}
+	reflector.FieldEmbedding = cjson.FieldEmbedding
+	// Fields with custom marshalers are described by the types of their marshaled values
+	reflector.TypeMapper = func(t reflect.Type) *jsonschema.Type {
+		if mt, marshaled, _ := cjson.MarshaledType(t); marshaled && mt != nil {
+			if schema := (&jsonschema.Reflector{DoNotReference: true}).ReflectFromType(mt); schema != nil {
+				return schema.Type
+			}
+		}
+		return nil
+	}
 	reflector.DoNotReference = true
 	reflector.FullyQualifyTypeNames = true
 	if schema := reflector.ReflectFromType(st); schema != nil {
@@ -83,41 +124,83 @@ func parseSchema(namespace string, st reflect.Type) *bindings.SchemaDef {
 
 }
 
-func parseIndexesImpl(indexDefs *[]bindings.IndexDef, st reflect.Type, subArray bool, reindexBasePath, jsonBasePath string, joined *map[string][]int, parsed *map[string]bool) (err error) {
-	if len(jsonBasePath) != 0 && !strings.HasSuffix(jsonBasePath, ".") {
-		jsonBasePath = jsonBasePath + "."
+// parsedStructs tracks structs, which are parsed by parseIndexesImpl
+type parsedStructs struct {
+	// Structs, which are already parsed at the index base paths. Struct may be parsed several times, if its fields are
+	// flattened with different prefixes or nested into the fields with different index names
+	paths map[string]bool
+	// Structs on the current path, which are not parsed again to stop recursion
+	stack map[reflect.Type]bool
+}
+
+func newParsedStructs() *parsedStructs {
+	return &parsedStructs{paths: make(map[string]bool), stack: make(map[reflect.Type]bool)}
+}
+
+// nestedBasePath returns base path of the fields of the nested struct at path
+func nestedBasePath(path string) string {
+	if len(path) != 0 && !strings.HasSuffix(path, ".") {
+		return path + "."
 	}
+	return path
+}
 
-	if len(reindexBasePath) != 0 && !strings.HasSuffix(reindexBasePath, ".") {
-		reindexBasePath = reindexBasePath + "."
+// embeddedBasePaths returns base index and JSON paths of the fields of the struct field. Fields of the flattened struct
+// are placed at the level of the parent with the prefix
+func embeddedBasePaths(reindexPath, jsonBasePath, jsonPath string, flatten bool, prefix string) (string, string) {
+	if flatten {
+		return nestedBasePath(reindexPath) + prefix, jsonBasePath + prefix
 	}
+	return nestedBasePath(reindexPath), nestedBasePath(jsonPath)
+}
 
+// parseIndexesImpl parses indexes of the struct. Base paths are prefixes of the index names and JSON paths of its fields
+// (see nestedBasePath and embeddedBasePaths)
+func parseIndexesImpl(indexDefs *[]bindings.IndexDef, st reflect.Type, subArray bool, reindexBasePath, jsonBasePath string, joined *map[string][]int, parsed *parsedStructs) (err error) {
 	if st.Kind() == reflect.Ptr {
 		st = st.Elem()
 	}
 
-	isParsed, parsed := cjson.IsStructParsed(st, parsed)
-	if isParsed {
+	parsedKey := st.PkgPath() + "." + st.Name() + "@" + reindexBasePath
+	if parsed.stack[st] || parsed.paths[parsedKey] {
 		return nil
 	}
+	parsed.paths[parsedKey] = true
+	parsed.stack[st] = true
+	defer delete(parsed.stack, st)
 
 	for i := 0; i < st.NumField(); i++ {
 		t := st.Field(i).Type
 		if t.Kind() == reflect.Ptr {
 			t = t.Elem()
 		}
+		idxName, idxType, expireAfter, idxSettings := parseRxTags(st.Field(i))
+		if idxName == "-" {
+			continue
+		}
+		flatten, prefix, err := parseEmbedding(st.Field(i), &idxSettings)
+		if err != nil {
+			return err
+		}
+		// Fields with custom marshalers are indexed by the type of their marshaled values
+		// Flattened structs are encoded by their fields, even if they have custom marshalers
+		mt, marshaled, err := cjson.MarshaledType(t)
+		if err != nil {
+			return err
+		}
+		if marshaled && !flatten {
+			t = mt
+		} else {
+			marshaled = false
+		}
 		// Get and parse tags
 		jsonPath := strings.Split(st.Field(i).Tag.Get("json"), ",")[0]
 
-		if len(jsonPath) == 0 && !st.Field(i).Anonymous {
+		if len(jsonPath) == 0 && !flatten {
 			jsonPath = st.Field(i).Name
 		}
 		jsonPath = jsonBasePath + jsonPath
 
-		idxName, idxType, expireAfter, idxSettings := parseRxTags(st.Field(i))
-		if idxName == "-" {
-			continue
-		}
 		reindexPath := reindexBasePath + idxName
 
 		opts := parseOpts(&idxSettings)
@@ -143,16 +226,17 @@ func parseIndexesImpl(indexDefs *[]bindings.IndexDef, st reflect.Type, subArray
 			if err := indexDefAppend(indexDefs, indexDef, opts.isAppenable); err != nil {
 				return err
 			}
-		} else if t.Kind() == reflect.Struct {
-			if err := parseIndexesImpl(indexDefs, t, subArray, reindexPath, jsonPath, joined, parsed); err != nil {
+		} else if t.Kind() == reflect.Struct && !marshaled {
+			reindexFieldsPath, jsonFieldsPath := embeddedBasePaths(reindexPath, jsonBasePath, jsonPath, flatten, prefix)
+			if err := parseIndexesImpl(indexDefs, t, subArray, reindexFieldsPath, jsonFieldsPath, joined, parsed); err != nil {
 				return err
 			}
-		} else if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) &&
+		} else if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !marshaled &&
 			(t.Elem().Kind() == reflect.Struct || (t.Elem().Kind() == reflect.Ptr && t.Elem().Elem().Kind() == reflect.Struct)) {
 			// Check if field nested slice of struct
 			if parseByKeyWord(&idxSettings, "joined") && len(idxName) > 0 {
 				(*joined)[idxName] = st.Field(i).Index
-			} else if err := parseIndexesImpl(indexDefs, t.Elem(), true, reindexPath, jsonPath, joined, parsed); err != nil {
+			} else if err := parseIndexesImpl(indexDefs, t.Elem(), true, nestedBasePath(reindexPath), nestedBasePath(jsonPath), joined, parsed); err != nil {
 				return err
 			}
 		} else if len(idxName) > 0 {
@@ -182,6 +266,124 @@ func parseIndexesImpl(indexDefs *[]bindings.IndexDef, st reflect.Type, subArray
 	return nil
 }
 
+// structFieldRef refers to the indexed field of the namespace's go struct
+type structFieldRef struct {
+	// Name of index
+	index string
+	// Index sequence of the field in go struct
+	fieldIdx []int
+}
+
+// value returns value of the referenced field. Returns false, if some of the pointers on the field's path is nil
+func (f *structFieldRef) value(item interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(item)
+	for _, idx := range f.fieldIdx {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+// walkStructFields calls fn for each field of the struct, which is not nested struct. Nested structs are walked recursively,
+// but slices of structs are not. reindexPath is the path, which will be used as index name for this field
+func walkStructFields(st reflect.Type, reindexBasePath string, fieldIdxBase []int, fn func(sf reflect.StructField, t reflect.Type, reindexPath string, fieldIdx []int, idxSettings []string) error) error {
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		t := sf.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		idxName, _, _, idxSettings := parseRxTags(sf)
+		if idxName == "-" {
+			continue
+		}
+		fieldIdx := append(fieldIdxBase[:len(fieldIdxBase):len(fieldIdxBase)], i)
+
+		// Structs with custom marshalers are not walked, because they are encoded as values
+		_, marshaled, _ := cjson.MarshaledType(t)
+		flatten, prefix := cjson.FieldEmbedding(sf)
+		if t.Kind() == reflect.Struct && (!marshaled || flatten) && !parseByKeyWord(&idxSettings, "composite") {
+			reindexFieldsPath, _ := embeddedBasePaths(reindexBasePath+idxName, "", "", flatten, prefix)
+			if err := walkStructFields(t, reindexFieldsPath, fieldIdx, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(sf, t, reindexBasePath+idxName, fieldIdx, idxSettings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findStructField returns the field of the struct, which is indexed by the index (or would be indexed, if the field is not indexed)
+func findStructField(st reflect.Type, index string) (ref structFieldRef, found bool) {
+	walkStructFields(st, "", nil, func(sf reflect.StructField, t reflect.Type, reindexPath string, fieldIdx []int, idxSettings []string) error {
+		if !found && strings.EqualFold(reindexPath, index) {
+			ref, found = structFieldRef{index: reindexPath, fieldIdx: fieldIdx}, true
+		}
+		return nil
+	})
+	return ref, found
+}
+
+// parsePkFields returns fields of the struct, which are parts of primary key. Parts of composite primary key are returned in the index order
+func parsePkFields(st reflect.Type) (pks []structFieldRef, err error) {
+	fields := make(map[string]structFieldRef)
+	var compositePk []string
+	err = walkStructFields(st, "", nil, func(sf reflect.StructField, t reflect.Type, reindexPath string, fieldIdx []int, idxSettings []string) error {
+		isPk := parseOpts(&idxSettings).isPk
+		if _, marshaled, _ := cjson.MarshaledType(t); t.Kind() == reflect.Struct && !marshaled {
+			if isPk {
+				compositePk = parseCompositeJsonPaths(reindexPath)
+			}
+			return nil
+		}
+		ref := structFieldRef{index: reindexPath, fieldIdx: fieldIdx}
+		fields[reindexPath] = ref
+		if isPk {
+			pks = append(pks, ref)
+		}
+		return nil
+	})
+	if err != nil || len(compositePk) == 0 {
+		return pks, err
+	}
+	pks = pks[:0]
+	for _, part := range compositePk {
+		ref, ok := fields[part]
+		if !ok {
+			return nil, nil
+		}
+		pks = append(pks, ref)
+	}
+	return pks, nil
+}
+
+// wherePk adds conditions on primary key values of the item to the query
+func (ns *reindexerNamespace) wherePk(q *Query, item interface{}) error {
+	if len(ns.pk) == 0 {
+		return ErrNoPK
+	}
+	for i := range ns.pk {
+		v, ok := ns.pk[i].value(item)
+		if v = reflect.Indirect(v); !ok || !v.IsValid() {
+			return ErrNoPK
+		}
+		q.Where(ns.pk[i].index, EQ, v.Interface())
+	}
+	return nil
+}
+
 func parseOpts(idxSettingsBuf *[]string) indexOptions {
 	newIdxSettingsBuf := make([]string, 0)
 
@@ -202,7 +404,13 @@ func parseOpts(idxSettingsBuf *[]string) indexOptions {
 			opts.rtreeType = idxSetting
 		case "uuid":
 			opts.isUuid = true
+		case "version":
+			opts.isVersion = true
 		default:
+			if strings.HasPrefix(idxSetting, "autotime:") {
+				// Autotime options are handled by parseAutotimeFields
+				continue
+			}
 			newIdxSettingsBuf = append(newIdxSettingsBuf, idxSetting)
 		}
 	}
@@ -250,6 +458,17 @@ func parseCollate(idxSettingsBuf *[]string) (int, string) {
 			continue
 		}
 
+		if strings.HasPrefix(idxSetting, "collate_") && len(kvIdxSettings) == 1 {
+			if order, ok := getCollation(strings.TrimPrefix(idxSetting, "collate_")); ok {
+				if collateMode != CollateNone {
+					panic(fmt.Errorf("Collate mode is already set to %d. Misunderstanding %s", collateMode, idxSetting))
+				}
+				collateMode = CollateCustom
+				sortOrderLetters = order
+				continue
+			}
+		}
+
 		newIdxSettingsBuf = append(newIdxSettingsBuf, idxSetting)
 	}
 
@@ -288,6 +507,34 @@ func parseByKeyWord(idxSettingsBuf *[]string, keyWord string) bool {
 	return isPresented
 }
 
+// parseEmbedding removes embedding options (flatten, nested and prefix=) from the index settings and returns, whether
+// fields of the struct field are flattened into the parent object, and the prefix of their names (see cjson.FieldEmbedding)
+func parseEmbedding(sf reflect.StructField, idxSettingsBuf *[]string) (flatten bool, prefix string, err error) {
+	newIdxSettingsBuf := make([]string, 0)
+
+	isFlatten, isNested := false, false
+	for _, idxSetting := range *idxSettingsBuf {
+		switch {
+		case idxSetting == "flatten" || strings.HasPrefix(idxSetting, "prefix="):
+			isFlatten = true
+		case idxSetting == "nested":
+			isNested = true
+		default:
+			newIdxSettingsBuf = append(newIdxSettingsBuf, idxSetting)
+		}
+	}
+	*idxSettingsBuf = newIdxSettingsBuf
+
+	flatten, prefix = cjson.FieldEmbedding(sf)
+	if isFlatten && isNested {
+		return false, "", fmt.Errorf("Field %s can't be both flattened and nested", sf.Name)
+	}
+	if isFlatten && !flatten {
+		return false, "", fmt.Errorf("Only struct fields may be flattened: field %s has type %s", sf.Name, sf.Type)
+	}
+	return flatten, prefix, nil
+}
+
 func getFieldType(t reflect.Type) (string, error) {
 
 	switch t.Kind() {
@@ -2,8 +2,13 @@ package reindexer
 
 import (
 	"context"
+	"io"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
 	"github.com/restream/reindexer/v3/dsl"
 )
 
@@ -112,11 +117,28 @@ type DeepCopy interface {
 	DeepCopy() interface{}
 }
 
+// FieldMarshaler is implemented by the types of the struct fields, which control their indexed representation (e.g. enums, money, custom IDs).
+// Structs, which implement encoding.TextMarshaler, are indexed as strings. See cjson.FieldMarshaler
+type FieldMarshaler = cjson.FieldMarshaler
+
+// FieldUnmarshaler is implemented by the types of the struct fields, which are encoded by FieldMarshaler. See cjson.FieldUnmarshaler
+type FieldUnmarshaler = cjson.FieldUnmarshaler
+
 // Logger interface for reindexer
 type Logger interface {
 	Printf(level int, fmt string, msg ...interface{})
 }
 
+// StructuredLogger is the Logger, which receives the fields of the records (namespace, query, duration, error code)
+// separately from the message. See NewSlogLogger and NewZapLogger
+type StructuredLogger = bindings.StructuredLogger
+
+// LogField - field of the structured log record
+type LogField = bindings.LogField
+
+// LevelLogger is the Logger, which reports the enabled levels, so the client doesn't build the records, which are discarded
+type LevelLogger = bindings.LevelLogger
+
 type nullLogger struct {
 }
 
@@ -141,6 +163,9 @@ var (
 	ErrMustBePointer       = bindings.NewError("rq: Argument must be a pointer to element, not element", ErrCodeParams)
 	ErrNotFound            = bindings.NewError("rq: Not found", ErrCodeNotFound)
 	ErrDeepCopyType        = bindings.NewError("rq: DeepCopy() returns wrong type", ErrCodeParams)
+	ErrVersionConflict     = bindings.NewError("rq: Item version conflict", ErrCodeConflict)
+	ErrSnapshotChanged     = bindings.NewError("rq: Namespaces are modified since read snapshot", ErrCodeConflict)
+	ErrJSONExecutorBusy    = bindings.NewError("rq: Previous results of JSON executor are not closed", ErrCodeLogic)
 )
 
 type AggregationResult struct {
@@ -169,6 +194,37 @@ func (db *Reindexer) Status() bindings.Status {
 	return db.impl.getStatus(db.ctx)
 }
 
+// ReleaseItem returns the decoded item of the namespace to the pool of WithItemPool, so the struct is reused for decoding of the next items.
+// The item must not be used after the release. Items, which were returned from the object cache (e.g. with AllowUnsafe), must not be released.
+// It does nothing, if the pool of the namespace is not enabled
+func (db *Reindexer) ReleaseItem(namespace string, item interface{}) {
+	db.impl.releaseItem(namespace, item)
+}
+
+// ClientStats returns runtime statistics of the client: open iterators, transactions in flight, etc
+func (db *Reindexer) ClientStats() ClientStats {
+	return db.impl.clientStats()
+}
+
+// PoolStats returns statistics of the connections of cproto binding's pool (see WithConnPoolSize) in the order of the pool's slots.
+// It returns nil for the bindings without connections pool
+func (db *Reindexer) PoolStats() []ConnStats {
+	return db.impl.poolStats()
+}
+
+// Activity returns client side operations, which are in progress (items modifications, queries, commits of transactions,
+// namespaces and indexes management), from the oldest to the newest. It may be dumped by stuck service
+// (e.g. on SIGQUIT or by debug endpoint) to show, what the client is waiting for
+func (db *Reindexer) Activity(ctx context.Context) []Activity {
+	return db.impl.activity()
+}
+
+// NamespaceHashes returns client side hashes of the namespaces, opened by this client (for debugging).
+// Hashes are unique among the opened namespaces: on collision the next free value is assigned
+func (db *Reindexer) NamespaceHashes() map[string]int {
+	return db.impl.namespaceHashes()
+}
+
 // SetLogger sets logger interface for output reindexer logs
 func (db *Reindexer) SetLogger(log Logger) {
 	db.impl.setLogger(log)
@@ -213,6 +269,16 @@ type NamespaceOptions struct {
 	disableObjCache bool
 	// Object cache items count
 	objCacheItemsCount uint64
+	// Field, which marks soft deleted items
+	softDeleteField string
+	// Deadline for the operations without deadline in context
+	defaultDeadline time.Duration
+	// Fail on suspicious tags of struct
+	strictTags bool
+	// Mirror writes of items to the history namespace
+	history bool
+	// Filters, added to the queries of the namespace
+	defaultFilters []DefaultFilter
 }
 
 // DefaultNamespaceOptions return default namespace options
@@ -249,10 +315,93 @@ func (opts *NamespaceOptions) ObjCacheSize(count int) *NamespaceOptions {
 	return opts
 }
 
+// WithSoftDelete enables soft delete for namespace. Delete sets field to the deletion time (unix seconds) instead of removing item,
+// and queries skip items with non-zero field, unless Query.WithDeleted() is used. Use PurgeDeleted to remove old deleted items
+func (opts *NamespaceOptions) WithSoftDelete(field string) *NamespaceOptions {
+	opts.softDeleteField = field
+	return opts
+}
+
+// WithDefaultDeadline sets timeout for queries and item modifications of the namespace, which are called with context without deadline
+func (opts *NamespaceOptions) WithDefaultDeadline(d time.Duration) *NamespaceOptions {
+	opts.defaultDeadline = d
+	return opts
+}
+
+// StrictTags enables strict validation of struct tags. Unknown options, duplicate index names, composite indexes over
+// missing fields, pk on arrays and similar mistakes cause OpenNamespace to fail with *TagsValidationError, which lists all the mistakes
+func (opts *NamespaceOptions) StrictTags() *NamespaceOptions {
+	opts.strictTags = true
+	return opts
+}
+
+// WithHistory enables history of the items. Each Insert, Update, Upsert and Delete of the item (including the ones by transactions
+// and by queries) is also written to the companion namespace HistoryNamespace(namespace) with version, timestamp and operation,
+// if the item is modified. Use ItemHistory to get the changes of the item. Queries of transactions (Tx.Query()) are not supported
+func (opts *NamespaceOptions) WithHistory() *NamespaceOptions {
+	opts.history = true
+	return opts
+}
+
+// WithDefaultFilter adds filter, which conditions are added to each select, update and delete query of the namespace, including
+// joined and merged queries (e.g. 'deleted = false' or tenant of the request from the context). Use Query.WithoutDefaults() to skip them.
+// Modifications of the items and the transaction's queries are not filtered
+func (opts *NamespaceOptions) WithDefaultFilter(filter DefaultFilter) *NamespaceOptions {
+	opts.defaultFilters = append(opts.defaultFilters, filter)
+	return opts
+}
+
 // OpenNamespace Open or create new namespace and indexes based on passed struct.
 // IndexDef fields of struct are marked by `reindex:` tag
 func (db *Reindexer) OpenNamespace(namespace string, opts *NamespaceOptions, s interface{}) (err error) {
-	return db.impl.openNamespace(db.ctx, namespace, opts, s)
+	return db.impl.openNamespace(db.ctx, namespace, opts, s, nil)
+}
+
+// OpenNamespaceAsync starts opening of the namespace in background and returns handle to track its progress.
+// Struct tags are checked synchronously, so error is returned, if the struct can't be registered.
+// Namespace may be used for queries after OpenNamespaceHandle.Done is closed without error. Other namespaces may be used meanwhile
+func (db *Reindexer) OpenNamespaceAsync(namespace string, opts *NamespaceOptions, s interface{}) (*OpenNamespaceHandle, error) {
+	namespace = strings.ToLower(namespace)
+	if err := db.impl.registerNamespaceImpl(namespace, opts, s); err != nil {
+		return nil, err
+	}
+	handle := newOpenNamespaceHandle(namespace)
+	ctx := db.ctx
+	go func() {
+		handle.finish(db.impl.openNamespace(ctx, namespace, opts, s, handle))
+	}()
+	return handle, nil
+}
+
+// DescribeStruct returns definitions of indexes, which are declared by `reindex:` tags of the struct.
+// s is the struct (or pointer to it), like for OpenNamespace, or its reflect.Type.
+// Tags are validated the same way as on OpenNamespace, so DescribeStruct may be used to check tags without DB connection
+func DescribeStruct(s interface{}) ([]IndexDef, error) {
+	t, ok := s.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(s)
+	} else {
+		s = reflect.New(t).Elem().Interface()
+	}
+	if t == nil {
+		return nil, ErrWrongType
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, ErrWrongType
+	}
+
+	ns := &reindexerNamespace{rtype: t, joined: make(map[string][]int)}
+	if err := ns.parseStruct(s); err != nil {
+		return nil, err
+	}
+	indexDefs := make([]IndexDef, 0, len(ns.indexes))
+	for _, indexDef := range ns.indexes {
+		indexDefs = append(indexDefs, IndexDef(indexDef))
+	}
+	return indexDefs, nil
 }
 
 // RegisterNamespace Register go type against namespace. There are no data and indexes changes will be performed
@@ -265,7 +414,8 @@ func (db *Reindexer) DropNamespace(namespace string) error {
 	return db.impl.dropNamespace(db.ctx, namespace)
 }
 
-// TruncateNamespace - delete all items from namespace
+// TruncateNamespace - delete all items from namespace. Cached items, cached query results and payload types of the namespace are reset
+// before returning
 func (db *Reindexer) TruncateNamespace(namespace string) error {
 	return db.impl.truncateNamespace(db.ctx, namespace)
 }
@@ -287,9 +437,23 @@ func (db *Reindexer) Upsert(namespace string, item interface{}, precepts ...stri
 	return db.impl.upsert(db.ctx, namespace, item, precepts...)
 }
 
+// UpsertMsgPack (Insert or Update) item in msgpack format to namespace. Item is sent to the server as is, so it doesn't require
+// registered Go struct or conversion to JSON. Namespaces with history (see WithHistory) don't support items in msgpack format
+func (db *Reindexer) UpsertMsgPack(namespace string, data []byte, precepts ...string) error {
+	return db.impl.upsertMsgPack(db.ctx, namespace, data, precepts...)
+}
+
+// UpsertBatch (Insert or Update) items to namespace by one call of the binding. Items are packed into one buffer and sent together,
+// so the network round trips are not multiplied by the count of items. Bindings without batch support upsert items one by one.
+// Items must be the same type as item passed to OpenNamespace, or []byte with json.
+// If some of the items were not upserted, *BatchError with the errors of the items is returned. Other items stay upserted
+func (db *Reindexer) UpsertBatch(ctx context.Context, namespace string, items []interface{}, precepts ...string) error {
+	return db.impl.upsertBatch(ctx, namespace, items, precepts...)
+}
+
 // Insert item to namespace by PK
 // Item must be the same type as item passed to OpenNamespace, or []byte with json data
-// Return 0, if no item was inserted, 1 if item was inserted
+// Return 1 if item was inserted, or 0 and UniqueViolationError, if the item with the same primary key exists
 // If the precepts are provided and the item is a pointer, the value pointed by item will be updated
 func (db *Reindexer) Insert(namespace string, item interface{}, precepts ...string) (int, error) {
 	return db.impl.insert(db.ctx, namespace, item, precepts...)
@@ -303,6 +467,12 @@ func (db *Reindexer) Update(namespace string, item interface{}, precepts ...stri
 	return db.impl.update(db.ctx, namespace, item, precepts...)
 }
 
+// UpdateIfVersion - update item to namespace by PK, only if the version of the stored item (see Iterator.Version) is equal to expectedVersion.
+// Otherwise *VersionConflictError is returned. Returns ErrNotFound, if there is no such item
+func (db *Reindexer) UpdateIfVersion(ctx context.Context, namespace string, item interface{}, expectedVersion int64) error {
+	return db.impl.updateIfVersion(ctx, namespace, item, expectedVersion)
+}
+
 // Delete - remove single item from namespace by PK
 // Item must be the same type as item passed to OpenNamespace, or []byte with json data
 // If the precepts are provided and the item is a pointer, the value pointed by item will be updated
@@ -310,6 +480,57 @@ func (db *Reindexer) Delete(namespace string, item interface{}, precepts ...stri
 	return db.impl.delete(db.ctx, namespace, item, precepts...)
 }
 
+// PurgeDeleted - remove items, which were soft deleted more than olderThan ago, from namespace with soft delete enabled
+// Return count of removed items
+func (db *Reindexer) PurgeDeleted(namespace string, olderThan time.Duration) (int, error) {
+	return db.impl.purgeDeleted(db.ctx, namespace, olderThan)
+}
+
+// ItemHistory - get changes of the item from the history of namespace with history enabled, ordered by version.
+// Values of primary key fields must be passed in the order of the key parts
+func (db *Reindexer) ItemHistory(namespace string, pk ...interface{}) ([]*HistoryItem, error) {
+	return db.impl.itemHistory(db.ctx, namespace, pk...)
+}
+
+// Subscribe - start watching changes of the namespace's items. Changes are read from the namespace's WAL (see WALQuery) from the LSN
+// of the last read record, so each modification of the item is delivered. Subscription requires cproto binding.
+// Connection errors are retried until ctx is canceled. The channel is closed, when ctx is canceled
+func (db *Reindexer) Subscribe(ctx context.Context, namespace string, opts *SubscribeOptions) (<-chan UpdateEvent, error) {
+	return db.impl.subscribe(ctx, namespace, opts)
+}
+
+// InvalidateQueryCache - remove cached results (see Query.CachePolicy) of the queries, which use the namespace.
+// Writes of this client (and of the other clients with cproto binding) invalidate the results automatically, so it's needed only
+// for the changes made by other clients with builtin binding
+func (db *Reindexer) InvalidateQueryCache(namespace string) {
+	db.impl.queryCache.invalidate(strings.ToLower(namespace))
+}
+
+// BeginReadSnapshot - pin the current LSNs of the namespaces. Queries of the snapshot are regular queries, and
+// ReadSnapshot.Validate reports ErrSnapshotChanged, if some of the namespaces were modified since the snapshot was taken
+func (db *Reindexer) BeginReadSnapshot(namespaces ...string) (*ReadSnapshot, error) {
+	return db.impl.beginReadSnapshot(db.ctx, namespaces)
+}
+
+// ReadConsistent - call fn with read snapshot of the namespaces, and repeat it with the new snapshot, if the namespaces
+// were modified meanwhile. Returns ErrSnapshotChanged, if the namespaces were modified during all the attempts
+func (db *Reindexer) ReadConsistent(namespaces []string, attempts int, fn func(s *ReadSnapshot) error) error {
+	return db.impl.readConsistent(db.ctx, namespaces, attempts, fn)
+}
+
+// GetByCompositePK - get item by values of primary key fields. For composite primary key values must be passed in the order of
+// the key parts (e.g. 'tenant_id+slug'). Returns ErrNotFound, if there is no such item
+func (db *Reindexer) GetByCompositePK(namespace string, parts ...interface{}) (interface{}, error) {
+	return db.impl.getByPK(db.ctx, namespace, parts...)
+}
+
+// PrimaryKeyOf returns value of the primary key of the item (struct of the namespace or pointer to it). Fields of primary key
+// are resolved once on OpenNamespace, so it's cheaper than reflection of the application. For composite primary key
+// []interface{} with values of the key parts is returned in the same order, as they are passed to GetByCompositePK
+func (db *Reindexer) PrimaryKeyOf(namespace string, item interface{}) (interface{}, error) {
+	return db.impl.primaryKeyOf(namespace, item)
+}
+
 // ConfigureIndex - congigure index.
 // config argument must be struct with index configuration
 // Deprecated: Use UpdateIndex instead.
@@ -337,11 +558,47 @@ func (db *Reindexer) SetDefaultQueryDebug(namespace string, level int) error {
 	return db.impl.setDefaultQueryDebug(db.ctx, namespace, level)
 }
 
+// GetNamespaceConfig returns config of the namespace from '#config' system namespace.
+// If the namespace has no own config, config for all the namespaces ('*') is returned
+func (db *Reindexer) GetNamespaceConfig(namespace string) (*DBNamespacesConfig, error) {
+	return db.impl.getNamespaceConfig(db.ctx, namespace)
+}
+
+// SetNamespaceConfig reads config of the namespace (see GetNamespaceConfig), passes it to update and writes it back,
+// so the fields, which are not changed by update, keep their values. Concurrent modifications of '#config' are not detected
+func (db *Reindexer) SetNamespaceConfig(namespace string, update func(cfg *DBNamespacesConfig)) error {
+	return db.impl.setNamespaceConfig(db.ctx, namespace, update)
+}
+
+// GetProfilingConfig returns profiling config from '#config' system namespace
+func (db *Reindexer) GetProfilingConfig() (*DBProfilingConfig, error) {
+	return db.impl.getProfilingConfig(db.ctx)
+}
+
+// SetProfilingConfig reads profiling config, passes it to update and writes it back
+func (db *Reindexer) SetProfilingConfig(update func(cfg *DBProfilingConfig)) error {
+	return db.impl.setProfilingConfig(db.ctx, update)
+}
+
+// WALQuery returns iterator over the records of the namespace's write ahead log with LSN greater than fromLSN,
+// so external replication and ETL pipelines may consume the changes incrementally (see WALIterator.LSN).
+// Namespace's WAL is limited by its wal_size config, so the records before the oldest one are not available
+func (db *Reindexer) WALQuery(ctx context.Context, namespace string, fromLSN int64) *WALIterator {
+	return db.impl.walQuery(ctx, namespace, fromLSN)
+}
+
 // Query Create new Query for building request
 func (db *Reindexer) Query(namespace string) *Query {
 	return db.impl.query(namespace)
 }
 
+// ExecBatch executes several independent select queries and returns their iterators in the order of the queries.
+// cproto binding sends all the queries via one connection without waiting for the responses, so the batch takes one network round trip.
+// Other bindings execute the queries one by one. Errors of the queries are returned by their iterators, which must be closed
+func (db *Reindexer) ExecBatch(ctx context.Context, queries []*Query) []*Iterator {
+	return db.impl.execBatch(ctx, queries)
+}
+
 // ExecSQL make query to database. Query is a SQL statement.
 // Return Iterator.
 func (db *Reindexer) ExecSQL(query string) *Iterator {
@@ -368,6 +625,12 @@ func (db *Reindexer) MustBeginTx(namespace string) *Tx {
 	return db.impl.mustBeginTx(db.ctx, namespace)
 }
 
+// BeginMultiTx - start transactions of several namespaces, which are committed together by MultiTx.Commit. Transactions are committed
+// one by one, so on failure the committed namespaces are reverted by the compensations (see MultiTx.OnCompensate), not by the server
+func (db *Reindexer) BeginMultiTx(namespaces ...string) (*MultiTx, error) {
+	return db.impl.beginMultiTx(db.ctx, namespaces)
+}
+
 // QueryFrom - create query from DSL and execute it
 func (db *Reindexer) QueryFrom(d dsl.DSL) (*Query, error) {
 	return db.impl.queryFrom(&d)
@@ -399,6 +662,19 @@ func (db *Reindexer) GetMeta(namespace, key string) ([]byte, error) {
 	return db.impl.getMeta(db.ctx, namespace, key)
 }
 
+// DumpNamespace writes index definitions, meta and all the items of the opened namespace to w as NDJSON stream (see readme for the format).
+// Items are read by pages, so the dump is not a consistent snapshot, if the namespace is modified concurrently.
+// Meta is dumped only by the bindings, which are able to enumerate its keys (cproto)
+func (db *Reindexer) DumpNamespace(ctx context.Context, namespace string, w io.Writer) error {
+	return db.impl.dumpNamespace(ctx, namespace, w)
+}
+
+// RestoreNamespace reads the dump, written by DumpNamespace, and restores it into the opened namespace: missing indexes are added,
+// meta is put and items are upserted as is. Existing items of the namespace are not removed
+func (db *Reindexer) RestoreNamespace(ctx context.Context, namespace string, r io.Reader) error {
+	return db.impl.restoreNamespace(ctx, namespace, r)
+}
+
 // WithContext Add context to next method call
 func (db *Reindexer) WithContext(ctx context.Context) *Reindexer {
 	dbC := &Reindexer{
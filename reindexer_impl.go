@@ -8,8 +8,9 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/prometheus/client_golang/prometheus"
 	otel "go.opentelemetry.io/otel"
 	otelattr "go.opentelemetry.io/otel/attribute"
@@ -33,6 +34,14 @@ type reindexerNamespace struct {
 	cjsonState    cjson.State
 	nsHash        int
 	opened        bool
+	autotime      []autotimeField
+	version       *versionField
+	pk            []structFieldRef
+	// Pool of the decoded structs. nil, if it's not enabled by WithItemPool
+	itemPool *itemPool
+	// Fields for validation of the queries (see WithQueryValidation)
+	queryFields     *queryFields
+	queryFieldsOnce sync.Once
 }
 
 // reindexerImpl The reindxer state struct
@@ -43,38 +52,159 @@ type reindexerImpl struct {
 	binding       bindings.RawBinding
 	debugLevels   map[string]int
 	nsHashCounter int
-	status        error
+	// Names of the registered namespaces by their nsHash
+	nsHashes map[int]string
+	status   error
 
 	promMetrics *reindexerPrometheusMetrics
 
 	otelTracer           oteltrace.Tracer
 	otelCommonTraceAttrs []otelattr.KeyValue
+	otelAttrsFunc        TracingAttributesFunc
+	otelSpanName         func(op string) string
+	otelQuerySQL         bool
+	otelSlowThreshold    time.Duration
+
+	pprofLabels bool
+	// Fields of the queries are validated against the namespaces' structs
+	queryValidation bool
+	// Default of Iterator.AllowUnsafe for the queries (see WithAllowUnsafe)
+	allowUnsafe bool
+
+	rateLimiter    *rateLimiter
+	nsRateLimiters map[string]*rateLimiter
+	nsLimiters     map[string]concurrencyLimiter
+
+	memBudget *memoryBudget
+	// Limits and stores of the object caches by namespaces
+	itemCaches map[string]*bindings.OptionItemCache
+	// Pools of the decoded structs by namespaces (see WithItemPool)
+	itemPools map[string]bindings.OptionItemPool
+
+	txWindow bindings.OptionTxAsyncWindow
+	// Adaptive fetch count of the query results. nil, if fetch count is fixed (see WithAdaptiveFetch)
+	adaptiveFetch *AdaptiveFetch
+
+	counters *clientCounters
+
+	recoverHandler RecoverHandler
+	// Interceptors of the binding calls (see WithInterceptor)
+	interceptors []Interceptor
+	// Sampling and handler of the verification of the queries' results (see WithResultVerification)
+	verification *bindings.OptionResultVerification
+
+	queryCache *queryCache
+
+	activities *activityTracker
 }
 
+// cacheItems is cache of deserialized items (LRU by default, see WithItemCacheConfig). Items are keyed by internal item id, which is unique
+// within namespace for any primary key (including composite), so cache doesn't depend on primary key layout
 type cacheItems struct {
+	// estimated size of cached items in bytes. Tracked only with memory budget or size limit
+	size int64
+	// usage statistics of the cache (see NamespaceCacheStats)
+	hits        int64
+	misses      int64
+	evictions   int64
+	decodes     int64
+	decodeNanos int64
+	// count of running removals of the items, which are not evictions (e.g. invalidation of the deleted items)
+	dropping int32
+	// max size of cached items in bytes. Not limited, if zero
+	maxBytes int64
 	// cached items
-	items *lru.Cache
+	items CacheStore
+	// memory budget, shared with caches of other namespaces. May be nil
+	budget *memoryBudget
+	lock   sync.Mutex
+	closed bool
+}
+
+// sizeTracked returns true, if size of the cached items is tracked
+func (ci *cacheItems) sizeTracked() bool {
+	return ci.budget != nil || ci.maxBytes > 0
 }
 
 func (ci *cacheItems) Reset() {
 	if ci.items == nil {
 		return
 	}
-	ci.items.Purge()
+	ci.drop(ci.items.Purge)
 }
 
 func (ci *cacheItems) Remove(key int) {
 	if ci.items == nil {
 		return
 	}
-	ci.items.Remove(key)
+	ci.drop(func() { ci.items.Remove(key) })
+}
+
+// drop removes items by fn without counting them as evictions
+func (ci *cacheItems) drop(fn func()) {
+	atomic.AddInt32(&ci.dropping, 1)
+	fn()
+	atomic.AddInt32(&ci.dropping, -1)
 }
 
 func (ci *cacheItems) Add(key int, item *cacheItem) {
 	if ci.items == nil {
 		return
 	}
+	if !ci.sizeTracked() {
+		ci.items.Add(key, item)
+		return
+	}
+	// Replacement of the item doesn't call eviction callback, so the previous item is removed explicitly to release its size
+	ci.lock.Lock()
+	if ci.closed {
+		ci.lock.Unlock()
+		return
+	}
+	ci.drop(func() { ci.items.Remove(key) })
+	atomic.AddInt64(&ci.size, item.size)
+	if ci.budget != nil {
+		ci.budget.charge(item.size)
+	}
 	ci.items.Add(key, item)
+	for ci.maxBytes > 0 && atomic.LoadInt64(&ci.size) > ci.maxBytes {
+		if !ci.items.RemoveOldest() {
+			break
+		}
+	}
+	ci.lock.Unlock()
+	if ci.budget != nil {
+		ci.budget.enforce()
+	}
+}
+
+// close releases memory of the cache and excludes it from the memory budget. Items are not cached after close
+func (ci *cacheItems) close() {
+	if !ci.sizeTracked() {
+		return
+	}
+	ci.lock.Lock()
+	ci.closed = true
+	ci.lock.Unlock()
+	if ci.budget != nil {
+		ci.budget.unregister(ci)
+	}
+	ci.drop(ci.items.Purge)
+}
+
+func (ci *cacheItems) onEvict(key int, value interface{}) {
+	// Concurrent evictions may be missed, while items are dropped, so the counter is approximate
+	if atomic.LoadInt32(&ci.dropping) == 0 {
+		atomic.AddInt64(&ci.evictions, 1)
+	}
+	if !ci.sizeTracked() {
+		return
+	}
+	size := value.(*cacheItem).size
+	atomic.AddInt64(&ci.size, -size)
+	if ci.budget != nil {
+		ci.budget.release(size)
+	}
 }
 
 func (ci *cacheItems) Len() int {
@@ -84,6 +214,24 @@ func (ci *cacheItems) Len() int {
 	return ci.items.Len()
 }
 
+// stats returns usage statistics of the cache
+func (ci *cacheItems) stats() NamespaceCacheStats {
+	return NamespaceCacheStats{
+		Items:      ci.Len(),
+		Hits:       atomic.LoadInt64(&ci.hits),
+		Misses:     atomic.LoadInt64(&ci.misses),
+		Evictions:  atomic.LoadInt64(&ci.evictions),
+		Decodes:    atomic.LoadInt64(&ci.decodes),
+		DecodeTime: time.Duration(atomic.LoadInt64(&ci.decodeNanos)),
+	}
+}
+
+// decoded accounts time of decoding of the item, which was started at start
+func (ci *cacheItems) decoded(start time.Time) {
+	atomic.AddInt64(&ci.decodes, 1)
+	atomic.AddInt64(&ci.decodeNanos, int64(time.Since(start)))
+}
+
 func (ci *cacheItems) Get(key int) (*cacheItem, bool) {
 	if ci.items == nil {
 		return nil, false
@@ -101,16 +249,45 @@ type cacheItem struct {
 	item interface{}
 	// version of item
 	version int
+	// estimated size of item in bytes
+	size int64
 }
 
-func newCacheItems(count uint64) (*cacheItems, error) {
-	cache, err := lru.New(int(count))
+// newCacheItems creates object cache of the namespace. Limits and store of the cache are set by cfg, if it's not nil
+func newCacheItems(namespace string, count uint64, cfg *bindings.OptionItemCache, budget *memoryBudget) (*cacheItems, error) {
+	ci := &cacheItems{budget: budget}
+	maxItems := int(count)
+	policy := CacheLRU
+	var newStore CacheStoreFactory
+	shards := 1
+	if cfg != nil {
+		if cfg.MaxItems > 0 {
+			maxItems = cfg.MaxItems
+		}
+		ci.maxBytes = cfg.MaxBytes
+		policy = CachePolicy(cfg.Policy)
+		newStore = cfg.NewStore
+		if cfg.Shards > 1 {
+			shards = cfg.Shards
+		}
+	}
+
+	newShard := func(maxItems int) (CacheStore, error) {
+		if newStore != nil {
+			return newStore(namespace, maxItems, ci.onEvict)
+		}
+		return newCacheStore(policy, maxItems, ci.onEvict)
+	}
+	var err error
+	if shards > 1 {
+		ci.items, err = newShardedCacheStore(shards, maxItems, newShard)
+	} else {
+		ci.items, err = newShard(maxItems)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &cacheItems{
-		items: cache,
-	}, nil
+	return ci, nil
 }
 
 // NewReindexImpl Create new instanse of Reindexer DB
@@ -126,27 +303,122 @@ func newReindexImpl(dsn interface{}, options ...interface{}) *reindexerImpl {
 	binding = binding.Clone()
 
 	rx := &reindexerImpl{
-		ns:      make(map[string]*reindexerNamespace, 100),
-		binding: binding,
+		ns:         make(map[string]*reindexerNamespace, 100),
+		nsHashes:   make(map[int]string, 100),
+		binding:    binding,
+		counters:   &clientCounters{},
+		queryCache: newQueryCache(),
+		activities: newActivityTracker(),
 	}
 
 	for _, opt := range options {
 		switch v := opt.(type) {
 		case bindings.OptionPrometheusMetrics:
 			if v.EnablePrometheusMetrics {
-				rx.promMetrics = newPrometheusMetrics(dsnParsed)
+				promMetrics, err := newPrometheusMetrics(rx, dsnParsed, v)
+				if err != nil {
+					rx.status = err
+				}
+				rx.promMetrics = promMetrics
 			}
 
 		case bindings.OptionOpenTelemetry:
 			if v.EnableTracing {
-				rx.otelTracer = otel.Tracer(
+				tp := v.TracerProvider
+				if tp == nil {
+					tp = otel.GetTracerProvider()
+				}
+				rx.otelTracer = tp.Tracer(
 					"reindexer/v3",
 					oteltrace.WithInstrumentationVersion(bindings.ReindexerVersion),
 				)
 				rx.otelCommonTraceAttrs = []otelattr.KeyValue{
 					otelattr.String("rx.dsn", dsnString(dsnParsed)),
 				}
+				rx.otelSpanName, rx.otelQuerySQL, rx.otelSlowThreshold = v.SpanName, v.QuerySQL, v.SlowThreshold
+			}
+
+		case bindings.OptionOpenTelemetryAttributes:
+			rx.otelAttrsFunc = v.AttributesFunc
+
+		case bindings.OptionPprofLabels:
+			rx.pprofLabels = v.EnablePprofLabels
+
+		case bindings.OptionQueryValidation:
+			rx.queryValidation = v.EnableQueryValidation
+
+		case bindings.OptionAllowUnsafe:
+			rx.allowUnsafe = v.AllowUnsafe
+
+		case bindings.OptionRateLimit:
+			if v.OpsPerSecond <= 0 {
+				break
+			}
+			if v.Namespace == "" {
+				rx.rateLimiter = newRateLimiter(v.OpsPerSecond, v.Burst)
+			} else {
+				if rx.nsRateLimiters == nil {
+					rx.nsRateLimiters = make(map[string]*rateLimiter)
+				}
+				rx.nsRateLimiters[strings.ToLower(v.Namespace)] = newRateLimiter(v.OpsPerSecond, v.Burst)
 			}
+
+		case bindings.OptionMaxConcurrentQueries:
+			if v.Count <= 0 {
+				break
+			}
+			if rx.nsLimiters == nil {
+				rx.nsLimiters = make(map[string]concurrencyLimiter)
+			}
+			rx.nsLimiters[strings.ToLower(v.Namespace)] = newConcurrencyLimiter(v.Count)
+
+		case bindings.OptionMemoryBudget:
+			if v.Bytes > 0 {
+				rx.memBudget = newMemoryBudget(v.Bytes)
+			}
+
+		case bindings.OptionRecoverHandler:
+			rx.recoverHandler = v.Handler
+
+		case bindings.OptionTxAsyncWindow:
+			rx.txWindow = v
+
+		case bindings.OptionAdaptiveFetch:
+			rx.adaptiveFetch = newAdaptiveFetch(v)
+
+		case bindings.OptionInterceptor:
+			if v.Interceptor != nil {
+				rx.interceptors = append(rx.interceptors, v.Interceptor)
+			}
+
+		case bindings.OptionResultVerification:
+			if v.SampleRate > 0 {
+				rx.verification = &v
+			}
+
+		case bindings.OptionItemCache:
+			if rx.itemCaches == nil {
+				rx.itemCaches = make(map[string]*bindings.OptionItemCache)
+			}
+			// Limits and store of the same namespace are set by different options
+			namespace := strings.ToLower(v.Namespace)
+			cfg, ok := rx.itemCaches[namespace]
+			if !ok {
+				cfg = &bindings.OptionItemCache{Namespace: namespace}
+				rx.itemCaches[namespace] = cfg
+			}
+			if v.NewStore != nil {
+				cfg.NewStore = v.NewStore
+			} else if v.Shards > 0 {
+				cfg.Shards = v.Shards
+			} else {
+				cfg.MaxItems, cfg.MaxBytes, cfg.Policy = v.MaxItems, v.MaxBytes, v.Policy
+			}
+		case bindings.OptionItemPool:
+			if rx.itemPools == nil {
+				rx.itemPools = make(map[string]bindings.OptionItemPool)
+			}
+			rx.itemPools[strings.ToLower(v.Namespace)] = v
 		}
 	}
 
@@ -173,7 +445,9 @@ func newReindexImpl(dsn interface{}, options ...interface{}) *reindexerImpl {
 // getStatus will return current db status
 func (db *reindexerImpl) getStatus(ctx context.Context) bindings.Status {
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Status").End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Status")
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -215,7 +489,9 @@ func (db *reindexerImpl) reopenLogFiles() error {
 // ping checks connection with reindexer
 func (db *reindexerImpl) ping(ctx context.Context) error {
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Ping").End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Ping")
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -226,6 +502,10 @@ func (db *reindexerImpl) ping(ctx context.Context) error {
 }
 
 func (db *reindexerImpl) close() {
+	db.queryCache.close()
+	if db.promMetrics != nil {
+		db.promMetrics.close()
+	}
 	if err := db.binding.Finalize(); err != nil {
 		panic(err)
 	}
@@ -233,16 +513,20 @@ func (db *reindexerImpl) close() {
 
 // openNamespace Open or create new namespace and indexes based on passed struct.
 // IndexDef fields of struct are marked by `reindex:` tag
-func (db *reindexerImpl) openNamespace(ctx context.Context, namespace string, opts *NamespaceOptions, s interface{}) (err error) {
+// Progress of the opening is reported to the handle, if it's not nil
+func (db *reindexerImpl) openNamespace(ctx context.Context, namespace string, opts *NamespaceOptions, s interface{}, handle *OpenNamespaceHandle) (err error) {
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.OpenNamespace", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.OpenNamespace", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("OpenNamespace", namespace)).ObserveDuration()
 	}
+	defer db.startActivity(ctx, "OpenNamespace", namespace)()
 
 	if err = db.registerNamespaceImpl(namespace, opts, s); err != nil {
 		return err
@@ -254,17 +538,20 @@ func (db *reindexerImpl) openNamespace(ctx context.Context, namespace string, op
 	}
 
 	for retry := 0; retry < 2; retry++ {
+		handle.setStage(OpenNamespaceLoading, 0, len(ns.indexes))
 		if err = db.binding.OpenNamespace(ctx, namespace, opts.enableStorage, opts.dropOnFileFormatError); err != nil {
 			break
 		}
 
-		for _, indexDef := range ns.indexes {
+		for i, indexDef := range ns.indexes {
+			handle.setStage(OpenNamespaceIndexes, i, len(ns.indexes))
 			if err = db.binding.AddIndex(ctx, namespace, indexDef); err != nil {
 				break
 			}
 		}
 
 		if err == nil {
+			handle.setStage(OpenNamespaceSchema, len(ns.indexes), len(ns.indexes))
 			if err = db.binding.SetSchema(ctx, namespace, ns.schema); err != nil {
 				if rerr, ok := err.(bindings.Error); ok && rerr.Code() == bindings.ErrParams {
 					// Ignore error from old server which doesn't support SetSchema
@@ -288,6 +575,14 @@ func (db *reindexerImpl) openNamespace(ctx context.Context, namespace string, op
 		break
 	}
 
+	if err == nil {
+		db.lock.Lock()
+		ns.opened = true
+		db.lock.Unlock()
+	}
+	if err == nil && opts.history {
+		err = db.openNamespace(ctx, HistoryNamespace(namespace), historyNamespaceOptions(opts), HistoryItem{}, nil)
+	}
 	return err
 }
 
@@ -296,7 +591,8 @@ func (db *reindexerImpl) registerNamespace(ctx context.Context, namespace string
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.RegisterNamespace", otelattr.String("rx.ns", namespace)).End()
+		_, span := db.startTracingSpan(ctx, "Reindexer.RegisterNamespace", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -339,7 +635,7 @@ func (db *reindexerImpl) registerNamespaceImpl(namespace string, opts *Namespace
 				return ErrDeepCopyType
 			}
 		}
-		cacheItems, err = newCacheItems(opts.objCacheItemsCount)
+		cacheItems, err = newCacheItems(namespace, opts.objCacheItemsCount, db.itemCaches[namespace], db.memBudget)
 		if err != nil {
 			return err
 		}
@@ -353,42 +649,82 @@ func (db *reindexerImpl) registerNamespaceImpl(namespace string, opts *Namespace
 		opts:          *opts,
 		cjsonState:    cjson.NewState(),
 		deepCopyIface: haveDeepCopy,
-		nsHash:        db.nsHashCounter,
+		nsHash:        db.allocNsHash(),
 		opened:        false,
 	}
 
-	validator := cjson.Validator{}
-
-	if err = validator.Validate(s); err != nil {
+	if err = ns.parseStruct(s); err != nil {
 		return err
 	}
-	if ns.indexes, err = parseIndexes(namespace, ns.rtype, &ns.joined); err != nil {
-		return err
-	}
-	if schema := parseSchema(namespace, ns.rtype); schema != nil {
-		ns.schema = *schema
+	if cfg, ok := db.itemPools[namespace]; ok {
+		if ns.itemPool, err = newItemPool(t, cfg); err != nil {
+			return err
+		}
 	}
 
-	db.nsHashCounter++
+	if cacheItems.budget != nil {
+		cacheItems.budget.register(cacheItems)
+	}
+	db.nsHashes[ns.nsHash] = namespace
 	db.ns[namespace] = ns
 	return nil
 }
 
+// allocNsHash returns nsHash for the new namespace. Hash is taken from the counter, and if it's already used by another namespace
+// (e.g. after the counter overflow), the next free value is chosen, so the mapping is deterministic for the same sequence of namespaces.
+// db.lock must be held by the caller
+func (db *reindexerImpl) allocNsHash() int {
+	for {
+		if db.nsHashCounter < 0 {
+			db.nsHashCounter = 0
+		}
+		hash := db.nsHashCounter
+		db.nsHashCounter++
+		if _, used := db.nsHashes[hash]; !used {
+			return hash
+		}
+	}
+}
+
+// removeNs removes namespace from the registered ones and releases its nsHash. db.lock must be held by the caller
+func (db *reindexerImpl) removeNs(namespace string) {
+	if ns, ok := db.ns[namespace]; ok {
+		ns.cacheItems.close()
+		delete(db.nsHashes, ns.nsHash)
+	}
+	delete(db.ns, namespace)
+}
+
+// namespaceHashes returns nsHash of each registered namespace
+func (db *reindexerImpl) namespaceHashes() map[string]int {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	hashes := make(map[string]int, len(db.ns))
+	for name, ns := range db.ns {
+		hashes[name] = ns.nsHash
+	}
+	return hashes
+}
+
 // dropNamespace - drop whole namespace from DB
 func (db *reindexerImpl) dropNamespace(ctx context.Context, namespace string) error {
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.DropNamespace", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.DropNamespace", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("DropNamespace", namespace)).ObserveDuration()
 	}
+	defer db.startActivity(ctx, "DropNamespace", namespace)()
 
 	db.lock.Lock()
-	delete(db.ns, namespace)
+	db.removeNs(namespace)
 	db.lock.Unlock()
+	db.queryCache.invalidate(namespace)
 
 	return db.binding.DropNamespace(ctx, namespace)
 }
@@ -398,14 +734,25 @@ func (db *reindexerImpl) truncateNamespace(ctx context.Context, namespace string
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.TruncateNamespace", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.TruncateNamespace", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("TruncateNamespace", namespace)).ObserveDuration()
 	}
+	defer db.startActivity(ctx, "TruncateNamespace", namespace)()
 
-	return db.binding.TruncateNamespace(ctx, namespace)
+	if err := db.binding.TruncateNamespace(ctx, namespace); err != nil {
+		return err
+	}
+	// Ids of the removed items are reused by the new ones, so decoded items and payload types are dropped before returning to the caller
+	db.queryCache.invalidate(namespace)
+	if ns, err := db.getNS(namespace); err == nil {
+		db.resetNamespaceCaches(ctx, ns)
+	}
+	return nil
 }
 
 // RenameNamespace - Rename namespace. If namespace with dstNsName exists, then it is replaced.
@@ -414,26 +761,30 @@ func (db *reindexerImpl) renameNamespace(ctx context.Context, srcNsName string,
 	dstNsName = strings.ToLower(dstNsName)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.RenameNamespace", otelattr.String("rx.ns", srcNsName)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.RenameNamespace", otelattr.String("rx.ns", srcNsName))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("RenameNamespace", srcNsName)).ObserveDuration()
 	}
+	defer db.startActivity(ctx, "RenameNamespace", srcNsName)()
 
 	err := db.binding.RenameNamespace(ctx, srcNsName, dstNsName)
 	if err != nil {
 		return err
 	}
+	db.queryCache.invalidate(srcNsName)
+	db.queryCache.invalidate(dstNsName)
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
-	srcNs, ok := db.ns[srcNsName]
-	if ok {
+	db.removeNs(dstNsName)
+	if srcNs, ok := db.ns[srcNsName]; ok {
 		delete(db.ns, srcNsName)
 		db.ns[dstNsName] = srcNs
-	} else {
-		delete(db.ns, dstNsName)
+		db.nsHashes[srcNs.nsHash] = dstNsName
 	}
 	return err
 }
@@ -443,7 +794,9 @@ func (db *reindexerImpl) closeNamespace(ctx context.Context, namespace string) e
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.CloseNamespace", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.CloseNamespace", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -451,8 +804,9 @@ func (db *reindexerImpl) closeNamespace(ctx context.Context, namespace string) e
 	}
 
 	db.lock.Lock()
-	delete(db.ns, namespace)
+	db.removeNs(namespace)
 	db.lock.Unlock()
+	db.queryCache.invalidate(namespace)
 
 	return db.binding.CloseNamespace(ctx, namespace)
 }
@@ -463,7 +817,9 @@ func (db *reindexerImpl) upsert(ctx context.Context, namespace string, item inte
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Upsert", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Upsert", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -476,12 +832,14 @@ func (db *reindexerImpl) upsert(ctx context.Context, namespace string, item inte
 
 // insert item to namespace by PK
 // Item must be the same type as item passed to OpenNamespace, or []byte with json data
-// Return 0, if no item was inserted, 1 if item was inserted
+// Return 1 if item was inserted, or 0 and UniqueViolationError, if the item with the same primary key exists
 func (db *reindexerImpl) insert(ctx context.Context, namespace string, item interface{}, precepts ...string) (int, error) {
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Insert", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Insert", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -498,7 +856,9 @@ func (db *reindexerImpl) update(ctx context.Context, namespace string, item inte
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Update", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Update", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -514,7 +874,9 @@ func (db *reindexerImpl) delete(ctx context.Context, namespace string, item inte
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Delete", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Delete", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -525,6 +887,70 @@ func (db *reindexerImpl) delete(ctx context.Context, namespace string, item inte
 	return err
 }
 
+// getByPK - get item by values of primary key fields. Composite primary key requires values for all of it's parts in the index order
+func (db *reindexerImpl) getByPK(ctx context.Context, namespace string, parts ...interface{}) (interface{}, error) {
+	namespace = strings.ToLower(namespace)
+
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.GetByPK", otelattr.String("rx.ns", namespace))
+		defer span.End()
+	}
+
+	if db.promMetrics != nil {
+		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("GetByPK", namespace)).ObserveDuration()
+	}
+
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, indexDef := range ns.indexes {
+		if !indexDef.IsPK {
+			continue
+		}
+		if indexDef.FieldType != "composite" {
+			if len(parts) != 1 {
+				return nil, bindings.NewError(fmt.Sprintf("rq: Primary key '%s' expects 1 value, got %d", indexDef.Name, len(parts)), ErrCodeParams)
+			}
+			return db.query(namespace).Where(indexDef.Name, EQ, parts[0]).ExecCtx(ctx).FetchOne()
+		}
+		if len(parts) != len(indexDef.JSONPaths) {
+			return nil, bindings.NewError(fmt.Sprintf("rq: Composite primary key '%s' expects %d values, got %d", indexDef.Name, len(indexDef.JSONPaths), len(parts)), ErrCodeParams)
+		}
+		return db.query(namespace).WhereComposite(indexDef.Name, EQ, parts).ExecCtx(ctx).FetchOne()
+	}
+	return nil, ErrNoPK
+}
+
+// primaryKeyOf returns value of the primary key of the item, or values of its parts for composite primary key
+func (db *reindexerImpl) primaryKeyOf(namespace string, item interface{}) (interface{}, error) {
+	ns, err := db.getNS(strings.ToLower(namespace))
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf(item)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != ns.rtype || reflect.ValueOf(item).Kind() == reflect.Ptr && reflect.ValueOf(item).IsNil() {
+		return nil, ErrWrongType
+	}
+	if len(ns.pk) == 0 {
+		return nil, ErrNoPK
+	}
+	values := ns.pkValues(item)
+	if values == nil {
+		// Some of the pointers on the path to primary key field is nil
+		return nil, ErrNoPK
+	}
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return values, nil
+}
+
 // configureIndex - configure an index.
 // config argument must be struct with index configuration
 // Deprecated: Use UpdateIndex instead.
@@ -532,7 +958,9 @@ func (db *reindexerImpl) configureIndex(ctx context.Context, namespace, index st
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.ConfigureIndex", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.ConfigureIndex", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -559,12 +987,15 @@ func (db *reindexerImpl) addIndex(ctx context.Context, namespace string, indexDe
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.AddIndex", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.AddIndex", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("AddIndex", namespace)).ObserveDuration()
 	}
+	defer db.startActivity(ctx, "AddIndex", namespace)()
 
 	for _, index := range indexDef {
 		if err := db.binding.AddIndex(ctx, namespace, bindings.IndexDef(index)); err != nil {
@@ -579,12 +1010,15 @@ func (db *reindexerImpl) updateIndex(ctx context.Context, namespace string, inde
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.UpdateIndex", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.UpdateIndex", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("UpdateIndex", namespace)).ObserveDuration()
 	}
+	defer db.startActivity(ctx, "UpdateIndex", namespace)()
 
 	return db.binding.UpdateIndex(ctx, namespace, bindings.IndexDef(indexDef))
 }
@@ -594,12 +1028,15 @@ func (db *reindexerImpl) dropIndex(ctx context.Context, namespace, index string)
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.DropIndex", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.DropIndex", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("DropIndex", namespace)).ObserveDuration()
 	}
+	defer db.startActivity(ctx, "DropIndex", namespace)()
 
 	return db.binding.DropIndex(ctx, namespace, index)
 }
@@ -608,7 +1045,9 @@ func (db *reindexerImpl) putMeta(ctx context.Context, namespace, key string, dat
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.PutMeta", otelattr.String("rx.ns", namespace), otelattr.String("rx.meta.key", key)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.PutMeta", otelattr.String("rx.ns", namespace), otelattr.String("rx.meta.key", key))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -622,7 +1061,9 @@ func (db *reindexerImpl) getMeta(ctx context.Context, namespace, key string) ([]
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.GetMeta", otelattr.String("rx.ns", namespace), otelattr.String("rx.meta.key", key)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.GetMeta", otelattr.String("rx.ns", namespace), otelattr.String("rx.meta.key", key))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -661,45 +1102,9 @@ func loglevelToString(logLevel int) string {
 
 // setDefaultQueryDebug sets default debug level for queries to namespaces
 func (db *reindexerImpl) setDefaultQueryDebug(ctx context.Context, namespace string, level int) error {
-	citem := &DBConfigItem{Type: "namespaces"}
-	item, err := db.query(ConfigNamespaceName).WhereString("type", EQ, "namespaces").ExecCtx(ctx).FetchOne()
-	if err != nil {
-		return err
-	}
-
-	citem = item.(*DBConfigItem)
-	defaultCfg := DBNamespacesConfig{
-		JoinCacheMode:           "off",
-		StartCopyPolicyTxSize:   10000,
-		CopyPolicyMultiplier:    5,
-		TxSizeToAlwaysCopy:      100000,
-		OptimizationTimeout:     800,
-		OptimizationSortWorkers: 4,
-		WALSize:                 4000000,
-	}
-	found := false
-
-	if citem.Namespaces == nil {
-		namespaces := make([]DBNamespacesConfig, 0, 1)
-		citem.Namespaces = &namespaces
-	}
-
-	for i := range *citem.Namespaces {
-		switch (*citem.Namespaces)[i].Namespace {
-		case namespace:
-			(*citem.Namespaces)[i].LogLevel = loglevelToString(level)
-			found = true
-		case "*":
-			defaultCfg = (*citem.Namespaces)[i]
-		}
-	}
-	if !found {
-		nsCfg := defaultCfg
-		nsCfg.Namespace = namespace
-		nsCfg.LogLevel = loglevelToString(level)
-		*citem.Namespaces = append(*citem.Namespaces, nsCfg)
-	}
-	return db.upsert(ctx, ConfigNamespaceName, citem)
+	return db.setNamespaceConfig(ctx, namespace, func(cfg *DBNamespacesConfig) {
+		cfg.LogLevel = loglevelToString(level)
+	})
 }
 
 // query Create new Query for building request
@@ -718,22 +1123,45 @@ func (db *reindexerImpl) queryTx(namespace string, tx *Tx) *Query {
 // execSQL make query to database. Query is a SQL statement.
 // Return Iterator.
 func (db *reindexerImpl) execSQL(ctx context.Context, query string) *Iterator {
-	namespace := getQueryNamespace(query)
+	return db.execParsedSQL(ctx, query, getQueryNamespace(query), getQueryNamespaces(query))
+}
+
+// execParsedSQL executes SQL statement with namespaces, which are already extracted from the statement
+func (db *reindexerImpl) execParsedSQL(ctx context.Context, query string, namespace string, namespaces sqlNamespaces) (iter *Iterator) {
+	defer db.recoverPanicIterator("ExecSQL", &iter)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.ExecSQL", otelattr.String("rx.ns", namespace), otelattr.String("rx.sql", query)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.ExecSQL", otelattr.String("rx.ns", namespace), otelattr.String("rx.sql", query))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("ExecSQL", namespace)).ObserveDuration()
 	}
 
-	result, nsArray, err := db.prepareSQL(ctx, namespace, query, false)
+	result, nsArray, err := db.prepareSQL(ctx, namespace, namespaces, query, false)
 	if err != nil {
 		return errIterator(err)
 	}
+	if !isSelectSQL(query) {
+		// Namespace of UPDATE and DELETE statements is not known here
+		db.queryCache.reset()
+	}
 
-	iter := newIterator(ctx, db, namespace, nil, result, nsArray, nil, nil, nil)
+	var joinToFields []string
+	var joinHandlers []JoinHandler
+	if len(nsArray) > 1 {
+		joined := nsArray[1+len(namespaces.merged) : 1+len(namespaces.merged)+len(namespaces.joined)]
+		joinToFields = make([]string, 0, len(joined))
+		for _, joinedNs := range joined {
+			joinToFields = append(joinToFields, getJoinFieldName(nsArray[0].reindexerNamespace, joinedNs.reindexerNamespace))
+		}
+		joinHandlers = make([]JoinHandler, len(joinToFields))
+	}
+
+	iter = newIterator(ctx, db, namespace, nil, result, nsArray, joinToFields, joinHandlers, nil)
+	iter.sqlMerged = len(namespaces.merged)
 
 	return iter
 }
@@ -744,26 +1172,34 @@ func (db *reindexerImpl) execSQLToJSON(ctx context.Context, query string) *JSONI
 	namespace := getQueryNamespace(query)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.ExecSQLToJSON", otelattr.String("rx.ns", namespace), otelattr.String("rx.sql", query)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.ExecSQLToJSON", otelattr.String("rx.ns", namespace), otelattr.String("rx.sql", query))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
 		defer prometheus.NewTimer(db.promMetrics.clientCallsLatency.WithLabelValues("ExecSQLToJSON", namespace)).ObserveDuration()
 	}
 
-	result, _, err := db.prepareSQL(ctx, namespace, query, true)
+	result, _, err := db.prepareSQL(ctx, namespace, getQueryNamespaces(query), query, true)
 	if err != nil {
 		return errJSONIterator(err)
 	}
 
 	defer result.Free()
 
-	json, jsonOffsets, explain, err := db.rawResultToJson(result.GetBuf(), namespace, "total", nil, nil)
+	json, jsonOffsets, explain, aggs, err := db.rawResultToJson(result.GetBuf(), namespace, "total", "", nil, nil, nil)
 	if err != nil {
 		return errJSONIterator(err)
 	}
 
-	return newJSONIterator(ctx, nil, json, jsonOffsets, explain)
+	return newJSONIterator(ctx, nil, json, jsonOffsets, explain, aggs)
+}
+
+// isSelectSQL returns true, if SQL statement doesn't modify items
+func isSelectSQL(query string) bool {
+	fields := strings.Fields(query)
+	return len(fields) > 0 && (strings.EqualFold(fields[0], "select") || strings.EqualFold(fields[0], "explain"))
 }
 
 func getQueryNamespace(query string) string {
@@ -781,12 +1217,130 @@ func getQueryNamespace(query string) string {
 	return namespace
 }
 
+// sqlNamespaces - namespaces of SQL statement in the order of the namespaces of the results
+type sqlNamespaces struct {
+	// Namespaces of the merged queries
+	merged []string
+	// Namespaces, joined by the main query
+	joined []string
+	// Namespaces, joined by the merged queries
+	mergedJoined []string
+}
+
+// sqlFrame is the part of SQL statement in brackets
+type sqlFrame struct {
+	// Namespaces, joined by the query of the frame, are appended to joined. nil for the subqueries, which namespaces are not in the results
+	joined *[]string
+	// Namespace of the joined or merged subquery is appended to ns after FROM. nil, if it's already found or the frame is not the subquery
+	ns *[]string
+}
+
+// getQueryNamespaces returns namespaces of merged and joined queries of SQL statement. Statement is split into words and brackets,
+// string literals are skipped, so it's not the full parser: namespaces of unknown constructions are not returned
+func getQueryNamespaces(query string) sqlNamespaces {
+	var res sqlNamespaces
+	tokens := sqlTokens(strings.ToLower(query))
+	frames := []sqlFrame{{joined: &res.joined}}
+	for i, token := range tokens {
+		frame := &frames[len(frames)-1]
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		prev := ""
+		if i > 0 {
+			prev = tokens[i-1]
+		}
+		switch token {
+		case "(":
+			switch {
+			case prev == "join":
+				frames = append(frames, sqlFrame{ns: frame.joined})
+			case prev == "merge":
+				frames = append(frames, sqlFrame{joined: &res.mergedJoined, ns: &res.merged})
+			case next == "select":
+				// Subquery of the condition
+				frames = append(frames, sqlFrame{})
+			default:
+				frames = append(frames, sqlFrame{joined: frame.joined})
+			}
+		case ")":
+			if len(frames) > 1 {
+				frames = frames[:len(frames)-1]
+			}
+		case "join":
+			if frame.joined != nil && isSQLWord(next) {
+				*frame.joined = append(*frame.joined, next)
+			}
+		case "from":
+			if frame.ns != nil && isSQLWord(next) {
+				*frame.ns = append(*frame.ns, next)
+				frame.ns = nil
+			}
+		}
+	}
+	return res
+}
+
+// sqlTokens splits SQL statement into words, brackets and commas. String literals are skipped
+func sqlTokens(query string) []string {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			for i++; i < len(query) && query[i] != '\''; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, query[i:i+1])
+			i++
+		default:
+			end := i
+			for end < len(query) && !strings.ContainsRune(" \t\n\r'(),", rune(query[end])) {
+				end++
+			}
+			tokens = append(tokens, query[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
+
+func isSQLWord(token string) bool {
+	return token != "" && token != "(" && token != ")" && token != ","
+}
+
+// getJoinFieldName returns name of the joined field of the parent namespace's struct, which receives items of joined namespace.
+// Field, tagged by the joined namespace name, has priority over the other joined fields of the matching type
+func getJoinFieldName(parent *reindexerNamespace, joinedNs *reindexerNamespace) string {
+	for field := range parent.joined {
+		if strings.EqualFold(field, joinedNs.name) {
+			return field
+		}
+	}
+	for field, idx := range parent.joined {
+		if t := parent.rtype.FieldByIndex(idx).Type; t.Kind() == reflect.Slice &&
+			(t.Elem() == joinedNs.rtype || t.Elem() == reflect.PtrTo(joinedNs.rtype)) {
+			return field
+		}
+	}
+	return joinedNs.name
+}
+
 // beginTx - start update transaction
 func (db *reindexerImpl) beginTx(ctx context.Context, namespace string) (*Tx, error) {
 	namespace = strings.ToLower(namespace)
 
 	if db.otelTracer != nil {
-		defer db.startTracingSpan(ctx, "Reindexer.Tx.Begin", otelattr.String("rx.ns", namespace)).End()
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.Tx.Begin", otelattr.String("rx.ns", namespace))
+		defer span.End()
 	}
 
 	if db.promMetrics != nil {
@@ -807,6 +1361,9 @@ func (db *reindexerImpl) mustBeginTx(ctx context.Context, namespace string) *Tx
 }
 
 func (db *reindexerImpl) addFilterDSL(filter *dsl.Filter, q *Query) error {
+	if filter.FirstField != "" || filter.SecondField != "" {
+		return db.addBetweenFieldsFilterDSL(filter, q)
+	}
 	if filter.Field == "" {
 		return ErrEmptyFieldName
 	}
@@ -824,6 +1381,21 @@ func (db *reindexerImpl) addFilterDSL(filter *dsl.Filter, q *Query) error {
 	return nil
 }
 
+func (db *reindexerImpl) addBetweenFieldsFilterDSL(filter *dsl.Filter, q *Query) error {
+	if filter.FirstField == "" || filter.SecondField == "" {
+		return ErrEmptyFieldName
+	}
+	if filter.Field != "" || filter.Value != nil {
+		return bindings.NewError("rq: dsl filter can not contain both 'field' (or 'value') and 'first_field'/'second_field' at the same time", ErrCodeParams)
+	}
+	cond, err := GetCondType(filter.Cond)
+	if err != nil {
+		return err
+	}
+	q.WhereBetweenFields(filter.FirstField, cond, filter.SecondField)
+	return nil
+}
+
 func (db *reindexerImpl) addJoinedDSL(joined *dsl.JoinQuery, resultField string, q *Query) error {
 	if joined.Namespace == "" {
 		return ErrEmptyNamespace
@@ -909,15 +1481,7 @@ func (db *reindexerImpl) handleFiltersDSL(filters []dsl.Filter, joinIDs *map[str
 				return nil, bindings.NewError("rq: dsl filter can not contain both 'fielters' and 'join_query' at the same time", ErrCodeParams)
 			}
 
-			var joinedFieldName string
-			if v, found := (*joinIDs)[filter.Joined.Namespace]; found {
-				joinedFieldName = fmt.Sprintf("_dsl_joined_%s_%d", filter.Joined.Namespace, v)
-				(*joinIDs)[filter.Joined.Namespace] += 1
-			} else {
-				joinedFieldName = fmt.Sprintf("_dsl_joined_%s", filter.Joined.Namespace)
-				(*joinIDs)[filter.Joined.Namespace] = 0
-			}
-			err := db.addJoinedDSL(filter.Joined, joinedFieldName, q)
+			err := db.addJoinedDSL(filter.Joined, dslJoinedField(*joinIDs, filter.Joined.Namespace), q)
 			if err != nil {
 				return nil, err
 			}
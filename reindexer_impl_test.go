@@ -0,0 +1,33 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+)
+
+func TestNamespaceHashes(t *testing.T) {
+	db, _ := newMockDB(t, "hashes")
+	defer db.Close()
+
+	opts := reindexer.DefaultNamespaceOptions()
+	require.NoError(t, db.OpenNamespace("second", opts, testItem{}))
+	require.NoError(t, db.OpenNamespace("third", opts, testItem{}))
+	thirdHash := db.NamespaceHashes()["third"]
+	require.NoError(t, db.DropNamespace("second"))
+	require.NoError(t, db.RenameNamespace("third", testNs))
+	require.NoError(t, db.OpenNamespace("fourth", opts, testItem{}))
+
+	hashes := db.NamespaceHashes()
+	assert.NotContains(t, hashes, "second")
+	assert.NotContains(t, hashes, "third")
+	assert.Equal(t, thirdHash, hashes[testNs])
+	used := make(map[int]string)
+	for name, hash := range hashes {
+		assert.NotContains(t, used, hash, "hash of %s is used by %s", name, used[hash])
+		used[hash] = name
+	}
+}
@@ -0,0 +1,108 @@
+// Package reindexerhttp contains helpers to serve reindexer's queries via net/http
+package reindexerhttp
+
+import (
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+
+	"github.com/restream/reindexer/v3"
+)
+
+// QueryBuilder returns query for the request. nil query means, that the request is not valid
+type QueryBuilder func(r *http.Request) *reindexer.Query
+
+// QueryHandler returns handler, which executes the query, built for each request, and responds with its results in JSON format.
+// The query is executed with the request's context, so it's canceled, when the client goes away.
+// ETag of the response is derived from the query and LSNs of its namespaces, which are requested before the query, so requests
+// with matching If-None-Match get 304 Not Modified without execution of the query. Results are written directly to the response
+func QueryHandler(db *reindexer.Reindexer, build QueryBuilder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := build(r)
+		if q == nil {
+			http.Error(w, "Invalid query", http.StatusBadRequest)
+			return
+		}
+
+		etag, err := queryETag(db.WithContext(r.Context()), q)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+			if match := r.Header.Get("If-None-Match"); match != "" && match == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		rw := &resultsWriter{ResponseWriter: w}
+		if _, err := q.ExecToJsonStream(r.Context(), rw); err != nil && !rw.written {
+			writeError(w, r, err)
+		}
+	})
+}
+
+// resultsWriter sets headers of the results, which are written by single Write (see Query.ExecToJsonStream)
+type resultsWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *resultsWriter) Write(p []byte) (int, error) {
+	w.written = true
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(p)))
+	return w.ResponseWriter.Write(p)
+}
+
+// queryETag returns ETag for the results of the query: they are not changed, until the query's namespaces are modified.
+// Empty ETag is returned for the queries, which can't be represented by DSL
+func queryETag(db *reindexer.Reindexer, q *reindexer.Query) (string, error) {
+	dsl, err := q.DSL()
+	if err != nil {
+		return "", nil
+	}
+	namespaces := q.Namespaces()
+	s, err := db.BeginReadSnapshot(namespaces...)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	h.Write(dsl)
+	for _, namespace := range namespaces {
+		lsn, _ := s.LSN(namespace)
+		h.Write([]byte(namespace + ":" + strconv.Itoa(lsn.ServerId) + ":" + strconv.FormatInt(lsn.Counter, 10) + ";"))
+	}
+	// Items of the same results may be ordered differently without sorting, so the ETag is weak
+	return `W/"` + strconv.FormatUint(h.Sum64(), 16) + `"`, nil
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		// Client has gone away, nobody reads the response
+		return
+	}
+	http.Error(w, err.Error(), errorStatus(err))
+}
+
+// errorStatus returns HTTP status code for the query error
+func errorStatus(err error) int {
+	var rerr reindexer.Error
+	if !errors.As(err, &rerr) {
+		return http.StatusInternalServerError
+	}
+	switch rerr.Code() {
+	case reindexer.ErrCodeParams, reindexer.ErrCodeParseSQL, reindexer.ErrCodeParseDSL, reindexer.ErrCodeParseJson, reindexer.ErrCodeNotValid:
+		return http.StatusBadRequest
+	case reindexer.ErrCodeNotFound:
+		return http.StatusNotFound
+	case reindexer.ErrCodeForbidden:
+		return http.StatusForbidden
+	case reindexer.ErrCodeTimeout:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,116 @@
+package reindexerhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+type testHandlerItem struct {
+	ID   int    `reindex:"id,,pk" json:"id"`
+	Name string `reindex:"name" json:"name"`
+}
+
+const testHandlerNs = "items"
+
+func setTestLSN(srv *mock.Server, counter int64) {
+	stat := reindexer.NamespaceMemStat{Name: testHandlerNs}
+	stat.Replication.LastLSN = reindexer.LsnT{Counter: counter}
+	srv.SetResults(reindexer.MemstatsNamespaceName, stat)
+}
+
+func countSelects(srv *mock.Server) int {
+	count := 0
+	for _, call := range srv.CallsOf(mock.MethodSelectQuery) {
+		if call.Namespace == testHandlerNs {
+			count++
+		}
+	}
+	return count
+}
+
+func newTestHandler(t *testing.T) (http.Handler, *mock.Server) {
+	db, srv := mock.NewDB("reindexerhttp")
+	t.Cleanup(db.Close)
+	require.NoError(t, db.OpenNamespace(testHandlerNs, reindexer.DefaultNamespaceOptions(), testHandlerItem{}))
+	setTestLSN(srv, 1)
+
+	handler := QueryHandler(db, func(r *http.Request) *reindexer.Query {
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			return nil
+		}
+		return db.Query(testHandlerNs).WhereInt("id", reindexer.EQ, id)
+	})
+	return handler, srv
+}
+
+func TestQueryHandler(t *testing.T) {
+	handler, srv := newTestHandler(t)
+	srv.SetResults(testHandlerNs, testHandlerItem{ID: 1, Name: "first"})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?id=1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[{"id":1,"name":"first"}]}`, rec.Body.String())
+	assert.Equal(t, strconv.Itoa(rec.Body.Len()), rec.Header().Get("Content-Length"))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("matching ETag is not modified without the query", func(t *testing.T) {
+		selects := countSelects(srv)
+		req := httptest.NewRequest(http.MethodGet, "/items?id=1", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, selects, countSelects(srv))
+	})
+
+	t.Run("ETag depends on the query", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?id=2", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("ETag is changed with namespace's LSN", func(t *testing.T) {
+		srv.SetResults(testHandlerNs, testHandlerItem{ID: 1, Name: "updated"})
+		setTestLSN(srv, 2)
+		req := httptest.NewRequest(http.MethodGet, "/items?id=1", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"items":[{"id":1,"name":"updated"}]}`, rec.Body.String())
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+}
+
+func TestQueryHandlerErrors(t *testing.T) {
+	handler, srv := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?id=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	srv.SetError(mock.MethodSelectQuery, bindings.NewError("namespace not found", bindings.ErrNotFound))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?id=1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	srv.SetError(mock.MethodSelectQuery, errors.New("connection reset"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?id=1", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,41 @@
+package reindexer
+
+import (
+	"fmt"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// ErrResultTooLarge may be used with errors.Is to check, if the error is ResultSizeError
+var ErrResultTooLarge = bindings.NewError("rq: Query result is too large", ErrCodeLogic)
+
+// ResultSizeError is returned by the iterator, when the raw results, received by the query, exceed the limit, set by Query.MaxResultBytes
+type ResultSizeError struct {
+	Namespace string
+	// Limit of the query, bytes
+	Limit int64
+	// Size of the results, received before the limit was exceeded, bytes
+	Size int64
+}
+
+func (e *ResultSizeError) Error() string {
+	return fmt.Sprintf("rq: results of the query to '%s' exceed %d bytes limit (received %d bytes)", e.Namespace, e.Limit, e.Size)
+}
+
+// Code returns code of the error
+func (e *ResultSizeError) Code() int {
+	return ErrCodeLogic
+}
+
+func (e *ResultSizeError) Is(target error) bool {
+	return target == ErrResultTooLarge || isCodeError(target, e.Code())
+}
+
+// checkResultSize accounts chunk of size bytes, received by the query, and returns ResultSizeError, if the limit is exceeded
+func checkResultSize(q *Query, received *int64, size int) error {
+	*received += int64(size)
+	if q == nil || q.maxResultBytes <= 0 || *received <= q.maxResultBytes {
+		return nil
+	}
+	return &ResultSizeError{Namespace: q.Namespace, Limit: q.maxResultBytes, Size: *received}
+}
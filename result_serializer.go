@@ -14,6 +14,10 @@ type rawResultItemParams struct {
 	proc    int
 	cptr    uintptr
 	data    []byte
+	// data is item in msgpack format, which is not decoded into the struct
+	msgpack bool
+	// data is item in JSON format, which is not decoded (see Iterator.JSONBytes)
+	json bool
 }
 
 type rawResultsExtraParam struct {
@@ -62,8 +66,14 @@ func (s *resultSerializer) readRawtItemParams() (v rawResultItemParams) {
 	case bindings.ResultsPure:
 	case bindings.ResultsPtrs:
 		v.cptr = uintptr(s.GetUInt64())
-	case bindings.ResultsJson, bindings.ResultsCJson:
+	case bindings.ResultsCJson:
 		v.data = s.GetBytes()
+	case bindings.ResultsJson:
+		v.data = s.GetBytes()
+		v.json = true
+	case bindings.ResultsMsgPack:
+		v.data = s.GetBytes()
+		v.msgpack = true
 	}
 	return v
 }
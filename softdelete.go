@@ -0,0 +1,79 @@
+package reindexer
+
+import (
+	"context"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+var errSoftDeleteJSON = bindings.NewError("rq: Soft delete of items in JSON format is not supported", ErrCodeParams)
+
+// softDeleteField returns name of the soft delete field of the namespace, or empty string, if soft delete is not enabled
+func (db *reindexerImpl) softDeleteField(namespace string) string {
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return ""
+	}
+	return ns.opts.softDeleteField
+}
+
+// addSoftDeleteFilter adds condition, which excludes soft deleted items, to the query.
+// Condition is added once, so it's safe to call it several times for the same query
+func (q *Query) addSoftDeleteFilter() {
+	if q.withDeleted || q.softDeleteAdded {
+		return
+	}
+	q.softDeleteAdded = true
+	if field := q.db.softDeleteField(q.Namespace); field != "" {
+		q.markFiltersPos()
+		q.Not().WhereInt64(field, GT, 0)
+	}
+}
+
+// softDeleteItem marks item as deleted, by setting soft delete field to the current time
+func (db *reindexerImpl) softDeleteItem(ctx context.Context, ns *reindexerNamespace, item interface{}, precepts []string) (int, error) {
+	if _, isJSON := item.([]byte); isJSON {
+		return 0, errSoftDeleteJSON
+	}
+
+	// Items are deleted by primary key, like the items without soft delete, so default filters are not applied
+	q := db.query(ns.name).WithoutDefaults()
+	defer q.close()
+	if err := ns.wherePk(q, item); err != nil {
+		return 0, err
+	}
+	for _, precept := range precepts {
+		field, expr, ok := splitPrecept(precept)
+		if !ok {
+			return 0, bindings.NewError("rq: Invalid precept '"+precept+"'", ErrCodeParams)
+		}
+		q.SetExpression(field, expr)
+	}
+	return db.softDeleteQuery(ctx, q)
+}
+
+// softDeleteQuery marks items, which match the query, as deleted
+func (db *reindexerImpl) softDeleteQuery(ctx context.Context, q *Query) (int, error) {
+	q.SetExpression(db.softDeleteField(q.Namespace), "now(sec)")
+	it := db.updateQuery(ctx, q)
+	// Query is closed by the caller
+	it.query = nil
+	defer it.Close()
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	return it.Count(), nil
+}
+
+// purgeDeleted removes soft deleted items, which were deleted earlier than olderThan ago
+func (db *reindexerImpl) purgeDeleted(ctx context.Context, namespace string, olderThan time.Duration) (int, error) {
+	field := db.softDeleteField(namespace)
+	if field == "" {
+		return 0, bindings.NewError("rq: Soft delete is not enabled for namespace '"+namespace+"'", ErrCodeParams)
+	}
+	return db.query(namespace).WithDeleted().
+		WhereInt64(field, GT, 0).
+		WhereInt64(field, LT, time.Now().Add(-olderThan).Unix()).
+		DeleteCtx(ctx)
+}
@@ -0,0 +1,55 @@
+package reindexer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+type testSoftDeleteItem struct {
+	ID        int   `reindex:"id,,pk"`
+	DeletedAt int64 `reindex:"deleted_at"`
+}
+
+func TestSoftDeleteFilter(t *testing.T) {
+	db, srv := mock.NewDB("soft_delete")
+	defer db.Close()
+	require.NoError(t, db.OpenNamespace(testNs, reindexer.DefaultNamespaceOptions().WithSoftDelete("deleted_at"), testSoftDeleteItem{}))
+
+	_, err := db.Query(testNs).Exec().FetchAll()
+	require.NoError(t, err)
+	calls := srv.CallsOf(mock.MethodSelectQuery)
+	require.Len(t, calls, 1)
+	assert.Contains(t, string(calls[0].Query), "deleted_at")
+}
+
+func TestSoftDeleteFilterResume(t *testing.T) {
+	db, srv := mock.NewDB("soft_delete_resume")
+	defer db.Close()
+	require.NoError(t, db.OpenNamespace(testNs, reindexer.DefaultNamespaceOptions().WithSoftDelete("deleted_at"), testSoftDeleteItem{}))
+
+	srv.SetResults(testNs, testSoftDeleteItem{ID: 1}, testSoftDeleteItem{ID: 2}, testSoftDeleteItem{ID: 3})
+	srv.SetFetchLimit(2)
+	srv.SetError(mock.MethodFetchResults, errors.New("connection reset"))
+	it := db.Query(testNs).Sort("id", false).Exec()
+	defer it.Close()
+	for it.Next() {
+	}
+	require.Error(t, it.Error())
+	srv.SetError(mock.MethodFetchResults, nil)
+	require.NoError(t, it.Resume(context.Background()))
+
+	// Filter of the re-executed query is not duplicated
+	calls := srv.CallsOf(mock.MethodSelectQuery)
+	require.Len(t, calls, 2)
+	for _, call := range calls {
+		assert.Equal(t, 1, bytes.Count(call.Query, []byte("deleted_at")))
+	}
+}
@@ -0,0 +1,130 @@
+package reindexer
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// spillBuffer is a query result, which chunks are staged to the temporary file.
+// It reads chunks from the file one by one, so only one chunk is kept in memory
+type spillBuffer struct {
+	name   string
+	file   *os.File
+	reader *bufio.Reader
+	buf    []byte
+}
+
+// spillResult fetches all the chunks of the result from the server to the temporary file in dir and frees the result.
+// Results, which are not fetched by chunks (e.g. results of builtin binding), are returned as is
+func (db *reindexerImpl) spillResult(ctx context.Context, dir string, fetchCount int, result bindings.RawBuffer) (bindings.RawBuffer, error) {
+	fetchMore, ok := result.(bindings.FetchMore)
+	if !ok {
+		return result, nil
+	}
+	defer result.Free()
+
+	file, err := os.CreateTemp(dir, "rx_spill_*")
+	if err != nil {
+		return nil, err
+	}
+	// File is removed right away (if OS allows it), so it is deleted even if iterator is not closed
+	os.Remove(file.Name())
+	sb := &spillBuffer{name: file.Name(), file: file}
+
+	w := bufio.NewWriter(file)
+	err = forEachResultChunk(ctx, fetchMore, result, fetchCount, func(chunk []byte) error {
+		return writeSpillChunk(w, chunk)
+	})
+	if err != nil {
+		sb.Free()
+		return nil, err
+	}
+	if err = w.Flush(); err != nil {
+		sb.Free()
+		return nil, err
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		sb.Free()
+		return nil, err
+	}
+	sb.reader = bufio.NewReader(file)
+	if err = sb.next(); err != nil {
+		sb.Free()
+		return nil, err
+	}
+	return sb, nil
+}
+
+// forEachResultChunk calls fn for each chunk of the result. The next chunks are fetched from the server after fn returns
+func forEachResultChunk(ctx context.Context, fetchMore bindings.FetchMore, result bindings.RawBuffer, fetchCount int, fn func(chunk []byte) error) error {
+	for fetched := 0; ; {
+		buf := result.GetBuf()
+		if err := fn(buf); err != nil {
+			return err
+		}
+		ser := newSerializer(buf)
+		ser.GetVarUInt() // flags
+		ser.GetVarUInt() // totalcount
+		qcount := int(ser.GetVarUInt())
+		fetched += int(ser.GetVarUInt())
+		if fetched >= qcount {
+			return nil
+		}
+		if err := fetchMore.Fetch(ctx, fetched, fetchCount, false); err != nil {
+			return err
+		}
+	}
+}
+
+func writeSpillChunk(w io.Writer, chunk []byte) error {
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(chunk)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(chunk)
+	return err
+}
+
+// next reads the next chunk from the file
+func (sb *spillBuffer) next() error {
+	var size [8]byte
+	if _, err := io.ReadFull(sb.reader, size[:]); err != nil {
+		return err
+	}
+	n := int(binary.LittleEndian.Uint64(size[:]))
+	if cap(sb.buf) < n {
+		sb.buf = make([]byte, n)
+	}
+	sb.buf = sb.buf[:n]
+	_, err := io.ReadFull(sb.reader, sb.buf)
+	return err
+}
+
+func (sb *spillBuffer) GetBuf() []byte {
+	return sb.buf
+}
+
+// Fetch reads the next chunk from the file. Chunks are read sequentially, so offset and limit are ignored
+func (sb *spillBuffer) Fetch(ctx context.Context, offset, limit int, asJson bool) error {
+	if sb.reader == nil {
+		return bindings.NewError("rq: Spilled result is already freed", ErrCodeLogic)
+	}
+	return sb.next()
+}
+
+func (sb *spillBuffer) Free() {
+	if sb.file != nil {
+		sb.file.Close()
+		os.Remove(sb.name)
+		sb.file = nil
+	}
+	sb.reader = nil
+	sb.buf = nil
+}
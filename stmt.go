@@ -0,0 +1,172 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// Stmt is prepared SQL statement with positional placeholders ($1, $2, ...) for values.
+// Statement is parsed once by PrepareSQL, arguments are bound on the client side on each execution.
+// Stmt is safe for concurrent use
+type Stmt struct {
+	db *Reindexer
+	// Serialized statement without placeholders. Values of the arguments are inserted at positions of the placeholders on each execution
+	sql []byte
+	// Placeholders of the statement in order of their positions
+	params []stmtParam
+	// Count of arguments, required by the statement
+	argsCount  int
+	namespace  string
+	namespaces sqlNamespaces
+}
+
+// stmtParam is the placeholder of the statement
+type stmtParam struct {
+	// Position of the placeholder in the serialized statement
+	pos int
+	// Index of the argument
+	arg int
+}
+
+// PrepareSQL parses SQL statement with positional placeholders ($1, $2, ...) for values.
+// Placeholders may be used only for values (not for names of namespaces and fields)
+func (db *Reindexer) PrepareSQL(query string) (*Stmt, error) {
+	stmt := &Stmt{
+		db:         db,
+		namespace:  getQueryNamespace(query),
+		namespaces: getQueryNamespaces(query),
+	}
+	sql := make([]byte, 0, len(query))
+	// Only string literals are quoted by ' and `. " quotes names, which can't contain placeholders
+	var quote byte
+	last := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '`':
+			quote = c
+		case c == '$':
+			end := i + 1
+			for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+				end++
+			}
+			n, err := strconv.Atoi(query[i+1 : end])
+			if err != nil || n < 1 {
+				return nil, bindings.NewError(fmt.Sprintf("rq: invalid placeholder at position %d of SQL statement", i), ErrCodeParams)
+			}
+			sql = append(sql, query[last:i]...)
+			stmt.params = append(stmt.params, stmtParam{pos: len(sql), arg: n - 1})
+			if n > stmt.argsCount {
+				stmt.argsCount = n
+			}
+			last = end
+			i = end - 1
+		}
+	}
+	stmt.sql = append(sql, query[last:]...)
+
+	used := make([]bool, stmt.argsCount)
+	for _, p := range stmt.params {
+		used[p.arg] = true
+	}
+	for i, u := range used {
+		if !u {
+			return nil, bindings.NewError(fmt.Sprintf("rq: placeholder $%d is not used in SQL statement", i+1), ErrCodeParams)
+		}
+	}
+	return stmt, nil
+}
+
+// Exec executes the statement with the arguments. Return Iterator.
+func (s *Stmt) Exec(args ...interface{}) *Iterator {
+	return s.ExecCtx(s.db.ctx, args...)
+}
+
+// ExecCtx executes the statement with the arguments. Return Iterator.
+func (s *Stmt) ExecCtx(ctx context.Context, args ...interface{}) *Iterator {
+	query, err := s.bind(args)
+	if err != nil {
+		return errIterator(err)
+	}
+	return s.db.impl.execParsedSQL(ctx, query, s.namespace, s.namespaces)
+}
+
+// bind returns SQL statement with values of the arguments, inserted at positions of the placeholders
+func (s *Stmt) bind(args []interface{}) (string, error) {
+	if len(args) != s.argsCount {
+		return "", bindings.NewError(fmt.Sprintf("rq: SQL statement expects %d arguments, got %d", s.argsCount, len(args)), ErrCodeParams)
+	}
+	var sb strings.Builder
+	sb.Grow(len(s.sql) + 8*len(s.params))
+	prev := 0
+	for _, p := range s.params {
+		sb.Write(s.sql[prev:p.pos])
+		if err := writeSQLValue(&sb, args[p.arg]); err != nil {
+			return "", err
+		}
+		prev = p.pos
+	}
+	sb.Write(s.sql[prev:])
+	return sb.String(), nil
+}
+
+// writeSQLValue writes SQL literal of the value. Slices and arrays are written as lists of values (e.g. for IN condition)
+func writeSQLValue(sb *strings.Builder, value interface{}) error {
+	if value == nil {
+		sb.WriteString("null")
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			sb.WriteString("null")
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		sb.WriteByte('\'')
+		str := v.String()
+		for i := 0; i < len(str); i++ {
+			if str[i] == '\'' || str[i] == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(str[i])
+		}
+		sb.WriteByte('\'')
+	case reflect.Bool:
+		sb.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sb.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		sb.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		sb.WriteString(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()))
+	case reflect.Slice, reflect.Array:
+		sb.WriteByte('(')
+		for i := 0; i < v.Len(); i++ {
+			if i != 0 {
+				sb.WriteByte(',')
+			}
+			if err := writeSQLValue(sb, v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(')')
+	default:
+		return bindings.NewError(fmt.Sprintf("rq: unsupported type of SQL argument: %s", v.Type()), ErrCodeParams)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package reindexer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+var knownIndexTypes = map[string]bool{
+	"":          true,
+	"-":         true,
+	"hash":      true,
+	"tree":      true,
+	"text":      true,
+	"fuzzytext": true,
+	"ttl":       true,
+	"rtree":     true,
+}
+
+// TagsValidationError is returned by OpenNamespace with StrictTags option, if tags of the struct contain mistakes.
+// It contains all the found mistakes
+type TagsValidationError struct {
+	// Name of the struct type
+	Type   string
+	Errors []error
+}
+
+func (e *TagsValidationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "rq: Invalid reindex tags of struct '%s':", e.Type)
+	for _, err := range e.Errors {
+		sb.WriteString("\n - ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Code returns ErrCodeParams
+func (e *TagsValidationError) Code() int {
+	return ErrCodeParams
+}
+
+func (e *TagsValidationError) Is(target error) bool {
+	return isCodeError(target, e.Code())
+}
+
+type strictIndex struct {
+	field      string
+	appendable bool
+}
+
+type strictTagsValidator struct {
+	errs       []error
+	indexes    map[string]strictIndex
+	jsonPaths  map[string]bool
+	composites map[string][]string
+	visited    map[reflect.Type]bool
+}
+
+// validateTagsStrict checks tags of the struct for mistakes, which are silently accepted (or partially applied) by default:
+// unknown index types and options, duplicate index names, composite indexes over missing fields, pk on arrays, etc
+func validateTagsStrict(st reflect.Type) error {
+	v := &strictTagsValidator{
+		indexes:    make(map[string]strictIndex),
+		jsonPaths:  make(map[string]bool),
+		composites: make(map[string][]string),
+		visited:    make(map[reflect.Type]bool),
+	}
+	v.walk(st, "", "", false)
+
+	names := make([]string, 0, len(v.composites))
+	for name := range v.composites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, part := range v.composites[name] {
+			if _, ok := v.indexes[part]; !ok && !v.jsonPaths[part] {
+				v.errorf("Composite index '%s' refers to missing field '%s'", name, part)
+			}
+		}
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &TagsValidationError{Type: st.Name(), Errors: v.errs}
+}
+
+func (v *strictTagsValidator) errorf(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *strictTagsValidator) walk(st reflect.Type, reindexBasePath, jsonBasePath string, subArray bool) {
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if v.visited[st] {
+		return
+	}
+	v.visited[st] = true
+	defer delete(v.visited, st)
+
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		t := sf.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		tag, hasTag := sf.Tag.Lookup("reindex")
+
+		jsonPath := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if jsonPath == "-" {
+			if hasTag && tag != "-" {
+				v.errorf("Field %s.%s is skipped by json tag, but has reindex tag '%s'", st.Name(), sf.Name, tag)
+			}
+			continue
+		}
+		flatten, prefix := cjson.FieldEmbedding(sf)
+		if len(jsonPath) == 0 && !flatten {
+			jsonPath = sf.Name
+		}
+		jsonPath = jsonBasePath + jsonPath
+
+		if sf.PkgPath != "" && !sf.Anonymous {
+			if hasTag && sf.Name != "_" {
+				v.errorf("Field %s.%s is unexported, but has reindex tag '%s'", st.Name(), sf.Name, tag)
+			}
+			if sf.Name != "_" || !hasTag {
+				continue
+			}
+		}
+		v.jsonPaths[jsonPath] = true
+
+		idxName, idxType, _, idxSettings := parseRxTags(sf)
+		if idxName == "-" {
+			if strings.TrimSpace(tag) != "-" {
+				v.errorf("Field %s.%s is not indexed, but has reindex tag '%s'", st.Name(), sf.Name, tag)
+			}
+			continue
+		}
+		reindexPath := reindexBasePath + idxName
+		fieldName := st.Name() + "." + sf.Name
+
+		if !knownIndexTypes[idxType] {
+			v.errorf("Unknown index type '%s' of field %s", idxType, fieldName)
+		}
+		if _, _, err := parseEmbedding(sf, &idxSettings); err != nil {
+			v.errorf("%s", err.Error())
+		}
+		isArray := subArray || t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+		opts := parseOpts(&idxSettings)
+		isComposite := parseByKeyWord(&idxSettings, "composite")
+		isJoined := parseByKeyWord(&idxSettings, "joined")
+		collateMode, unknown := v.parseCollate(idxSettings, fieldName)
+		for _, opt := range unknown {
+			v.errorf("Unknown option '%s' of field %s", opt, fieldName)
+		}
+
+		switch {
+		case isComposite:
+			if t.Kind() != reflect.Struct || t.NumField() != 0 {
+				v.errorf("Composite index '%s' must be declared on empty struct field, not on %s", idxName, fieldName)
+			}
+			name := parseCompositeName(reindexPath)
+			v.addIndex(name, fieldName, opts.isAppenable)
+			v.composites[name] = parseCompositeJsonPaths(reindexPath)
+		case isJoined:
+			if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Ptr || t.Elem().Elem().Kind() != reflect.Struct {
+				v.errorf("Joined field %s must have type []*SubitemType, not %s", fieldName, sf.Type)
+			}
+			if opts.isPk {
+				v.errorf("Joined field %s can't be a primary key", fieldName)
+			}
+		case t.Kind() == reflect.Struct && idxType != "rtree":
+			if opts.isPk {
+				v.errorf("Primary key option on struct field %s is ignored. Mark fields of the nested struct or use composite index", fieldName)
+			}
+			if idxType != "" {
+				v.errorf("Index type '%s' on struct field %s is ignored", idxType, fieldName)
+			}
+			reindexFieldsPath, jsonFieldsPath := embeddedBasePaths(reindexPath, jsonBasePath, jsonPath, flatten, prefix)
+			v.walk(t, reindexFieldsPath, jsonFieldsPath, subArray)
+		case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) &&
+			(t.Elem().Kind() == reflect.Struct || (t.Elem().Kind() == reflect.Ptr && t.Elem().Elem().Kind() == reflect.Struct)):
+			if opts.isPk {
+				v.errorf("Primary key can't be an array: field %s", fieldName)
+			}
+			v.walk(t.Elem(), nestedBasePath(reindexPath), nestedBasePath(jsonPath), true)
+		case len(idxName) > 0:
+			if opts.isPk && isArray && idxType != "rtree" {
+				v.errorf("Primary key can't be an array: field %s", fieldName)
+			}
+			if opts.isPk && opts.isSparse {
+				v.errorf("Primary key can't be sparse: field %s", fieldName)
+			}
+			if idxType == "ttl" && t.Kind() != reflect.Int64 && t.Kind() != reflect.Int {
+				v.errorf("TTL index must be declared on int64 field, not on %s of type %s", fieldName, sf.Type)
+			}
+			if collateMode != CollateNone && t.Kind() != reflect.String && !(isArray && t.Elem().Kind() == reflect.String) {
+				v.errorf("Collate option is allowed only for string fields: field %s has type %s", fieldName, sf.Type)
+			}
+			v.addIndex(reindexPath, fieldName, opts.isAppenable)
+		case tag != "":
+			v.errorf("Field %s has reindex tag '%s' without index name", fieldName, tag)
+		}
+	}
+}
+
+func (v *strictTagsValidator) addIndex(name, field string, appendable bool) {
+	if prev, ok := v.indexes[name]; ok {
+		if !prev.appendable || !appendable {
+			v.errorf("Duplicate index name '%s' on fields %s and %s. Use 'appendable' option to combine fields into single index", name, prev.field, field)
+		}
+		return
+	}
+	v.indexes[name] = strictIndex{field: field, appendable: appendable}
+}
+
+// parseCollate returns collate mode and options, which are not collate options. Conflicting collate options are reported
+func (v *strictTagsValidator) parseCollate(idxSettings []string, fieldName string) (collateMode int, unknown []string) {
+	collateMode = CollateNone
+	for _, opt := range idxSettings {
+		settings := []string{opt}
+		mode, _ := parseCollate(&settings)
+		if len(settings) != 0 {
+			unknown = append(unknown, opt)
+			continue
+		}
+		if collateMode != CollateNone {
+			v.errorf("Several collate options are set for field %s", fieldName)
+		}
+		collateMode = mode
+	}
+	return collateMode, unknown
+}
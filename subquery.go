@@ -0,0 +1,128 @@
+package reindexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/cjson"
+)
+
+// subQueryEntry is the condition of WhereQuery, which is inserted into the serialized query, when the subquery is executed
+type subQueryEntry struct {
+	// Position of the condition in the serialized query
+	pos       int
+	op        int
+	field     string
+	condition int
+	query     *Query
+}
+
+// WhereQuery - Add where condition to DB query with values, returned by the subquery
+// (e.g. 'id IN (SELECT user_id FROM orders WHERE ...)').
+// Subquery must select exactly one field by Select. Server doesn't support subqueries, so the subquery is executed
+// by the separate request just before the query (and is not atomic with it). Subquery is closed after execution
+func (q *Query) WhereQuery(field string, condition int, subQuery *Query) *Query {
+	q.subQueries = append(q.subQueries, subQueryEntry{
+		pos:       len(q.ser.Bytes()),
+		op:        q.nextOp,
+		field:     field,
+		condition: condition,
+		query:     subQuery,
+	})
+	q.nextOp = opAND
+	q.queriesCount++
+	return q
+}
+
+// resolveSubQueries executes subqueries of the query (and of its joined and merged queries)
+// and inserts conditions with their results into the serialized query
+func (q *Query) resolveSubQueries(ctx context.Context) error {
+	for _, jq := range q.joinQueries {
+		if err := jq.resolveSubQueries(ctx); err != nil {
+			return err
+		}
+	}
+	for _, mq := range q.mergedQueries {
+		if err := mq.resolveSubQueries(ctx); err != nil {
+			return err
+		}
+	}
+	if len(q.subQueries) == 0 {
+		return nil
+	}
+
+	buf := q.ser.Bytes()
+	res := make([]byte, 0, len(buf))
+	prev := 0
+	for i, sq := range q.subQueries {
+		values, err := sq.query.subQueryValues(ctx)
+		if err != nil {
+			for _, rest := range q.subQueries[i+1:] {
+				rest.query.close()
+			}
+			q.subQueries = q.subQueries[:0]
+			return err
+		}
+		cond := &Query{ser: cjson.NewSerializer(nil), nextOp: sq.op}
+		cond.Where(sq.field, sq.condition, values)
+		res = append(res, buf[prev:sq.pos]...)
+		res = append(res, cond.ser.Bytes()...)
+		prev = sq.pos
+	}
+	res = append(res, buf[prev:]...)
+	q.ser = cjson.NewSerializer(res)
+	q.subQueries = q.subQueries[:0]
+	return nil
+}
+
+// subQueryValues executes the subquery and returns values of its selected field
+func (q *Query) subQueryValues(ctx context.Context) ([]interface{}, error) {
+	if len(q.selectFilter) != 1 {
+		q.close()
+		return nil, bindings.NewError("rq: Subquery must select exactly one field", ErrCodeParams)
+	}
+
+	it := q.ExecToJsonCtx(ctx)
+	defer it.Close()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, it.Count())
+	for it.Next() {
+		// Results contain only the selected field, so all the scalars of the item are its values
+		var item interface{}
+		dec := json.NewDecoder(bytes.NewReader(it.JSON()))
+		dec.UseNumber()
+		if err := dec.Decode(&item); err != nil {
+			return nil, err
+		}
+		values = appendJSONScalars(values, item)
+	}
+	return values, it.Error()
+}
+
+// appendJSONScalars appends all the scalar values of decoded JSON. Integer numbers are converted to int64
+func appendJSONScalars(values []interface{}, v interface{}) []interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, field := range v {
+			values = appendJSONScalars(values, field)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			values = appendJSONScalars(values, elem)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			values = append(values, i)
+		} else if f, err := v.Float64(); err == nil {
+			values = append(values, f)
+		}
+	case nil:
+	default:
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,360 @@
+package reindexer
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+const (
+	defaultSubscribeInterval   = time.Second
+	defaultSubscribeBufferSize = 100
+)
+
+var errSubscribeWithoutLSN = bindings.NewError("rq: Subscribe requires LSN of WAL records, which are returned by cproto binding only", ErrCodeParams)
+
+// UpdateEvent - change of the namespace, delivered by subscription
+type UpdateEvent struct {
+	Namespace string
+	// Modification: "Insert", "Update", "Delete" or "Reset". "Reset" means, that changes since SubscribeOptions.FromLSN
+	// are not available in the namespace's WAL anymore, or the namespace was modified by update/delete query or truncated:
+	// subscriber should drop its state, and all the current items are delivered as "Insert" after it
+	Op string
+	// Current item for "Insert" and "Update", item with the primary key's fields for "Delete". nil for "Reset"
+	Item interface{}
+	// LSN of the WAL record of the change
+	LSN LsnT
+}
+
+// SubscribeOptions is options of the namespace's subscription
+type SubscribeOptions struct {
+	// Filter adds conditions to the query of the namespace, so only changes of the matching items are delivered. nil means all the items
+	Filter func(q *Query) *Query
+	// Interval of polling of the namespace's WAL. Default is 1 second
+	Interval time.Duration
+	// LSN of the last event, received by the previous subscription. Changes after it are read from the namespace's WAL, and if they are
+	// not available anymore, "Reset" event is delivered first. nil means, that only the changes after Subscribe are delivered
+	FromLSN *LsnT
+	// Size of the events channel buffer. Default is 100
+	BufferSize int
+	// OnError is called on errors of polling (e.g. while connection is lost). Polling is retried after Interval
+	OnError func(err error)
+}
+
+type subscription struct {
+	db        *reindexerImpl
+	ns        *reindexerNamespace
+	namespace string
+	opts      SubscribeOptions
+	// LSN counter of the last read WAL record
+	lsn int64
+	// Primary keys of the items, which match SubscribeOptions.Filter. It's used to deliver "Delete", when the modified item doesn't
+	// match the filter anymore. nil without the filter
+	matched map[string]struct{}
+	// onChange is called instead of delivering of the events, when the namespace is changed (see watchQueryCache)
+	onChange func()
+}
+
+// subscribe starts polling of the namespace's WAL. Current state of the namespace is read synchronously, so errors are returned
+func (db *reindexerImpl) subscribe(ctx context.Context, namespace string, opts *SubscribeOptions) (<-chan UpdateEvent, error) {
+	namespace = strings.ToLower(namespace)
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(ns.pk) == 0 {
+		return nil, ErrNoPK
+	}
+	if _, withLSN := db.binding.(bindings.RawBindingResultsFlags); !withLSN {
+		return nil, errSubscribeWithoutLSN
+	}
+
+	s := &subscription{db: db, ns: ns, namespace: namespace}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Interval <= 0 {
+		s.opts.Interval = defaultSubscribeInterval
+	}
+	if s.opts.BufferSize <= 0 {
+		s.opts.BufferSize = defaultSubscribeBufferSize
+	}
+
+	if s.opts.FromLSN != nil {
+		s.lsn = s.opts.FromLSN.Counter
+	} else {
+		lsn, err := s.currentLSN(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.lsn = lsn.Counter
+	}
+	if s.opts.Filter != nil {
+		items, err := s.currentItems(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.setMatched(items)
+	}
+
+	ch := make(chan UpdateEvent, s.opts.BufferSize)
+	go func() {
+		defer close(ch)
+		s.run(ctx, ch)
+	}()
+	return ch, nil
+}
+
+// run polls the namespace's WAL and delivers changes of the items from its records
+func (s *subscription) run(ctx context.Context, ch chan<- UpdateEvent) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.poll(ctx, ch); err != nil {
+			s.onError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the namespace's WAL records after the last read one and delivers their changes
+func (s *subscription) poll(ctx context.Context, ch chan<- UpdateEvent) error {
+	it := s.db.walQuery(ctx, s.namespace, s.lsn)
+	var records []WALRecord
+	for it.Next() {
+		records = append(records, it.Record())
+	}
+	err := it.Error()
+	it.Close()
+	if rerr, ok := err.(bindings.Error); ok && rerr.Code() == bindings.ErrOutdatedWAL {
+		return s.reset(ctx, ch)
+	} else if err != nil {
+		return err
+	}
+	if s.onChange != nil {
+		if len(records) != 0 {
+			s.lsn = records[len(records)-1].LSN
+			s.onChange()
+		}
+		return nil
+	}
+
+	for len(records) > 0 {
+		n := 1
+		switch records[0].Type {
+		case WALItemModify:
+			for n < len(records) && records[n].Type == WALItemModify {
+				n++
+			}
+			if err := s.sendModified(ctx, ch, records[:n]); err != nil {
+				return err
+			}
+		case WALTruncate, WALUpdateQuery:
+			// Items, modified by the query, are unknown, so the current items are delivered
+			return s.reset(ctx, ch)
+		}
+		s.lsn = records[n-1].LSN
+		records = records[n:]
+	}
+	return nil
+}
+
+// sendModified delivers changes of the items of the consecutive WALItemModify records. With the filter current states of the items
+// are requested by one query
+func (s *subscription) sendModified(ctx context.Context, ch chan<- UpdateEvent, records []WALRecord) error {
+	items := make([]interface{}, len(records))
+	keys := make([]string, len(records))
+	var modified []interface{}
+	for i := range records {
+		item := reflect.New(s.ns.rtype).Interface()
+		if err := json.Unmarshal(records[i].Item, item); err != nil {
+			// Broken record is skipped, so the subscription is not stuck on it
+			s.onError(ctx, bindings.NewError("rq: Can't decode item of WAL record: "+err.Error(), ErrCodeParseJson))
+			continue
+		}
+		key, err := s.ns.pkKey(item)
+		if err != nil {
+			s.onError(ctx, err)
+			continue
+		}
+		items[i], keys[i] = item, key
+		if records[i].Mode != modifyModeNames[modeDelete] {
+			modified = append(modified, item)
+		}
+	}
+
+	var current map[string]interface{}
+	if s.matched != nil && len(modified) != 0 {
+		var err error
+		if current, err = s.currentItemsOf(ctx, modified); err != nil {
+			return err
+		}
+	}
+
+	for i, record := range records {
+		if items[i] == nil {
+			continue
+		}
+		event := UpdateEvent{Namespace: s.namespace, Op: "Update", Item: items[i], LSN: LsnT{Counter: record.LSN}}
+		switch {
+		case record.Mode == modifyModeNames[modeDelete]:
+			if s.matched != nil {
+				if _, ok := s.matched[keys[i]]; !ok {
+					continue
+				}
+				delete(s.matched, keys[i])
+			}
+			event.Op = "Delete"
+		case s.matched != nil:
+			_, wasMatched := s.matched[keys[i]]
+			item, ok := current[keys[i]]
+			switch {
+			case ok && wasMatched:
+				event.Item = item
+			case ok:
+				s.matched[keys[i]] = struct{}{}
+				event.Op, event.Item = "Insert", item
+			case wasMatched:
+				// Item doesn't match the filter after the change
+				delete(s.matched, keys[i])
+				event.Op = "Delete"
+			default:
+				continue
+			}
+		case record.Mode == modifyModeNames[modeInsert]:
+			event.Op = "Insert"
+		}
+		if err := s.send(ctx, ch, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset delivers "Reset" and all the current items as "Insert". WAL is read from the namespace's LSN, which is requested before the items
+func (s *subscription) reset(ctx context.Context, ch chan<- UpdateEvent) error {
+	lsn, err := s.currentLSN(ctx)
+	if err != nil {
+		return err
+	}
+	if s.onChange != nil {
+		s.lsn = lsn.Counter
+		s.onChange()
+		return nil
+	}
+	items, err := s.currentItems(ctx)
+	if err != nil {
+		return err
+	}
+	s.lsn = lsn.Counter
+	if s.matched != nil {
+		s.setMatched(items)
+	}
+	if err = s.send(ctx, ch, UpdateEvent{Namespace: s.namespace, Op: "Reset", LSN: lsn}); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err = s.send(ctx, ch, UpdateEvent{Namespace: s.namespace, Op: "Insert", Item: item, LSN: lsn}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchQueryCache starts watching of the namespaces' WAL, so cached query results are invalidated on writes of the other clients.
+// Without LSN of WAL records (builtin binding) only writes of this client are tracked
+func (db *reindexerImpl) watchQueryCache(namespaces []string) {
+	if _, withLSN := db.binding.(bindings.RawBindingResultsFlags); !withLSN {
+		return
+	}
+	ctx := db.queryCache.ctx
+	for _, namespace := range namespaces {
+		if !db.queryCache.setWatched(namespace, true) {
+			continue
+		}
+		namespace := namespace
+		s := &subscription{db: db, namespace: namespace, opts: SubscribeOptions{Interval: defaultSubscribeInterval}}
+		s.onChange = func() { db.queryCache.invalidate(namespace) }
+		// Results, which were cached before the start LSN, may miss the changes, so they are invalidated too
+		if err := s.reset(ctx, nil); err != nil {
+			db.queryCache.setWatched(namespace, false)
+			continue
+		}
+		go s.run(ctx, nil)
+	}
+}
+
+func (s *subscription) query() *Query {
+	q := s.db.query(s.namespace)
+	if s.opts.Filter != nil {
+		q = s.opts.Filter(q)
+	}
+	return q
+}
+
+// currentItems returns the items, which match the filter
+func (s *subscription) currentItems(ctx context.Context) ([]interface{}, error) {
+	return s.query().ExecCtx(ctx).FetchAll()
+}
+
+// currentItemsOf returns current states of the items, which match the filter, by their primary keys
+func (s *subscription) currentItemsOf(ctx context.Context, items []interface{}) (map[string]interface{}, error) {
+	q := s.query()
+	if err := s.ns.wherePks(q, items); err != nil {
+		q.close()
+		return nil, err
+	}
+	found, err := q.ExecCtx(ctx).FetchAll()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]interface{}, len(found))
+	for _, item := range found {
+		if key, err := s.ns.pkKey(item); err == nil {
+			current[key] = item
+		}
+	}
+	return current, nil
+}
+
+func (s *subscription) setMatched(items []interface{}) {
+	s.matched = make(map[string]struct{}, len(items))
+	for _, item := range items {
+		if key, err := s.ns.pkKey(item); err == nil {
+			s.matched[key] = struct{}{}
+		}
+	}
+}
+
+// currentLSN returns LSN of the last modification of the namespace
+func (s *subscription) currentLSN(ctx context.Context) (LsnT, error) {
+	lsns, err := s.db.namespacesLSN(ctx, []string{s.namespace})
+	if err != nil {
+		return LsnT{}, err
+	}
+	return lsns[s.namespace], nil
+}
+
+func (s *subscription) onError(ctx context.Context, err error) {
+	if s.opts.OnError != nil && ctx.Err() == nil {
+		s.opts.OnError(err)
+	}
+}
+
+// send delivers the event. Returns error, if the subscription is canceled
+func (s *subscription) send(ctx context.Context, ch chan<- UpdateEvent, event UpdateEvent) error {
+	select {
+	case ch <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
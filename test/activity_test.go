@@ -0,0 +1,53 @@
+package reindexer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemActivity struct {
+	ID int `reindex:"id,,pk"`
+}
+
+const testActivityNs = "test_items_activity"
+
+func TestActivity(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithNamespaceRateLimit(testActivityNs, 1, 1))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+	require.NoError(t, rx.OpenNamespace(testActivityNs, reindexer.DefaultNamespaceOptions(), TestItemActivity{}))
+	defer rx.DropNamespace(testActivityNs)
+	assert.Empty(t, rx.Activity(context.Background()))
+
+	// The first upsert takes the only token of the rate limiter, so the second one waits
+	require.NoError(t, rx.Upsert(testActivityNs, TestItemActivity{ID: 1}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- rx.WithContext(ctx).Upsert(testActivityNs, TestItemActivity{ID: 2})
+	}()
+
+	var activity []reindexer.Activity
+	require.Eventually(t, func() bool {
+		activity = rx.Activity(context.Background())
+		return len(activity) > 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Upsert", activity[0].Op)
+	assert.Equal(t, testActivityNs, activity[0].Namespace)
+	deadline, _ := ctx.Deadline()
+	assert.Equal(t, deadline, activity[0].Deadline)
+
+	require.NoError(t, <-done)
+	assert.Empty(t, rx.Activity(context.Background()))
+}
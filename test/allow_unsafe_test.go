@@ -0,0 +1,66 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemAllowUnsafe struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testAllowUnsafeNs = "test_items_allow_unsafe"
+
+func TestAllowUnsafe(t *testing.T) {
+	fetchFirst := func(t *testing.T, it *reindexer.Iterator) *TestItemAllowUnsafe {
+		items, err := it.FetchAll()
+		require.NoError(t, err)
+		require.NotEmpty(t, items)
+		return items[0].(*TestItemAllowUnsafe)
+	}
+	openNs := func(t *testing.T, rx *reindexer.Reindexer) {
+		require.NoError(t, rx.OpenNamespace(testAllowUnsafeNs, reindexer.DefaultNamespaceOptions(), TestItemAllowUnsafe{}))
+		for i := 0; i < 10; i++ {
+			require.NoError(t, rx.Upsert(testAllowUnsafeNs, TestItemAllowUnsafe{ID: i, Name: "item"}))
+		}
+	}
+
+	t.Run("objects are copied by default", func(t *testing.T) {
+		rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing())
+		defer rx.Close()
+		openNs(t, rx)
+		defer rx.DropNamespace(testAllowUnsafeNs)
+
+		q := func() *reindexer.Query { return rx.Query(testAllowUnsafeNs).Sort("id", false) }
+		assert.NotSame(t, fetchFirst(t, q().Exec()), fetchFirst(t, q().Exec()))
+
+		shared := fetchFirst(t, q().AllowUnsafe(true).Exec())
+		assert.Same(t, shared, fetchFirst(t, q().AllowUnsafe(true).Exec()))
+		assert.NotSame(t, shared, fetchFirst(t, q().Exec()))
+	})
+
+	t.Run("unsafe objects are returned with the DB's default", func(t *testing.T) {
+		rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithAllowUnsafe(true))
+		defer rx.Close()
+		openNs(t, rx)
+		defer rx.DropNamespace(testAllowUnsafeNs)
+
+		q := func() *reindexer.Query { return rx.Query(testAllowUnsafeNs).Sort("id", false) }
+		shared := fetchFirst(t, q().Exec())
+		assert.Same(t, shared, fetchFirst(t, q().Exec()))
+
+		// Copy, returned for the query, may be modified without corruption of the cache
+		safe := fetchFirst(t, q().AllowUnsafe(false).Exec())
+		assert.NotSame(t, shared, safe)
+		safe.Name = "modified"
+		assert.Equal(t, "item", fetchFirst(t, q().Exec()).Name)
+
+		// Iterator's setting takes precedence over the query's one
+		assert.Same(t, shared, fetchFirst(t, q().AllowUnsafe(false).Exec().AllowUnsafe(true)))
+		assert.NotSame(t, shared, fetchFirst(t, q().Exec().AllowUnsafe(false)))
+	})
+}
@@ -0,0 +1,80 @@
+package reindexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemAutotime struct {
+	ID        int    `reindex:"id,,pk"`
+	Name      string `reindex:"name"`
+	CreatedAt int64  `reindex:"created_at,-,autotime:create"`
+	UpdatedAt int64  `reindex:"updated_at,-,autotime:update:msec"`
+}
+
+const testAutotimeNs = "test_items_autotime"
+
+func init() {
+	tnamespaces[testAutotimeNs] = TestItemAutotime{}
+}
+
+func TestAutotime(t *testing.T) {
+	t.Run("insert sets both create and update fields", func(t *testing.T) {
+		item := TestItemAutotime{ID: 1, Name: "first"}
+		cnt, err := DB.Insert(testAutotimeNs, &item)
+		require.NoError(t, err)
+		require.Equal(t, 1, cnt)
+		assert.GreaterOrEqual(t, item.CreatedAt, time.Now().Unix()-1)
+		assert.LessOrEqual(t, item.CreatedAt, time.Now().Unix())
+		assert.GreaterOrEqual(t, item.UpdatedAt, time.Now().Add(-time.Second).UnixNano()/int64(time.Millisecond))
+	})
+
+	t.Run("update keeps create field and changes update field", func(t *testing.T) {
+		item := TestItemAutotime{ID: 2, Name: "second"}
+		_, err := DB.Insert(testAutotimeNs, &item)
+		require.NoError(t, err)
+		created, updated := item.CreatedAt, item.UpdatedAt
+
+		time.Sleep(5 * time.Millisecond)
+		item.Name = "second_updated"
+		cnt, err := DB.Update(testAutotimeNs, &item)
+		require.NoError(t, err)
+		require.Equal(t, 1, cnt)
+		assert.Equal(t, created, item.CreatedAt)
+		assert.Greater(t, item.UpdatedAt, updated)
+	})
+
+	t.Run("upsert sets create field only if it is empty", func(t *testing.T) {
+		item := TestItemAutotime{ID: 3, Name: "third"}
+		require.NoError(t, DB.Upsert(testAutotimeNs, &item))
+		assert.NotZero(t, item.CreatedAt)
+		assert.NotZero(t, item.UpdatedAt)
+
+		item.CreatedAt = 100
+		require.NoError(t, DB.Upsert(testAutotimeNs, &item))
+		assert.Equal(t, int64(100), item.CreatedAt)
+	})
+
+	t.Run("explicit precept has priority", func(t *testing.T) {
+		item := TestItemAutotime{ID: 4, Name: "fourth"}
+		_, err := DB.Insert(testAutotimeNs, &item, "updated_at=now(sec)")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, item.UpdatedAt, time.Now().Unix())
+	})
+
+	t.Run("autotime is applied in transactions", func(t *testing.T) {
+		tx, err := DB.BeginTx(testAutotimeNs)
+		require.NoError(t, err)
+		require.NoError(t, tx.Insert(&TestItemAutotime{ID: 5, Name: "fifth"}))
+		require.NoError(t, tx.Commit())
+
+		item, found := DB.Query(testAutotimeNs).WhereInt("id", reindexer.EQ, 5).Get()
+		require.True(t, found)
+		assert.NotZero(t, item.(*TestItemAutotime).CreatedAt)
+		assert.NotZero(t, item.(*TestItemAutotime).UpdatedAt)
+	})
+}
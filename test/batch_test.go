@@ -0,0 +1,52 @@
+package reindexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemBatch struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testBatchNs = "test_items_batch"
+
+func init() {
+	tnamespaces[testBatchNs] = TestItemBatch{}
+}
+
+func TestUpsertBatch(t *testing.T) {
+	items := make([]interface{}, 0, 100)
+	for i := 0; i < 100; i++ {
+		items = append(items, TestItemBatch{ID: i, Name: "item"})
+	}
+	require.NoError(t, DB.UpsertBatch(context.Background(), testBatchNs, items))
+
+	it := DB.Query(testBatchNs).q.ReqTotal().Exec()
+	require.NoError(t, it.Error())
+	assert.Equal(t, 100, it.TotalCount())
+	it.Close()
+
+	t.Run("errors are returned per item", func(t *testing.T) {
+		err := DB.UpsertBatch(context.Background(), testBatchNs, []interface{}{
+			TestItemBatch{ID: 200, Name: "valid"},
+			[]byte(`{"id":201,"name":`),
+			TestItemBatch{ID: 202, Name: "valid"},
+		})
+		var berr *reindexer.BatchError
+		require.True(t, errors.As(err, &berr))
+		require.Len(t, berr.Errors, 3)
+		assert.NoError(t, berr.Errors[0])
+		assert.Error(t, berr.Errors[1])
+		assert.NoError(t, berr.Errors[2])
+
+		_, found := DB.Query(testBatchNs).WhereInt("id", reindexer.EQ, 202).Get()
+		assert.True(t, found)
+	})
+}
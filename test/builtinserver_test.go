@@ -9,6 +9,7 @@ import (
 	_ "github.com/restream/reindexer/v3/bindings/builtinserver"
 	"github.com/restream/reindexer/v3/bindings/builtinserver/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type ScvTestItem struct {
@@ -60,3 +61,51 @@ func TestBuiltinServer(t *testing.T) {
 	assert.NoError(t, rx5.Status().Err)
 	assert.NoError(t, rx5.OpenNamespace("testns", reindexer.DefaultNamespaceOptions(), &ScvTestItem{}))
 }
+
+func TestBuiltinServerControl(t *testing.T) {
+	cfg := config.DefaultServerConfig()
+	cfg.Net.HTTPAddr = "127.0.0.1:29091"
+	cfg.Net.RPCAddr = "127.0.0.1:26537"
+	cfg.Storage.Path = "/tmp/rx_builtinserver_test_control"
+
+	os.RemoveAll(cfg.Storage.Path)
+	rx := reindexer.NewReindex("builtinserver://xxx", reindexer.WithServerConfig(time.Second*100, cfg))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+	require.NoError(t, rx.OpenNamespace("testns", reindexer.DefaultNamespaceOptions(), &ScvTestItem{}))
+	require.NoError(t, rx.Upsert("testns", &ScvTestItem{ID: 1}))
+
+	srv := rx.BuiltinServer()
+	require.NotNil(t, srv)
+	select {
+	case <-srv.Ready():
+	default:
+		t.Fatal("server is not ready")
+	}
+	assert.Equal(t, "127.0.0.1:29091", srv.HTTPAddr())
+	assert.Equal(t, "127.0.0.1:26537", srv.RPCAddr())
+
+	// Server is restarted on the new ports with the same storage
+	cfg2 := *cfg
+	cfg2.Net.HTTPAddr = "127.0.0.1:29092"
+	cfg2.Net.RPCAddr = "127.0.0.1:26538"
+	require.NoError(t, srv.ReloadConfig(&cfg2))
+	<-srv.Ready()
+	assert.Equal(t, "127.0.0.1:26538", srv.RPCAddr())
+
+	client := reindexer.NewReindex("cproto://" + srv.RPCAddr() + "/xxx")
+	defer client.Close()
+	require.NoError(t, client.OpenNamespace("testns", reindexer.DefaultNamespaceOptions(), &ScvTestItem{}))
+	items, err := client.Query("testns").Exec().FetchAll()
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	require.NoError(t, rx.Upsert("testns", &ScvTestItem{ID: 2}))
+
+	require.NoError(t, srv.Stop())
+	select {
+	case <-srv.Ready():
+		t.Fatal("stopped server is ready")
+	default:
+	}
+	assert.Error(t, rx.Ping())
+}
@@ -0,0 +1,76 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemClientStats struct {
+	ID int `reindex:"id,,pk"`
+}
+
+const testClientStatsNs = "test_items_client_stats"
+
+func init() {
+	tnamespaces[testClientStatsNs] = TestItemClientStats{}
+}
+
+func TestClientStats(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		require.NoError(t, DB.Upsert(testClientStatsNs, TestItemClientStats{ID: i}))
+	}
+
+	t.Run("open iterators are counted", func(t *testing.T) {
+		before := DB.ClientStats().OpenIterators
+		it := DB.Query(testClientStatsNs).q.Exec()
+		require.NoError(t, it.Error())
+		assert.Equal(t, before+1, DB.ClientStats().OpenIterators)
+		it.Close()
+		assert.Equal(t, before, DB.ClientStats().OpenIterators)
+		// Second close must not change the counter
+		it.Close()
+		assert.Equal(t, before, DB.ClientStats().OpenIterators)
+	})
+
+	t.Run("transactions in flight are counted", func(t *testing.T) {
+		before := DB.ClientStats().TxInFlight
+		tx, err := DB.BeginTx(testClientStatsNs)
+		require.NoError(t, err)
+		assert.Equal(t, before+1, DB.ClientStats().TxInFlight)
+		require.NoError(t, tx.Upsert(TestItemClientStats{ID: 10}))
+		require.NoError(t, tx.Commit())
+		assert.Equal(t, before, DB.ClientStats().TxInFlight)
+		// Rollback after commit must not change the counter
+		tx.Rollback()
+		assert.Equal(t, before, DB.ClientStats().TxInFlight)
+	})
+
+	t.Run("pending async operations are released after commit", func(t *testing.T) {
+		tx, err := DB.BeginTx(testClientStatsNs)
+		require.NoError(t, err)
+		for i := 20; i < 30; i++ {
+			require.NoError(t, tx.UpsertAsync(TestItemClientStats{ID: i}, func(err error) {}))
+		}
+		require.NoError(t, tx.Commit())
+		assert.Equal(t, int64(0), DB.ClientStats().PendingAsyncOps)
+	})
+	t.Run("state invalidated retries are not counted without schema changes", func(t *testing.T) {
+		before := DB.ClientStats().StateInvalidatedRetries
+		require.NoError(t, DB.Upsert(testClientStatsNs, TestItemClientStats{ID: 40}))
+		assert.Equal(t, before, DB.ClientStats().StateInvalidatedRetries)
+	})
+
+	t.Run("cached total without plan cache is accurate", func(t *testing.T) {
+		expected, err := DB.Query(testClientStatsNs).q.Where("id", reindexer.LT, 5).ReqTotal().Exec().FetchAll()
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			it := DB.Query(testClientStatsNs).q.Where("id", reindexer.LT, 5).CachedTotal().AccurateTotal().Exec()
+			require.NoError(t, it.Error())
+			assert.Equal(t, len(expected), it.TotalCount())
+			it.Close()
+		}
+	})
+}
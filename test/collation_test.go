@@ -0,0 +1,41 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemNamedCollation struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name,tree,collate_test_digits_first"`
+}
+
+const testNamedCollationNs = "test_items_named_collation"
+
+func init() {
+	if err := reindexer.RegisterCollation("test_digits_first", "0-9a-zA-Z"); err != nil {
+		panic(err)
+	}
+	tnamespaces[testNamedCollationNs] = TestItemNamedCollation{}
+}
+
+func TestNamedCollation(t *testing.T) {
+	for i, name := range []string{"b", "A", "1", "a"} {
+		require.NoError(t, DB.Upsert(testNamedCollationNs, TestItemNamedCollation{ID: i, Name: name}))
+	}
+
+	items, err := DB.Query(testNamedCollationNs).Sort("name", false).Exec(t).FetchAll()
+	require.NoError(t, err)
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.(*TestItemNamedCollation).Name)
+	}
+	assert.Equal(t, []string{"1", "a", "b", "A"}, names)
+
+	assert.Error(t, reindexer.RegisterCollation("utf8", "a-z"))
+	_, err = reindexer.IndexDef{Name: "name"}.CustomCollation("unknown_collation")
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemMultiFieldPk struct {
+	TenantID int      `reindex:"tenant_id"`
+	Slug     string   `reindex:"slug"`
+	Title    string   `reindex:"title"`
+	_        struct{} `reindex:"tenant_id+slug,,composite,pk"`
+}
+
+type TestItemCompositePk struct {
+	ID    int      `reindex:"id"`
+	SubID int      `reindex:"sub_id"`
+	Title string   `reindex:"title"`
+	_     struct{} `reindex:"id+sub_id,,composite,pk"`
+}
+
+const (
+	testMultiFieldPkNs = "test_items_multi_field_pk"
+	testCompositePkNs  = "test_items_composite_pk"
+)
+
+func init() {
+	tnamespaces[testMultiFieldPkNs] = TestItemMultiFieldPk{}
+	tnamespaces[testCompositePkNs] = TestItemCompositePk{}
+}
+
+func TestCompositePK(t *testing.T) {
+	t.Run("pk over fields of different types", func(t *testing.T) {
+		require.NoError(t, DB.Upsert(testMultiFieldPkNs, TestItemMultiFieldPk{TenantID: 1, Slug: "post", Title: "first"}))
+		require.NoError(t, DB.Upsert(testMultiFieldPkNs, TestItemMultiFieldPk{TenantID: 2, Slug: "post", Title: "second"}))
+		require.NoError(t, DB.Upsert(testMultiFieldPkNs, TestItemMultiFieldPk{TenantID: 1, Slug: "post", Title: "first_updated"}))
+
+		items, err := DB.Query(testMultiFieldPkNs).Exec(t).FetchAll()
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(items))
+
+		item, err := DB.GetByCompositePK(testMultiFieldPkNs, 1, "post")
+		require.NoError(t, err)
+		assert.Equal(t, "first_updated", item.(*TestItemMultiFieldPk).Title)
+
+		_, err = DB.GetByCompositePK(testMultiFieldPkNs, 3, "post")
+		assert.Equal(t, reindexer.ErrNotFound, err)
+
+		_, err = DB.GetByCompositePK(testMultiFieldPkNs, 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("composite index pk", func(t *testing.T) {
+		require.NoError(t, DB.Upsert(testCompositePkNs, TestItemCompositePk{ID: 1, SubID: 2, Title: "first"}))
+
+		item, err := DB.GetByCompositePK(testCompositePkNs, 1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, "first", item.(*TestItemCompositePk).Title)
+	})
+
+	t.Run("composite condition arity is validated", func(t *testing.T) {
+		require.NoError(t, DB.Upsert(testCompositePkNs, TestItemCompositePk{ID: 3, SubID: 4, Title: "second"}))
+
+		items, err := DB.Query(testCompositePkNs).q.WhereComposite("id+sub_id", reindexer.SET, []interface{}{1, 2}, []interface{}{3, 4}).Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+
+		var verr *reindexer.QueryValidationError
+		_, err = DB.Query(testCompositePkNs).q.WhereComposite("id+sub_id", reindexer.EQ, []interface{}{1}).Exec().FetchAll()
+		assert.ErrorAs(t, err, &verr)
+		_, err = DB.Query(testCompositePkNs).q.WhereComposite("id+sub_id", reindexer.EQ, 1, 2).Delete()
+		assert.ErrorAs(t, err, &verr)
+		err = DB.Query(testCompositePkNs).q.WhereComposite("title", reindexer.EQ, []interface{}{"first"}).Set("title", "third").Update().Error()
+		assert.ErrorAs(t, err, &verr)
+	})
+
+	t.Run("primary key of the item", func(t *testing.T) {
+		pk, err := DB.PrimaryKeyOf(testMultiFieldPkNs, &TestItemMultiFieldPk{TenantID: 1, Slug: "post"})
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{1, "post"}, pk)
+		item, err := DB.GetByCompositePK(testMultiFieldPkNs, pk.([]interface{})...)
+		require.NoError(t, err)
+		assert.Equal(t, "first_updated", item.(*TestItemMultiFieldPk).Title)
+
+		pk, err = DB.PrimaryKeyOf(testCompositePkNs, TestItemCompositePk{ID: 3, SubID: 4})
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{3, 4}, pk)
+
+		pk, err = DB.PrimaryKeyOf(testQuerySQLNs, TestItemQuerySQL{ID: 7})
+		require.NoError(t, err)
+		assert.Equal(t, 7, pk)
+
+		_, err = DB.PrimaryKeyOf(testCompositePkNs, &TestItemMultiFieldPk{TenantID: 1})
+		assert.Equal(t, reindexer.ErrWrongType, err)
+		_, err = DB.PrimaryKeyOf(testCompositePkNs, (*TestItemCompositePk)(nil))
+		assert.Equal(t, reindexer.ErrWrongType, err)
+	})
+}
@@ -0,0 +1,56 @@
+package reindexer
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemConcurrencyLimit struct {
+	ID int `reindex:"id,,pk"`
+}
+
+const testConcurrencyLimitNs = "test_items_concurrency_limit"
+
+func TestMaxConcurrentQueries(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithMaxConcurrentQueries(testConcurrencyLimitNs, 2))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+	require.NoError(t, rx.OpenNamespace(testConcurrencyLimitNs, reindexer.DefaultNamespaceOptions(), TestItemConcurrencyLimit{}))
+	defer rx.DropNamespace(testConcurrencyLimitNs)
+
+	const count = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := rx.Upsert(testConcurrencyLimitNs, TestItemConcurrencyLimit{ID: id}); err != nil {
+				errs <- err
+				return
+			}
+			it := rx.Query(testConcurrencyLimitNs).WhereInt("id", reindexer.EQ, id).Exec()
+			errs <- it.Error()
+			it.Close()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	it := rx.Query(testConcurrencyLimitNs).Exec()
+	defer it.Close()
+	require.NoError(t, it.Error())
+	assert.Equal(t, count, it.Count())
+}
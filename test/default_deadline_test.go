@@ -0,0 +1,41 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemDefaultDeadline struct {
+	ID int `reindex:"id,,pk"`
+}
+
+const testDefaultDeadlineNs = "test_items_default_deadline"
+
+func TestNamespaceDefaultDeadline(t *testing.T) {
+	DB.CloseNamespace(testDefaultDeadlineNs)
+	err := DB.OpenNamespace(testDefaultDeadlineNs, reindexer.DefaultNamespaceOptions().WithDefaultDeadline(time.Nanosecond), TestItemDefaultDeadline{})
+	require.NoError(t, err)
+	defer DB.DropNamespace(testDefaultDeadlineNs)
+
+	t.Run("operations without deadline use namespace's one", func(t *testing.T) {
+		assert.Error(t, DB.Upsert(testDefaultDeadlineNs, TestItemDefaultDeadline{ID: 1}))
+		it := DB.Query(testDefaultDeadlineNs).q.Exec()
+		assert.Error(t, it.Error())
+		it.Close()
+	})
+
+	t.Run("deadline from context has priority", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, DB.WithContext(ctx).Upsert(testDefaultDeadlineNs, TestItemDefaultDeadline{ID: 1}))
+		it := DB.Query(testDefaultDeadlineNs).q.ExecCtx(ctx)
+		require.NoError(t, it.Error())
+		assert.Equal(t, 1, it.Count())
+		it.Close()
+	})
+}
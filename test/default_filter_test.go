@@ -0,0 +1,67 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemDefaultFilter struct {
+	ID     int    `reindex:"id,,pk"`
+	Tenant string `reindex:"tenant"`
+}
+
+const testDefaultFilterNs = "test_items_default_filter"
+
+type testTenantKey struct{}
+
+func TestDefaultFilter(t *testing.T) {
+	opts := reindexer.DefaultNamespaceOptions().WithDefaultFilter(func(ctx context.Context, q *reindexer.Query) {
+		if tenant, ok := ctx.Value(testTenantKey{}).(string); ok {
+			q.WhereString("tenant", reindexer.EQ, tenant)
+		}
+	})
+	DB.CloseNamespace(testDefaultFilterNs)
+	require.NoError(t, DB.OpenNamespace(testDefaultFilterNs, opts, TestItemDefaultFilter{}))
+	defer DB.DropNamespace(testDefaultFilterNs)
+
+	for i := 0; i < 10; i++ {
+		tenant := "a"
+		if i%2 != 0 {
+			tenant = "b"
+		}
+		require.NoError(t, DB.Upsert(testDefaultFilterNs, TestItemDefaultFilter{ID: i, Tenant: tenant}))
+	}
+	ctxA := context.WithValue(context.Background(), testTenantKey{}, "a")
+	count := func(t *testing.T, ctx context.Context, q *reindexer.Query) int {
+		it := q.ReqTotal().ExecCtx(ctx)
+		defer it.Close()
+		require.NoError(t, it.Error())
+		return it.TotalCount()
+	}
+
+	t.Run("select is filtered by the context", func(t *testing.T) {
+		assert.Equal(t, 5, count(t, ctxA, DB.Reindexer.Query(testDefaultFilterNs)))
+		// Condition of the query is combined with the filter by AND
+		q := DB.Reindexer.Query(testDefaultFilterNs).WhereInt("id", reindexer.EQ, 1).Or().WhereInt("id", reindexer.EQ, 2)
+		assert.Equal(t, 1, count(t, ctxA, q))
+	})
+
+	t.Run("filter without conditions", func(t *testing.T) {
+		assert.Equal(t, 10, count(t, context.Background(), DB.Reindexer.Query(testDefaultFilterNs)))
+	})
+
+	t.Run("without defaults", func(t *testing.T) {
+		assert.Equal(t, 10, count(t, ctxA, DB.Reindexer.Query(testDefaultFilterNs).WithoutDefaults()))
+	})
+
+	t.Run("delete is filtered", func(t *testing.T) {
+		deleted, err := DB.Reindexer.Query(testDefaultFilterNs).WhereInt("id", reindexer.LT, 4).DeleteCtx(ctxA)
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+		assert.Equal(t, 8, count(t, context.Background(), DB.Reindexer.Query(testDefaultFilterNs)))
+	})
+}
@@ -0,0 +1,40 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemDeleteReturning struct {
+	ID  int `reindex:"id,,pk"`
+	Age int `reindex:"age"`
+}
+
+const testDeleteReturningNs = "test_items_delete_returning"
+
+func TestDeleteReturning(t *testing.T) {
+	DB.CloseNamespace(testDeleteReturningNs)
+	require.NoError(t, DB.OpenNamespace(testDeleteReturningNs, reindexer.DefaultNamespaceOptions(), TestItemDeleteReturning{}))
+	defer DB.DropNamespace(testDeleteReturningNs)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testDeleteReturningNs, TestItemDeleteReturning{ID: i, Age: i * 10}))
+	}
+
+	it := DB.Reindexer.Query(testDeleteReturningNs).WhereInt("age", reindexer.GE, 50).Sort("id", true).Limit(3).DeleteReturning(context.Background())
+	defer it.Close()
+	require.NoError(t, it.Error())
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Object().(*TestItemDeleteReturning).ID)
+	}
+	assert.Equal(t, []int{9, 8, 7}, ids)
+
+	left := DB.Reindexer.Query(testDeleteReturningNs).ReqTotal().Exec()
+	defer left.Close()
+	require.NoError(t, left.Error())
+	assert.Equal(t, 7, left.TotalCount())
+}
@@ -0,0 +1,63 @@
+package reindexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestDescribeStructNested struct {
+	Value int `reindex:"value"`
+}
+
+type TestDescribeStructItem struct {
+	ID     int                      `reindex:"id,,pk"`
+	Name   string                   `reindex:"name,hash"`
+	Tags   []string                 `reindex:"tags"`
+	Nested TestDescribeStructNested `json:"nested"`
+	_      struct{}                 `reindex:"id+name,,composite"`
+}
+
+type TestDescribeStructInvalid struct {
+	ID      int    `reindex:"id,,pk"`
+	Version string `reindex:"version,-,version"`
+}
+
+func TestDescribeStructTags(t *testing.T) {
+	t.Run("index definitions are parsed from tags", func(t *testing.T) {
+		indexes, err := reindexer.DescribeStruct(TestDescribeStructItem{})
+		require.NoError(t, err)
+		byName := make(map[string]reindexer.IndexDef)
+		for _, idx := range indexes {
+			byName[idx.Name] = idx
+		}
+		require.Len(t, byName, 5)
+		assert.True(t, byName["id"].IsPK)
+		assert.Equal(t, "int64", byName["id"].FieldType)
+		assert.Equal(t, "hash", byName["name"].IndexType)
+		assert.True(t, byName["tags"].IsArray)
+		assert.Equal(t, []string{"nested.Value"}, byName["value"].JSONPaths)
+		assert.Equal(t, "composite", byName["id+name"].FieldType)
+	})
+
+	t.Run("pointer and reflect.Type are accepted", func(t *testing.T) {
+		expected, err := reindexer.DescribeStruct(TestDescribeStructItem{})
+		require.NoError(t, err)
+		indexes, err := reindexer.DescribeStruct(&TestDescribeStructItem{})
+		require.NoError(t, err)
+		assert.Equal(t, expected, indexes)
+		indexes, err = reindexer.DescribeStruct(reflect.TypeOf(TestDescribeStructItem{}))
+		require.NoError(t, err)
+		assert.Equal(t, expected, indexes)
+	})
+
+	t.Run("invalid tags are reported", func(t *testing.T) {
+		_, err := reindexer.DescribeStruct(TestDescribeStructInvalid{})
+		assert.Error(t, err)
+		_, err = reindexer.DescribeStruct(42)
+		assert.Error(t, err)
+	})
+}
@@ -1941,4 +1941,54 @@ func TestDSLQueries(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, expectedIDs, getTesDSLJoinItemsIDs(items))
 	})
+
+	t.Run("dsl between fields condition", func(t *testing.T) {
+		const jsonDSL = `
+		{
+			"namespace": "test_namespace_dsl",
+			"sort": {
+				"field": "id"
+			},
+			"filters": [
+				{
+					"first_field": "id",
+					"cond": "eq",
+					"second_field": "id"
+				},
+				{
+					"field": "id",
+					"cond": "lt",
+					"value": 3
+				}
+			]
+		}
+		`
+
+		var dslQ dsl.DSL
+		err := json.Unmarshal([]byte(jsonDSL), &dslQ)
+		require.NoError(t, err)
+		require.Equal(t, "id", dslQ.Filters[0].FirstField)
+		require.Equal(t, "id", dslQ.Filters[0].SecondField)
+		q, err := DBD.QueryFrom(dslQ)
+		require.NoError(t, err)
+		items, err := q.MustExec().FetchAll()
+		require.NoError(t, err)
+		require.Equal(t, []int{0, 1, 2}, getTesDSLJoinItemsIDs(items))
+
+		dslQ.Filters[0].Cond = "lt"
+		q, err = DBD.QueryFrom(dslQ)
+		require.NoError(t, err)
+		items, err = q.MustExec().FetchAll()
+		require.NoError(t, err)
+		require.Empty(t, items)
+	})
+
+	t.Run("dsl between fields condition with field", func(t *testing.T) {
+		q, err := DBD.QueryFrom(dsl.DSL{
+			Namespace: "test_namespace_dsl",
+			Filters:   []dsl.Filter{{Field: "id", FirstField: "id", Cond: "eq", SecondField: "id"}},
+		})
+		require.Error(t, err)
+		require.Nil(t, q)
+	})
 }
@@ -0,0 +1,64 @@
+package reindexer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemDump struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+	Age  int    `reindex:"age"`
+}
+
+const (
+	testDumpNs     = "test_items_dump"
+	testRestoredNs = "test_items_dump_restored"
+)
+
+func TestDumpRestoreNamespace(t *testing.T) {
+	DB.CloseNamespace(testDumpNs)
+	require.NoError(t, DB.OpenNamespace(testDumpNs, reindexer.DefaultNamespaceOptions(), TestItemDump{}))
+	defer DB.DropNamespace(testDumpNs)
+	for i := 0; i < 2500; i++ {
+		require.NoError(t, DB.Upsert(testDumpNs, TestItemDump{ID: i, Name: "item", Age: i % 100}))
+	}
+	require.NoError(t, DB.PutMeta(testDumpNs, "schema_version", []byte("7")))
+
+	var dump bytes.Buffer
+	require.NoError(t, DB.DumpNamespace(context.Background(), testDumpNs, &dump))
+
+	// Namespace without 'age' index gets the index from the dump
+	type TestItemRestored struct {
+		ID   int    `reindex:"id,,pk"`
+		Name string `reindex:"name"`
+		Age  int
+	}
+	DB.CloseNamespace(testRestoredNs)
+	require.NoError(t, DB.OpenNamespace(testRestoredNs, reindexer.DefaultNamespaceOptions(), TestItemRestored{}))
+	defer DB.DropNamespace(testRestoredNs)
+	require.NoError(t, DB.RestoreNamespace(context.Background(), testRestoredNs, &dump))
+
+	it := DB.Reindexer.Query(testRestoredNs).WhereInt("age", reindexer.EQ, 42).ReqTotal().Exec()
+	defer it.Close()
+	require.NoError(t, it.Error())
+	assert.Equal(t, 25, it.TotalCount())
+
+	total := DB.Reindexer.Query(testRestoredNs).ReqTotal().Limit(0).Exec()
+	defer total.Close()
+	require.NoError(t, total.Error())
+	assert.Equal(t, 2500, total.TotalCount())
+
+	// Meta is dumped only by cproto binding
+	if strings.HasPrefix(*dsn, "cproto") {
+		meta, err := DB.GetMeta(testRestoredNs, "schema_version")
+		require.NoError(t, err)
+		assert.Equal(t, "7", string(meta))
+	}
+}
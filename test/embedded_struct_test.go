@@ -0,0 +1,77 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestEmbeddedAddress struct {
+	City string `json:"city" reindex:"city"`
+	Zip  string `json:"zip"`
+}
+
+type TestEmbeddedInfo struct {
+	Note string `json:"note"`
+}
+
+type TestItemEmbedded struct {
+	ID               int                  `reindex:"id,,pk" json:"id"`
+	Billing          TestEmbeddedAddress  `reindex:",,prefix=billing_"`
+	Shipping         *TestEmbeddedAddress `reindex:",,prefix=shipping_"`
+	Home             TestEmbeddedAddress  `json:"home" reindex:",,flatten"`
+	TestEmbeddedInfo `reindex:",,nested"`
+}
+
+type TestItemEmbeddedInvalid struct {
+	ID   int `reindex:"id,,pk"`
+	Name int `reindex:"name,,flatten"`
+}
+
+const (
+	testEmbeddedNs        = "test_items_embedded"
+	testEmbeddedInvalidNs = "test_items_embedded_invalid"
+)
+
+func init() {
+	tnamespaces[testEmbeddedNs] = TestItemEmbedded{}
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	item := TestItemEmbedded{
+		ID:               1,
+		Billing:          TestEmbeddedAddress{City: "Moscow", Zip: "101000"},
+		Shipping:         &TestEmbeddedAddress{City: "Tver", Zip: "170000"},
+		Home:             TestEmbeddedAddress{City: "Kazan", Zip: "420000"},
+		TestEmbeddedInfo: TestEmbeddedInfo{Note: "note"},
+	}
+	require.NoError(t, DB.Upsert(testEmbeddedNs, item))
+	require.NoError(t, DB.Upsert(testEmbeddedNs, TestItemEmbedded{ID: 2, Billing: TestEmbeddedAddress{City: "Tver"}}))
+
+	t.Run("flattened fields are stored with prefixes", func(t *testing.T) {
+		it := DB.Query(testEmbeddedNs).WhereInt("id", reindexer.EQ, 1).ExecToJson()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		require.True(t, it.Next())
+		assert.JSONEq(t, `{"id":1,"billing_city":"Moscow","billing_zip":"101000","shipping_city":"Tver","shipping_zip":"170000",
+			"city":"Kazan","zip":"420000","TestEmbeddedInfo":{"note":"note"}}`, string(it.JSON()))
+	})
+
+	t.Run("indexes of flattened fields are prefixed", func(t *testing.T) {
+		items, err := DB.Query(testEmbeddedNs).WhereString("shipping_city", reindexer.EQ, "Tver").Exec(t).FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, item, *items[0].(*TestItemEmbedded))
+
+		items, err = DB.Query(testEmbeddedNs).WhereString("billing_city", reindexer.EQ, "Tver").Exec(t).FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, 2, items[0].(*TestItemEmbedded).ID)
+	})
+
+	t.Run("flattening of non-struct fields is rejected", func(t *testing.T) {
+		assert.Error(t, DB.OpenNamespace(testEmbeddedInvalidNs, reindexer.DefaultNamespaceOptions(), TestItemEmbeddedInvalid{}))
+	})
+}
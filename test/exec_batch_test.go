@@ -0,0 +1,54 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemExecBatch struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testExecBatchNs = "test_items_exec_batch"
+
+func init() {
+	tnamespaces[testExecBatchNs] = TestItemExecBatch{}
+}
+
+func TestExecBatch(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		require.NoError(t, DB.Upsert(testExecBatchNs, TestItemExecBatch{ID: i, Name: []string{"first", "second"}[i%2]}))
+	}
+
+	iters := DB.ExecBatch(context.Background(), []*reindexer.Query{
+		DB.Reindexer.Query(testExecBatchNs).WhereInt("id", reindexer.LT, 5),
+		DB.Reindexer.Query(testExecBatchNs).WhereString("name", reindexer.EQ, "second").FetchCount(3),
+		DB.Reindexer.Query("test_items_exec_batch_missing"),
+		DB.Reindexer.Query(testExecBatchNs).WhereInt("id", reindexer.EQ, 7).CachePolicy(time.Minute, 10),
+	})
+	require.Len(t, iters, 4)
+
+	items, err := iters[0].FetchAll()
+	require.NoError(t, err)
+	assert.Len(t, items, 5)
+
+	items, err = iters[1].FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 10)
+	for _, item := range items {
+		assert.Equal(t, "second", item.(*TestItemExecBatch).Name)
+	}
+
+	assert.Error(t, iters[2].Error())
+	iters[2].Close()
+
+	item, err := iters[3].FetchOne()
+	require.NoError(t, err)
+	assert.Equal(t, 7, item.(*TestItemExecBatch).ID)
+}
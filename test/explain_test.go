@@ -0,0 +1,56 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemExplainAuthor struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+type TestItemExplainBook struct {
+	ID       int                      `reindex:"id,,pk"`
+	Year     int                      `reindex:"year,tree"`
+	AuthorID int                      `reindex:"author_id"`
+	Authors  []*TestItemExplainAuthor `reindex:"authors,,joined"`
+}
+
+const (
+	testExplainBooksNs   = "test_items_explain_books"
+	testExplainAuthorsNs = "test_items_explain_authors"
+)
+
+func init() {
+	tnamespaces[testExplainBooksNs] = TestItemExplainBook{}
+	tnamespaces[testExplainAuthorsNs] = TestItemExplainAuthor{}
+}
+
+func TestExplainResultsHelpers(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testExplainAuthorsNs, TestItemExplainAuthor{ID: i, Name: "author"}))
+		require.NoError(t, DB.Upsert(testExplainBooksNs, TestItemExplainBook{ID: i, Year: 2000 + i, AuthorID: i}))
+	}
+
+	q := DB.Query(testExplainBooksNs).q.WhereInt("year", reindexer.GT, 2004).Explain()
+	q.LeftJoin(DB.Query(testExplainAuthorsNs).q, "authors").On("author_id", reindexer.EQ, "id")
+	it := q.Exec()
+	defer it.Close()
+	require.NoError(t, it.Error())
+
+	explain, err := it.GetExplainResults()
+	require.NoError(t, err)
+	require.NotNil(t, explain)
+
+	assert.Contains(t, explain.IndexesUsed(), "year")
+	assert.Greater(t, explain.Cost(), 0.0)
+
+	joins := explain.Joins()
+	require.Len(t, joins, 1)
+	assert.Equal(t, "left_join", joins[0].Type)
+	assert.Equal(t, testExplainAuthorsNs, joins[0].Namespace)
+}
@@ -0,0 +1,88 @@
+package reindexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrderStatus is enum, which is indexed by the names of the statuses
+type TestOrderStatus int
+
+var testOrderStatuses = []string{"new", "paid", "shipped"}
+
+func (s TestOrderStatus) MarshalReindexField() (interface{}, error) {
+	if int(s) >= len(testOrderStatuses) {
+		return nil, fmt.Errorf("unknown status %d", s)
+	}
+	return testOrderStatuses[s], nil
+}
+
+func (s *TestOrderStatus) UnmarshalReindexField(value interface{}) error {
+	for i, name := range testOrderStatuses {
+		if name == value {
+			*s = TestOrderStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %v", value)
+}
+
+// TestMoney is struct without exported fields, which is indexed as string
+type TestMoney struct {
+	cents int64
+}
+
+func (m TestMoney) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d", m.cents/100, m.cents%100)), nil
+}
+
+func (m *TestMoney) UnmarshalText(text []byte) error {
+	var units, cents int64
+	if _, err := fmt.Sscanf(string(text), "%d.%d", &units, &cents); err != nil {
+		return err
+	}
+	m.cents = units*100 + cents
+	return nil
+}
+
+type TestItemFieldMarshaler struct {
+	ID     int             `reindex:"id,,pk" json:"id"`
+	Status TestOrderStatus `reindex:"status" json:"status"`
+	Price  TestMoney       `json:"price"`
+	Refund *TestMoney      `json:"refund,omitempty"`
+}
+
+const testFieldMarshalerNs = "test_items_field_marshaler"
+
+func init() {
+	tnamespaces[testFieldMarshalerNs] = TestItemFieldMarshaler{}
+}
+
+func TestFieldMarshaler(t *testing.T) {
+	require.NoError(t, DB.Upsert(testFieldMarshalerNs, TestItemFieldMarshaler{ID: 1, Status: 2, Price: TestMoney{cents: 1999}, Refund: &TestMoney{cents: 5}}))
+	require.NoError(t, DB.Upsert(testFieldMarshalerNs, TestItemFieldMarshaler{ID: 2, Status: 0, Price: TestMoney{cents: 100}}))
+
+	item, found := DB.Query(testFieldMarshalerNs).Where("status", reindexer.EQ, TestOrderStatus(2)).Get()
+	require.True(t, found)
+	assert.Equal(t, TestItemFieldMarshaler{ID: 1, Status: 2, Price: TestMoney{cents: 1999}, Refund: &TestMoney{cents: 5}}, *item.(*TestItemFieldMarshaler))
+
+	t.Run("fields are stored in marshaled representation", func(t *testing.T) {
+		it := DB.Query(testFieldMarshalerNs).WhereString("status", reindexer.EQ, "new").ExecToJson()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		require.True(t, it.Next())
+		assert.JSONEq(t, `{"id":2,"status":"new","price":"1.00"}`, string(it.JSON()))
+	})
+
+	t.Run("marshaling errors are returned", func(t *testing.T) {
+		assert.Error(t, DB.Upsert(testFieldMarshalerNs, TestItemFieldMarshaler{ID: 3, Status: 10}))
+
+		var verr *reindexer.QueryValidationError
+		_, err := DB.Query(testFieldMarshalerNs).q.Where("status", reindexer.EQ, TestOrderStatus(10)).Exec().FetchAll()
+		assert.ErrorAs(t, err, &verr)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/restream/reindexer/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -123,7 +124,7 @@ func TestAutogen(t *testing.T) {
 
 		item = TestItemAutogen{ID: id}
 		count, err = DB.Insert(ns, &item, precepts...)
-		require.NoError(t, err)
+		assert.ErrorIs(t, err, reindexer.ErrUniqueViolation)
 		assert.Equal(t, 0, count)
 		assert.Equal(t, int64(0), item.UpdatedTime)
 	})
@@ -0,0 +1,112 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemGenericExec struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testGenericExecNs = "test_items_generic_exec"
+
+func init() {
+	tnamespaces[testGenericExecNs] = TestItemGenericExec{}
+}
+
+func TestGenericExec(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testGenericExecNs, TestItemGenericExec{ID: i, Name: "item"}))
+	}
+
+	t.Run("pointer items with cap", func(t *testing.T) {
+		items, total, err := reindexer.Exec[*TestItemGenericExec](context.Background(), DB.Query(testGenericExecNs).ReqTotal().q, 3)
+		require.NoError(t, err)
+		assert.Equal(t, 3, len(items))
+		assert.Equal(t, 10, total)
+	})
+
+	t.Run("cap is applied as query's limit", func(t *testing.T) {
+		q := DB.Query(testGenericExecNs).Sort("id", false)
+		items, total, err := reindexer.Exec[*TestItemGenericExec](context.Background(), q.q, 3)
+		require.NoError(t, err)
+		require.Equal(t, 3, len(items))
+		assert.Equal(t, 10, total)
+		assert.Equal(t, 2, items[2].ID)
+
+		// Query is not modified, so it's executed without the cap
+		all, err := q.q.Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Equal(t, 10, len(all))
+	})
+
+	t.Run("smaller query's limit is kept", func(t *testing.T) {
+		items, total, err := reindexer.Exec[*TestItemGenericExec](context.Background(), DB.Query(testGenericExecNs).Limit(2).q, 5)
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(items))
+		assert.Equal(t, 10, total)
+	})
+
+	t.Run("value items without cap", func(t *testing.T) {
+		items, total, err := reindexer.Exec[TestItemGenericExec](context.Background(), DB.Query(testGenericExecNs).Sort("id", false).q, 0)
+		require.NoError(t, err)
+		require.Equal(t, 10, len(items))
+		assert.Equal(t, 10, total)
+		assert.Equal(t, 9, items[9].ID)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, _, err := reindexer.Exec[*TestItem](context.Background(), DB.Query(testGenericExecNs).q, 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestTypedQuery(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testGenericExecNs, TestItemGenericExec{ID: i, Name: "item"}))
+	}
+
+	t.Run("fetch pointer items", func(t *testing.T) {
+		items, err := reindexer.NewTypedQuery[*TestItemGenericExec](&DB.Reindexer, testGenericExecNs).
+			WhereInt("id", reindexer.LT, 5).Sort("id", false).Exec().FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, 5)
+		for i, item := range items {
+			assert.Equal(t, i, item.ID)
+		}
+	})
+
+	t.Run("iterate value items with aggregations", func(t *testing.T) {
+		q := reindexer.Typed[TestItemGenericExec](DB.Query(testGenericExecNs).Sort("id", true).q)
+		q.AggregateMax("id")
+		it := q.Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		var ids []int
+		for it.Next() {
+			ids = append(ids, it.Object().ID)
+		}
+		assert.Equal(t, []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, ids)
+		aggs := it.AggResults()
+		require.Len(t, aggs, 1)
+		require.NotNil(t, aggs[0].Value)
+		assert.Equal(t, 9.0, *aggs[0].Value)
+	})
+
+	t.Run("get item", func(t *testing.T) {
+		got, found := reindexer.NewTypedQuery[TestItemGenericExec](&DB.Reindexer, testGenericExecNs).WhereInt("id", reindexer.EQ, 3).Get()
+		require.True(t, found)
+		assert.Equal(t, 3, got.ID)
+	})
+
+	t.Run("mismatched type", func(t *testing.T) {
+		_, err := reindexer.NewTypedQuery[*TestItem](&DB.Reindexer, testGenericExecNs).Exec().FetchAll()
+		assert.Error(t, err)
+	})
+}
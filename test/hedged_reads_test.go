@@ -0,0 +1,57 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemHedgedReads struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testHedgedReadsNs = "test_items_hedged_reads"
+
+func TestHedgedReads(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	// Minimal delay to hedge each read query
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithConnPoolSize(2),
+		reindexer.WithHedgedReads(time.Nanosecond))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+
+	require.NoError(t, rx.OpenNamespace(testHedgedReadsNs, reindexer.DefaultNamespaceOptions(), TestItemHedgedReads{}))
+	defer rx.DropNamespace(testHedgedReadsNs)
+	const count = 100
+	for i := 0; i < count; i++ {
+		require.NoError(t, rx.Upsert(testHedgedReadsNs, TestItemHedgedReads{ID: i, Name: "name"}))
+	}
+
+	t.Run("hedged queries return complete results", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			items, err := rx.Query(testHedgedReadsNs).FetchCount(10).Exec().FetchAll()
+			require.NoError(t, err)
+			assert.Len(t, items, count)
+		}
+	})
+
+	t.Run("hedged sql queries return complete results", func(t *testing.T) {
+		items, err := rx.ExecSQL("SELECT * FROM " + testHedgedReadsNs + " WHERE id < 10").FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 10)
+	})
+
+	t.Run("modifications are not affected", func(t *testing.T) {
+		cnt, err := rx.Query(testHedgedReadsNs).WhereInt("id", reindexer.GE, 90).Delete()
+		require.NoError(t, err)
+		assert.Equal(t, 10, cnt)
+	})
+}
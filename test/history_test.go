@@ -0,0 +1,111 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemHistory struct {
+	ID   int    `reindex:"id,,pk" json:"id"`
+	Name string `reindex:"name" json:"name"`
+}
+
+const testHistoryNs = "test_items_history"
+
+func TestNamespaceHistory(t *testing.T) {
+	DB.CloseNamespace(testHistoryNs)
+	err := DB.OpenNamespace(testHistoryNs, reindexer.DefaultNamespaceOptions().WithHistory(), TestItemHistory{})
+	require.NoError(t, err)
+	defer DB.DropNamespace(reindexer.HistoryNamespace(testHistoryNs))
+	defer DB.DropNamespace(testHistoryNs)
+
+	_, err = DB.Insert(testHistoryNs, &TestItemHistory{ID: 1, Name: "first"})
+	require.NoError(t, err)
+	require.NoError(t, DB.Upsert(testHistoryNs, &TestItemHistory{ID: 1, Name: "second"}))
+	require.NoError(t, DB.Upsert(testHistoryNs, &TestItemHistory{ID: 2, Name: "other"}))
+
+	tx := DB.MustBeginTx(testHistoryNs)
+	require.NoError(t, tx.Upsert(&TestItemHistory{ID: 1, Name: "third"}))
+	require.NoError(t, tx.UpsertJSON([]byte(`{"id":1,"name":"fourth"}`)))
+	require.NoError(t, tx.Commit())
+
+	require.NoError(t, DB.Delete(testHistoryNs, &TestItemHistory{ID: 1}))
+
+	history, err := DB.ItemHistory(testHistoryNs, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 5)
+
+	expectedOps := []string{"Insert", "Upsert", "Upsert", "Upsert", "Delete"}
+	expectedNames := []string{"first", "second", "third", "fourth", ""}
+	for i, h := range history {
+		assert.Equal(t, expectedOps[i], h.Op)
+		assert.NotZero(t, h.Timestamp)
+		if i > 0 {
+			assert.Greater(t, h.Version, history[i-1].Version)
+			assert.GreaterOrEqual(t, h.Timestamp, history[i-1].Timestamp)
+		}
+		var item TestItemHistory
+		require.NoError(t, h.Decode(&item))
+		assert.Equal(t, 1, item.ID)
+		assert.Equal(t, expectedNames[i], item.Name)
+	}
+
+	history, err = DB.ItemHistory(testHistoryNs, 2)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	_, err = DB.ItemHistory("test_items", 1)
+	assert.Error(t, err)
+
+	t.Run("items, which are not modified, are not written", func(t *testing.T) {
+		_, err := DB.Insert(testHistoryNs, &TestItemHistory{ID: 2, Name: "existing"})
+		assert.ErrorIs(t, err, reindexer.ErrUniqueViolation)
+		_, err = DB.Update(testHistoryNs, &TestItemHistory{ID: 3, Name: "missing"})
+		require.NoError(t, err)
+
+		tx := DB.MustBeginTx(testHistoryNs)
+		require.NoError(t, tx.Insert(&TestItemHistory{ID: 2, Name: "existing"}))
+		require.NoError(t, tx.Upsert(&TestItemHistory{ID: 4, Name: "new"}))
+		assert.ErrorIs(t, tx.Commit(), reindexer.ErrUniqueViolation)
+
+		history, err := DB.ItemHistory(testHistoryNs, 2)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+		history, err = DB.ItemHistory(testHistoryNs, 3)
+		require.NoError(t, err)
+		assert.Len(t, history, 0)
+		history, err = DB.ItemHistory(testHistoryNs, 4)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+	})
+
+	t.Run("items, modified by queries, are written", func(t *testing.T) {
+		items, err := DB.Query(testHistoryNs).WhereInt("id", reindexer.EQ, 2).Set("name", "updated").Update().FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "updated", items[0].(*TestItemHistory).Name)
+
+		count, err := DB.Query(testHistoryNs).WhereInt("id", reindexer.EQ, 2).Delete()
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		history, err := DB.ItemHistory(testHistoryNs, 2)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		assert.Equal(t, "Update", history[1].Op)
+		assert.Equal(t, "Delete", history[2].Op)
+		var item TestItemHistory
+		require.NoError(t, history[1].Decode(&item))
+		assert.Equal(t, "updated", item.Name)
+	})
+
+	t.Run("queries of transaction are rejected", func(t *testing.T) {
+		tx := DB.MustBeginTx(testHistoryNs)
+		defer tx.Rollback()
+		_, err := tx.Query().WhereInt("id", reindexer.EQ, 4).Delete()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,233 @@
+package reindexer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemCacheStore struct {
+	ID   int    `reindex:"id,,pk"`
+	Data string `reindex:"data"`
+}
+
+const (
+	testItemCacheLRUNs   = "test_items_cache_lru"
+	testItemCacheARCNs   = "test_items_cache_arc"
+	testItemCacheStoreNs = "test_items_cache_store"
+	testItemCacheShardNs = "test_items_cache_shards"
+)
+
+// testMapCacheStore is external cache store without eviction policy, which evicts random items on overflow
+type testMapCacheStore struct {
+	items    map[int]interface{}
+	maxItems int
+	onEvict  func(key int, value interface{})
+	lock     sync.Mutex
+}
+
+func (s *testMapCacheStore) Get(key int) (interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	value, ok := s.items[key]
+	return value, ok
+}
+
+func (s *testMapCacheStore) Add(key int, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.items[key] = value
+	for len(s.items) > s.maxItems && s.removeAny() {
+	}
+}
+
+func (s *testMapCacheStore) Remove(key int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if value, ok := s.items[key]; ok {
+		delete(s.items, key)
+		s.evicted(key, value)
+	}
+}
+
+func (s *testMapCacheStore) RemoveOldest() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.removeAny()
+}
+
+func (s *testMapCacheStore) removeAny() bool {
+	for key, value := range s.items {
+		delete(s.items, key)
+		s.evicted(key, value)
+		return true
+	}
+	return false
+}
+
+func (s *testMapCacheStore) evicted(key int, value interface{}) {
+	if s.onEvict != nil {
+		s.onEvict(key, value)
+	}
+}
+
+func (s *testMapCacheStore) Purge() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for s.removeAny() {
+	}
+}
+
+func (s *testMapCacheStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.items)
+}
+
+func TestItemCacheConfig(t *testing.T) {
+	const (
+		count    = 500
+		maxItems = 100
+		maxBytes = 16 * 1024
+	)
+	stores := make(map[string]*testMapCacheStore)
+	var storesLock sync.Mutex
+	newStore := func(namespace string, maxItems int, onEvict func(key int, value interface{})) (reindexer.CacheStore, error) {
+		storesLock.Lock()
+		defer storesLock.Unlock()
+		s := &testMapCacheStore{items: make(map[int]interface{}), maxItems: maxItems, onEvict: onEvict}
+		stores[namespace] = s
+		return s, nil
+	}
+
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(),
+		reindexer.WithItemCacheConfig(testItemCacheLRUNs, maxItems, 0, reindexer.CacheLRU),
+		reindexer.WithItemCacheConfig(testItemCacheARCNs, maxItems, 0, reindexer.CacheARC),
+		reindexer.WithItemCacheConfig(testItemCacheStoreNs, maxItems, maxBytes, reindexer.CacheLRU),
+		reindexer.WithItemCacheStore(testItemCacheStoreNs, newStore))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+
+	for _, ns := range []string{testItemCacheLRUNs, testItemCacheARCNs, testItemCacheStoreNs} {
+		require.NoError(t, rx.OpenNamespace(ns, reindexer.DefaultNamespaceOptions(), TestItemCacheStore{}))
+		defer rx.DropNamespace(ns)
+		for i := 0; i < count; i++ {
+			require.NoError(t, rx.Upsert(ns, TestItemCacheStore{ID: i, Data: strings.Repeat("x", 256)}))
+		}
+		// Items are read twice, so they are moved to the cache of frequently used items by ARC
+		for i := 0; i < 2; i++ {
+			items, err := rx.Query(ns).Exec().AllowUnsafe(true).FetchAll()
+			require.NoError(t, err)
+			require.Len(t, items, count)
+			for j, item := range items {
+				assert.Equal(t, j, item.(*TestItemCacheStore).ID)
+			}
+		}
+	}
+
+	t.Run("external store is created with limits of the namespace", func(t *testing.T) {
+		storesLock.Lock()
+		s, ok := stores[testItemCacheStoreNs]
+		storesLock.Unlock()
+		require.True(t, ok)
+		assert.Equal(t, maxItems, s.maxItems)
+		assert.NotNil(t, s.onEvict)
+		// Each item takes more than 256 bytes, so size limit is stricter, than count limit
+		assert.Greater(t, s.Len(), 0)
+		assert.Less(t, s.Len(), maxBytes/256)
+	})
+
+	t.Run("memory of dropped namespace is released", func(t *testing.T) {
+		require.NoError(t, rx.DropNamespace(testItemCacheStoreNs))
+		storesLock.Lock()
+		defer storesLock.Unlock()
+		assert.Equal(t, 0, stores[testItemCacheStoreNs].Len())
+	})
+}
+
+func TestItemCacheShards(t *testing.T) {
+	const (
+		count    = 500
+		maxItems = 100
+	)
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(),
+		reindexer.WithItemCacheConfig(testItemCacheShardNs, maxItems, 0, reindexer.CacheLRU),
+		reindexer.WithItemCacheShards(testItemCacheShardNs, 8))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+	require.NoError(t, rx.OpenNamespace(testItemCacheShardNs, reindexer.DefaultNamespaceOptions(), TestItemCacheStore{}))
+	defer rx.DropNamespace(testItemCacheShardNs)
+	for i := 0; i < count; i++ {
+		require.NoError(t, rx.Upsert(testItemCacheShardNs, TestItemCacheStore{ID: i, Data: strings.Repeat("x", 16)}))
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 4; i++ {
+				items, err := rx.Query(testItemCacheShardNs).Exec().AllowUnsafe(true).FetchAll()
+				assert.NoError(t, err)
+				assert.Len(t, items, count)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Limit is divided between the shards, so each shard keeps up to maxItems/8 rounded up
+	stats := rx.ClientStats().NamespaceCaches[testItemCacheShardNs]
+	assert.Greater(t, stats.Items, 0)
+	assert.LessOrEqual(t, stats.Items, 8*((maxItems+7)/8))
+	assert.Greater(t, stats.Hits, int64(0))
+
+	// Items, which fit into the cache, are returned from it
+	items, err := rx.Query(testItemCacheShardNs).WhereInt("id", reindexer.LT, 10).Exec().AllowUnsafe(true).FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 10)
+	hits := rx.ClientStats().NamespaceCaches[testItemCacheShardNs].Hits
+	items, err = rx.Query(testItemCacheShardNs).WhereInt("id", reindexer.LT, 10).Exec().AllowUnsafe(true).FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 10)
+	assert.Equal(t, hits+10, rx.ClientStats().NamespaceCaches[testItemCacheShardNs].Hits)
+}
+
+// BenchmarkItemCacheShards measures concurrent reads of the cached items, which contend for the object cache of the namespace
+func BenchmarkItemCacheShards(b *testing.B) {
+	const count = 1000
+	for _, shards := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(),
+				reindexer.WithItemCacheConfig(testItemCacheShardNs, 2*count, 0, reindexer.CacheLRU),
+				reindexer.WithItemCacheShards(testItemCacheShardNs, shards))
+			defer rx.Close()
+			if err := rx.OpenNamespace(testItemCacheShardNs, reindexer.DefaultNamespaceOptions(), TestItemCacheStore{}); err != nil {
+				b.Fatal(err)
+			}
+			defer rx.DropNamespace(testItemCacheShardNs)
+			for i := 0; i < count; i++ {
+				if err := rx.Upsert(testItemCacheShardNs, TestItemCacheStore{ID: i, Data: strings.Repeat("x", 64)}); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					it := rx.Query(testItemCacheShardNs).Limit(100).Exec().AllowUnsafe(true)
+					for it.Next() {
+					}
+					if err := it.Error(); err != nil {
+						b.Error(err)
+					}
+					it.Close()
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package reindexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemIfVersion struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testIfVersionNs = "test_items_if_version"
+
+func TestUpdateIfVersion(t *testing.T) {
+	DB.CloseNamespace(testIfVersionNs)
+	require.NoError(t, DB.OpenNamespace(testIfVersionNs, reindexer.DefaultNamespaceOptions(), TestItemIfVersion{}))
+	defer DB.DropNamespace(testIfVersionNs)
+	require.NoError(t, DB.Upsert(testIfVersionNs, TestItemIfVersion{ID: 1, Name: "first"}))
+
+	get := func(t *testing.T) (*TestItemIfVersion, int64) {
+		it := DB.Reindexer.Query(testIfVersionNs).WhereInt("id", reindexer.EQ, 1).Exec()
+		defer it.Close()
+		require.True(t, it.Next())
+		require.NoError(t, it.Error())
+		return it.Object().(*TestItemIfVersion), it.Version()
+	}
+	ctx := context.Background()
+
+	item, version := get(t)
+	assert.GreaterOrEqual(t, version, int64(0))
+	item.Name = "second"
+	require.NoError(t, DB.UpdateIfVersion(ctx, testIfVersionNs, item, version))
+
+	item, newVersion := get(t)
+	assert.Equal(t, "second", item.Name)
+	assert.NotEqual(t, version, newVersion)
+
+	// Item was modified since version was read
+	err := DB.UpdateIfVersion(ctx, testIfVersionNs, &TestItemIfVersion{ID: 1, Name: "third"}, version)
+	var verr *reindexer.VersionConflictError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, newVersion, verr.Actual)
+	assert.True(t, errors.Is(err, reindexer.ErrVersionConflict))
+	item, _ = get(t)
+	assert.Equal(t, "second", item.Name)
+
+	err = DB.UpdateIfVersion(ctx, testIfVersionNs, &TestItemIfVersion{ID: 2}, version)
+	assert.True(t, errors.Is(err, reindexer.ErrNotFound))
+}
@@ -2,7 +2,9 @@ package reindexer
 
 import (
 	"context"
+	"errors"
 	"math/rand"
+	"strconv"
 	"testing"
 
 	"github.com/restream/reindexer/v3"
@@ -14,6 +16,20 @@ import (
 func init() {
 	tnamespaces["test_items_iter"] = TestItem{}
 	tnamespaces["test_items_iter_next_obj"] = TestItem{}
+	tnamespaces["test_items_iter_as_map"] = TestItemAsMap{}
+	tnamespaces["test_items_iter_stream"] = TestItem{}
+	tnamespaces["test_items_iter_collect"] = TestItem{}
+}
+
+type TestItemAsMapNested struct {
+	Rating float64  `json:"rating"`
+	Tags   []string `json:"tags"`
+}
+
+type TestItemAsMap struct {
+	ID     int                 `reindex:"id,,pk" json:"id"`
+	Name   string              `reindex:"name" json:"name"`
+	Nested TestItemAsMapNested `json:"nested"`
 }
 
 func TestQueryIter(t *testing.T) {
@@ -123,3 +139,124 @@ func TestNextObj(t *testing.T) {
 		assert.NoError(t, it.Error())
 	})
 }
+
+func TestObjectAsMap(t *testing.T) {
+	const ns = "test_items_iter_as_map"
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, DB.Upsert(ns, TestItemAsMap{ID: i, Name: "item" + strconv.Itoa(i), Nested: TestItemAsMapNested{Rating: 0.5, Tags: []string{"a", "b"}}}))
+	}
+	expected := map[string]interface{}{
+		"id":   1,
+		"name": "item1",
+		"nested": map[string]interface{}{
+			"rating": 0.5,
+			"tags":   []interface{}{"a", "b"},
+		},
+	}
+
+	t.Run("iterator decodes current object into map", func(t *testing.T) {
+		it := DB.Query(ns).WhereInt("id", reindexer.EQ, 1).Exec(t)
+		defer it.Close()
+		require.True(t, it.Next())
+		item, err := it.ObjectAsMap()
+		require.NoError(t, err)
+		assert.Equal(t, expected, item)
+	})
+
+	t.Run("query results are decoded into maps", func(t *testing.T) {
+		items, err := DB.Query(ns).Sort("id", false).q.ExecToMaps(context.Background())
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		assert.Equal(t, expected, items[1])
+	})
+}
+
+func TestIteratorStream(t *testing.T) {
+	const ns = "test_items_iter_stream"
+	const total = 50
+	for i := 0; i < total; i++ {
+		assert.NoError(t, DB.Upsert(ns, newTestItem(i, 5)))
+	}
+
+	t.Run("all the results are streamed by chunks", func(t *testing.T) {
+		ids := make([]int, 0, total)
+		for si := range DB.Query(ns).Sort("id", false).q.FetchCount(7).Exec().Stream(context.Background()) {
+			require.NoError(t, si.Err)
+			ids = append(ids, si.Item.(*TestItem).ID)
+		}
+		require.Len(t, ids, total)
+		for i, id := range ids {
+			assert.Equal(t, i, id)
+		}
+	})
+
+	t.Run("stream is stopped by context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := DB.Query(ns).q.FetchCount(5).Exec().Stream(ctx)
+		si := <-ch
+		require.NoError(t, si.Err)
+		cancel()
+		for range ch {
+		}
+	})
+
+	t.Run("error of the query is streamed", func(t *testing.T) {
+		var errs []error
+		for si := range DB.Reindexer.Query("test_items_iter_stream_missing").Exec().Stream(context.Background()) {
+			errs = append(errs, si.Err)
+		}
+		require.Len(t, errs, 1)
+		assert.Error(t, errs[0])
+	})
+
+	t.Run("ForEach stops on the error of callback", func(t *testing.T) {
+		count := 0
+		stop := errors.New("stop")
+		err := DB.Query(ns).q.FetchCount(10).Exec().ForEach(func(item interface{}) error {
+			if count++; count == 15 {
+				return stop
+			}
+			return nil
+		})
+		assert.Equal(t, stop, err)
+		assert.Equal(t, 15, count)
+
+		count = 0
+		require.NoError(t, DB.Query(ns).q.Exec().ForEach(func(item interface{}) error {
+			count++
+			return nil
+		}))
+		assert.Equal(t, total, count)
+	})
+}
+
+func TestIteratorCollect(t *testing.T) {
+	const ns = "test_items_iter_collect"
+	const total = 30
+	for i := 0; i < total; i++ {
+		assert.NoError(t, DB.Upsert(ns, newTestItem(i, 5)))
+	}
+
+	t.Run("results are collected into slice of pointers", func(t *testing.T) {
+		var items []*TestItem
+		require.NoError(t, DB.Query(ns).Sort("id", false).q.FetchCount(7).Exec().Collect(&items))
+		require.Len(t, items, total)
+		for i, item := range items {
+			assert.Equal(t, i, item.ID)
+		}
+	})
+
+	t.Run("results are appended to slice of values", func(t *testing.T) {
+		items := []TestItem{{ID: -1}}
+		require.NoError(t, DB.Query(ns).WhereInt("id", reindexer.LT, 5).Sort("id", false).Exec(t).Collect(&items))
+		require.Len(t, items, 6)
+		assert.Equal(t, -1, items[0].ID)
+		assert.Equal(t, 4, items[5].ID)
+	})
+
+	t.Run("wrong destination is rejected", func(t *testing.T) {
+		var items []TestItemAsMap
+		assert.Error(t, DB.Query(ns).Exec(t).Collect(&items))
+		assert.Error(t, DB.Query(ns).Exec(t).Collect(items))
+	})
+}
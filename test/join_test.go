@@ -1,6 +1,7 @@
 package reindexer
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"sort"
@@ -444,6 +445,63 @@ func TestJoinQueryResultsOnIterator(t *testing.T) {
 	}
 }
 
+func TestJoinQueryResultsToJson(t *testing.T) {
+	newQuery := func(join bool) *reindexer.Query {
+		qjoin := DB.Query("test_items_for_join").Where("GENRE", reindexer.EQ, 10).Sort("ID", false).Limit(10)
+		if join {
+			qj1 := DB.Query("test_join_items").Where("DEVICE", reindexer.EQ, "ottstb").Sort("name", false)
+			qj2 := DB.Query("test_join_items").Where("DEVICE", reindexer.EQ, "android")
+			qjoin.LeftJoin(qj1, "PRICES").On("PRICE_ID", reindexer.SET, "ID").
+				LeftJoin(qj2, "PRICESX").On("LOCATION", reindexer.EQ, "LOCATION").On("PRICE_ID", reindexer.SET, "Id")
+		}
+		return qjoin.q
+	}
+
+	items, err := newQuery(true).Exec().FetchAll()
+	require.NoError(t, err)
+
+	plainIter := newQuery(false).ExecToJson()
+	defer plainIter.Close()
+	require.NoError(t, plainIter.Error())
+	jsonIter := newQuery(true).ExecToJson()
+	defer jsonIter.Close()
+	require.NoError(t, jsonIter.Error())
+	require.Equal(t, len(items), jsonIter.Count())
+	require.Equal(t, len(items), plainIter.Count())
+
+	for i := 0; jsonIter.Next(); i++ {
+		require.True(t, plainIter.Next())
+		var plain, item map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(plainIter.JSON(), &plain), string(plainIter.JSON()))
+		require.NoError(t, json.Unmarshal(jsonIter.JSON(), &item), string(jsonIter.JSON()))
+
+		// Joined items are keyed by the joined fields, and the fields, embedded by the server, are removed
+		expectedKeys := []string{"PRICES", "PRICESX"}
+		for k := range plain {
+			expectedKeys = append(expectedKeys, k)
+		}
+		keys := make([]string, 0, len(item))
+		for k := range item {
+			keys = append(keys, k)
+		}
+		assert.ElementsMatch(t, expectedKeys, keys, string(jsonIter.JSON()))
+
+		expected := items[i].(*TestItem)
+		var prices, pricesx []TestJoinItem
+		require.NoError(t, json.Unmarshal(item["PRICES"], &prices))
+		require.NoError(t, json.Unmarshal(item["PRICESX"], &pricesx))
+		require.Len(t, prices, len(expected.Prices))
+		require.Len(t, pricesx, len(expected.Pricesx))
+		for j := range expected.Prices {
+			assert.Equal(t, expected.Prices[j].ID, prices[j].ID)
+		}
+		for j := range expected.Pricesx {
+			assert.Equal(t, expected.Pricesx[j].ID, pricesx[j].ID)
+		}
+	}
+	require.NoError(t, jsonIter.Error())
+}
+
 type explainNs struct {
 	Id                int          `reindex:"id,,pk"`
 	Data              int          `reindex:"data"`
@@ -0,0 +1,92 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestJoinedItemsParent struct {
+	ID      int `reindex:"id,,pk"`
+	ChildID int `reindex:"child_id"`
+}
+
+type TestJoinedItemsChild struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const (
+	testJoinedItemsParentNs = "test_items_joined_items_parent"
+	testJoinedItemsChildNs  = "test_items_joined_items_child"
+)
+
+func init() {
+	tnamespaces[testJoinedItemsParentNs] = TestJoinedItemsParent{}
+	tnamespaces[testJoinedItemsChildNs] = TestJoinedItemsChild{}
+}
+
+func checkJoinedItems(t *testing.T, it *reindexer.Iterator, field string) {
+	count := 0
+	for it.Next() {
+		item := it.Object().(*TestJoinedItemsParent)
+		objects, err := it.JoinedItemsFor(field)
+		require.NoError(t, err)
+		children, err := reindexer.JoinedItems[*TestJoinedItemsChild](it, field)
+		require.NoError(t, err)
+		if item.ChildID == 1 {
+			require.Len(t, objects, 1)
+			require.Len(t, children, 1)
+			assert.Equal(t, "child_1", children[0].Name)
+		} else {
+			assert.Empty(t, objects)
+			assert.Empty(t, children)
+		}
+
+		_, err = it.JoinedItemsFor("unknown")
+		assert.Error(t, err)
+		_, err = reindexer.JoinedItems[*TestJoinedItemsParent](it, field)
+		if item.ChildID == 1 {
+			assert.Error(t, err)
+		}
+		count++
+	}
+	require.NoError(t, it.Error())
+	assert.Equal(t, 6, count)
+}
+
+func TestJoinedItemsFor(t *testing.T) {
+	for i := 0; i < 6; i++ {
+		require.NoError(t, DB.Upsert(testJoinedItemsParentNs, TestJoinedItemsParent{ID: i, ChildID: i % 3}))
+	}
+	require.NoError(t, DB.Upsert(testJoinedItemsChildNs, TestJoinedItemsChild{ID: 1, Name: "child_1"}))
+
+	t.Run("sql query without join field", func(t *testing.T) {
+		it := DB.Reindexer.ExecSQL("SELECT * FROM " + testJoinedItemsParentNs + " LEFT JOIN " + testJoinedItemsChildNs +
+			" ON " + testJoinedItemsParentNs + ".child_id = " + testJoinedItemsChildNs + ".id ORDER BY id")
+		defer it.Close()
+		require.NoError(t, it.Error())
+		checkJoinedItems(t, it, testJoinedItemsChildNs)
+	})
+
+	t.Run("query with join handler", func(t *testing.T) {
+		q := DB.Reindexer.Query(testJoinedItemsParentNs).Sort("id", false)
+		q.LeftJoin(DB.Reindexer.Query(testJoinedItemsChildNs), "children").On("child_id", reindexer.EQ, "id")
+		q.JoinHandler("children", func(field string, item interface{}, subitems []interface{}) bool {
+			return false
+		})
+		it := q.Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		checkJoinedItems(t, it, "children")
+	})
+
+	t.Run("query without join field", func(t *testing.T) {
+		q := DB.Reindexer.Query(testJoinedItemsParentNs).Sort("id", false)
+		q.LeftJoin(DB.Reindexer.Query(testJoinedItemsChildNs), "children").On("child_id", reindexer.EQ, "id")
+		_, err := q.Exec().FetchAll()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,73 @@
+package reindexer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemJSONAggregations struct {
+	ID   int `reindex:"id,,pk"`
+	Year int `reindex:"year,tree"`
+}
+
+const testJSONAggregationsNs = "test_items_json_aggregations"
+
+func init() {
+	tnamespaces[testJSONAggregationsNs] = TestItemJSONAggregations{}
+}
+
+func TestJSONAggregations(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testJSONAggregationsNs, TestItemJSONAggregations{ID: i, Year: 2000 + i}))
+	}
+
+	t.Run("aggregations are not in output by default", func(t *testing.T) {
+		q := DB.Query(testJSONAggregationsNs).Limit(1)
+		q.q.AggregateMin("year")
+		it := q.q.ExecToJson("items")
+		assert.Equal(t, 1, len(it.AggResults()))
+		data, err := it.FetchAll()
+		require.NoError(t, err)
+		var out map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(data, &out))
+		assert.NotContains(t, out, "aggregations")
+		assert.Contains(t, out, "items")
+	})
+
+	t.Run("aggregations are in the key", func(t *testing.T) {
+		q := DB.Query(testJSONAggregationsNs).Limit(2)
+		q.q.AggregationsJsonName("aggregations").AggregateMax("year")
+		it := q.q.ExecToJson()
+		require.NoError(t, it.Error())
+		aggs := it.AggResults()
+		require.Equal(t, 1, len(aggs))
+		require.NotNil(t, aggs[0].Value)
+		assert.Equal(t, 2009.0, *aggs[0].Value)
+
+		data, err := it.FetchAll()
+		require.NoError(t, err)
+		var out struct {
+			Aggregations []reindexer.AggregationResult `json:"aggregations"`
+			Items        []TestItemJSONAggregations    `json:"test_items_json_aggregations"`
+		}
+		require.NoError(t, json.Unmarshal(data, &out))
+		require.Equal(t, 1, len(out.Aggregations))
+		assert.Equal(t, 2009.0, *out.Aggregations[0].Value)
+		assert.Equal(t, 2, len(out.Items))
+	})
+
+	t.Run("custom key", func(t *testing.T) {
+		q := DB.Query(testJSONAggregationsNs).Limit(1)
+		q.q.AggregationsJsonName("aggs").AggregateMin("year")
+		data, err := q.q.ExecToJson("items").FetchAll()
+		require.NoError(t, err)
+		var out map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(data, &out))
+		assert.Contains(t, out, "aggs")
+		assert.Contains(t, out, "items")
+	})
+}
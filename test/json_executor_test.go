@@ -0,0 +1,62 @@
+package reindexer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemJSONExecutor struct {
+	ID   int    `reindex:"id,,pk" json:"id"`
+	Name string `reindex:"name" json:"name"`
+}
+
+const testJSONExecutorNs = "test_items_json_executor"
+
+func init() {
+	tnamespaces[testJSONExecutorNs] = TestItemJSONExecutor{}
+}
+
+func TestJSONExecutor(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testJSONExecutorNs, TestItemJSONExecutor{ID: i, Name: "name"}))
+	}
+	executor := DB.NewJSONExecutor(testJSONExecutorNs)
+
+	t.Run("results are returned in JSON format", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			it := executor.Exec(executor.Query().WhereInt("id", reindexer.EQ, i), "items")
+			json, err := it.FetchAll()
+			require.NoError(t, err)
+			assert.JSONEq(t, fmt.Sprintf(`{"items":[{"id":%d,"name":"name"}]}`, i), string(json))
+		}
+	})
+
+	t.Run("results must be closed before the next call", func(t *testing.T) {
+		it := executor.Exec(executor.Query().Sort("id", false))
+		require.NoError(t, it.Error())
+		assert.Equal(t, 10, it.Count())
+
+		busy := executor.Exec(executor.Query())
+		assert.Equal(t, reindexer.ErrJSONExecutorBusy, busy.Error())
+		busy.Close()
+
+		require.True(t, it.Next())
+		assert.JSONEq(t, `{"id":0,"name":"name"}`, string(it.JSON()))
+		it.Close()
+
+		it = executor.Exec(executor.Query().Limit(1))
+		defer it.Close()
+		assert.NoError(t, it.Error())
+	})
+
+	t.Run("errors are returned", func(t *testing.T) {
+		missing := DB.NewJSONExecutor("nonexistent_json_executor_ns")
+		it := missing.Exec(missing.Query())
+		defer it.Close()
+		assert.Error(t, it.Error())
+	})
+}
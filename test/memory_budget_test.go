@@ -0,0 +1,70 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemMemoryBudget struct {
+	ID   int    `reindex:"id,,pk"`
+	Data string `reindex:"data"`
+}
+
+const (
+	testMemoryBudgetNs1 = "test_items_memory_budget_1"
+	testMemoryBudgetNs2 = "test_items_memory_budget_2"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	const budget = 64 * 1024
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithMemoryBudget(budget))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+
+	const count = 500
+	for _, ns := range []string{testMemoryBudgetNs1, testMemoryBudgetNs2} {
+		require.NoError(t, rx.OpenNamespace(ns, reindexer.DefaultNamespaceOptions(), TestItemMemoryBudget{}))
+		defer rx.DropNamespace(ns)
+		for i := 0; i < count; i++ {
+			require.NoError(t, rx.Upsert(ns, TestItemMemoryBudget{ID: i, Data: strings.Repeat("x", 256)}))
+		}
+	}
+
+	t.Run("caches are limited by budget", func(t *testing.T) {
+		for _, ns := range []string{testMemoryBudgetNs1, testMemoryBudgetNs2} {
+			items, err := rx.Query(ns).Exec().AllowUnsafe(true).FetchAll()
+			require.NoError(t, err)
+			require.Len(t, items, count)
+		}
+		stats := rx.ClientStats()
+		assert.Equal(t, int64(budget), stats.MemoryBudget)
+		assert.Greater(t, stats.CacheMemory, int64(0))
+		assert.LessOrEqual(t, stats.CacheMemory, int64(budget))
+		assert.Greater(t, stats.CacheEvictions, int64(0))
+	})
+
+	t.Run("JSON buffers are charged until iterator is closed", func(t *testing.T) {
+		it := rx.Query(testMemoryBudgetNs1).ExecToJson()
+		require.NoError(t, it.Error())
+		stats := rx.ClientStats()
+		assert.Greater(t, stats.JSONMemory, int64(0))
+		// Caches are evicted to fit JSON buffer into the budget
+		assert.LessOrEqual(t, stats.CacheMemory, int64(budget))
+		it.Close()
+		assert.Equal(t, int64(0), rx.ClientStats().JSONMemory)
+	})
+
+	t.Run("memory of dropped namespace is released", func(t *testing.T) {
+		require.NoError(t, rx.DropNamespace(testMemoryBudgetNs1))
+		require.NoError(t, rx.DropNamespace(testMemoryBudgetNs2))
+		assert.Equal(t, int64(0), rx.ClientStats().CacheMemory)
+	})
+}
@@ -0,0 +1,46 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemMsgPack struct {
+	ID   int    `reindex:"id,,pk" json:"id"`
+	Name string `reindex:"name" json:"name"`
+}
+
+const testMsgPackNs = "test_items_msgpack"
+
+func init() {
+	tnamespaces[testMsgPackNs] = TestItemMsgPack{}
+}
+
+// {"id":1,"name":"a"} in msgpack format
+var testMsgPackItem = []byte{0x82, 0xa2, 'i', 'd', 0x01, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'a'}
+
+func TestMsgPack(t *testing.T) {
+	require.NoError(t, DB.UpsertMsgPack(testMsgPackNs, testMsgPackItem))
+
+	item, found := DB.Query(testMsgPackNs).WhereInt("id", reindexer.EQ, 1).Get()
+	require.True(t, found)
+	assert.Equal(t, "a", item.(*TestItemMsgPack).Name)
+
+	t.Run("items of the results in msgpack format", func(t *testing.T) {
+		if !strings.HasPrefix(*dsn, "cproto") {
+			t.Skip()
+		}
+		it := DB.Query(testMsgPackNs).q.WhereInt("id", reindexer.EQ, 1).MsgPack().Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		require.True(t, it.Next())
+		data := it.MsgPack()
+		require.NotEmpty(t, data)
+		// Item is encoded as fixmap
+		assert.Equal(t, byte(0x80), data[0]&0xf0)
+	})
+}
@@ -0,0 +1,53 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestOpenAsyncItem struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+	Year int    `reindex:"year,tree"`
+}
+
+type TestOpenAsyncInvalidItem struct {
+	ID []int `reindex:"id,,pk"`
+}
+
+const (
+	testOpenAsyncNs        = "test_open_namespace_async"
+	testOpenAsyncInvalidNs = "test_open_namespace_async_invalid"
+)
+
+func TestOpenNamespaceAsync(t *testing.T) {
+	t.Run("namespace is usable after handle is done", func(t *testing.T) {
+		handle, err := DB.OpenNamespaceAsync(testOpenAsyncNs, reindexer.DefaultNamespaceOptions(), TestOpenAsyncItem{})
+		require.NoError(t, err)
+		defer DB.DropNamespace(testOpenAsyncNs)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, handle.WaitCtx(ctx))
+		progress := handle.Progress()
+		assert.Equal(t, reindexer.OpenNamespaceDone, progress.Stage)
+		assert.Equal(t, 3, progress.IndexesTotal)
+		assert.Equal(t, 3, progress.IndexesDone)
+
+		require.NoError(t, DB.Upsert(testOpenAsyncNs, TestOpenAsyncItem{ID: 1, Name: "one", Year: 2000}))
+		item, found := DB.Query(testOpenAsyncNs).WhereInt("id", reindexer.EQ, 1).Get()
+		require.True(t, found)
+		assert.Equal(t, "one", item.(*TestOpenAsyncItem).Name)
+	})
+
+	t.Run("invalid struct is reported synchronously", func(t *testing.T) {
+		handle, err := DB.OpenNamespaceAsync(testOpenAsyncInvalidNs, reindexer.DefaultNamespaceOptions().StrictTags(), TestOpenAsyncInvalidItem{})
+		assert.Error(t, err)
+		assert.Nil(t, handle)
+	})
+}
@@ -0,0 +1,69 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemPaginate struct {
+	ID   int    `reindex:"id,,pk"`
+	Year int    `reindex:"year"`
+	Name string `reindex:"name"`
+}
+
+const testPaginateNs = "test_items_paginate"
+
+func init() {
+	tnamespaces[testPaginateNs] = TestItemPaginate{}
+}
+
+func TestPaginate(t *testing.T) {
+	const itemsCount = 25
+	for i := 0; i < itemsCount; i++ {
+		// Years are duplicated, so the order of the pages depends on the primary key tiebreaker
+		require.NoError(t, DB.Upsert(testPaginateNs, TestItemPaginate{ID: i, Year: 2000 + i%3, Name: "item"}))
+	}
+
+	readAll := func(t *testing.T, cursor bool, desc bool) []int {
+		var ids []int
+		page := reindexer.PageRequest{Size: 7, Cursor: cursor}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, itemsCount)
+			q := DB.Query(testPaginateNs).Sort("year", desc).q
+			res, err := reindexer.Paginate[*TestItemPaginate](q, page)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(res.Items), page.Size)
+			if !cursor || page.Token == "" {
+				assert.Equal(t, itemsCount, res.Total)
+			}
+			for _, item := range res.Items {
+				ids = append(ids, item.ID)
+			}
+			if res.NextToken == "" {
+				return ids
+			}
+			page.Token = res.NextToken
+		}
+	}
+
+	for _, cursor := range []bool{false, true} {
+		for _, desc := range []bool{false, true} {
+			ids := readAll(t, cursor, desc)
+			require.Len(t, ids, itemsCount, "cursor=%v desc=%v", cursor, desc)
+			seen := make(map[int]bool, itemsCount)
+			for _, id := range ids {
+				assert.False(t, seen[id], "item %d is returned twice (cursor=%v desc=%v)", id, cursor, desc)
+				seen[id] = true
+			}
+			assert.Equal(t, ids, readAll(t, cursor, desc), "order is not deterministic (cursor=%v desc=%v)", cursor, desc)
+		}
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := reindexer.Paginate[*TestItemPaginate](DB.Query(testPaginateNs).q, reindexer.PageRequest{Token: "???"})
+		assert.ErrorIs(t, err, reindexer.ErrInvalidPageToken)
+	})
+}
@@ -0,0 +1,46 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemPprofLabels struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testPprofLabelsNs = "test_items_pprof_labels"
+
+func TestPprofLabels(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithPprofLabels())
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+
+	require.NoError(t, rx.OpenNamespace(testPprofLabelsNs, reindexer.DefaultNamespaceOptions(), TestItemPprofLabels{}))
+	defer rx.DropNamespace(testPprofLabelsNs)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, rx.Upsert(testPprofLabelsNs, TestItemPprofLabels{ID: i, Name: "name"}))
+	}
+
+	t.Run("labeled query returns items", func(t *testing.T) {
+		items, err := rx.Query(testPprofLabelsNs).Label("all_items").Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 10)
+	})
+
+	t.Run("labeled json query returns items", func(t *testing.T) {
+		it := rx.Query(testPprofLabelsNs).Label("all_items_json").ExecToJson()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		assert.Equal(t, 10, it.Count())
+	})
+}
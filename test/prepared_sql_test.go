@@ -0,0 +1,78 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestPreparedSQLItem struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+	Year int    `reindex:"year,tree"`
+}
+
+const testPreparedSQLNs = "test_prepared_sql"
+
+func init() {
+	tnamespaces[testPreparedSQLNs] = TestPreparedSQLItem{}
+}
+
+func TestPreparedSQL(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testPreparedSQLNs, TestPreparedSQLItem{ID: i, Name: "name's " + string(rune('a'+i)), Year: 2000 + i}))
+	}
+
+	stmt, err := DB.PrepareSQL("SELECT * FROM " + testPreparedSQLNs + " WHERE year > $1 AND id IN $2 ORDER BY id")
+	require.NoError(t, err)
+
+	t.Run("arguments are bound on each execution", func(t *testing.T) {
+		items, err := stmt.ExecCtx(context.Background(), 2003, []int{1, 5, 7}).FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, 5, items[0].(*TestPreparedSQLItem).ID)
+		assert.Equal(t, 7, items[1].(*TestPreparedSQLItem).ID)
+
+		items, err = stmt.Exec(2000, []int{1, 2}).FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+	})
+
+	t.Run("string arguments are escaped", func(t *testing.T) {
+		stmt, err := DB.PrepareSQL("SELECT * FROM " + testPreparedSQLNs + " WHERE name = $1")
+		require.NoError(t, err)
+		items, err := stmt.Exec("name's c").FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, 2, items[0].(*TestPreparedSQLItem).ID)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		_, err := stmt.Exec(2000).FetchAll()
+		assert.Error(t, err)
+		_, err = stmt.Exec(struct{}{}, []int{1}).FetchAll()
+		assert.Error(t, err)
+		_, err = DB.PrepareSQL("SELECT * FROM " + testPreparedSQLNs + " WHERE id = $2")
+		assert.Error(t, err)
+		_, err = DB.PrepareSQL("SELECT * FROM " + testPreparedSQLNs + " WHERE id = $")
+		assert.Error(t, err)
+	})
+
+	t.Run("quoted names", func(t *testing.T) {
+		stmt, err := DB.PrepareSQL("SELECT * FROM " + testPreparedSQLNs + " WHERE \"year\" = $1 OR \"id\" = $2")
+		require.NoError(t, err)
+		items, err := stmt.Exec(2001, 3).FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+	})
+
+	t.Run("placeholders in strings are not replaced", func(t *testing.T) {
+		stmt, err := DB.PrepareSQL("SELECT * FROM " + testPreparedSQLNs + " WHERE name = '$1' OR id = $1")
+		require.NoError(t, err)
+		items, err := stmt.Exec(0).FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+	})
+}
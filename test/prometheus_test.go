@@ -0,0 +1,111 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemPrometheus struct {
+	ID int `reindex:"id,,pk"`
+}
+
+const testPrometheusNs = "test_items_prometheus"
+
+func TestPrometheusCustomRegisterer(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	registry := prometheus.NewRegistry()
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithPrometheusMetrics(reindexer.PrometheusMetricsOptions{
+		Registerer:  registry,
+		Prefix:      "myapp",
+		ConstLabels: prometheus.Labels{"instance": "first"},
+	}))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+
+	require.NoError(t, rx.OpenNamespace(testPrometheusNs, reindexer.DefaultNamespaceOptions(), TestItemPrometheus{}))
+	defer rx.DropNamespace(testPrometheusNs)
+	require.NoError(t, rx.Upsert(testPrometheusNs, TestItemPrometheus{ID: 1}))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	names := make(map[string]int)
+	for i, f := range families {
+		names[f.GetName()] = i
+	}
+	assert.Contains(t, names, "myapp_client_open_iterators")
+	assert.Contains(t, names, "myapp_client_tx_in_flight")
+	assert.Contains(t, names, "myapp_client_retries_total")
+	assert.Contains(t, names, "myapp_client_retries_abandoned_total")
+	require.Contains(t, names, "myapp_client_calls_latency_seconds")
+	latency := families[names["myapp_client_calls_latency_seconds"]]
+
+	cmds := make(map[string]bool)
+	for _, m := range latency.GetMetric() {
+		labels := make(map[string]string)
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		assert.Equal(t, "first", labels["instance"])
+		if labels["ns"] == testPrometheusNs {
+			cmds[labels["cmd"]] = true
+		}
+	}
+	assert.True(t, cmds["Upsert"], "metrics: %v", cmds)
+}
+
+func TestPrometheusNamespaceCacheMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithPrometheusMetrics(reindexer.PrometheusMetricsOptions{
+		Registerer: registry,
+		Prefix:     "nscache",
+	}))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+
+	require.NoError(t, rx.OpenNamespace(testPrometheusNs, reindexer.DefaultNamespaceOptions(), TestItemPrometheus{}))
+	defer rx.DropNamespace(testPrometheusNs)
+	const count = 10
+	for i := 0; i < count; i++ {
+		require.NoError(t, rx.Upsert(testPrometheusNs, TestItemPrometheus{ID: i}))
+	}
+	// Items are decoded by the first query and are taken from the cache by the second one
+	for i := 0; i < 2; i++ {
+		items, err := rx.Query(testPrometheusNs).Exec().AllowUnsafe(true).FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, count)
+	}
+
+	stats, ok := rx.ClientStats().NamespaceCaches[testPrometheusNs]
+	require.True(t, ok)
+	assert.Equal(t, int64(count), stats.Hits)
+	assert.Equal(t, int64(count), stats.Misses)
+	assert.Equal(t, int64(count), stats.Decodes)
+	assert.Equal(t, count, stats.Items)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "ns" && l.GetValue() == testPrometheusNs {
+					values[f.GetName()] = m.GetCounter().GetValue() + m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	assert.Equal(t, float64(count), values["nscache_client_cache_hits_total"])
+	assert.Equal(t, float64(count), values["nscache_client_cache_misses_total"])
+	assert.Equal(t, float64(count), values["nscache_client_cache_items"])
+	assert.Equal(t, float64(count), values["nscache_client_decoded_items_total"])
+	assert.Contains(t, values, "nscache_client_cache_evictions_total")
+	assert.Contains(t, values, "nscache_client_decode_seconds_total")
+}
@@ -0,0 +1,85 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemQueryCache struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testQueryCacheNs = "test_items_query_cache"
+
+func TestQueryCachePolicy(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	// Writes of another client don't invalidate cached results
+	other := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing())
+	defer other.Close()
+	require.NoError(t, other.Status().Err)
+
+	DB.CloseNamespace(testQueryCacheNs)
+	err := DB.OpenNamespace(testQueryCacheNs, reindexer.DefaultNamespaceOptions(), TestItemQueryCache{})
+	require.NoError(t, err)
+	defer DB.DropNamespace(testQueryCacheNs)
+	require.NoError(t, other.RegisterNamespace(testQueryCacheNs, reindexer.DefaultNamespaceOptions(), TestItemQueryCache{}))
+
+	for i := 0; i < 300; i++ {
+		require.NoError(t, DB.Upsert(testQueryCacheNs, TestItemQueryCache{ID: i, Name: "item"}))
+	}
+
+	cachedQuery := func(ttl time.Duration) *reindexer.Query {
+		return DB.Query(testQueryCacheNs).q.WhereInt("id", reindexer.GE, 0).ReqTotal().CachePolicy(ttl, 10)
+	}
+	fetch := func(q *reindexer.Query) ([]interface{}, int) {
+		it := q.Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		items, err := it.FetchAll()
+		require.NoError(t, err)
+		return items, it.TotalCount()
+	}
+
+	items, total := fetch(cachedQuery(time.Minute))
+	assert.Len(t, items, 300)
+	assert.Equal(t, 300, total)
+
+	t.Run("results are cached", func(t *testing.T) {
+		require.NoError(t, other.Delete(testQueryCacheNs, TestItemQueryCache{ID: 0}))
+		items, total := fetch(cachedQuery(time.Minute))
+		assert.Len(t, items, 300)
+		assert.Equal(t, 300, total)
+	})
+
+	t.Run("write of client invalidates cache", func(t *testing.T) {
+		require.NoError(t, DB.Upsert(testQueryCacheNs, TestItemQueryCache{ID: 300, Name: "item"}))
+		items, _ := fetch(cachedQuery(time.Minute))
+		assert.Len(t, items, 300)
+	})
+
+	t.Run("expired results are not used", func(t *testing.T) {
+		DB.InvalidateQueryCache(testQueryCacheNs)
+		fetch(cachedQuery(time.Millisecond))
+		require.NoError(t, other.Delete(testQueryCacheNs, TestItemQueryCache{ID: 1}))
+		time.Sleep(10 * time.Millisecond)
+		items, _ := fetch(cachedQuery(time.Millisecond))
+		assert.Len(t, items, 299)
+	})
+
+	t.Run("explicit invalidation", func(t *testing.T) {
+		fetch(cachedQuery(time.Minute))
+		require.NoError(t, other.Delete(testQueryCacheNs, TestItemQueryCache{ID: 2}))
+		DB.InvalidateQueryCache(testQueryCacheNs)
+		items, _ := fetch(cachedQuery(time.Minute))
+		assert.Len(t, items, 298)
+	})
+}
@@ -0,0 +1,137 @@
+package reindexer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemQueryDSL struct {
+	ID     int     `reindex:"id,,pk" json:"id"`
+	Year   int     `reindex:"year,tree" json:"year"`
+	Price  float64 `reindex:"price" json:"price"`
+	Genre  string  `reindex:"genre" json:"genre"`
+	Author int     `reindex:"author" json:"author"`
+}
+
+type TestAuthorQueryDSL struct {
+	ID      int  `reindex:"id,,pk" json:"id"`
+	Visible bool `reindex:"visible" json:"visible"`
+}
+
+const (
+	testQueryDSLNs        = "test_items_query_dsl"
+	testQueryDSLAuthorsNs = "test_items_query_dsl_authors"
+)
+
+func init() {
+	tnamespaces[testQueryDSLNs] = TestItemQueryDSL{}
+	tnamespaces[testQueryDSLAuthorsNs] = TestAuthorQueryDSL{}
+}
+
+func fetchQueryDSLIDs(t *testing.T, q *reindexer.Query) []int {
+	it := q.Exec()
+	defer it.Close()
+	require.NoError(t, it.Error())
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Object().(*TestItemQueryDSL).ID)
+	}
+	return ids
+}
+
+func TestQueryDSL(t *testing.T) {
+	genres := []string{"drama", "comedy", "horror"}
+	for i := 0; i < 30; i++ {
+		item := TestItemQueryDSL{ID: i, Year: 2000 + i%10, Price: float64(i) + 0.5, Genre: genres[i%3], Author: i % 5}
+		require.NoError(t, DB.Upsert(testQueryDSLNs, item))
+	}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, DB.Upsert(testQueryDSLAuthorsNs, TestAuthorQueryDSL{ID: i, Visible: i%2 == 0}))
+	}
+
+	t.Run("query is restored from its DSL", func(t *testing.T) {
+		newQuery := func() *reindexer.Query {
+			q := DB.Reindexer.Query(testQueryDSLNs).
+				WhereInt("year", reindexer.GE, 2003).
+				OpenBracket().
+				WhereString("genre", reindexer.EQ, "drama").
+				Or().WhereDouble("price", reindexer.LT, 10).
+				CloseBracket().
+				Sort("year", true).Sort("id", false).
+				Limit(8).ReqTotal()
+			q.InnerJoin(DB.Reindexer.Query(testQueryDSLAuthorsNs).WhereBool("visible", reindexer.EQ, true), "authors").
+				On("author", reindexer.EQ, "id")
+			return q.JoinHandler("authors", func(field string, item interface{}, subitems []interface{}) bool {
+				return false
+			})
+		}
+		dsl, err := newQuery().DSL()
+		require.NoError(t, err)
+
+		q, err := DB.QueryFromDSL(dsl)
+		require.NoError(t, err)
+		restored, err := q.DSL()
+		require.NoError(t, err)
+		assert.JSONEq(t, string(dsl), string(restored))
+		assert.Equal(t, fetchQueryDSLIDs(t, newQuery()), fetchQueryDSLIDs(t, q))
+	})
+
+	t.Run("aggregations and merged queries are supported", func(t *testing.T) {
+		dsl := `{
+			"namespace": "test_items_query_dsl",
+			"filters": [{"op": "and", "cond": "lt", "field": "id", "value": 3}],
+			"merge_queries": [{"namespace": "test_items_query_dsl", "filters": [{"cond": "eq", "field": "id", "value": 20}]}],
+			"aggregations": [
+				{"type": "max", "fields": ["price"]},
+				{"type": "facet", "fields": ["genre"], "sort": [{"field": "count", "desc": true}], "limit": 2}
+			]
+		}`
+		q, err := DB.QueryFromDSL([]byte(dsl))
+		require.NoError(t, err)
+		it := q.Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		assert.Equal(t, 4, it.Count())
+		aggs := it.AggResults()
+		require.Len(t, aggs, 2)
+		assert.Equal(t, "max", aggs[0].Type)
+		assert.NotNil(t, aggs[0].Value)
+		assert.Len(t, aggs[1].Facets, 2)
+	})
+
+	t.Run("update fields are encoded", func(t *testing.T) {
+		dsl, err := DB.Reindexer.Query(testQueryDSLNs).WhereInt("id", reindexer.EQ, 1).Set("genre", "musical").Drop("author").DSL()
+		require.NoError(t, err)
+		var parsed map[string]interface{}
+		require.NoError(t, json.Unmarshal(dsl, &parsed))
+		assert.Equal(t, "update", parsed["type"])
+		assert.Equal(t, []interface{}{"author"}, parsed["drop_fields"])
+
+		q, err := DB.QueryFromDSL(dsl)
+		require.NoError(t, err)
+		restored, err := q.DSL()
+		require.NoError(t, err)
+		assert.JSONEq(t, string(dsl), string(restored))
+	})
+
+	t.Run("invalid DSL is rejected", func(t *testing.T) {
+		for _, dsl := range []string{
+			`{"filters": []}`,
+			`{"namespace": "test_items_query_dsl", "unknown": 1}`,
+			`{"namespace": "test_items_query_dsl", "filters": [{"cond": "eq", "value": 1}]}`,
+			`{"namespace": "test_items_query_dsl", "filters": [{"op": "xor", "cond": "eq", "field": "id", "value": 1}]}`,
+			`{"namespace": "test_items_query_dsl", "aggregations": [{"type": "median", "fields": ["price"]}]}`,
+		} {
+			_, err := DB.QueryFromDSL([]byte(dsl))
+			assert.Error(t, err, dsl)
+		}
+
+		_, err := DB.Reindexer.Query(testQueryDSLNs).
+			WhereQuery("id", reindexer.SET, DB.Reindexer.Query(testQueryDSLNs).Select("id")).DSL()
+		assert.Error(t, err)
+	})
+}
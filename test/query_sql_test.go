@@ -0,0 +1,57 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemQuerySQL struct {
+	ID   int    `reindex:"id,,pk" json:"id"`
+	Name string `reindex:"name" json:"name"`
+	Year int    `reindex:"year,tree" json:"year"`
+}
+
+const testQuerySQLNs = "test_items_query_sql"
+
+func init() {
+	tnamespaces[testQuerySQLNs] = TestItemQuerySQL{}
+}
+
+func TestQuerySQL(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		require.NoError(t, DB.Upsert(testQuerySQLNs, TestItemQuerySQL{ID: i, Name: []string{"Vasya", "O'Brien"}[i%2], Year: 2010 + i}))
+	}
+
+	t.Run("query is rendered as SQL", func(t *testing.T) {
+		sql, err := DB.Reindexer.Query(testQuerySQLNs).
+			WhereString("name", reindexer.EQ, "O'Brien").
+			OpenBracket().WhereInt("year", reindexer.GT, 2020).Or().WhereInt("id", reindexer.SET, 1, 3).CloseBracket().
+			Sort("year", true).Limit(5).ReqTotal().SQL()
+		require.NoError(t, err)
+		assert.Equal(t, `SELECT *, COUNT(*) FROM test_items_query_sql WHERE name = 'O\'Brien' AND (year > 2020 OR id IN (1,3)) ORDER BY year DESC LIMIT 5`, sql)
+
+		it := DB.ExecSQL(sql)
+		defer it.Close()
+		require.NoError(t, it.Error())
+		assert.Equal(t, 5, it.Count())
+		assert.Equal(t, 5, it.TotalCount())
+		for it.Next() {
+			assert.Equal(t, "O'Brien", it.Object().(*TestItemQuerySQL).Name)
+		}
+	})
+
+	t.Run("update query is rendered as SQL", func(t *testing.T) {
+		sql, err := DB.Reindexer.Query(testQuerySQLNs).WhereInt("id", reindexer.EQ, 1).Set("name", "Petya").SetExpression("year", "year + 1").SQL()
+		require.NoError(t, err)
+		assert.Equal(t, `UPDATE test_items_query_sql SET name = 'Petya',year = year + 1 WHERE id = 1`, sql)
+	})
+
+	t.Run("query with subqueries can't be rendered", func(t *testing.T) {
+		_, err := DB.Reindexer.Query(testQuerySQLNs).
+			WhereQuery("id", reindexer.SET, DB.Reindexer.Query(testQuerySQLNs).Select("id")).SQL()
+		assert.Error(t, err)
+	})
+}
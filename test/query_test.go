@@ -252,6 +252,13 @@ func (tx *txTest) Commit() (int, error) {
 	return res, err
 }
 
+func (tx *txTest) CommitAsync(ctx context.Context, cmpl bindings.Completion) {
+	tx.tx.CommitAsync(ctx, func(err error) {
+		tx.db.SetSyncRequired()
+		cmpl(err)
+	})
+}
+
 func (tx *txTest) Rollback() error {
 	err := tx.tx.Rollback()
 	tx.db.SetSyncRequired()
@@ -509,6 +516,11 @@ func (qt *queryTest) Sort(sortIndex string, desc bool, values ...interface{}) *q
 	return qt
 }
 
+func (qt *queryTest) SortMulti(entries []reindexer.SortEntry, pkTiebreaker bool) *queryTest {
+	qt.q.SortMulti(entries, pkTiebreaker)
+	return qt
+}
+
 // OR - next condition will added with OR
 func (qt *queryTest) Or() *queryTest {
 	qt.q.Or()
@@ -626,6 +638,11 @@ func (qt *queryTest) Explain() *queryTest {
 	return qt
 }
 
+func (qt *queryTest) WithDeleted() *queryTest {
+	qt.q.WithDeleted()
+	return qt
+}
+
 // SelectFilter
 func (qt *queryTest) Select(filters ...string) *queryTest {
 	qt.q.Select(filters...)
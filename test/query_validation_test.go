@@ -0,0 +1,76 @@
+package reindexer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemQueryValidationNested struct {
+	Price float64  `json:"price" reindex:"price"`
+	Tags  []string `json:"tags"`
+}
+
+type TestItemQueryValidation struct {
+	ID     int                             `json:"id" reindex:"id,,pk"`
+	Name   string                          `json:"name" reindex:"name"`
+	Year   int                             `json:"year" reindex:"year,tree"`
+	Active bool                            `json:"active"`
+	Nested TestItemQueryValidationNested   `json:"nested" reindex:"nested"`
+	Items  []TestItemQueryValidationNested `json:"items"`
+	Attrs  map[string]interface{}          `json:"attrs"`
+	_      struct{}                        `reindex:"name+year,,composite"`
+}
+
+const testQueryValidationNs = "test_items_query_validation"
+
+func TestQueryValidation(t *testing.T) {
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithQueryValidation())
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+	require.NoError(t, rx.OpenNamespace(testQueryValidationNs, reindexer.DefaultNamespaceOptions(), TestItemQueryValidation{}))
+	defer rx.DropNamespace(testQueryValidationNs)
+	require.NoError(t, rx.Upsert(testQueryValidationNs, TestItemQueryValidation{ID: 1, Name: "first", Year: 2020, Nested: TestItemQueryValidationNested{Price: 5}}))
+
+	t.Run("valid fields are accepted", func(t *testing.T) {
+		items, err := rx.Query(testQueryValidationNs).
+			WhereInt("id", reindexer.EQ, 1).
+			WhereString("NAME", reindexer.EQ, "first").
+			Where("nested.price", reindexer.GT, 1.5).
+			Where("items[0].tags", reindexer.EMPTY, nil).
+			Where("attrs.any.field", reindexer.EMPTY, nil).
+			WhereComposite("name+year", reindexer.EQ, []interface{}{"first", 2020}).
+			Sort("year", true).
+			Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+	})
+
+	t.Run("unknown fields and mismatched types are reported", func(t *testing.T) {
+		_, err := rx.Query(testQueryValidationNs).
+			Where("nmae", reindexer.EQ, "first").
+			WhereInt("name", reindexer.EQ, 1).
+			Sort("yaer", false).
+			Exec().FetchAll()
+		var verr *reindexer.QueryValidationError
+		require.True(t, errors.As(err, &verr))
+		assert.Equal(t, testQueryValidationNs, verr.Namespace)
+		require.Len(t, verr.Errors, 3)
+		assert.Contains(t, verr.Errors[0].Error(), "Did you mean 'name'?")
+		assert.Contains(t, verr.Errors[1].Error(), "has string type, but value of type int is passed")
+		assert.Contains(t, verr.Errors[2].Error(), "Did you mean 'year'?")
+	})
+
+	t.Run("fields of update are validated", func(t *testing.T) {
+		it := rx.Query(testQueryValidationNs).WhereInt("id", reindexer.EQ, 1).Set("year", "2021").Update()
+		defer it.Close()
+		assert.Error(t, it.Error())
+
+		n, err := rx.Query(testQueryValidationNs).Where("idd", reindexer.EQ, 1).Delete()
+		assert.Error(t, err)
+		assert.Equal(t, 0, n)
+	})
+}
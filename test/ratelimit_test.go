@@ -0,0 +1,62 @@
+package reindexer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemRateLimit struct {
+	ID int `reindex:"id,,pk"`
+}
+
+const (
+	testRateLimitNs        = "test_items_rate_limit"
+	testRateLimitLimitedNs = "test_items_rate_limit_limited"
+)
+
+func TestRateLimit(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(),
+		reindexer.WithRateLimit(1000, 1000),
+		reindexer.WithNamespaceRateLimit(testRateLimitLimitedNs, 20, 1))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+
+	for _, ns := range []string{testRateLimitNs, testRateLimitLimitedNs} {
+		require.NoError(t, rx.OpenNamespace(ns, reindexer.DefaultNamespaceOptions(), TestItemRateLimit{}))
+		defer rx.DropNamespace(ns)
+	}
+
+	t.Run("calls of limited namespace are throttled", func(t *testing.T) {
+		start := time.Now()
+		for i := 0; i < 11; i++ {
+			require.NoError(t, rx.Upsert(testRateLimitLimitedNs, TestItemRateLimit{ID: i}))
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	})
+
+	t.Run("calls of other namespaces are not throttled by namespace's limit", func(t *testing.T) {
+		start := time.Now()
+		for i := 0; i < 11; i++ {
+			require.NoError(t, rx.Upsert(testRateLimitNs, TestItemRateLimit{ID: i}))
+		}
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+	})
+
+	t.Run("wait longer than context deadline fails", func(t *testing.T) {
+		require.NoError(t, rx.Upsert(testRateLimitLimitedNs, TestItemRateLimit{ID: 100}))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		err := rx.WithContext(ctx).Upsert(testRateLimitLimitedNs, TestItemRateLimit{ID: 101})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,68 @@
+package reindexer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/test/helpers"
+)
+
+func TestReadReplicas(t *testing.T) {
+	const ns = "items"
+	type Item struct {
+		ID   int    `reindex:"id,,pk"`
+		Name string `reindex:"name"`
+	}
+
+	leader := helpers.TestServer{T: t, RpcPort: "6691", HttpPort: "9991", DbName: "reindex_test_read_replicas"}
+	replica := helpers.TestServer{T: t, RpcPort: "6692", HttpPort: "9992", DbName: "reindex_test_read_replicas"}
+	leaderDSN := fmt.Sprintf("cproto://127.0.0.1:%s/%s", leader.RpcPort, leader.DbName)
+	replicaDSN := fmt.Sprintf("cproto://127.0.0.1:%s/%s", replica.RpcPort, replica.DbName)
+	require.NoError(t, leader.Run())
+	defer leader.Clean()
+	require.NoError(t, replica.Run())
+	defer replica.Clean()
+
+	// Servers are not replicated, so the server, which executes the query, is detected by its data
+	replicaDB := reindexer.NewReindex(replicaDSN, reindexer.WithCreateDBIfMissing())
+	require.NoError(t, replicaDB.OpenNamespace(ns, reindexer.DefaultNamespaceOptions(), Item{}))
+	require.NoError(t, replicaDB.Upsert(ns, Item{ID: 1, Name: "replica"}))
+	replicaDB.Close()
+
+	db := reindexer.NewReindex(leaderDSN, reindexer.WithCreateDBIfMissing(),
+		reindexer.WithReadReplicas(replicaDSN), reindexer.WithReplicaHealthCheck(100*time.Millisecond))
+	defer db.Close()
+	require.NoError(t, db.OpenNamespace(ns, reindexer.DefaultNamespaceOptions(), Item{}))
+	require.NoError(t, db.Upsert(ns, Item{ID: 1, Name: "leader"}))
+
+	readName := func() string {
+		item, err := db.Query(ns).WhereInt("id", reindexer.EQ, 1).Exec().FetchOne()
+		require.NoError(t, err)
+		return item.(*Item).Name
+	}
+
+	t.Run("selects are sent to the replica", func(t *testing.T) {
+		assert.Equal(t, "replica", readName())
+		items, err := db.ExecSQL("SELECT * FROM items WHERE id = 1").FetchAll()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "replica", items[0].(*Item).Name)
+	})
+
+	t.Run("selects fail over to the leader", func(t *testing.T) {
+		require.NoError(t, replica.Stop())
+		assert.Equal(t, "leader", readName())
+	})
+
+	t.Run("selects fail back to the replica", func(t *testing.T) {
+		require.NoError(t, replica.Run())
+		require.Eventually(t, func() bool {
+			return readName() == "replica"
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+}
@@ -0,0 +1,70 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemReadSnapshot struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testReadSnapshotNs = "test_items_read_snapshot"
+
+func TestReadSnapshot(t *testing.T) {
+	DB.CloseNamespace(testReadSnapshotNs)
+	err := DB.OpenNamespace(testReadSnapshotNs, reindexer.DefaultNamespaceOptions(), TestItemReadSnapshot{})
+	require.NoError(t, err)
+	defer DB.DropNamespace(testReadSnapshotNs)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, DB.Upsert(testReadSnapshotNs, TestItemReadSnapshot{ID: i, Name: "item"}))
+	}
+
+	t.Run("snapshot is valid without modifications", func(t *testing.T) {
+		s, err := DB.BeginReadSnapshot(testReadSnapshotNs)
+		require.NoError(t, err)
+		_, ok := s.LSN(testReadSnapshotNs)
+		assert.True(t, ok)
+
+		items, err := s.Query(testReadSnapshotNs).Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 5)
+		assert.NoError(t, s.Validate())
+	})
+
+	t.Run("modification invalidates snapshot", func(t *testing.T) {
+		s, err := DB.BeginReadSnapshot(testReadSnapshotNs)
+		require.NoError(t, err)
+		require.NoError(t, DB.Upsert(testReadSnapshotNs, TestItemReadSnapshot{ID: 5, Name: "item"}))
+		assert.Equal(t, reindexer.ErrSnapshotChanged, s.Validate())
+	})
+
+	t.Run("read consistent retries on modification", func(t *testing.T) {
+		attempts := 0
+		var count int
+		err := DB.ReadConsistent([]string{testReadSnapshotNs}, 3, func(s *reindexer.ReadSnapshot) error {
+			attempts++
+			if attempts == 1 {
+				if err := DB.Upsert(testReadSnapshotNs, TestItemReadSnapshot{ID: 6, Name: "item"}); err != nil {
+					return err
+				}
+			}
+			items, err := s.Query(testReadSnapshotNs).Exec().FetchAll()
+			count = len(items)
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, 7, count)
+	})
+
+	t.Run("unknown namespace", func(t *testing.T) {
+		_, err := DB.BeginReadSnapshot("test_items_read_snapshot_unknown")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,64 @@
+package reindexer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestRecoverItem struct {
+	ID      int                `reindex:"id,,pk"`
+	ChildID int                `reindex:"child_id"`
+	Child   []*TestRecoverItem `reindex:"child,,joined"`
+}
+
+const testRecoverNs = "test_items_recover_handler"
+
+func TestRecoverHandler(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	var recoveredOp string
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithRecoverHandler(func(op string, r interface{}) error {
+		recoveredOp = op
+		if op == "Iterator.Next" {
+			return nil
+		}
+		return errors.New("recovered")
+	}))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+	require.NoError(t, rx.OpenNamespace(testRecoverNs, reindexer.DefaultNamespaceOptions(), TestRecoverItem{}))
+	defer rx.DropNamespace(testRecoverNs)
+	require.NoError(t, rx.Upsert(testRecoverNs, TestRecoverItem{ID: 1, ChildID: 2}))
+	require.NoError(t, rx.Upsert(testRecoverNs, TestRecoverItem{ID: 2}))
+
+	t.Run("panic in iterator is returned as error", func(t *testing.T) {
+		q := rx.Query(testRecoverNs).WhereInt("id", reindexer.EQ, 1)
+		q.LeftJoin(rx.Query(testRecoverNs), "child").On("child_id", reindexer.EQ, "id")
+		q.JoinHandler("child", func(field string, item interface{}, subitems []interface{}) bool {
+			panic("join handler failed")
+		})
+		it := q.Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		assert.False(t, it.Next())
+		var panicErr *reindexer.PanicError
+		require.True(t, errors.As(it.Error(), &panicErr))
+		assert.Equal(t, "Iterator.Next", panicErr.Op)
+		assert.Equal(t, "join handler failed", panicErr.Value)
+		assert.NotEmpty(t, panicErr.Stack)
+		assert.Equal(t, "Iterator.Next", recoveredOp)
+	})
+
+	t.Run("iterator works without panics", func(t *testing.T) {
+		items, err := rx.Query(testRecoverNs).Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+	})
+}
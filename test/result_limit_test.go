@@ -0,0 +1,57 @@
+package reindexer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemResultLimit struct {
+	ID   int    `reindex:"id,,pk"`
+	Data string `reindex:"data"`
+}
+
+const testResultLimitNs = "test_items_result_limit"
+
+func init() {
+	tnamespaces[testResultLimitNs] = TestItemResultLimit{}
+}
+
+func TestMaxResultBytes(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		require.NoError(t, DB.Upsert(testResultLimitNs, TestItemResultLimit{ID: i, Data: strings.Repeat("x", 1000)}))
+	}
+
+	t.Run("iterator stops, when the limit is exceeded", func(t *testing.T) {
+		it := DB.Reindexer.Query(testResultLimitNs).FetchCount(10).MaxResultBytes(30000).Exec()
+		defer it.Close()
+		count := 0
+		for it.Next() {
+			count++
+		}
+		err := it.Error()
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, reindexer.ErrResultTooLarge))
+		assert.Less(t, count, 100)
+
+		var serr *reindexer.ResultSizeError
+		require.True(t, errors.As(err, &serr))
+		assert.Equal(t, int64(30000), serr.Limit)
+		assert.Greater(t, serr.Size, serr.Limit)
+	})
+
+	t.Run("results within the limit are fetched", func(t *testing.T) {
+		items, err := DB.Reindexer.Query(testResultLimitNs).FetchCount(10).Limit(5).MaxResultBytes(30000).Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 5)
+	})
+
+	t.Run("JSON results are limited", func(t *testing.T) {
+		_, err := DB.Reindexer.Query(testResultLimitNs).MaxResultBytes(30000).ExecToJson().FetchAll()
+		assert.True(t, errors.Is(err, reindexer.ErrResultTooLarge))
+	})
+}
@@ -0,0 +1,84 @@
+package reindexer
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemResultsDiff struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testResultsDiffNs = "test_items_results_diff"
+
+func init() {
+	tnamespaces[testResultsDiffNs] = TestItemResultsDiff{}
+}
+
+func resultsDiffIDs(items []interface{}) []int {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.(*TestItemResultsDiff).ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestResultsDiff(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		require.NoError(t, DB.Upsert(testResultsDiffNs, TestItemResultsDiff{ID: i, Name: "initial"}))
+	}
+
+	diff, err := DB.Query(testResultsDiffNs).q.Diff(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, resultsDiffIDs(diff.Added))
+	assert.Empty(t, diff.Changed)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, 3, diff.Snapshot.Len())
+
+	t.Run("no changes", func(t *testing.T) {
+		next, err := DB.Query(testResultsDiffNs).q.Diff(diff.Snapshot)
+		require.NoError(t, err)
+		assert.Empty(t, next.Added)
+		assert.Empty(t, next.Changed)
+		assert.Empty(t, next.Removed)
+	})
+
+	t.Run("added, changed and removed items", func(t *testing.T) {
+		require.NoError(t, DB.Upsert(testResultsDiffNs, TestItemResultsDiff{ID: 1, Name: "changed"}))
+		require.NoError(t, DB.Delete(testResultsDiffNs, TestItemResultsDiff{ID: 2}))
+		require.NoError(t, DB.Upsert(testResultsDiffNs, TestItemResultsDiff{ID: 3, Name: "new"}))
+
+		next, err := DB.Query(testResultsDiffNs).q.Diff(diff.Snapshot)
+		require.NoError(t, err)
+		assert.Equal(t, []int{3}, resultsDiffIDs(next.Added))
+		require.Equal(t, []int{1}, resultsDiffIDs(next.Changed))
+		assert.Equal(t, "changed", next.Changed[0].(*TestItemResultsDiff).Name)
+		require.Equal(t, []int{2}, resultsDiffIDs(next.Removed))
+		assert.Equal(t, "initial", next.Removed[0].(*TestItemResultsDiff).Name)
+		assert.Equal(t, 3, next.Snapshot.Len())
+	})
+
+	t.Run("removed items are ordered by primary key", func(t *testing.T) {
+		for i := 12; i >= 4; i-- {
+			require.NoError(t, DB.Upsert(testResultsDiffNs, TestItemResultsDiff{ID: i, Name: "removed"}))
+		}
+		prev, err := DB.Query(testResultsDiffNs).q.Diff(nil)
+		require.NoError(t, err)
+		_, err = DB.Query(testResultsDiffNs).WhereInt("id", reindexer.GE, 4).Delete()
+		require.NoError(t, err)
+
+		next, err := DB.Query(testResultsDiffNs).q.Diff(prev.Snapshot)
+		require.NoError(t, err)
+		ids := make([]int, 0, len(next.Removed))
+		for _, item := range next.Removed {
+			ids = append(ids, item.(*TestItemResultsDiff).ID)
+		}
+		assert.Equal(t, []int{4, 5, 6, 7, 8, 9, 10, 11, 12}, ids)
+	})
+}
@@ -0,0 +1,55 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemResultsFlags struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testResultsFlagsNs = "test_items_results_flags"
+
+func TestQueryResultsFlags(t *testing.T) {
+	DB.CloseNamespace(testResultsFlagsNs)
+	err := DB.OpenNamespace(testResultsFlagsNs, reindexer.DefaultNamespaceOptions(), TestItemResultsFlags{})
+	require.NoError(t, err)
+	defer DB.DropNamespace(testResultsFlagsNs)
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, DB.Upsert(testResultsFlagsNs, TestItemResultsFlags{ID: i, Name: "item"}))
+	}
+
+	t.Run("cjson without item ids", func(t *testing.T) {
+		it := DB.Query(testResultsFlagsNs).q.ResultsFlags(bindings.ResultsCJson | bindings.ResultsWithPayloadTypes).Limit(30).Exec()
+		defer it.Close()
+		items, err := it.FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, 30)
+	})
+
+	t.Run("pure results contain only counters", func(t *testing.T) {
+		if !strings.HasPrefix(*dsn, "cproto") {
+			t.Skip()
+		}
+		it := DB.Query(testResultsFlagsNs).q.ResultsFlags(bindings.ResultsPure).ReqTotal().Limit(10).Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		assert.Equal(t, 50, it.TotalCount())
+		assert.False(t, it.Next())
+		assert.NoError(t, it.Error())
+	})
+
+	t.Run("json format is not supported", func(t *testing.T) {
+		it := DB.Query(testResultsFlagsNs).q.ResultsFlags(bindings.ResultsJson).Exec()
+		defer it.Close()
+		assert.Error(t, it.Error())
+	})
+}
@@ -0,0 +1,69 @@
+package reindexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemSoftDelete struct {
+	ID        int    `reindex:"id,,pk"`
+	Name      string `reindex:"name"`
+	DeletedAt int64  `reindex:"deleted_at"`
+}
+
+const testSoftDeleteNs = "test_items_soft_delete"
+
+func TestSoftDelete(t *testing.T) {
+	DB.CloseNamespace(testSoftDeleteNs)
+	err := DB.OpenNamespace(testSoftDeleteNs, reindexer.DefaultNamespaceOptions().WithSoftDelete("deleted_at"), TestItemSoftDelete{})
+	require.NoError(t, err)
+	defer DB.DropNamespace(testSoftDeleteNs)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testSoftDeleteNs, TestItemSoftDelete{ID: i, Name: "item"}))
+	}
+
+	t.Run("delete item marks it as deleted", func(t *testing.T) {
+		require.NoError(t, DB.Delete(testSoftDeleteNs, TestItemSoftDelete{ID: 0}))
+
+		_, found := DB.Query(testSoftDeleteNs).WhereInt("id", reindexer.EQ, 0).Get()
+		assert.False(t, found)
+
+		item, found := DB.Query(testSoftDeleteNs).WithDeleted().WhereInt("id", reindexer.EQ, 0).Get()
+		require.True(t, found)
+		assert.Equal(t, "item", item.(*TestItemSoftDelete).Name)
+		assert.NotZero(t, item.(*TestItemSoftDelete).DeletedAt)
+	})
+
+	t.Run("delete query marks items as deleted", func(t *testing.T) {
+		cnt, err := DB.Query(testSoftDeleteNs).WhereInt("id", reindexer.LT, 3).Delete()
+		require.NoError(t, err)
+		assert.Equal(t, 2, cnt)
+
+		total, err := DB.Query(testSoftDeleteNs).Exec(t).FetchAll()
+		require.NoError(t, err)
+		assert.Equal(t, 7, len(total))
+
+		all, err := DB.Query(testSoftDeleteNs).WithDeleted().Exec(t).FetchAll()
+		require.NoError(t, err)
+		assert.Equal(t, 10, len(all))
+	})
+
+	t.Run("purge removes old deleted items", func(t *testing.T) {
+		cnt, err := DB.PurgeDeleted(testSoftDeleteNs, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, 0, cnt)
+
+		cnt, err = DB.PurgeDeleted(testSoftDeleteNs, -time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 3, cnt)
+
+		all, err := DB.Query(testSoftDeleteNs).WithDeleted().Exec(t).FetchAll()
+		require.NoError(t, err)
+		assert.Equal(t, 7, len(all))
+	})
+}
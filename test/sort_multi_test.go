@@ -0,0 +1,71 @@
+package reindexer
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemSortMulti struct {
+	ID    int    `reindex:"id,,pk"`
+	Group int    `reindex:"group,tree"`
+	Name  string `reindex:"name,tree"`
+	Title string `reindex:"title,tree,collate_numeric"`
+}
+
+const testSortMultiNs = "test_items_sort_multi"
+
+func init() {
+	tnamespaces[testSortMultiNs] = TestItemSortMulti{}
+}
+
+func TestSortMulti(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		require.NoError(t, DB.Upsert(testSortMultiNs, TestItemSortMulti{ID: 19 - i, Group: i % 3, Name: "same", Title: strconv.Itoa(i)}))
+	}
+
+	entries := []reindexer.SortEntry{{Field: "group", Desc: true}, {Field: "name"}}
+	ids := make([]int, 0, 20)
+	for offset := 0; offset < 20; offset += 7 {
+		items, err := DB.Query(testSortMultiNs).SortMulti(entries, true).Offset(offset).Limit(7).Exec(t).FetchAll()
+		require.NoError(t, err)
+		for _, item := range items {
+			ids = append(ids, item.(*TestItemSortMulti).ID)
+		}
+	}
+	require.Equal(t, 20, len(ids))
+
+	prevGroup, prevID := 3, -1
+	for _, id := range ids {
+		group := (19 - id) % 3
+		require.LessOrEqual(t, group, prevGroup)
+		if group == prevGroup {
+			assert.Greater(t, id, prevID)
+		}
+		prevGroup, prevID = group, id
+	}
+
+	t.Run("pk in sort entries is not duplicated", func(t *testing.T) {
+		items, err := DB.Query(testSortMultiNs).SortMulti([]reindexer.SortEntry{{Field: "id", Desc: true}}, true).Limit(1).Exec(t).FetchAll()
+		require.NoError(t, err)
+		require.Equal(t, 1, len(items))
+		assert.Equal(t, 19, items[0].(*TestItemSortMulti).ID)
+	})
+
+	t.Run("collation of the index", func(t *testing.T) {
+		items, err := DB.Query(testSortMultiNs).SortMulti([]reindexer.SortEntry{{Field: "title", Collate: reindexer.CollateNumeric}}, true).
+			Limit(3).Exec(t).FetchAll()
+		require.NoError(t, err)
+		require.Equal(t, 3, len(items))
+		assert.Equal(t, "2", items[2].(*TestItemSortMulti).Title)
+	})
+
+	t.Run("collation, which differs from the index's one", func(t *testing.T) {
+		_, err := DB.Query(testSortMultiNs).q.SortMulti([]reindexer.SortEntry{{Field: "name", Collate: reindexer.CollateUTF8}}, true).
+			Exec().FetchAll()
+		assert.Error(t, err)
+	})
+}
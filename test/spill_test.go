@@ -0,0 +1,60 @@
+package reindexer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemSpill struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testSpillNs = "test_items_spill"
+
+func init() {
+	tnamespaces[testSpillNs] = TestItemSpill{}
+}
+
+func TestSpillToDisk(t *testing.T) {
+	const count = 1000
+	for i := 0; i < count; i++ {
+		require.NoError(t, DB.Upsert(testSpillNs, TestItemSpill{ID: i, Name: "name"}))
+	}
+
+	dir := t.TempDir()
+	it := DB.Query(testSpillNs).q.SpillToDisk(dir).FetchCount(64).Sort("id", false).ReqTotal().Exec()
+	require.NoError(t, it.Error())
+	assert.Equal(t, count, it.TotalCount())
+
+	i := 0
+	for it.Next() {
+		assert.Equal(t, i, it.Object().(*TestItemSpill).ID)
+		i++
+	}
+	require.NoError(t, it.Error())
+	assert.Equal(t, count, i)
+	it.Close()
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	t.Run("spilled results with aggregations", func(t *testing.T) {
+		q := DB.Query(testSpillNs).q.SpillToDisk(dir).FetchCount(100)
+		q.AggregateMax("id")
+		it := q.Exec()
+		defer it.Close()
+		require.NoError(t, it.Error())
+		items, err := it.FetchAll()
+		require.NoError(t, err)
+		assert.Len(t, items, count)
+		aggs := it.AggResults()
+		require.Len(t, aggs, 1)
+		require.NotNil(t, aggs[0].Value)
+		assert.Equal(t, float64(count-1), *aggs[0].Value)
+	})
+}
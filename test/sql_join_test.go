@@ -0,0 +1,68 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestSQLJoinParent struct {
+	ID       int                 `reindex:"id,,pk"`
+	ChildID  int                 `reindex:"child_id"`
+	Children []*TestSQLJoinChild `reindex:"children,,joined"`
+}
+
+type TestSQLJoinChild struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const (
+	testSQLJoinParentNs = "test_sql_join_parent"
+	testSQLJoinChildNs  = "test_sql_join_child"
+)
+
+func init() {
+	tnamespaces[testSQLJoinParentNs] = TestSQLJoinParent{}
+	tnamespaces[testSQLJoinChildNs] = TestSQLJoinChild{}
+}
+
+func TestSQLJoin(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		require.NoError(t, DB.Upsert(testSQLJoinParentNs, TestSQLJoinParent{ID: i, ChildID: i % 2}))
+	}
+	require.NoError(t, DB.Upsert(testSQLJoinChildNs, TestSQLJoinChild{ID: 1, Name: "child_1"}))
+
+	check := func(t *testing.T, query string, expectedJoined int) {
+		it := DB.ExecSQL(query)
+		defer it.Close()
+		require.NoError(t, it.Error())
+		joined := 0
+		for it.Next() {
+			item := it.Object().(*TestSQLJoinParent)
+			objects, err := it.JoinedObjects("children")
+			require.NoError(t, err)
+			require.Equal(t, len(objects), len(item.Children))
+			if item.ChildID == 1 {
+				require.Equal(t, 1, len(item.Children))
+				assert.Equal(t, "child_1", item.Children[0].Name)
+				joined++
+			} else {
+				assert.Equal(t, 0, len(item.Children))
+			}
+		}
+		require.NoError(t, it.Error())
+		assert.Equal(t, expectedJoined, joined)
+	}
+
+	t.Run("left join", func(t *testing.T) {
+		check(t, "SELECT * FROM "+testSQLJoinParentNs+" LEFT JOIN "+testSQLJoinChildNs+" ON "+testSQLJoinParentNs+".child_id = "+testSQLJoinChildNs+".id", 2)
+	})
+	t.Run("inner join with subquery", func(t *testing.T) {
+		check(t, "SELECT * FROM "+testSQLJoinParentNs+" INNER JOIN (SELECT * FROM "+testSQLJoinChildNs+" WHERE name = 'child_1') ON "+testSQLJoinParentNs+".child_id = "+testSQLJoinChildNs+".id", 2)
+	})
+	t.Run("join keyword in string literal", func(t *testing.T) {
+		check(t, "SELECT * FROM "+testSQLJoinParentNs+" LEFT JOIN(SELECT * FROM "+testSQLJoinChildNs+" WHERE name <> 'inner join x') ON "+testSQLJoinParentNs+".child_id = "+testSQLJoinChildNs+".id", 2)
+	})
+}
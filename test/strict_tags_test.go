@@ -0,0 +1,54 @@
+package reindexer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestStrictTagsNested struct {
+	Value int `reindex:"value"`
+}
+
+type TestStrictTagsValid struct {
+	ID     int                  `reindex:"id,,pk"`
+	Name   string               `reindex:"name,hash,collate_ascii"`
+	Tags   []string             `reindex:"tags"`
+	Nested TestStrictTagsNested `json:"nested"`
+	Alias1 string               `reindex:"alias,,appendable"`
+	Alias2 string               `reindex:"alias,,appendable"`
+	_      struct{}             `reindex:"id+name,,composite"`
+}
+
+type TestStrictTagsInvalid struct {
+	ID    []int    `reindex:"id,,pk"`
+	Name  string   `reindex:"name,hahs"`
+	Dup   string   `reindex:"name"`
+	Count int      `reindex:"count,,collate_numeric"`
+	_     struct{} `reindex:"id+missing,,composite"`
+}
+
+const (
+	testStrictTagsValidNs   = "test_strict_tags_valid"
+	testStrictTagsInvalidNs = "test_strict_tags_invalid"
+)
+
+func TestStrictTags(t *testing.T) {
+	t.Run("valid struct is accepted", func(t *testing.T) {
+		require.NoError(t, DB.OpenNamespace(testStrictTagsValidNs, reindexer.DefaultNamespaceOptions().StrictTags(), TestStrictTagsValid{}))
+		DB.DropNamespace(testStrictTagsValidNs)
+	})
+
+	t.Run("all mistakes are reported", func(t *testing.T) {
+		err := DB.OpenNamespace(testStrictTagsInvalidNs, reindexer.DefaultNamespaceOptions().StrictTags(), TestStrictTagsInvalid{})
+		require.Error(t, err)
+		var terr *reindexer.TagsValidationError
+		require.True(t, errors.As(err, &terr))
+		assert.Equal(t, "TestStrictTagsInvalid", terr.Type)
+		assert.Len(t, terr.Errors, 5)
+		assert.Equal(t, reindexer.ErrCodeParams, terr.Code())
+	})
+}
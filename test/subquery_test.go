@@ -0,0 +1,78 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestSubQueryUser struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+type TestSubQueryOrder struct {
+	ID     int `reindex:"id,,pk"`
+	UserID int `reindex:"user_id" json:"user"`
+	Amount int `reindex:"amount"`
+}
+
+const (
+	testSubQueryUsersNs  = "test_subquery_users"
+	testSubQueryOrdersNs = "test_subquery_orders"
+)
+
+func init() {
+	tnamespaces[testSubQueryUsersNs] = TestSubQueryUser{}
+	tnamespaces[testSubQueryOrdersNs] = TestSubQueryOrder{}
+}
+
+func TestWhereQuery(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testSubQueryUsersNs, TestSubQueryUser{ID: i, Name: "user"}))
+	}
+	orders := []TestSubQueryOrder{{1, 2, 100}, {2, 2, 500}, {3, 5, 700}, {4, 7, 50}}
+	for _, order := range orders {
+		require.NoError(t, DB.Upsert(testSubQueryOrdersNs, order))
+	}
+
+	userIDs := func(items []interface{}) []int {
+		ids := make([]int, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.(*TestSubQueryUser).ID)
+		}
+		return ids
+	}
+
+	t.Run("values of subquery are used as condition", func(t *testing.T) {
+		sub := DB.Query(testSubQueryOrdersNs).q.Select("user_id").WhereInt("amount", reindexer.GE, 100)
+		items, err := DB.Query(testSubQueryUsersNs).q.WhereQuery("id", reindexer.SET, sub).Sort("id", false).Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Equal(t, []int{2, 5}, userIDs(items))
+	})
+
+	t.Run("subquery is combined with other conditions", func(t *testing.T) {
+		sub := DB.Query(testSubQueryOrdersNs).q.Select("user_id")
+		items, err := DB.Query(testSubQueryUsersNs).q.WhereInt("id", reindexer.GT, 2).
+			Not().WhereQuery("id", reindexer.SET, sub).
+			WhereInt("id", reindexer.LT, 7).Sort("id", false).Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Equal(t, []int{3, 4, 6}, userIDs(items))
+	})
+
+	t.Run("empty subquery results", func(t *testing.T) {
+		sub := DB.Query(testSubQueryOrdersNs).q.Select("user_id").WhereInt("amount", reindexer.GT, 1000)
+		items, err := DB.Query(testSubQueryUsersNs).q.WhereQuery("id", reindexer.SET, sub).Exec().FetchAll()
+		require.NoError(t, err)
+		assert.Empty(t, items)
+	})
+
+	t.Run("subquery must select single field", func(t *testing.T) {
+		sub := DB.Query(testSubQueryOrdersNs).q
+		it := DB.Query(testSubQueryUsersNs).q.WhereQuery("id", reindexer.SET, sub).Exec()
+		defer it.Close()
+		assert.Error(t, it.Error())
+	})
+}
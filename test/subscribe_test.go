@@ -0,0 +1,118 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemSubscribe struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testSubscribeNs = "test_items_subscribe"
+
+func receiveUpdates(t *testing.T, ch <-chan reindexer.UpdateEvent, count int) []reindexer.UpdateEvent {
+	events := make([]reindexer.UpdateEvent, 0, count)
+	for len(events) < count {
+		select {
+		case event, ok := <-ch:
+			require.True(t, ok, "channel is closed")
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout", "got %d events of %d", len(events), count)
+		}
+	}
+	return events
+}
+
+func TestSubscribe(t *testing.T) {
+	DB.CloseNamespace(testSubscribeNs)
+	err := DB.OpenNamespace(testSubscribeNs, reindexer.DefaultNamespaceOptions(), TestItemSubscribe{})
+	require.NoError(t, err)
+	defer DB.DropNamespace(testSubscribeNs)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, DB.Upsert(testSubscribeNs, TestItemSubscribe{ID: i, Name: "item"}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := &reindexer.SubscribeOptions{
+		Interval: 10 * time.Millisecond,
+		Filter: func(q *reindexer.Query) *reindexer.Query {
+			return q.WhereInt("id", reindexer.LT, 100)
+		},
+	}
+	ch, err := DB.Subscribe(ctx, testSubscribeNs, opts)
+	require.NoError(t, err)
+
+	t.Run("changes of the items are delivered", func(t *testing.T) {
+		require.NoError(t, DB.Upsert(testSubscribeNs, TestItemSubscribe{ID: 3, Name: "new"}))
+		events := receiveUpdates(t, ch, 1)
+		assert.Equal(t, "Insert", events[0].Op)
+		assert.Equal(t, 3, events[0].Item.(*TestItemSubscribe).ID)
+
+		require.NoError(t, DB.Upsert(testSubscribeNs, TestItemSubscribe{ID: 0, Name: "changed"}))
+		events = receiveUpdates(t, ch, 1)
+		assert.Equal(t, "Update", events[0].Op)
+		assert.Equal(t, "changed", events[0].Item.(*TestItemSubscribe).Name)
+
+		require.NoError(t, DB.Delete(testSubscribeNs, TestItemSubscribe{ID: 1}))
+		events = receiveUpdates(t, ch, 1)
+		assert.Equal(t, "Delete", events[0].Op)
+		assert.Equal(t, 1, events[0].Item.(*TestItemSubscribe).ID)
+	})
+
+	t.Run("changes of filtered out items are not delivered", func(t *testing.T) {
+		require.NoError(t, DB.Upsert(testSubscribeNs, TestItemSubscribe{ID: 100, Name: "skipped"}))
+		require.NoError(t, DB.Upsert(testSubscribeNs, TestItemSubscribe{ID: 4, Name: "new"}))
+		events := receiveUpdates(t, ch, 1)
+		assert.Equal(t, 4, events[0].Item.(*TestItemSubscribe).ID)
+	})
+
+	t.Run("resume from outdated LSN resets subscriber", func(t *testing.T) {
+		stat, err := DB.GetNamespaceMemStat(testSubscribeNs)
+		require.NoError(t, err)
+		lsn := stat.Replication.LastLSN
+		require.NoError(t, DB.Upsert(testSubscribeNs, TestItemSubscribe{ID: 5, Name: "new"}))
+
+		resumeCtx, resumeCancel := context.WithCancel(context.Background())
+		defer resumeCancel()
+		resumeOpts := *opts
+		resumeOpts.FromLSN = &lsn
+		resumed, err := DB.Subscribe(resumeCtx, testSubscribeNs, &resumeOpts)
+		require.NoError(t, err)
+		events := receiveUpdates(t, resumed, 6)
+		assert.Equal(t, "Reset", events[0].Op)
+		for _, event := range events[1:] {
+			assert.Equal(t, "Insert", event.Op)
+		}
+	})
+
+	t.Run("truncate resets subscriber", func(t *testing.T) {
+		require.NoError(t, DB.TruncateNamespace(testSubscribeNs))
+		// Pending events of the previous changes may be delivered before reset, but removed items are not delivered
+		for {
+			event := receiveUpdates(t, ch, 1)[0]
+			require.NotEqual(t, "Delete", event.Op)
+			if event.Op == "Reset" {
+				break
+			}
+		}
+
+		require.NoError(t, DB.Upsert(testSubscribeNs, TestItemSubscribe{ID: 6, Name: "new"}))
+		events := receiveUpdates(t, ch, 1)
+		assert.Equal(t, "Insert", events[0].Op)
+		assert.Equal(t, 6, events[0].Item.(*TestItemSubscribe).ID)
+	})
+
+	cancel()
+	for range ch {
+	}
+}
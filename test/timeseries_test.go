@@ -0,0 +1,51 @@
+package reindexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemTimeSeries struct {
+	ID        int    `reindex:"id,,pk"`
+	Kind      string `reindex:"kind"`
+	Amount    int    `reindex:"amount"`
+	CreatedAt int64  `reindex:"created_at"`
+}
+
+const testTimeSeriesNs = "test_items_time_series"
+
+func TestTimeSeries(t *testing.T) {
+	DB.CloseNamespace(testTimeSeriesNs)
+	require.NoError(t, DB.OpenNamespace(testTimeSeriesNs, reindexer.DefaultNamespaceOptions(), TestItemTimeSeries{}))
+	defer DB.DropNamespace(testTimeSeriesNs)
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	// 2 paid items at 00:xx, none at 01:xx and 1 paid item at 02:xx
+	items := []TestItemTimeSeries{
+		{ID: 1, Kind: "paid", Amount: 10, CreatedAt: day.Add(5 * time.Minute).Unix()},
+		{ID: 2, Kind: "paid", Amount: 20, CreatedAt: day.Add(50 * time.Minute).Unix()},
+		{ID: 3, Kind: "refund", Amount: 5, CreatedAt: day.Add(55 * time.Minute).Unix()},
+		{ID: 4, Kind: "paid", Amount: 7, CreatedAt: day.Add(2*time.Hour + time.Minute).Unix()},
+	}
+	for _, item := range items {
+		require.NoError(t, DB.Upsert(testTimeSeriesNs, item))
+	}
+
+	points, err := DB.Reindexer.Query(testTimeSeriesNs).WhereString("kind", reindexer.EQ, "paid").TimeSeries(context.Background(), reindexer.TimeSeriesOptions{
+		Field:  "created_at",
+		From:   day,
+		To:     day.Add(3 * time.Hour),
+		Bucket: time.Hour,
+		Sum:    []string{"amount"},
+	})
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	assert.Equal(t, []int{2, 0, 1}, []int{points[0].Count, points[1].Count, points[2].Count})
+	assert.Equal(t, []float64{30, 0, 7}, []float64{points[0].Sums["amount"], points[1].Sums["amount"], points[2].Sums["amount"]})
+	assert.Equal(t, day.Add(time.Hour), points[1].Start)
+}
@@ -0,0 +1,72 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemTxReads struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+	Age  int    `reindex:"age"`
+}
+
+const testTxReadsNs = "test_items_tx_reads"
+
+func TestTxReadYourWrites(t *testing.T) {
+	DB.CloseNamespace(testTxReadsNs)
+	require.NoError(t, DB.OpenNamespace(testTxReadsNs, reindexer.DefaultNamespaceOptions(), TestItemTxReads{}))
+	defer DB.DropNamespace(testTxReadsNs)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, DB.Upsert(testTxReadsNs, TestItemTxReads{ID: i, Name: "committed", Age: i * 10}))
+	}
+	ids := func(t *testing.T, it *reindexer.Iterator) []int {
+		defer it.Close()
+		res := []int{}
+		for it.Next() {
+			res = append(res, it.Object().(*TestItemTxReads).ID)
+		}
+		require.NoError(t, it.Error())
+		return res
+	}
+
+	tx, err := DB.BeginTx(testTxReadsNs)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	tx.WithReadYourWrites()
+	require.NoError(t, tx.Upsert(TestItemTxReads{ID: 100, Name: "local", Age: 15}))
+	require.NoError(t, tx.Update(TestItemTxReads{ID: 2, Name: "local", Age: 200}))
+	require.NoError(t, tx.Delete(TestItemTxReads{ID: 3}))
+
+	t.Run("select sees modifications of the transaction", func(t *testing.T) {
+		it := tx.Query().WhereString("name", reindexer.EQ, "local").Sort("id", false).Exec()
+		assert.Equal(t, []int{2, 100}, ids(t, it))
+	})
+
+	t.Run("sort, limit and total", func(t *testing.T) {
+		it := tx.Query().WhereInt("age", reindexer.LT, 50).Sort("age", true).Limit(3).ReqTotal().Exec()
+		assert.Equal(t, 4, it.TotalCount())
+		assert.Equal(t, []int{4, 100, 1}, ids(t, it))
+	})
+
+	t.Run("select of the namespace doesn't see modifications", func(t *testing.T) {
+		it := DB.Reindexer.Query(testTxReadsNs).WhereString("name", reindexer.EQ, "local").Exec()
+		assert.Equal(t, []int{}, ids(t, it))
+	})
+
+	t.Run("unsupported query", func(t *testing.T) {
+		q := tx.Query()
+		q.AggregateSum("age")
+		it := q.Exec()
+		defer it.Close()
+		assert.Error(t, it.Error())
+	})
+
+	require.NoError(t, tx.Commit())
+	it := DB.Reindexer.Query(testTxReadsNs).WhereString("name", reindexer.EQ, "local").Sort("id", false).Exec()
+	assert.Equal(t, []int{2, 100}, ids(t, it))
+}
@@ -2,6 +2,7 @@ package reindexer
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"strconv"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/restream/reindexer/v3"
 	"github.com/restream/reindexer/v3/bindings"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type TextTxItem struct {
@@ -30,6 +32,8 @@ const testTxAsyncItemNs = "test_tx_async_item"
 const testTxAsyncTimeoutItemNs = "test_tx_async_timeout_item"
 const testTxQueryItemNs = "test_tx_queries_item"
 const testTxConcurrentTagsItemNs = "test_tx_concurrent_tags_item"
+const testTxCommitAsyncItemNs = "test_tx_commit_async_item"
+const testTxPublisherItemNs = "test_tx_publisher_item"
 
 func init() {
 	tnamespaces[testTxItemNs] = TextTxItem{}
@@ -37,6 +41,8 @@ func init() {
 	tnamespaces[testTxAsyncTimeoutItemNs] = TextTxItem{}
 	tnamespaces[testTxQueryItemNs] = TextTxItem{}
 	tnamespaces[testTxConcurrentTagsItemNs] = UntaggedTxItem{}
+	tnamespaces[testTxCommitAsyncItemNs] = TextTxItem{}
+	tnamespaces[testTxPublisherItemNs] = TextTxItem{}
 }
 
 func FillTextTxItem1Tx(count int, tx *txTest) {
@@ -98,6 +104,98 @@ func TestAsyncTx(t *testing.T) {
 	CheckTYx(t, testTxAsyncItemNs, count)
 }
 
+func TestTxCommitAsync(t *testing.T) {
+	const count = 3000
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx := newTestTx(DB, testTxCommitAsyncItemNs)
+	for i := 0; i < count; i++ {
+		err := tx.UpsertAsync(&TextTxItem{ID: i, Name: strconv.Itoa(i)}, func(err error) {
+			assert.NoError(t, err)
+		})
+		require.NoError(t, err)
+	}
+	done := make(chan error, 1)
+	tx.CommitAsync(ctx, func(err error) {
+		done <- err
+	})
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-ctx.Done():
+		require.FailNow(t, "CommitAsync completion was not called")
+	}
+	CheckTYx(t, testTxCommitAsyncItemNs, count)
+
+	tx.CommitAsync(ctx, func(err error) {
+		done <- err
+	})
+	assert.Error(t, <-done, "Finalized tx must not be committed")
+}
+
+func TestTxPublisher(t *testing.T) {
+	t.Run("events are published after commit with retries", func(t *testing.T) {
+		var attempts int
+		var published []reindexer.TxEvent
+		tx := newTestTx(DB, testTxPublisherItemNs)
+		tx.tx.WithPublisher(reindexer.TxPublisherFunc(func(ctx context.Context, events []reindexer.TxEvent) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("broker is not available")
+			}
+			published = events
+			return nil
+		}), 3, time.Millisecond)
+
+		require.NoError(t, tx.Upsert(&TextTxItem{ID: 1, Name: "1"}))
+		require.NoError(t, tx.UpsertAsync(&TextTxItem{ID: 2, Name: "2"}, func(err error) {
+			assert.NoError(t, err)
+		}))
+		require.NoError(t, tx.Delete(&TextTxItem{ID: 3}))
+		assert.Empty(t, published)
+		_, err := tx.Commit()
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, attempts)
+		require.Len(t, published, 3)
+		assert.Equal(t, reindexer.TxEvent{Namespace: testTxPublisherItemNs, Op: "Upsert", PK: []interface{}{1}, Item: &TextTxItem{ID: 1, Name: "1"}}, published[0])
+		assert.Equal(t, "Upsert", published[1].Op)
+		assert.Equal(t, []interface{}{2}, published[1].PK)
+		assert.Equal(t, "Delete", published[2].Op)
+		assert.Equal(t, []interface{}{3}, published[2].PK)
+	})
+
+	t.Run("commit reports not published events", func(t *testing.T) {
+		tx := newTestTx(DB, testTxPublisherItemNs)
+		tx.tx.WithPublisher(reindexer.TxPublisherFunc(func(ctx context.Context, events []reindexer.TxEvent) error {
+			return errors.New("broker is not available")
+		}), 2, 0)
+		require.NoError(t, tx.Upsert(&TextTxItem{ID: 4, Name: "4"}))
+		_, err := tx.Commit()
+		var publishErr *reindexer.TxPublishError
+		require.True(t, errors.As(err, &publishErr))
+		assert.Len(t, publishErr.Events, 1)
+
+		// Transaction is committed anyway
+		_, found := DB.Query(testTxPublisherItemNs).WhereInt("id", reindexer.EQ, 4).Get()
+		assert.True(t, found)
+	})
+
+	t.Run("events of rolled back transaction are not published", func(t *testing.T) {
+		published := false
+		tx := newTestTx(DB, testTxPublisherItemNs)
+		tx.tx.WithPublisher(reindexer.TxPublisherFunc(func(ctx context.Context, events []reindexer.TxEvent) error {
+			published = true
+			return nil
+		}), 1, 0)
+		require.NoError(t, tx.Upsert(&TextTxItem{ID: 5, Name: "5"}))
+		require.NoError(t, tx.Rollback())
+		assert.False(t, published)
+	})
+}
+
 func TestTxQueries(t *testing.T) {
 	tx := newTestTx(DB, testTxQueryItemNs)
 	count := 5000
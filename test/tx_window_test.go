@@ -0,0 +1,42 @@
+package reindexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemTxWindow struct {
+	ID   int    `reindex:"id,,pk"`
+	Data string `reindex:"data"`
+}
+
+const testTxWindowNs = "test_items_tx_window"
+
+func TestTxAsyncWindow(t *testing.T) {
+	if !strings.HasPrefix(*dsn, "cproto") {
+		t.Skip()
+	}
+
+	rx := reindexer.NewReindex(*dsn, reindexer.WithCreateDBIfMissing(), reindexer.WithTxAsyncWindow(reindexer.TxAsyncWindow{MaxItems: 4, MaxBytes: 256}))
+	defer rx.Close()
+	require.NoError(t, rx.Status().Err)
+	require.NoError(t, rx.OpenNamespace(testTxWindowNs, reindexer.DefaultNamespaceOptions(), TestItemTxWindow{}))
+	defer rx.DropNamespace(testTxWindowNs)
+
+	tx, err := rx.BeginTx(testTxWindowNs)
+	require.NoError(t, err)
+	for i := 0; i < 200; i++ {
+		require.NoError(t, tx.UpsertAsync(TestItemTxWindow{ID: i, Data: randString()}, func(err error) {
+			assert.NoError(t, err)
+		}))
+		assert.LessOrEqual(t, rx.ClientStats().PendingAsyncOps, int64(4))
+	}
+	count, err := tx.CommitWithCount()
+	require.NoError(t, err)
+	assert.Equal(t, 200, count)
+	assert.Equal(t, int64(0), rx.ClientStats().PendingAsyncBytes)
+}
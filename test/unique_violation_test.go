@@ -0,0 +1,67 @@
+package reindexer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemUniqueViolation struct {
+	ID   int    `reindex:"id,,pk"`
+	Name string `reindex:"name"`
+}
+
+const testUniqueViolationNs = "test_items_unique_violation"
+
+func init() {
+	tnamespaces[testUniqueViolationNs] = TestItemUniqueViolation{}
+}
+
+func TestUniqueViolation(t *testing.T) {
+	require.NoError(t, DB.Upsert(testUniqueViolationNs, TestItemUniqueViolation{ID: 1, Name: "first"}))
+	require.NoError(t, DB.Upsert(testUniqueViolationNs, TestItemUniqueViolation{ID: 2, Name: "second"}))
+
+	it := DB.Query(testUniqueViolationNs).WhereInt("id", reindexer.EQ, 2).q.Set("id", 1).Update()
+	defer it.Close()
+	err := it.Error()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, reindexer.ErrUniqueViolation))
+
+	var uerr *reindexer.UniqueViolationError
+	require.True(t, errors.As(err, &uerr))
+	assert.Equal(t, "id", uerr.Index)
+	assert.Equal(t, []string{"1"}, uerr.Key)
+	assert.Len(t, uerr.Rows, 2)
+
+	// Conflicting update is rolled back
+	item, found := DB.Query(testUniqueViolationNs).WhereInt("id", reindexer.EQ, 2).Get()
+	require.True(t, found)
+	assert.Equal(t, "second", item.(*TestItemUniqueViolation).Name)
+
+	t.Run("insert of existing item", func(t *testing.T) {
+		count, err := DB.Insert(testUniqueViolationNs, TestItemUniqueViolation{ID: 1, Name: "duplicate"})
+		assert.Equal(t, 0, count)
+		require.True(t, errors.Is(err, reindexer.ErrUniqueViolation))
+		var uerr *reindexer.UniqueViolationError
+		require.True(t, errors.As(err, &uerr))
+		assert.Equal(t, "id", uerr.Index)
+		assert.Equal(t, []string{"1"}, uerr.Key)
+	})
+
+	t.Run("insert of existing item by transaction", func(t *testing.T) {
+		tx, err := DB.BeginTx(testUniqueViolationNs)
+		require.NoError(t, err)
+		require.NoError(t, tx.Insert(TestItemUniqueViolation{ID: 2, Name: "duplicate"}))
+		require.NoError(t, tx.Insert(TestItemUniqueViolation{ID: 3, Name: "third"}))
+		count, err := tx.CommitWithCount()
+		assert.Equal(t, 1, count)
+		assert.True(t, errors.Is(err, reindexer.ErrUniqueViolation))
+
+		// Other items of the transaction are committed
+		_, found := DB.Query(testUniqueViolationNs).WhereInt("id", reindexer.EQ, 3).Get()
+		assert.True(t, found)
+	})
+}
@@ -0,0 +1,109 @@
+package reindexer
+
+import (
+	"testing"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TestItemVersioned struct {
+	ID      int    `reindex:"id,,pk"`
+	Name    string `reindex:"name"`
+	Version int64  `reindex:"version,-,version"`
+}
+
+const testVersionNs = "test_items_versioned"
+
+func init() {
+	tnamespaces[testVersionNs] = TestItemVersioned{}
+}
+
+func TestVersionField(t *testing.T) {
+	t.Run("insert sets version to 1", func(t *testing.T) {
+		item := TestItemVersioned{ID: 1, Name: "first", Version: 10}
+		cnt, err := DB.Insert(testVersionNs, &item)
+		require.NoError(t, err)
+		require.Equal(t, 1, cnt)
+		assert.Equal(t, int64(1), item.Version)
+	})
+
+	t.Run("update increments version", func(t *testing.T) {
+		item := TestItemVersioned{ID: 2, Name: "second"}
+		require.NoError(t, DB.Upsert(testVersionNs, &item))
+		require.Equal(t, int64(1), item.Version)
+
+		item.Name = "second_updated"
+		cnt, err := DB.Update(testVersionNs, &item)
+		require.NoError(t, err)
+		require.Equal(t, 1, cnt)
+		assert.Equal(t, int64(2), item.Version)
+
+		stored, found := DB.Query(testVersionNs).WhereInt("id", reindexer.EQ, 2).Get()
+		require.True(t, found)
+		assert.Equal(t, "second_updated", stored.(*TestItemVersioned).Name)
+		assert.Equal(t, int64(2), stored.(*TestItemVersioned).Version)
+	})
+
+	t.Run("stale version is rejected", func(t *testing.T) {
+		item := TestItemVersioned{ID: 3, Name: "third"}
+		_, err := DB.Insert(testVersionNs, &item)
+		require.NoError(t, err)
+
+		stale := item
+		item.Name = "third_updated"
+		_, err = DB.Update(testVersionNs, &item)
+		require.NoError(t, err)
+
+		stale.Name = "third_stale"
+		_, err = DB.Update(testVersionNs, &stale)
+		assert.Equal(t, reindexer.ErrVersionConflict, err)
+		assert.Equal(t, reindexer.ErrVersionConflict, DB.Upsert(testVersionNs, &stale))
+
+		stored, found := DB.Query(testVersionNs).WhereInt("id", reindexer.EQ, 3).Get()
+		require.True(t, found)
+		assert.Equal(t, "third_updated", stored.(*TestItemVersioned).Name)
+		assert.Equal(t, int64(2), stored.(*TestItemVersioned).Version)
+	})
+
+	t.Run("upsert with zero version is not checked", func(t *testing.T) {
+		item := TestItemVersioned{ID: 4, Name: "fourth"}
+		_, err := DB.Insert(testVersionNs, &item)
+		require.NoError(t, err)
+		_, err = DB.Update(testVersionNs, &item)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), item.Version)
+
+		item.Name, item.Version = "fourth_overwritten", 0
+		require.NoError(t, DB.Upsert(testVersionNs, &item))
+		assert.Equal(t, int64(1), item.Version)
+
+		stored, found := DB.Query(testVersionNs).WhereInt("id", reindexer.EQ, 4).Get()
+		require.True(t, found)
+		assert.Equal(t, "fourth_overwritten", stored.(*TestItemVersioned).Name)
+		assert.Equal(t, int64(1), stored.(*TestItemVersioned).Version)
+	})
+
+	t.Run("update of missing item", func(t *testing.T) {
+		cnt, err := DB.Update(testVersionNs, &TestItemVersioned{ID: 100, Version: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 0, cnt)
+	})
+
+	t.Run("update replaces the whole item", func(t *testing.T) {
+		item := TestItemVersioned{ID: 5, Name: "fifth"}
+		_, err := DB.Insert(testVersionNs, &item)
+		require.NoError(t, err)
+
+		item.Name = ""
+		cnt, err := DB.Update(testVersionNs, &item)
+		require.NoError(t, err)
+		require.Equal(t, 1, cnt)
+
+		stored, found := DB.Query(testVersionNs).WhereInt("id", reindexer.EQ, 5).Get()
+		require.True(t, found)
+		assert.Equal(t, "", stored.(*TestItemVersioned).Name)
+		assert.Equal(t, int64(2), stored.(*TestItemVersioned).Version)
+	})
+}
@@ -0,0 +1,147 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+const (
+	// maxTimeSeriesBuckets limits count of the queries, which are executed by Query.TimeSeries
+	maxTimeSeriesBuckets = 10000
+	// maxTimeSeriesUnbatchedBuckets limits count of the queries, if the binding doesn't execute them by one call (see RawBindingSelectQueries)
+	maxTimeSeriesUnbatchedBuckets = 100
+)
+
+// TimeSeriesOptions - options of Query.TimeSeries
+type TimeSeriesOptions struct {
+	// Field with the timestamp of the item (unix time)
+	Field string
+	// Unit of the timestamp. PreceptSec, if not set
+	Unit PreceptTimeUnit
+	// Range of the timestamps. From is included, To is excluded
+	From, To time.Time
+	// Size of the bucket, e.g. time.Hour or 24*time.Hour. Buckets are aligned to the zero time (see time.Time.Truncate),
+	// so hourly and daily buckets start at the beginning of UTC hour and day
+	Bucket time.Duration
+	// Numeric fields, whose values are summed in each bucket
+	Sum []string
+}
+
+// TimeSeriesPoint - aggregated values of the items of the bucket
+type TimeSeriesPoint struct {
+	// Start of the bucket. The bucket includes timestamps in [Start, Start+Bucket)
+	Start time.Time
+	// Count of the items of the bucket
+	Count int
+	// Sums of the fields by field names. Sums of the empty bucket are 0
+	Sums map[string]float64
+}
+
+// TimeSeries executes the query's filters for each bucket of the time range and returns count of the items and sums of the fields per bucket.
+// Queries of the buckets are executed by one call of the binding (see ExecBatch), so the items are not transferred to the client.
+// Points are returned in the order of time for all the buckets of the range, including the empty ones
+func (q *Query) TimeSeries(ctx context.Context, opts TimeSeriesOptions) ([]TimeSeriesPoint, error) {
+	if q.root != nil || len(q.joinQueries) != 0 || len(q.mergedQueries) != 0 {
+		return nil, bindings.NewError("rq: TimeSeries does not support joined and merged queries", ErrCodeParams)
+	}
+	if q.closed {
+		q.panicTrace("TimeSeries call on already closed query. You should create new Query")
+	}
+	defer q.close()
+
+	if q.tx != nil {
+		return nil, bindings.NewError("rq: TimeSeries is not supported by transaction's queries", ErrCodeParams)
+	}
+	if err := q.validationError(); err != nil {
+		return nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if err := q.resolveSubQueries(ctx); err != nil {
+		return nil, err
+	}
+
+	maxBuckets := maxTimeSeriesBuckets
+	if _, ok := q.db.binding.(bindings.RawBindingSelectQueries); !ok {
+		// Queries are executed one by one
+		maxBuckets = maxTimeSeriesUnbatchedBuckets
+	}
+	var starts []time.Time
+	for start := opts.From.Truncate(opts.Bucket); start.Before(opts.To); start = start.Add(opts.Bucket) {
+		if len(starts) == maxBuckets {
+			return nil, bindings.NewError(fmt.Sprintf("rq: TimeSeries range contains more than %d buckets", maxBuckets), ErrCodeParams)
+		}
+		starts = append(starts, start)
+	}
+
+	queries := make([]*Query, len(starts))
+	for i, start := range starts {
+		from, to := start, start.Add(opts.Bucket)
+		// Bounds of the first and the last buckets are limited by the range
+		if from.Before(opts.From) {
+			from = opts.From
+		}
+		if to.After(opts.To) {
+			to = opts.To
+		}
+		bq := q.makeCopy(q.db, nil)
+		bq.WhereInt64(opts.Field, GE, timestampIn(from, opts.Unit)).WhereInt64(opts.Field, LT, timestampIn(to, opts.Unit)).ReqTotal().Limit(0)
+		for _, field := range opts.Sum {
+			bq.AggregateSum(field)
+		}
+		queries[i] = bq
+	}
+
+	points := make([]TimeSeriesPoint, len(starts))
+	var firstErr error
+	for i, it := range q.db.execBatch(ctx, queries) {
+		points[i] = TimeSeriesPoint{Start: starts[i], Count: it.TotalCount(), Sums: make(map[string]float64, len(opts.Sum))}
+		for _, field := range opts.Sum {
+			points[i].Sums[field] = 0
+		}
+		for _, agg := range it.AggResults() {
+			if agg.Type == "sum" && len(agg.Fields) == 1 && agg.Value != nil {
+				points[i].Sums[agg.Fields[0]] = *agg.Value
+			}
+		}
+		if err := it.Error(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		it.Close()
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return points, nil
+}
+
+func (opts *TimeSeriesOptions) validate() error {
+	switch {
+	case opts.Field == "":
+		return bindings.NewError("rq: TimeSeries field is not set", ErrCodeParams)
+	case opts.Bucket <= 0:
+		return bindings.NewError("rq: TimeSeries bucket must be positive", ErrCodeParams)
+	case !opts.From.Before(opts.To):
+		return bindings.NewError("rq: TimeSeries range is empty", ErrCodeParams)
+	case opts.Unit != "" && !autotimeUnits[string(opts.Unit)]:
+		return bindings.NewError(fmt.Sprintf("rq: Invalid time unit '%s' of TimeSeries. Expected one of sec, msec, usec or nsec", opts.Unit), ErrCodeParams)
+	}
+	return nil
+}
+
+// timestampIn returns unix time in the unit
+func timestampIn(t time.Time, unit PreceptTimeUnit) int64 {
+	switch unit {
+	case PreceptMsec:
+		return t.UnixMilli()
+	case PreceptUsec:
+		return t.UnixMicro()
+	case PreceptNsec:
+		return t.UnixNano()
+	}
+	return t.Unix()
+}
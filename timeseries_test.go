@@ -0,0 +1,49 @@
+package reindexer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestTimeSeries(t *testing.T) {
+	db, srv := newMockDB(t, "timeseries")
+	defer db.Close()
+
+	srv.SetResults(testNs, testItem{ID: 1}, testItem{ID: 2})
+	from := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	points, err := db.Query(testNs).WhereString("name", reindexer.EQ, "x").TimeSeries(context.Background(), reindexer.TimeSeriesOptions{
+		Field:  "id",
+		From:   from,
+		To:     from.Add(2 * time.Hour),
+		Bucket: time.Hour,
+		Sum:    []string{"id"},
+	})
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), points[0].Start)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), points[2].Start)
+	assert.Equal(t, 2, points[0].Count)
+	assert.Equal(t, map[string]float64{"id": 0}, points[0].Sums)
+	assert.Len(t, srv.CallsOf(mock.MethodSelectQueries), 3)
+
+	_, err = db.Query(testNs).TimeSeries(context.Background(), reindexer.TimeSeriesOptions{Field: "id", From: from, To: from, Bucket: time.Hour})
+	assert.Error(t, err)
+
+	for _, q := range []*reindexer.Query{
+		db.Query(testNs).Merge(db.Query(testNs)),
+		db.Query(testNs),
+	} {
+		_, err = q.TimeSeries(context.Background(), reindexer.TimeSeriesOptions{Field: "id", From: from, To: from.Add(10001 * time.Hour), Bucket: time.Hour})
+		rerr, ok := err.(bindings.Error)
+		require.True(t, ok)
+		assert.Equal(t, reindexer.ErrCodeParams, rerr.Code())
+	}
+}
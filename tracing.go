@@ -2,18 +2,116 @@ package reindexer
 
 import (
 	"context"
+	"time"
 
 	otelattr "go.opentelemetry.io/otel/attribute"
 	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/restream/reindexer/v3/bindings"
 )
 
-func (db *reindexerImpl) startTracingSpan(ctx context.Context, spanName string, attributes ...otelattr.KeyValue) oteltrace.Span {
-	_, span := db.otelTracer.Start(
-		ctx,
-		spanName,
-		oteltrace.WithAttributes(db.otelCommonTraceAttrs...),
-		oteltrace.WithAttributes(attributes...),
-	)
+// TracingAttributesFunc returns custom attributes for the span of operation op on namespace ns.
+// ns is empty for operations, which are not related to a namespace
+type TracingAttributesFunc func(ctx context.Context, op string, ns string) []otelattr.KeyValue
+
+// TracingOption - option of WithOpenTelemetryTracerProvider
+type TracingOption func(opt *bindings.OptionOpenTelemetry)
+
+// WithSpanNames sets function, which returns name of the span by the name of the operation (e.g. "Reindexer.Query.Exec")
+func WithSpanNames(fn func(op string) string) TracingOption {
+	return func(opt *bindings.OptionOpenTelemetry) {
+		opt.SpanName = fn
+	}
+}
+
+// WithQuerySQL adds SQL of the queries to the spans of the queries as 'rx.query' attribute (see Query.SQL)
+func WithQuerySQL() TracingOption {
+	return func(opt *bindings.OptionOpenTelemetry) {
+		opt.QuerySQL = true
+	}
+}
+
+// WithSlowOperations enables tracing of only the operations, which take longer than threshold. Spans of such operations
+// are created, when the operations are finished, with the start time of the operations
+func WithSlowOperations(threshold time.Duration) TracingOption {
+	return func(opt *bindings.OptionOpenTelemetry) {
+		opt.SlowThreshold = threshold
+	}
+}
+
+// tracingSpan is the span of the client side call. With slow operations threshold the span is created on End,
+// if the call is slow enough, so the attributes are collected until then
+type tracingSpan struct {
+	db    *reindexerImpl
+	ctx   context.Context
+	name  string
+	start time.Time
+	attrs []otelattr.KeyValue
+	span  oteltrace.Span
+}
+
+// startTracingSpan starts span of the call. Returned context carries the span, so the binding's calls with it send trace context
+// of the span to the server. With slow operations threshold the span is not started yet, so the context of the call is returned as is
+func (db *reindexerImpl) startTracingSpan(ctx context.Context, spanName string, attributes ...otelattr.KeyValue) (context.Context, *tracingSpan) {
+	attrs := make([]otelattr.KeyValue, 0, len(db.otelCommonTraceAttrs)+len(attributes))
+	attrs = append(attrs, db.otelCommonTraceAttrs...)
+	attrs = append(attrs, attributes...)
+	if db.otelAttrsFunc != nil {
+		attrs = append(attrs, db.otelAttrsFunc(ctx, spanName, tracingNamespace(attributes))...)
+	}
+
+	s := &tracingSpan{db: db, ctx: ctx, name: spanName}
+	if db.otelSpanName != nil {
+		s.name = db.otelSpanName(spanName)
+	}
+	if db.otelSlowThreshold > 0 {
+		s.start, s.attrs = time.Now(), attrs
+		return ctx, s
+	}
+	ctx, s.span = db.otelTracer.Start(ctx, s.name, oteltrace.WithAttributes(attrs...))
+	return ctx, s
+}
+
+// startQuerySpan starts span of the query with SQL of the query, if it's enabled by WithQuerySQL
+func (db *reindexerImpl) startQuerySpan(ctx context.Context, spanName string, q *Query) (context.Context, *tracingSpan) {
+	attrs := []otelattr.KeyValue{otelattr.String("rx.ns", q.Namespace)}
+	if db.otelQuerySQL {
+		if sql, err := q.SQL(); err == nil {
+			attrs = append(attrs, otelattr.String("rx.query", sql))
+		}
+	}
+	return db.startTracingSpan(ctx, spanName, attrs...)
+}
+
+// SetAttributes adds attributes to the span
+func (s *tracingSpan) SetAttributes(attributes ...otelattr.KeyValue) {
+	if s.span != nil {
+		s.span.SetAttributes(attributes...)
+	} else {
+		s.attrs = append(s.attrs, attributes...)
+	}
+}
+
+// End finishes the span. Span of the fast operation is not created with slow operations threshold
+func (s *tracingSpan) End() {
+	if s.span != nil {
+		s.span.End()
+		return
+	}
+	end := time.Now()
+	if end.Sub(s.start) < s.db.otelSlowThreshold {
+		return
+	}
+	_, span := s.db.otelTracer.Start(s.ctx, s.name, oteltrace.WithTimestamp(s.start), oteltrace.WithAttributes(s.attrs...))
+	span.End(oteltrace.WithTimestamp(end))
+}
 
-	return span
+// tracingNamespace returns value of 'rx.ns' attribute
+func tracingNamespace(attributes []otelattr.KeyValue) string {
+	for _, attr := range attributes {
+		if attr.Key == "rx.ns" {
+			return attr.Value.AsString()
+		}
+	}
+	return ""
 }
@@ -0,0 +1,86 @@
+package reindexer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otelattr "go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/restream/reindexer/v3"
+)
+
+type testSpan struct {
+	oteltrace.Span
+	name  string
+	attrs map[otelattr.Key]otelattr.Value
+	ended bool
+}
+
+func (s *testSpan) SetAttributes(kv ...otelattr.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *testSpan) End(options ...oteltrace.SpanEndOption) {
+	s.ended = true
+}
+
+// testTracerProvider records the spans, started by its tracer
+type testTracerProvider struct {
+	spans []*testSpan
+}
+
+func (tp *testTracerProvider) Tracer(name string, options ...oteltrace.TracerOption) oteltrace.Tracer {
+	return tp
+}
+
+func (tp *testTracerProvider) Start(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	span := &testSpan{Span: oteltrace.SpanFromContext(ctx), name: name, attrs: make(map[otelattr.Key]otelattr.Value)}
+	cfg := oteltrace.NewSpanStartConfig(opts...)
+	span.SetAttributes(cfg.Attributes()...)
+	tp.spans = append(tp.spans, span)
+	return ctx, span
+}
+
+func (tp *testTracerProvider) span(name string) *testSpan {
+	for _, span := range tp.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracerProvider(t *testing.T) {
+	tp := &testTracerProvider{}
+	db, srv := newMockDB(t, "tracing", reindexer.WithOpenTelemetryTracerProvider(tp,
+		reindexer.WithQuerySQL(),
+		reindexer.WithSpanNames(func(op string) string { return strings.TrimPrefix(op, "Reindexer.") }),
+	))
+	defer db.Close()
+
+	srv.SetResults(testNs, testItem{ID: 1}, testItem{ID: 2})
+	_, err := db.Query(testNs).WhereInt("id", reindexer.GT, 0).Exec().FetchAll()
+	require.NoError(t, err)
+
+	span := tp.span("Query.Exec")
+	require.NotNil(t, span)
+	assert.True(t, span.ended)
+	assert.Equal(t, "SELECT * FROM items WHERE id > 0", span.attrs["rx.query"].AsString())
+	assert.Equal(t, int64(2), span.attrs["rx.items.count"].AsInt64())
+	assert.Equal(t, testNs, span.attrs["rx.ns"].AsString())
+
+	// Fast operations are not traced with slow operations threshold
+	slowTp := &testTracerProvider{}
+	db2, _ := newMockDB(t, "tracing", reindexer.WithOpenTelemetryTracerProvider(slowTp, reindexer.WithSlowOperations(time.Hour)))
+	defer db2.Close()
+	_, err = db2.Query(testNs).Exec().FetchAll()
+	require.NoError(t, err)
+	assert.Empty(t, slowTp.spans)
+}
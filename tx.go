@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	otelattr "go.opentelemetry.io/otel/attribute"
@@ -32,16 +33,61 @@ type Tx struct {
 	lock         sync.Mutex
 	asyncErr     error
 	asyncErrLock sync.RWMutex
+	// 1, if commit was started by CommitAsync and is not completed yet
+	commitPending uint32
+	publisher     *txPublisher
+	// Modifications of the items for the publisher and for the namespace's history
+	events []TxEvent
+	// Size of the async items, which are waiting for the responses (see WithTxAsyncWindow). Guarded by cmplCond.L
+	asyncBytes int64
+	// Modifications, which are replayed by RollbackTo. nil, if savepoints are not enabled (see WithSavepoints)
+	journal    []txStep
+	savepoints []txSavepoint
+	// true, if the transaction has modifications
+	modified bool
+	// Modified items for the transaction's selects. nil, if read-your-writes is not enabled (see WithReadYourWrites)
+	reads *txReads
+	// Size of the serialized items, sent to the server. Accessed atomically
+	itemsBytes int64
+	// Counts of the items, sent to the server (see insertViolation)
+	itemCounts txItemCounts
+	// Transaction of the namespace's history. nil, if history is not enabled (see WithHistory)
+	history *historyTx
+}
+
+// txItemCounts - counts of the items of the transaction by modification
+type txItemCounts struct {
+	items   int
+	inserts int
+	// Updates and deletes are not applied to the missing items
+	updatesDeletes int
 }
 
 func newTx(db *reindexerImpl, namespace string, ctx context.Context) (tx *Tx, err error) {
-	tx = &Tx{db: db, namespace: namespace}
-	if tx.ns, err = tx.db.getNS(tx.namespace); err != nil {
+	ns, err := db.getNS(namespace)
+	if err != nil {
+		return nil, err
+	}
+	history, err := db.beginHistory(ctx, ns)
+	if err != nil {
 		return nil, err
 	}
-	if err = tx.startTxCtx(ctx); err != nil {
+	if tx, err = db.beginNsTx(ctx, namespace, ns); err != nil {
+		history.rollback()
+		return nil, err
+	}
+	tx.history = history
+	return tx, nil
+}
+
+// beginNsTx starts transaction of the namespace without history. It's used for the internal transactions, which
+// modifications are written to history by their callers
+func (db *reindexerImpl) beginNsTx(ctx context.Context, namespace string, ns *reindexerNamespace) (*Tx, error) {
+	tx := &Tx{db: db, namespace: namespace, ns: ns}
+	if err := tx.startTxCtx(ctx); err != nil {
 		return nil, err
 	}
+	atomic.AddInt64(&db.counters.txInFlight, 1)
 
 	return tx, nil
 }
@@ -75,6 +121,13 @@ func (tx *Tx) startTxCtx(ctx context.Context) (err error) {
 	return nil
 }
 
+// startTracingSpan starts span of the transaction's call. The calls send trace context of the transaction's context
+// (i.e. of 'Reindexer.Tx.Begin' span) to the server, because the context is shared with the completions of async calls
+func (tx *Tx) startTracingSpan(spanName string) *tracingSpan {
+	_, span := tx.db.startTracingSpan(tx.ctx.UserCtx, spanName, otelattr.String("rx.ns", tx.namespace))
+	return span
+}
+
 func (tx *Tx) startAsyncRoutines() error {
 	if tx.cmplCh == nil {
 		tx.cmplCh = make(chan modifyInfo, asyncResponseQueueSize)
@@ -82,12 +135,15 @@ func (tx *Tx) startAsyncRoutines() error {
 		go tx.cmplHandlingRoutine(tx.cmplCh)
 	}
 
+	if err := tx.db.waitRateLimit(tx.ctx.UserCtx, tx.namespace); err != nil {
+		return err
+	}
 	return tx.checkReqCount()
 }
 
 func (tx *Tx) Insert(item interface{}, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.Insert", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.Insert").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -104,7 +160,7 @@ func (tx *Tx) Insert(item interface{}, precepts ...string) error {
 
 func (tx *Tx) Update(item interface{}, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.Update", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.Update").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -122,7 +178,7 @@ func (tx *Tx) Update(item interface{}, precepts ...string) error {
 // Upsert (Insert or Update) item to namespace
 func (tx *Tx) Upsert(item interface{}, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.Upsert", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.Upsert").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -140,7 +196,7 @@ func (tx *Tx) Upsert(item interface{}, precepts ...string) error {
 // UpsertJSON (Insert or Update) item to namespace
 func (tx *Tx) UpsertJSON(json []byte, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.UpsertJSON", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.UpsertJSON").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -158,7 +214,7 @@ func (tx *Tx) UpsertJSON(json []byte, precepts ...string) error {
 // Delete - remove item by id from namespace
 func (tx *Tx) Delete(item interface{}, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.Delete", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.Delete").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -176,7 +232,7 @@ func (tx *Tx) Delete(item interface{}, precepts ...string) error {
 // DeleteJSON - remove item by id from namespace
 func (tx *Tx) DeleteJSON(json []byte, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.DeleteJSON", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.DeleteJSON").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -193,7 +249,7 @@ func (tx *Tx) DeleteJSON(json []byte, precepts ...string) error {
 
 func (tx *Tx) handleRecoverFromAsyncOp(rec interface{}) error {
 	tx.cmplCond.L.Lock()
-	atomic.AddUint32(&tx.asyncRspCnt, ^uint32(0))
+	tx.asyncOpDone()
 	tx.cmplCond.Broadcast()
 	tx.cmplCond.L.Unlock()
 	switch x := rec.(type) {
@@ -207,7 +263,7 @@ func (tx *Tx) handleRecoverFromAsyncOp(rec interface{}) error {
 // InsertAsync Insert item to namespace. Calls completion on result
 func (tx *Tx) InsertAsync(item interface{}, cmpl bindings.Completion, precepts ...string) (err error) {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.InsertAsync", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.InsertAsync").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -235,7 +291,7 @@ func (tx *Tx) InsertAsync(item interface{}, cmpl bindings.Completion, precepts .
 // UpdateAsync Update item to namespace. Calls completion on result
 func (tx *Tx) UpdateAsync(item interface{}, cmpl bindings.Completion, precepts ...string) (err error) {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.UpdateAsync", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.UpdateAsync").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -263,7 +319,7 @@ func (tx *Tx) UpdateAsync(item interface{}, cmpl bindings.Completion, precepts .
 // UpsertAsync (Insert or Update) item to namespace. Calls completion on result
 func (tx *Tx) UpsertAsync(item interface{}, cmpl bindings.Completion, precepts ...string) (err error) {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.UpsertAsync", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.UpsertAsync").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -291,7 +347,7 @@ func (tx *Tx) UpsertAsync(item interface{}, cmpl bindings.Completion, precepts .
 // UpsertJSONAsync (Insert or Update) item to index. Calls completion on result
 func (tx *Tx) UpsertJSONAsync(json []byte, cmpl bindings.Completion, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.UpsertJSONAsync", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.UpsertJSONAsync").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -313,7 +369,7 @@ func (tx *Tx) UpsertJSONAsync(json []byte, cmpl bindings.Completion, precepts ..
 // DeleteAsync - remove item by id from namespace. Calls completion on result
 func (tx *Tx) DeleteAsync(item interface{}, cmpl bindings.Completion, precepts ...string) (err error) {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.DeleteAsync", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.DeleteAsync").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -341,7 +397,7 @@ func (tx *Tx) DeleteAsync(item interface{}, cmpl bindings.Completion, precepts .
 // DeleteJSONAsync - remove item by id from namespace. Calls completion on result
 func (tx *Tx) DeleteJSONAsync(json []byte, cmpl bindings.Completion, precepts ...string) error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.DeleteJSONAsync", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.DeleteJSONAsync").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -369,8 +425,15 @@ func (tx *Tx) checkFinalization() error {
 
 // CommitWithCount apply changes, and return count of changed items
 func (tx *Tx) CommitWithCount() (count int, err error) {
+	if err = tx.checkCommitPending(); err != nil {
+		return 0, err
+	}
+	return tx.commitWithCount()
+}
+
+func (tx *Tx) commitWithCount() (count int, err error) {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.CommitWithCount", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.CommitWithCount").End()
 	}
 
 	if tx.db.promMetrics != nil {
@@ -380,11 +443,28 @@ func (tx *Tx) CommitWithCount() (count int, err error) {
 	if !tx.started {
 		return 0, nil
 	}
+	if tx.db.promMetrics != nil {
+		defer func(start time.Time) {
+			tx.db.promMetrics.observeWrite(tx.namespace, "Tx.Commit", start, err)
+			if err == nil {
+				tx.db.promMetrics.observeWriteSize(tx.namespace, "Tx.Commit", atomic.LoadInt64(&tx.itemsBytes))
+			}
+		}(time.Now())
+	}
 
 	if count, err = tx.commitInternal(); err != nil {
+		tx.history.rollback()
 		return
 	}
+	tx.db.queryCache.invalidate(tx.namespace)
 
+	historyErr := tx.writeHistory(count)
+	if err = tx.publish(); err == nil {
+		err = historyErr
+	}
+	if err == nil {
+		err = tx.insertViolation(count)
+	}
 	return
 }
 
@@ -392,11 +472,42 @@ func (tx *Tx) CommitWithCount() (count int, err error) {
 // if any error occurred during prepare process, then tx.Commit should
 // return an error. So it is enough, to check error returned by Commit - to be sure
 // that all data has been successfully committed or not.
+// Exceptions are HistoryError, TxPublishError and UniqueViolationError for inserts of the existing items: the transaction is committed with them
 func (tx *Tx) Commit() error {
 	_, err := tx.CommitWithCount()
 	return err
 }
 
+// CommitAsync - apply changes in background. Caller is not blocked by the pending async operations and by the commit itself:
+// cmpl is called, when all the async operations are done and the changes are applied (or on error).
+// ctx is used for the commit request. Transaction must not be used after CommitAsync
+func (tx *Tx) CommitAsync(ctx context.Context, cmpl bindings.Completion) {
+	if err := tx.checkFinalization(); err != nil {
+		cmpl(err)
+		return
+	}
+	if !atomic.CompareAndSwapUint32(&tx.commitPending, 0, 1) {
+		cmpl(bindings.NewError("Tx commit is already in progress", bindings.ErrLogic))
+		return
+	}
+	go func() {
+		tx.AwaitResults()
+		if ctx != nil {
+			tx.ctx.UserCtx = ctx
+		}
+		_, err := tx.commitWithCount()
+		atomic.StoreUint32(&tx.commitPending, 0)
+		cmpl(err)
+	}()
+}
+
+func (tx *Tx) checkCommitPending() error {
+	if atomic.LoadUint32(&tx.commitPending) != 0 {
+		return bindings.NewError("Tx commit is already in progress", bindings.ErrLogic)
+	}
+	return nil
+}
+
 // MustCommit apply changes and starts panic on errors
 func (tx *Tx) MustCommit() int {
 	count, err := tx.CommitWithCount()
@@ -429,6 +540,9 @@ func (tx *Tx) Query() *Query {
 
 // finalize transaction
 func (tx *Tx) finalize() {
+	if !tx.finalized {
+		atomic.AddInt64(&tx.db.counters.txInFlight, -1)
+	}
 	if tx.cmplCh != nil {
 		close(tx.cmplCh)
 		tx.cmplCh = nil
@@ -441,6 +555,24 @@ func (tx *Tx) finalize() {
 }
 
 func (tx *Tx) modifyInternal(item interface{}, json []byte, mode int, precepts ...string) (err error) {
+	defer tx.db.recoverPanic("Tx.Modify", &err)
+	if err = tx.db.waitRateLimit(tx.ctx.UserCtx, tx.namespace); err != nil {
+		return err
+	}
+	if err = tx.ns.validatePrecepts(precepts); err != nil {
+		return err
+	}
+	precepts = tx.ns.appendAutotimePrecepts(mode, item, precepts)
+	if err = tx.sendItem(item, json, mode, precepts); err != nil {
+		return err
+	}
+	tx.addEvent(item, json, mode)
+	tx.journalItem(item, json, mode, precepts)
+	return nil
+}
+
+// sendItem adds modification of the item to the transaction on the server
+func (tx *Tx) sendItem(item interface{}, json []byte, mode int, precepts []string) (err error) {
 	for tryCount := 0; tryCount < 2; tryCount++ {
 		ser := cjson.NewPoolSerializer()
 		defer ser.Close()
@@ -456,6 +588,7 @@ func (tx *Tx) modifyInternal(item interface{}, json []byte, mode int, precepts .
 		if err != nil {
 			rerr, ok := err.(bindings.Error)
 			if ok && rerr.Code() == bindings.ErrStateInvalidated {
+				atomic.AddInt64(&tx.db.counters.stateRetries, 1)
 				it := tx.db.query(tx.ns.name).Limit(0).ExecCtx(tx.ctx.UserCtx)
 				it.Close()
 				err = rerr
@@ -463,6 +596,7 @@ func (tx *Tx) modifyInternal(item interface{}, json []byte, mode int, precepts .
 			}
 			return err
 		}
+		atomic.AddInt64(&tx.itemsBytes, int64(len(ser.Bytes())))
 		return nil
 	}
 	return nil
@@ -495,6 +629,7 @@ func (tx *Tx) cmplHandlingRoutine(cmplCh chan modifyInfo) {
 			if err != nil {
 				rerr, ok := err.(bindings.Error)
 				if ok && rerr.Code() == bindings.ErrStateInvalidated && modifyRes.retries > 0 {
+					atomic.AddInt64(&tx.db.counters.stateRetries, 1)
 					it := tx.db.query(tx.ns.name).Limit(0).ExecCtx(tx.ctx.UserCtx)
 					err = it.Error()
 					it.Close()
@@ -508,7 +643,7 @@ func (tx *Tx) cmplHandlingRoutine(cmplCh chan modifyInfo) {
 
 			tx.setAsyncError(err)
 			tx.cmplCond.L.Lock()
-			atomic.AddUint32(&tx.asyncRspCnt, ^uint32(0))
+			tx.asyncOpDone()
 			tx.cmplCond.Broadcast()
 			tx.cmplCond.L.Unlock()
 		} else {
@@ -518,10 +653,13 @@ func (tx *Tx) cmplHandlingRoutine(cmplCh chan modifyInfo) {
 }
 
 func (tx *Tx) modifyInternalAsync(item interface{}, json []byte, mode int, cmpl bindings.Completion, retriesRemain uint32, precepts ...string) (err error) {
+	precepts = tx.ns.appendAutotimePrecepts(mode, item, precepts)
+	var reserved int64
 	internalCmpl := func(buf bindings.RawBuffer, err error) {
 		if buf != nil {
 			buf.Free()
 		}
+		tx.releaseAsyncBytes(reserved)
 		if err != nil {
 			tx.cmplCh <- modifyInfo{err: err, cmpl: cmpl, item: item, json: json, mode: mode, precepts: precepts, retries: retriesRemain}
 		} else {
@@ -534,35 +672,66 @@ func (tx *Tx) modifyInternalAsync(item interface{}, json []byte, mode int, cmpl
 	format := 0
 	stateToken := 0
 
+	if err = tx.ns.validatePrecepts(precepts); err != nil {
+		internalCmpl(nil, err)
+		return err
+	}
 	if format, stateToken, err = packItem(tx.ns, item, json, ser); err != nil {
 		internalCmpl(nil, err)
 		return err
 	}
 
+	if reserved, err = tx.acquireAsyncBytes(len(ser.Bytes()), retriesRemain == retriesOnInvalidStateCnt); err != nil {
+		// The item is not sent, so its slot is released without completion
+		tx.cmplCond.L.Lock()
+		tx.asyncOpDone()
+		tx.cmplCond.Broadcast()
+		tx.cmplCond.L.Unlock()
+		return err
+	}
+
+	if retriesRemain == retriesOnInvalidStateCnt {
+		// Retries are called by completions handling routine and are already recorded
+		tx.addEvent(item, json, mode)
+		tx.journalItem(item, json, mode, precepts)
+		atomic.AddInt64(&tx.itemsBytes, int64(len(ser.Bytes())))
+	}
 	tx.db.binding.ModifyItemTxAsync(&tx.ctx, format, ser.Bytes(), mode, precepts, stateToken, internalCmpl)
 
 	return nil
 }
 
+// asyncOpDone decrements counters of pending async operations
+func (tx *Tx) asyncOpDone() {
+	atomic.AddUint32(&tx.asyncRspCnt, ^uint32(0))
+	atomic.AddInt64(&tx.db.counters.pendingAsyncOps, -1)
+}
+
 func (tx *Tx) checkReqCount() error {
+	maxItems := tx.db.txWindowItems()
 	for {
 		asyncRspCnt := atomic.LoadUint32(&tx.asyncRspCnt)
-		if asyncRspCnt < maxAsyncRequests {
+		if asyncRspCnt < maxItems {
 			if atomic.CompareAndSwapUint32(&tx.asyncRspCnt, asyncRspCnt, asyncRspCnt+1) {
+				atomic.AddInt64(&tx.db.counters.pendingAsyncOps, 1)
 				tx.asyncErrLock.RLock()
 				err := tx.asyncErr
 				tx.asyncErrLock.RUnlock()
 				if err != nil {
 					tx.cmplCond.L.Lock()
-					atomic.AddUint32(&tx.asyncRspCnt, ^uint32(0))
+					tx.asyncOpDone()
 					tx.cmplCond.Broadcast()
 					tx.cmplCond.L.Unlock()
 				}
 				return err
 			}
+		} else if tx.db.txWindow.FailOnFull {
+			atomic.AddInt64(&tx.db.counters.txWindowRejects, 1)
+			return ErrTxAsyncWindowFull
 		} else {
+			atomic.AddInt64(&tx.db.counters.txWindowWaits, 1)
 			tx.cmplCond.L.Lock()
-			for atomic.LoadUint32(&tx.asyncRspCnt) == maxAsyncRequests {
+			for atomic.LoadUint32(&tx.asyncRspCnt) >= maxItems {
 				tx.cmplCond.Wait()
 			}
 			tx.cmplCond.L.Unlock()
@@ -572,12 +741,14 @@ func (tx *Tx) checkReqCount() error {
 
 // Commit apply changes
 func (tx *Tx) commitInternal() (count int, err error) {
+	defer tx.db.recoverPanic("Tx.Commit", &err)
 	count = 0
 	err = tx.checkFinalization()
 	if err != nil {
 		return
 	}
 
+	defer tx.db.startActivity(tx.ctx.UserCtx, "Tx.Commit", tx.namespace)()
 	tx.AwaitResults()
 	defer tx.finalize()
 	if tx.asyncErr != nil {
@@ -589,10 +760,18 @@ func (tx *Tx) commitInternal() (count int, err error) {
 		return 0, err
 	}
 
-	out, err := tx.db.binding.CommitTx(&tx.ctx)
+	release, err := tx.db.acquireNsSlot(tx.ctx.UserCtx, tx.namespace)
 	if err != nil {
+		tx.db.binding.RollbackTx(&tx.ctx)
 		return 0, err
 	}
+	out, err := tx.db.interceptCall(tx.ctx.UserCtx, OpInfo{Op: OpCommitTx, Namespace: tx.namespace}, func(ctx context.Context) (bindings.RawBuffer, error) {
+		return tx.db.binding.CommitTx(&tx.ctx)
+	})
+	release()
+	if err != nil {
+		return 0, asUniqueViolation(err)
+	}
 	defer out.Free()
 
 	rdSer := newSerializer(out.GetBuf())
@@ -618,15 +797,19 @@ func (tx *Tx) commitInternal() (count int, err error) {
 // It is safe to call Rollback after Commit
 func (tx *Tx) Rollback() error {
 	if tx.db.otelTracer != nil {
-		defer tx.db.startTracingSpan(tx.ctx.UserCtx, "Reindexer.Tx.Rollback", otelattr.String("rx.ns", tx.namespace)).End()
+		defer tx.startTracingSpan("Reindexer.Tx.Rollback").End()
 	}
 
 	if tx.db.promMetrics != nil {
 		defer prometheus.NewTimer(tx.db.promMetrics.clientCallsLatency.WithLabelValues("Tx.Rollback", tx.namespace)).ObserveDuration()
 	}
 
+	if err := tx.checkCommitPending(); err != nil {
+		return err
+	}
 	tx.AwaitResults()
 	tx.asyncErr = nil
 	defer tx.finalize()
+	tx.history.rollback()
 	return tx.db.binding.RollbackTx(&tx.ctx)
 }
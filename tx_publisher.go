@@ -0,0 +1,126 @@
+package reindexer
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// TxEvent - modification of the item, which is delivered to TxPublisher after commit of the transaction
+type TxEvent struct {
+	Namespace string
+	// Modification: "Insert", "Update", "Upsert" or "Delete"
+	Op string
+	// Values of the primary key's fields. nil for items in JSON format
+	PK []interface{}
+	// Modified item, which was passed to the transaction. Raw JSON ([]byte) for items in JSON format
+	Item interface{}
+}
+
+// TxPublisher receives items, written by the committed transaction (e.g. to publish them to message broker).
+// Events may be delivered more than once, if Publish returns error, so Publish must be idempotent
+type TxPublisher interface {
+	Publish(ctx context.Context, events []TxEvent) error
+}
+
+// TxPublisherFunc is an adapter to use ordinary function as TxPublisher
+type TxPublisherFunc func(ctx context.Context, events []TxEvent) error
+
+func (f TxPublisherFunc) Publish(ctx context.Context, events []TxEvent) error {
+	return f(ctx, events)
+}
+
+// TxPublishError is returned by Commit, if the transaction is committed, but its events were not published by all the attempts.
+// Events may be saved and published later by the application
+type TxPublishError struct {
+	Events []TxEvent
+	Err    error
+}
+
+func (e *TxPublishError) Error() string {
+	return "rq: transaction is committed, but its events are not published: " + e.Err.Error()
+}
+
+func (e *TxPublishError) Unwrap() error {
+	return e.Err
+}
+
+type txPublisher struct {
+	publisher TxPublisher
+	attempts  int
+	backoff   time.Duration
+}
+
+// WithPublisher sets publisher, which receives items, written by the transaction, after successful commit.
+// Publishing is retried up to attempts times with backoff delay between the attempts. Modifications by the transaction's
+// queries (Tx.Query().Update(), etc) are not published
+func (tx *Tx) WithPublisher(publisher TxPublisher, attempts int, backoff time.Duration) *Tx {
+	if attempts < 1 {
+		attempts = 1
+	}
+	tx.publisher = &txPublisher{publisher: publisher, attempts: attempts, backoff: backoff}
+	return tx
+}
+
+// addEvent records modification of the item for the publisher and for the namespace's history
+func (tx *Tx) addEvent(item interface{}, json []byte, mode int) {
+	if tx.publisher == nil && !tx.ns.opts.history {
+		return
+	}
+	tx.events = append(tx.events, tx.ns.newTxEvent(item, json, mode))
+}
+
+// newTxEvent returns event for modification of the item
+func (ns *reindexerNamespace) newTxEvent(item interface{}, json []byte, mode int) TxEvent {
+	event := TxEvent{Namespace: ns.name, Op: modifyModeNames[mode], Item: item}
+	if json != nil {
+		event.Item = json
+	} else {
+		event.PK = ns.pkValues(item)
+	}
+	return event
+}
+
+// publish delivers events of the committed transaction to the publisher
+func (tx *Tx) publish() error {
+	p := tx.publisher
+	if p == nil || len(tx.events) == 0 {
+		return nil
+	}
+	ctx := tx.ctx.UserCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var err error
+	for attempt := 0; attempt < p.attempts; attempt++ {
+		if attempt != 0 && p.backoff > 0 {
+			timer := time.NewTimer(p.backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return &TxPublishError{Events: tx.events, Err: ctx.Err()}
+			}
+		}
+		if err = p.publisher.Publish(ctx, tx.events); err == nil {
+			return nil
+		}
+	}
+	return &TxPublishError{Events: tx.events, Err: err}
+}
+
+// pkValues returns values of the primary key's fields of the item
+func (ns *reindexerNamespace) pkValues(item interface{}) []interface{} {
+	if len(ns.pk) == 0 || item == nil || isRawItem(item) {
+		return nil
+	}
+	values := make([]interface{}, 0, len(ns.pk))
+	for i := range ns.pk {
+		v, ok := ns.pk[i].value(item)
+		if v = reflect.Indirect(v); !ok || !v.IsValid() {
+			return nil
+		}
+		values = append(values, v.Interface())
+	}
+	return values
+}
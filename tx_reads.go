@@ -0,0 +1,572 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// txReads - modifications of the items by the transaction, which are visible to the transaction's selects (see Tx.WithReadYourWrites)
+type txReads struct {
+	// Modifications of the items by the keys of primary key (see pkKey) in the order of the calls
+	writes map[string][]txWrite
+	// Keys in the order of the first modification
+	keys []string
+	// Reason, why the modified items are not known by the client (e.g. the transaction's update query). Selects fail, if it's set
+	unknown string
+}
+
+type txWrite struct {
+	item interface{}
+	mode int
+}
+
+// WithReadYourWrites makes the items, modified by the transaction, visible to the transaction's selects (tx.Query().Exec()).
+// It must be called before the modifications of the transaction. Selects are executed on the committed items and merged with
+// the modified ones by the client, so only queries with conditions on the fields of the namespace's struct, sort by the fields,
+// limit, offset and total count are supported. Selects fail after the transaction's update and delete queries and items in JSON format
+func (tx *Tx) WithReadYourWrites() *Tx {
+	if tx.reads == nil {
+		tx.reads = &txReads{writes: make(map[string][]txWrite)}
+		if tx.modified {
+			tx.reads.unknown = "modifications before WithReadYourWrites"
+		}
+	}
+	return tx
+}
+
+func (r *txReads) addItem(ns *reindexerNamespace, item interface{}, json []byte, mode int) {
+	if json != nil || item == nil {
+		r.unknown = "items in JSON format"
+		return
+	}
+	key, err := ns.pkKey(item)
+	if err != nil {
+		r.unknown = "items without primary key"
+		return
+	}
+	if _, ok := r.writes[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.writes[key] = append(r.writes[key], txWrite{item: item, mode: mode})
+}
+
+func (r *txReads) addQuery() {
+	r.unknown = "update and delete queries"
+}
+
+// addStep adds the journaled modification of the transaction (see RollbackTo)
+func (r *txReads) addStep(ns *reindexerNamespace, step *txStep) {
+	if step.query != nil {
+		r.addQuery()
+	} else {
+		r.addItem(ns, step.item, step.json, step.mode)
+	}
+}
+
+// apply returns the item after the modifications of the transaction, or nil, if there is no such item. committed is the committed item or nil
+func (r *txReads) apply(key string, committed interface{}) interface{} {
+	item := committed
+	for _, w := range r.writes[key] {
+		switch w.mode {
+		case modeInsert:
+			if item == nil {
+				item = w.item
+			}
+		case modeUpdate:
+			if item != nil {
+				item = w.item
+			}
+		case modeDelete:
+			item = nil
+		default:
+			item = w.item
+		}
+	}
+	return item
+}
+
+func errTxReadUnsupported(what string) error {
+	return bindings.NewError("rq: "+what+" is not supported by read-your-writes query of the transaction", ErrCodeParams)
+}
+
+// execQueryTx executes select of the transaction with the items, modified by the transaction
+func (db *reindexerImpl) execQueryTx(ctx context.Context, q *Query) *Iterator {
+	reads := q.tx.reads
+	if len(reads.writes) == 0 && reads.unknown == "" {
+		return db.execQuery(ctx, q)
+	}
+	defer q.close()
+	if reads.unknown != "" {
+		return errIterator(errTxReadUnsupported("select after " + reads.unknown))
+	}
+	ns, err := db.getNS(q.Namespace)
+	if err != nil {
+		return errIterator(err)
+	}
+	// Local items must match the same filters as the committed ones
+	q.addDefaultFilters(ctx)
+	q.addSoftDeleteFilter()
+	d, err := q.toDSL()
+	if err != nil {
+		return errIterator(err)
+	}
+	m, err := newTxMatcher(ns, d, q.pkTiebreaker)
+	if err != nil {
+		return errIterator(err)
+	}
+
+	committed, total, err := db.selectCommitted(ctx, q, d, len(reads.writes))
+	if err != nil {
+		return errIterator(err)
+	}
+	touched, err := db.selectByPk(ctx, ns, reads)
+	if err != nil {
+		return errIterator(err)
+	}
+
+	objects := make([]interface{}, 0, len(committed)+len(reads.keys))
+	for _, obj := range committed {
+		if key, err := ns.pkKey(obj); err == nil && reads.writes[key] != nil {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	for _, key := range reads.keys {
+		if base := touched[key]; base != nil {
+			matched, err := m.match(base)
+			if err != nil {
+				return errIterator(err)
+			}
+			if matched {
+				total--
+			}
+		}
+		item := reads.apply(key, touched[key])
+		if item == nil {
+			continue
+		}
+		matched, err := m.match(item)
+		if err != nil {
+			return errIterator(err)
+		}
+		if matched {
+			total++
+			objects = append(objects, copyItem(item))
+		}
+	}
+	if err = m.sort(objects); err != nil {
+		return errIterator(err)
+	}
+
+	offset := 0
+	if d.Offset != nil {
+		offset = *d.Offset
+	}
+	if offset > len(objects) {
+		offset = len(objects)
+	}
+	objects = objects[offset:]
+	if d.Limit != nil && *d.Limit < len(objects) {
+		objects = objects[:*d.Limit]
+	}
+	if d.ReqTotal == dslReqTotalModes[modeNoCalc] {
+		total = 0
+	}
+	return newObjectsIterator(ctx, db, q.Namespace, objects, total)
+}
+
+// selectCommitted executes the query on the committed items. Limit is increased by the count of the modified items,
+// so the results contain enough items after exclusion of the modified ones
+func (db *reindexerImpl) selectCommitted(ctx context.Context, q *Query, d *dslQuery, modified int) ([]interface{}, int, error) {
+	cd := *d
+	cd.Offset = nil
+	if d.Limit != nil {
+		limit := *d.Limit + modified
+		if d.Offset != nil {
+			limit += *d.Offset
+		}
+		cd.Limit = &limit
+	}
+	cq, err := db.queryFromDSLCopy(&cd)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Filters are already in the DSL
+	cq.WithoutDefaults().WithDeleted()
+	cq.pkTiebreaker = q.pkTiebreaker
+	it := cq.ExecCtx(ctx)
+	defer it.Close()
+	var objects []interface{}
+	for it.Next() {
+		objects = append(objects, it.Object())
+	}
+	return objects, it.TotalCount(), it.Error()
+}
+
+// selectByPk returns committed versions of the items, modified by the transaction, by the keys of primary key
+func (db *reindexerImpl) selectByPk(ctx context.Context, ns *reindexerNamespace, reads *txReads) (map[string]interface{}, error) {
+	q := db.query(ns.name).WithoutDefaults().WithDeleted()
+	for i, key := range reads.keys {
+		if i != 0 {
+			q.Or()
+		}
+		q.OpenBracket()
+		if err := ns.wherePk(q, reads.writes[key][0].item); err != nil {
+			q.close()
+			return nil, err
+		}
+		q.CloseBracket()
+	}
+	it := q.ExecCtx(ctx)
+	defer it.Close()
+	res := make(map[string]interface{}, len(reads.keys))
+	for it.Next() {
+		if key, err := ns.pkKey(it.Object()); err == nil {
+			res[key] = it.Object()
+		}
+	}
+	return res, it.Error()
+}
+
+// copyItem returns pointer to the shallow copy of the item, so the items, passed to the transaction, are not shared with the results
+func copyItem(item interface{}) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	res := reflect.New(v.Type())
+	res.Elem().Set(v)
+	return res.Interface()
+}
+
+// txMatcher evaluates conditions and sort of the query on the items of the namespace's struct
+type txMatcher struct {
+	ns      *reindexerNamespace
+	filters []dslFilter
+	sorts   []txSortField
+	fields  map[string]structFieldRef
+}
+
+type txSortField struct {
+	ref  structFieldRef
+	desc bool
+}
+
+func newTxMatcher(ns *reindexerNamespace, d *dslQuery, pkTiebreaker bool) (*txMatcher, error) {
+	switch {
+	case len(d.MergeQueries) != 0:
+		return nil, errTxReadUnsupported("merge")
+	case len(d.Aggregations) != 0:
+		return nil, errTxReadUnsupported("aggregation")
+	case d.Explain:
+		return nil, errTxReadUnsupported("explain")
+	case d.WithRank || len(d.SelectFunctions) != 0:
+		return nil, errTxReadUnsupported("full text search")
+	case len(d.EqualPositions) != 0:
+		return nil, errTxReadUnsupported("equal position")
+	}
+	m := &txMatcher{ns: ns, filters: d.Filters, fields: make(map[string]structFieldRef)}
+	if err := m.checkFilters(d.Filters); err != nil {
+		return nil, err
+	}
+	for _, s := range d.Sort {
+		if len(s.Values) != 0 {
+			return nil, errTxReadUnsupported("sort with forced values")
+		}
+		ref, err := m.field(s.Field)
+		if err != nil {
+			return nil, err
+		}
+		m.sorts = append(m.sorts, txSortField{ref: ref, desc: s.Desc})
+	}
+	if pkTiebreaker && len(m.sorts) != 0 {
+		for _, pk := range ns.pk {
+			m.sorts = append(m.sorts, txSortField{ref: pk})
+		}
+	}
+	return m, nil
+}
+
+func (m *txMatcher) field(name string) (structFieldRef, error) {
+	if ref, ok := m.fields[name]; ok {
+		return ref, nil
+	}
+	ref, ok := findStructField(m.ns.rtype, name)
+	if !ok {
+		return ref, errTxReadUnsupported("field '" + name + "', which is not in the struct,")
+	}
+	m.fields[name] = ref
+	return ref, nil
+}
+
+func (m *txMatcher) checkFilters(filters []dslFilter) error {
+	for _, f := range filters {
+		switch {
+		case f.JoinQuery != nil:
+			return errTxReadUnsupported("join")
+		case len(f.EqualPositions) != 0:
+			return errTxReadUnsupported("equal position")
+		case f.Filters != nil:
+			if err := m.checkFilters(f.Filters); err != nil {
+				return err
+			}
+			continue
+		}
+		switch f.Cond {
+		case dslCondNames[LIKE], dslCondNames[DWITHIN]:
+			return errTxReadUnsupported("condition '" + f.Cond + "'")
+		}
+		fields := []string{f.Field}
+		if f.FirstField != "" {
+			fields = []string{f.FirstField, f.SecondField}
+		}
+		for _, field := range fields {
+			if _, err := m.field(field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *txMatcher) match(item interface{}) (bool, error) {
+	return m.matchFilters(m.filters, item)
+}
+
+// matchFilters evaluates the filters on the item. OR has higher priority than AND, so 'A AND B OR C' is 'A AND (B OR C)'
+func (m *txMatcher) matchFilters(filters []dslFilter, item interface{}) (bool, error) {
+	res, group, hasGroup := true, false, false
+	for _, f := range filters {
+		matched, err := m.matchFilter(&f, item)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case f.Op == dslOpNames[opOR] && hasGroup:
+			group = group || matched
+			continue
+		case hasGroup:
+			res = res && group
+		}
+		group, hasGroup = matched, true
+		if f.Op == dslOpNames[opNOT] {
+			group = !matched
+		}
+	}
+	if hasGroup {
+		res = res && group
+	}
+	return res, nil
+}
+
+func (m *txMatcher) matchFilter(f *dslFilter, item interface{}) (bool, error) {
+	if f.Filters != nil {
+		return m.matchFilters(f.Filters, item)
+	}
+	if f.FirstField != "" {
+		second := m.values(f.SecondField, item)
+		if len(second) == 0 {
+			return false, nil
+		}
+		return matchCond(f.Cond, m.values(f.FirstField, item), second)
+	}
+	var values []interface{}
+	switch v := f.Value.(type) {
+	case nil:
+	case []interface{}:
+		values = v
+	default:
+		values = []interface{}{v}
+	}
+	return matchCond(f.Cond, m.values(f.Field, item), values)
+}
+
+func (m *txMatcher) values(field string, item interface{}) []interface{} {
+	ref := m.fields[field]
+	return fieldValues(&ref, item)
+}
+
+// fieldValues returns values of the item's field. Elements of arrays are returned as separate values
+func fieldValues(ref *structFieldRef, item interface{}) []interface{} {
+	v, ok := ref.value(item)
+	if v = reflect.Indirect(v); !ok || !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Array || (v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8) {
+		res := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if elem := reflect.Indirect(v.Index(i)); elem.IsValid() {
+				res = append(res, elem.Interface())
+			}
+		}
+		return res
+	}
+	return []interface{}{v.Interface()}
+}
+
+func matchCond(cond string, fieldValues, values []interface{}) (bool, error) {
+	switch cond {
+	case dslCondNames[ANY]:
+		return len(fieldValues) != 0, nil
+	case dslCondNames[EMPTY]:
+		return len(fieldValues) == 0, nil
+	case dslCondNames[ALLSET]:
+		for _, v := range values {
+			if found, err := containsValue(fieldValues, v); err != nil || !found {
+				return false, err
+			}
+		}
+		return len(values) != 0, nil
+	case dslCondNames[EQ], dslCondNames[SET]:
+		for _, v := range values {
+			if found, err := containsValue(fieldValues, v); err != nil || found {
+				return found, err
+			}
+		}
+		return false, nil
+	case dslCondNames[RANGE]:
+		if len(values) != 2 {
+			return false, bindings.NewError("rq: range condition expects 2 values", ErrCodeParams)
+		}
+		for _, fv := range fieldValues {
+			lo, err := compareValues(fv, values[0])
+			if err != nil {
+				return false, err
+			}
+			hi, err := compareValues(fv, values[1])
+			if err != nil {
+				return false, err
+			}
+			if lo >= 0 && hi <= 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case dslCondNames[LT], dslCondNames[LE], dslCondNames[GT], dslCondNames[GE]:
+		if len(values) == 0 {
+			return false, nil
+		}
+		for _, fv := range fieldValues {
+			c, err := compareValues(fv, values[0])
+			if err != nil {
+				return false, err
+			}
+			if (cond == dslCondNames[LT] && c < 0) || (cond == dslCondNames[LE] && c <= 0) ||
+				(cond == dslCondNames[GT] && c > 0) || (cond == dslCondNames[GE] && c >= 0) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, errTxReadUnsupported("condition '" + cond + "'")
+}
+
+func containsValue(fieldValues []interface{}, v interface{}) (bool, error) {
+	for _, fv := range fieldValues {
+		c, err := compareValues(fv, v)
+		if err != nil {
+			return false, err
+		}
+		if c == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compareValues compares scalar values of the fields and of the conditions. Integers and floats are compared as numbers
+func compareValues(a, b interface{}) (int, error) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isIntKind(va.Kind()) && isIntKind(vb.Kind()):
+		ia, ib := intValue(va), intValue(vb)
+		switch {
+		case ia < ib:
+			return -1, nil
+		case ia > ib:
+			return 1, nil
+		}
+		return 0, nil
+	case isNumberKind(va.Kind()) && isNumberKind(vb.Kind()):
+		fa, fb := floatValue(va), floatValue(vb)
+		switch {
+		case fa < fb:
+			return -1, nil
+		case fa > fb:
+			return 1, nil
+		}
+		return 0, nil
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return strings.Compare(va.String(), vb.String()), nil
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		switch {
+		case va.Bool() == vb.Bool():
+			return 0, nil
+		case vb.Bool():
+			return -1, nil
+		}
+		return 1, nil
+	}
+	return 0, errTxReadUnsupported(fmt.Sprintf("comparison of %T and %T", a, b))
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Int64) || (k >= reflect.Uint && k <= reflect.Uint64)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return isIntKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func intValue(v reflect.Value) int64 {
+	if v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64 {
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+func floatValue(v reflect.Value) float64 {
+	switch {
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float()
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return float64(v.Int())
+}
+
+// sort sorts the items by the sort of the query. Items without value of the field are placed first. Order of the items is kept,
+// if the query has no sort
+func (m *txMatcher) sort(objects []interface{}) (err error) {
+	if len(m.sorts) == 0 {
+		return nil
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		for _, s := range m.sorts {
+			vi, vj := fieldValues(&s.ref, objects[i]), fieldValues(&s.ref, objects[j])
+			c := 0
+			switch {
+			case len(vi) == 0 && len(vj) == 0:
+			case len(vi) == 0:
+				c = -1
+			case len(vj) == 0:
+				c = 1
+			default:
+				var cerr error
+				if c, cerr = compareValues(vi[0], vj[0]); cerr != nil && err == nil {
+					err = cerr
+				}
+			}
+			if s.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return err
+}
@@ -0,0 +1,54 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+)
+
+func TestTxReadYourWrites(t *testing.T) {
+	db, srv := newMockDB(t, "tx_reads")
+	defer db.Close()
+	// Mock returns all the committed items for any select, so the queries have conditions, matched by all of them
+	srv.SetResults(testNs, testItem{ID: 1, Name: "a"}, testItem{ID: 2, Name: "b"}, testItem{ID: 3, Name: "c"})
+
+	tx, err := db.BeginTx(testNs)
+	require.NoError(t, err)
+	tx.WithReadYourWrites()
+	require.NoError(t, tx.Update(testItem{ID: 2, Name: "z"}))
+	require.NoError(t, tx.Delete(testItem{ID: 3}))
+	require.NoError(t, tx.Insert(&testItem{ID: 4, Name: "d"}))
+	// Item exists, so insert is ignored
+	require.NoError(t, tx.Insert(testItem{ID: 1, Name: "ignored"}))
+	// Item doesn't exist, so update is ignored
+	require.NoError(t, tx.Update(testItem{ID: 5, Name: "ignored"}))
+
+	names := func(it *reindexer.Iterator) []string {
+		var res []string
+		for it.Next() {
+			res = append(res, it.Object().(*testItem).Name)
+		}
+		require.NoError(t, it.Error())
+		return res
+	}
+
+	it := tx.Query().Sort("name", false).ReqTotal().Exec()
+	assert.Equal(t, []string{"a", "d", "z"}, names(it))
+	assert.Equal(t, 3, it.TotalCount())
+
+	it = tx.Query().WhereInt("id", reindexer.LT, 4).Sort("name", true).Limit(1).ReqTotal().Exec()
+	assert.Equal(t, []string{"z"}, names(it))
+	assert.Equal(t, 2, it.TotalCount())
+
+	_, err = tx.Query().WhereString("name", reindexer.LIKE, "a%").Exec().FetchAll()
+	assert.Error(t, err)
+
+	// Modified items are not known after the transaction's queries
+	require.NoError(t, tx.Query().WhereInt("id", reindexer.EQ, 1).Update().Error())
+	_, err = tx.Query().Exec().FetchAll()
+	assert.Error(t, err)
+	require.NoError(t, tx.Rollback())
+}
@@ -0,0 +1,175 @@
+package reindexer
+
+import (
+	"sync/atomic"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// ErrTxSavepointNotFound is returned by RollbackTo, if the savepoint with the name is not created or is already rolled back
+var ErrTxSavepointNotFound = bindings.NewError("rq: savepoint of the transaction is not found", ErrCodeParams)
+
+// ErrTxSavepointsDisabled is returned by Savepoint, if savepoints are not enabled by WithSavepoints before the modifications of the transaction
+var ErrTxSavepointsDisabled = bindings.NewError("rq: savepoints of the transaction are not enabled", ErrCodeLogic)
+
+// txStep - modification of the transaction, which is replayed by RollbackTo
+type txStep struct {
+	item     interface{}
+	json     []byte
+	mode     int
+	precepts []string
+	// Serialized query for the transaction's queries (Tx.Query().Update() and Tx.Query().Delete())
+	query  []byte
+	update bool
+}
+
+type txSavepoint struct {
+	name string
+	// Count of the steps and of the events of the transaction before the savepoint
+	steps  int
+	events int
+	counts txItemCounts
+}
+
+// WithSavepoints enables Savepoint and RollbackTo for the transaction. It must be called before the modifications of the transaction,
+// otherwise Savepoint returns ErrTxSavepointsDisabled. All the modifications are kept by the transaction until commit, so it's
+// not recommended for huge transactions, and the items must not be changed by the caller until commit
+func (tx *Tx) WithSavepoints() *Tx {
+	if tx.journal == nil && !tx.modified {
+		tx.journal = make([]txStep, 0, 16)
+	}
+	return tx
+}
+
+// Savepoint marks the current state of the transaction with the name. Modifications, made after the savepoint, may be discarded by RollbackTo
+// without the rollback of the whole transaction (e.g. to retry partial batch). Savepoint with existing name is moved to the current state
+func (tx *Tx) Savepoint(name string) error {
+	if err := tx.checkFinalization(); err != nil {
+		return err
+	}
+	if tx.journal == nil {
+		return ErrTxSavepointsDisabled
+	}
+	// Async items are journaled on send, so the savepoint doesn't depend on their responses
+	tx.removeSavepoint(name)
+	tx.savepoints = append(tx.savepoints, txSavepoint{name: name, steps: len(tx.journal), events: len(tx.events), counts: tx.itemCounts})
+	return nil
+}
+
+// RollbackTo discards modifications, made after the savepoint, including failed async items. Savepoint remains, while the later
+// savepoints are removed. Server transaction is rolled back and started again with the modifications, made before the savepoint,
+// so RollbackTo takes time, proportional to the size of the transaction
+func (tx *Tx) RollbackTo(name string) error {
+	if err := tx.checkCommitPending(); err != nil {
+		return err
+	}
+	if err := tx.checkFinalization(); err != nil {
+		return err
+	}
+	idx := tx.findSavepoint(name)
+	if idx < 0 {
+		return ErrTxSavepointNotFound
+	}
+	tx.AwaitResults()
+
+	sp := tx.savepoints[idx]
+	userCtx := tx.ctx.UserCtx
+	if err := tx.db.binding.RollbackTx(&tx.ctx); err != nil {
+		tx.setAsyncError(err)
+		return err
+	}
+	if tx.ctx.Result != nil {
+		tx.ctx.Result.Free()
+		tx.ctx.Result = nil
+	}
+	ctx, err := tx.db.binding.BeginTx(userCtx, tx.namespace)
+	if err != nil {
+		tx.setAsyncError(err)
+		return err
+	}
+	tx.ctx = ctx
+	tx.ctx.UserCtx = userCtx
+	tx.asyncErrLock.Lock()
+	tx.asyncErr = nil
+	tx.asyncErrLock.Unlock()
+	atomic.StoreInt64(&tx.itemsBytes, 0)
+
+	tx.journal = tx.journal[:sp.steps]
+	tx.events = tx.events[:sp.events]
+	tx.itemCounts = sp.counts
+	tx.savepoints = tx.savepoints[:idx+1]
+	if tx.reads != nil {
+		tx.reads = &txReads{writes: make(map[string][]txWrite)}
+	}
+	for i := range tx.journal {
+		if tx.reads != nil {
+			tx.reads.addStep(tx.ns, &tx.journal[i])
+		}
+		if err = tx.replayStep(&tx.journal[i]); err != nil {
+			// Transaction doesn't contain all the modifications before the savepoint, so it must not be committed
+			tx.setAsyncError(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *Tx) replayStep(step *txStep) error {
+	switch {
+	case step.query == nil:
+		return tx.sendItem(step.item, step.json, step.mode, step.precepts)
+	case step.update:
+		return tx.db.binding.UpdateQueryTx(&tx.ctx, step.query)
+	default:
+		return tx.db.binding.DeleteQueryTx(&tx.ctx, step.query)
+	}
+}
+
+func (tx *Tx) findSavepoint(name string) int {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tx *Tx) removeSavepoint(name string) {
+	if idx := tx.findSavepoint(name); idx >= 0 {
+		tx.savepoints = append(tx.savepoints[:idx], tx.savepoints[idx+1:]...)
+	}
+}
+
+// journalItem records modification of the item for RollbackTo and for read-your-writes selects, and counts it
+func (tx *Tx) journalItem(item interface{}, json []byte, mode int, precepts []string) {
+	tx.modified = true
+	tx.itemCounts.items++
+	switch mode {
+	case modeInsert:
+		tx.itemCounts.inserts++
+	case modeUpdate, modeDelete:
+		tx.itemCounts.updatesDeletes++
+	}
+	if tx.reads != nil {
+		tx.reads.addItem(tx.ns, item, json, mode)
+	}
+	if tx.journal == nil {
+		return
+	}
+	if json != nil {
+		json = append([]byte(nil), json...)
+	}
+	tx.journal = append(tx.journal, txStep{item: item, json: json, mode: mode, precepts: precepts})
+}
+
+// journalQuery records the transaction's query for RollbackTo. Query's buffer is reused after the execution, so it's copied
+func (tx *Tx) journalQuery(query []byte, update bool) {
+	tx.modified = true
+	if tx.reads != nil {
+		tx.reads.addQuery()
+	}
+	if tx.journal == nil {
+		return
+	}
+	tx.journal = append(tx.journal, txStep{query: append([]byte(nil), query...), update: update})
+}
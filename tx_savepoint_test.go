@@ -0,0 +1,52 @@
+package reindexer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestTxSavepoints(t *testing.T) {
+	db, srv := newMockDB(t, "tx_savepoints")
+	defer db.Close()
+
+	tx, err := db.BeginTx(testNs)
+	require.NoError(t, err)
+	tx.WithSavepoints()
+	require.NoError(t, tx.Upsert(testItem{ID: 1, Name: "first"}))
+	require.NoError(t, tx.Savepoint("batch"))
+	require.NoError(t, tx.Upsert(testItem{ID: 2, Name: "failed"}))
+	require.NoError(t, tx.RollbackTo("batch"))
+	require.NoError(t, tx.Upsert(testItem{ID: 2, Name: "retried"}))
+	assert.ErrorIs(t, tx.RollbackTo("unknown"), reindexer.ErrTxSavepointNotFound)
+	require.NoError(t, tx.Commit())
+
+	// Server transaction is restarted with the items before the savepoint
+	begins := srv.CallsOf(mock.MethodBeginTx)
+	require.Len(t, begins, 2)
+	assert.Len(t, srv.CallsOf(mock.MethodRollbackTx), 1)
+	commits := srv.CallsOf(mock.MethodCommitTx)
+	require.Len(t, commits, 1)
+	assert.Equal(t, begins[1].TxID, commits[0].TxID)
+
+	var names []string
+	for _, call := range srv.CallsOf(mock.MethodModifyItemTx) {
+		if call.TxID != begins[1].TxID {
+			continue
+		}
+		var item testItem
+		require.NoError(t, call.DecodeItem(&item))
+		names = append(names, item.Name)
+	}
+	assert.Equal(t, []string{"first", "retried"}, names)
+
+	tx, err = db.BeginTx(testNs)
+	require.NoError(t, err)
+	require.NoError(t, tx.Upsert(testItem{ID: 3}))
+	assert.ErrorIs(t, tx.WithSavepoints().Savepoint("late"), reindexer.ErrTxSavepointsDisabled)
+	require.NoError(t, tx.Rollback())
+}
@@ -0,0 +1,66 @@
+package reindexer
+
+import (
+	"sync/atomic"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// ErrTxAsyncWindowFull is returned by async methods of the transaction, if the window of the items, waiting for the responses,
+// is full and WithTxAsyncWindow option has FailOnFull set
+var ErrTxAsyncWindowFull = bindings.NewError("rq: Window of async items of the transaction is full", ErrCodeLogic)
+
+// TxAsyncWindow - limits of the items of each transaction, which are sent by async methods and are waiting for the responses (see WithTxAsyncWindow)
+type TxAsyncWindow struct {
+	// Max count of the items. 500, if zero
+	MaxItems int
+	// Max size of the serialized items in bytes. Item, which exceeds the limit itself, is sent, when there are no other items in the window.
+	// Size is not limited, if zero
+	MaxBytes int64
+	// Async methods return ErrTxAsyncWindowFull instead of waiting for the responses, if the window is full
+	FailOnFull bool
+}
+
+// txWindowItems returns max count of the async items of the transaction
+func (db *reindexerImpl) txWindowItems() uint32 {
+	if db.txWindow.MaxItems > 0 {
+		return uint32(db.txWindow.MaxItems)
+	}
+	return maxAsyncRequests
+}
+
+// acquireAsyncBytes reserves size bytes of the window for the async item. Retries of the items are sent by completions handling routine,
+// so they must not wait for the window. Returns reserved size, which must be released after the response
+func (tx *Tx) acquireAsyncBytes(size int, wait bool) (int64, error) {
+	maxBytes := tx.db.txWindow.MaxBytes
+	if maxBytes <= 0 {
+		return 0, nil
+	}
+	tx.cmplCond.L.Lock()
+	defer tx.cmplCond.L.Unlock()
+	if wait && tx.asyncBytes > 0 && tx.asyncBytes+int64(size) > maxBytes {
+		if tx.db.txWindow.FailOnFull {
+			atomic.AddInt64(&tx.db.counters.txWindowRejects, 1)
+			return 0, ErrTxAsyncWindowFull
+		}
+		atomic.AddInt64(&tx.db.counters.txWindowWaits, 1)
+		for tx.asyncBytes > 0 && tx.asyncBytes+int64(size) > maxBytes {
+			tx.cmplCond.Wait()
+		}
+	}
+	tx.asyncBytes += int64(size)
+	atomic.AddInt64(&tx.db.counters.pendingAsyncBytes, int64(size))
+	return int64(size), nil
+}
+
+// releaseAsyncBytes releases bytes of the window, reserved by acquireAsyncBytes
+func (tx *Tx) releaseAsyncBytes(size int64) {
+	if size == 0 {
+		return
+	}
+	tx.cmplCond.L.Lock()
+	tx.asyncBytes -= size
+	atomic.AddInt64(&tx.db.counters.pendingAsyncBytes, -size)
+	tx.cmplCond.Broadcast()
+	tx.cmplCond.L.Unlock()
+}
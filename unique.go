@@ -0,0 +1,126 @@
+package reindexer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// ErrUniqueViolation may be used with errors.Is to check, if the error is UniqueViolationError
+var ErrUniqueViolation = bindings.NewError("rq: Unique violation", ErrCodeLogic)
+
+// UniqueViolationError is returned, when modification of items violates uniqueness of the primary key
+// (e.g. update query sets primary key of the item to the value of another item's key)
+type UniqueViolationError struct {
+	// Name of the primary key index
+	Index string
+	// Values of the conflicting key. Strings are unquoted, composite key contains value for each of its fields
+	Key []string
+	// Internal ids of items with the same key
+	Rows []int
+	err  bindings.Error
+}
+
+func (e *UniqueViolationError) Error() string {
+	return e.err.Error()
+}
+
+// Code returns code of the server's error
+func (e *UniqueViolationError) Code() int {
+	return e.err.Code()
+}
+
+func (e *UniqueViolationError) Is(target error) bool {
+	return target == ErrUniqueViolation
+}
+
+func (e *UniqueViolationError) Unwrap() error {
+	return e.err
+}
+
+// Server's error looks like 'Duplicate Primary Key {id: {5}} for rows [10, 12]!'
+var uniqueViolationRe = regexp.MustCompile(`^Duplicate Primary Key \{(.+?): \{(.*)\}\} for rows \[(.*)\]!$`)
+
+// asUniqueViolation converts server's error about duplicate primary key to UniqueViolationError. Other errors are returned as is
+func asUniqueViolation(err error) error {
+	rerr, ok := err.(bindings.Error)
+	if !ok || rerr.Code() != ErrCodeLogic {
+		return err
+	}
+	m := uniqueViolationRe.FindStringSubmatch(rerr.Error())
+	if m == nil {
+		return err
+	}
+	uerr := &UniqueViolationError{Index: m[1], err: rerr}
+	key := m[2]
+	if strings.HasPrefix(key, "{") && strings.HasSuffix(key, "}") {
+		// Composite key
+		key = key[1 : len(key)-1]
+	}
+	uerr.Key = splitDumpedValues(key)
+	for _, row := range strings.Split(m[3], ", ") {
+		if id, err := strconv.Atoi(row); err == nil {
+			uerr.Rows = append(uerr.Rows, id)
+		}
+	}
+	return uerr
+}
+
+// splitDumpedValues splits comma separated values, dumped by the server. Strings are quoted by ', which are removed
+func splitDumpedValues(s string) []string {
+	var values []string
+	for len(s) > 0 {
+		var v string
+		if s[0] == '\'' {
+			end := strings.Index(s[1:], "', ")
+			if end < 0 {
+				v, s = strings.TrimSuffix(s[1:], "'"), ""
+			} else {
+				v, s = s[1:end+1], s[end+4:]
+			}
+		} else if end := strings.Index(s, ", "); end < 0 {
+			v, s = s, ""
+		} else {
+			v, s = s[:end], s[end+2:]
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// pkIndexName returns name of the primary key index: names of the fields of the composite key are joined by '+'
+func (ns *reindexerNamespace) pkIndexName() string {
+	names := make([]string, 0, len(ns.pk))
+	for i := range ns.pk {
+		names = append(names, ns.pk[i].index)
+	}
+	return strings.Join(names, "+")
+}
+
+// insertViolation returns UniqueViolationError for the item, which was not inserted, because the item with the same primary key exists.
+// Key is empty for items in JSON format
+func (ns *reindexerNamespace) insertViolation(item interface{}) error {
+	uerr := &UniqueViolationError{Index: ns.pkIndexName()}
+	for _, v := range ns.pkValues(item) {
+		uerr.Key = append(uerr.Key, fmt.Sprint(v))
+	}
+	msg := fmt.Sprintf("rq: Item with primary key {%s: {%s}} already exists in namespace '%s'", uerr.Index, strings.Join(uerr.Key, ", "), ns.name)
+	uerr.err = bindings.NewError(msg, ErrCodeLogic).(bindings.Error)
+	return uerr
+}
+
+// insertViolation returns UniqueViolationError, if some of the items, inserted by the committed transaction, already existed.
+// Server doesn't report, which items of the transaction were not modified, so the error is returned only for the transactions
+// without updates and deletes of the items (their items are not modified, if they don't exist): count of the committed items
+// is less, than count of the sent ones, only because of inserts then. Key is empty
+func (tx *Tx) insertViolation(count int) error {
+	c := &tx.itemCounts
+	if c.inserts == 0 || c.updatesDeletes != 0 || count >= c.items {
+		return nil
+	}
+	msg := fmt.Sprintf("rq: %d of %d items, inserted by the transaction, already exist in namespace '%s'", c.items-count, c.inserts, tx.namespace)
+	return &UniqueViolationError{Index: tx.ns.pkIndexName(), err: bindings.NewError(msg, ErrCodeLogic).(bindings.Error)}
+}
@@ -0,0 +1,57 @@
+package reindexer
+
+import (
+	"math/rand"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// ResultMismatch - item of the query's results, which doesn't match conditions of the query, evaluated by the client (see WithResultVerification)
+type ResultMismatch = bindings.ResultMismatch
+
+// ResultMismatchHandler is called for each item of the verified query, which doesn't match the query's conditions.
+// It's called by Iterator.Next, so it must not block
+type ResultMismatchHandler = bindings.ResultMismatchHandler
+
+// resultVerifier evaluates conditions of the sampled query on the items of its results
+type resultVerifier struct {
+	matcher *txMatcher
+}
+
+// newResultVerifier returns verifier of the query's results, or nil, if the query is not sampled or its conditions can't be
+// evaluated by the client (e.g. joins, full text search or fields, which are not described by the struct)
+func (db *reindexerImpl) newResultVerifier(q *Query) *resultVerifier {
+	if db.verification == nil || rand.Float64() >= db.verification.SampleRate {
+		return nil
+	}
+	ns, err := db.getNS(q.Namespace)
+	if err != nil || ns.rtype == nil {
+		return nil
+	}
+	d, err := q.toDSL()
+	if err != nil {
+		return nil
+	}
+	m, err := newTxMatcher(ns, d, false)
+	if err != nil {
+		return nil
+	}
+	if db.promMetrics != nil {
+		db.promMetrics.verifiedQueries.WithLabelValues(ns.name).Inc()
+	}
+	return &resultVerifier{matcher: m}
+}
+
+// verifyCurrent checks, that the current item of the iterator matches conditions of the query, and reports mismatch otherwise
+func (it *Iterator) verifyCurrent() {
+	if matched, err := it.verifier.matcher.match(it.current.obj); err != nil || matched {
+		return
+	}
+	if it.db.promMetrics != nil {
+		it.db.promMetrics.resultMismatches.WithLabelValues(it.namespace).Inc()
+	}
+	if handler := it.db.verification.Handler; handler != nil {
+		sql, _ := it.query.SQL()
+		handler(it.userCtx, ResultMismatch{Namespace: it.namespace, Query: sql, Label: it.query.label, Item: it.current.obj})
+	}
+}
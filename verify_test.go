@@ -0,0 +1,55 @@
+package reindexer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+)
+
+func TestResultVerification(t *testing.T) {
+	var mismatches []reindexer.ResultMismatch
+	registry := prometheus.NewRegistry()
+	db, srv := newMockDB(t, "verification",
+		reindexer.WithPrometheusMetrics(reindexer.PrometheusMetricsOptions{Registerer: registry, Prefix: "mockverify"}),
+		reindexer.WithResultVerification(1, func(ctx context.Context, m reindexer.ResultMismatch) {
+			mismatches = append(mismatches, m)
+		}))
+	defer db.Close()
+	// Server returns the same items for any query, like misconfigured index does
+	srv.SetResults(testNs, testItem{ID: 1, Name: "first", Tags: []string{"a"}}, testItem{ID: 2, Name: "second", Tags: []string{"b"}})
+
+	items, err := db.Query(testNs).WhereString("name", reindexer.EQ, "first").Label("by_name").Exec().FetchAll()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, testNs, mismatches[0].Namespace)
+	assert.Equal(t, "by_name", mismatches[0].Label)
+	assert.Equal(t, 2, mismatches[0].Item.(*testItem).ID)
+	assert.Contains(t, mismatches[0].Query, "name")
+
+	mismatches = nil
+	_, err = db.Query(testNs).WhereString("tags", reindexer.SET, "a", "b").Exec().FetchAll()
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	// Conditions, which can't be evaluated by the client, are not verified
+	_, err = db.Query(testNs).WhereString("name", reindexer.LIKE, "fir%").Exec().FetchAll()
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			values[f.GetName()] += m.GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, float64(2), values["mockverify_client_verified_queries_total"])
+	assert.Equal(t, float64(1), values["mockverify_client_result_mismatches_total"])
+}
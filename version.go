@@ -0,0 +1,134 @@
+package reindexer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// versionField describes field, marked by `version` option in `reindex:` tag, which is used for optimistic locking
+type versionField struct {
+	structFieldRef
+}
+
+func parseVersionField(st reflect.Type, pks []structFieldRef) (*versionField, error) {
+	var ver *versionField
+	err := walkStructFields(st, "", nil, func(sf reflect.StructField, t reflect.Type, reindexPath string, fieldIdx []int, idxSettings []string) error {
+		if !parseOpts(&idxSettings).isVersion {
+			return nil
+		}
+		if ver != nil {
+			return fmt.Errorf("Only one version field is allowed. Found '%s' and '%s'", ver.index, reindexPath)
+		}
+		if len(fieldIdx) != 1 {
+			return fmt.Errorf("Version field '%s' must be a top level field of the struct", sf.Name)
+		}
+		if strings.TrimSpace(reindexPath) == "" || t.Kind() == reflect.Struct {
+			return fmt.Errorf("version option requires index on field %s", sf.Name)
+		}
+		switch t.Kind() {
+		case reflect.Int, reflect.Int64:
+		default:
+			return fmt.Errorf("version option is allowed only for int64 fields. Field %s has type %s", sf.Name, t.Kind().String())
+		}
+		ver = &versionField{structFieldRef: structFieldRef{index: reindexPath, fieldIdx: fieldIdx}}
+		return nil
+	})
+	if err != nil || ver == nil {
+		return nil, err
+	}
+	if len(pks) == 0 {
+		return nil, fmt.Errorf("version option requires pk fields in the struct")
+	}
+	return ver, nil
+}
+
+// get returns current version of the item. Returns false for non-struct items
+func (f *versionField) get(item interface{}) (int64, bool) {
+	if item == nil {
+		return 0, false
+	}
+	if isRawItem(item) {
+		return 0, false
+	}
+	v, ok := f.value(item)
+	if !ok {
+		return 0, false
+	}
+	return reflect.Indirect(v).Int(), true
+}
+
+// modifyVersionedItem modifies item of the namespace with version field.
+// Insert creates item with version 1. Upsert of item with zero version writes it without the check and sets its version to 1.
+// Update (and Upsert of item with non-zero version) replaces the item only if its version in the namespace
+// is equal to the version of the passed item, and increments the version. Otherwise ErrVersionConflict is returned
+func (db *reindexerImpl) modifyVersionedItem(ctx context.Context, ns *reindexerNamespace, item interface{}, mode int, precepts []string) (int, error) {
+	ver, _ := ns.version.get(item)
+
+	if mode == modeInsert || (mode == modeUpsert && ver == 0) {
+		precepts = append(precepts[:len(precepts):len(precepts)], ns.version.index+"=1")
+		return db.modifyItemImpl(ctx, ns, item, nil, mode, precepts)
+	}
+
+	qf := db.query(ns.name)
+	if err := ns.wherePk(qf, item); err != nil {
+		qf.close()
+		return 0, err
+	}
+	if _, found := qf.GetCtx(ctx); !found {
+		// Missing item is not created, like regular Update does
+		if mode == modeUpdate {
+			return 0, nil
+		}
+		return 0, ErrVersionConflict
+	}
+
+	// Server has no conditions on the item's modification, so the item is replaced by the transaction: the stored item is deleted
+	// only if it has the expected version, and the insert of the whole item succeeds only after the delete
+	replacement := reflect.New(reflect.Indirect(reflect.ValueOf(item)).Type())
+	replacement.Elem().Set(reflect.Indirect(reflect.ValueOf(item)))
+	replacement.Elem().Field(ns.version.fieldIdx[0]).SetInt(ver + 1)
+
+	tx, err := db.beginNsTx(ctx, ns.name, ns)
+	if err != nil {
+		return 0, err
+	}
+	q := tx.Query()
+	if err = ns.wherePk(q, item); err != nil {
+		q.close()
+		tx.Rollback()
+		return 0, err
+	}
+	if _, err = q.WhereInt64(ns.version.index, EQ, ver).DeleteCtx(ctx); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err = tx.sendItem(replacement.Interface(), nil, modeInsert, precepts); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	count, err := tx.commitInternal()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, ErrVersionConflict
+	}
+
+	if dst := reflect.ValueOf(item); dst.Kind() == reflect.Ptr {
+		if len(precepts) != 0 {
+			// Fields, which are set by precepts, are not returned by the transaction
+			qf = db.query(ns.name)
+			if err = ns.wherePk(qf, item); err != nil {
+				qf.close()
+				return count, err
+			}
+			if stored, found := qf.GetCtx(ctx); found && reflect.TypeOf(stored) == dst.Type() {
+				replacement = reflect.ValueOf(stored)
+			}
+		}
+		dst.Elem().Set(replacement.Elem())
+	}
+	return count, nil
+}
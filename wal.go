@@ -0,0 +1,222 @@
+package reindexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	otelattr "go.opentelemetry.io/otel/attribute"
+
+	"github.com/restream/reindexer/v3/bindings"
+)
+
+// walPageSize is count of the records, which are requested by one query of WALIterator
+const walPageSize = 1000
+
+// WALRecordType - type of the record of namespace's write ahead log
+type WALRecordType string
+
+const (
+	// Modification of the item. The current state of the item is returned for the items, which were not modified since the record
+	WALItemModify WALRecordType = "item_modify"
+	// Addition, update or drop of the index
+	WALIndexUpdate WALRecordType = "index_update"
+	// Truncation of the namespace
+	WALTruncate WALRecordType = "truncate"
+	// Update or delete query
+	WALUpdateQuery WALRecordType = "update_query"
+	// Other records (meta, schema, transactions, etc). They are available in WALRecord.Raw
+	WALOther WALRecordType = "other"
+)
+
+// WALRecord - record of namespace's write ahead log, returned by WALIterator
+type WALRecord struct {
+	// LSN counter of the record. -1, if the binding doesn't return LSN of the records
+	LSN  int64
+	Type WALRecordType
+	// Modification of WALItemModify record: "Insert", "Update", "Upsert" or "Delete"
+	Mode string
+	// Item of WALItemModify record in JSON format
+	Item json.RawMessage
+	// Operation of WALIndexUpdate record: "add", "update" or "drop"
+	IndexOp string
+	// Index of WALIndexUpdate record
+	Index *IndexDef
+	// SQL of WALUpdateQuery and WALTruncate records
+	Query string
+	// Record in JSON format, as it is returned by the server
+	Raw json.RawMessage
+}
+
+// walRecordJSON is the server's JSON of the raw WAL record
+type walRecordJSON struct {
+	Type  string          `json:"type"`
+	Mode  json.RawMessage `json:"mode"`
+	Item  json.RawMessage `json:"item"`
+	Index *IndexDef       `json:"index"`
+	Query string          `json:"query"`
+}
+
+var walIndexOps = map[string]string{
+	"WalIndexAdd":    "add",
+	"WalIndexUpdate": "update",
+	"WalIndexDrop":   "drop",
+}
+
+// WALIterator - iterator over the records of namespace's write ahead log (see WALQuery). Records are requested by pages,
+// so the log is consumed incrementally. WALIterator is not thread safe
+type WALIterator struct {
+	db        *reindexerImpl
+	ctx       context.Context
+	namespace string
+	// LSN of the last returned record
+	lsn     int64
+	records []WALRecord
+	ptr     int
+	// The last page is received
+	done    bool
+	current WALRecord
+	err     error
+}
+
+func (db *reindexerImpl) walQuery(ctx context.Context, namespace string, fromLSN int64) *WALIterator {
+	return &WALIterator{db: db, ctx: ctx, namespace: strings.ToLower(namespace), lsn: fromLSN}
+}
+
+// Next moves iterator to the next record. Returns false, if there are no more records or on error
+func (it *WALIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.ptr >= len(it.records) {
+		if it.done {
+			return false
+		}
+		if it.records, it.err = it.db.selectWAL(it.ctx, it.namespace, it.lsn); it.err != nil {
+			return false
+		}
+		it.ptr = 0
+		// Without LSN of the records the next page can't be requested, so the whole log is requested at once
+		it.done = len(it.records) < walPageSize || it.records[len(it.records)-1].LSN < 0
+		if len(it.records) == 0 {
+			return false
+		}
+	}
+	it.current = it.records[it.ptr]
+	it.ptr++
+	if it.current.LSN >= 0 {
+		it.lsn = it.current.LSN
+	}
+	return true
+}
+
+// Record returns the current record
+func (it *WALIterator) Record() WALRecord {
+	return it.current
+}
+
+// LSN returns LSN of the last returned record, or fromLSN of WALQuery, if there are no such records.
+// It may be stored to continue reading of the log by the next WALQuery
+func (it *WALIterator) LSN() int64 {
+	return it.lsn
+}
+
+// Error returns error of reading of the log
+func (it *WALIterator) Error() error {
+	return it.err
+}
+
+// Close releases the records of the iterator
+func (it *WALIterator) Close() {
+	it.records = nil
+}
+
+// selectWAL returns the page of the namespace's WAL records after the LSN
+func (db *reindexerImpl) selectWAL(ctx context.Context, namespace string, fromLSN int64) (records []WALRecord, err error) {
+	defer db.recoverPanic("WALQuery", &err)
+	if db.otelTracer != nil {
+		var span *tracingSpan
+		ctx, span = db.startTracingSpan(ctx, "Reindexer.WALQuery", otelattr.String("rx.ns", namespace))
+		defer span.End()
+	}
+
+	flagsBinding, withLSN := db.binding.(bindings.RawBindingResultsFlags)
+	q := db.query(namespace).WhereInt64("#lsn", GT, fromLSN)
+	defer q.close()
+	if withLSN {
+		q.Limit(walPageSize)
+	}
+	// WAL is not filtered by the default filters, so the query is serialized as is
+	ser := q.ser
+	ser.PutVarCUInt(queryEnd)
+
+	ctx, cancel := db.withDefaultDeadline(ctx, namespace)
+	defer cancel()
+	var result bindings.RawBuffer
+	if withLSN {
+		result, err = flagsBinding.SelectQueryWithFlags(ctx, ser.Bytes(), bindings.ResultsJson|bindings.ResultsWithItemID, nil, -1)
+	} else {
+		result, err = db.binding.SelectQuery(ctx, ser.Bytes(), true, nil, -1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer result.Free()
+
+	rser := newSerializer(result.GetBuf())
+	params := rser.readRawQueryParams()
+	records = make([]WALRecord, 0, params.count)
+	for i := 0; i < params.count; i++ {
+		item := rser.readRawtItemParams()
+		lsn := int64(-1)
+		if params.flags&bindings.ResultsWithItemID != 0 {
+			lsn = int64(item.version)
+		}
+		record, err := parseWALRecord(item.data)
+		if err != nil {
+			return nil, err
+		}
+		record.LSN = lsn
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseWALRecord converts the server's JSON of the record into WALRecord. Items without WAL type are the current states of the modified items
+func parseWALRecord(data []byte) (WALRecord, error) {
+	raw := append(json.RawMessage(nil), data...)
+	record := WALRecord{Raw: raw}
+	var rec walRecordJSON
+	if err := json.Unmarshal(raw, &rec); err != nil || !strings.HasPrefix(rec.Type, "Wal") {
+		record.Type, record.Mode, record.Item = WALItemModify, modifyModeNames[modeUpsert], raw
+		return record, nil
+	}
+
+	switch {
+	case rec.Type == "WalItemModify":
+		record.Type, record.Item = WALItemModify, rec.Item
+		record.Mode = walModifyMode(rec.Mode)
+	case walIndexOps[rec.Type] != "":
+		record.Type, record.IndexOp, record.Index = WALIndexUpdate, walIndexOps[rec.Type], rec.Index
+	case rec.Type == "WalUpdateQuery":
+		record.Type, record.Query = WALUpdateQuery, rec.Query
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(rec.Query)), "TRUNCATE") {
+			record.Type = WALTruncate
+		}
+	default:
+		record.Type = WALOther
+	}
+	return record, nil
+}
+
+// walModifyMode returns name of the modification. Server's mode is either the number of the mode or its name
+func walModifyMode(mode json.RawMessage) string {
+	var num int
+	if err := json.Unmarshal(mode, &num); err == nil {
+		return modifyModeNames[num]
+	}
+	var name string
+	json.Unmarshal(bytes.TrimSpace(mode), &name)
+	return name
+}
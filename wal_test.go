@@ -0,0 +1,50 @@
+package reindexer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/restream/reindexer/v3"
+	"github.com/restream/reindexer/v3/bindings/mock"
+)
+
+func TestWALQuery(t *testing.T) {
+	db, srv := newMockDB(t, "wal")
+	defer db.Close()
+
+	srv.SetResults(testNs,
+		testItem{ID: 1, Name: "current"},
+		map[string]interface{}{"type": "WalItemModify", "mode": 3, "item": map[string]interface{}{"id": 2}},
+		map[string]interface{}{"type": "WalIndexAdd", "index": map[string]interface{}{"name": "tags", "json_paths": []string{"tags"}, "index_type": "hash", "field_type": "string"}},
+		map[string]interface{}{"type": "WalUpdateQuery", "query": "TRUNCATE items"},
+		map[string]interface{}{"type": "WalPutMeta"},
+	)
+	it := db.WALQuery(context.Background(), testNs, 10)
+	defer it.Close()
+	var records []reindexer.WALRecord
+	for it.Next() {
+		records = append(records, it.Record())
+	}
+	require.NoError(t, it.Error())
+	require.Len(t, records, 5)
+
+	assert.Equal(t, reindexer.WALItemModify, records[0].Type)
+	assert.Equal(t, "Upsert", records[0].Mode)
+	assert.JSONEq(t, `{"ID":1,"Name":"current","Tags":null}`, string(records[0].Item))
+	assert.Equal(t, reindexer.WALItemModify, records[1].Type)
+	assert.Equal(t, "Delete", records[1].Mode)
+	assert.JSONEq(t, `{"id":2}`, string(records[1].Item))
+	assert.Equal(t, reindexer.WALIndexUpdate, records[2].Type)
+	assert.Equal(t, "add", records[2].IndexOp)
+	require.NotNil(t, records[2].Index)
+	assert.Equal(t, "tags", records[2].Index.Name)
+	assert.Equal(t, reindexer.WALTruncate, records[3].Type)
+	assert.Equal(t, reindexer.WALOther, records[4].Type)
+	// Mock binding doesn't return LSN of the records
+	assert.Equal(t, int64(-1), records[0].LSN)
+	assert.Equal(t, int64(10), it.LSN())
+	assert.Len(t, srv.CallsOf(mock.MethodSelectQuery), 1)
+}